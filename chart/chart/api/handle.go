@@ -1,25 +1,52 @@
 package api
 
 import (
+    "bufio"
+    "encoding/csv"
+    "encoding/json"
+    "errors"
     "net/http"
+    "chart/domain"
     "chart/storage"
     "strconv"
+    "sync"
+    "time"
 
     "github.com/gin-gonic/gin"
 )
 
+// MaxPageSize 单次区间查询允许返回的最大玩家数
+const MaxPageSize = 1000
+
+const (
+	defaultStreamInterval = time.Second      // 前N名推送流检查版本的默认间隔
+	minStreamInterval     = 100 * time.Millisecond
+	streamHeartbeat       = 15 * time.Second // 无更新时发送注释行，避免代理断开空闲连接
+)
+
 // Handler HTTP请求处理器
 type Handler struct {
-	repo storage.Repository
+	repo  storage.Repository
+	audit *domain.MemoryAuditLog
+
+	closing   chan struct{} // 关闭后推送流立即结束
+	closeOnce sync.Once
 }
 
-// NewHandler 创建处理器
-func NewHandler(repo storage.Repository) *Handler {
+// NewHandler 创建处理器，audit 为 nil 时审计查询返回空列表
+func NewHandler(repo storage.Repository, audit *domain.MemoryAuditLog) *Handler {
 	return &Handler{
-		repo: repo,
+		repo:    repo,
+		audit:   audit,
+		closing: make(chan struct{}),
 	}
 }
 
+// Close 结束所有推送流，服务关闭时调用，避免长连接拖慢优雅退出
+func (h *Handler) Close() {
+	h.closeOnce.Do(func() { close(h.closing) })
+}
+
 // UpdateScore 更新玩家分数
 func (h *Handler) UpdateScore(c *gin.Context) {
 	leaderboardID := c.Query("leaderboard_id")
@@ -29,14 +56,21 @@ func (h *Handler) UpdateScore(c *gin.Context) {
 	}
 
 	var req struct {
-		PlayerID int64 `json:"player_id" binding:"required"`
-		Score    int64 `json:"score" binding:"required"`
+		PlayerID       int64               `json:"player_id" binding:"required"`
+		Score          int64               `json:"score" binding:"required"`
+		SecondaryScore int64               `json:"secondary_score"` // 可选，主分数相同时用于排序
+		Policy         domain.UpdatePolicy `json:"policy"`          // 可选，覆盖排行榜配置的更新策略
+		Timestamp      time.Time           `json:"timestamp"`       // 可选，RFC3339，早于玩家上一次被采纳的更新时忽略
 	}
 
 	if err := c.BindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !req.Policy.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid policy"})
+		return
+	}
 
 	leaderboard, err := h.repo.GetLeaderboard(leaderboardID)
 	if err != nil {
@@ -44,7 +78,12 @@ func (h *Handler) UpdateScore(c *gin.Context) {
 		return
 	}
 
-	if err := leaderboard.UpdateScore(req.PlayerID, req.Score); err != nil {
+	applied, err := leaderboard.UpdateScoreAt(req.PlayerID, req.Score, req.SecondaryScore, req.Timestamp, req.Policy)
+	if errors.Is(err, domain.ErrQueueFull) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -55,7 +94,55 @@ func (h *Handler) UpdateScore(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": "success"})
+	c.JSON(http.StatusOK, gin.H{"status": "success", "applied": applied})
+}
+
+// BatchUpdateScore 批量更新玩家分数，直接写入排行榜的批量通道
+// 更新按顺序入队，返回成功入队的条数；通道已满时返回 503，已入队的更新仍会被应用
+// 入队不代表被采纳：被校验器拒绝的更新不逐条返回，只写入审计日志
+func (h *Handler) BatchUpdateScore(c *gin.Context) {
+	leaderboardID := c.Query("leaderboard_id")
+	if leaderboardID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "leaderboard_id is required"})
+		return
+	}
+
+	var req struct {
+		Updates []*domain.ScoreUpdate `json:"updates" binding:"required,min=1,max=1000,dive,required"`
+		Policy  domain.UpdatePolicy   `json:"policy"` // 可选，覆盖排行榜配置的更新策略
+	}
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !req.Policy.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid policy"})
+		return
+	}
+
+	leaderboard, err := h.repo.GetLeaderboard(leaderboardID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "leaderboard not found"})
+		return
+	}
+
+	queued, err := leaderboard.UpdateScores(req.Updates, req.Policy)
+	if errors.Is(err, domain.ErrQueueFull) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error(), "queued": queued})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "queued": queued})
+		return
+	}
+
+	if err := h.repo.SaveLeaderboard(leaderboard); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success", "queued": queued})
 }
 
 // GetPlayerRank 获取玩家排名
@@ -92,6 +179,222 @@ func (h *Handler) GetPlayerRank(c *gin.Context) {
 	})
 }
 
+// GetPlayerScore 获取玩家当前分数（不计算排名）
+func (h *Handler) GetPlayerScore(c *gin.Context) {
+	leaderboardID := c.Query("leaderboard_id")
+	playerIDStr := c.Query("player_id")
+
+	if leaderboardID == "" || playerIDStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "leaderboard_id and player_id are required"})
+		return
+	}
+
+	playerID, err := strconv.ParseInt(playerIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player_id"})
+		return
+	}
+
+	leaderboard, err := h.repo.GetLeaderboard(leaderboardID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "leaderboard not found"})
+		return
+	}
+
+	player, version, err := leaderboard.GetPlayerScore(playerID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"player_id":       player.ID,
+		"score":           player.Score,
+		"secondary_score": player.SecondaryScore,
+		"update_time":     player.UpdateTime,
+		"version":         version,
+	})
+}
+
+// GetPlayerHistory 获取玩家的分数变化历史，按时间由旧到新排列，用于绘制成绩曲线
+// 查询参数：leaderboard_id 必填；since 为 RFC3339 时间，只返回之后的记录；limit 默认 100、最多 MaxPageSize
+func (h *Handler) GetPlayerHistory(c *gin.Context) {
+	leaderboardID := c.Query("leaderboard_id")
+	if leaderboardID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "leaderboard_id is required"})
+		return
+	}
+
+	playerID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player id"})
+		return
+	}
+
+	var since time.Time
+	if raw := c.Query("since"); raw != "" {
+		if since, err = time.Parse(time.RFC3339, raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid since, must be RFC3339"})
+			return
+		}
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	leaderboard, err := h.repo.GetLeaderboard(leaderboardID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "leaderboard not found"})
+		return
+	}
+
+	history, err := leaderboard.GetScoreHistory(playerID, since, limit)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"player_id": playerID,
+		"history":   history,
+	})
+}
+
+// GetRankByScore 查询给定分数将会获得的排名
+func (h *Handler) GetRankByScore(c *gin.Context) {
+	leaderboardID := c.Query("leaderboard_id")
+	scoreStr := c.Query("score")
+
+	if leaderboardID == "" || scoreStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "leaderboard_id and score are required"})
+		return
+	}
+
+	score, err := strconv.ParseInt(scoreStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid score"})
+		return
+	}
+
+	leaderboard, err := h.repo.GetLeaderboard(leaderboardID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "leaderboard not found"})
+		return
+	}
+
+	rank, total := leaderboard.GetRankByScore(score)
+	c.JSON(http.StatusOK, gin.H{
+		"score": score,
+		"rank":  rank,
+		"total": total,
+	})
+}
+
+// GetAroundScore 获取分数附近的玩家，用于按分数匹配对手
+func (h *Handler) GetAroundScore(c *gin.Context) {
+	leaderboardID := c.Query("leaderboard_id")
+	scoreStr := c.Query("score")
+
+	if leaderboardID == "" || scoreStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "leaderboard_id and score are required"})
+		return
+	}
+
+	score, err := strconv.ParseInt(scoreStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid score"})
+		return
+	}
+
+	count, err := strconv.Atoi(c.DefaultQuery("count", "5"))
+	if err != nil || count <= 0 {
+		count = 5
+	}
+	if count > MaxPageSize/2 {
+		count = MaxPageSize / 2
+	}
+
+	leaderboard, err := h.repo.GetLeaderboard(leaderboardID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "leaderboard not found"})
+		return
+	}
+
+	players, rank := leaderboard.GetAroundScore(score, count)
+	c.JSON(http.StatusOK, gin.H{
+		"score":   score,
+		"rank":    rank,
+		"players": players,
+	})
+}
+
+// GetPlayerRanks 批量获取玩家排名
+func (h *Handler) GetPlayerRanks(c *gin.Context) {
+	leaderboardID := c.Query("leaderboard_id")
+	if leaderboardID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "leaderboard_id is required"})
+		return
+	}
+
+	var req struct {
+		PlayerIDs []int64 `json:"player_ids" binding:"required"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.PlayerIDs) > MaxPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "too many player_ids, max " + strconv.Itoa(MaxPageSize)})
+		return
+	}
+
+	leaderboard, err := h.repo.GetLeaderboard(leaderboardID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "leaderboard not found"})
+		return
+	}
+
+	players, notFound := leaderboard.GetPlayerRanks(req.PlayerIDs)
+	c.JSON(http.StatusOK, gin.H{
+		"players":   players,
+		"not_found": notFound,
+	})
+}
+
+// RemovePlayer 从排行榜移除玩家
+func (h *Handler) RemovePlayer(c *gin.Context) {
+	leaderboardID := c.Query("leaderboard_id")
+	playerIDStr := c.Query("player_id")
+
+	if leaderboardID == "" || playerIDStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "leaderboard_id and player_id are required"})
+		return
+	}
+
+	playerID, err := strconv.ParseInt(playerIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player_id"})
+		return
+	}
+
+	if !h.repo.ExistsLeaderboard(leaderboardID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "leaderboard not found"})
+		return
+	}
+
+	if err := h.repo.RemovePlayer(leaderboardID, playerID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
 // GetTopRanks 获取前N名
 func (h *Handler) GetTopRanks(c *gin.Context) {
 	leaderboardID := c.Query("leaderboard_id")
@@ -117,6 +420,192 @@ func (h *Handler) GetTopRanks(c *gin.Context) {
 	c.JSON(http.StatusOK, topRanks)
 }
 
+// StreamTopRanks 以 Server-Sent Events 推送前N名
+// 连接建立后立即推送一次，之后每个 interval 检查排行榜版本，有变化时推送最新的前N名，
+// 同一间隔内的多次写入合并为一次推送。事件名为 top-ranks，数据为 { "version", "players" }。
+// 查询参数：leaderboard_id 必填；limit 默认 100、最多 MaxPageSize；interval 默认 1s、最小 100ms
+func (h *Handler) StreamTopRanks(c *gin.Context) {
+	leaderboardID := c.Query("leaderboard_id")
+	if leaderboardID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "leaderboard_id is required"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	interval := defaultStreamInterval
+	if raw := c.Query("interval"); raw != "" {
+		if interval, err = time.ParseDuration(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid interval, e.g. 500ms"})
+			return
+		}
+		if interval < minStreamInterval {
+			interval = minStreamInterval
+		}
+	}
+
+	leaderboard, err := h.repo.GetLeaderboard(leaderboardID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "leaderboard not found"})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	lastSent := time.Now()
+	sent := int64(-1)
+	for {
+		if version := leaderboard.Version(); version != sent {
+			c.SSEvent("top-ranks", gin.H{"version": version, "players": leaderboard.GetTopRanks(limit)})
+			sent, lastSent = version, time.Now()
+			c.Writer.Flush()
+		} else if time.Since(lastSent) >= streamHeartbeat {
+			c.Writer.WriteString(": heartbeat\n\n")
+			lastSent = time.Now()
+			c.Writer.Flush()
+		}
+
+		select {
+		case <-ticker.C:
+		case <-c.Request.Context().Done():
+			return
+		case <-h.closing:
+			return
+		}
+	}
+}
+
+// GetBottomRanks 获取最后N名，从最后一名开始排列
+func (h *Handler) GetBottomRanks(c *gin.Context) {
+	leaderboardID := c.Query("leaderboard_id")
+	if leaderboardID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "leaderboard_id is required"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	leaderboard, err := h.repo.GetLeaderboard(leaderboardID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "leaderboard not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, leaderboard.GetBottomRanks(limit))
+}
+
+// GetRankRange 按排名区间分页获取玩家，reverse=true 时 start/end 从榜尾倒数
+func (h *Handler) GetRankRange(c *gin.Context) {
+	leaderboardID := c.Query("leaderboard_id")
+	if leaderboardID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "leaderboard_id is required"})
+		return
+	}
+
+	start, err := strconv.Atoi(c.Query("start"))
+	if err != nil || start < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start must be a positive integer"})
+		return
+	}
+	end, err := strconv.Atoi(c.Query("end"))
+	if err != nil || end < start {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end must be an integer not less than start"})
+		return
+	}
+	if end-start+1 > MaxPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "range exceeds max page size " + strconv.Itoa(MaxPageSize)})
+		return
+	}
+
+	leaderboard, err := h.repo.GetLeaderboard(leaderboardID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "leaderboard not found"})
+		return
+	}
+
+	var players []*domain.Player
+	var total int
+	if c.Query("reverse") == "true" {
+		players, total = leaderboard.GetRangeReverse(start, end)
+	} else {
+		players, total = leaderboard.GetRange(start, end)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"total":   total,
+		"start":   start,
+		"end":     end,
+		"players": players,
+	})
+}
+
+// ExportLeaderboard 按排名顺序流式导出整个榜单（csv 或 jsonl）
+// 逐批写出，不在内存中构造完整的玩家列表；响应头发出后出错只能中断连接。
+func (h *Handler) ExportLeaderboard(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "jsonl" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or jsonl"})
+		return
+	}
+
+	leaderboard, err := h.repo.GetLeaderboard(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "leaderboard not found"})
+		return
+	}
+
+	w := bufio.NewWriter(c.Writer)
+	var write func(p *domain.Player) error
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		cw := csv.NewWriter(w)
+		_ = cw.Write([]string{"rank", "player_id", "score", "secondary_score", "update_time"})
+		write = func(p *domain.Player) error {
+			cw.Write([]string{
+				strconv.Itoa(p.Rank),
+				strconv.FormatInt(p.ID, 10),
+				strconv.FormatInt(p.Score, 10),
+				strconv.FormatInt(p.SecondaryScore, 10),
+				p.UpdateTime.Format(time.RFC3339Nano),
+			})
+			cw.Flush()
+			return cw.Error()
+		}
+	} else {
+		c.Header("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(w)
+		write = func(p *domain.Player) error {
+			return enc.Encode(p)
+		}
+	}
+	c.Header("Content-Disposition", "attachment; filename=\""+leaderboard.ID+"."+format+"\"")
+	c.Status(http.StatusOK)
+
+	if err := leaderboard.Export(write); err != nil {
+		c.Error(err)
+		return
+	}
+	if err := w.Flush(); err != nil {
+		c.Error(err)
+	}
+}
+
 // GetLeaderboardInfo 获取排行榜信息
 func (h *Handler) GetLeaderboardInfo(c *gin.Context) {
 	leaderboardID := c.Query("leaderboard_id")
@@ -138,16 +627,76 @@ func (h *Handler) GetLeaderboardInfo(c *gin.Context) {
 		"name":         leaderboard.Name,
 		"player_count": playerCount,
 		"config":       leaderboard.Config,
+		"queue":        leaderboard.QueueStats(),
 	})
 }
 
+// DeleteLeaderboard 删除排行榜
+func (h *Handler) DeleteLeaderboard(c *gin.Context) {
+	leaderboardID := c.Param("id")
+	if !h.repo.ExistsLeaderboard(leaderboardID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "leaderboard not found"})
+		return
+	}
+
+	if err := h.repo.DeleteLeaderboard(leaderboardID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// ResetLeaderboard 重置排行榜（清空所有玩家数据，保留配置）
+func (h *Handler) ResetLeaderboard(c *gin.Context) {
+	leaderboard, err := h.repo.GetLeaderboard(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "leaderboard not found"})
+		return
+	}
+
+	leaderboard.Reset()
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// GetRejectedUpdates 查询最近被校验器拒绝的分数更新
+func (h *Handler) GetRejectedUpdates(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	rejected := []*domain.RejectedUpdate{}
+	if h.audit != nil {
+		rejected = h.audit.Recent(c.Query("leaderboard_id"), limit)
+	}
+	c.JSON(http.StatusOK, gin.H{"rejected": rejected})
+}
+
 // RegisterRoutes 注册路由
 func (h *Handler) RegisterRoutes(router *gin.Engine) {
 	api := router.Group("/api/v1")
 	{
 		api.PUT("/scores", h.UpdateScore)
+		api.PUT("/scores/batch", h.BatchUpdateScore)
 		api.GET("/player-rank", h.GetPlayerRank)
+		api.GET("/player-score", h.GetPlayerScore)
+		api.GET("/rank-by-score", h.GetRankByScore)
+		api.GET("/around-score", h.GetAroundScore)
+		api.POST("/player-ranks", h.GetPlayerRanks)
+		api.DELETE("/players", h.RemovePlayer)
+		api.GET("/players/:id/history", h.GetPlayerHistory)
 		api.GET("/top-ranks", h.GetTopRanks)
+		api.GET("/top-ranks/stream", h.StreamTopRanks)
+		api.GET("/bottom-ranks", h.GetBottomRanks)
+		api.GET("/ranks", h.GetRankRange)
 		api.GET("/leaderboard", h.GetLeaderboardInfo)
+		api.GET("/leaderboards/:id/export", h.ExportLeaderboard)
+		api.DELETE("/leaderboards/:id", h.DeleteLeaderboard)
+		api.POST("/leaderboards/:id/reset", h.ResetLeaderboard)
+		api.GET("/audit/rejected", h.GetRejectedUpdates)
 	}
 }
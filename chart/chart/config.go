@@ -0,0 +1,63 @@
+package main
+
+import (
+	"chart/domain"
+	"config"
+	"time"
+)
+
+// appConfig 服务配置，从 RANK_CONFIG_PATH 指向的 YAML / JSON 文件加载，可被环境变量覆盖
+type appConfig struct {
+	Server      config.Server     `yaml:"server"`
+	Leaderboard leaderboardConfig `yaml:"leaderboard"`
+}
+
+// leaderboardConfig 默认排行榜的容量、批处理参数、排名与聚合方式、排序方向、分数历史及反作弊校验，为零值时使用排行榜内置的默认值
+type leaderboardConfig struct {
+	TopK           int           `yaml:"top_k" env:"RANK_TOPK"`
+	CacheTTL       time.Duration `yaml:"cache_ttl" env:"RANK_CACHE_TTL"`
+	BatchSize      int           `yaml:"batch_size" env:"RANK_BATCH_SIZE"`
+	BatchQueueSize int           `yaml:"batch_queue_size" env:"RANK_BATCH_QUEUE_SIZE"`
+	RankingMode    string        `yaml:"ranking_mode" env:"RANK_RANKING_MODE"` // ordinal（默认）或 competition
+	SortOrder      string        `yaml:"sort_order" env:"RANK_SORT_ORDER"`     // desc（默认）或 asc
+	Aggregation    string        `yaml:"aggregation" env:"RANK_AGGREGATION"`   // latest（默认）、max 或 sum
+	HistorySize    int           `yaml:"history_size" env:"RANK_HISTORY_SIZE"` // 每名玩家保留的分数历史条数，0 表示不记录
+
+	// 反作弊校验，MaxScore、MaxScoreDelta、MaxSubmissionsPerMinute 为 0 时不启用对应的校验器
+	MinScore                int64 `yaml:"min_score" env:"RANK_MIN_SCORE"`
+	MaxScore                int64 `yaml:"max_score" env:"RANK_MAX_SCORE"`
+	MaxScoreDelta           int64 `yaml:"max_score_delta" env:"RANK_MAX_SCORE_DELTA"`
+	MaxSubmissionsPerMinute int   `yaml:"max_submissions_per_minute" env:"RANK_MAX_SUBMISSIONS_PER_MINUTE"`
+}
+
+// validators 按配置创建分数更新校验器
+func (c leaderboardConfig) validators() []domain.ScoreValidator {
+	var validators []domain.ScoreValidator
+	if c.MaxScore != 0 {
+		validators = append(validators, domain.ScoreRangeValidator{Min: c.MinScore, Max: c.MaxScore})
+	}
+	if c.MaxScoreDelta != 0 {
+		validators = append(validators, domain.MaxDeltaValidator{MaxDelta: c.MaxScoreDelta})
+	}
+	if c.MaxSubmissionsPerMinute != 0 {
+		validators = append(validators, domain.NewRateLimitValidator(c.MaxSubmissionsPerMinute))
+	}
+	return validators
+}
+
+// loadConfig 加载服务配置
+func loadConfig() (*appConfig, error) {
+	cfg := &appConfig{
+		Server: config.Server{Port: 8080, ShutdownTimeout: 10 * time.Second},
+		Leaderboard: leaderboardConfig{
+			HistorySize:             30,
+			MaxScore:                1000000,
+			MaxScoreDelta:           100000,
+			MaxSubmissionsPerMinute: 120,
+		},
+	}
+	if err := config.Load(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
@@ -1,10 +1,12 @@
 // RankCache 前 N 名结果的轻量级缓存
 //
 // 设计要点：
-// - 以 limit 作为键缓存不同维度的 TopN 结果；
-// - 使用短 TTL（duration）权衡实时性与性能；
-// - 读写分离锁：Get 使用 RLock，Set/Invalidate 使用 Lock，避免数据竞争；
-// - Get 返回副本以避免外部修改导致共享数据不一致。
+//   - 以 limit 作为键缓存不同维度的 TopN 结果；
+//   - 使用短 TTL（duration）权衡实时性与性能；
+//   - 读写分离锁：Get 使用 RLock，Set/Invalidate 使用 Lock，避免数据竞争；
+//   - Get 返回副本以避免外部修改导致共享数据不一致；
+//   - 单个玩家的排名按玩家ID缓存（对应 rank-system 的 CacheKeyPlayerRank），条目记录写入时的排行榜版本，
+//     版本不一致或超过 TTL 即视为未命中，写入后 Invalidate 一并清空。
 package domain
 
 import (
@@ -12,20 +14,32 @@ import (
 	"time"
 )
 
+// maxCachedPlayerRanks 玩家排名缓存的最大条目数，写满后不再缓存新玩家，直到下次失效
+const maxCachedPlayerRanks = 10000
+
+// cachedRank 缓存的玩家排名
+type cachedRank struct {
+	rank     int
+	version  int64
+	cachedAt time.Time
+}
+
 // RankCache 排名缓存
 type RankCache struct {
-	mu        sync.RWMutex
-	topRanks  map[int][]*Player // limit -> players
-	cacheTime map[int]time.Time // limit -> cache time
-	duration  time.Duration
+	mu          sync.RWMutex
+	topRanks    map[int][]*Player    // limit -> players
+	cacheTime   map[int]time.Time    // limit -> cache time
+	playerRanks map[int64]cachedRank // player id -> rank
+	duration    time.Duration
 }
 
 // NewRankCache 创建排名缓存
 func NewRankCache(duration time.Duration) *RankCache {
 	return &RankCache{
-		topRanks:  make(map[int][]*Player),
-		cacheTime: make(map[int]time.Time),
-		duration:  duration,
+		topRanks:    make(map[int][]*Player),
+		cacheTime:   make(map[int]time.Time),
+		playerRanks: make(map[int64]cachedRank),
+		duration:    duration,
 	}
 }
 
@@ -61,4 +75,28 @@ func (c *RankCache) Invalidate() {
 
 	c.topRanks = make(map[int][]*Player)
 	c.cacheTime = make(map[int]time.Time)
+	clear(c.playerRanks)
+}
+
+// GetPlayerRank 获取缓存的玩家排名，version 为当前排行榜版本
+func (c *RankCache) GetPlayerRank(playerID int64, version int64) (int, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, exists := c.playerRanks[playerID]
+	if !exists || entry.version != version || time.Since(entry.cachedAt) >= c.duration {
+		return 0, false
+	}
+	return entry.rank, true
+}
+
+// SetPlayerRank 缓存玩家在 version 版本下的排名
+func (c *RankCache) SetPlayerRank(playerID int64, version int64, rank int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.playerRanks[playerID]; !exists && len(c.playerRanks) >= maxCachedPlayerRanks {
+		return
+	}
+	c.playerRanks[playerID] = cachedRank{rank: rank, version: version, cachedAt: time.Now()}
 }
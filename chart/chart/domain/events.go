@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"fmt"
+	"pubsub"
+)
+
+// RankEventType 排名事件类型
+type RankEventType string
+
+const (
+	RankEventScore RankEventType = "score" // 分数更新被采纳
+	RankEventTopK  RankEventType = "topk"  // 玩家进入或离开前K名
+)
+
+// RankEvent 排行榜发布的排名变化事件
+//
+// 主题格式为 leaderboard.{id}.score 与 leaderboard.{id}.topk，
+// 订阅 leaderboard.{id}.* 可接收单个排行榜的全部事件。
+type RankEvent struct {
+	Type          RankEventType `json:"type"`
+	LeaderboardID string        `json:"leaderboard_id"`
+	PlayerID      int64         `json:"player_id"`
+	Score         int64         `json:"score"`
+	OldScore      int64         `json:"old_score,omitempty"` // 仅 score 事件：更新前的分数，新玩家为 0
+	IsNew         bool          `json:"is_new,omitempty"`    // 仅 score 事件：是否为首次上榜
+	EnteredTopK   bool          `json:"entered_top_k"`       // 仅 topk 事件：true 为进入，false 为离开
+}
+
+// EventBus 排名事件总线
+type EventBus = pubsub.GenericPubSub[*RankEvent]
+
+// NewEventBus 创建排名事件总线
+func NewEventBus() *EventBus {
+	return pubsub.NewGenericPubSub[*RankEvent]()
+}
+
+// EventSubject 返回排行榜指定类型事件的发布主题
+func EventSubject(leaderboardID string, typ RankEventType) string {
+	return fmt.Sprintf("leaderboard.%s.%s", leaderboardID, typ)
+}
+
+// SetEventBus 设置排名事件总线，为 nil 时不发布事件
+// 应在排行榜开始接收更新前调用。
+func (lb *HybridLeaderboard) SetEventBus(bus *EventBus) {
+	lb.hookMu.Lock()
+	defer lb.hookMu.Unlock()
+	lb.events = bus
+}
+
+// recordEvent 记录待发布的事件，调用方需持有写锁
+func (lb *HybridLeaderboard) recordEvent(event *RankEvent) {
+	lb.hookMu.RLock()
+	enabled := lb.events != nil
+	lb.hookMu.RUnlock()
+	if !enabled {
+		return
+	}
+
+	event.LeaderboardID = lb.ID
+	lb.eventsMu.Lock()
+	lb.pendingEvents = append(lb.pendingEvents, event)
+	lb.eventsMu.Unlock()
+}
+
+// recordTopK 记录玩家进入或离开前K名的事件，调用方需持有写锁
+func (lb *HybridLeaderboard) recordTopK(player *Player, entered bool) {
+	lb.recordEvent(&RankEvent{
+		Type:        RankEventTopK,
+		PlayerID:    player.ID,
+		Score:       player.Score,
+		EnteredTopK: entered,
+	})
+}
+
+// publishPending 在释放写锁后发布积累的事件，避免订阅者回调中访问排行榜造成死锁
+// 只占用 eventsMu 与 hookMu，不与排名查询争用 mu。
+func (lb *HybridLeaderboard) publishPending() {
+	lb.eventsMu.Lock()
+	events := lb.pendingEvents
+	lb.pendingEvents = nil
+	lb.eventsMu.Unlock()
+	if len(events) == 0 {
+		return
+	}
+
+	lb.hookMu.RLock()
+	bus := lb.events
+	lb.hookMu.RUnlock()
+	if bus == nil {
+		return
+	}
+	for _, event := range events {
+		_ = bus.Publish(EventSubject(event.LeaderboardID, event.Type), event)
+	}
+}
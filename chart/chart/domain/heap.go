@@ -1,57 +1,117 @@
 // TopPlayersHeap 最小堆说明
 //
-// 该堆按玩家分数从小到大排序（Less 比较 Score），因此堆顶元素是当前前 K 集合中的“最低分”。
+// 该堆按与跳表相同的排序键（排行榜排序方向的 SortOrder.Compare）从后到前排序，因此堆顶元素是当前前 K 集合中的最后一名。
 // 在维护前 K 名时：
 // - 当集合未满，直接 Push；
-// - 当集合已满且新分数更高时，弹出堆顶（最低分）再插入新玩家；
-// 这样能在 O(log K) 时间维护 TopK 集合，并在读取时近似 O(1) 拿到前 K 个元素（连续内存切片）。
+// - 当集合已满且新玩家排在堆顶之前，弹出堆顶再插入新玩家；
+// - 前 K 名中的玩家分数变化时按下标 Fix，降到第 K 名之后时移出并由跳表第 K 名补位。
+// 堆同时维护玩家ID到下标的索引，Fix / Remove 为 O(log K)，不需要线性查找。
 //
 // Push/Pop 中包含容量扩缩逻辑：以倍增/减半方式调整底层切片容量，避免频繁分配。
 package domain
 
-// TopPlayersHeap 前K名最小堆
+import (
+	"container/heap"
+	"leaderboardcore"
+)
 
 const MIN_CAP = 32 // 最小容量
-type TopPlayersHeap []*Player
 
-func (h TopPlayersHeap) Len() int           { return len(h) }
-func (h TopPlayersHeap) Less(i, j int) bool { return h[i].Score < h[j].Score }
-func (h TopPlayersHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+// TopPlayersHeap 前K名最小堆
+type TopPlayersHeap struct {
+	players []*Player
+	index   map[int64]int // 玩家ID -> players 中的下标
+	order   leaderboardcore.SortOrder
+}
+
+// NewTopPlayersHeap 创建按指定排序方向维护的空前K名堆
+func NewTopPlayersHeap(order leaderboardcore.SortOrder) *TopPlayersHeap {
+	return &TopPlayersHeap{index: make(map[int64]int), order: order}
+}
+
+func (h *TopPlayersHeap) Len() int { return len(h.players) }
+func (h *TopPlayersHeap) Less(i, j int) bool {
+	return h.order.Compare(h.players[i], h.players[j]) < 0
+}
+func (h *TopPlayersHeap) Swap(i, j int) {
+	h.players[i], h.players[j] = h.players[j], h.players[i]
+	h.index[h.players[i].ID] = i
+	h.index[h.players[j].ID] = j
+}
 
 func (h *TopPlayersHeap) Push(x interface{}) {
-	n := len(*h)
+	n := len(h.players)
 
-	if n+1 > cap(*h) {
-		newCap := max(cap(*h)*2, MIN_CAP)
-		newH := make(TopPlayersHeap, n, newCap)
-		copy(newH, *h)
-		*h = newH
+	if n+1 > cap(h.players) {
+		newCap := max(cap(h.players)*2, MIN_CAP)
+		newPlayers := make([]*Player, n, newCap)
+		copy(newPlayers, h.players)
+		h.players = newPlayers
 	}
 
-	*h = (*h)[:n+1]
+	h.players = h.players[:n+1]
 	player := x.(*Player)
-	(*h)[n] = player
+	h.players[n] = player
+	h.index[player.ID] = n
 }
 
 func (h *TopPlayersHeap) Pop() interface{} {
-	n := len(*h)
+	n := len(h.players)
 	if n == 0 {
 		return nil
 	}
 
-	c := cap(*h)
+	c := cap(h.players)
 	if n < (c/2) && c > MIN_CAP {
 		newCap := c / 2
 		if newCap < n {
 			newCap = n
 		}
-		newH := make(TopPlayersHeap, n, newCap)
-		copy(newH, *h)
-		*h = newH
+		newPlayers := make([]*Player, n, newCap)
+		copy(newPlayers, h.players)
+		h.players = newPlayers
 	}
 
-	x := (*h)[n-1]
-	*h = (*h)[:n-1]
+	x := h.players[n-1]
+	h.players[n-1] = nil
+	h.players = h.players[:n-1]
+	delete(h.index, x.ID)
 
 	return x
 }
+
+// Contains 判断玩家是否在堆中 - O(1)
+func (h *TopPlayersHeap) Contains(playerID int64) bool {
+	_, ok := h.index[playerID]
+	return ok
+}
+
+// Min 返回堆顶（前K名中的最后一名），堆为空时返回 nil - O(1)
+func (h *TopPlayersHeap) Min() *Player {
+	if len(h.players) == 0 {
+		return nil
+	}
+	return h.players[0]
+}
+
+// Fix 在玩家排序键变化后恢复堆序 - O(log K)
+func (h *TopPlayersHeap) Fix(playerID int64) {
+	if i, ok := h.index[playerID]; ok {
+		heap.Fix(h, i)
+	}
+}
+
+// Remove 从堆中移除玩家，玩家不在堆中时返回 false - O(log K)
+func (h *TopPlayersHeap) Remove(playerID int64) bool {
+	i, ok := h.index[playerID]
+	if !ok {
+		return false
+	}
+	heap.Remove(h, i)
+	return true
+}
+
+// Players 返回堆中的玩家，顺序为堆的内部顺序
+func (h *TopPlayersHeap) Players() []*Player {
+	return h.players
+}
@@ -0,0 +1,144 @@
+// 玩家分数历史
+//
+// 每名玩家一个固定容量的环形缓冲区，按时间顺序保存最近的分数变化，写满后覆盖最旧的记录；
+// 缓冲区随记录增长，未写满的玩家不会预先占用整段容量。内存上限约为 玩家数 × 容量 × 32 字节。
+package domain
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrHistoryDisabled 排行榜没有开启分数历史
+var ErrHistoryDisabled = errors.New("score history disabled")
+
+// ScorePoint 玩家的一次分数变化
+type ScorePoint struct {
+	Score          int64     `json:"score"`
+	SecondaryScore int64     `json:"secondary_score,omitempty"`
+	Time           time.Time `json:"time"`
+}
+
+// historyRing 单个玩家的环形缓冲区
+type historyRing struct {
+	points []ScorePoint
+	next   int // 写满后下一个被覆盖的位置，即最旧的记录
+}
+
+// ScoreHistory 按玩家保存最近的分数变化
+type ScoreHistory struct {
+	mu       sync.Mutex
+	capacity int
+	players  map[int64]*historyRing
+}
+
+// NewScoreHistory 创建分数历史，capacity 为每名玩家保留的记录数
+func NewScoreHistory(capacity int) *ScoreHistory {
+	return &ScoreHistory{
+		capacity: capacity,
+		players:  make(map[int64]*historyRing),
+	}
+}
+
+// Record 追加一条分数变化
+func (h *ScoreHistory) Record(playerID int64, point ScorePoint) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.capacity <= 0 {
+		return
+	}
+	ring, ok := h.players[playerID]
+	if !ok {
+		ring = &historyRing{}
+		h.players[playerID] = ring
+	}
+	if len(ring.points) < h.capacity {
+		ring.points = append(ring.points, point)
+		return
+	}
+	ring.points[ring.next] = point
+	ring.next = (ring.next + 1) % len(ring.points)
+}
+
+// Get 返回玩家在 since 之后（不含）的最近 limit 条记录，按时间由旧到新排列；since 为零值时不限制
+func (h *ScoreHistory) Get(playerID int64, since time.Time, limit int) []ScorePoint {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ring, ok := h.players[playerID]
+	if !ok || limit <= 0 {
+		return []ScorePoint{}
+	}
+	n := len(ring.points)
+	// 从最新的记录向前收集，再整体反转
+	result := make([]ScorePoint, 0, min(limit, n))
+	for i := 1; i <= n && len(result) < limit; i++ {
+		p := ring.points[(ring.next-i+n)%n]
+		if !since.IsZero() && !p.Time.After(since) {
+			break
+		}
+		result = append(result, p)
+	}
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return result
+}
+
+// Has 判断是否保存了该玩家的记录
+func (h *ScoreHistory) Has(playerID int64) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	_, ok := h.players[playerID]
+	return ok
+}
+
+// Remove 删除玩家的全部记录
+func (h *ScoreHistory) Remove(playerID int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.players, playerID)
+}
+
+// Clear 删除所有玩家的记录
+func (h *ScoreHistory) Clear() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.players = make(map[int64]*historyRing)
+}
+
+// SetScoreHistory 设置分数历史，为 nil 时不记录；已应用的每次分数变化都会写入
+func (lb *HybridLeaderboard) SetScoreHistory(history *ScoreHistory) {
+	lb.hookMu.Lock()
+	defer lb.hookMu.Unlock()
+	lb.history = history
+}
+
+// scoreHistory 返回当前的分数历史，可能为 nil
+func (lb *HybridLeaderboard) scoreHistory() *ScoreHistory {
+	lb.hookMu.RLock()
+	defer lb.hookMu.RUnlock()
+	return lb.history
+}
+
+// GetScoreHistory 获取玩家在 since 之后的最近 limit 条分数变化，按时间由旧到新排列
+// 玩家已不在榜上且没有记录时返回 ErrPlayerNotFound。
+func (lb *HybridLeaderboard) GetScoreHistory(playerID int64, since time.Time, limit int) ([]ScorePoint, error) {
+	history := lb.scoreHistory()
+	if history == nil {
+		return nil, ErrHistoryDisabled
+	}
+
+	lb.mu.RLock()
+	_, exists := lb.playerMap[playerID]
+	lb.mu.RUnlock()
+	if !exists && !history.Has(playerID) {
+		return nil, ErrPlayerNotFound
+	}
+	return history.Get(playerID, since, limit), nil
+}
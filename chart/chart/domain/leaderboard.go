@@ -2,8 +2,12 @@ package domain
 
 import (
 	"container/heap"
+	"crontab"
 	"errors"
+	"leaderboardcore"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,14 +17,155 @@ type RankConfig struct {
 	RewardRatio  float64 `json:"reward_ratio"`  // 奖励比例
 	MinReward    int     `json:"min_reward"`    // 最小奖励
 	MaxReward    int     `json:"max_reward"`    // 最大奖励
+
+	UpdatePolicy UpdatePolicy `json:"update_policy,omitempty"` // 分数更新策略，默认总是覆盖
+	Aggregation  Aggregation  `json:"aggregation,omitempty"`   // 多次提交的分数聚合方式，默认以最近一次为准
+	RankingMode  RankingMode  `json:"ranking_mode,omitempty"`  // 排名方式，默认顺序排名
+
+	SortOrder leaderboardcore.SortOrder `json:"sort_order,omitempty"` // 主分数的排序方向，默认 desc（分数高者在前）；asc 用于用时等越小越好的指标
+
+	OverflowPolicy    OverflowPolicy `json:"overflow_policy,omitempty"`     // 批量通道已满时的处理策略，默认回退为同步更新
+	OverflowTimeoutMs int            `json:"overflow_timeout_ms,omitempty"` // block / adaptive 策略的最长等待时间，默认 100ms
+
+	EntryTTLDays int `json:"entry_ttl_days,omitempty"` // 玩家超过该天数未更新分数即被后台清理，0 表示不过期
+
+	ShardBoundaries []int64 `json:"shard_boundaries,omitempty"` // 跳表按分数区间分片的边界，为空时使用单个跳表；写入仍在 mu 下串行，分片不提升写入吞吐
+
+	TopK           int `json:"top_k,omitempty"`            // 前K名堆的容量，默认 1000
+	CacheTTLMs     int `json:"cache_ttl_ms,omitempty"`     // 排名缓存有效期，默认 2000ms
+	BatchSize      int `json:"batch_size,omitempty"`       // 批处理的常规批次大小，默认 100
+	BatchQueueSize int `json:"batch_queue_size,omitempty"` // 批量通道容量，默认 10000
+}
+
+// OverflowPolicy 批量通道已满时的处理策略
+type OverflowPolicy string
+
+const (
+	OverflowSync     OverflowPolicy = "sync"     // 回退为同步更新（默认）
+	OverflowBlock    OverflowPolicy = "block"    // 阻塞等待通道空位，超时返回 ErrQueueFull
+	OverflowDrop     OverflowPolicy = "drop"     // 立即丢弃并返回 ErrQueueFull
+	OverflowAdaptive OverflowPolicy = "adaptive" // 同 block，且批处理协程按积压深度放大批次以尽快排空
+)
+
+// Valid 判断策略是否合法，空值表示默认的 sync
+func (p OverflowPolicy) Valid() bool {
+	switch p {
+	case "", OverflowSync, OverflowBlock, OverflowDrop, OverflowAdaptive:
+		return true
+	default:
+		return false
+	}
+}
+
+const (
+	defaultTopK            = 1000                   // 前K名堆的默认容量
+	defaultCacheTTL        = 2 * time.Second        // 排名缓存的默认有效期
+	batchQueueSize         = 10000                  // 批量通道默认容量
+	batchSize              = 100                    // 默认的常规批次大小
+	maxAdaptiveBatchSize   = 2000                   // adaptive 策略下的最大批次
+	exportChunkSize        = 1000                   // Export 每次持锁取出的玩家数
+	defaultOverflowTimeout = 100 * time.Millisecond // block / adaptive 策略的默认等待时间
+)
+
+// QueueStats 批量通道的运行指标，用于观察写入是否饱和
+type QueueStats struct {
+	Depth         int   `json:"depth"`          // 当前积压的更新数
+	Capacity      int   `json:"capacity"`       // 通道容量
+	Dropped       int64 `json:"dropped"`        // 因通道已满被拒绝的更新数
+	Blocked       int64 `json:"blocked"`        // 因通道已满而阻塞等待的次数
+	SyncFallbacks int64 `json:"sync_fallbacks"` // 因通道已满回退为同步更新的次数
+}
+
+// UpdatePolicy 分数更新策略
+type UpdatePolicy string
+
+const (
+	UpdatePolicyAlways     UpdatePolicy = "always"      // 总是以新分数覆盖
+	UpdatePolicyOnlyHigher UpdatePolicy = "only_higher" // 仅当新成绩优于当前成绩时更新（最佳成绩语义，升序排行榜中分数更低者更优）
+)
+
+// Valid 判断策略是否合法，空值表示沿用排行榜配置
+func (p UpdatePolicy) Valid() bool {
+	switch p {
+	case "", UpdatePolicyAlways, UpdatePolicyOnlyHigher:
+		return true
+	default:
+		return false
+	}
+}
+
+// Aggregation 同一玩家多次提交时的分数聚合方式
+type Aggregation string
+
+const (
+	AggregationLatest Aggregation = "latest" // 以最近一次提交为准（默认）
+	AggregationMax    Aggregation = "max"    // 保留最好成绩，不优于当前成绩的提交被忽略（按排序方向比较）
+	AggregationSum    Aggregation = "sum"    // 累加每次提交的分数，如总积分；次级分数取最近一次提交
+)
+
+// Valid 判断聚合方式是否合法，空值表示默认的 latest
+func (a Aggregation) Valid() bool {
+	switch a {
+	case "", AggregationLatest, AggregationMax, AggregationSum:
+		return true
+	default:
+		return false
+	}
+}
+
+// RankingMode 排名方式，决定同分玩家的排名
+type RankingMode string
+
+const (
+	RankingOrdinal     RankingMode = "ordinal"     // 顺序排名：同分玩家按排序规则依次排名，如 1、2、3
+	RankingCompetition RankingMode = "competition" // 竞赛排名：主分数与次级分数都相同的玩家并列，如 1、1、3
+)
+
+// Valid 判断排名方式是否合法，空值表示默认的 ordinal
+func (m RankingMode) Valid() bool {
+	switch m {
+	case "", RankingOrdinal, RankingCompetition:
+		return true
+	default:
+		return false
+	}
 }
 
 type ScoreUpdate struct {
-	PlayerID int64 `json:"player_id" binding:"required"` // 玩家ID
-	Score    int64 `json:"score" binding:"required"`     // 玩家分数
+	PlayerID       int64 `json:"player_id" binding:"required"` // 玩家ID
+	Score          int64 `json:"score" binding:"required"`     // 玩家分数
+	SecondaryScore int64 `json:"secondary_score"`              // 次级分数，主分数相同时较高者排前；越小越好的指标（如耗时）请取负值
+	// Timestamp 客户端（游戏服）产生该更新的时间，可选；不晚于玩家上一次被采纳更新的时间戳时忽略，
+	// 避免重试或延迟到达的消息把分数回滚
+	Timestamp time.Time `json:"timestamp"`
+
+	epoch   int64         // 入队时排行榜所处的纪元，重置后旧纪元的更新会被丢弃
+	policy  UpdatePolicy  // 入队时生效的更新策略
+	flushed chan struct{} // 非 nil 时为 Flush 标记，批处理协程处理完此前的更新后关闭它
 }
 
+var (
+	// ErrLeaderboardClosed 排行榜已关闭，不再接受更新
+	ErrLeaderboardClosed = errors.New("leaderboard closed")
+	// ErrQueueFull 批量通道已满，更新未被接受
+	ErrQueueFull = errors.New("batch queue full")
+	// ErrPlayerNotFound 玩家不在排行榜中
+	ErrPlayerNotFound = errors.New("player not found")
+)
+
 // HybridLeaderboard 混合策略排行榜（跳表 + 分段）
+//
+// 锁层级（只能自上而下获取，持有下层锁时不得再获取上层锁）：
+//  1. closeMu：生命周期，保护 closed、batchUpdates 的关闭与 sweeper；
+//  2. mu：排名数据，保护 playerMap、stamps、skipList、topHeap、topChanges 以及玩家实体的字段，写入持写锁、查询持读锁；
+//  3. topMu：前K名的有序视图 topSorted，查询在持有 mu 读锁时获取并按需重建；
+//  4. hookMu：运行期可替换的扩展点（校验器、审计日志、事件总线、分数历史），在 mu 内按需读取；
+//  5. eventsMu：待发布的事件队列，发布事件时不再占用 mu；
+//  6. 组件内部的锁：RankCache.mu、ScoreHistory.mu、SkipList.mu（分片跳表按分片下标升序）。
+//
+// version、epoch、玩家数与通道指标为原子变量，读取无需加锁。
+// 后缀为 Locked 的内部方法不加锁，由调用方持有 mu；公开方法各自加锁，持有 mu 时不得相互调用，
+// 否则读锁重入在有写者等待时会死锁。
 type HybridLeaderboard struct {
 	mu     sync.RWMutex
 	ID     string
@@ -28,16 +173,46 @@ type HybridLeaderboard struct {
 	Config *RankConfig
 
 	// 核心数据结构
-	skipList  *SkipList         // 跳表 - 用于精确排名计算
-	topK      int               // 维护前K名 没有降分淘汰，其topk的容量比返回大给定兼容即可
-	topHeap   *TopPlayersHeap   // 前K名最小堆 - 用于快速获取前N名
-	playerMap map[int64]*Player // 所有玩家数据 - O(1)查找
-	topMap    map[int64]*Player // 前K名玩家快速查找
+	skipList   RankIndex           // 跳表（或分片跳表）- 用于精确排名计算
+	topK       int                 // 前K名堆的容量
+	topHeap    *TopPlayersHeap     // 前K名最小堆，始终与跳表的前 min(K, 玩家数) 名一致
+	topChanges int64               // 前K名集合或其中玩家排序键的变化次数，受 mu 保护
+	playerMap  map[int64]*Player   // 所有玩家数据 - O(1)查找
+	stamps     map[int64]time.Time // 玩家最近一次被采纳的带时间戳更新的客户端时间，只记录发送过时间戳的玩家
+
+	// 前K名有序视图，受 topMu 保护；topBuilt 与 topChanges 不一致时在查询时重建
+	topMu     sync.Mutex
+	topSorted []*Player
+	topBuilt  int64
 
 	// 性能优化
 	batchUpdates chan *ScoreUpdate // 批量更新通道
 	cache        *RankCache        // 排名缓存
-	version      int64             // 版本控制
+	version      int64             // 版本号，每次写入后递增（原子读写）
+	playerCount  int64             // 玩家数（原子读写），与 playerMap 的大小一致
+	epoch        int64             // 重置纪元，每次 Reset 递增（原子读写）
+
+	// 通道指标（原子读写）
+	dropped       int64
+	blocked       int64
+	syncFallbacks int64
+
+	// 扩展点，受 hookMu 保护
+	hookMu     sync.RWMutex
+	events     *EventBus        // 排名事件总线，可为 nil
+	validators []ScoreValidator // 更新应用前执行的校验器
+	audit      AuditLog         // 被拒绝更新的审计日志，可为 nil
+	history    *ScoreHistory    // 玩家分数历史，可为 nil
+
+	// 待发布事件，受 eventsMu 保护
+	eventsMu      sync.Mutex
+	pendingEvents []*RankEvent // 写锁内积累、释放锁后发布的事件
+
+	// 生命周期
+	closeMu sync.RWMutex // 保护 closed 与 batchUpdates 的关闭，避免向已关闭通道发送
+	closed  bool
+	done    chan struct{}  // 批处理协程退出时关闭
+	sweeper *crontab.Timer // 过期清理定时器，受 closeMu 保护
 }
 
 // NewHybridLeaderboard 创建混合策略排行榜
@@ -46,16 +221,17 @@ func NewHybridLeaderboard(id, name string, config *RankConfig) *HybridLeaderboar
 		ID:           id,
 		Name:         name,
 		Config:       config,
-		skipList:     NewSkipList(),
-		topK:         1000,
-		topHeap:      &TopPlayersHeap{},
+		skipList:     newRankIndex(config),
+		topK:         config.topK(),
+		topHeap:      NewTopPlayersHeap(config.sortOrder()),
 		playerMap:    make(map[int64]*Player),
-		topMap:       make(map[int64]*Player),
-		batchUpdates: make(chan *ScoreUpdate, 10000),
-		cache:        NewRankCache(2 * time.Second),
+		stamps:       make(map[int64]time.Time),
+		topBuilt:     -1,
+		batchUpdates: make(chan *ScoreUpdate, config.batchQueueSize()),
+		cache:        NewRankCache(config.cacheTTL()),
+		done:         make(chan struct{}),
 	}
 
-	heap.Init(lb.topHeap)
 	go lb.processBatchUpdates()
 
 	return lb
@@ -63,22 +239,221 @@ func NewHybridLeaderboard(id, name string, config *RankConfig) *HybridLeaderboar
 
 // UpdateScore 更新玩家分数 - O(log n)
 func (lb *HybridLeaderboard) UpdateScore(playerID, score int64) error {
-	update := &ScoreUpdate{
+	return lb.enqueue(&ScoreUpdate{
 		PlayerID: playerID,
 		Score:    score,
+		policy:   lb.updatePolicy(),
+	})
+}
+
+// enqueue 将更新写入批量通道，通道满时按配置的溢出策略处理
+func (lb *HybridLeaderboard) enqueue(update *ScoreUpdate) error {
+	update.epoch = atomic.LoadInt64(&lb.epoch)
+
+	lb.closeMu.RLock()
+	defer lb.closeMu.RUnlock()
+	if lb.closed {
+		return ErrLeaderboardClosed
 	}
 
 	select {
 	case lb.batchUpdates <- update:
 		return nil
 	default:
-		return lb.syncUpdateScore(playerID, score)
 	}
+
+	switch lb.overflowPolicy() {
+	case OverflowDrop:
+		atomic.AddInt64(&lb.dropped, 1)
+		return ErrQueueFull
+	case OverflowBlock, OverflowAdaptive:
+		atomic.AddInt64(&lb.blocked, 1)
+		timer := time.NewTimer(lb.overflowTimeout())
+		defer timer.Stop()
+		select {
+		case lb.batchUpdates <- update:
+			return nil
+		case <-timer.C:
+			atomic.AddInt64(&lb.dropped, 1)
+			return ErrQueueFull
+		}
+	default:
+		atomic.AddInt64(&lb.syncFallbacks, 1)
+		return lb.syncApply(update)
+	}
+}
+
+// overflowPolicy 返回排行榜配置的溢出策略
+func (lb *HybridLeaderboard) overflowPolicy() OverflowPolicy {
+	if lb.Config == nil || lb.Config.OverflowPolicy == "" {
+		return OverflowSync
+	}
+	return lb.Config.OverflowPolicy
+}
+
+// overflowTimeout 返回 block / adaptive 策略的最长等待时间
+func (lb *HybridLeaderboard) overflowTimeout() time.Duration {
+	if lb.Config == nil || lb.Config.OverflowTimeoutMs <= 0 {
+		return defaultOverflowTimeout
+	}
+	return time.Duration(lb.Config.OverflowTimeoutMs) * time.Millisecond
+}
+
+// sortOrder 返回主分数的排序方向，未配置时为分数高者在前
+func (c *RankConfig) sortOrder() leaderboardcore.SortOrder {
+	if c == nil || c.SortOrder == "" {
+		return leaderboardcore.Descending
+	}
+	return c.SortOrder
+}
+
+// topK 返回前K名堆的容量，未配置时使用默认值
+func (c *RankConfig) topK() int {
+	if c == nil || c.TopK <= 0 {
+		return defaultTopK
+	}
+	return c.TopK
+}
+
+// cacheTTL 返回排名缓存的有效期，未配置时使用默认值
+func (c *RankConfig) cacheTTL() time.Duration {
+	if c == nil || c.CacheTTLMs <= 0 {
+		return defaultCacheTTL
+	}
+	return time.Duration(c.CacheTTLMs) * time.Millisecond
+}
+
+// batchSize 返回批处理的常规批次大小，未配置时使用默认值
+func (c *RankConfig) batchSize() int {
+	if c == nil || c.BatchSize <= 0 {
+		return batchSize
+	}
+	return c.BatchSize
+}
+
+// batchQueueSize 返回批量通道容量，未配置时使用默认值
+func (c *RankConfig) batchQueueSize() int {
+	if c == nil || c.BatchQueueSize <= 0 {
+		return batchQueueSize
+	}
+	return c.BatchQueueSize
+}
+
+// QueueStats 返回批量通道的当前指标
+func (lb *HybridLeaderboard) QueueStats() QueueStats {
+	return QueueStats{
+		Depth:         len(lb.batchUpdates),
+		Capacity:      cap(lb.batchUpdates),
+		Dropped:       atomic.LoadInt64(&lb.dropped),
+		Blocked:       atomic.LoadInt64(&lb.blocked),
+		SyncFallbacks: atomic.LoadInt64(&lb.syncFallbacks),
+	}
+}
+
+// batchLimit 返回当前的批次大小，adaptive 策略下随积压深度放大
+func (lb *HybridLeaderboard) batchLimit() int {
+	size := lb.Config.batchSize()
+	if lb.overflowPolicy() != OverflowAdaptive {
+		return size
+	}
+	return min(max(len(lb.batchUpdates), size), max(maxAdaptiveBatchSize, size))
+}
+
+// UpdateScoreWithPolicy 按指定策略更新玩家分数，返回更新是否被采纳
+// policy 为空时沿用排行榜配置的策略。
+func (lb *HybridLeaderboard) UpdateScoreWithPolicy(playerID, score int64, policy UpdatePolicy) (bool, error) {
+	return lb.UpdateScoreWithSecondary(playerID, score, 0, policy)
+}
+
+// UpdateScoreWithSecondary 按指定策略更新玩家的主分数与次级分数，返回更新是否被采纳
+// policy 为空时沿用排行榜配置的策略；only_higher 按（主分数，次级分数）整体比较。
+// 总是覆盖的策略走批量通道，入队即视为采纳；条件更新、max 聚合与设置了校验器时需要与当前分数比较或执行校验，
+// 走同步路径以便返回结果。
+func (lb *HybridLeaderboard) UpdateScoreWithSecondary(playerID, score, secondary int64, policy UpdatePolicy) (bool, error) {
+	return lb.UpdateScoreAt(playerID, score, secondary, time.Time{}, policy)
+}
+
+// UpdateScoreAt 同 UpdateScoreWithSecondary，at 为客户端产生该更新的时间，零值表示不带时间戳
+// 带时间戳的更新不晚于玩家上一次被采纳更新的时间戳时被忽略（重复投递同一更新也会被忽略），
+// 需要与当前状态比较，同样走同步路径以便返回结果。
+func (lb *HybridLeaderboard) UpdateScoreAt(playerID, score, secondary int64, at time.Time, policy UpdatePolicy) (bool, error) {
+	if policy == "" {
+		policy = lb.updatePolicy()
+	}
+	update := &ScoreUpdate{
+		PlayerID:       playerID,
+		Score:          score,
+		SecondaryScore: secondary,
+		Timestamp:      at,
+		policy:         policy,
+	}
+	if policy == UpdatePolicyAlways && lb.aggregation() != AggregationMax && at.IsZero() && !lb.hasValidators() {
+		if err := lb.enqueue(update); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	lb.closeMu.RLock()
+	defer lb.closeMu.RUnlock()
+	if lb.closed {
+		return false, ErrLeaderboardClosed
+	}
+
+	defer lb.publishPending()
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	if !lb.applySingleUpdate(update) {
+		return false, nil
+	}
+	lb.markChangedLocked()
+	return true, nil
+}
+
+// UpdateScores 将一批更新按顺序写入批量通道，返回成功入队的条数 - 每条 O(1) 入队，由批处理协程合并应用
+// policy 为空时沿用排行榜配置的策略；条件更新与校验同样在批处理中执行，但不返回逐条的采纳结果，入队不代表被采纳。
+// 通道已满且溢出策略拒绝时停止入队并返回错误，此前入队的更新仍会被应用。
+func (lb *HybridLeaderboard) UpdateScores(updates []*ScoreUpdate, policy UpdatePolicy) (int, error) {
+	if policy == "" {
+		policy = lb.updatePolicy()
+	}
+	for i, u := range updates {
+		err := lb.enqueue(&ScoreUpdate{
+			PlayerID:       u.PlayerID,
+			Score:          u.Score,
+			SecondaryScore: u.SecondaryScore,
+			Timestamp:      u.Timestamp,
+			policy:         policy,
+		})
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(updates), nil
+}
+
+// aggregation 返回排行榜配置的聚合方式
+func (lb *HybridLeaderboard) aggregation() Aggregation {
+	if lb.Config == nil || lb.Config.Aggregation == "" {
+		return AggregationLatest
+	}
+	return lb.Config.Aggregation
+}
+
+// updatePolicy 返回排行榜配置的更新策略
+func (lb *HybridLeaderboard) updatePolicy() UpdatePolicy {
+	if lb.Config == nil || lb.Config.UpdatePolicy == "" {
+		return UpdatePolicyAlways
+	}
+	return lb.Config.UpdatePolicy
 }
 
 // processBatchUpdates 处理批量更新
 func (lb *HybridLeaderboard) processBatchUpdates() {
-	batch := make([]*ScoreUpdate, 0, 100)
+	defer close(lb.done)
+
+	batch := make([]*ScoreUpdate, 0, lb.Config.batchSize())
 	ticker := time.NewTicker(50 * time.Millisecond) // 更快的批处理
 	defer ticker.Stop()
 
@@ -92,8 +467,16 @@ func (lb *HybridLeaderboard) processBatchUpdates() {
 				}
 				return
 			}
+			if update.flushed != nil {
+				if len(batch) > 0 {
+					lb.processBatch(batch)
+					batch = batch[:0]
+				}
+				close(update.flushed)
+				continue
+			}
 			batch = append(batch, update)
-			if len(batch) >= 100 {
+			if len(batch) >= lb.batchLimit() {
 				lb.processBatch(batch)
 				batch = batch[:0]
 			}
@@ -106,102 +489,428 @@ func (lb *HybridLeaderboard) processBatchUpdates() {
 	}
 }
 
+// Flush 阻塞直到调用前已入队的更新全部应用到排行榜
+// 排行榜已关闭时等待批处理协程处理完剩余更新后返回。
+func (lb *HybridLeaderboard) Flush() {
+	flushed := make(chan struct{})
+
+	lb.closeMu.RLock()
+	if lb.closed {
+		lb.closeMu.RUnlock()
+		<-lb.done
+		return
+	}
+	// 标记与普通更新走同一通道，保证排在此前入队的更新之后
+	lb.batchUpdates <- &ScoreUpdate{flushed: flushed}
+	lb.closeMu.RUnlock()
+
+	<-flushed
+}
+
 // Close 关闭排行榜 - 释放资源
+// 阻塞直到批处理协程应用完剩余更新并退出，后续 UpdateScore 返回 ErrLeaderboardClosed；重复调用是安全的。
 func (lb *HybridLeaderboard) Close() {
-	close(lb.batchUpdates)
+	lb.closeMu.Lock()
+	if !lb.closed {
+		lb.closed = true
+		close(lb.batchUpdates)
+		if lb.sweeper != nil {
+			lb.sweeper.Cancel()
+		}
+	}
+	lb.closeMu.Unlock()
+
+	// 在锁外等待，避免事件订阅者在批处理中回调排行榜时死锁
+	<-lb.done
+}
+
+// Reset 清空排行榜的所有玩家数据 - O(1)
+// 在写锁内一次性替换跳表、玩家索引、前K名结构并清空缓存与分数历史；
+// 重置前已入队但尚未处理的更新属于旧纪元，会被批处理协程丢弃，批处理协程本身保持运行。
+func (lb *HybridLeaderboard) Reset() {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	atomic.AddInt64(&lb.epoch, 1)
+	lb.skipList = newRankIndex(lb.Config)
+	lb.topHeap = NewTopPlayersHeap(lb.Config.sortOrder())
+	lb.topChanges++
+	lb.playerMap = make(map[int64]*Player)
+	lb.stamps = make(map[int64]time.Time)
+	atomic.StoreInt64(&lb.playerCount, 0)
+	if history := lb.scoreHistory(); history != nil {
+		history.Clear()
+	}
+	lb.markChangedLocked()
 }
 
 // processBatch 批量处理更新
 func (lb *HybridLeaderboard) processBatch(updates []*ScoreUpdate) {
+	defer lb.publishPending()
 	lb.mu.Lock()
 	defer lb.mu.Unlock()
 
+	epoch := atomic.LoadInt64(&lb.epoch)
 	for _, update := range updates {
-		lb.applySingleUpdate(update.PlayerID, update.Score)
+		if update.epoch != epoch {
+			continue
+		}
+		lb.applySingleUpdate(update)
 	}
 
-	lb.version++
-	lb.cache.Invalidate()
+	lb.markChangedLocked()
 }
 
-// applySingleUpdate 应用单个更新
-func (lb *HybridLeaderboard) applySingleUpdate(playerID, score int64) {
-	player, exists := lb.playerMap[playerID]
+// applySingleUpdate 应用单个更新，返回更新是否被采纳
+// 过期的带时间戳更新直接忽略；提交的分数先按排行榜的聚合方式换算为新的总分数，再经过校验器，被拒绝时不采纳。
+func (lb *HybridLeaderboard) applySingleUpdate(update *ScoreUpdate) bool {
+	if lb.staleLocked(update) {
+		return false
+	}
+	player, exists := lb.playerMap[update.PlayerID]
+	update = lb.aggregateLocked(player, update)
+	playerID, score := update.PlayerID, update.Score
+	if !lb.validateUpdate(player, update) {
+		return false
+	}
+	if exists && (update.policy == UpdatePolicyOnlyHigher || lb.aggregation() == AggregationMax) && !lb.improves(player, update) {
+		return false
+	}
 
 	if !exists {
 		// 新玩家
 		player = NewPlayer(playerID, score)
+		player.SecondaryScore = update.SecondaryScore
 		lb.playerMap[playerID] = player
+		atomic.AddInt64(&lb.playerCount, 1)
 		lb.skipList.Insert(player)
-
-		// 检查是否应该进入前K名
-		if lb.shouldPromoteToTop(score) {
-			lb.promoteToTop(player)
-		}
+		lb.recordEvent(&RankEvent{Type: RankEventScore, PlayerID: playerID, Score: score, IsNew: true})
 	} else {
 		// 更新现有玩家
-		//oldScore := player.Score
-		lb.skipList.UpdateScore(player, score)
+		oldScore := player.Score
+		lb.skipList.UpdateScore(player, score, update.SecondaryScore)
+		lb.recordEvent(&RankEvent{Type: RankEventScore, PlayerID: playerID, Score: score, OldScore: oldScore})
+	}
+	lb.updateTopLocked(player)
+	if !update.Timestamp.IsZero() {
+		lb.stamps[playerID] = update.Timestamp
+	}
+	if history := lb.scoreHistory(); history != nil {
+		history.Record(playerID, ScorePoint{Score: score, SecondaryScore: player.SecondaryScore, Time: player.UpdateTime})
+	}
+	return true
+}
 
-		// 更新前K名逻辑
-		if _, inTop := lb.topMap[playerID]; inTop {
-			lb.adjustTopPlayer(player)
-		} else if lb.shouldPromoteToTop(score) {
+// staleLocked 判断带时间戳的更新是否不晚于玩家上一次被采纳的时间戳，不带时间戳的更新总是有效
+func (lb *HybridLeaderboard) staleLocked(update *ScoreUpdate) bool {
+	if update.Timestamp.IsZero() {
+		return false
+	}
+	last, ok := lb.stamps[update.PlayerID]
+	return ok && !update.Timestamp.After(last)
+}
+
+// aggregateLocked 按聚合方式返回实际写入的更新：sum 返回分数累加后的副本，其余方式原样返回
+func (lb *HybridLeaderboard) aggregateLocked(player *Player, update *ScoreUpdate) *ScoreUpdate {
+	if player == nil || lb.aggregation() != AggregationSum {
+		return update
+	}
+	total := *update
+	total.Score = player.Score + update.Score
+	return &total
+}
+
+// improves 判断更新是否优于玩家当前成绩：按排序方向比较主分数，相同时次级分数更高
+func (lb *HybridLeaderboard) improves(player *Player, update *ScoreUpdate) bool {
+	if update.Score != player.Score {
+		return lb.Config.sortOrder().Better(update.Score, player.Score)
+	}
+	return update.SecondaryScore > player.SecondaryScore
+}
+
+// updateTopLocked 在玩家写入跳表后维护前K名堆，调用方需持有写锁 - O(log K)，降出前K名时 O(log n)
+func (lb *HybridLeaderboard) updateTopLocked(player *Player) {
+	if !lb.topHeap.Contains(player.ID) {
+		if lb.shouldPromoteToTop(player) {
 			lb.promoteToTop(player)
 		}
+		return
+	}
+
+	lb.topHeap.Fix(player.ID)
+	lb.topChanges++
+
+	// 降分后只可能成为堆顶；此时与跳表核对排名，落到第K名之后则移出并由新的第K名补位
+	if lb.topHeap.Min() != player || len(lb.playerMap) <= lb.topK {
+		return
+	}
+	if rank, found := lb.skipList.GetRankByPlayer(player); found && rank > lb.topK {
+		lb.topHeap.Remove(player.ID)
+		lb.recordTopK(player, false)
+		lb.backfillTopLocked()
 	}
 }
 
-// shouldPromoteToTop 判断是否应该进入前K名
-func (lb *HybridLeaderboard) shouldPromoteToTop(score int64) bool {
+// shouldPromoteToTop 判断不在堆中的玩家是否应该进入前K名
+func (lb *HybridLeaderboard) shouldPromoteToTop(player *Player) bool {
 	if lb.topHeap.Len() < lb.topK {
 		return true
 	}
-	return score > (*lb.topHeap)[0].Score
+	return lb.Config.sortOrder().Compare(player, lb.topHeap.Min()) > 0
 }
 
-// promoteToTop 提升玩家到前K名
+// promoteToTop 提升玩家到前K名，堆已满时淘汰堆顶
 func (lb *HybridLeaderboard) promoteToTop(player *Player) {
 	if lb.topHeap.Len() >= lb.topK {
-		// 移除最低分玩家
 		removed := heap.Pop(lb.topHeap).(*Player)
-		delete(lb.topMap, removed.ID)
+		lb.recordTopK(removed, false)
 	}
 
 	heap.Push(lb.topHeap, player)
-	lb.topMap[player.ID] = player
+	lb.topChanges++
+	lb.recordTopK(player, true)
+}
+
+// backfillTopLocked 前K名出现空位且榜上还有其他玩家时，从跳表按排名补位，调用方需持有写锁
+// 堆中恰好是跳表的前 Len 名，因此第 Len+1 名就是堆外排名最高的玩家。
+func (lb *HybridLeaderboard) backfillTopLocked() {
+	for lb.topHeap.Len() < lb.topK && lb.topHeap.Len() < len(lb.playerMap) {
+		next := lb.skipList.GetRange(lb.topHeap.Len()+1, lb.topHeap.Len()+1)
+		if len(next) == 0 {
+			return
+		}
+		heap.Push(lb.topHeap, next[0])
+		lb.recordTopK(next[0], true)
+	}
+	lb.topChanges++
+}
+
+// RemovePlayer 移除玩家 - O(log n + K)
+// 从跳表、玩家索引与前K名结构中删除玩家，并使缓存失效。
+func (lb *HybridLeaderboard) RemovePlayer(playerID int64) error {
+	defer lb.publishPending()
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	player, exists := lb.playerMap[playerID]
+	if !exists {
+		return ErrPlayerNotFound
+	}
+	lb.removeLocked(player)
+
+	lb.markChangedLocked()
+	return nil
+}
+
+// removeLocked 从跳表、玩家索引与前K名结构中移除玩家，调用方需持有写锁
+func (lb *HybridLeaderboard) removeLocked(player *Player) {
+	lb.skipList.Delete(player)
+	delete(lb.playerMap, player.ID)
+	delete(lb.stamps, player.ID)
+	atomic.AddInt64(&lb.playerCount, -1)
+	if history := lb.scoreHistory(); history != nil {
+		history.Remove(player.ID)
+	}
+
+	if lb.topHeap.Remove(player.ID) {
+		lb.recordTopK(player, false)
+		lb.backfillTopLocked()
+	}
 }
 
-// adjustTopPlayer 调整前K名玩家位置
-func (lb *HybridLeaderboard) adjustTopPlayer(player *Player) {
+// EvictInactive 移除最近一次更新早于 cutoff 的玩家，返回移除数量 - O(n)
+func (lb *HybridLeaderboard) EvictInactive(cutoff time.Time) int {
+	defer lb.publishPending()
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
 
-	for index, p := range *lb.topHeap {
-		if player.ID == p.ID {
-			heap.Fix(lb.topHeap, index)
+	evicted := 0
+	for _, player := range lb.playerMap {
+		if player.UpdateTime.Before(cutoff) {
+			lb.removeLocked(player)
+			evicted++
 		}
 	}
+	if evicted > 0 {
+		lb.markChangedLocked()
+	}
+	return evicted
 }
 
-// GetPlayerRank 获取玩家排名 - O(log n)
+// StartExpirySweep 在调度器上注册周期性的过期清理，每隔 interval 移除超过 EntryTTLDays 未更新的玩家
+// 未配置 EntryTTLDays 或排行榜已关闭时不做任何事；Close 会取消清理。
+func (lb *HybridLeaderboard) StartExpirySweep(scheduler *crontab.Scheduler, interval time.Duration) {
+	if lb.Config == nil || lb.Config.EntryTTLDays <= 0 {
+		return
+	}
+	ttl := time.Duration(lb.Config.EntryTTLDays) * 24 * time.Hour
+
+	lb.closeMu.Lock()
+	defer lb.closeMu.Unlock()
+	if lb.closed {
+		return
+	}
+	if lb.sweeper != nil {
+		lb.sweeper.Cancel()
+	}
+	lb.sweeper = scheduler.AddTimer(interval, func() {
+		lb.EvictInactive(time.Now().Add(-ttl))
+	})
+}
+
+// GetPlayerRank 获取玩家排名 - O(log n)，命中缓存时 O(1)
+// 排名按当前版本短时缓存，排行榜有写入后即失效，热门玩家的重复查询无需访问跳表。
 func (lb *HybridLeaderboard) GetPlayerRank(playerID int64) (int, error) {
+	if rank, ok := lb.cache.GetPlayerRank(playerID, atomic.LoadInt64(&lb.version)); ok {
+		return rank, nil
+	}
+
 	lb.mu.RLock()
 	defer lb.mu.RUnlock()
 
+	rank, err := lb.playerRankLocked(playerID)
+	if err == nil {
+		// 在读锁内写入：写者提升版本并使缓存失效时持有写锁，不会写入过期排名
+		lb.cache.SetPlayerRank(playerID, atomic.LoadInt64(&lb.version), rank)
+	}
+	return rank, err
+}
+
+// playerRankLocked 按排行榜的排名方式获取玩家排名，调用方需持有 mu（读锁或写锁）
+func (lb *HybridLeaderboard) playerRankLocked(playerID int64) (int, error) {
+	position, err := lb.playerPositionLocked(playerID)
+	if err != nil || !lb.competitionRanking() {
+		return position, err
+	}
+	player := lb.playerMap[playerID]
+	return lb.skipList.GetCompetitionRank(player.Score, player.SecondaryScore), nil
+}
+
+// playerPositionLocked 获取玩家在跳表中的位置（顺序排名），调用方需持有 mu（读锁或写锁）
+func (lb *HybridLeaderboard) playerPositionLocked(playerID int64) (int, error) {
 	player, exists := lb.playerMap[playerID]
 	if !exists {
-		return 0, errors.New("player not found")
+		return 0, ErrPlayerNotFound
 	}
 
 	// 使用跳表基于排序键获取精确排名
 	rank, found := lb.skipList.GetRankByPlayer(player)
 	if !found {
-		return 0, errors.New("player not found")
+		return 0, ErrPlayerNotFound
 	}
 
 	return rank, nil
 }
 
-// GetTopRanks 获取前N名 - O(1) 从堆中获取
+// competitionRanking 判断排行榜是否使用竞赛排名
+func (lb *HybridLeaderboard) competitionRanking() bool {
+	return lb.Config != nil && lb.Config.RankingMode == RankingCompetition
+}
+
+// sameRankKey 判断两名玩家在竞赛排名下是否并列
+func sameRankKey(a, b *Player) bool {
+	return a.Score == b.Score && a.SecondaryScore == b.SecondaryScore
+}
+
+// GetRankByScore 返回以给定分数提交时将会获得的排名及当前玩家总数 - O(log n)
+// 用于“再得多少分可进入前 N 名”之类的提示；同分时排在已有玩家之后，
+// 竞赛排名下则与主分数相同、次级分数为 0 的玩家并列。
+func (lb *HybridLeaderboard) GetRankByScore(score int64) (int, int) {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	return lb.scoreRankLocked(score), len(lb.playerMap)
+}
+
+// scoreRankLocked 按排行榜的排名方式返回以给定分数提交时的排名，调用方需持有 mu
+func (lb *HybridLeaderboard) scoreRankLocked(score int64) int {
+	if lb.competitionRanking() {
+		return lb.skipList.GetCompetitionRank(score, 0)
+	}
+	return lb.skipList.GetRankByScore(score)
+}
+
+// GetAroundScore 获取分数附近的玩家 - O(log n + k)
+// 以该分数新提交时的假设性排名为界，返回之前最多 count 名（分数不差于 score）
+// 与之后最多 count 名（分数差于 score）的玩家，按排名排序，并返回该假设性排名。
+// 调用方无需在榜上，可用于按分数匹配对手。
+func (lb *HybridLeaderboard) GetAroundScore(score int64, count int) ([]*Player, int) {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	rank := lb.scoreRankLocked(score)
+	if count <= 0 {
+		return []*Player{}, rank
+	}
+	position := lb.skipList.GetRankByScore(score)
+	return lb.rangeLocked(position-count, position+count-1), rank
+}
+
+// GetPlayerScore 获取玩家当前分数，不计算排名 - O(1)
+// 返回玩家副本及读取时排行榜的版本号，可用于判断两次读取之间排行榜是否有更新。
+func (lb *HybridLeaderboard) GetPlayerScore(playerID int64) (*Player, int64, error) {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	player, exists := lb.playerMap[playerID]
+	if !exists {
+		return nil, 0, ErrPlayerNotFound
+	}
+
+	cp := *player
+	return &cp, atomic.LoadInt64(&lb.version), nil
+}
+
+// GetPlayerRanks 批量获取玩家排名 - O(k log n)
+// 在一次读锁内完成查询，返回带排名的玩家副本（按排名排序）以及不在榜上的玩家ID。
+func (lb *HybridLeaderboard) GetPlayerRanks(playerIDs []int64) ([]*Player, []int64) {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	found := make([]*Player, 0, len(playerIDs))
+	notFound := make([]int64, 0)
+	seen := make(map[int64]struct{}, len(playerIDs))
+	for _, id := range playerIDs {
+		if _, dup := seen[id]; dup {
+			continue
+		}
+		seen[id] = struct{}{}
+
+		if player, exists := lb.playerMap[id]; exists {
+			found = append(found, player)
+		} else {
+			notFound = append(notFound, id)
+		}
+	}
+
+	// 按排序键从高到低排列，使跳表只需单向遍历一次
+	order := lb.Config.sortOrder()
+	sort.Slice(found, func(i, j int) bool { return order.Compare(found[i], found[j]) > 0 })
+	ranks := lb.skipList.GetRanksByPlayers(found)
+
+	competition := lb.competitionRanking()
+	ranked := make([]*Player, 0, len(found))
+	for i, p := range found {
+		if ranks[i] == 0 {
+			notFound = append(notFound, p.ID)
+			continue
+		}
+		rank := ranks[i]
+		if competition {
+			rank = lb.skipList.GetCompetitionRank(p.Score, p.SecondaryScore)
+		}
+		ranked = append(ranked, &Player{
+			ID:             p.ID,
+			Score:          p.Score,
+			SecondaryScore: p.SecondaryScore,
+			Rank:           rank,
+			UpdateTime:     p.UpdateTime,
+		})
+	}
+	return ranked, notFound
+}
+
+// GetTopRanks 获取前N名 - N 不超过 K 时从前K名的有序视图中取出，视图未变化时为 O(N)
 func (lb *HybridLeaderboard) GetTopRanks(limit int) []*Player {
 	// 尝试从缓存获取
 	if cached := lb.cache.GetTopRanks(limit); cached != nil {
@@ -212,71 +921,214 @@ func (lb *HybridLeaderboard) GetTopRanks(limit int) []*Player {
 }
 
 // refreshTopRanks 刷新前N名缓存
+// 在读锁内写入缓存：写者使缓存失效时持有写锁，因此不会写入已过期的结果。
 func (lb *HybridLeaderboard) refreshTopRanks(limit int) []*Player {
-    lb.mu.RLock()
-    defer lb.mu.RUnlock()
-
-    // 直接使用跳表获取前 N 名，保证顺序正确
-    if limit > lb.skipList.Length() {
-        limit = lb.skipList.Length()
-    }
-    original := lb.skipList.GetRange(1, limit)
-    // 返回副本并填充 Rank，避免修改共享实体导致竞态
-    ranked := make([]*Player, len(original))
-    for i, p := range original {
-        ranked[i] = &Player{
-            ID:         p.ID,
-            Score:      p.Score,
-            Rank:       i + 1,
-            UpdateTime: p.UpdateTime,
-        }
-    }
-
-    lb.cache.SetTopRanks(limit, ranked)
-    return ranked
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	var ranked []*Player
+	if limit <= lb.topK {
+		ranked = lb.topRanksLocked(limit)
+	} else {
+		ranked = lb.rangeLocked(1, limit)
+	}
+	lb.cache.SetTopRanks(limit, ranked)
+	return ranked
+}
+
+// topRanksLocked 从前K名堆取前 limit 名的副本，调用方需持有 mu（读锁或写锁）
+// 有序视图在前K名变化后的首次查询时重建 - O(K log K)，之后直接截取
+func (lb *HybridLeaderboard) topRanksLocked(limit int) []*Player {
+	lb.topMu.Lock()
+	defer lb.topMu.Unlock()
+
+	if lb.topBuilt != lb.topChanges {
+		sorted := make([]*Player, lb.topHeap.Len())
+		for i, p := range lb.topHeap.Players() {
+			sorted[i] = &Player{
+				ID:             p.ID,
+				Score:          p.Score,
+				SecondaryScore: p.SecondaryScore,
+				UpdateTime:     p.UpdateTime,
+			}
+		}
+		order := lb.Config.sortOrder()
+		sort.Slice(sorted, func(i, j int) bool { return order.Compare(sorted[i], sorted[j]) > 0 })
+		competition := lb.competitionRanking()
+		for i, p := range sorted {
+			p.Rank = i + 1
+			if competition && i > 0 && sameRankKey(p, sorted[i-1]) {
+				p.Rank = sorted[i-1].Rank
+			}
+		}
+		lb.topSorted = sorted
+		lb.topBuilt = lb.topChanges
+	}
+
+	n := min(max(limit, 0), len(lb.topSorted))
+	ranked := make([]*Player, n)
+	for i := 0; i < n; i++ {
+		cp := *lb.topSorted[i]
+		ranked[i] = &cp
+	}
+	return ranked
 }
 
 // GetNearbyRanks 获取临近排名 - O(log n + k)
 func (lb *HybridLeaderboard) GetNearbyRanks(playerID int64, rangeSize int) ([]*Player, error) {
-    lb.mu.RLock()
-    defer lb.mu.RUnlock()
-
-    rank, err := lb.GetPlayerRank(playerID)
-    if err != nil {
-        return nil, err
-    }
-
-    start := max(1, rank-rangeSize)
-    end := min(lb.skipList.Length(), rank+rangeSize)
-    original := lb.skipList.GetRange(start, end)
-    // 返回副本并填充 Rank，避免修改共享实体导致竞态
-    ranked := make([]*Player, len(original))
-    for i, p := range original {
-        ranked[i] = &Player{
-            ID:         p.ID,
-            Score:      p.Score,
-            Rank:       start + i,
-            UpdateTime: p.UpdateTime,
-        }
-    }
-    return ranked, nil
-}
-
-// GetPlayerCount 获取玩家数量 - O(1)
-func (lb *HybridLeaderboard) GetPlayerCount() int {
 	lb.mu.RLock()
 	defer lb.mu.RUnlock()
-	return len(lb.playerMap)
+
+	position, err := lb.playerPositionLocked(playerID)
+	if err != nil {
+		return nil, err
+	}
+	return lb.rangeLocked(position-rangeSize, position+rangeSize), nil
+}
+
+// GetRange 获取排名区间 [start, end] 内的玩家及玩家总数 - O(log n + k)
+func (lb *HybridLeaderboard) GetRange(start, end int) ([]*Player, int) {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	return lb.rangeLocked(start, end), len(lb.playerMap)
+}
+
+// GetRangeReverse 从榜尾倒数获取区间 [start, end] 内的玩家及玩家总数 - O(log n + k)
+// start=1 表示最后一名；返回按从榜尾到榜首的顺序排列，Rank 仍为正序排名。
+func (lb *HybridLeaderboard) GetRangeReverse(start, end int) ([]*Player, int) {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	return lb.reverseRangeLocked(start, end), len(lb.playerMap)
+}
+
+// GetBottomRanks 获取最后N名，从最后一名开始排列 - O(log n + k)
+func (lb *HybridLeaderboard) GetBottomRanks(limit int) []*Player {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	return lb.reverseRangeLocked(1, limit)
+}
+
+// reverseRangeLocked 将倒数区间换算为正序排名区间后取出并反转，调用方需持有 mu
+func (lb *HybridLeaderboard) reverseRangeLocked(start, end int) []*Player {
+	start = max(1, start)
+	if end < start {
+		return []*Player{}
+	}
+	total := len(lb.playerMap)
+	ranked := lb.rangeLocked(total-end+1, total-start+1)
+	for i, j := 0, len(ranked)-1; i < j; i, j = i+1, j-1 {
+		ranked[i], ranked[j] = ranked[j], ranked[i]
+	}
+	return ranked
+}
+
+// rangeLocked 返回位置区间 [start, end] 内玩家的副本并填充 Rank，调用方需持有 mu
+// 返回副本以避免调用方修改共享实体导致竞态。竞赛排名下区间第一名的排名单独查询，
+// 之后与前一名并列的玩家沿用其排名，否则排名等于位置。
+func (lb *HybridLeaderboard) rangeLocked(start, end int) []*Player {
+	start = max(1, start)
+	original := lb.skipList.GetRange(start, end)
+	competition := lb.competitionRanking()
+	ranked := make([]*Player, len(original))
+	for i, p := range original {
+		rank := start + i
+		if competition {
+			if i == 0 {
+				rank = lb.skipList.GetCompetitionRank(p.Score, p.SecondaryScore)
+			} else if sameRankKey(p, original[i-1]) {
+				rank = ranked[i-1].Rank
+			}
+		}
+		ranked[i] = &Player{
+			ID:             p.ID,
+			Score:          p.Score,
+			SecondaryScore: p.SecondaryScore,
+			Rank:           rank,
+			UpdateTime:     p.UpdateTime,
+		}
+	}
+	return ranked
+}
+
+// Export 按排名顺序将整个榜单逐个交给 fn，fn 返回错误时停止并返回该错误
+// 每次在读锁内从跳表取出 exportChunkSize 个玩家的副本，释放锁后再调用 fn，
+// 慢速的消费方（如 HTTP 客户端）不会长时间阻塞写入。下一批从上一批最后一名的排序键之后继续，
+// 因此导出期间的并发更新不会造成重复或遗漏，但导出结果不是某一时刻的快照，排名为取出时的排名。
+// 竞赛排名下与前一名并列的玩家沿用其排名。
+func (lb *HybridLeaderboard) Export(fn func(player *Player) error) error {
+	competition := lb.competitionRanking()
+	chunk := make([]*Player, 0, exportChunkSize)
+	var after *Player
+	for {
+		chunk = chunk[:0]
+		lb.mu.RLock()
+		lb.skipList.Iterate(after, func(rank int, p *Player) bool {
+			prev := after
+			if len(chunk) > 0 {
+				prev = chunk[len(chunk)-1]
+			}
+			if competition && prev != nil && sameRankKey(p, prev) {
+				rank = prev.Rank
+			}
+			chunk = append(chunk, &Player{
+				ID:             p.ID,
+				Score:          p.Score,
+				SecondaryScore: p.SecondaryScore,
+				Rank:           rank,
+				UpdateTime:     p.UpdateTime,
+			})
+			return len(chunk) < exportChunkSize
+		})
+		lb.mu.RUnlock()
+
+		for _, p := range chunk {
+			if err := fn(p); err != nil {
+				return err
+			}
+		}
+		if len(chunk) < exportChunkSize {
+			return nil
+		}
+		after = chunk[len(chunk)-1]
+	}
+}
+
+// Version 返回排行榜的版本号，每次写入后递增，可用于判断排行榜是否有更新 - O(1)，无锁读取
+func (lb *HybridLeaderboard) Version() int64 {
+	return atomic.LoadInt64(&lb.version)
+}
+
+// GetPlayerCount 获取玩家数量 - O(1)，无锁读取
+func (lb *HybridLeaderboard) GetPlayerCount() int {
+	return int(atomic.LoadInt64(&lb.playerCount))
+}
+
+// markChangedLocked 提升版本号并使缓存失效，调用方需持有写锁
+func (lb *HybridLeaderboard) markChangedLocked() {
+	atomic.AddInt64(&lb.version, 1)
+	lb.cache.Invalidate()
 }
 
 // syncUpdateScore 同步更新分数
 func (lb *HybridLeaderboard) syncUpdateScore(playerID, score int64) error {
+	return lb.syncApply(&ScoreUpdate{
+		PlayerID: playerID,
+		Score:    score,
+		policy:   lb.updatePolicy(),
+	})
+}
+
+// syncApply 同步应用单个更新
+func (lb *HybridLeaderboard) syncApply(update *ScoreUpdate) error {
+	defer lb.publishPending()
 	lb.mu.Lock()
 	defer lb.mu.Unlock()
 
-	lb.applySingleUpdate(playerID, score)
-	lb.version++
-	lb.cache.Invalidate()
+	lb.applySingleUpdate(update)
+	lb.markChangedLocked()
 
 	return nil
 }
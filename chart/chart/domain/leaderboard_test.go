@@ -1,7 +1,11 @@
 package domain
 
 import (
+    "crontab"
+    "errors"
+    "leaderboardcore"
     "sync"
+    "sync/atomic"
     "testing"
     "time"
 )
@@ -221,7 +225,8 @@ func TestLeaderboardConcurrentInsert100k(t *testing.T) {
             defer wg.Done()
             for id := range jobs {
                 if err := lb.UpdateScore(id, id); err != nil {
-                    t.Fatalf("UpdateScore(%d) error: %v", id, err)
+                    t.Errorf("UpdateScore(%d) error: %v", id, err)
+                    return
                 }
             }
         }()
@@ -234,17 +239,8 @@ func TestLeaderboardConcurrentInsert100k(t *testing.T) {
     close(jobs)
     wg.Wait()
 
-    // 关闭以 flush 批处理并退出后台协程
+    // Close 阻塞到批处理协程应用完剩余更新并退出
     lb.Close()
-    // 等待后台批处理协程完全退出并处理完缓冲数据
-    // Close 仅关闭通道，不阻塞等待消费者结束，这里做短轮询以确保处理完成
-    deadline := time.Now().Add(5 * time.Second)
-    for time.Now().Before(deadline) {
-        if lb.GetPlayerCount() == N {
-            break
-        }
-        time.Sleep(10 * time.Millisecond)
-    }
 
     // 断言：数量与关键排名
     if lb.GetPlayerCount() != N {
@@ -269,3 +265,1118 @@ func TestLeaderboardConcurrentInsert100k(t *testing.T) {
         t.Fatalf("TopRanks should contain highest 5 ids, got=%v", ids)
     }
 }
+
+// 重置后排行榜应为空，且重置前已入队的更新不会回填到新纪元
+func TestLeaderboardReset(t *testing.T) {
+	lb := setupLeaderboardBasic()
+	defer lb.Close()
+
+	_ = lb.GetTopRanks(3) // 填充缓存
+	if err := lb.UpdateScore(100, 1000); err != nil {
+		t.Fatalf("UpdateScore error: %v", err)
+	}
+	lb.Reset()
+
+	lb.Flush() // 等待批处理协程消费旧纪元的更新
+	if n := lb.GetPlayerCount(); n != 0 {
+		t.Fatalf("player count after reset: got=%d want=0", n)
+	}
+	if top := lb.GetTopRanks(3); len(top) != 0 {
+		t.Fatalf("TopRanks after reset should be empty, got=%v", idsOf(top))
+	}
+
+	// 重置后排行榜仍可正常写入
+	if err := lb.syncUpdateScore(7, 70); err != nil {
+		t.Fatalf("syncUpdateScore error: %v", err)
+	}
+	if r, err := lb.GetPlayerRank(7); err != nil || r != 1 {
+		t.Fatalf("rank after reset mismatch: got=%d err=%v want=1", r, err)
+	}
+}
+
+// 关闭后的排行榜拒绝更新，重复关闭不会 panic
+func TestLeaderboardCloseRejectsUpdates(t *testing.T) {
+	lb := setupLeaderboardBasic()
+	lb.Close()
+	lb.Close()
+
+	if err := lb.UpdateScore(1, 1); err != ErrLeaderboardClosed {
+		t.Fatalf("UpdateScore after Close: got=%v want=%v", err, ErrLeaderboardClosed)
+	}
+}
+
+// 移除玩家后排名前移，TopN 不再包含该玩家
+func TestLeaderboardRemovePlayer(t *testing.T) {
+	lb := setupLeaderboardBasic()
+	defer lb.Close()
+
+	_ = lb.GetTopRanks(3) // 填充缓存
+	if err := lb.RemovePlayer(2); err != nil {
+		t.Fatalf("RemovePlayer error: %v", err)
+	}
+
+	if _, err := lb.GetPlayerRank(2); err != ErrPlayerNotFound {
+		t.Fatalf("removed player should not be found, got err=%v", err)
+	}
+	if r, err := lb.GetPlayerRank(4); err != nil || r != 1 {
+		t.Fatalf("rank of player 4 mismatch: got=%d err=%v want=1", r, err)
+	}
+	if n := lb.GetPlayerCount(); n != 4 {
+		t.Fatalf("player count mismatch: got=%d want=4", n)
+	}
+	if ids := idsOf(lb.GetTopRanks(3)); containsAll(ids, []int64{2}) {
+		t.Fatalf("TopRanks should not contain removed player, got=%v", ids)
+	}
+	if err := lb.RemovePlayer(2); err != ErrPlayerNotFound {
+		t.Fatalf("second RemovePlayer: got=%v want=%v", err, ErrPlayerNotFound)
+	}
+}
+
+// 乱序更新已有玩家后，跳表排名仍与分数一致
+func TestLeaderboardUpdateExistingPlayersRandomOrder(t *testing.T) {
+	lb := NewHybridLeaderboard("shuffle", "乱序榜", &RankConfig{TotalPlayers: 2000})
+	defer lb.Close()
+
+	const N = 2000
+	for i := int64(1); i <= N; i++ {
+		_ = lb.syncUpdateScore(i, i)
+	}
+	// 以非单调顺序更新，使被更新的节点分布在跳表各处
+	for i := int64(0); i < N; i++ {
+		id := (i*7919)%N + 1
+		_ = lb.syncUpdateScore(id, 10*N-id)
+	}
+
+	for id := int64(1); id <= N; id++ {
+		if r, err := lb.GetPlayerRank(id); err != nil || r != int(id) {
+			t.Fatalf("rank of player %d mismatch: got=%d err=%v want=%d", id, r, err, id)
+		}
+	}
+}
+
+// 仅当更高时更新：低分提交被忽略，高分提交生效
+func TestLeaderboardUpdatePolicyOnlyHigher(t *testing.T) {
+	lb := NewHybridLeaderboard("best", "最佳成绩榜", &RankConfig{UpdatePolicy: UpdatePolicyOnlyHigher})
+	defer lb.Close()
+
+	cases := []struct {
+		score   int64
+		applied bool
+		want    int64
+	}{
+		{50, true, 50},  // 新玩家总是写入
+		{30, false, 50}, // 低分被忽略
+		{50, false, 50}, // 同分不算提高
+		{80, true, 80},  // 高分生效
+	}
+
+	for i, c := range cases {
+		applied, err := lb.UpdateScoreWithPolicy(1, c.score, "")
+		if err != nil {
+			t.Fatalf("case %d: UpdateScoreWithPolicy error: %v", i, err)
+		}
+		if applied != c.applied {
+			t.Fatalf("case %d: applied mismatch: got=%v want=%v", i, applied, c.applied)
+		}
+		top := lb.GetTopRanks(1)
+		if len(top) != 1 || top[0].Score != c.want {
+			t.Fatalf("case %d: score mismatch: got=%v want=%d", i, top, c.want)
+		}
+	}
+}
+
+// 单次请求的策略覆盖排行榜默认策略
+func TestLeaderboardUpdatePolicyPerRequest(t *testing.T) {
+	lb := setupLeaderboardBasic()
+	defer lb.Close()
+
+	// 玩家 2 当前 50 分，按最佳成绩语义提交 40 分应被忽略
+	applied, err := lb.UpdateScoreWithPolicy(2, 40, UpdatePolicyOnlyHigher)
+	if err != nil || applied {
+		t.Fatalf("lower score should be ignored: applied=%v err=%v", applied, err)
+	}
+	if r, _ := lb.GetPlayerRank(2); r != 1 {
+		t.Fatalf("rank of player 2 mismatch: got=%d want=1", r)
+	}
+}
+
+func TestLeaderboardGetRange(t *testing.T) {
+	lb := NewHybridLeaderboard("range", "range", &RankConfig{})
+	defer lb.Close()
+
+	const N = 50
+	for i := 1; i <= N; i++ {
+		if err := lb.syncUpdateScore(int64(i), int64(i*10)); err != nil {
+			t.Fatalf("update failed: %v", err)
+		}
+	}
+
+	players, total := lb.GetRange(11, 20)
+	if total != N {
+		t.Fatalf("expected total %d, got %d", N, total)
+	}
+	if len(players) != 10 {
+		t.Fatalf("expected 10 players, got %d", len(players))
+	}
+	for i, p := range players {
+		wantRank := 11 + i
+		wantID := int64(N + 1 - wantRank)
+		if p.Rank != wantRank || p.ID != wantID {
+			t.Fatalf("index %d: expected rank %d id %d, got rank %d id %d", i, wantRank, wantID, p.Rank, p.ID)
+		}
+	}
+
+	// 超出榜单长度的区间被截断
+	players, _ = lb.GetRange(45, 100)
+	if len(players) != 6 {
+		t.Fatalf("expected 6 players in truncated range, got %d", len(players))
+	}
+}
+
+func TestLeaderboardSecondaryScoreTieBreak(t *testing.T) {
+	lb := NewHybridLeaderboard("tie", "tie", &RankConfig{})
+	defer lb.Close()
+
+	// 主分数相同，次级分数取通关耗时的负值：耗时越短排名越高
+	for _, c := range []struct {
+		id       int64
+		duration int64
+	}{{1, 300}, {2, 120}, {3, 200}} {
+		if _, err := lb.UpdateScoreWithSecondary(c.id, 100, -c.duration, UpdatePolicyOnlyHigher); err != nil {
+			t.Fatalf("update %d failed: %v", c.id, err)
+		}
+	}
+
+	for id, want := range map[int64]int{2: 1, 3: 2, 1: 3} {
+		rank, err := lb.GetPlayerRank(id)
+		if err != nil {
+			t.Fatalf("GetPlayerRank(%d) error: %v", id, err)
+		}
+		if rank != want {
+			t.Fatalf("player %d: expected rank %d, got %d", id, want, rank)
+		}
+	}
+
+	// only_higher 按（主分数，次级分数）比较：同分但耗时更长不会覆盖
+	applied, err := lb.UpdateScoreWithSecondary(2, 100, -150, UpdatePolicyOnlyHigher)
+	if err != nil || applied {
+		t.Fatalf("expected slower run to be rejected, applied=%v err=%v", applied, err)
+	}
+	applied, err = lb.UpdateScoreWithSecondary(1, 100, -100, UpdatePolicyOnlyHigher)
+	if err != nil || !applied {
+		t.Fatalf("expected faster run to be applied, applied=%v err=%v", applied, err)
+	}
+	if rank, _ := lb.GetPlayerRank(1); rank != 1 {
+		t.Fatalf("expected player 1 to move to rank 1, got %d", rank)
+	}
+}
+
+func TestLeaderboardPublishesRankEvents(t *testing.T) {
+	lb := NewHybridLeaderboard("events", "events", &RankConfig{})
+	defer lb.Close()
+	lb.topK = 2
+
+	bus := NewEventBus()
+	lb.SetEventBus(bus)
+
+	var mu sync.Mutex
+	var scores, topk []*RankEvent
+	if err := bus.Subscribe("test", "leaderboard.events.*", func(subject string, e *RankEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch subject {
+		case EventSubject("events", RankEventScore):
+			scores = append(scores, e)
+		case EventSubject("events", RankEventTopK):
+			topk = append(topk, e)
+		}
+		// 回调中访问排行榜不应死锁
+		_ = lb.GetPlayerCount()
+	}); err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	_ = lb.syncUpdateScore(1, 10)
+	_ = lb.syncUpdateScore(2, 20)
+	_ = lb.syncUpdateScore(3, 30) // 挤出玩家1
+	_ = lb.syncUpdateScore(2, 25)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(scores) != 4 {
+		t.Fatalf("expected 4 score events, got %d", len(scores))
+	}
+	if !scores[0].IsNew || scores[3].IsNew || scores[3].OldScore != 20 || scores[3].Score != 25 {
+		t.Fatalf("unexpected score events: %+v %+v", scores[0], scores[3])
+	}
+
+	// 1、2 进入；1 离开、3 进入
+	want := []struct {
+		id      int64
+		entered bool
+	}{{1, true}, {2, true}, {1, false}, {3, true}}
+	if len(topk) != len(want) {
+		t.Fatalf("expected %d topk events, got %d", len(want), len(topk))
+	}
+	for i, w := range want {
+		if topk[i].PlayerID != w.id || topk[i].EnteredTopK != w.entered {
+			t.Fatalf("topk event %d: expected id=%d entered=%v, got %+v", i, w.id, w.entered, topk[i])
+		}
+	}
+}
+
+func TestLeaderboardGetPlayerRanks(t *testing.T) {
+	lb := NewHybridLeaderboard("batch", "batch", &RankConfig{})
+	defer lb.Close()
+
+	const N = 500
+	for i := 1; i <= N; i++ {
+		_ = lb.syncUpdateScore(int64(i), int64(i%37))
+	}
+
+	ids := []int64{N + 1, 7, 250, 1, 7, 499, 123}
+	players, notFound := lb.GetPlayerRanks(ids)
+	if len(notFound) != 1 || notFound[0] != N+1 {
+		t.Fatalf("expected not_found [%d], got %v", N+1, notFound)
+	}
+	if len(players) != 5 {
+		t.Fatalf("expected 5 ranked players, got %d", len(players))
+	}
+	for i, p := range players {
+		want, err := lb.GetPlayerRank(p.ID)
+		if err != nil {
+			t.Fatalf("GetPlayerRank(%d) error: %v", p.ID, err)
+		}
+		if p.Rank != want {
+			t.Fatalf("player %d: batch rank %d, single rank %d", p.ID, p.Rank, want)
+		}
+		if i > 0 && players[i-1].Rank >= p.Rank {
+			t.Fatalf("players not ordered by rank: %d then %d", players[i-1].Rank, p.Rank)
+		}
+	}
+
+	// 全量批量查询与逐个查询结果一致
+	all := make([]int64, 0, N)
+	for i := N; i >= 1; i -= 3 {
+		all = append(all, int64(i))
+	}
+	players, _ = lb.GetPlayerRanks(all)
+	for _, p := range players {
+		if want, _ := lb.GetPlayerRank(p.ID); p.Rank != want {
+			t.Fatalf("player %d: batch rank %d, single rank %d", p.ID, p.Rank, want)
+		}
+	}
+}
+
+// Flush 返回时此前入队的更新均已生效；Close 返回时剩余更新均已生效
+func TestLeaderboardFlushAndClose(t *testing.T) {
+	lb := NewHybridLeaderboard("flush", "flush", &RankConfig{})
+
+	for i := int64(1); i <= 50; i++ {
+		if err := lb.UpdateScore(i, i); err != nil {
+			t.Fatalf("UpdateScore(%d) error: %v", i, err)
+		}
+	}
+	lb.Flush()
+	if n := lb.GetPlayerCount(); n != 50 {
+		t.Fatalf("player count after Flush: got=%d want=50", n)
+	}
+
+	for i := int64(51); i <= 80; i++ {
+		if err := lb.UpdateScore(i, i); err != nil {
+			t.Fatalf("UpdateScore(%d) error: %v", i, err)
+		}
+	}
+	lb.Close()
+	if n := lb.GetPlayerCount(); n != 80 {
+		t.Fatalf("player count after Close: got=%d want=80", n)
+	}
+
+	// 关闭后 Flush 与 Close 均立即返回
+	lb.Flush()
+	lb.Close()
+}
+
+// 通道已满时按溢出策略处理，并计入通道指标
+func TestLeaderboardOverflowPolicy(t *testing.T) {
+	for _, policy := range []OverflowPolicy{OverflowSync, OverflowDrop, OverflowBlock} {
+		lb := NewHybridLeaderboard("overflow", "overflow", &RankConfig{OverflowPolicy: policy, OverflowTimeoutMs: 10})
+		// 占住写锁，使批处理协程阻塞，从而填满通道
+		lb.mu.Lock()
+		var rejected int
+		for i := int64(1); i <= batchQueueSize+batchSize+10; i++ {
+			if policy == OverflowSync && len(lb.batchUpdates) == cap(lb.batchUpdates) {
+				break // 同步回退需要写锁，这里不再继续
+			}
+			if err := lb.UpdateScore(i, i); err == ErrQueueFull {
+				rejected++
+			} else if err != nil {
+				t.Fatalf("%s: UpdateScore error: %v", policy, err)
+			}
+		}
+		stats := lb.QueueStats()
+		lb.mu.Unlock()
+
+		if stats.Capacity != batchQueueSize {
+			t.Fatalf("%s: capacity got=%d want=%d", policy, stats.Capacity, batchQueueSize)
+		}
+		switch policy {
+		case OverflowSync:
+			if rejected != 0 || stats.Dropped != 0 {
+				t.Fatalf("sync: unexpected rejections %d/%d", rejected, stats.Dropped)
+			}
+		case OverflowDrop, OverflowBlock:
+			if rejected == 0 || int64(rejected) != stats.Dropped {
+				t.Fatalf("%s: rejected=%d dropped=%d", policy, rejected, stats.Dropped)
+			}
+			if policy == OverflowBlock && stats.Blocked < stats.Dropped {
+				t.Fatalf("block: blocked=%d dropped=%d", stats.Blocked, stats.Dropped)
+			}
+		}
+		lb.Close()
+	}
+}
+
+func TestLeaderboardUpdateScores(t *testing.T) {
+	lb := NewHybridLeaderboard("batch", "batch", &RankConfig{})
+	defer lb.Close()
+
+	queued, err := lb.UpdateScores([]*ScoreUpdate{
+		{PlayerID: 1, Score: 50},
+		{PlayerID: 2, Score: 80},
+		{PlayerID: 1, Score: 90}, // 同一批次内按顺序应用，后者覆盖前者
+	}, "")
+	if err != nil || queued != 3 {
+		t.Fatalf("UpdateScores: queued=%d err=%v", queued, err)
+	}
+	lb.Flush()
+	if r, _ := lb.GetPlayerRank(1); r != 1 {
+		t.Fatalf("rank of player 1 mismatch: got=%d want=1", r)
+	}
+
+	// 条件更新在批处理中同样与当前分数比较
+	if _, err := lb.UpdateScores([]*ScoreUpdate{{PlayerID: 1, Score: 10}, {PlayerID: 2, Score: 100}}, UpdatePolicyOnlyHigher); err != nil {
+		t.Fatalf("UpdateScores error: %v", err)
+	}
+	lb.Flush()
+	top := lb.GetTopRanks(2)
+	if len(top) != 2 || top[0].ID != 2 || top[0].Score != 100 || top[1].Score != 90 {
+		t.Fatalf("top ranks mismatch: %v", top)
+	}
+
+	// 通道已满时返回已入队的条数
+	full := NewHybridLeaderboard("batch-full", "batch-full", &RankConfig{BatchQueueSize: 2, OverflowPolicy: OverflowDrop})
+	full.mu.Lock()
+	updates := make([]*ScoreUpdate, 0, batchSize+10)
+	for i := int64(1); i <= int64(cap(updates)); i++ {
+		updates = append(updates, &ScoreUpdate{PlayerID: i, Score: i})
+	}
+	queued, err = full.UpdateScores(updates, "")
+	full.mu.Unlock()
+	if err != ErrQueueFull || queued == 0 || queued == len(updates) {
+		t.Fatalf("full queue: queued=%d err=%v", queued, err)
+	}
+	full.Close()
+	if got := full.GetPlayerCount(); got != queued {
+		t.Fatalf("player count mismatch: got=%d want=%d", got, queued)
+	}
+}
+
+func TestLeaderboardTimestampedUpdates(t *testing.T) {
+	lb := NewHybridLeaderboard("stamped", "stamped", &RankConfig{Aggregation: AggregationSum})
+	defer lb.Close()
+
+	base := time.Now()
+	cases := []struct {
+		score   int64
+		at      time.Time
+		applied bool
+		want    int64
+	}{
+		{10, base, true, 10},
+		{10, base, false, 10},                     // 重复投递被忽略
+		{5, base.Add(-time.Second), false, 10},    // 延迟到达的旧消息被忽略
+		{5, base.Add(time.Second), true, 15},      // 更新的消息正常累加
+		{1, time.Time{}, true, 16},                // 不带时间戳的更新总是生效
+		{5, base.Add(time.Second / 2), false, 16}, // 仍以最近一次带时间戳的更新为准
+	}
+	for i, c := range cases {
+		applied, err := lb.UpdateScoreAt(1, c.score, 0, c.at, "")
+		if err != nil {
+			t.Fatalf("case %d: UpdateScoreAt error: %v", i, err)
+		}
+		lb.Flush()
+		if applied != c.applied {
+			t.Fatalf("case %d: applied mismatch: got=%v want=%v", i, applied, c.applied)
+		}
+		if p, _, _ := lb.GetPlayerScore(1); p.Score != c.want {
+			t.Fatalf("case %d: score mismatch: got=%d want=%d", i, p.Score, c.want)
+		}
+	}
+
+	// 批量通道同样按时间戳过滤，移除玩家后时间戳随之清除
+	if _, err := lb.UpdateScores([]*ScoreUpdate{
+		{PlayerID: 2, Score: 7, Timestamp: base.Add(time.Second)},
+		{PlayerID: 2, Score: 3, Timestamp: base},
+	}, ""); err != nil {
+		t.Fatalf("UpdateScores error: %v", err)
+	}
+	lb.Flush()
+	if p, _, _ := lb.GetPlayerScore(2); p.Score != 7 {
+		t.Fatalf("batch: score mismatch: got=%d want=7", p.Score)
+	}
+	if err := lb.RemovePlayer(2); err != nil {
+		t.Fatalf("RemovePlayer error: %v", err)
+	}
+	if applied, _ := lb.UpdateScoreAt(2, 3, 0, base, ""); !applied {
+		t.Fatalf("update after removal should be applied")
+	}
+}
+
+func TestLeaderboardGetPlayerScore(t *testing.T) {
+	lb := setupLeaderboardBasic()
+	defer lb.Close()
+
+	if _, _, err := lb.GetPlayerScore(999); err != ErrPlayerNotFound {
+		t.Fatalf("expected ErrPlayerNotFound, got %v", err)
+	}
+
+	if _, err := lb.UpdateScoreWithSecondary(1, 500, 7, ""); err != nil {
+		t.Fatalf("UpdateScoreWithSecondary error: %v", err)
+	}
+	lb.Flush()
+	p, v1, err := lb.GetPlayerScore(1)
+	if err != nil || p.Score != 500 || p.SecondaryScore != 7 || p.UpdateTime.IsZero() {
+		t.Fatalf("unexpected player score: %+v err=%v", p, err)
+	}
+
+	// 返回副本，修改不影响排行榜
+	p.Score = 0
+	if q, _, _ := lb.GetPlayerScore(1); q.Score != 500 {
+		t.Fatalf("GetPlayerScore should return a copy, got score %d", q.Score)
+	}
+
+	if err := lb.syncUpdateScore(1, 600); err != nil {
+		t.Fatalf("syncUpdateScore error: %v", err)
+	}
+	if _, v2, _ := lb.GetPlayerScore(1); v2 <= v1 {
+		t.Fatalf("version should increase after update: %d -> %d", v1, v2)
+	}
+}
+
+func TestLeaderboardGetRankByScore(t *testing.T) {
+	lb := setupLeaderboardBasic() // 分数：2=50, 4=50, 3=20, 1=10, 5=5
+	defer lb.Close()
+
+	cases := []struct {
+		score int64
+		want  int
+	}{
+		{100, 1}, // 高于所有人
+		{50, 3},  // 同分排在已有玩家之后
+		{49, 3},
+		{20, 4},
+		{6, 5},
+		{0, 6}, // 低于所有人
+	}
+	for _, c := range cases {
+		rank, total := lb.GetRankByScore(c.score)
+		if rank != c.want || total != 5 {
+			t.Fatalf("GetRankByScore(%d) = (%d, %d), want (%d, 5)", c.score, rank, total, c.want)
+		}
+	}
+
+	// 按预测分数实际提交后，排名与预测一致
+	rank, _ := lb.GetRankByScore(20)
+	if err := lb.syncUpdateScore(9, 20); err != nil {
+		t.Fatalf("syncUpdateScore error: %v", err)
+	}
+	if got, _ := lb.GetPlayerRank(9); got != rank {
+		t.Fatalf("actual rank %d, predicted %d", got, rank)
+	}
+}
+
+func TestLeaderboardEvictInactive(t *testing.T) {
+	lb := setupLeaderboardBasic()
+	defer lb.Close()
+
+	cutoff := time.Now()
+	time.Sleep(time.Millisecond)
+	if err := lb.syncUpdateScore(1, 100); err != nil { // 更新后不再过期
+		t.Fatalf("syncUpdateScore error: %v", err)
+	}
+
+	if n := lb.EvictInactive(cutoff); n != 4 {
+		t.Fatalf("evicted %d players, want 4", n)
+	}
+	if n := lb.GetPlayerCount(); n != 1 {
+		t.Fatalf("player count after evict: got=%d want=1", n)
+	}
+	if top := lb.GetTopRanks(10); len(top) != 1 || top[0].ID != 1 {
+		t.Fatalf("TopRanks after evict: got=%v want=[1]", idsOf(top))
+	}
+	if r, err := lb.GetPlayerRank(1); err != nil || r != 1 {
+		t.Fatalf("rank after evict: got=%d err=%v want=1", r, err)
+	}
+}
+
+func TestLeaderboardExpirySweep(t *testing.T) {
+	lb := NewHybridLeaderboard("ttl", "ttl", &RankConfig{EntryTTLDays: 1})
+	defer lb.Close()
+
+	_ = lb.syncUpdateScore(1, 10)
+	_ = lb.syncUpdateScore(2, 20)
+	// 回拨玩家 1 的更新时间；分数互不相同，不影响跳表顺序
+	lb.mu.Lock()
+	lb.playerMap[1].UpdateTime = time.Now().Add(-48 * time.Hour)
+	lb.mu.Unlock()
+
+	scheduler := crontab.NewScheduler()
+	lb.StartExpirySweep(scheduler, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	scheduler.Tick()
+
+	if _, err := lb.GetPlayerRank(1); err != ErrPlayerNotFound {
+		t.Fatalf("expired player should be evicted, err=%v", err)
+	}
+	if _, err := lb.GetPlayerRank(2); err != nil {
+		t.Fatalf("active player should remain, err=%v", err)
+	}
+}
+
+// 被校验器拒绝的更新不生效，并写入审计日志
+func TestLeaderboardValidators(t *testing.T) {
+	lb := NewHybridLeaderboard("guard", "guard", &RankConfig{UpdatePolicy: UpdatePolicyOnlyHigher})
+	defer lb.Close()
+
+	audit := NewMemoryAuditLog(10)
+	lb.SetAuditLog(audit)
+	lb.SetValidators(
+		ScoreRangeValidator{Min: 0, Max: 1000},
+		MaxDeltaValidator{MaxDelta: 100},
+		NewRateLimitValidator(2),
+	)
+
+	cases := []struct {
+		player  int64
+		score   int64
+		applied bool
+	}{
+		{1, 50, true},
+		{1, 2000, false}, // 超出区间
+		{1, 500, false},  // 单次变化过大
+		{1, 120, true},
+		{1, 130, false}, // 一分钟内第 3 次通过前置校验的提交
+		{2, -1, false},  // 低于下限
+	}
+	for i, c := range cases {
+		applied, err := lb.UpdateScoreWithSecondary(c.player, c.score, 0, "")
+		if err != nil {
+			t.Fatalf("case %d: UpdateScoreWithSecondary error: %v", i, err)
+		}
+		if applied != c.applied {
+			t.Fatalf("case %d: applied=%v want=%v", i, applied, c.applied)
+		}
+	}
+
+	p, _, err := lb.GetPlayerScore(1)
+	if err != nil || p.Score != 120 {
+		t.Fatalf("player 1 score: got=%v err=%v want=120", p, err)
+	}
+	if _, _, err := lb.GetPlayerScore(2); err != ErrPlayerNotFound {
+		t.Fatalf("rejected new player should not be inserted, err=%v", err)
+	}
+
+	rejected := audit.Recent("guard", 10)
+	if len(rejected) != 4 {
+		t.Fatalf("audit records: got=%d want=4", len(rejected))
+	}
+	if rejected[0].PlayerID != 2 || rejected[3].Score != 2000 {
+		t.Fatalf("audit records not newest first: %+v", rejected)
+	}
+	if got := audit.Recent("other", 10); len(got) != 0 {
+		t.Fatalf("audit filter by leaderboard: got=%d want=0", len(got))
+	}
+	if got := audit.Recent("", 2); len(got) != 2 {
+		t.Fatalf("audit limit: got=%d want=2", len(got))
+	}
+}
+
+// 设置了校验器时 always 策略的单条更新走同步路径，被拒绝时返回未采纳
+func TestLeaderboardValidatorsAlwaysPolicy(t *testing.T) {
+	lb := NewHybridLeaderboard("guard", "guard", &RankConfig{})
+	defer lb.Close()
+	lb.SetValidators(ScoreRangeValidator{Min: 0, Max: 100})
+
+	if applied, err := lb.UpdateScoreWithPolicy(1, 50, UpdatePolicyAlways); err != nil || !applied {
+		t.Fatalf("valid update: applied=%v err=%v want applied", applied, err)
+	}
+	if applied, err := lb.UpdateScoreWithPolicy(1, 500, UpdatePolicyAlways); err != nil || applied {
+		t.Fatalf("out of range update: applied=%v err=%v want rejected", applied, err)
+	}
+	if p, _, _ := lb.GetPlayerScore(1); p.Score != 50 {
+		t.Fatalf("score: got=%d want=50", p.Score)
+	}
+}
+
+// 频率校验器清理窗口已过期的玩家，提交过的玩家数不会无限增长
+func TestRateLimitValidatorEviction(t *testing.T) {
+	v := NewRateLimitValidator(1)
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := int64(0); i < 100; i++ {
+		if err := v.Validate(nil, &ScoreUpdate{PlayerID: i}, start); err != nil {
+			t.Fatalf("player %d: %v", i, err)
+		}
+	}
+	if err := v.Validate(nil, &ScoreUpdate{PlayerID: 0}, start.Add(30*time.Second)); !errors.Is(err, ErrTooManySubmissions) {
+		t.Fatalf("second submission within a minute: got=%v want=%v", err, ErrTooManySubmissions)
+	}
+
+	if err := v.Validate(nil, &ScoreUpdate{PlayerID: 1000}, start.Add(2*time.Minute)); err != nil {
+		t.Fatalf("new player: %v", err)
+	}
+	if len(v.submissions) != 1 {
+		t.Fatalf("tracked players: got=%d want=1", len(v.submissions))
+	}
+	if err := v.Validate(nil, &ScoreUpdate{PlayerID: 0}, start.Add(2*time.Minute)); err != nil {
+		t.Fatalf("evicted player submits again: %v", err)
+	}
+}
+
+// Export 跨多个批次按排名顺序输出全部玩家，排名与 GetPlayerRank 一致
+func TestLeaderboardExport(t *testing.T) {
+	lb := NewHybridLeaderboard("export", "export", &RankConfig{})
+	defer lb.Close()
+
+	const N = exportChunkSize*2 + 17
+	for i := 1; i <= N; i++ {
+		_ = lb.syncUpdateScore(int64(i), int64(i%101))
+	}
+
+	var exported []*Player
+	if err := lb.Export(func(p *Player) error {
+		exported = append(exported, p)
+		return nil
+	}); err != nil {
+		t.Fatalf("Export error: %v", err)
+	}
+	if len(exported) != N {
+		t.Fatalf("exported %d players, want %d", len(exported), N)
+	}
+	for i, p := range exported {
+		if p.Rank != i+1 {
+			t.Fatalf("exported[%d] rank=%d want=%d", i, p.Rank, i+1)
+		}
+		if want, _ := lb.GetPlayerRank(p.ID); p.Rank != want {
+			t.Fatalf("player %d: export rank %d, GetPlayerRank %d", p.ID, p.Rank, want)
+		}
+	}
+
+	// fn 返回错误时停止导出
+	stop := errors.New("stop")
+	count := 0
+	err := lb.Export(func(*Player) error {
+		count++
+		if count == 10 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || count != 10 {
+		t.Fatalf("Export should stop on error: err=%v count=%d", err, count)
+	}
+}
+
+// 写入持续进行时的各类查询不会死锁（GetNearbyRanks 曾在持有读锁时重入读锁）
+func TestLeaderboardConcurrentReadsDuringWrites(t *testing.T) {
+	lb := NewHybridLeaderboard("locks", "locks", &RankConfig{})
+	defer lb.Close()
+	lb.SetEventBus(NewEventBus())
+
+	for i := int64(1); i <= 200; i++ {
+		_ = lb.syncUpdateScore(i, i)
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := int64(0); ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			_ = lb.syncUpdateScore(i%200+1, i%997)
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 2000; i++ {
+			id := int64(i%200 + 1)
+			if _, err := lb.GetNearbyRanks(id, 5); err != nil {
+				t.Errorf("GetNearbyRanks(%d) error: %v", id, err)
+				return
+			}
+			_, _ = lb.GetPlayerRank(id)
+			_, _ = lb.GetRange(1, 20)
+			_ = lb.GetTopRanks(10)
+			if n := lb.GetPlayerCount(); n != 200 {
+				t.Errorf("player count: got=%d want=200", n)
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("reads did not finish, possible deadlock")
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func TestLeaderboardBottomRanksAndReverseRange(t *testing.T) {
+	lb := NewHybridLeaderboard("tail", "tail", &RankConfig{})
+	defer lb.Close()
+
+	for i := int64(1); i <= 10; i++ {
+		_ = lb.syncUpdateScore(i, i*10)
+	}
+
+	bottom := lb.GetBottomRanks(3)
+	if got := idsOf(bottom); len(got) != 3 || got[0] != 1 || got[1] != 2 || got[2] != 3 {
+		t.Fatalf("GetBottomRanks(3): got=%v want=[1 2 3]", got)
+	}
+	if bottom[0].Rank != 10 || bottom[2].Rank != 8 {
+		t.Fatalf("bottom ranks: got=%d,%d want=10,8", bottom[0].Rank, bottom[2].Rank)
+	}
+	if got := lb.GetBottomRanks(100); len(got) != 10 || got[9].ID != 10 {
+		t.Fatalf("GetBottomRanks(100): got=%v", idsOf(got))
+	}
+
+	players, total := lb.GetRangeReverse(2, 4)
+	if total != 10 {
+		t.Fatalf("total: got=%d want=10", total)
+	}
+	if got := idsOf(players); len(got) != 3 || got[0] != 2 || got[2] != 4 {
+		t.Fatalf("GetRangeReverse(2,4): got=%v want=[2 3 4]", got)
+	}
+	if players[0].Rank != 9 {
+		t.Fatalf("reverse rank: got=%d want=9", players[0].Rank)
+	}
+	if got, _ := lb.GetRangeReverse(11, 20); len(got) != 0 {
+		t.Fatalf("reverse range past head: got=%v want=[]", idsOf(got))
+	}
+}
+
+func TestLeaderboardGetAroundScore(t *testing.T) {
+	lb := NewHybridLeaderboard("around", "around", &RankConfig{})
+	defer lb.Close()
+
+	for i := int64(1); i <= 10; i++ {
+		_ = lb.syncUpdateScore(i, i*10) // 玩家 i 分数 i*10，排名 11-i
+	}
+
+	players, rank := lb.GetAroundScore(55, 2)
+	if rank != 6 {
+		t.Fatalf("rank: got=%d want=6", rank)
+	}
+	if got := idsOf(players); len(got) != 4 || got[0] != 7 || got[1] != 6 || got[2] != 5 || got[3] != 4 {
+		t.Fatalf("GetAroundScore(55,2): got=%v want=[7 6 5 4]", got)
+	}
+
+	// 高于榜首：只有下方的玩家
+	players, rank = lb.GetAroundScore(1000, 3)
+	if got := idsOf(players); rank != 1 || len(got) != 3 || got[0] != 10 {
+		t.Fatalf("GetAroundScore(1000,3): rank=%d got=%v", rank, got)
+	}
+	// 低于榜尾：只有上方的玩家
+	players, rank = lb.GetAroundScore(0, 3)
+	if got := idsOf(players); rank != 11 || len(got) != 3 || got[2] != 1 {
+		t.Fatalf("GetAroundScore(0,3): rank=%d got=%v", rank, got)
+	}
+}
+
+// 玩家排名缓存在版本不变时命中，写入后失效
+func TestLeaderboardPlayerRankCache(t *testing.T) {
+	lb := NewHybridLeaderboard("rank-cache", "rank-cache", &RankConfig{})
+	defer lb.Close()
+
+	_ = lb.syncUpdateScore(1, 10)
+	_ = lb.syncUpdateScore(2, 20)
+
+	if r, err := lb.GetPlayerRank(1); err != nil || r != 2 {
+		t.Fatalf("rank: got=%d err=%v want=2", r, err)
+	}
+	version := atomic.LoadInt64(&lb.version)
+	if r, ok := lb.cache.GetPlayerRank(1, version); !ok || r != 2 {
+		t.Fatalf("rank should be cached: got=%d ok=%v", r, ok)
+	}
+
+	_ = lb.syncUpdateScore(3, 30)
+	if _, ok := lb.cache.GetPlayerRank(1, version); ok {
+		t.Fatal("cached rank should be invalidated after update")
+	}
+	if r, err := lb.GetPlayerRank(1); err != nil || r != 3 {
+		t.Fatalf("rank after update: got=%d err=%v want=3", r, err)
+	}
+	if _, err := lb.GetPlayerRank(99); err != ErrPlayerNotFound {
+		t.Fatalf("missing player: err=%v", err)
+	}
+}
+
+// RankConfig 中的容量与批处理参数生效，未配置时使用默认值
+func TestLeaderboardConfigurableSizes(t *testing.T) {
+	lb := NewHybridLeaderboard("sizes", "sizes", &RankConfig{TopK: 5, CacheTTLMs: 250, BatchSize: 7, BatchQueueSize: 64})
+	defer lb.Close()
+
+	if lb.topK != 5 || lb.batchLimit() != 7 || lb.cache.duration != 250*time.Millisecond {
+		t.Fatalf("configured sizes: topK=%d batch=%d ttl=%v", lb.topK, lb.batchLimit(), lb.cache.duration)
+	}
+	if c := lb.QueueStats().Capacity; c != 64 {
+		t.Fatalf("queue capacity: got=%d want=64", c)
+	}
+
+	def := NewHybridLeaderboard("defaults", "defaults", &RankConfig{})
+	defer def.Close()
+	if def.topK != defaultTopK || def.batchLimit() != batchSize || def.QueueStats().Capacity != batchQueueSize {
+		t.Fatalf("defaults: topK=%d batch=%d capacity=%d", def.topK, def.batchLimit(), def.QueueStats().Capacity)
+	}
+}
+
+// assertTopMatchesSkipList 校验前K名堆与跳表前K名一致，且 GetTopRanks 与跳表区间相同
+func assertTopMatchesSkipList(t *testing.T, lb *HybridLeaderboard) {
+	t.Helper()
+	want, _ := lb.GetRange(1, lb.topK)
+	heapIDs := idsOf(lb.topHeap.Players())
+	if len(heapIDs) != len(want) || !containsAll(heapIDs, idsOf(want)) {
+		t.Fatalf("top heap %v does not match skip list top %v", heapIDs, idsOf(want))
+	}
+	got := lb.GetTopRanks(lb.topK)
+	for i := range want {
+		if got[i].ID != want[i].ID || got[i].Rank != want[i].Rank || got[i].Score != want[i].Score {
+			t.Fatalf("top ranks %v, want %v", idsOf(got), idsOf(want))
+		}
+	}
+}
+
+// 前K名玩家降分跌出前K名时应被移出堆，由新的第K名补位
+func TestTopHeapDemotion(t *testing.T) {
+	lb := NewHybridLeaderboard("demote", "demote", &RankConfig{TopK: 3})
+	defer lb.Close()
+
+	for id := int64(1); id <= 6; id++ {
+		_ = lb.syncUpdateScore(id, id*10)
+	}
+	assertTopMatchesSkipList(t, lb)
+
+	_ = lb.syncUpdateScore(6, 5) // 第一名跌到最后
+	assertTopMatchesSkipList(t, lb)
+	if lb.topHeap.Contains(6) || !lb.topHeap.Contains(3) {
+		t.Fatalf("player 6 should be demoted and 3 promoted, heap=%v", idsOf(lb.topHeap.Players()))
+	}
+
+	_ = lb.syncUpdateScore(5, 45) // 前K名内部加分只调整顺序
+	assertTopMatchesSkipList(t, lb)
+
+	_ = lb.syncUpdateScore(1, 100) // 堆外玩家超过堆顶
+	assertTopMatchesSkipList(t, lb)
+}
+
+// 移除前K名玩家后应从跳表补位，玩家不足K名时堆包含全部玩家
+func TestTopHeapBackfillOnRemove(t *testing.T) {
+	lb := NewHybridLeaderboard("backfill", "backfill", &RankConfig{TopK: 3})
+	defer lb.Close()
+
+	for id := int64(1); id <= 4; id++ {
+		_ = lb.syncUpdateScore(id, id*10)
+	}
+	_ = lb.GetTopRanks(3) // 填充缓存
+
+	if err := lb.RemovePlayer(4); err != nil {
+		t.Fatal(err)
+	}
+	assertTopMatchesSkipList(t, lb)
+	if !lb.topHeap.Contains(1) {
+		t.Fatalf("player 1 should be backfilled, heap=%v", idsOf(lb.topHeap.Players()))
+	}
+
+	_ = lb.RemovePlayer(3)
+	assertTopMatchesSkipList(t, lb)
+	if lb.topHeap.Len() != 2 {
+		t.Fatalf("heap should hold remaining 2 players, got %d", lb.topHeap.Len())
+	}
+}
+
+// 竞赛排名：同分玩家并列（1、1、3），前N名、区间、邻近、批量与导出的排名一致
+func TestLeaderboardCompetitionRanking(t *testing.T) {
+	lb := NewHybridLeaderboard("competition", "competition", &RankConfig{RankingMode: RankingCompetition})
+	defer lb.Close()
+
+	for id, score := range map[int64]int64{1: 100, 2: 100, 3: 90, 4: 80, 5: 80, 6: 80, 7: 70} {
+		_ = lb.syncUpdateScore(id, score)
+	}
+	// 次级分数不同时不并列
+	_, _ = lb.UpdateScoreWithSecondary(8, 70, 1, UpdatePolicyAlways)
+	lb.Flush()
+
+	want := map[int64]int{1: 1, 2: 1, 3: 3, 4: 4, 5: 4, 6: 4, 8: 7, 7: 8}
+	check := func(name string, players []*Player) {
+		t.Helper()
+		for _, p := range players {
+			if p.Rank != want[p.ID] {
+				t.Fatalf("%s: player %d rank=%d want=%d", name, p.ID, p.Rank, want[p.ID])
+			}
+		}
+	}
+	for id, rank := range want {
+		if got, err := lb.GetPlayerRank(id); err != nil || got != rank {
+			t.Fatalf("GetPlayerRank(%d)=%d,%v want %d", id, got, err, rank)
+		}
+	}
+	top := lb.GetTopRanks(8)
+	if len(top) != 8 {
+		t.Fatalf("top: got %d players", len(top))
+	}
+	check("top", top)
+	middle, _ := lb.GetRange(5, 8)
+	if len(middle) != 4 {
+		t.Fatalf("range: got %d players", len(middle))
+	}
+	check("range", middle)
+	nearby, err := lb.GetNearbyRanks(6, 1)
+	if err != nil || len(nearby) != 3 {
+		t.Fatalf("nearby: %v %v", idsOf(nearby), err)
+	}
+	check("nearby", nearby)
+	ranked, _ := lb.GetPlayerRanks([]int64{2, 5, 7})
+	check("batch", ranked)
+	var exported []*Player
+	_ = lb.Export(func(p *Player) error {
+		exported = append(exported, p)
+		return nil
+	})
+	check("export", exported)
+
+	if rank, _ := lb.GetRankByScore(80); rank != 4 {
+		t.Fatalf("GetRankByScore(80)=%d want 4", rank)
+	}
+}
+
+// 升序排行榜：分数低者在前，前K名、区间与 only_higher（只接受更好的成绩）都按升序方向
+func TestLeaderboardAscendingOrder(t *testing.T) {
+	lb := NewHybridLeaderboard("laps", "laps", &RankConfig{SortOrder: leaderboardcore.Ascending, TopK: 3})
+	defer lb.Close()
+
+	for id := int64(1); id <= 6; id++ {
+		_ = lb.syncUpdateScore(id, 100-id*10) // 6 号用时最短
+	}
+	if rank, _ := lb.GetPlayerRank(6); rank != 1 {
+		t.Fatalf("fastest player rank=%d want 1", rank)
+	}
+	top := lb.GetTopRanks(3)
+	if ids := idsOf(top); len(ids) != 3 || ids[0] != 6 || ids[1] != 5 || ids[2] != 4 {
+		t.Fatalf("top=%v want [6 5 4]", ids)
+	}
+	assertTopMatchesSkipList(t, lb)
+
+	// only_higher 语义为“更好”：更慢的成绩被忽略，更快的成绩被采纳
+	if applied, _ := lb.UpdateScoreWithPolicy(1, 95, UpdatePolicyOnlyHigher); applied {
+		t.Fatal("slower lap should be ignored")
+	}
+	if applied, _ := lb.UpdateScoreWithPolicy(1, 5, UpdatePolicyOnlyHigher); !applied {
+		t.Fatal("faster lap should be applied")
+	}
+	lb.Flush()
+	if rank, _ := lb.GetPlayerRank(1); rank != 1 {
+		t.Fatalf("player 1 rank=%d want 1", rank)
+	}
+	assertTopMatchesSkipList(t, lb)
+
+	if rank, total := lb.GetRankByScore(45); rank != 3 || total != 6 {
+		t.Fatalf("GetRankByScore(45)=%d/%d want 3/6", rank, total)
+	}
+}
+
+// 分数历史：只记录被采纳的更新，超出容量时保留最近的记录，移除与重置时清空
+func TestLeaderboardScoreHistory(t *testing.T) {
+	lb := NewHybridLeaderboard("history", "history", &RankConfig{})
+	defer lb.Close()
+	if _, err := lb.GetScoreHistory(1, time.Time{}, 10); !errors.Is(err, ErrHistoryDisabled) {
+		t.Fatalf("expected ErrHistoryDisabled, got %v", err)
+	}
+	lb.SetScoreHistory(NewScoreHistory(3))
+
+	for _, score := range []int64{10, 20, 30, 40} {
+		_ = lb.syncUpdateScore(1, score)
+	}
+	_, _ = lb.UpdateScoreWithPolicy(1, 5, UpdatePolicyOnlyHigher) // 未被采纳，不记录
+
+	history, err := lb.GetScoreHistory(1, time.Time{}, 10)
+	if err != nil || len(history) != 3 {
+		t.Fatalf("history=%v err=%v, want 3 points", history, err)
+	}
+	for i, want := range []int64{20, 30, 40} {
+		if history[i].Score != want {
+			t.Fatalf("history[%d]=%d want %d", i, history[i].Score, want)
+		}
+	}
+	if recent, _ := lb.GetScoreHistory(1, history[0].Time, 10); len(recent) != 2 || recent[0].Score != 30 {
+		t.Fatalf("since: got %v", recent)
+	}
+	if latest, _ := lb.GetScoreHistory(1, time.Time{}, 1); len(latest) != 1 || latest[0].Score != 40 {
+		t.Fatalf("limit: got %v", latest)
+	}
+	if _, err := lb.GetScoreHistory(2, time.Time{}, 10); !errors.Is(err, ErrPlayerNotFound) {
+		t.Fatalf("unknown player: %v", err)
+	}
+
+	_ = lb.RemovePlayer(1)
+	if _, err := lb.GetScoreHistory(1, time.Time{}, 10); !errors.Is(err, ErrPlayerNotFound) {
+		t.Fatalf("removed player: %v", err)
+	}
+	_ = lb.syncUpdateScore(3, 1)
+	lb.Reset()
+	if _, err := lb.GetScoreHistory(3, time.Time{}, 10); !errors.Is(err, ErrPlayerNotFound) {
+		t.Fatalf("after reset: %v", err)
+	}
+}
+
+// 聚合方式：max 保留最好成绩，sum 累加每次提交，校验器看到的是聚合后的分数
+func TestLeaderboardAggregation(t *testing.T) {
+	best := NewHybridLeaderboard("best", "best", &RankConfig{Aggregation: AggregationMax})
+	defer best.Close()
+	for _, score := range []int64{30, 50, 20} {
+		_ = best.UpdateScore(1, score)
+	}
+	best.Flush()
+	if p, _, _ := best.GetPlayerScore(1); p.Score != 50 {
+		t.Fatalf("max: score=%d want 50", p.Score)
+	}
+	if applied, _ := best.UpdateScoreWithPolicy(1, 40, UpdatePolicyAlways); applied {
+		t.Fatal("max: worse score should not be applied")
+	}
+
+	total := NewHybridLeaderboard("total", "total", &RankConfig{Aggregation: AggregationSum})
+	defer total.Close()
+	total.SetValidators(ScoreRangeValidator{Min: 0, Max: 100})
+	for _, score := range []int64{30, 50, 20} {
+		_ = total.UpdateScore(1, score)
+	}
+	_ = total.UpdateScore(2, 10)
+	total.Flush()
+	if p, _, _ := total.GetPlayerScore(1); p.Score != 100 {
+		t.Fatalf("sum: score=%d want 100", p.Score)
+	}
+	if rank, _ := total.GetPlayerRank(1); rank != 1 {
+		t.Fatalf("sum: rank=%d want 1", rank)
+	}
+	if applied, _ := total.UpdateScoreWithPolicy(1, 1, UpdatePolicyOnlyHigher); applied {
+		t.Fatal("sum: total above validator range should be rejected")
+	}
+}
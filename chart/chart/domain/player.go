@@ -1,33 +1,26 @@
 // 玩家实体
 //
-// 语义说明：
-// - ID：玩家唯一标识；
-// - Score：用于排名的分数；
-// - Rank：可选的排名字段（部分接口返回时填充），不作为跳表排序依据；
-// - UpdateTime：最近一次分数更新的时间，作为分数相同情况下的次序比较键。
+// Player 与跳表、排序规则统一定义在 leaderboardcore 中，三个排行榜服务共用；
+// 此处以类型别名引入，domain 内的代码与接口返回格式保持不变。
 package domain
 
-import "time"
+import "leaderboardcore"
 
 // Player 玩家实体
-type Player struct {
-    ID         int64     `json:"id"`          // 玩家ID
-    Score      int64     `json:"score"`       // 玩家分数
-    Rank       int       `json:"rank"`        // 玩家排名
-    UpdateTime time.Time `json:"update_time"` // 玩家更新时间
-}
+type Player = leaderboardcore.Player
+
+// RankIndex 排名索引，单个跳表与分片跳表均实现该接口
+type RankIndex = leaderboardcore.RankIndex
 
 // NewPlayer 创建新玩家
 func NewPlayer(id, score int64) *Player {
-    return &Player{
-        ID:         id,
-        Score:      score,
-        UpdateTime: time.Now(),
-    }
+	return leaderboardcore.NewPlayer(id, score)
 }
 
-// UpdateScore 更新分数
-func (p *Player) UpdateScore(score int64) {
-    p.Score = score
-    p.UpdateTime = time.Now()
+// newRankIndex 按配置的排序方向创建排名索引，未配置分片边界时使用单个跳表
+func newRankIndex(config *RankConfig) RankIndex {
+	if config == nil {
+		return leaderboardcore.NewRankIndex(leaderboardcore.Descending, nil)
+	}
+	return leaderboardcore.NewRankIndex(config.sortOrder(), config.ShardBoundaries)
 }
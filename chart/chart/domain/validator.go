@@ -0,0 +1,225 @@
+// 分数更新校验（反作弊）
+//
+// 语义说明：
+// - ScoreValidator 在更新应用到排行榜前调用，返回错误即拒绝该更新；
+// - 设置了校验器时单条更新走同步路径，返回的 applied 反映校验结果；
+// - 批量通道中的更新被拒绝时无法回传给调用方，入队不代表被采纳，拒绝记录统一写入 AuditLog；
+// - 校验在排行榜写锁内执行，实现应保持轻量且并发安全（同一校验器可被多个排行榜共享）。
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrScoreOutOfRange 分数超出允许区间
+	ErrScoreOutOfRange = errors.New("score out of range")
+	// ErrScoreDeltaTooLarge 单次分数变化过大
+	ErrScoreDeltaTooLarge = errors.New("score delta too large")
+	// ErrTooManySubmissions 玩家提交过于频繁
+	ErrTooManySubmissions = errors.New("too many submissions")
+)
+
+// ScoreValidator 分数更新校验器
+// current 为玩家当前数据，新玩家为 nil。
+type ScoreValidator interface {
+	Validate(current *Player, update *ScoreUpdate, now time.Time) error
+}
+
+// ScoreRangeValidator 分数区间校验，与 rank-system 中 ScoreType.Validate 的上下限语义一致
+type ScoreRangeValidator struct {
+	Min int64
+	Max int64
+}
+
+// Validate 实现 ScoreValidator
+func (v ScoreRangeValidator) Validate(_ *Player, update *ScoreUpdate, _ time.Time) error {
+	if update.Score < v.Min || update.Score > v.Max {
+		return fmt.Errorf("%w: %d not in [%d, %d]", ErrScoreOutOfRange, update.Score, v.Min, v.Max)
+	}
+	return nil
+}
+
+// MaxDeltaValidator 单次更新相对当前分数的最大变化量，新玩家不校验
+type MaxDeltaValidator struct {
+	MaxDelta int64
+}
+
+// Validate 实现 ScoreValidator
+func (v MaxDeltaValidator) Validate(current *Player, update *ScoreUpdate, _ time.Time) error {
+	if current == nil {
+		return nil
+	}
+	delta := update.Score - current.Score
+	if delta < 0 {
+		delta = -delta
+	}
+	if delta > v.MaxDelta {
+		return fmt.Errorf("%w: %d > %d", ErrScoreDeltaTooLarge, delta, v.MaxDelta)
+	}
+	return nil
+}
+
+// RateLimitValidator 限制每个玩家每分钟的提交次数（滑动窗口）
+// 每分钟最多清理一次窗口已过期的玩家，内存只与最近一分钟内提交过的玩家数相关。
+type RateLimitValidator struct {
+	mu          sync.Mutex
+	maxPerMin   int
+	submissions map[int64][]time.Time // 玩家最近一分钟内的提交时间
+	lastSweep   time.Time             // 上次清理过期玩家的时间
+}
+
+// NewRateLimitValidator 创建频率校验器
+func NewRateLimitValidator(maxPerMinute int) *RateLimitValidator {
+	return &RateLimitValidator{
+		maxPerMin:   maxPerMinute,
+		submissions: make(map[int64][]time.Time),
+	}
+}
+
+// Validate 实现 ScoreValidator，被拒绝的提交同样计入窗口
+func (v *RateLimitValidator) Validate(_ *Player, update *ScoreUpdate, now time.Time) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	window := now.Add(-time.Minute)
+	if now.Sub(v.lastSweep) >= time.Minute {
+		v.evictLocked(window)
+		v.lastSweep = now
+	}
+	times := v.submissions[update.PlayerID]
+	i := 0
+	for i < len(times) && !times[i].After(window) {
+		i++
+	}
+	times = append(times[i:], now)
+	v.submissions[update.PlayerID] = times
+
+	if len(times) > v.maxPerMin {
+		return fmt.Errorf("%w: %d in the last minute, max %d", ErrTooManySubmissions, len(times), v.maxPerMin)
+	}
+	return nil
+}
+
+// evictLocked 移除最近一次提交早于窗口的玩家，调用方需持有 mu
+func (v *RateLimitValidator) evictLocked(window time.Time) {
+	for playerID, times := range v.submissions {
+		if !times[len(times)-1].After(window) {
+			delete(v.submissions, playerID)
+		}
+	}
+}
+
+// RejectedUpdate 被拒绝的分数更新
+type RejectedUpdate struct {
+	LeaderboardID  string    `json:"leaderboard_id"`
+	PlayerID       int64     `json:"player_id"`
+	Score          int64     `json:"score"`
+	SecondaryScore int64     `json:"secondary_score,omitempty"`
+	Reason         string    `json:"reason"`
+	Time           time.Time `json:"time"`
+}
+
+// AuditLog 审计日志，记录被拒绝的更新
+type AuditLog interface {
+	RecordRejected(rejected *RejectedUpdate)
+}
+
+// MemoryAuditLog 固定容量的内存审计日志，写满后覆盖最旧的记录
+type MemoryAuditLog struct {
+	mu      sync.Mutex
+	records []*RejectedUpdate
+	next    int
+	full    bool
+}
+
+// NewMemoryAuditLog 创建内存审计日志
+func NewMemoryAuditLog(capacity int) *MemoryAuditLog {
+	return &MemoryAuditLog{records: make([]*RejectedUpdate, capacity)}
+}
+
+// RecordRejected 实现 AuditLog
+func (a *MemoryAuditLog) RecordRejected(rejected *RejectedUpdate) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.records) == 0 {
+		return
+	}
+	a.records[a.next] = rejected
+	a.next = (a.next + 1) % len(a.records)
+	if a.next == 0 {
+		a.full = true
+	}
+}
+
+// Recent 返回最近的拒绝记录（由新到旧），leaderboardID 非空时只返回该排行榜的记录
+func (a *MemoryAuditLog) Recent(leaderboardID string, limit int) []*RejectedUpdate {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	n := a.next
+	if a.full {
+		n = len(a.records)
+	}
+	result := make([]*RejectedUpdate, 0, min(limit, n))
+	for i := 1; i <= n && len(result) < limit; i++ {
+		r := a.records[(a.next-i+len(a.records))%len(a.records)]
+		if leaderboardID == "" || r.LeaderboardID == leaderboardID {
+			cp := *r
+			result = append(result, &cp)
+		}
+	}
+	return result
+}
+
+// SetValidators 设置分数更新校验器，按顺序执行，任一拒绝即丢弃该更新
+func (lb *HybridLeaderboard) SetValidators(validators ...ScoreValidator) {
+	lb.hookMu.Lock()
+	defer lb.hookMu.Unlock()
+	lb.validators = validators
+}
+
+// SetAuditLog 设置审计日志，为 nil 时不记录被拒绝的更新
+func (lb *HybridLeaderboard) SetAuditLog(audit AuditLog) {
+	lb.hookMu.Lock()
+	defer lb.hookMu.Unlock()
+	lb.audit = audit
+}
+
+// hasValidators 返回是否设置了校验器
+func (lb *HybridLeaderboard) hasValidators() bool {
+	lb.hookMu.RLock()
+	defer lb.hookMu.RUnlock()
+	return len(lb.validators) > 0
+}
+
+// validateUpdate 依次执行校验器，拒绝时写入审计日志，调用方需持有 mu 写锁
+func (lb *HybridLeaderboard) validateUpdate(current *Player, update *ScoreUpdate) bool {
+	lb.hookMu.RLock()
+	validators, audit := lb.validators, lb.audit
+	lb.hookMu.RUnlock()
+	if len(validators) == 0 {
+		return true
+	}
+	now := time.Now()
+	for _, v := range validators {
+		if err := v.Validate(current, update, now); err != nil {
+			if audit != nil {
+				audit.RecordRejected(&RejectedUpdate{
+					LeaderboardID:  lb.ID,
+					PlayerID:       update.PlayerID,
+					Score:          update.Score,
+					SecondaryScore: update.SecondaryScore,
+					Reason:         err.Error(),
+					Time:           now,
+				})
+			}
+			return false
+		}
+	}
+	return true
+}
@@ -1,15 +1,29 @@
 package main
 
 import (
+    "context"
+    "errors"
     "log"
+    "net/http"
+    "os"
+    "os/signal"
+    "syscall"
+    "time"
     "chart/api"
     "chart/domain"
     "chart/storage"
+    "crontab"
+    "leaderboardcore"
 
     "github.com/gin-gonic/gin"
 )
 
 func main() {
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatal("Failed to load config:", err)
+	}
+
 	// 初始化存储
 	repo := storage.NewMemoryRepository()
 
@@ -19,15 +33,47 @@ func main() {
 		RewardRatio:  0.003,
 		MinReward:    100,
 		MaxReward:    1000,
+
+		TopK:           cfg.Leaderboard.TopK,
+		CacheTTLMs:     int(cfg.Leaderboard.CacheTTL / time.Millisecond),
+		BatchSize:      cfg.Leaderboard.BatchSize,
+		BatchQueueSize: cfg.Leaderboard.BatchQueueSize,
+		RankingMode:    domain.RankingMode(cfg.Leaderboard.RankingMode),
+		SortOrder:      leaderboardcore.SortOrder(cfg.Leaderboard.SortOrder),
+		Aggregation:    domain.Aggregation(cfg.Leaderboard.Aggregation),
+	}
+	if !config.RankingMode.Valid() {
+		log.Fatalf("Invalid ranking mode %q: must be ordinal or competition", config.RankingMode)
+	}
+	if !config.SortOrder.Valid() {
+		log.Fatalf("Invalid sort order %q: must be desc or asc", config.SortOrder)
+	}
+	if !config.Aggregation.Valid() {
+		log.Fatalf("Invalid aggregation %q: must be latest, max or sum", config.Aggregation)
 	}
 
     leaderboard := domain.NewHybridLeaderboard("default", "默认排行榜", config)
+	// 排名事件总线：通知、统计等模块可订阅 leaderboard.{id}.score / leaderboard.{id}.topk
+	events := domain.NewEventBus()
+	leaderboard.SetEventBus(events)
+	// 反作弊校验：分数区间、单次变化量与提交频率，被拒绝的更新写入审计日志
+	audit := domain.NewMemoryAuditLog(10000)
+	leaderboard.SetAuditLog(audit)
+	// 分数历史：每名玩家保留最近 HistorySize 次分数变化，供 /players/:id/history 绘制成绩曲线
+	if cfg.Leaderboard.HistorySize > 0 {
+		leaderboard.SetScoreHistory(domain.NewScoreHistory(cfg.Leaderboard.HistorySize))
+	}
+	leaderboard.SetValidators(cfg.Leaderboard.validators()...)
+	// 过期清理：配置 EntryTTLDays 后每小时清理一次长期未更新的玩家
+	scheduler := crontab.NewScheduler()
+	scheduler.Start(time.Second)
+	leaderboard.StartExpirySweep(scheduler, time.Hour)
 	if err := repo.SaveLeaderboard(leaderboard); err != nil {
 		log.Fatal("Failed to create default leaderboard:", err)
 	}
 
 	// 初始化处理器
-	handler := api.NewHandler(repo)
+	handler := api.NewHandler(repo, audit)
 
 	// 设置Gin
 	router := gin.Default()
@@ -36,8 +82,27 @@ func main() {
 	handler.RegisterRoutes(router)
 
 	// 启动服务
-	log.Println("Server starting on :8080")
-	if err := router.Run(":8080"); err != nil {
-		log.Fatal("Server failed to start:", err)
+	server := &http.Server{Addr: cfg.Server.Addr(), Handler: router}
+	server.RegisterOnShutdown(handler.Close)
+	go func() {
+		log.Println("Server starting on", server.Addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Server failed to start:", err)
+		}
+	}()
+
+	// 收到 SIGINT / SIGTERM 后停止接收新请求，等待进行中的请求完成，再关闭排行榜应用剩余的批量更新
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	stop()
+	log.Println("Shutting down server...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Println("Server forced to shutdown:", err)
 	}
+	repo.Close()
+	log.Println("Server stopped.")
 }
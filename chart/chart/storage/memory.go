@@ -46,6 +46,10 @@ func (r *MemoryRepository) DeleteLeaderboard(id string) error {
     r.mu.Lock()
     defer r.mu.Unlock()
 
+	// 关闭排行榜，释放其后台批处理协程
+	if leaderboard, exists := r.leaderboards[id]; exists {
+		leaderboard.Close()
+	}
 	delete(r.leaderboards, id)
 	return nil
 }
@@ -68,7 +72,8 @@ func (r *MemoryRepository) SavePlayer(leaderboardID string, player *domain.Playe
         return errors.New("leaderboard not found")
     }
     // 将玩家当前分数写入排行榜（作为一次更新）
-    return lb.UpdateScore(player.ID, player.Score)
+    _, err := lb.UpdateScoreWithSecondary(player.ID, player.Score, player.SecondaryScore, "")
+    return err
 }
 
 func (r *MemoryRepository) GetPlayer(leaderboardID string, playerID int64) (*domain.Player, error) {
@@ -78,8 +83,12 @@ func (r *MemoryRepository) GetPlayer(leaderboardID string, playerID int64) (*dom
 }
 
 func (r *MemoryRepository) RemovePlayer(leaderboardID string, playerID int64) error {
-    // 领域暂未提供移除 API，此处留空实现
-    return errors.New("RemovePlayer not implemented")
+    leaderboard, err := r.GetLeaderboard(leaderboardID)
+    if err != nil {
+        return err
+    }
+
+    return leaderboard.RemovePlayer(playerID)
 }
 
 func (r *MemoryRepository) GetTopPlayers(leaderboardID string, limit int) ([]*domain.Player, error) {
@@ -99,3 +108,13 @@ func (r *MemoryRepository) GetPlayerCount(leaderboardID string) (int, error) {
 
     return leaderboard.GetPlayerCount(), nil
 }
+
+// Close 关闭所有排行榜，等待已入队的分数更新全部应用后返回
+func (r *MemoryRepository) Close() {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    for _, leaderboard := range r.leaderboards {
+        leaderboard.Close()
+    }
+}
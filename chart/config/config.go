@@ -0,0 +1,167 @@
+// Package config 为各排行榜服务提供统一的配置加载：先读取配置文件，再以环境变量覆盖。
+//
+// 配置文件按 yaml 标签解析，JSON 是 YAML 的子集，因此 .json 文件同样适用；
+// 结构体字段上的 env 标签声明覆盖该字段的环境变量。
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// EnvConfigPath 是存储配置文件路径的环境变量键。
+	EnvConfigPath = "RANK_CONFIG_PATH"
+	// EnvServerPort 是配置服务器端口的环境变量键。
+	EnvServerPort = "RANK_SERVER_PORT"
+	// EnvLogLevel 是控制日志级别的环境变量键。
+	EnvLogLevel = "RANK_LOG_LEVEL"
+)
+
+// Server 定义了各服务通用的HTTP服务器配置。
+type Server struct {
+	Port            int           `yaml:"port" env:"RANK_SERVER_PORT"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"RANK_SHUTDOWN_TIMEOUT"` // 优雅退出时等待进行中请求完成的最长时间
+}
+
+// Addr 返回HTTP服务的监听地址。
+func (s Server) Addr() string {
+	return fmt.Sprintf(":%d", s.Port)
+}
+
+// Log 定义了日志配置。
+type Log struct {
+	Level string `yaml:"level" env:"RANK_LOG_LEVEL"`
+}
+
+// Load 从 RANK_CONFIG_PATH 指向的文件加载配置并应用环境变量覆盖。
+// cfg 必须是结构体指针，调用前填入的值作为默认值；未设置 RANK_CONFIG_PATH 时只应用环境变量。
+func Load(cfg interface{}) error {
+	return LoadFile(os.Getenv(EnvConfigPath), cfg)
+}
+
+// LoadFile 从指定文件加载配置并应用环境变量覆盖，path 为空时只应用环境变量。
+// 文件中出现结构体未声明的字段时返回错误，避免拼写错误的配置被静默忽略。
+func LoadFile(path string, cfg interface{}) error {
+	if path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		dec := yaml.NewDecoder(file)
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("config %s: %w", path, err)
+		}
+	}
+	return ApplyEnv(cfg)
+}
+
+// ApplyEnv 按字段的 env 标签用环境变量覆盖配置，递归处理嵌套结构体。
+// 支持字符串、布尔、整数、浮点数、time.Duration 以及逗号分隔的 []string。
+func ApplyEnv(cfg interface{}) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return errors.New("config: cfg must be a pointer to struct")
+	}
+	return applyEnv(v.Elem())
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func applyEnv(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+
+		if key := field.Tag.Get("env"); key != "" {
+			if raw, ok := os.LookupEnv(key); ok {
+				if err := setValue(fv, raw); err != nil {
+					return fmt.Errorf("config: env %s: %w", key, err)
+				}
+			}
+			continue
+		}
+
+		switch {
+		case fv.Kind() == reflect.Struct:
+			if err := applyEnv(fv); err != nil {
+				return err
+			}
+		case fv.Kind() == reflect.Ptr && !fv.IsNil() && fv.Elem().Kind() == reflect.Struct:
+			if err := applyEnv(fv.Elem()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// setValue 将环境变量的字符串值解析后写入字段
+func setValue(fv reflect.Value, raw string) error {
+	if fv.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", fv.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		fv.Set(reflect.ValueOf(items).Convert(fv.Type()))
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Server Server `yaml:"server"`
+	Data   struct {
+		Dir string `yaml:"dir" env:"TEST_DATA_DIR"`
+	} `yaml:"data"`
+	TopK     int           `yaml:"top_k" env:"TEST_TOP_K"`
+	CacheTTL time.Duration `yaml:"cache_ttl" env:"TEST_CACHE_TTL"`
+	Keys     []string      `yaml:"keys" env:"TEST_KEYS"`
+	Enabled  bool          `yaml:"enabled" env:"TEST_ENABLED"`
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFileYAMLAndJSON(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": "server:\n  port: 9000\n  shutdown_timeout: 3s\ndata:\n  dir: /var/rank\ntop_k: 50\ncache_ttl: 1m\nkeys: [a, b]\n",
+		"config.json": `{"server": {"port": 9000, "shutdown_timeout": "3s"}, "data": {"dir": "/var/rank"}, "top_k": 50, "cache_ttl": "1m", "keys": ["a", "b"]}`,
+	}
+	for name, content := range files {
+		cfg := testConfig{TopK: 10}
+		if err := LoadFile(writeFile(t, name, content), &cfg); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if cfg.Server.Port != 9000 || cfg.Server.ShutdownTimeout != 3*time.Second {
+			t.Fatalf("%s: server got %+v", name, cfg.Server)
+		}
+		if cfg.Data.Dir != "/var/rank" || cfg.TopK != 50 || cfg.CacheTTL != time.Minute {
+			t.Fatalf("%s: got %+v", name, cfg)
+		}
+		if !reflect.DeepEqual(cfg.Keys, []string{"a", "b"}) {
+			t.Fatalf("%s: keys got %v", name, cfg.Keys)
+		}
+	}
+}
+
+func TestLoadFileRejectsUnknownFields(t *testing.T) {
+	var cfg testConfig
+	if err := LoadFile(writeFile(t, "config.yaml", "topk: 50\n"), &cfg); err == nil {
+		t.Fatal("expected error for unknown field")
+	}
+}
+
+func TestEnvOverridesFile(t *testing.T) {
+	t.Setenv(EnvServerPort, "7000")
+	t.Setenv("TEST_DATA_DIR", "/tmp/rank")
+	t.Setenv("TEST_CACHE_TTL", "250ms")
+	t.Setenv("TEST_KEYS", "x, y,,z")
+	t.Setenv("TEST_ENABLED", "true")
+
+	cfg := testConfig{TopK: 10}
+	if err := LoadFile(writeFile(t, "config.yaml", "server:\n  port: 9000\ntop_k: 50\n"), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Port != 7000 || cfg.Data.Dir != "/tmp/rank" || cfg.CacheTTL != 250*time.Millisecond || !cfg.Enabled {
+		t.Fatalf("env overrides not applied: %+v", cfg)
+	}
+	if cfg.TopK != 50 {
+		t.Fatalf("file value lost: top_k=%d", cfg.TopK)
+	}
+	if !reflect.DeepEqual(cfg.Keys, []string{"x", "y", "z"}) {
+		t.Fatalf("keys got %v", cfg.Keys)
+	}
+}
+
+func TestLoadWithoutFileKeepsDefaults(t *testing.T) {
+	t.Setenv(EnvConfigPath, "")
+	cfg := testConfig{TopK: 10, Server: Server{Port: 8080}}
+	if err := Load(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.TopK != 10 || cfg.Server.Port != 8080 {
+		t.Fatalf("defaults changed: %+v", cfg)
+	}
+}
+
+func TestApplyEnvInvalidValue(t *testing.T) {
+	t.Setenv("TEST_TOP_K", "many")
+	var cfg testConfig
+	if err := ApplyEnv(&cfg); err == nil {
+		t.Fatal("expected parse error")
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"config"
+	"fmt"
+	"leaderboard/internal/infrastructure/persistence"
+	"time"
+)
+
+// appConfig 服务配置，从 RANK_CONFIG_PATH 指向的 YAML / JSON 文件加载，可被环境变量覆盖
+type appConfig struct {
+	Server      config.Server     `yaml:"server"`
+	GRPCPort    int               `yaml:"grpc_port" env:"RANK_GRPC_PORT"`
+	DataDir     string            `yaml:"data_dir" env:"RANK_DATA_DIR"`
+	Persistence persistenceConfig `yaml:"persistence"`
+}
+
+// persistenceConfig 持久化配置
+type persistenceConfig struct {
+	SnapshotInterval time.Duration `yaml:"snapshot_interval" env:"LEADERBOARD_SNAPSHOT_INTERVAL"` // 小于等于 0 表示不做定期快照
+	AOFFsync         string        `yaml:"aof_fsync" env:"LEADERBOARD_AOF_FSYNC"`                 // always / everysec / no
+	AOFReplay        string        `yaml:"aof_replay" env:"LEADERBOARD_AOF_REPLAY"`               // tolerant / strict / repair
+}
+
+// loadConfig 加载服务配置
+func loadConfig() (*appConfig, error) {
+	cfg := &appConfig{
+		Server:   config.Server{Port: 8080, ShutdownTimeout: 10 * time.Second},
+		GRPCPort: 9090,
+		DataDir:  "./data",
+		Persistence: persistenceConfig{
+			SnapshotInterval: persistence.DefaultSnapshotInterval,
+		},
+	}
+	if err := config.Load(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// storeOptions 将持久化配置转换为存储选项
+func (c *appConfig) storeOptions() (persistence.Options, error) {
+	opts := persistence.DefaultOptions()
+	opts.SnapshotInterval = c.Persistence.SnapshotInterval
+
+	var err error
+	if opts.FsyncPolicy, err = persistence.ParseFsyncPolicy(c.Persistence.AOFFsync); err != nil {
+		return opts, fmt.Errorf("invalid aof_fsync: %w", err)
+	}
+	if opts.ReplayMode, err = persistence.ParseReplayMode(c.Persistence.AOFReplay); err != nil {
+		return opts, fmt.Errorf("invalid aof_replay: %w", err)
+	}
+	return opts, nil
+}
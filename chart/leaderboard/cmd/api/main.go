@@ -1,25 +1,47 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"leaderboard/internal/application"
 	"leaderboard/internal/infrastructure/persistence"
+	grpcapi "leaderboard/internal/interfaces/grpc"
 	"leaderboard/internal/interfaces/http"
 	"log"
+	"net"
+	nethttp "net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
 )
 
 func main() {
 	log.Println("Starting application...")
-	// 初始化存储库
-	lb, repo, err := persistence.NewLeaderboardRepository("./data", "default")
+	// 配置：默认值 < RANK_CONFIG_PATH 指向的 YAML / JSON 文件 < 环境变量
+	// AOF 刷盘策略（always / everysec / no）与回放模式（tolerant / strict / repair）
+	// 仍可通过 LEADERBOARD_AOF_FSYNC、LEADERBOARD_AOF_REPLAY 调整
+	cfg, err := loadConfig()
 	if err != nil {
-		log.Fatalf("failed to create repository: %v", err)
+		log.Fatalf("failed to load config: %v", err)
 	}
-	log.Println("Repository created.")
+	opts, err := cfg.storeOptions()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	// 初始化存储，每个排行榜在数据目录下拥有独立的目录，并定期快照以控制启动回放时间
+	store, err := persistence.NewLeaderboardStore(cfg.DataDir, opts)
+	if err != nil {
+		log.Fatalf("failed to create leaderboard store: %v", err)
+	}
+	log.Println("Leaderboard store created.")
 
 	// 初始化应用服务
-	rankService, err := application.NewRankService(lb, repo)
+	rankService, err := application.NewRankService(store)
 	if err != nil {
 		log.Fatalf("failed to create rank service: %v", err)
 	}
@@ -37,10 +59,55 @@ func main() {
 	handler.RegisterRoutes(router)
 	log.Println("Routes registered.")
 
+	// 在同一进程中启动 gRPC 服务，供内部调用方低延迟访问
+	grpcServer := grpc.NewServer()
+	grpcapi.NewServer(rankService).Register(grpcServer)
+	grpcAddr := fmt.Sprintf(":%d", cfg.GRPCPort)
+	lis, err := net.Listen("tcp", grpcAddr)
+	if err != nil {
+		log.Fatalf("failed to listen for gRPC: %v", err)
+	}
+	go func() {
+		log.Printf("Starting gRPC server on %s...", grpcAddr)
+		if err := grpcServer.Serve(lis); err != nil {
+			log.Fatalf("failed to serve gRPC: %v", err)
+		}
+	}()
+
 	// 启动服务器
-	log.Println("Starting server on :8080...")
-	if err := router.Run(":8080"); err != nil {
-		log.Fatalf("failed to run server: %v", err)
+	server := &nethttp.Server{Addr: cfg.Server.Addr(), Handler: router}
+	go func() {
+		log.Printf("Starting server on %s...", server.Addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, nethttp.ErrServerClosed) {
+			log.Fatalf("failed to run server: %v", err)
+		}
+	}()
+
+	// 收到 SIGINT / SIGTERM 后依次停止 HTTP 与 gRPC 服务，等待进行中的请求完成，
+	// 再为每个排行榜保存最终快照并关闭 AOF 日志
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	stop()
+	log.Println("Shutting down server...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("HTTP server forced to shutdown: %v", err)
+	}
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+	select {
+	case <-grpcStopped:
+	case <-shutdownCtx.Done():
+		grpcServer.Stop()
+	}
+	if err := rankService.Close(); err != nil {
+		log.Printf("failed to close leaderboards: %v", err)
 	}
 	log.Println("Server stopped.")
-}
\ No newline at end of file
+}
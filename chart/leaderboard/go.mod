@@ -34,5 +34,8 @@ require (
 	golang.org/x/sys v0.35.0 // indirect
 	golang.org/x/text v0.27.0 // indirect
 	golang.org/x/tools v0.34.0 // indirect
-	google.golang.org/protobuf v1.36.9 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250218202821-56aae31c358a // indirect
+	github.com/DATA-DOG/go-sqlmock v1.5.2
+	google.golang.org/grpc v1.72.2
+	google.golang.org/protobuf v1.36.9
 )
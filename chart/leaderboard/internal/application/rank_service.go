@@ -1,49 +1,244 @@
 package application
 
 import (
+	"errors"
+	"fmt"
 	"leaderboard/internal/domain/model"
 	"leaderboard/internal/domain/repository"
+	"sort"
+	"sync"
 )
 
+// DefaultLeaderboardID 是服务启动时保证存在的默认排行榜 ID。
+const DefaultLeaderboardID = "default"
+
+var (
+	ErrLeaderboardNotFound = errors.New("leaderboard not found")
+	ErrLeaderboardExists   = errors.New("leaderboard already exists")
+)
+
+// LeaderboardInfo 描述一个排行榜的基本信息。
+type LeaderboardInfo struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	PlayerCount int    `json:"player_count"`
+}
+
 // RankService 定义了排行榜应用服务。
 type RankService interface {
-	UpdateScore(playerID int64, score int64) error
-	GetPlayerRank(playerID int64) (int64, error)
-	GetTopN(n int) ([]*model.Player, error)
-	GetNearbyRanks(playerID int64, count int) ([]*model.Player, error)
+	CreateLeaderboard(id, name string) (*LeaderboardInfo, error)
+	ListLeaderboards() []*LeaderboardInfo
+	DeleteLeaderboard(id string) error
+	RewriteAOF(id string) error
+
+	UpdateScore(leaderboardID string, playerID int64, score int64) error
+	GetPlayerRank(leaderboardID string, playerID int64) (int64, error)
+	GetTopN(leaderboardID string, n int) ([]*model.Player, error)
+	GetNearbyRanks(leaderboardID string, playerID int64, count int) ([]*model.Player, error)
+
+	Close() error
+}
+
+// board 将排行榜与其持久化存储绑定在一起。
+type board struct {
+	leaderboard *model.Leaderboard
+	repo        repository.LeaderboardRepository
 }
 
 // rankServiceImpl 是 RankService 的实现。
 type rankServiceImpl struct {
-	leaderboardRepo repository.LeaderboardRepository
-	leaderboard     *model.Leaderboard
+	store  repository.LeaderboardStore
+	mu     sync.RWMutex
+	boards map[string]*board
+}
+
+// NewRankService 创建一个新的 RankService，加载存储中已有的所有排行榜，并保证默认排行榜存在。
+func NewRankService(store repository.LeaderboardStore) (RankService, error) {
+	s := &rankServiceImpl{
+		store:  store,
+		boards: make(map[string]*board),
+	}
+
+	ids, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if _, err := s.open(id, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := s.boards[DefaultLeaderboardID]; !ok {
+		if _, err := s.open(DefaultLeaderboardID, DefaultLeaderboardID); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// open 打开排行榜存储并注册到服务中，name 非空时覆盖排行榜名称。调用方需保证并发安全。
+func (s *rankServiceImpl) open(id, name string) (*board, error) {
+	lb, repo, err := s.store.Open(id)
+	if err != nil {
+		return nil, err
+	}
+	if name != "" {
+		lb.Name = name
+		if err := repo.Save(lb); err != nil {
+			repo.Close()
+			return nil, err
+		}
+	}
+
+	b := &board{leaderboard: lb, repo: repo}
+	s.boards[id] = b
+	return b, nil
+}
+
+// getBoard 按 ID 获取排行榜。
+func (s *rankServiceImpl) getBoard(id string) (*board, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	b, ok := s.boards[id]
+	if !ok {
+		return nil, ErrLeaderboardNotFound
+	}
+	return b, nil
 }
 
-// NewRankService 创建一个新的 RankService。
-func NewRankService(leaderboard *model.Leaderboard, repo repository.LeaderboardRepository) (RankService, error) {
-	return &rankServiceImpl{
-		leaderboard:     leaderboard,
-		leaderboardRepo: repo,
-	}, nil
+// CreateLeaderboard 创建一个新的排行榜。
+func (s *rankServiceImpl) CreateLeaderboard(id, name string) (*LeaderboardInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.boards[id]; ok {
+		return nil, ErrLeaderboardExists
+	}
+	if name == "" {
+		name = id
+	}
+
+	b, err := s.open(id, name)
+	if err != nil {
+		return nil, err
+	}
+	return infoOf(b.leaderboard), nil
+}
+
+// ListLeaderboards 列出所有排行榜，按 ID 排序。
+func (s *rankServiceImpl) ListLeaderboards() []*LeaderboardInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	infos := make([]*LeaderboardInfo, 0, len(s.boards))
+	for _, b := range s.boards {
+		infos = append(infos, infoOf(b.leaderboard))
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
+
+// DeleteLeaderboard 删除排行榜及其持久化数据。
+func (s *rankServiceImpl) DeleteLeaderboard(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.boards[id]
+	if !ok {
+		return ErrLeaderboardNotFound
+	}
+	delete(s.boards, id)
+
+	if err := b.repo.Close(); err != nil {
+		return err
+	}
+	return s.store.Remove(id)
+}
+
+// Close 为每个排行榜保存最终快照并关闭其持久化存储，之后服务不再持有任何排行榜。
+// 快照会截断已覆盖的 AOF 日志，下次启动无需回放；单个排行榜失败不影响其余排行榜的关闭。
+func (s *rankServiceImpl) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for id, b := range s.boards {
+		if err := b.repo.Save(b.leaderboard); err != nil {
+			errs = append(errs, fmt.Errorf("leaderboard %s: final snapshot: %w", id, err))
+		}
+		if err := b.repo.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("leaderboard %s: close: %w", id, err))
+		}
+		delete(s.boards, id)
+	}
+	return errors.Join(errs...)
+}
+
+// RewriteAOF 立即重写排行榜的 AOF 日志，压缩已被覆盖的历史更新。
+func (s *rankServiceImpl) RewriteAOF(id string) error {
+	b, err := s.getBoard(id)
+	if err != nil {
+		return err
+	}
+	return s.removedDuring(id, b, b.repo.RewriteAOF(b.leaderboard))
 }
 
 // UpdateScore 更新玩家的分数。
-func (s *rankServiceImpl) UpdateScore(playerID int64, score int64) error {
-	s.leaderboard.UpdateScore(playerID, score)
-	return s.leaderboardRepo.LogUpdate(playerID, score)
+func (s *rankServiceImpl) UpdateScore(leaderboardID string, playerID int64, score int64) error {
+	b, err := s.getBoard(leaderboardID)
+	if err != nil {
+		return err
+	}
+	b.leaderboard.UpdateScore(playerID, score)
+	return s.removedDuring(leaderboardID, b, b.repo.LogUpdate(playerID, score))
+}
+
+// removedDuring 在操作失败且排行榜已在操作期间被删除时返回 ErrLeaderboardNotFound，
+// 而不是关闭存储导致的内部错误；其余情况原样返回 err。
+func (s *rankServiceImpl) removedDuring(id string, b *board, err error) error {
+	if err == nil {
+		return nil
+	}
+	if current, _ := s.getBoard(id); current != b {
+		return ErrLeaderboardNotFound
+	}
+	return err
 }
 
 // GetPlayerRank 获取玩家的排名。
-func (s *rankServiceImpl) GetPlayerRank(playerID int64) (int64, error) {
-	return s.leaderboard.GetPlayerRank(playerID)
+func (s *rankServiceImpl) GetPlayerRank(leaderboardID string, playerID int64) (int64, error) {
+	b, err := s.getBoard(leaderboardID)
+	if err != nil {
+		return 0, err
+	}
+	return b.leaderboard.GetPlayerRank(playerID)
 }
 
 // GetTopN 获取排名前 N 的玩家。
-func (s *rankServiceImpl) GetTopN(n int) ([]*model.Player, error) {
-	return s.leaderboard.GetTopN(n), nil
+func (s *rankServiceImpl) GetTopN(leaderboardID string, n int) ([]*model.Player, error) {
+	b, err := s.getBoard(leaderboardID)
+	if err != nil {
+		return nil, err
+	}
+	return b.leaderboard.GetTopN(n), nil
 }
 
 // GetNearbyRanks 获取玩家临近的排名。
-func (s *rankServiceImpl) GetNearbyRanks(playerID int64, count int) ([]*model.Player, error) {
-	return s.leaderboard.GetNearbyRanks(playerID, count)
-}
\ No newline at end of file
+func (s *rankServiceImpl) GetNearbyRanks(leaderboardID string, playerID int64, count int) ([]*model.Player, error) {
+	b, err := s.getBoard(leaderboardID)
+	if err != nil {
+		return nil, err
+	}
+	return b.leaderboard.GetNearbyRanks(playerID, count)
+}
+
+// infoOf 生成排行榜的基本信息。
+func infoOf(lb *model.Leaderboard) *LeaderboardInfo {
+	return &LeaderboardInfo{
+		ID:          lb.ID,
+		Name:        lb.Name,
+		PlayerCount: lb.PlayerCount(),
+	}
+}
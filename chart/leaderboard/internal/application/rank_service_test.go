@@ -0,0 +1,160 @@
+package application
+
+import (
+	"errors"
+	"leaderboard/internal/infrastructure/persistence"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// newTestService 在 dir/data 中创建服务，服务在测试结束时关闭
+func newTestService(t *testing.T, dir string) RankService {
+	t.Helper()
+	store, err := persistence.NewLeaderboardStore(filepath.Join(dir, "data"), persistence.Options{})
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	s, err := NewRankService(store)
+	if err != nil {
+		t.Fatalf("new service: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// leaderboardIDs 按顺序返回服务中的排行榜 ID
+func leaderboardIDs(s RankService) []string {
+	var ids []string
+	for _, info := range s.ListLeaderboards() {
+		ids = append(ids, info.ID)
+	}
+	return ids
+}
+
+// 重复创建排行榜（包括默认排行榜）返回 ErrLeaderboardExists，已有排行榜不受影响
+func TestCreateLeaderboardTwice(t *testing.T) {
+	s := newTestService(t, t.TempDir())
+
+	info, err := s.CreateLeaderboard("weekly", "Weekly")
+	if err != nil || info.ID != "weekly" || info.Name != "Weekly" {
+		t.Fatalf("create: got=%+v, %v", info, err)
+	}
+	if err := s.UpdateScore("weekly", 1, 100); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	for _, id := range []string{"weekly", DefaultLeaderboardID} {
+		if _, err := s.CreateLeaderboard(id, "again"); !errors.Is(err, ErrLeaderboardExists) {
+			t.Fatalf("create %s again: got=%v want=%v", id, err, ErrLeaderboardExists)
+		}
+	}
+
+	infos := s.ListLeaderboards()
+	if len(infos) != 2 || infos[1].ID != "weekly" || infos[1].Name != "Weekly" || infos[1].PlayerCount != 1 {
+		t.Fatalf("leaderboards after duplicate create: %+v", infos)
+	}
+}
+
+// 不存在的排行榜上的所有操作返回 ErrLeaderboardNotFound，且不会创建存储目录
+func TestUnknownLeaderboard(t *testing.T) {
+	dir := t.TempDir()
+	s := newTestService(t, dir)
+
+	calls := map[string]func() error{
+		"UpdateScore":       func() error { return s.UpdateScore("missing", 1, 10) },
+		"GetPlayerRank":     func() error { _, err := s.GetPlayerRank("missing", 1); return err },
+		"GetTopN":           func() error { _, err := s.GetTopN("missing", 10); return err },
+		"GetNearbyRanks":    func() error { _, err := s.GetNearbyRanks("missing", 1, 5); return err },
+		"RewriteAOF":        func() error { return s.RewriteAOF("missing") },
+		"DeleteLeaderboard": func() error { return s.DeleteLeaderboard("missing") },
+	}
+	for name, call := range calls {
+		if err := call(); !errors.Is(err, ErrLeaderboardNotFound) {
+			t.Fatalf("%s: got=%v want=%v", name, err, ErrLeaderboardNotFound)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "data", "missing")); !os.IsNotExist(err) {
+		t.Fatalf("storage created for unknown leaderboard: %v", err)
+	}
+}
+
+// 可能逃逸出数据目录的 ID 被拒绝，不会在数据目录之外创建或删除文件
+func TestInvalidLeaderboardID(t *testing.T) {
+	dir := t.TempDir()
+	s := newTestService(t, dir)
+	outside := filepath.Join(dir, "x")
+	if err := os.Mkdir(outside, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	for _, id := range []string{"../x", "..", ".", "", "a/b", `..\x`} {
+		if _, err := s.CreateLeaderboard(id, "evil"); !errors.Is(err, persistence.ErrInvalidLeaderboardID) {
+			t.Fatalf("create %q: got=%v want=%v", id, err, persistence.ErrInvalidLeaderboardID)
+		}
+		if err := s.DeleteLeaderboard(id); !errors.Is(err, ErrLeaderboardNotFound) {
+			t.Fatalf("delete %q: got=%v want=%v", id, err, ErrLeaderboardNotFound)
+		}
+	}
+	if ids := leaderboardIDs(s); len(ids) != 1 || ids[0] != DefaultLeaderboardID {
+		t.Fatalf("leaderboards: got=%v want=[%s]", ids, DefaultLeaderboardID)
+	}
+	if entries, err := os.ReadDir(outside); err != nil || len(entries) != 0 {
+		t.Fatalf("directory outside the data dir: entries=%v err=%v", entries, err)
+	}
+}
+
+// 删除排行榜时正在进行的更新要么成功，要么返回 ErrLeaderboardNotFound；
+// 删除后存储目录不会被迟到的写入重新创建，同名排行榜重新创建后为空
+func TestDeleteLeaderboardDuringUpdates(t *testing.T) {
+	dir := t.TempDir()
+	s := newTestService(t, dir)
+	if _, err := s.CreateLeaderboard("live", ""); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	const workers = 4
+	var wg sync.WaitGroup
+	started := make(chan struct{}, workers)
+	errs := make(chan error, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := int64(0); ; i++ {
+				err := s.UpdateScore("live", int64(w)*100000+i, i)
+				if i == 10 {
+					started <- struct{}{}
+				}
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(w)
+	}
+	for w := 0; w < workers; w++ {
+		<-started
+	}
+	if err := s.DeleteLeaderboard("live"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if !errors.Is(err, ErrLeaderboardNotFound) {
+			t.Fatalf("update during delete: got=%v want=%v", err, ErrLeaderboardNotFound)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "data", "live")); !os.IsNotExist(err) {
+		t.Fatalf("storage left after delete: %v", err)
+	}
+	if err := s.DeleteLeaderboard("live"); !errors.Is(err, ErrLeaderboardNotFound) {
+		t.Fatalf("delete twice: got=%v want=%v", err, ErrLeaderboardNotFound)
+	}
+	info, err := s.CreateLeaderboard("live", "")
+	if err != nil || info.PlayerCount != 0 {
+		t.Fatalf("recreate: got=%+v, %v want an empty leaderboard", info, err)
+	}
+}
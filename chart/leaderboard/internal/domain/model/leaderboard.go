@@ -2,6 +2,7 @@ package model
 
 import (
 	"errors"
+	"leaderboardcore"
 	"sync"
 )
 
@@ -10,11 +11,13 @@ var (
 )
 
 // Leaderboard 是排行榜的聚合根。
+// 排名由 leaderboardcore 的跳表维护；玩家对象写入后不再修改，更新分数时以新对象替换，
+// 因此 Players 等方法返回的玩家可以在锁外安全读取（例如写快照）。
 type Leaderboard struct {
 	ID      string
 	Name    string
-	players map[int64]*Node
-	sl      *SkipList
+	players map[int64]*Player
+	sl      *leaderboardcore.SkipList
 	mu      sync.RWMutex
 }
 
@@ -23,8 +26,8 @@ func NewLeaderboard(id, name string) *Leaderboard {
 	return &Leaderboard{
 		ID:      id,
 		Name:    name,
-		players: make(map[int64]*Node),
-		sl:      NewSkipList(),
+		players: make(map[int64]*Player),
+		sl:      leaderboardcore.NewSkipList(),
 	}
 }
 
@@ -33,18 +36,18 @@ func (l *Leaderboard) UpdateScore(playerID int64, score int64) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	if node, ok := l.players[playerID]; ok {
+	if old, ok := l.players[playerID]; ok {
 		// 如果分数没有变化，则不更新
-		if node.Player.Score == score {
+		if old.Score == score {
 			return
 		}
 		// 从跳表中删除旧节点
-		l.sl.Delete(node.Player.Score, node.Player.ID)
+		l.sl.Delete(old)
 	}
 
 	player := NewPlayer(playerID, score)
-	node := l.sl.Insert(player)
-	l.players[playerID] = node
+	l.sl.Insert(player)
+	l.players[playerID] = player
 }
 
 // GetPlayerRank 获取玩家的排名。
@@ -52,9 +55,10 @@ func (l *Leaderboard) GetPlayerRank(playerID int64) (int64, error) {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 
-	if node, ok := l.players[playerID]; ok {
-		rank := l.sl.GetRank(node.Player.Score, node.Player.ID)
-		return rank, nil
+	if player, ok := l.players[playerID]; ok {
+		if rank, found := l.sl.GetRankByPlayer(player); found {
+			return int64(rank), nil
+		}
 	}
 
 	return 0, ErrPlayerNotFound
@@ -62,41 +66,72 @@ func (l *Leaderboard) GetPlayerRank(playerID int64) (int64, error) {
 
 // GetTopN 获取排名前 N 的玩家。
 func (l *Leaderboard) GetTopN(n int) []*Player {
-    l.mu.RLock()
-    defer l.mu.RUnlock()
-
-    players := make([]*Player, 0, n)
-    node := l.sl.header.level[0].forward
-    for i := 0; i < n && node != nil; i++ {
-        players = append(players, node.Player)
-        node = node.level[0].forward
-    }
-    return players
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	players := l.sl.GetRange(1, n)
+	if players == nil {
+		players = []*Player{}
+	}
+	return players
 }
 
 // GetNearbyRanks 获取玩家临近的排名。
 func (l *Leaderboard) GetNearbyRanks(playerID int64, count int) ([]*Player, error) {
-    l.mu.RLock()
-    defer l.mu.RUnlock()
-
-	if node, ok := l.players[playerID]; ok {
-		rank := l.sl.GetRank(node.Player.Score, node.Player.ID)
-		startRank := rank - int64(count/2)
-		if startRank < 1 {
-			startRank = 1
-		}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
 
-        players := make([]*Player, 0, count)
-        startNode := l.sl.GetElementByRank(startRank)
-        if startNode == nil {
-            return players, nil
-        }
-        for i := 0; i < count && startNode != nil; i++ {
-            players = append(players, startNode.Player)
-            startNode = startNode.level[0].forward
-        }
-        return players, nil
-    }
-
-	return nil, ErrPlayerNotFound
-}
\ No newline at end of file
+	player, ok := l.players[playerID]
+	if !ok {
+		return nil, ErrPlayerNotFound
+	}
+	rank, found := l.sl.GetRankByPlayer(player)
+	if !found {
+		return nil, ErrPlayerNotFound
+	}
+
+	startRank := rank - count/2
+	if startRank < 1 {
+		startRank = 1
+	}
+	players := l.sl.GetRange(startRank, startRank+count-1)
+	if players == nil {
+		players = []*Player{}
+	}
+	return players, nil
+}
+
+// PlayerCount 返回排行榜中的玩家数量。
+func (l *Leaderboard) PlayerCount() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return len(l.players)
+}
+
+// Players 按排名顺序返回排行榜中的全部玩家。
+func (l *Leaderboard) Players() []*Player {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	players := make([]*Player, 0, len(l.players))
+	l.sl.Iterate(nil, func(_ int, player *Player) bool {
+		players = append(players, player)
+		return true
+	})
+	return players
+}
+
+// Restore 以给定的玩家数据（保留其更新时间）写入排行榜，用于从快照恢复。
+func (l *Leaderboard) Restore(players []*Player) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, player := range players {
+		if old, ok := l.players[player.ID]; ok {
+			l.sl.Delete(old)
+		}
+		l.sl.Insert(player)
+		l.players[player.ID] = player
+	}
+}
@@ -1,19 +1,11 @@
 package model
 
-import "time"
+import "leaderboardcore"
 
-// Player 表示排行榜中的一个玩家。
-type Player struct {
-    ID        int64     `json:"id"`
-    Score     int64     `json:"score"`
-    UpdatedAt time.Time `json:"updated_at"`
-}
+// Player 表示排行榜中的一个玩家，与其他排行榜服务共用 leaderboardcore 中的定义。
+type Player = leaderboardcore.Player
 
 // NewPlayer 创建一个新玩家。
 func NewPlayer(id int64, score int64) *Player {
-	return &Player{
-		ID:        id,
-		Score:     score,
-		UpdatedAt: time.Now(),
-	}
-}
\ No newline at end of file
+	return leaderboardcore.NewPlayer(id, score)
+}
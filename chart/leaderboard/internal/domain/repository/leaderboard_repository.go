@@ -9,4 +9,17 @@ type LeaderboardRepository interface {
 	Save(*model.Leaderboard) error
 	Load(id string) (*model.Leaderboard, error)
 	LogUpdate(playerID int64, score int64) error
-}
\ No newline at end of file
+	// RewriteAOF 按排行榜当前状态重写更新日志，每个玩家只保留一条记录。
+	RewriteAOF(*model.Leaderboard) error
+	Close() error
+}
+
+// LeaderboardStore 定义了多排行榜的持久化管理接口，每个排行榜拥有独立的存储。
+type LeaderboardStore interface {
+	// Open 打开（不存在时创建）指定排行榜的存储，并加载其当前状态。
+	Open(id string) (*model.Leaderboard, LeaderboardRepository, error)
+	// List 列出所有已持久化的排行榜 ID。
+	List() ([]string, error)
+	// Remove 删除指定排行榜的全部持久化数据。
+	Remove(id string) error
+}
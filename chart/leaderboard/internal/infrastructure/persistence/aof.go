@@ -5,70 +5,163 @@ import (
 	"fmt"
 	"io"
 	"leaderboard/internal/domain/model"
-	"os"
+	"leaderboardcore"
 	"strconv"
 	"strings"
 )
 
+// AOF 的缓冲、刷盘、回放与压缩由 leaderboardcore.AppendLog 实现，这里只负责 update 记录的编码。
+
+// FsyncPolicy 决定 AOF 写入何时落盘。
+type FsyncPolicy = leaderboardcore.FsyncPolicy
+
+const (
+	// FsyncAlways 每次写入后立即刷盘，最安全也最慢。
+	FsyncAlways = leaderboardcore.FsyncAlways
+	// FsyncEverySec 写入先进入缓冲区，由后台每秒刷盘一次，宕机最多丢失约一秒的更新。
+	FsyncEverySec = leaderboardcore.FsyncEverySec
+	// FsyncNo 后台每秒把缓冲区写入操作系统，但不主动刷盘，由操作系统决定落盘时机。
+	FsyncNo = leaderboardcore.FsyncNo
+)
+
+// aofFlushInterval 是后台刷新缓冲区的间隔。
+const aofFlushInterval = leaderboardcore.AppendLogFlushInterval
+
+var (
+	// ErrAOFClosed 表示 AOF 日志已关闭。
+	ErrAOFClosed = leaderboardcore.ErrLogClosed
+	// ErrInvalidFsyncPolicy 表示未知的 fsync 策略。
+	ErrInvalidFsyncPolicy = leaderboardcore.ErrInvalidFsyncPolicy
+)
+
+// ParseFsyncPolicy 解析 fsync 策略，空字符串表示默认的 everysec。
+func ParseFsyncPolicy(s string) (FsyncPolicy, error) {
+	return leaderboardcore.ParseFsyncPolicy(s)
+}
+
 // AOFLogger 负责记录和回放排行榜的更新操作。
 type AOFLogger struct {
-	file *os.File
+	log *leaderboardcore.AppendLog
 }
 
-// NewAOFLogger 创建一个新的 AOFLogger。
-func NewAOFLogger(filePath string) (*AOFLogger, error) {
-	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+// NewAOFLogger 创建一个新的 AOFLogger，policy 为空时使用 everysec。
+func NewAOFLogger(filePath string, policy FsyncPolicy) (*AOFLogger, error) {
+	l, err := leaderboardcore.OpenAppendLog(filePath, policy)
 	if err != nil {
 		return nil, err
 	}
-	return &AOFLogger{file: file}, nil
+	return &AOFLogger{log: l}, nil
+}
+
+// Flush 将缓冲区中的更新写入文件，并按策略决定是否刷盘。
+func (l *AOFLogger) Flush() error {
+	return l.log.Flush()
 }
 
 // LogUpdate 记录一次分数更新操作。
 func (l *AOFLogger) LogUpdate(playerID int64, score int64) error {
-	_, err := fmt.Fprintf(l.file, "update %d %d\n", playerID, score)
-	return err
+	return l.log.Append(formatUpdate(playerID, score))
 }
 
-// Replay 回放 AOF 日志，重建排行榜状态。
-func (l *AOFLogger) Replay(lb *model.Leaderboard) error {
-	file, err := os.Open(l.file.Name())
-	if err != nil {
-		return err
-	}
-	defer file.Close()
+// formatUpdate 编码一条 update 记录。
+func formatUpdate(playerID, score int64) []byte {
+	return []byte(fmt.Sprintf("update %d %d\n", playerID, score))
+}
 
-	reader := bufio.NewReader(file)
-	for {
-		line, err := reader.ReadString('\n')
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return err
-		}
+// Size 返回当前 AOF 文件的大小（字节）。
+func (l *AOFLogger) Size() int64 {
+	return l.log.Size()
+}
 
-		parts := strings.Split(strings.TrimSpace(line), " ")
-		if len(parts) != 3 || parts[0] != "update" {
-			continue
+// Rewrite 根据排行榜当前状态重写 AOF 日志：每个玩家只保留一条 update 记录。
+func (l *AOFLogger) Rewrite(lb *model.Leaderboard) error {
+	return l.compact(lb, func(players []*model.Player, w io.Writer) error {
+		bw := bufio.NewWriter(w)
+		for _, p := range players {
+			if _, err := bw.Write(formatUpdate(p.ID, p.Score)); err != nil {
+				return err
+			}
 		}
+		return bw.Flush()
+	})
+}
 
-		playerID, err := strconv.ParseInt(parts[1], 10, 64)
-		if err != nil {
-			continue
-		}
+// Truncate 在 base 持久化排行榜当前状态后截断 AOF 日志，只保留此后的更新。
+func (l *AOFLogger) Truncate(lb *model.Leaderboard, base func(players []*model.Player) error) error {
+	return l.compact(lb, func(players []*model.Player, _ io.Writer) error {
+		return base(players)
+	})
+}
 
-		score, err := strconv.ParseInt(parts[2], 10, 64)
-		if err != nil {
-			continue
+// compact 以排行榜当前状态为基准替换 AOF 日志，writeBase 负责写出基准状态，
+// 可以写入新日志的开头，也可以持久化到别处。玩家列表在持有日志写锁时获取，
+// 期间的并发更新由 AppendLog.Compact 追加到新日志，不会丢失。
+func (l *AOFLogger) compact(lb *model.Leaderboard, writeBase func(players []*model.Player, w io.Writer) error) error {
+	return l.log.Compact(func() func(w io.Writer) error {
+		players := lb.Players()
+		return func(w io.Writer) error {
+			return writeBase(players, w)
 		}
+	})
+}
+
+// ReplayMode 决定回放 AOF 日志时如何处理损坏的记录。
+type ReplayMode = leaderboardcore.ReplayMode
+
+const (
+	// ReplayTolerant 跳过无法解析的记录，继续回放后续内容。
+	ReplayTolerant = leaderboardcore.ReplayTolerant
+	// ReplayStrict 遇到损坏的记录立即失败。
+	ReplayStrict = leaderboardcore.ReplayStrict
+	// ReplayRepair 在第一条损坏的记录处截断日志，只保留之前的内容，适合处理宕机留下的半条记录。
+	ReplayRepair = leaderboardcore.ReplayRepair
+)
+
+// ErrInvalidReplayMode 表示未知的回放模式。
+var ErrInvalidReplayMode = leaderboardcore.ErrInvalidReplayMode
+
+// ParseReplayMode 解析回放模式，空字符串表示默认的 repair。
+func ParseReplayMode(s string) (ReplayMode, error) {
+	return leaderboardcore.ParseReplayMode(s)
+}
+
+// CorruptRecordError 描述 AOF 日志中一条损坏的记录。
+type CorruptRecordError = leaderboardcore.CorruptRecordError
+
+// ReplayResult 汇总一次回放的结果。
+type ReplayResult = leaderboardcore.ReplayResult
 
+// Replay 回放 AOF 日志，重建排行榜状态。没有换行结尾的最后一行视为写了一半的损坏记录。
+func (l *AOFLogger) Replay(lb *model.Leaderboard, mode ReplayMode) (ReplayResult, error) {
+	return l.log.Replay(mode, func(record string) error {
+		playerID, score, ok := parseUpdate(record)
+		if !ok {
+			return leaderboardcore.ErrMalformedRecord
+		}
 		lb.UpdateScore(playerID, score)
+		return nil
+	})
+}
+
+// parseUpdate 解析一条 update 记录。
+func parseUpdate(line string) (playerID, score int64, ok bool) {
+	parts := strings.Split(strings.TrimSpace(line), " ")
+	if len(parts) != 3 || parts[0] != "update" {
+		return 0, 0, false
 	}
-	return nil
+
+	playerID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	score, err = strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return playerID, score, true
 }
 
-// Close 关闭 AOF 日志文件。
+// Close 写出缓冲区并关闭 AOF 日志文件。
 func (l *AOFLogger) Close() error {
-	return l.file.Close()
-}
\ No newline at end of file
+	return l.log.Close()
+}
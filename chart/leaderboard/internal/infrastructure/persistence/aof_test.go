@@ -0,0 +1,356 @@
+package persistence
+
+import (
+	"leaderboard/internal/domain/model"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// openTestRepo 以 opts 在 dir 中打开排行榜存储，零值 Options 表示不做定期快照
+func openTestRepo(t *testing.T, dir string, opts Options) (*model.Leaderboard, *leaderboardRepositoryImpl) {
+	t.Helper()
+	lb, repo, err := NewLeaderboardRepository(dir, "test", opts)
+	if err != nil {
+		t.Fatalf("open repository: %v", err)
+	}
+	return lb, repo.(*leaderboardRepositoryImpl)
+}
+
+// assertSameScores 检查两个排行榜的玩家与分数（按排名顺序）完全一致
+func assertSameScores(t *testing.T, got, want *model.Leaderboard) {
+	t.Helper()
+	gp, wp := got.Players(), want.Players()
+	if len(gp) != len(wp) {
+		t.Fatalf("players: got=%d want=%d", len(gp), len(wp))
+	}
+	for i := range wp {
+		if gp[i].ID != wp[i].ID || gp[i].Score != wp[i].Score {
+			t.Fatalf("player at %d: got=%d/%d want=%d/%d", i, gp[i].ID, gp[i].Score, wp[i].ID, wp[i].Score)
+		}
+	}
+}
+
+// concurrentUpdates 以 workers 个协程并发更新排行榜并记录日志，每个协程负责各自的玩家，
+// 与 RankService.UpdateScore 一样先更新排行榜再写日志
+func concurrentUpdates(t *testing.T, lb *model.Leaderboard, repo *leaderboardRepositoryImpl, workers, rounds int) {
+	t.Helper()
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				playerID := int64(w*100 + i%50)
+				score := int64(i*workers + w)
+				lb.UpdateScore(playerID, score)
+				if err := repo.LogUpdate(playerID, score); err != nil {
+					t.Errorf("log update: %v", err)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+// 并发写入的同时反复重写 AOF，重启回放后的排行榜与内存中的一致
+func TestAOFRewriteConcurrentUpdates(t *testing.T) {
+	dir := t.TempDir()
+	lb, repo := openTestRepo(t, dir, Options{})
+
+	stop := make(chan struct{})
+	var rewrites atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if err := repo.RewriteAOF(lb); err != nil {
+				t.Errorf("rewrite: %v", err)
+				return
+			}
+			rewrites.Add(1)
+		}
+	}()
+	concurrentUpdates(t, lb, repo, 8, 2000)
+	close(stop)
+	wg.Wait()
+	if rewrites.Load() == 0 {
+		t.Fatal("no rewrite happened during updates")
+	}
+	if err := repo.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	replayed, repo2 := openTestRepo(t, dir, Options{})
+	defer repo2.Close()
+	assertSameScores(t, replayed, lb)
+}
+
+// AOF 超过阈值时在后台自动重写，重写期间的并发写入不会丢失
+func TestAOFAutoRewrite(t *testing.T) {
+	dir := t.TempDir()
+	lb, repo := openTestRepo(t, dir, Options{})
+	repo.rewriteMinSize = 4 << 10
+
+	concurrentUpdates(t, lb, repo, 8, 2000)
+	// 等待最后一次后台重写结束
+	for repo.rewriting.Load() {
+		time.Sleep(time.Millisecond)
+	}
+	if repo.rewriteBase.Load() == 0 {
+		t.Fatal("auto rewrite never happened")
+	}
+	if err := repo.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	replayed, repo2 := openTestRepo(t, dir, Options{})
+	defer repo2.Close()
+	assertSameScores(t, replayed, lb)
+}
+
+// 重写后每个玩家只保留一条记录
+func TestAOFRewriteKeepsOneRecordPerPlayer(t *testing.T) {
+	dir := t.TempDir()
+	lb, repo := openTestRepo(t, dir, Options{})
+	defer repo.Close()
+
+	for i := int64(1); i <= 10; i++ {
+		for p := int64(1); p <= 3; p++ {
+			lb.UpdateScore(p, i*p)
+			if err := repo.LogUpdate(p, i*p); err != nil {
+				t.Fatalf("log update: %v", err)
+			}
+		}
+	}
+	if err := repo.RewriteAOF(lb); err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	data, err := os.ReadFile(dir + "/aof.log")
+	if err != nil {
+		t.Fatalf("read aof: %v", err)
+	}
+	want := "update 3 30\nupdate 2 20\nupdate 1 10\n"
+	if string(data) != want {
+		t.Fatalf("aof:\ngot  %q\nwant %q", data, want)
+	}
+	if got := repo.aofLogger.Size(); got != int64(len(want)) {
+		t.Fatalf("size: got=%d want=%d", got, len(want))
+	}
+}
+
+// readAOF 读取磁盘上的 AOF 文件内容
+func readAOF(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read aof: %v", err)
+	}
+	return string(data)
+}
+
+// always 每次写入后立即落盘；everysec 与 no 先写缓冲区，由后台定期写出
+func TestAOFFsyncPolicy(t *testing.T) {
+	const record = "update 1 100\n"
+	tests := []struct {
+		policy   FsyncPolicy
+		buffered bool
+	}{
+		{FsyncAlways, false},
+		{FsyncEverySec, true},
+		{FsyncNo, true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			path := t.TempDir() + "/aof.log"
+			l, err := NewAOFLogger(path, tt.policy)
+			if err != nil {
+				t.Fatalf("new logger: %v", err)
+			}
+			defer l.Close()
+
+			if err := l.LogUpdate(1, 100); err != nil {
+				t.Fatalf("log update: %v", err)
+			}
+			got := readAOF(t, path)
+			if !tt.buffered {
+				if got != record {
+					t.Fatalf("aof: got=%q want=%q", got, record)
+				}
+				return
+			}
+			if got != "" {
+				t.Fatalf("aof before flush: got=%q want empty", got)
+			}
+
+			// 后台每 aofFlushInterval 写出一次缓冲区
+			deadline := time.Now().Add(3 * aofFlushInterval)
+			for readAOF(t, path) != record {
+				if time.Now().After(deadline) {
+					t.Fatalf("buffered update not flushed within %v", 3*aofFlushInterval)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
+
+// Close 写出缓冲区中尚未落盘的更新，关闭后不再接受写入
+func TestAOFCloseFlushesPending(t *testing.T) {
+	for _, policy := range []FsyncPolicy{FsyncEverySec, FsyncNo} {
+		t.Run(string(policy), func(t *testing.T) {
+			path := t.TempDir() + "/aof.log"
+			l, err := NewAOFLogger(path, policy)
+			if err != nil {
+				t.Fatalf("new logger: %v", err)
+			}
+			for i := int64(1); i <= 3; i++ {
+				if err := l.LogUpdate(i, i*10); err != nil {
+					t.Fatalf("log update: %v", err)
+				}
+			}
+			if err := l.Close(); err != nil {
+				t.Fatalf("close: %v", err)
+			}
+
+			want := "update 1 10\nupdate 2 20\nupdate 3 30\n"
+			if got := readAOF(t, path); got != want {
+				t.Fatalf("aof: got=%q want=%q", got, want)
+			}
+			if err := l.LogUpdate(4, 40); err != ErrAOFClosed {
+				t.Fatalf("log update after close: got=%v want=%v", err, ErrAOFClosed)
+			}
+			if err := l.Close(); err != nil {
+				t.Fatalf("second close: %v", err)
+			}
+		})
+	}
+}
+
+// 未知的 fsync 策略被拒绝，空字符串使用默认的 everysec
+func TestParseFsyncPolicy(t *testing.T) {
+	if p, err := ParseFsyncPolicy(""); err != nil || p != FsyncEverySec {
+		t.Fatalf("default: got=%q, %v want=%q", p, err, FsyncEverySec)
+	}
+	if _, err := NewAOFLogger(t.TempDir()+"/aof.log", "sometimes"); err != ErrInvalidFsyncPolicy {
+		t.Fatalf("invalid policy: got=%v want=%v", err, ErrInvalidFsyncPolicy)
+	}
+}
+
+// 各回放模式对损坏记录与写了一半的最后一行的处理
+func TestAOFReplayModes(t *testing.T) {
+	const valid = "update 1 10\nupdate 2 20\n"
+	tests := []struct {
+		name    string
+		content string
+		mode    ReplayMode
+		wantErr bool
+		want    ReplayResult
+		wantAOF string // 回放后磁盘上的内容
+		scores  map[int64]int64
+	}{
+		{"strict/half line", valid + "update 3 3", ReplayStrict, true,
+			ReplayResult{Applied: 2}, valid + "update 3 3", map[int64]int64{1: 10, 2: 20}},
+		{"strict/corrupt line", valid + "update x 30\n", ReplayStrict, true,
+			ReplayResult{Applied: 2}, valid + "update x 30\n", map[int64]int64{1: 10, 2: 20}},
+		{"repair/half line", valid + "update 3 3", ReplayRepair, false,
+			ReplayResult{Applied: 2, Truncated: int64(len("update 3 3"))}, valid, map[int64]int64{1: 10, 2: 20}},
+		{"repair/corrupt middle", "update 1 10\ngarbage\nupdate 2 20\n", ReplayRepair, false,
+			ReplayResult{Applied: 1, Truncated: int64(len("garbage\nupdate 2 20\n"))}, "update 1 10\n", map[int64]int64{1: 10}},
+		{"tolerant/half line", valid + "update 3 3", ReplayTolerant, false,
+			ReplayResult{Applied: 2, Skipped: 1}, valid + "update 3 3", map[int64]int64{1: 10, 2: 20}},
+		{"tolerant/corrupt middle", "update 1 10\ngarbage\nupdate 2 20\n", ReplayTolerant, false,
+			ReplayResult{Applied: 2, Skipped: 1}, "update 1 10\ngarbage\nupdate 2 20\n", map[int64]int64{1: 10, 2: 20}},
+		{"repair/intact", valid, ReplayRepair, false,
+			ReplayResult{Applied: 2}, valid, map[int64]int64{1: 10, 2: 20}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := t.TempDir() + "/aof.log"
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("write aof: %v", err)
+			}
+			l, err := NewAOFLogger(path, FsyncAlways)
+			if err != nil {
+				t.Fatalf("new logger: %v", err)
+			}
+			defer l.Close()
+
+			lb := model.NewLeaderboard("test", "test")
+			result, err := l.Replay(lb, tt.mode)
+			if tt.wantErr {
+				corrupt, ok := err.(*CorruptRecordError)
+				if !ok || corrupt.Offset != int64(len(valid)) {
+					t.Fatalf("replay error: got=%v want corrupt record at %d", err, len(valid))
+				}
+			} else if err != nil {
+				t.Fatalf("replay: %v", err)
+			}
+			if result != tt.want {
+				t.Fatalf("result: got=%+v want=%+v", result, tt.want)
+			}
+			if got := readAOF(t, path); got != tt.wantAOF {
+				t.Fatalf("aof: got=%q want=%q", got, tt.wantAOF)
+			}
+			if lb.PlayerCount() != len(tt.scores) {
+				t.Fatalf("players: got=%d want=%d", lb.PlayerCount(), len(tt.scores))
+			}
+			for _, p := range lb.Players() {
+				if p.Score != tt.scores[p.ID] {
+					t.Fatalf("player %d: got=%d want=%d", p.ID, p.Score, tt.scores[p.ID])
+				}
+			}
+		})
+	}
+}
+
+// repair 截断后继续写入的记录紧接在有效内容之后，再次回放不会再遇到损坏
+func TestAOFReplayRepairThenAppend(t *testing.T) {
+	path := t.TempDir() + "/aof.log"
+	if err := os.WriteFile(path, []byte("update 1 10\nupdate 2 2"), 0644); err != nil {
+		t.Fatalf("write aof: %v", err)
+	}
+	l, err := NewAOFLogger(path, FsyncAlways)
+	if err != nil {
+		t.Fatalf("new logger: %v", err)
+	}
+	defer l.Close()
+
+	if _, err := l.Replay(model.NewLeaderboard("test", "test"), ReplayRepair); err != nil {
+		t.Fatalf("repair: %v", err)
+	}
+	if got, want := l.Size(), int64(len("update 1 10\n")); got != want {
+		t.Fatalf("size: got=%d want=%d", got, want)
+	}
+	if err := l.LogUpdate(2, 20); err != nil {
+		t.Fatalf("log update: %v", err)
+	}
+
+	lb := model.NewLeaderboard("test", "test")
+	result, err := l.Replay(lb, ReplayStrict)
+	if err != nil {
+		t.Fatalf("strict replay after repair: %v", err)
+	}
+	if result.Applied != 2 || lb.PlayerCount() != 2 {
+		t.Fatalf("replay: applied=%d players=%d want 2", result.Applied, lb.PlayerCount())
+	}
+}
+
+// 未知的回放模式被拒绝，空字符串使用默认的 repair
+func TestParseReplayMode(t *testing.T) {
+	if m, err := ParseReplayMode(""); err != nil || m != ReplayRepair {
+		t.Fatalf("default: got=%q, %v want=%q", m, err, ReplayRepair)
+	}
+	if _, err := ParseReplayMode("lenient"); err != ErrInvalidReplayMode {
+		t.Fatalf("invalid mode: got=%v want=%v", err, ErrInvalidReplayMode)
+	}
+}
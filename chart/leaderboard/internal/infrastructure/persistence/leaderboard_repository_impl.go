@@ -3,65 +3,193 @@ package persistence
 import (
 	"leaderboard/internal/domain/model"
 	"leaderboard/internal/domain/repository"
+	"log"
 	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// AOFRewriteMinSize 是触发自动重写的 AOF 最小大小；
+// 超过该值且较上次重写后的大小翻倍时，会在后台重写日志。
+const AOFRewriteMinSize = 64 << 20
+
+// DefaultSnapshotInterval 是默认的定期快照间隔。
+const DefaultSnapshotInterval = 5 * time.Minute
+
+// Options 配置排行榜的持久化行为。
+type Options struct {
+	// SnapshotInterval 是定期快照的间隔，快照完成后会截断 AOF 日志；小于等于 0 表示不做定期快照。
+	SnapshotInterval time.Duration
+	// FsyncPolicy 是 AOF 写入的刷盘策略，为空时使用 everysec。
+	FsyncPolicy FsyncPolicy
+	// ReplayMode 决定启动回放遇到损坏记录时的处理方式，为空时使用 repair。
+	ReplayMode ReplayMode
+}
+
+// DefaultOptions 返回默认的持久化配置。
+func DefaultOptions() Options {
+	return Options{
+		SnapshotInterval: DefaultSnapshotInterval,
+		FsyncPolicy:      FsyncEverySec,
+		ReplayMode:       ReplayRepair,
+	}
+}
+
 // leaderboardRepositoryImpl 是 LeaderboardRepository 的实现。
 type leaderboardRepositoryImpl struct {
 	snapshotter *Snapshotter
 	aofLogger   *AOFLogger
+	replayMode  ReplayMode
+
+	lb             *model.Leaderboard // Load 得到的排行榜，用于后台重写与定期快照
+	rewriting      atomic.Bool
+	rewriteBase    atomic.Int64 // 上次重写或快照后的 AOF 大小
+	rewriteMinSize int64        // 触发自动重写的最小大小，默认为 AOFRewriteMinSize
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
 // NewLeaderboardRepository 创建一个新的 leaderboardRepositoryImpl。
-func NewLeaderboardRepository(dataDir string, id string) (*model.Leaderboard, repository.LeaderboardRepository, error) {
+func NewLeaderboardRepository(dataDir string, id string, opts Options) (*model.Leaderboard, repository.LeaderboardRepository, error) {
 	if err := os.MkdirAll(dataDir, 0755); err != nil {
 		return nil, nil, err
 	}
 
 	snapshotter := NewSnapshotter(dataDir + "/snapshot.gob")
-	aofLogger, err := NewAOFLogger(dataDir + "/aof.log")
+	aofLogger, err := NewAOFLogger(dataDir+"/aof.log", opts.FsyncPolicy)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	repo := &leaderboardRepositoryImpl{
-		snapshotter: snapshotter,
-		aofLogger:   aofLogger,
+		snapshotter:    snapshotter,
+		aofLogger:      aofLogger,
+		replayMode:     opts.ReplayMode,
+		rewriteMinSize: AOFRewriteMinSize,
 	}
 
 	lb, err := repo.Load(id)
 	if err != nil {
+		aofLogger.Close()
 		return nil, nil, err
 	}
+	repo.lb = lb
+
+	if opts.SnapshotInterval > 0 {
+		repo.stop = make(chan struct{})
+		repo.done = make(chan struct{})
+		go repo.snapshotLoop(opts.SnapshotInterval)
+	}
 
 	return lb, repo, nil
 }
 
-// Save 保存排行榜快照。
+// Save 保存排行榜快照，并截断快照已覆盖的 AOF 日志。
 func (r *leaderboardRepositoryImpl) Save(lb *model.Leaderboard) error {
-	return r.snapshotter.Save(lb)
+	err := r.aofLogger.Truncate(lb, func(players []*model.Player) error {
+		return r.snapshotter.save(lb, players)
+	})
+	if err != nil {
+		return err
+	}
+	r.rewriteBase.Store(r.aofLogger.Size())
+	return nil
+}
+
+// snapshotLoop 按固定间隔保存快照，自上次快照后没有新的更新时跳过。
+func (r *leaderboardRepositoryImpl) snapshotLoop(interval time.Duration) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if r.aofLogger.Size() == 0 {
+				continue
+			}
+			if err := r.Save(r.lb); err != nil && err != ErrAOFClosed {
+				log.Printf("leaderboard %s: snapshot failed: %v", r.lb.ID, err)
+			}
+		case <-r.stop:
+			return
+		}
+	}
 }
 
 // Load 加载排行榜。
 func (r *leaderboardRepositoryImpl) Load(id string) (*model.Leaderboard, error) {
 	lb, err := r.snapshotter.Load()
 	if err != nil {
-		// 如果快照不存在，则创建一个新的排行榜
-		if os.IsNotExist(err) {
-			return model.NewLeaderboard(id, "default"), nil
+		// 如果快照不存在，则创建一个新的排行榜，并继续回放已有的 AOF 日志
+		if !os.IsNotExist(err) {
+			return nil, err
 		}
-		return nil, err
+		lb = model.NewLeaderboard(id, id)
 	}
 
 	// 回放 AOF 日志
-	if err := r.aofLogger.Replay(lb); err != nil {
+	result, err := r.aofLogger.Replay(lb, r.replayMode)
+	if err != nil {
 		return nil, err
 	}
+	if result.Skipped > 0 || result.Truncated > 0 {
+		log.Printf("leaderboard %s: aof replay recovered %d entries, skipped %d corrupted, truncated %d bytes",
+			lb.ID, result.Applied, result.Skipped, result.Truncated)
+	}
 
 	return lb, nil
 }
 
 // LogUpdate 记录分数更新。
 func (r *leaderboardRepositoryImpl) LogUpdate(playerID int64, score int64) error {
-	return r.aofLogger.LogUpdate(playerID, score)
-}
\ No newline at end of file
+	if err := r.aofLogger.LogUpdate(playerID, score); err != nil {
+		return err
+	}
+	r.maybeRewrite()
+	return nil
+}
+
+// RewriteAOF 按排行榜当前状态重写 AOF 日志。
+func (r *leaderboardRepositoryImpl) RewriteAOF(lb *model.Leaderboard) error {
+	if err := r.aofLogger.Rewrite(lb); err != nil {
+		return err
+	}
+	r.rewriteBase.Store(r.aofLogger.Size())
+	return nil
+}
+
+// maybeRewrite 在 AOF 超过阈值时启动后台重写，同一时间最多一个。
+func (r *leaderboardRepositoryImpl) maybeRewrite() {
+	threshold := 2 * r.rewriteBase.Load()
+	if threshold < r.rewriteMinSize {
+		threshold = r.rewriteMinSize
+	}
+	if r.lb == nil || r.aofLogger.Size() < threshold {
+		return
+	}
+	if !r.rewriting.CompareAndSwap(false, true) {
+		return
+	}
+
+	go func() {
+		defer r.rewriting.Store(false)
+		if err := r.RewriteAOF(r.lb); err != nil && err != ErrAOFClosed {
+			log.Printf("leaderboard %s: aof rewrite failed: %v", r.lb.ID, err)
+		}
+	}()
+}
+
+// Close 停止定期快照并关闭底层的 AOF 日志文件。
+func (r *leaderboardRepositoryImpl) Close() error {
+	r.closeOnce.Do(func() {
+		if r.stop != nil {
+			close(r.stop)
+			<-r.done
+		}
+	})
+	return r.aofLogger.Close()
+}
@@ -0,0 +1,78 @@
+package persistence
+
+import (
+	"leaderboard/internal/domain/model"
+	"os"
+	"testing"
+	"time"
+)
+
+// updateAndLog 与 RankService.UpdateScore 一样更新排行榜并记录日志
+func updateAndLog(t *testing.T, lb *model.Leaderboard, repo *leaderboardRepositoryImpl, playerID, score int64) {
+	t.Helper()
+	lb.UpdateScore(playerID, score)
+	if err := repo.LogUpdate(playerID, score); err != nil {
+		t.Fatalf("log update: %v", err)
+	}
+}
+
+// 快照后截断 AOF，之后的更新只记录在 AOF 中；重启时快照与 AOF 合并恢复完整状态
+func TestSnapshotTruncateThenRestart(t *testing.T) {
+	dir := t.TempDir()
+	lb, repo := openTestRepo(t, dir, Options{})
+
+	for p := int64(1); p <= 100; p++ {
+		updateAndLog(t, lb, repo, p, p*10)
+	}
+	if err := repo.Save(lb); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if size := repo.aofLogger.Size(); size != 0 {
+		t.Fatalf("aof size after snapshot: got=%d want=0", size)
+	}
+
+	// 覆盖部分已在快照中的玩家，并加入新玩家
+	for p := int64(50); p <= 150; p++ {
+		updateAndLog(t, lb, repo, p, p*7)
+	}
+	if err := repo.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	replayed, repo2 := openTestRepo(t, dir, Options{})
+	defer repo2.Close()
+	if replayed.PlayerCount() != 150 {
+		t.Fatalf("players: got=%d want=150", replayed.PlayerCount())
+	}
+	assertSameScores(t, replayed, lb)
+}
+
+// 定期快照在后台截断 AOF，与并发写入交错后重启依然恢复完整状态
+func TestSnapshotLoopThenRestart(t *testing.T) {
+	dir := t.TempDir()
+	lb, repo := openTestRepo(t, dir, Options{SnapshotInterval: 5 * time.Millisecond})
+
+	concurrentUpdates(t, lb, repo, 4, 500)
+	deadline := time.Now().Add(5 * time.Second)
+	for repo.aofLogger.Size() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("periodic snapshot did not truncate the aof")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := os.Stat(dir + "/snapshot.gob"); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+
+	concurrentUpdates(t, lb, repo, 4, 500)
+	for p := int64(1000); p < 1010; p++ {
+		updateAndLog(t, lb, repo, p, p)
+	}
+	if err := repo.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	replayed, repo2 := openTestRepo(t, dir, Options{})
+	defer repo2.Close()
+	assertSameScores(t, replayed, lb)
+}
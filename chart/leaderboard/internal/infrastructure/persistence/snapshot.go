@@ -1,11 +1,108 @@
 package persistence
 
 import (
+	"bytes"
+	"encoding/binary"
 	"encoding/gob"
+	"errors"
+	"fmt"
 	"leaderboard/internal/domain/model"
+	"leaderboardcore"
 	"os"
+	"path/filepath"
+	"sync"
+	"time"
 )
 
+// SnapshotVersion 是当前写入的快照格式版本。修改 snapshot 结构时应递增该值，
+// 并通过 RegisterSnapshotMigration 注册从上一版本升级的迁移函数。
+const SnapshotVersion uint32 = 2
+
+// snapshotMagic 是带版本快照文件的文件头，其后紧跟 4 字节大端序的版本号。
+// 没有该文件头的快照为早期直接写入的 gob 数据，视为版本 0。
+var snapshotMagic = []byte("LBSNAP")
+
+// ErrUnsupportedSnapshotVersion 表示快照版本无法迁移到当前版本。
+var ErrUnsupportedSnapshotVersion = errors.New("unsupported snapshot version")
+
+// SnapshotMigration 将某一版本的快照负载升级为下一版本。
+type SnapshotMigration func(data []byte) ([]byte, error)
+
+var (
+	migrationsMu sync.RWMutex
+	// migrations[v] 将版本 v 的负载升级为版本 v+1。
+	migrations = map[uint32]SnapshotMigration{
+		// 版本 0 没有文件头，但负载与版本 1 同为 snapshot 的 gob 编码
+		// （更早只含 ID、Name 的数据也能直接解码），无需转换。
+		0: func(data []byte) ([]byte, error) { return data, nil },
+		// 版本 2 的玩家改用 leaderboardcore.Player，更新时间字段由 UpdatedAt 改名为 UpdateTime。
+		1: migrateV1PlayerUpdateTime,
+	}
+)
+
+// migrateV1PlayerUpdateTime 将版本 1 快照中玩家的 UpdatedAt 转写为版本 2 的 UpdateTime。
+func migrateV1PlayerUpdateTime(data []byte) ([]byte, error) {
+	var old struct {
+		ID      string
+		Name    string
+		Players []*struct {
+			ID        int64
+			Score     int64
+			UpdatedAt time.Time
+		}
+	}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&old); err != nil {
+		return nil, err
+	}
+
+	snap := snapshot{ID: old.ID, Name: old.Name, Players: make([]*model.Player, len(old.Players))}
+	for i, p := range old.Players {
+		snap.Players[i] = &model.Player{ID: p.ID, Score: p.Score, UpdateTime: p.UpdatedAt}
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&snap); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// RegisterSnapshotMigration 注册从 from 版本升级到 from+1 版本的迁移函数。
+func RegisterSnapshotMigration(from uint32, m SnapshotMigration) {
+	migrationsMu.Lock()
+	defer migrationsMu.Unlock()
+
+	migrations[from] = m
+}
+
+// migrate 将 version 版本的负载逐级升级到 SnapshotVersion。
+func migrate(version uint32, data []byte) ([]byte, error) {
+	if version > SnapshotVersion {
+		return nil, fmt.Errorf("%w: %d is newer than %d", ErrUnsupportedSnapshotVersion, version, SnapshotVersion)
+	}
+
+	migrationsMu.RLock()
+	defer migrationsMu.RUnlock()
+	for ; version < SnapshotVersion; version++ {
+		m, ok := migrations[version]
+		if !ok {
+			return nil, fmt.Errorf("%w: no migration from %d", ErrUnsupportedSnapshotVersion, version)
+		}
+		var err error
+		if data, err = m(data); err != nil {
+			return nil, fmt.Errorf("migrate snapshot from version %d: %w", version, err)
+		}
+	}
+	return data, nil
+}
+
+// snapshot 是快照文件中保存的排行榜数据。
+type snapshot struct {
+	ID      string
+	Name    string
+	Players []*model.Player
+}
+
 // Snapshotter 负责创建和加载排行榜快照。
 type Snapshotter struct {
 	filePath string
@@ -18,28 +115,63 @@ func NewSnapshotter(filePath string) *Snapshotter {
 
 // Save 创建排行榜的快照。
 func (s *Snapshotter) Save(lb *model.Leaderboard) error {
-	file, err := os.Create(s.filePath)
+	return s.save(lb, lb.Players())
+}
+
+// save 将排行榜及给定的玩家列表写入快照，先写临时文件再原子替换，避免留下半截快照。
+func (s *Snapshotter) save(lb *model.Leaderboard, players []*model.Player) error {
+	tmpPath := s.filePath + ".tmp"
+	file, err := os.Create(tmpPath)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
-	encoder := gob.NewEncoder(file)
-	return encoder.Encode(lb)
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], SnapshotVersion)
+	_, err = file.Write(append(append([]byte{}, snapshotMagic...), header[:]...))
+	if err == nil {
+		err = gob.NewEncoder(file).Encode(&snapshot{ID: lb.ID, Name: lb.Name, Players: players})
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, s.filePath)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	leaderboardcore.SyncDir(filepath.Dir(s.filePath))
+	return nil
 }
 
-// Load 从快照文件中加载排行榜。
+// Load 从快照文件中加载排行榜，旧版本的快照会先迁移到当前版本。
 func (s *Snapshotter) Load() (*model.Leaderboard, error) {
-	file, err := os.Open(s.filePath)
+	data, err := os.ReadFile(s.filePath)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
 
-	decoder := gob.NewDecoder(file)
-	var lb model.Leaderboard
-	if err := decoder.Decode(&lb); err != nil {
+	var version uint32
+	headerLen := len(snapshotMagic) + 4
+	if len(data) >= headerLen && bytes.HasPrefix(data, snapshotMagic) {
+		version = binary.BigEndian.Uint32(data[len(snapshotMagic):headerLen])
+		data = data[headerLen:]
+	}
+	if data, err = migrate(version, data); err != nil {
 		return nil, err
 	}
-	return &lb, nil
-}
\ No newline at end of file
+
+	var snap snapshot
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&snap); err != nil {
+		return nil, err
+	}
+	// gob 只会还原导出字段，这里重新构造以初始化内部的玩家索引与跳表
+	lb := model.NewLeaderboard(snap.ID, snap.Name)
+	lb.Restore(snap.Players)
+	return lb, nil
+}
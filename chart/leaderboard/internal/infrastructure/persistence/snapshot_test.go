@@ -0,0 +1,115 @@
+package persistence
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// testdata 中的快照由旧版本代码写出：
+//   - snapshot_v0_nameonly.gob：最早直接编码 Leaderboard，只有 ID 与 Name
+//   - snapshot_v0.gob：没有文件头的 snapshot，玩家更新时间字段为 UpdatedAt
+//   - snapshot_v1.gob：带版本 1 文件头的 snapshot，玩家更新时间字段为 UpdatedAt
+//
+// 三个文件的排行榜均为 weekly/Weekly，后两者含玩家 1、2、3，分数 300、200、100，
+// 更新时间从 2024-05-01 12:00 UTC 起每人递增一分钟。
+
+// loadFixture 将 testdata 中的快照复制到临时目录并加载
+func loadFixture(t *testing.T, name string) *Snapshotter {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	path := t.TempDir() + "/snapshot.gob"
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("write snapshot: %v", err)
+	}
+	return NewSnapshotter(path)
+}
+
+// 旧格式的快照迁移后加载为当前的排行榜模型，玩家的更新时间保留不变
+func TestSnapshotLoadOldVersions(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, name := range []string{"snapshot_v0.gob", "snapshot_v1.gob"} {
+		t.Run(name, func(t *testing.T) {
+			lb, err := loadFixture(t, name).Load()
+			if err != nil {
+				t.Fatalf("load: %v", err)
+			}
+			if lb.ID != "weekly" || lb.Name != "Weekly" {
+				t.Fatalf("leaderboard: got=%s/%s want=weekly/Weekly", lb.ID, lb.Name)
+			}
+			players := lb.Players()
+			if len(players) != 3 {
+				t.Fatalf("players: got=%d want=3", len(players))
+			}
+			for i, p := range players {
+				wantID, wantScore := int64(i+1), int64(300-100*i)
+				wantTime := base.Add(time.Duration(i) * time.Minute)
+				if p.ID != wantID || p.Score != wantScore || !p.UpdateTime.Equal(wantTime) {
+					t.Fatalf("player at %d: got=%d/%d/%v want=%d/%d/%v", i, p.ID, p.Score, p.UpdateTime, wantID, wantScore, wantTime)
+				}
+			}
+			if rank, err := lb.GetPlayerRank(2); err != nil || rank != 2 {
+				t.Fatalf("rank of player 2: got=%d, %v want=2", rank, err)
+			}
+		})
+	}
+}
+
+// 最早只含 ID 与 Name 的快照加载为空排行榜
+func TestSnapshotLoadNameOnly(t *testing.T) {
+	lb, err := loadFixture(t, "snapshot_v0_nameonly.gob").Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if lb.ID != "weekly" || lb.Name != "Weekly" || lb.PlayerCount() != 0 {
+		t.Fatalf("leaderboard: got=%s/%s players=%d want=weekly/Weekly players=0", lb.ID, lb.Name, lb.PlayerCount())
+	}
+}
+
+// 加载旧快照后再保存，写出的是当前版本并可以原样读回
+func TestSnapshotResaveAsCurrentVersion(t *testing.T) {
+	s := loadFixture(t, "snapshot_v1.gob")
+	lb, err := s.Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if err := s.Save(lb); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	data, err := os.ReadFile(s.filePath)
+	if err != nil {
+		t.Fatalf("read snapshot: %v", err)
+	}
+	if !bytes.HasPrefix(data, snapshotMagic) {
+		t.Fatalf("snapshot has no header: %q", data[:len(snapshotMagic)])
+	}
+	if v := binary.BigEndian.Uint32(data[len(snapshotMagic):]); v != SnapshotVersion {
+		t.Fatalf("version: got=%d want=%d", v, SnapshotVersion)
+	}
+
+	reloaded, err := s.Load()
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	assertSameScores(t, reloaded, lb)
+}
+
+// 比当前版本新的快照无法加载
+func TestSnapshotNewerVersion(t *testing.T) {
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], SnapshotVersion+1)
+	path := t.TempDir() + "/snapshot.gob"
+	if err := os.WriteFile(path, append(append([]byte{}, snapshotMagic...), header[:]...), 0644); err != nil {
+		t.Fatalf("write snapshot: %v", err)
+	}
+	if _, err := NewSnapshotter(path).Load(); !errors.Is(err, ErrUnsupportedSnapshotVersion) {
+		t.Fatalf("load: got=%v want=%v", err, ErrUnsupportedSnapshotVersion)
+	}
+}
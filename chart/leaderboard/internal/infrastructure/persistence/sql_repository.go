@@ -0,0 +1,228 @@
+package persistence
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"leaderboard/internal/domain/model"
+	"leaderboard/internal/domain/repository"
+	"time"
+)
+
+// SQLDialect 表示 SQL 存储所使用的数据库方言。
+type SQLDialect string
+
+const (
+	DialectPostgres SQLDialect = "postgres"
+	DialectMySQL    SQLDialect = "mysql"
+)
+
+// ErrUnknownSQLDialect 表示不支持的数据库方言。
+var ErrUnknownSQLDialect = errors.New("unknown sql dialect")
+
+// placeholder 返回第 n 个（从 1 开始）参数的占位符。
+func (d SQLDialect) placeholder(n int) string {
+	if d == DialectPostgres {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+// upsertScoreSQL 返回写入或覆盖一条玩家分数的语句。
+func (d SQLDialect) upsertScoreSQL() string {
+	insert := fmt.Sprintf("INSERT INTO leaderboard_scores (leaderboard_id, player_id, score, updated_at) VALUES (%s, %s, %s, %s)",
+		d.placeholder(1), d.placeholder(2), d.placeholder(3), d.placeholder(4))
+	if d == DialectMySQL {
+		return insert + " ON DUPLICATE KEY UPDATE score = VALUES(score), updated_at = VALUES(updated_at)"
+	}
+	return insert + " ON CONFLICT (leaderboard_id, player_id) DO UPDATE SET score = EXCLUDED.score, updated_at = EXCLUDED.updated_at"
+}
+
+// upsertLeaderboardSQL 返回写入或覆盖排行榜信息的语句。
+func (d SQLDialect) upsertLeaderboardSQL() string {
+	insert := fmt.Sprintf("INSERT INTO leaderboards (id, name) VALUES (%s, %s)", d.placeholder(1), d.placeholder(2))
+	if d == DialectMySQL {
+		return insert + " ON DUPLICATE KEY UPDATE name = VALUES(name)"
+	}
+	return insert + " ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name"
+}
+
+// sqlSchema 是 SQL 存储的表结构，两种方言通用。
+// leaderboard_scores 每个玩家一行，可直接供 BI 工具按分数查询。
+var sqlSchema = []string{
+	`CREATE TABLE IF NOT EXISTS leaderboards (
+		id   VARCHAR(255) NOT NULL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS leaderboard_scores (
+		leaderboard_id VARCHAR(255) NOT NULL,
+		player_id      BIGINT       NOT NULL,
+		score          BIGINT       NOT NULL,
+		updated_at     TIMESTAMP    NOT NULL,
+		PRIMARY KEY (leaderboard_id, player_id)
+	)`,
+}
+
+// CreateSQLSchema 在数据库中创建 SQL 存储所需的表（已存在时跳过）。
+func CreateSQLSchema(db *sql.DB) error {
+	for _, stmt := range sqlSchema {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SQLRepository 是基于 Postgres / MySQL 的 LeaderboardRepository 实现。
+// 每次分数更新直接写入对应玩家的一行，数据不依赖本机磁盘。
+type SQLRepository struct {
+	db      *sql.DB
+	dialect SQLDialect
+	id      string
+}
+
+// NewSQLRepository 创建排行榜 id 的 SQL 存储并加载其当前状态。db 由调用方管理，Close 不会关闭它。
+func NewSQLRepository(db *sql.DB, dialect SQLDialect, id string) (*model.Leaderboard, *SQLRepository, error) {
+	if dialect != DialectPostgres && dialect != DialectMySQL {
+		return nil, nil, ErrUnknownSQLDialect
+	}
+
+	repo := &SQLRepository{db: db, dialect: dialect, id: id}
+	lb, err := repo.Load(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return lb, repo, nil
+}
+
+// Save 在一个事务中写入排行榜信息及全部玩家分数。
+func (r *SQLRepository) Save(lb *model.Leaderboard) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(r.dialect.upsertLeaderboardSQL(), lb.ID, lb.Name); err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(r.dialect.upsertScoreSQL())
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, p := range lb.Players() {
+		if _, err := stmt.Exec(lb.ID, p.ID, p.Score, p.UpdateTime.UTC()); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Load 从数据库加载排行榜，不存在时返回一个新的排行榜。
+func (r *SQLRepository) Load(id string) (*model.Leaderboard, error) {
+	lb := model.NewLeaderboard(id, id)
+	err := r.db.QueryRow("SELECT name FROM leaderboards WHERE id = "+r.dialect.placeholder(1), id).Scan(&lb.Name)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	rows, err := r.db.Query("SELECT player_id, score, updated_at FROM leaderboard_scores WHERE leaderboard_id = "+r.dialect.placeholder(1), id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var players []*model.Player
+	for rows.Next() {
+		var p model.Player
+		if err := rows.Scan(&p.ID, &p.Score, &p.UpdateTime); err != nil {
+			return nil, err
+		}
+		players = append(players, &p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	lb.Restore(players)
+	return lb, nil
+}
+
+// LogUpdate 将一次分数更新写入对应玩家的行。
+func (r *SQLRepository) LogUpdate(playerID int64, score int64) error {
+	_, err := r.db.Exec(r.dialect.upsertScoreSQL(), r.id, playerID, score, time.Now().UTC())
+	return err
+}
+
+// RewriteAOF 对 SQL 存储无意义：每个玩家始终只有一行，没有需要压缩的日志。
+func (r *SQLRepository) RewriteAOF(*model.Leaderboard) error {
+	return nil
+}
+
+// Close 对 SQL 存储无需释放资源，数据库连接由调用方关闭。
+func (r *SQLRepository) Close() error {
+	return nil
+}
+
+// sqlStore 是基于 SQL 的 LeaderboardStore 实现，所有排行榜共用同一组表。
+type sqlStore struct {
+	db      *sql.DB
+	dialect SQLDialect
+}
+
+// NewSQLStore 创建基于 SQL 的多排行榜存储，并确保表结构存在。
+func NewSQLStore(db *sql.DB, dialect SQLDialect) (repository.LeaderboardStore, error) {
+	if dialect != DialectPostgres && dialect != DialectMySQL {
+		return nil, ErrUnknownSQLDialect
+	}
+	if err := CreateSQLSchema(db); err != nil {
+		return nil, err
+	}
+	return &sqlStore{db: db, dialect: dialect}, nil
+}
+
+// Open 打开指定排行榜的 SQL 存储并加载排行榜。
+func (s *sqlStore) Open(id string) (*model.Leaderboard, repository.LeaderboardRepository, error) {
+	if id == "" {
+		return nil, nil, ErrInvalidLeaderboardID
+	}
+	return NewSQLRepository(s.db, s.dialect, id)
+}
+
+// List 列出数据库中所有排行榜的 ID。
+func (s *sqlStore) List() ([]string, error) {
+	rows, err := s.db.Query("SELECT id FROM leaderboards ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Remove 删除指定排行榜及其全部玩家分数。
+func (s *sqlStore) Remove(id string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM leaderboard_scores WHERE leaderboard_id = "+s.dialect.placeholder(1), id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM leaderboards WHERE id = "+s.dialect.placeholder(1), id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
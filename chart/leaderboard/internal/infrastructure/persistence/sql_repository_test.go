@@ -0,0 +1,202 @@
+package persistence
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// newMockDB 创建按语句原文精确匹配的 sqlmock 数据库，测试结束时检查所有预期都已满足
+func newMockDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("expectations: %v", err)
+		}
+		db.Close()
+	})
+	return db, mock
+}
+
+// expectLoad 预期一次 Load 查询，name 为空表示排行榜尚不存在
+func expectLoad(mock sqlmock.Sqlmock, d SQLDialect, id, name string, scores *sqlmock.Rows) {
+	nameRows := sqlmock.NewRows([]string{"name"})
+	if name != "" {
+		nameRows.AddRow(name)
+	}
+	mock.ExpectQuery("SELECT name FROM leaderboards WHERE id = " + d.placeholder(1)).WithArgs(id).WillReturnRows(nameRows)
+	mock.ExpectQuery("SELECT player_id, score, updated_at FROM leaderboard_scores WHERE leaderboard_id = " + d.placeholder(1)).
+		WithArgs(id).WillReturnRows(scores)
+}
+
+// 加载时读取排行榜名称与全部玩家，保留数据库中的更新时间
+func TestSQLRepositoryLoad(t *testing.T) {
+	db, mock := newMockDB(t)
+	updated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	expectLoad(mock, DialectPostgres, "weekly", "Weekly", sqlmock.NewRows([]string{"player_id", "score", "updated_at"}).
+		AddRow(1, 100, updated).
+		AddRow(2, 300, updated).
+		AddRow(3, 200, updated))
+
+	lb, _, err := NewSQLRepository(db, DialectPostgres, "weekly")
+	if err != nil {
+		t.Fatalf("new repository: %v", err)
+	}
+	if lb.ID != "weekly" || lb.Name != "Weekly" {
+		t.Fatalf("leaderboard: got=%s/%s want=weekly/Weekly", lb.ID, lb.Name)
+	}
+	players := lb.Players()
+	if len(players) != 3 {
+		t.Fatalf("players: got=%d want=3", len(players))
+	}
+	for i, wantID := range []int64{2, 3, 1} {
+		if players[i].ID != wantID || !players[i].UpdateTime.Equal(updated) {
+			t.Fatalf("player at %d: got=%d/%v want=%d/%v", i, players[i].ID, players[i].UpdateTime, wantID, updated)
+		}
+	}
+}
+
+// 数据库中没有的排行榜加载为以 ID 命名的空排行榜
+func TestSQLRepositoryLoadMissing(t *testing.T) {
+	db, mock := newMockDB(t)
+	expectLoad(mock, DialectMySQL, "new", "", sqlmock.NewRows([]string{"player_id", "score", "updated_at"}))
+
+	lb, _, err := NewSQLRepository(db, DialectMySQL, "new")
+	if err != nil {
+		t.Fatalf("new repository: %v", err)
+	}
+	if lb.Name != "new" || lb.PlayerCount() != 0 {
+		t.Fatalf("leaderboard: name=%s players=%d want name=new players=0", lb.Name, lb.PlayerCount())
+	}
+}
+
+// Save 在一个事务中写入排行榜信息与所有玩家，两种方言使用各自的 upsert 语句
+func TestSQLRepositorySave(t *testing.T) {
+	for _, d := range []SQLDialect{DialectPostgres, DialectMySQL} {
+		t.Run(string(d), func(t *testing.T) {
+			db, mock := newMockDB(t)
+			expectLoad(mock, d, "weekly", "", sqlmock.NewRows([]string{"player_id", "score", "updated_at"}))
+			lb, repo, err := NewSQLRepository(db, d, "weekly")
+			if err != nil {
+				t.Fatalf("new repository: %v", err)
+			}
+			lb.Name = "Weekly"
+			lb.UpdateScore(1, 100)
+			lb.UpdateScore(2, 200)
+
+			mock.ExpectBegin()
+			mock.ExpectExec(d.upsertLeaderboardSQL()).WithArgs("weekly", "Weekly").WillReturnResult(sqlmock.NewResult(0, 1))
+			prep := mock.ExpectPrepare(d.upsertScoreSQL())
+			prep.ExpectExec().WithArgs("weekly", int64(2), int64(200), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+			prep.ExpectExec().WithArgs("weekly", int64(1), int64(100), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			if err := repo.Save(lb); err != nil {
+				t.Fatalf("save: %v", err)
+			}
+		})
+	}
+}
+
+// 写入失败时回滚事务并返回错误
+func TestSQLRepositorySaveRollback(t *testing.T) {
+	db, mock := newMockDB(t)
+	expectLoad(mock, DialectPostgres, "weekly", "", sqlmock.NewRows([]string{"player_id", "score", "updated_at"}))
+	lb, repo, err := NewSQLRepository(db, DialectPostgres, "weekly")
+	if err != nil {
+		t.Fatalf("new repository: %v", err)
+	}
+	lb.UpdateScore(1, 100)
+
+	failure := errors.New("disk full")
+	mock.ExpectBegin()
+	mock.ExpectExec(DialectPostgres.upsertLeaderboardSQL()).WithArgs("weekly", "weekly").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare(DialectPostgres.upsertScoreSQL()).
+		ExpectExec().WithArgs("weekly", int64(1), int64(100), sqlmock.AnyArg()).WillReturnError(failure)
+	mock.ExpectRollback()
+
+	if err := repo.Save(lb); !errors.Is(err, failure) {
+		t.Fatalf("save: got=%v want=%v", err, failure)
+	}
+}
+
+// afterTime 匹配不早于给定时间的 UTC 时间参数
+type afterTime struct {
+	t time.Time
+}
+
+func (a afterTime) Match(v driver.Value) bool {
+	tm, ok := v.(time.Time)
+	return ok && tm.Location() == time.UTC && !tm.Before(a.t)
+}
+
+// LogUpdate 只写入被更新玩家的一行
+func TestSQLRepositoryLogUpdate(t *testing.T) {
+	db, mock := newMockDB(t)
+	expectLoad(mock, DialectMySQL, "weekly", "Weekly", sqlmock.NewRows([]string{"player_id", "score", "updated_at"}))
+	_, repo, err := NewSQLRepository(db, DialectMySQL, "weekly")
+	if err != nil {
+		t.Fatalf("new repository: %v", err)
+	}
+
+	before := time.Now().UTC()
+	mock.ExpectExec(DialectMySQL.upsertScoreSQL()).
+		WithArgs("weekly", int64(7), int64(700), afterTime{before}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := repo.LogUpdate(7, 700); err != nil {
+		t.Fatalf("log update: %v", err)
+	}
+	if err := repo.RewriteAOF(nil); err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+}
+
+// 不支持的方言被拒绝，不会访问数据库
+func TestSQLRepositoryUnknownDialect(t *testing.T) {
+	db, _ := newMockDB(t)
+	if _, _, err := NewSQLRepository(db, "sqlite", "weekly"); err != ErrUnknownSQLDialect {
+		t.Fatalf("new repository: got=%v want=%v", err, ErrUnknownSQLDialect)
+	}
+	if _, err := NewSQLStore(db, "sqlite"); err != ErrUnknownSQLDialect {
+		t.Fatalf("new store: got=%v want=%v", err, ErrUnknownSQLDialect)
+	}
+}
+
+// SQL 存储创建表结构，列出与删除排行榜
+func TestSQLStore(t *testing.T) {
+	db, mock := newMockDB(t)
+	for _, stmt := range sqlSchema {
+		mock.ExpectExec(stmt).WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	store, err := NewSQLStore(db, DialectPostgres)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+
+	mock.ExpectQuery("SELECT id FROM leaderboards ORDER BY id").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("daily").AddRow("weekly"))
+	ids, err := store.List()
+	if err != nil || len(ids) != 2 || ids[0] != "daily" || ids[1] != "weekly" {
+		t.Fatalf("list: got=%v, %v want=[daily weekly]", ids, err)
+	}
+
+	if _, _, err := store.Open(""); err != ErrInvalidLeaderboardID {
+		t.Fatalf("open empty id: got=%v want=%v", err, ErrInvalidLeaderboardID)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM leaderboard_scores WHERE leaderboard_id = $1").WithArgs("daily").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("DELETE FROM leaderboards WHERE id = $1").WithArgs("daily").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := store.Remove("daily"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+}
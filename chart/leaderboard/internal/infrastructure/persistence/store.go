@@ -0,0 +1,69 @@
+package persistence
+
+import (
+	"errors"
+	"leaderboard/internal/domain/model"
+	"leaderboard/internal/domain/repository"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidLeaderboardID 表示排行榜 ID 无法安全地用作目录名。
+var ErrInvalidLeaderboardID = errors.New("invalid leaderboard id")
+
+// leaderboardStoreImpl 是 LeaderboardStore 的实现，每个排行榜占用 dataDir 下的一个子目录。
+type leaderboardStoreImpl struct {
+	dataDir string
+	opts    Options
+}
+
+// NewLeaderboardStore 创建一个新的多排行榜存储。
+func NewLeaderboardStore(dataDir string, opts Options) (repository.LeaderboardStore, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, err
+	}
+	return &leaderboardStoreImpl{dataDir: dataDir, opts: opts}, nil
+}
+
+// Open 打开指定排行榜的存储目录并加载排行榜。
+func (s *leaderboardStoreImpl) Open(id string) (*model.Leaderboard, repository.LeaderboardRepository, error) {
+	dir, err := s.dir(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return NewLeaderboardRepository(dir, id, s.opts)
+}
+
+// List 列出数据目录下所有排行榜的 ID。
+func (s *leaderboardStoreImpl) List() ([]string, error) {
+	entries, err := os.ReadDir(s.dataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			ids = append(ids, entry.Name())
+		}
+	}
+	return ids, nil
+}
+
+// Remove 删除指定排行榜的存储目录。
+func (s *leaderboardStoreImpl) Remove(id string) error {
+	dir, err := s.dir(id)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// dir 返回排行榜对应的存储目录，拒绝可能逃逸出数据目录的 ID。
+func (s *leaderboardStoreImpl) dir(id string) (string, error) {
+	if id == "" || id == "." || id == ".." || strings.ContainsAny(id, `/\`) {
+		return "", ErrInvalidLeaderboardID
+	}
+	return filepath.Join(s.dataDir, id), nil
+}
@@ -0,0 +1,78 @@
+package persistence
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 可能逃逸出数据目录的 ID 在 Open 与 Remove 时都被拒绝
+func TestStoreRejectsPathLikeIDs(t *testing.T) {
+	root := t.TempDir()
+	victim := filepath.Join(root, "x")
+	if err := os.MkdirAll(filepath.Join(victim, "keep"), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	store, err := NewLeaderboardStore(filepath.Join(root, "data"), Options{})
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+
+	for _, id := range []string{"../x", "..", ".", "", "a/b", `..\x`, "/abs"} {
+		if _, _, err := store.Open(id); err != ErrInvalidLeaderboardID {
+			t.Fatalf("open %q: got=%v want=%v", id, err, ErrInvalidLeaderboardID)
+		}
+		if err := store.Remove(id); err != ErrInvalidLeaderboardID {
+			t.Fatalf("remove %q: got=%v want=%v", id, err, ErrInvalidLeaderboardID)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(victim, "keep")); err != nil {
+		t.Fatalf("directory outside the data dir was touched: %v", err)
+	}
+	if ids, err := store.List(); err != nil || len(ids) != 0 {
+		t.Fatalf("list: got=%v, %v want none", ids, err)
+	}
+}
+
+// List 只返回排行榜目录，Remove 删除排行榜的全部数据
+func TestStoreOpenListRemove(t *testing.T) {
+	dataDir := t.TempDir()
+	store, err := NewLeaderboardStore(dataDir, Options{})
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	for _, id := range []string{"b", "a"} {
+		lb, repo, err := store.Open(id)
+		if err != nil {
+			t.Fatalf("open %s: %v", id, err)
+		}
+		lb.UpdateScore(1, 10)
+		if err := repo.LogUpdate(1, 10); err != nil {
+			t.Fatalf("log update: %v", err)
+		}
+		repo.Close()
+	}
+	if err := os.WriteFile(filepath.Join(dataDir, "README"), nil, 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	ids, err := store.List()
+	if err != nil || len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+		t.Fatalf("list: got=%v, %v want=[a b]", ids, err)
+	}
+	if err := store.Remove("a"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if ids, _ := store.List(); len(ids) != 1 || ids[0] != "b" {
+		t.Fatalf("list after remove: got=%v want=[b]", ids)
+	}
+
+	lb, repo, err := store.Open("a")
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer repo.Close()
+	if lb.PlayerCount() != 0 {
+		t.Fatalf("players after remove: got=%d want=0", lb.PlayerCount())
+	}
+}
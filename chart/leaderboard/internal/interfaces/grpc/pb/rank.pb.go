@@ -0,0 +1,543 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: rank.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type UpdateScoreRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	LeaderboardId string                 `protobuf:"bytes,1,opt,name=leaderboard_id,json=leaderboardId,proto3" json:"leaderboard_id,omitempty"`
+	PlayerId      int64                  `protobuf:"varint,2,opt,name=player_id,json=playerId,proto3" json:"player_id,omitempty"`
+	Score         int64                  `protobuf:"varint,3,opt,name=score,proto3" json:"score,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateScoreRequest) Reset() {
+	*x = UpdateScoreRequest{}
+	mi := &file_rank_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateScoreRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateScoreRequest) ProtoMessage() {}
+
+func (x *UpdateScoreRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rank_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateScoreRequest.ProtoReflect.Descriptor instead.
+func (*UpdateScoreRequest) Descriptor() ([]byte, []int) {
+	return file_rank_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *UpdateScoreRequest) GetLeaderboardId() string {
+	if x != nil {
+		return x.LeaderboardId
+	}
+	return ""
+}
+
+func (x *UpdateScoreRequest) GetPlayerId() int64 {
+	if x != nil {
+		return x.PlayerId
+	}
+	return 0
+}
+
+func (x *UpdateScoreRequest) GetScore() int64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+type UpdateScoreResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateScoreResponse) Reset() {
+	*x = UpdateScoreResponse{}
+	mi := &file_rank_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateScoreResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateScoreResponse) ProtoMessage() {}
+
+func (x *UpdateScoreResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rank_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateScoreResponse.ProtoReflect.Descriptor instead.
+func (*UpdateScoreResponse) Descriptor() ([]byte, []int) {
+	return file_rank_proto_rawDescGZIP(), []int{1}
+}
+
+type GetRankRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	LeaderboardId string                 `protobuf:"bytes,1,opt,name=leaderboard_id,json=leaderboardId,proto3" json:"leaderboard_id,omitempty"`
+	PlayerId      int64                  `protobuf:"varint,2,opt,name=player_id,json=playerId,proto3" json:"player_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRankRequest) Reset() {
+	*x = GetRankRequest{}
+	mi := &file_rank_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRankRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRankRequest) ProtoMessage() {}
+
+func (x *GetRankRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rank_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRankRequest.ProtoReflect.Descriptor instead.
+func (*GetRankRequest) Descriptor() ([]byte, []int) {
+	return file_rank_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetRankRequest) GetLeaderboardId() string {
+	if x != nil {
+		return x.LeaderboardId
+	}
+	return ""
+}
+
+func (x *GetRankRequest) GetPlayerId() int64 {
+	if x != nil {
+		return x.PlayerId
+	}
+	return 0
+}
+
+type GetRankResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rank          int64                  `protobuf:"varint,1,opt,name=rank,proto3" json:"rank,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRankResponse) Reset() {
+	*x = GetRankResponse{}
+	mi := &file_rank_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRankResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRankResponse) ProtoMessage() {}
+
+func (x *GetRankResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rank_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRankResponse.ProtoReflect.Descriptor instead.
+func (*GetRankResponse) Descriptor() ([]byte, []int) {
+	return file_rank_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetRankResponse) GetRank() int64 {
+	if x != nil {
+		return x.Rank
+	}
+	return 0
+}
+
+type GetTopNRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	LeaderboardId string                 `protobuf:"bytes,1,opt,name=leaderboard_id,json=leaderboardId,proto3" json:"leaderboard_id,omitempty"`
+	N             int32                  `protobuf:"varint,2,opt,name=n,proto3" json:"n,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTopNRequest) Reset() {
+	*x = GetTopNRequest{}
+	mi := &file_rank_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTopNRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTopNRequest) ProtoMessage() {}
+
+func (x *GetTopNRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rank_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTopNRequest.ProtoReflect.Descriptor instead.
+func (*GetTopNRequest) Descriptor() ([]byte, []int) {
+	return file_rank_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetTopNRequest) GetLeaderboardId() string {
+	if x != nil {
+		return x.LeaderboardId
+	}
+	return ""
+}
+
+func (x *GetTopNRequest) GetN() int32 {
+	if x != nil {
+		return x.N
+	}
+	return 0
+}
+
+type GetNearbyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	LeaderboardId string                 `protobuf:"bytes,1,opt,name=leaderboard_id,json=leaderboardId,proto3" json:"leaderboard_id,omitempty"`
+	PlayerId      int64                  `protobuf:"varint,2,opt,name=player_id,json=playerId,proto3" json:"player_id,omitempty"`
+	Count         int32                  `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetNearbyRequest) Reset() {
+	*x = GetNearbyRequest{}
+	mi := &file_rank_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetNearbyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetNearbyRequest) ProtoMessage() {}
+
+func (x *GetNearbyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rank_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetNearbyRequest.ProtoReflect.Descriptor instead.
+func (*GetNearbyRequest) Descriptor() ([]byte, []int) {
+	return file_rank_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetNearbyRequest) GetLeaderboardId() string {
+	if x != nil {
+		return x.LeaderboardId
+	}
+	return ""
+}
+
+func (x *GetNearbyRequest) GetPlayerId() int64 {
+	if x != nil {
+		return x.PlayerId
+	}
+	return 0
+}
+
+func (x *GetNearbyRequest) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+// RankedPlayer 是带排名的玩家信息。
+type RankedPlayer struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Score         int64                  `protobuf:"varint,2,opt,name=score,proto3" json:"score,omitempty"`
+	Rank          int64                  `protobuf:"varint,3,opt,name=rank,proto3" json:"rank,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RankedPlayer) Reset() {
+	*x = RankedPlayer{}
+	mi := &file_rank_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RankedPlayer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RankedPlayer) ProtoMessage() {}
+
+func (x *RankedPlayer) ProtoReflect() protoreflect.Message {
+	mi := &file_rank_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RankedPlayer.ProtoReflect.Descriptor instead.
+func (*RankedPlayer) Descriptor() ([]byte, []int) {
+	return file_rank_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *RankedPlayer) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *RankedPlayer) GetScore() int64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *RankedPlayer) GetRank() int64 {
+	if x != nil {
+		return x.Rank
+	}
+	return 0
+}
+
+func (x *RankedPlayer) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type PlayersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Players       []*RankedPlayer        `protobuf:"bytes,1,rep,name=players,proto3" json:"players,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PlayersResponse) Reset() {
+	*x = PlayersResponse{}
+	mi := &file_rank_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PlayersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PlayersResponse) ProtoMessage() {}
+
+func (x *PlayersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rank_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PlayersResponse.ProtoReflect.Descriptor instead.
+func (*PlayersResponse) Descriptor() ([]byte, []int) {
+	return file_rank_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *PlayersResponse) GetPlayers() []*RankedPlayer {
+	if x != nil {
+		return x.Players
+	}
+	return nil
+}
+
+var File_rank_proto protoreflect.FileDescriptor
+
+const file_rank_proto_rawDesc = "" +
+	"\n" +
+	"\n" +
+	"rank.proto\x12\x0eleaderboard.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"n\n" +
+	"\x12UpdateScoreRequest\x12%\n" +
+	"\x0eleaderboard_id\x18\x01 \x01(\tR\rleaderboardId\x12\x1b\n" +
+	"\tplayer_id\x18\x02 \x01(\x03R\bplayerId\x12\x14\n" +
+	"\x05score\x18\x03 \x01(\x03R\x05score\"\x15\n" +
+	"\x13UpdateScoreResponse\"T\n" +
+	"\x0eGetRankRequest\x12%\n" +
+	"\x0eleaderboard_id\x18\x01 \x01(\tR\rleaderboardId\x12\x1b\n" +
+	"\tplayer_id\x18\x02 \x01(\x03R\bplayerId\"%\n" +
+	"\x0fGetRankResponse\x12\x12\n" +
+	"\x04rank\x18\x01 \x01(\x03R\x04rank\"E\n" +
+	"\x0eGetTopNRequest\x12%\n" +
+	"\x0eleaderboard_id\x18\x01 \x01(\tR\rleaderboardId\x12\f\n" +
+	"\x01n\x18\x02 \x01(\x05R\x01n\"l\n" +
+	"\x10GetNearbyRequest\x12%\n" +
+	"\x0eleaderboard_id\x18\x01 \x01(\tR\rleaderboardId\x12\x1b\n" +
+	"\tplayer_id\x18\x02 \x01(\x03R\bplayerId\x12\x14\n" +
+	"\x05count\x18\x03 \x01(\x05R\x05count\"\x83\x01\n" +
+	"\fRankedPlayer\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x14\n" +
+	"\x05score\x18\x02 \x01(\x03R\x05score\x12\x12\n" +
+	"\x04rank\x18\x03 \x01(\x03R\x04rank\x129\n" +
+	"\n" +
+	"updated_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"I\n" +
+	"\x0fPlayersResponse\x126\n" +
+	"\aplayers\x18\x01 \x03(\v2\x1c.leaderboard.v1.RankedPlayerR\aplayers2\xcd\x02\n" +
+	"\vRankService\x12V\n" +
+	"\vUpdateScore\x12\".leaderboard.v1.UpdateScoreRequest\x1a#.leaderboard.v1.UpdateScoreResponse\x12J\n" +
+	"\aGetRank\x12\x1e.leaderboard.v1.GetRankRequest\x1a\x1f.leaderboard.v1.GetRankResponse\x12J\n" +
+	"\aGetTopN\x12\x1e.leaderboard.v1.GetTopNRequest\x1a\x1f.leaderboard.v1.PlayersResponse\x12N\n" +
+	"\tGetNearby\x12 .leaderboard.v1.GetNearbyRequest\x1a\x1f.leaderboard.v1.PlayersResponseB,Z*leaderboard/internal/interfaces/grpc/pb;pbb\x06proto3"
+
+var (
+	file_rank_proto_rawDescOnce sync.Once
+	file_rank_proto_rawDescData []byte
+)
+
+func file_rank_proto_rawDescGZIP() []byte {
+	file_rank_proto_rawDescOnce.Do(func() {
+		file_rank_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_rank_proto_rawDesc), len(file_rank_proto_rawDesc)))
+	})
+	return file_rank_proto_rawDescData
+}
+
+var file_rank_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_rank_proto_goTypes = []any{
+	(*UpdateScoreRequest)(nil),    // 0: leaderboard.v1.UpdateScoreRequest
+	(*UpdateScoreResponse)(nil),   // 1: leaderboard.v1.UpdateScoreResponse
+	(*GetRankRequest)(nil),        // 2: leaderboard.v1.GetRankRequest
+	(*GetRankResponse)(nil),       // 3: leaderboard.v1.GetRankResponse
+	(*GetTopNRequest)(nil),        // 4: leaderboard.v1.GetTopNRequest
+	(*GetNearbyRequest)(nil),      // 5: leaderboard.v1.GetNearbyRequest
+	(*RankedPlayer)(nil),          // 6: leaderboard.v1.RankedPlayer
+	(*PlayersResponse)(nil),       // 7: leaderboard.v1.PlayersResponse
+	(*timestamppb.Timestamp)(nil), // 8: google.protobuf.Timestamp
+}
+var file_rank_proto_depIdxs = []int32{
+	8, // 0: leaderboard.v1.RankedPlayer.updated_at:type_name -> google.protobuf.Timestamp
+	6, // 1: leaderboard.v1.PlayersResponse.players:type_name -> leaderboard.v1.RankedPlayer
+	0, // 2: leaderboard.v1.RankService.UpdateScore:input_type -> leaderboard.v1.UpdateScoreRequest
+	2, // 3: leaderboard.v1.RankService.GetRank:input_type -> leaderboard.v1.GetRankRequest
+	4, // 4: leaderboard.v1.RankService.GetTopN:input_type -> leaderboard.v1.GetTopNRequest
+	5, // 5: leaderboard.v1.RankService.GetNearby:input_type -> leaderboard.v1.GetNearbyRequest
+	1, // 6: leaderboard.v1.RankService.UpdateScore:output_type -> leaderboard.v1.UpdateScoreResponse
+	3, // 7: leaderboard.v1.RankService.GetRank:output_type -> leaderboard.v1.GetRankResponse
+	7, // 8: leaderboard.v1.RankService.GetTopN:output_type -> leaderboard.v1.PlayersResponse
+	7, // 9: leaderboard.v1.RankService.GetNearby:output_type -> leaderboard.v1.PlayersResponse
+	6, // [6:10] is the sub-list for method output_type
+	2, // [2:6] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_rank_proto_init() }
+func file_rank_proto_init() {
+	if File_rank_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_rank_proto_rawDesc), len(file_rank_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_rank_proto_goTypes,
+		DependencyIndexes: file_rank_proto_depIdxs,
+		MessageInfos:      file_rank_proto_msgTypes,
+	}.Build()
+	File_rank_proto = out.File
+	file_rank_proto_goTypes = nil
+	file_rank_proto_depIdxs = nil
+}
@@ -0,0 +1,247 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: rank.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	RankService_UpdateScore_FullMethodName = "/leaderboard.v1.RankService/UpdateScore"
+	RankService_GetRank_FullMethodName     = "/leaderboard.v1.RankService/GetRank"
+	RankService_GetTopN_FullMethodName     = "/leaderboard.v1.RankService/GetTopN"
+	RankService_GetNearby_FullMethodName   = "/leaderboard.v1.RankService/GetNearby"
+)
+
+// RankServiceClient is the client API for RankService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// RankService 是排行榜的 gRPC 接口，与 HTTP 接口共享同一个应用服务。
+type RankServiceClient interface {
+	// UpdateScore 更新玩家的分数。
+	UpdateScore(ctx context.Context, in *UpdateScoreRequest, opts ...grpc.CallOption) (*UpdateScoreResponse, error)
+	// GetRank 获取玩家的排名。
+	GetRank(ctx context.Context, in *GetRankRequest, opts ...grpc.CallOption) (*GetRankResponse, error)
+	// GetTopN 获取排名前 N 的玩家。
+	GetTopN(ctx context.Context, in *GetTopNRequest, opts ...grpc.CallOption) (*PlayersResponse, error)
+	// GetNearby 获取玩家临近的排名。
+	GetNearby(ctx context.Context, in *GetNearbyRequest, opts ...grpc.CallOption) (*PlayersResponse, error)
+}
+
+type rankServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRankServiceClient(cc grpc.ClientConnInterface) RankServiceClient {
+	return &rankServiceClient{cc}
+}
+
+func (c *rankServiceClient) UpdateScore(ctx context.Context, in *UpdateScoreRequest, opts ...grpc.CallOption) (*UpdateScoreResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateScoreResponse)
+	err := c.cc.Invoke(ctx, RankService_UpdateScore_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rankServiceClient) GetRank(ctx context.Context, in *GetRankRequest, opts ...grpc.CallOption) (*GetRankResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetRankResponse)
+	err := c.cc.Invoke(ctx, RankService_GetRank_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rankServiceClient) GetTopN(ctx context.Context, in *GetTopNRequest, opts ...grpc.CallOption) (*PlayersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PlayersResponse)
+	err := c.cc.Invoke(ctx, RankService_GetTopN_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rankServiceClient) GetNearby(ctx context.Context, in *GetNearbyRequest, opts ...grpc.CallOption) (*PlayersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PlayersResponse)
+	err := c.cc.Invoke(ctx, RankService_GetNearby_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RankServiceServer is the server API for RankService service.
+// All implementations must embed UnimplementedRankServiceServer
+// for forward compatibility.
+//
+// RankService 是排行榜的 gRPC 接口，与 HTTP 接口共享同一个应用服务。
+type RankServiceServer interface {
+	// UpdateScore 更新玩家的分数。
+	UpdateScore(context.Context, *UpdateScoreRequest) (*UpdateScoreResponse, error)
+	// GetRank 获取玩家的排名。
+	GetRank(context.Context, *GetRankRequest) (*GetRankResponse, error)
+	// GetTopN 获取排名前 N 的玩家。
+	GetTopN(context.Context, *GetTopNRequest) (*PlayersResponse, error)
+	// GetNearby 获取玩家临近的排名。
+	GetNearby(context.Context, *GetNearbyRequest) (*PlayersResponse, error)
+	mustEmbedUnimplementedRankServiceServer()
+}
+
+// UnimplementedRankServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRankServiceServer struct{}
+
+func (UnimplementedRankServiceServer) UpdateScore(context.Context, *UpdateScoreRequest) (*UpdateScoreResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method UpdateScore not implemented")
+}
+func (UnimplementedRankServiceServer) GetRank(context.Context, *GetRankRequest) (*GetRankResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetRank not implemented")
+}
+func (UnimplementedRankServiceServer) GetTopN(context.Context, *GetTopNRequest) (*PlayersResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetTopN not implemented")
+}
+func (UnimplementedRankServiceServer) GetNearby(context.Context, *GetNearbyRequest) (*PlayersResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetNearby not implemented")
+}
+func (UnimplementedRankServiceServer) mustEmbedUnimplementedRankServiceServer() {}
+func (UnimplementedRankServiceServer) testEmbeddedByValue()                     {}
+
+// UnsafeRankServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RankServiceServer will
+// result in compilation errors.
+type UnsafeRankServiceServer interface {
+	mustEmbedUnimplementedRankServiceServer()
+}
+
+func RegisterRankServiceServer(s grpc.ServiceRegistrar, srv RankServiceServer) {
+	// If the following call panics, it indicates UnimplementedRankServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RankService_ServiceDesc, srv)
+}
+
+func _RankService_UpdateScore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateScoreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RankServiceServer).UpdateScore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RankService_UpdateScore_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RankServiceServer).UpdateScore(ctx, req.(*UpdateScoreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RankService_GetRank_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRankRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RankServiceServer).GetRank(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RankService_GetRank_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RankServiceServer).GetRank(ctx, req.(*GetRankRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RankService_GetTopN_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTopNRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RankServiceServer).GetTopN(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RankService_GetTopN_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RankServiceServer).GetTopN(ctx, req.(*GetTopNRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RankService_GetNearby_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNearbyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RankServiceServer).GetNearby(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RankService_GetNearby_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RankServiceServer).GetNearby(ctx, req.(*GetNearbyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// RankService_ServiceDesc is the grpc.ServiceDesc for RankService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RankService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "leaderboard.v1.RankService",
+	HandlerType: (*RankServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "UpdateScore",
+			Handler:    _RankService_UpdateScore_Handler,
+		},
+		{
+			MethodName: "GetRank",
+			Handler:    _RankService_GetRank_Handler,
+		},
+		{
+			MethodName: "GetTopN",
+			Handler:    _RankService_GetTopN_Handler,
+		},
+		{
+			MethodName: "GetNearby",
+			Handler:    _RankService_GetNearby_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "rank.proto",
+}
@@ -0,0 +1,108 @@
+package grpc
+
+//go:generate protoc -I ../../../api/proto --go_out=pb --go_opt=paths=source_relative --go-grpc_out=pb --go-grpc_opt=paths=source_relative rank.proto
+
+import (
+	"context"
+	"errors"
+	"leaderboard/internal/application"
+	"leaderboard/internal/domain/model"
+	"leaderboard/internal/infrastructure/persistence"
+	"leaderboard/internal/interfaces/grpc/pb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server 实现 pb.RankServiceServer，与 HTTP 处理器共享同一个 RankService。
+type Server struct {
+	pb.UnimplementedRankServiceServer
+	rankService application.RankService
+}
+
+// NewServer 创建一个新的 gRPC Server。
+func NewServer(rankService application.RankService) *Server {
+	return &Server{rankService: rankService}
+}
+
+// Register 将服务注册到 gRPC 服务器。
+func (s *Server) Register(registrar grpc.ServiceRegistrar) {
+	pb.RegisterRankServiceServer(registrar, s)
+}
+
+// toStatus 将应用层错误映射为 gRPC 状态码。
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, application.ErrLeaderboardNotFound), errors.Is(err, model.ErrPlayerNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, application.ErrLeaderboardExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, persistence.ErrInvalidLeaderboardID):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// UpdateScore 更新玩家的分数。
+func (s *Server) UpdateScore(ctx context.Context, req *pb.UpdateScoreRequest) (*pb.UpdateScoreResponse, error) {
+	if err := s.rankService.UpdateScore(req.GetLeaderboardId(), req.GetPlayerId(), req.GetScore()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.UpdateScoreResponse{}, nil
+}
+
+// GetRank 获取玩家的排名。
+func (s *Server) GetRank(ctx context.Context, req *pb.GetRankRequest) (*pb.GetRankResponse, error) {
+	rank, err := s.rankService.GetPlayerRank(req.GetLeaderboardId(), req.GetPlayerId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.GetRankResponse{Rank: rank}, nil
+}
+
+// GetTopN 获取排名前 N 的玩家。
+func (s *Server) GetTopN(ctx context.Context, req *pb.GetTopNRequest) (*pb.PlayersResponse, error) {
+	if req.GetN() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "n must be positive")
+	}
+
+	players, err := s.rankService.GetTopN(req.GetLeaderboardId(), int(req.GetN()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return s.rankedPlayers(req.GetLeaderboardId(), players)
+}
+
+// GetNearby 获取玩家临近的排名。
+func (s *Server) GetNearby(ctx context.Context, req *pb.GetNearbyRequest) (*pb.PlayersResponse, error) {
+	if req.GetCount() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "count must not be negative")
+	}
+
+	players, err := s.rankService.GetNearbyRanks(req.GetLeaderboardId(), req.GetPlayerId(), int(req.GetCount()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return s.rankedPlayers(req.GetLeaderboardId(), players)
+}
+
+// rankedPlayers 为玩家列表补充排名后生成响应。
+func (s *Server) rankedPlayers(leaderboardID string, players []*model.Player) (*pb.PlayersResponse, error) {
+	resp := &pb.PlayersResponse{Players: make([]*pb.RankedPlayer, 0, len(players))}
+	for _, p := range players {
+		rank, err := s.rankService.GetPlayerRank(leaderboardID, p.ID)
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		resp.Players = append(resp.Players, &pb.RankedPlayer{
+			Id:        p.ID,
+			Score:     p.Score,
+			Rank:      rank,
+			UpdatedAt: timestamppb.New(p.UpdateTime),
+		})
+	}
+	return resp, nil
+}
@@ -0,0 +1,162 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"leaderboard/internal/application"
+	"leaderboard/internal/infrastructure/persistence"
+	"leaderboard/internal/interfaces/grpc/pb"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestClient 在内存连接上启动 gRPC 服务并返回客户端，服务与连接在测试结束时关闭
+func newTestClient(t *testing.T) pb.RankServiceClient {
+	t.Helper()
+	store, err := persistence.NewLeaderboardStore(t.TempDir(), persistence.Options{})
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	rankService, err := application.NewRankService(store)
+	if err != nil {
+		t.Fatalf("new service: %v", err)
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	NewServer(rankService).Register(server)
+	go server.Serve(lis)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		server.Stop()
+		rankService.Close()
+	})
+	return pb.NewRankServiceClient(conn)
+}
+
+// playerRanks 将响应中的玩家格式化为 "id:score@rank"
+func playerRanks(resp *pb.PlayersResponse) string {
+	var s string
+	for _, p := range resp.GetPlayers() {
+		if p.GetUpdatedAt() == nil {
+			return "missing updated_at"
+		}
+		s += fmt.Sprintf("%d:%d@%d ", p.GetId(), p.GetScore(), p.GetRank())
+	}
+	return s
+}
+
+// 经 gRPC 调用每个 RPC，结果与 RankService 一致
+func TestRPCs(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	lb := application.DefaultLeaderboardID
+
+	for id, score := range map[int64]int64{1: 100, 2: 300, 3: 200} {
+		if _, err := client.UpdateScore(ctx, &pb.UpdateScoreRequest{LeaderboardId: lb, PlayerId: id, Score: score}); err != nil {
+			t.Fatalf("UpdateScore %d: %v", id, err)
+		}
+	}
+
+	rank, err := client.GetRank(ctx, &pb.GetRankRequest{LeaderboardId: lb, PlayerId: 3})
+	if err != nil || rank.GetRank() != 2 {
+		t.Fatalf("GetRank: got=%v, %v want=2", rank.GetRank(), err)
+	}
+	top, err := client.GetTopN(ctx, &pb.GetTopNRequest{LeaderboardId: lb, N: 2})
+	if err != nil {
+		t.Fatalf("GetTopN: %v", err)
+	}
+	if got, want := playerRanks(top), "2:300@1 3:200@2 "; got != want {
+		t.Fatalf("GetTopN: got=%q want=%q", got, want)
+	}
+	nearby, err := client.GetNearby(ctx, &pb.GetNearbyRequest{LeaderboardId: lb, PlayerId: 1, Count: 3})
+	if err != nil {
+		t.Fatalf("GetNearby: %v", err)
+	}
+	if got, want := playerRanks(nearby), "3:200@2 1:100@3 "; got != want {
+		t.Fatalf("GetNearby: got=%q want=%q", got, want)
+	}
+}
+
+// 不存在的排行榜与玩家映射为 NotFound，非法参数映射为 InvalidArgument
+func TestRPCErrorCodes(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	lb := application.DefaultLeaderboardID
+	if _, err := client.UpdateScore(ctx, &pb.UpdateScoreRequest{LeaderboardId: lb, PlayerId: 1, Score: 10}); err != nil {
+		t.Fatalf("UpdateScore: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		call func() error
+		code codes.Code
+	}{
+		{"UpdateScore/unknown board", func() error {
+			_, err := client.UpdateScore(ctx, &pb.UpdateScoreRequest{LeaderboardId: "missing", PlayerId: 1, Score: 10})
+			return err
+		}, codes.NotFound},
+		{"GetRank/unknown board", func() error {
+			_, err := client.GetRank(ctx, &pb.GetRankRequest{LeaderboardId: "missing", PlayerId: 1})
+			return err
+		}, codes.NotFound},
+		{"GetRank/unknown player", func() error {
+			_, err := client.GetRank(ctx, &pb.GetRankRequest{LeaderboardId: lb, PlayerId: 2})
+			return err
+		}, codes.NotFound},
+		{"GetTopN/unknown board", func() error {
+			_, err := client.GetTopN(ctx, &pb.GetTopNRequest{LeaderboardId: "missing", N: 10})
+			return err
+		}, codes.NotFound},
+		{"GetTopN/non-positive n", func() error {
+			_, err := client.GetTopN(ctx, &pb.GetTopNRequest{LeaderboardId: lb, N: 0})
+			return err
+		}, codes.InvalidArgument},
+		{"GetNearby/unknown player", func() error {
+			_, err := client.GetNearby(ctx, &pb.GetNearbyRequest{LeaderboardId: lb, PlayerId: 2, Count: 5})
+			return err
+		}, codes.NotFound},
+		{"GetNearby/negative count", func() error {
+			_, err := client.GetNearby(ctx, &pb.GetNearbyRequest{LeaderboardId: lb, PlayerId: 1, Count: -1})
+			return err
+		}, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		if got := status.Code(tt.call()); got != tt.code {
+			t.Fatalf("%s: got=%v want=%v", tt.name, got, tt.code)
+		}
+	}
+}
+
+// 应用层与存储层错误（含包装后的错误）映射为对应的状态码
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		code codes.Code
+	}{
+		{application.ErrLeaderboardNotFound, codes.NotFound},
+		{application.ErrLeaderboardExists, codes.AlreadyExists},
+		{fmt.Errorf("open ../x: %w", persistence.ErrInvalidLeaderboardID), codes.InvalidArgument},
+		{errors.New("disk full"), codes.Internal},
+	}
+	for _, tt := range tests {
+		if got := status.Code(toStatus(tt.err)); got != tt.code {
+			t.Fatalf("%v: got=%v want=%v", tt.err, got, tt.code)
+		}
+	}
+}
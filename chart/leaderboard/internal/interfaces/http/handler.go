@@ -1,12 +1,15 @@
 package http
 
 import (
-    "leaderboard/internal/application"
-    "net/http"
-    "strconv"
-    "time"
-
-    "github.com/gin-gonic/gin"
+	"errors"
+	"leaderboard/internal/application"
+	"leaderboard/internal/domain/model"
+	"leaderboard/internal/infrastructure/persistence"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
 )
 
 // Handler 负责处理 HTTP 请求。
@@ -23,13 +26,82 @@ func NewHandler(rankService application.RankService) *Handler {
 func (h *Handler) RegisterRoutes(router *gin.Engine) {
 	api := router.Group("/api/v1")
 	{
-		api.POST("/scores", h.updateScore)
-		api.GET("/ranks/:playerID", h.getPlayerRank)
-		api.GET("/ranks/top/:n", h.getTopN)
-		api.GET("/ranks/nearby/:playerID/:count", h.getNearbyRanks)
+		api.POST("/leaderboards", h.createLeaderboard)
+		api.GET("/leaderboards", h.listLeaderboards)
+		api.DELETE("/leaderboards/:leaderboardID", h.deleteLeaderboard)
+
+		lb := api.Group("/leaderboards/:leaderboardID")
+		lb.POST("/scores", h.updateScore)
+		lb.GET("/ranks/:playerID", h.getPlayerRank)
+		lb.GET("/ranks/top/:n", h.getTopN)
+		lb.GET("/ranks/nearby/:playerID/:count", h.getNearbyRanks)
+		lb.POST("/aof/rewrite", h.rewriteAOF)
 	}
 }
 
+// rankedPlayer 是返回给客户端的带排名玩家信息。
+type rankedPlayer struct {
+	ID        int64     `json:"id"`
+	Score     int64     `json:"score"`
+	Rank      int64     `json:"rank"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// errorStatus 将应用层错误映射为 HTTP 状态码。
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, application.ErrLeaderboardNotFound), errors.Is(err, model.ErrPlayerNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, application.ErrLeaderboardExists):
+		return http.StatusConflict
+	case errors.Is(err, persistence.ErrInvalidLeaderboardID):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (h *Handler) createLeaderboard(c *gin.Context) {
+	var req struct {
+		ID   string `json:"id" binding:"required"`
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	info, err := h.rankService.CreateLeaderboard(req.ID, req.Name)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, info)
+}
+
+func (h *Handler) listLeaderboards(c *gin.Context) {
+	c.JSON(http.StatusOK, h.rankService.ListLeaderboards())
+}
+
+func (h *Handler) deleteLeaderboard(c *gin.Context) {
+	if err := h.rankService.DeleteLeaderboard(c.Param("leaderboardID")); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) rewriteAOF(c *gin.Context) {
+	if err := h.rankService.RewriteAOF(c.Param("leaderboardID")); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
 func (h *Handler) updateScore(c *gin.Context) {
 	var req struct {
 		PlayerID int64 `json:"player_id"`
@@ -40,8 +112,8 @@ func (h *Handler) updateScore(c *gin.Context) {
 		return
 	}
 
-	if err := h.rankService.UpdateScore(req.PlayerID, req.Score); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.rankService.UpdateScore(c.Param("leaderboardID"), req.PlayerID, req.Score); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -55,9 +127,9 @@ func (h *Handler) getPlayerRank(c *gin.Context) {
 		return
 	}
 
-	rank, err := h.rankService.GetPlayerRank(playerID)
+	rank, err := h.rankService.GetPlayerRank(c.Param("leaderboardID"), playerID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -65,49 +137,28 @@ func (h *Handler) getPlayerRank(c *gin.Context) {
 }
 
 func (h *Handler) getTopN(c *gin.Context) {
-    n, err := strconv.Atoi(c.Param("n"))
-    if err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid n"})
-        return
-    }
-
-    players, err := h.rankService.GetTopN(n)
-    if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-        return
-    }
-
-    type rankedPlayer struct {
-        ID        int64     `json:"id"`
-        Score     int64     `json:"score"`
-        Rank      int64     `json:"rank"`
-        UpdatedAt time.Time `json:"updated_at"`
-    }
-
-    resp := make([]rankedPlayer, 0, len(players))
-    for _, p := range players {
-        rank, err := h.rankService.GetPlayerRank(p.ID)
-        if err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-            return
-        }
-        resp = append(resp, rankedPlayer{
-            ID:        p.ID,
-            Score:     p.Score,
-            Rank:      rank,
-            UpdatedAt: p.UpdatedAt,
-        })
-    }
-
-    c.JSON(http.StatusOK, resp)
+	n, err := strconv.Atoi(c.Param("n"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid n"})
+		return
+	}
+
+	leaderboardID := c.Param("leaderboardID")
+	players, err := h.rankService.GetTopN(leaderboardID, n)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.writeRankedPlayers(c, leaderboardID, players)
 }
 
 func (h *Handler) getNearbyRanks(c *gin.Context) {
-    playerID, err := strconv.ParseInt(c.Param("playerID"), 10, 64)
-    if err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player id"})
-        return
-    }
+	playerID, err := strconv.ParseInt(c.Param("playerID"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid player id"})
+		return
+	}
 
 	count, err := strconv.Atoi(c.Param("count"))
 	if err != nil {
@@ -115,32 +166,32 @@ func (h *Handler) getNearbyRanks(c *gin.Context) {
 		return
 	}
 
-    players, err := h.rankService.GetNearbyRanks(playerID, count)
-    if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-        return
-    }
-
-    type rankedPlayer struct {
-        ID        int64     `json:"id"`
-        Score     int64     `json:"score"`
-        Rank      int64     `json:"rank"`
-        UpdatedAt time.Time `json:"updated_at"`
-    }
-    resp := make([]rankedPlayer, 0, len(players))
-    for _, p := range players {
-        rank, err := h.rankService.GetPlayerRank(p.ID)
-        if err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-            return
-        }
-        resp = append(resp, rankedPlayer{
-            ID:        p.ID,
-            Score:     p.Score,
-            Rank:      rank,
-            UpdatedAt: p.UpdatedAt,
-        })
-    }
-
-    c.JSON(http.StatusOK, resp)
-}
\ No newline at end of file
+	leaderboardID := c.Param("leaderboardID")
+	players, err := h.rankService.GetNearbyRanks(leaderboardID, playerID, count)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.writeRankedPlayers(c, leaderboardID, players)
+}
+
+// writeRankedPlayers 为玩家列表补充排名后写出响应。
+func (h *Handler) writeRankedPlayers(c *gin.Context, leaderboardID string, players []*model.Player) {
+	resp := make([]rankedPlayer, 0, len(players))
+	for _, p := range players {
+		rank, err := h.rankService.GetPlayerRank(leaderboardID, p.ID)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		resp = append(resp, rankedPlayer{
+			ID:        p.ID,
+			Score:     p.Score,
+			Rank:      rank,
+			UpdatedAt: p.UpdateTime,
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,397 @@
+// 按行追加的持久化日志
+//
+// 语义说明：
+//   - 每条记录是以换行结尾的一行，记录的编码由使用方决定（如 leaderboard 的 AOF 与 rank-system 的 JSON 日志）；
+//   - 写入先进入缓冲区，按 fsync 策略立即或由后台每秒写出并刷盘；
+//   - 回放时没有换行结尾的最后一行视为宕机留下的半条记录，按回放模式失败、截断或跳过；
+//   - 压缩以调用方给出的基准状态替换整个日志，压缩期间的并发写入缓存在内存中并追加到新日志，不会丢失。
+package leaderboardcore
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FsyncPolicy 决定日志写入何时落盘
+type FsyncPolicy string
+
+const (
+	// FsyncAlways 每次写入后立即刷盘，最安全也最慢
+	FsyncAlways FsyncPolicy = "always"
+	// FsyncEverySec 写入先进入缓冲区，由后台每秒刷盘一次，宕机最多丢失约一秒的更新
+	FsyncEverySec FsyncPolicy = "everysec"
+	// FsyncNo 后台每秒把缓冲区写入操作系统，但不主动刷盘，由操作系统决定落盘时机
+	FsyncNo FsyncPolicy = "no"
+)
+
+// ReplayMode 决定回放日志时如何处理损坏的记录
+type ReplayMode string
+
+const (
+	// ReplayTolerant 跳过无法解析的记录，继续回放后续内容
+	ReplayTolerant ReplayMode = "tolerant"
+	// ReplayStrict 遇到损坏的记录立即失败
+	ReplayStrict ReplayMode = "strict"
+	// ReplayRepair 在第一条损坏的记录处截断日志，只保留之前的内容，适合处理宕机留下的半条记录
+	ReplayRepair ReplayMode = "repair"
+)
+
+// AppendLogFlushInterval 后台写出缓冲区的间隔
+const AppendLogFlushInterval = time.Second
+
+var (
+	// ErrLogClosed 表示日志已关闭
+	ErrLogClosed = errors.New("append log closed")
+	// ErrInvalidFsyncPolicy 表示未知的 fsync 策略
+	ErrInvalidFsyncPolicy = errors.New("invalid fsync policy")
+	// ErrInvalidReplayMode 表示未知的回放模式
+	ErrInvalidReplayMode = errors.New("invalid replay mode")
+	// ErrMalformedRecord 由回放回调返回，表示记录无法解析，按回放模式处理
+	ErrMalformedRecord = errors.New("malformed record")
+)
+
+// ParseFsyncPolicy 解析 fsync 策略，空字符串表示默认的 everysec
+func ParseFsyncPolicy(s string) (FsyncPolicy, error) {
+	switch p := FsyncPolicy(s); p {
+	case "":
+		return FsyncEverySec, nil
+	case FsyncAlways, FsyncEverySec, FsyncNo:
+		return p, nil
+	default:
+		return "", ErrInvalidFsyncPolicy
+	}
+}
+
+// ParseReplayMode 解析回放模式，空字符串表示默认的 repair
+func ParseReplayMode(s string) (ReplayMode, error) {
+	switch m := ReplayMode(s); m {
+	case "":
+		return ReplayRepair, nil
+	case ReplayTolerant, ReplayStrict, ReplayRepair:
+		return m, nil
+	default:
+		return "", ErrInvalidReplayMode
+	}
+}
+
+// CorruptRecordError 描述日志中一条损坏的记录
+type CorruptRecordError struct {
+	Path   string // 日志文件路径
+	Offset int64  // 记录在文件中的起始偏移
+	Record string // 记录内容
+}
+
+func (e *CorruptRecordError) Error() string {
+	return fmt.Sprintf("%s: corrupted record at offset %d: %q", e.Path, e.Offset, e.Record)
+}
+
+// ReplayResult 汇总一次回放的结果
+type ReplayResult struct {
+	Applied   int   // 成功回放的记录数
+	Skipped   int   // tolerant 模式下跳过的损坏记录数
+	Truncated int64 // repair 模式下截断的字节数
+}
+
+// AppendLog 按行追加的日志文件
+type AppendLog struct {
+	mu     sync.Mutex
+	path   string
+	policy FsyncPolicy
+	file   *os.File
+	w      *bufio.Writer
+	size   int64
+
+	stop chan struct{}
+	done chan struct{}
+
+	// compactMu 保证同一时间只有一个压缩在进行；
+	// pending 非空时表示压缩进行中，期间的新写入会同时追加到其中
+	compactMu sync.Mutex
+	pending   *bytes.Buffer
+}
+
+// OpenAppendLog 打开（必要时创建）日志文件，policy 为空时使用 everysec
+func OpenAppendLog(path string, policy FsyncPolicy) (*AppendLog, error) {
+	policy, err := ParseFsyncPolicy(string(policy))
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	l := &AppendLog{
+		path:   path,
+		policy: policy,
+		file:   file,
+		w:      bufio.NewWriter(file),
+		size:   info.Size(),
+	}
+	if policy != FsyncAlways {
+		l.stop = make(chan struct{})
+		l.done = make(chan struct{})
+		go l.flushLoop()
+	}
+	return l, nil
+}
+
+// flushLoop 定期将缓冲区写入文件，everysec 策略下同时刷盘
+func (l *AppendLog) flushLoop() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(AppendLogFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := l.Flush(); err != nil && err != ErrLogClosed {
+				log.Printf("append log %s: flush failed: %v", l.path, err)
+			}
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// Flush 将缓冲区中的记录写入文件，并按策略决定是否刷盘
+func (l *AppendLog) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return ErrLogClosed
+	}
+	return l.flushLocked()
+}
+
+// flushLocked 写出缓冲区，除 no 策略外都会刷盘。调用方需持有 mu
+func (l *AppendLog) flushLocked() error {
+	if err := l.w.Flush(); err != nil {
+		return err
+	}
+	if l.policy == FsyncNo {
+		return nil
+	}
+	return l.file.Sync()
+}
+
+// Append 按顺序追加记录，每条记录必须以换行结尾；everysec 与 no 策略下只写入缓冲区
+func (l *AppendLog) Append(records ...[]byte) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return ErrLogClosed
+	}
+	for _, r := range records {
+		n, err := l.w.Write(r)
+		l.size += int64(n)
+		if err != nil {
+			return err
+		}
+		if l.pending != nil {
+			l.pending.Write(r)
+		}
+	}
+	if l.policy == FsyncAlways {
+		return l.flushLocked()
+	}
+	return nil
+}
+
+// Size 返回日志的大小（字节），包含缓冲区中尚未写出的记录
+func (l *AppendLog) Size() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.size
+}
+
+// Compact 以基准状态替换整个日志。capture 在持有写锁时调用，返回的 writeBase 随后在锁外写出基准状态，
+// 可以写入新日志的开头，也可以持久化到别处而不写入 w。
+//
+// capture 与开始缓存新写入是原子的，期间的新写入在写出基准后追加到新日志，
+// 再原子地替换旧日志，因此压缩过程不会丢失并发的写入；失败时原日志保持不变。
+func (l *AppendLog) Compact(capture func() (writeBase func(w io.Writer) error)) error {
+	l.compactMu.Lock()
+	defer l.compactMu.Unlock()
+
+	l.mu.Lock()
+	if l.file == nil {
+		l.mu.Unlock()
+		return ErrLogClosed
+	}
+	writeBase := capture()
+	l.pending = &bytes.Buffer{}
+	l.mu.Unlock()
+
+	tmpPath := l.path + ".rewrite"
+	tmp, err := os.OpenFile(tmpPath, os.O_APPEND|os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err == nil {
+		err = writeBase(tmp)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	pending := l.pending
+	l.pending = nil
+	if err == nil && l.file == nil {
+		err = ErrLogClosed
+	}
+	if err == nil {
+		err = appendAndSync(tmp, pending.Bytes())
+	}
+	var info os.FileInfo
+	if err == nil {
+		info, err = tmp.Stat()
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, l.path)
+	}
+	if err != nil {
+		if tmp != nil {
+			tmp.Close()
+		}
+		os.Remove(tmpPath)
+		return err
+	}
+	SyncDir(filepath.Dir(l.path))
+
+	// 旧日志已被替换，缓冲区中尚未写出的内容已包含在新日志中，直接丢弃
+	l.file.Close()
+	l.file = tmp
+	l.w = bufio.NewWriter(tmp)
+	l.size = info.Size()
+	return nil
+}
+
+// appendAndSync 追加压缩期间缓存的写入并落盘
+func appendAndSync(file *os.File, pending []byte) error {
+	if _, err := file.Write(pending); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// SyncDir 刷新目录项，确保重命名在宕机后依然可见
+func SyncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+// Replay 按顺序回放日志中的记录，apply 收到的记录不含结尾的换行。
+// apply 返回 ErrMalformedRecord 时按回放模式处理该记录，返回其他错误时回放立即失败；
+// 没有换行结尾的最后一行视为写了一半的损坏记录，不会交给 apply
+func (l *AppendLog) Replay(mode ReplayMode, apply func(record string) error) (ReplayResult, error) {
+	var result ReplayResult
+	mode, err := ParseReplayMode(string(mode))
+	if err != nil {
+		return result, err
+	}
+
+	file, err := os.Open(l.path)
+	if err != nil {
+		return result, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return result, err
+		}
+		if line == "" {
+			break
+		}
+
+		applyErr := ErrMalformedRecord
+		if strings.HasSuffix(line, "\n") {
+			applyErr = apply(strings.TrimSuffix(line, "\n"))
+		}
+		switch {
+		case applyErr == nil:
+			result.Applied++
+		case applyErr != ErrMalformedRecord:
+			return result, applyErr
+		case mode == ReplayStrict:
+			return result, &CorruptRecordError{Path: l.path, Offset: offset, Record: strings.TrimSpace(line)}
+		case mode == ReplayRepair:
+			truncated, err := l.truncateAt(offset)
+			result.Truncated = truncated
+			return result, err
+		default:
+			result.Skipped++
+		}
+		offset += int64(len(line))
+	}
+	return result, nil
+}
+
+// truncateAt 将日志截断到 offset 处并落盘，返回被截掉的字节数
+func (l *AppendLog) truncateAt(offset int64) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return 0, ErrLogClosed
+	}
+	if err := l.w.Flush(); err != nil {
+		return 0, err
+	}
+	info, err := l.file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if err := l.file.Truncate(offset); err != nil {
+		return 0, err
+	}
+	if err := l.file.Sync(); err != nil {
+		return 0, err
+	}
+	l.size = offset
+	return info.Size() - offset, nil
+}
+
+// Close 写出缓冲区并关闭日志文件，重复调用是安全的
+func (l *AppendLog) Close() error {
+	l.mu.Lock()
+	if l.file == nil {
+		l.mu.Unlock()
+		return nil
+	}
+	err := l.w.Flush()
+	if serr := l.file.Sync(); err == nil {
+		err = serr
+	}
+	if cerr := l.file.Close(); err == nil {
+		err = cerr
+	}
+	l.file = nil
+	l.mu.Unlock()
+
+	if l.stop != nil {
+		close(l.stop)
+		<-l.done
+	}
+	return err
+}
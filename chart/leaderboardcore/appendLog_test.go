@@ -0,0 +1,248 @@
+package leaderboardcore
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// openTestLog 在临时目录中以 content 为初始内容打开日志
+func openTestLog(t *testing.T, content string, policy FsyncPolicy) (*AppendLog, string) {
+	t.Helper()
+	path := t.TempDir() + "/append.log"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write log: %v", err)
+	}
+	l, err := OpenAppendLog(path, policy)
+	if err != nil {
+		t.Fatalf("open log: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l, path
+}
+
+// readLog 读取磁盘上的日志内容
+func readLog(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	return string(data)
+}
+
+// replayAll 回放日志并收集记录，以 "bad" 开头的记录视为无法解析
+func replayAll(l *AppendLog, mode ReplayMode) ([]string, ReplayResult, error) {
+	var records []string
+	result, err := l.Replay(mode, func(record string) error {
+		if strings.HasPrefix(record, "bad") {
+			return ErrMalformedRecord
+		}
+		records = append(records, record)
+		return nil
+	})
+	return records, result, err
+}
+
+// 各回放模式对无法解析的记录与写了一半的最后一行的处理
+func TestAppendLogReplayModes(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		mode    ReplayMode
+		want    []string
+		result  ReplayResult
+		wantLog string // 回放后磁盘上的内容
+		corrupt int64  // strict 模式下损坏记录的偏移，-1 表示不出错
+	}{
+		{"strict/malformed", "a\nbad\nb\n", ReplayStrict, []string{"a"}, ReplayResult{Applied: 1}, "a\nbad\nb\n", 2},
+		{"strict/half line", "a\nb", ReplayStrict, []string{"a"}, ReplayResult{Applied: 1}, "a\nb", 2},
+		{"repair/malformed", "a\nbad\nb\n", ReplayRepair, []string{"a"}, ReplayResult{Applied: 1, Truncated: 6}, "a\n", -1},
+		{"repair/half line", "a\nb", ReplayRepair, []string{"a"}, ReplayResult{Applied: 1, Truncated: 1}, "a\n", -1},
+		{"tolerant", "a\nbad\nb\nc", ReplayTolerant, []string{"a", "b"}, ReplayResult{Applied: 2, Skipped: 2}, "a\nbad\nb\nc", -1},
+		{"empty", "", ReplayStrict, nil, ReplayResult{}, "", -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, path := openTestLog(t, tt.content, FsyncAlways)
+			records, result, err := replayAll(l, tt.mode)
+			if tt.corrupt >= 0 {
+				var corrupt *CorruptRecordError
+				if !errors.As(err, &corrupt) || corrupt.Offset != tt.corrupt || corrupt.Path != path {
+					t.Fatalf("replay error: got=%v want corrupt record at %d", err, tt.corrupt)
+				}
+			} else if err != nil {
+				t.Fatalf("replay: %v", err)
+			}
+			if fmt.Sprint(records) != fmt.Sprint(tt.want) || result != tt.result {
+				t.Fatalf("replay: got=%v %+v want=%v %+v", records, result, tt.want, tt.result)
+			}
+			if got := readLog(t, path); got != tt.wantLog {
+				t.Fatalf("log: got=%q want=%q", got, tt.wantLog)
+			}
+			if tt.mode == ReplayRepair && l.Size() != int64(len(tt.wantLog)) {
+				t.Fatalf("size after repair: got=%d want=%d", l.Size(), len(tt.wantLog))
+			}
+		})
+	}
+}
+
+// 回调返回其他错误时回放立即失败，不按损坏记录处理，日志保持不变
+func TestAppendLogReplayApplyError(t *testing.T) {
+	l, path := openTestLog(t, "a\nb\n", FsyncAlways)
+	failure := errors.New("apply failed")
+	result, err := l.Replay(ReplayRepair, func(record string) error {
+		if record == "b" {
+			return failure
+		}
+		return nil
+	})
+	if err != failure || result.Applied != 1 {
+		t.Fatalf("replay: got=%+v, %v want applied=1, %v", result, err, failure)
+	}
+	if got := readLog(t, path); got != "a\nb\n" {
+		t.Fatalf("log: got=%q want unchanged", got)
+	}
+	if _, err := l.Replay("lenient", func(string) error { return nil }); err != ErrInvalidReplayMode {
+		t.Fatalf("invalid mode: got=%v want=%v", err, ErrInvalidReplayMode)
+	}
+}
+
+// 压缩期间并发追加的记录不会丢失，压缩后新日志为基准加上这些记录
+func TestAppendLogCompactConcurrentAppends(t *testing.T) {
+	l, path := openTestLog(t, "old 1\nold 2\n", FsyncEverySec)
+
+	const workers, rounds = 4, 500
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			<-start
+			for i := 0; i < rounds; i++ {
+				if err := l.Append([]byte(fmt.Sprintf("w%d %d\n", w, i))); err != nil {
+					t.Errorf("append: %v", err)
+					return
+				}
+			}
+		}(w)
+	}
+
+	var captured []string
+	close(start)
+	for i := 0; i < 20; i++ {
+		err := l.Compact(func() func(w io.Writer) error {
+			// 基准为持有写锁时已写入的全部记录
+			captured = nil
+			for _, line := range strings.Split(strings.TrimSuffix(readLogFlushed(t, l, path), "\n"), "\n") {
+				if line != "" && !strings.HasPrefix(line, "old") {
+					captured = append(captured, line)
+				}
+			}
+			base := strings.Join(captured, "\n")
+			during := fmt.Sprintf("during %d\n", i)
+			return func(w io.Writer) error {
+				// 写出基准期间的写入一定落在压缩窗口内
+				if err := l.Append([]byte(during)); err != nil {
+					return err
+				}
+				if base == "" {
+					return nil
+				}
+				_, err := io.WriteString(w, base+"\n")
+				return err
+			}
+		})
+		if err != nil {
+			t.Fatalf("compact: %v", err)
+		}
+	}
+	wg.Wait()
+	if err := l.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	seen := make(map[string]int)
+	for _, line := range strings.Split(strings.TrimSuffix(readLog(t, path), "\n"), "\n") {
+		seen[line]++
+	}
+	for w := 0; w < workers; w++ {
+		for i := 0; i < rounds; i++ {
+			if r := fmt.Sprintf("w%d %d", w, i); seen[r] != 1 {
+				t.Fatalf("record %q: got %d copies want 1", r, seen[r])
+			}
+		}
+	}
+	for i := 0; i < 20; i++ {
+		if r := fmt.Sprintf("during %d", i); seen[r] != 1 {
+			t.Fatalf("record %q: got %d copies want 1", r, seen[r])
+		}
+	}
+	if seen["old 1"] != 0 {
+		t.Fatal("compacted log still contains records replaced by the base")
+	}
+	if got := l.Size(); got != int64(len(readLog(t, path))) {
+		t.Fatalf("size: got=%d want=%d", got, len(readLog(t, path)))
+	}
+}
+
+// readLogFlushed 在持有写锁的 capture 中读取日志：先写出缓冲区再读取文件
+func readLogFlushed(t *testing.T, l *AppendLog, path string) string {
+	t.Helper()
+	if err := l.w.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	return readLog(t, path)
+}
+
+// 写出基准失败时原日志保持不变，可以继续追加
+func TestAppendLogCompactFailure(t *testing.T) {
+	l, path := openTestLog(t, "a\n", FsyncAlways)
+	failure := errors.New("disk full")
+	err := l.Compact(func() func(w io.Writer) error {
+		return func(io.Writer) error { return failure }
+	})
+	if err != failure {
+		t.Fatalf("compact: got=%v want=%v", err, failure)
+	}
+	if err := l.Append([]byte("b\n")); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if got := readLog(t, path); got != "a\nb\n" {
+		t.Fatalf("log: got=%q want=%q", got, "a\nb\n")
+	}
+	if _, err := os.Stat(path + ".rewrite"); !os.IsNotExist(err) {
+		t.Fatalf("temporary file left behind: %v", err)
+	}
+}
+
+// 关闭后写入、压缩返回 ErrLogClosed，重复关闭是安全的
+func TestAppendLogClosed(t *testing.T) {
+	l, path := openTestLog(t, "", FsyncNo)
+	if err := l.Append([]byte("a\n")); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if got := readLog(t, path); got != "a\n" {
+		t.Fatalf("log after close: got=%q want=%q", got, "a\n")
+	}
+	if err := l.Append([]byte("b\n")); err != ErrLogClosed {
+		t.Fatalf("append after close: got=%v want=%v", err, ErrLogClosed)
+	}
+	if err := l.Compact(func() func(io.Writer) error { return nil }); err != ErrLogClosed {
+		t.Fatalf("compact after close: got=%v want=%v", err, ErrLogClosed)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("second close: %v", err)
+	}
+	if _, err := OpenAppendLog(path, "sometimes"); err != ErrInvalidFsyncPolicy {
+		t.Fatalf("invalid policy: got=%v want=%v", err, ErrInvalidFsyncPolicy)
+	}
+}
@@ -0,0 +1,3 @@
+module leaderboardcore
+
+go 1.24
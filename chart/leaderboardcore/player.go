@@ -0,0 +1,64 @@
+// 玩家实体
+//
+// 语义说明：
+// - ID：玩家唯一标识；
+// - Score：用于排名的分数；
+// - SecondaryScore：次级分数，Score 相同时较高者排前（越小越好的指标请取负值）；
+// - Rank：可选的排名字段（部分接口返回时填充），不作为跳表排序依据；
+// - UpdateTime：最近一次分数更新的时间，作为分数相同情况下的次序比较键；
+// - Metadata：展示用的附加信息，如 nickname、avatar、region，不参与排序。
+package leaderboardcore
+
+import "time"
+
+// Player 玩家实体
+type Player struct {
+	ID             int64             `json:"id"`                        // 玩家ID
+	Score          int64             `json:"score"`                     // 玩家分数
+	SecondaryScore int64             `json:"secondary_score,omitempty"` // 次级分数，用于同分排序
+	Rank           int               `json:"rank"`                      // 玩家排名
+	UpdateTime     time.Time         `json:"update_time"`               // 玩家更新时间
+	Metadata       map[string]string `json:"metadata,omitempty"`        // 展示用的附加信息
+}
+
+// NewPlayer 创建新玩家
+func NewPlayer(id, score int64) *Player {
+	return &Player{
+		ID:         id,
+		Score:      score,
+		UpdateTime: time.Now(),
+	}
+}
+
+// UpdateScore 更新分数
+// 玩家已在跳表中时不能直接调用，应使用 SkipList.UpdateScore 以保持排序。
+func (p *Player) UpdateScore(score int64) {
+	p.Score = score
+	p.UpdateTime = time.Now()
+}
+
+// MergeMetadata 合并附加信息，值为空字符串的键会被删除
+func (p *Player) MergeMetadata(metadata map[string]string) {
+	for k, v := range metadata {
+		if v == "" {
+			delete(p.Metadata, k)
+			continue
+		}
+		if p.Metadata == nil {
+			p.Metadata = make(map[string]string, len(metadata))
+		}
+		p.Metadata[k] = v
+	}
+}
+
+// Clone 深拷贝玩家，附加信息不与原玩家共享
+func (p *Player) Clone() *Player {
+	cloned := *p
+	if p.Metadata != nil {
+		cloned.Metadata = make(map[string]string, len(p.Metadata))
+		for k, v := range p.Metadata {
+			cloned.Metadata[k] = v
+		}
+	}
+	return &cloned
+}
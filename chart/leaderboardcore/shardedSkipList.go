@@ -0,0 +1,249 @@
+// 按分数区间分片的跳表
+//
+// 语义说明：
+//   - 分片边界按分数降序给出，n 个边界划分出 n+1 个分片：分片 0 保存 score >= bounds[0]，
+//     分片 i 保存 bounds[i-1] > score >= bounds[i]，最后一个分片保存 score < bounds[n-1]；
+//     升序排行榜的边界按升序排列，不等号方向随之反转；
+//   - 分片之间天然有序（高分片的所有玩家排在低分片之前），排名 = 更高分片的玩家总数 + 分片内排名；
+//   - 每个分片持有独立的锁，直接并发使用时落在不同分片的写入互不阻塞；
+//     调用方自己在外层加锁串行写入时（如 chart/chart 的 HybridLeaderboard），分片不会带来写入并行；
+//   - 锁顺序：跨分片操作一律按分片下标升序加锁，读操作锁住从分片 0 到目标分片的前缀，
+//     保证聚合出的排名与单个跳表一致，不会观察到玩家跨分片移动的中间状态。
+package leaderboardcore
+
+import (
+	"sort"
+	"time"
+)
+
+// RankIndex 排名索引，SkipList 与 ShardedSkipList 均实现该接口
+type RankIndex interface {
+	Insert(player *Player)
+	Delete(player *Player) bool
+	UpdateScore(player *Player, newScore, newSecondary int64)
+	Length() int
+	GetRange(start, end int) []*Player
+	GetRankByPlayer(player *Player) (int, bool)
+	GetRankByScore(score int64) int
+	GetCompetitionRank(score, secondary int64) int
+	GetRanksByPlayers(players []*Player) []int
+	Iterate(after *Player, fn func(rank int, player *Player) bool)
+}
+
+var (
+	_ RankIndex = (*SkipList)(nil)
+	_ RankIndex = (*ShardedSkipList)(nil)
+)
+
+// NewRankIndex 创建按指定方向排序的排名索引，未给出分片边界时使用单个跳表
+func NewRankIndex(order SortOrder, shardBoundaries []int64) RankIndex {
+	if len(shardBoundaries) == 0 {
+		return NewSkipListWithOrder(order)
+	}
+	return NewShardedSkipListWithOrder(order, shardBoundaries)
+}
+
+// ShardedSkipList 按分数区间分片的跳表
+type ShardedSkipList struct {
+	order  SortOrder
+	bounds []int64     // 分片边界，按排序方向严格有序
+	shards []*SkipList // len(bounds)+1 个分片
+}
+
+// NewShardedSkipList 创建分数高者在前的分片跳表，bounds 会被排序去重为严格降序
+func NewShardedSkipList(bounds []int64) *ShardedSkipList {
+	return NewShardedSkipListWithOrder(Descending, bounds)
+}
+
+// NewShardedSkipListWithOrder 创建按指定方向排序的分片跳表，bounds 会按该方向排序去重
+func NewShardedSkipListWithOrder(order SortOrder, bounds []int64) *ShardedSkipList {
+	sorted := append([]int64(nil), bounds...)
+	sort.Slice(sorted, func(i, j int) bool { return order.Better(sorted[i], sorted[j]) })
+	uniq := sorted[:0]
+	for i, b := range sorted {
+		if i == 0 || b != sorted[i-1] {
+			uniq = append(uniq, b)
+		}
+	}
+
+	ssl := &ShardedSkipList{
+		order:  order,
+		bounds: uniq,
+		shards: make([]*SkipList, len(uniq)+1),
+	}
+	for i := range ssl.shards {
+		ssl.shards[i] = NewSkipListWithOrder(order)
+	}
+	return ssl
+}
+
+// shardOf 返回分数所属的分片下标 - O(log 分片数)
+func (ssl *ShardedSkipList) shardOf(score int64) int {
+	return sort.Search(len(ssl.bounds), func(i int) bool { return !ssl.order.Better(ssl.bounds[i], score) })
+}
+
+// rlockPrefix 按升序对分片 [0, n] 加读锁，返回对应的解锁函数
+func (ssl *ShardedSkipList) rlockPrefix(n int) func() {
+	for i := 0; i <= n; i++ {
+		ssl.shards[i].mu.RLock()
+	}
+	return func() {
+		for i := n; i >= 0; i-- {
+			ssl.shards[i].mu.RUnlock()
+		}
+	}
+}
+
+// offset 返回分片 idx 之前所有分片的玩家总数，调用方需持有这些分片的读锁
+func (ssl *ShardedSkipList) offset(idx int) int {
+	n := 0
+	for i := 0; i < idx; i++ {
+		n += ssl.shards[i].length
+	}
+	return n
+}
+
+// Insert 插入玩家，只锁定其所属分片
+func (ssl *ShardedSkipList) Insert(player *Player) {
+	ssl.shards[ssl.shardOf(player.Score)].Insert(player)
+}
+
+// Delete 删除玩家，player 的排序字段必须与插入时一致
+func (ssl *ShardedSkipList) Delete(player *Player) bool {
+	return ssl.shards[ssl.shardOf(player.Score)].Delete(player)
+}
+
+// UpdateScore 更新分数；跨分片移动时按下标升序锁定新旧两个分片
+func (ssl *ShardedSkipList) UpdateScore(player *Player, newScore, newSecondary int64) {
+	from, to := ssl.shardOf(player.Score), ssl.shardOf(newScore)
+	if from == to {
+		ssl.shards[from].UpdateScore(player, newScore, newSecondary)
+		return
+	}
+
+	first, second := ssl.shards[min(from, to)], ssl.shards[max(from, to)]
+	first.mu.Lock()
+	defer first.mu.Unlock()
+	second.mu.Lock()
+	defer second.mu.Unlock()
+
+	if x := ssl.shards[from].unlinkNode(player); x != nil {
+		player.Score = newScore
+		player.SecondaryScore = newSecondary
+		player.UpdateTime = time.Now()
+		ssl.shards[to].linkNode(x)
+	}
+}
+
+// Length 返回玩家总数
+func (ssl *ShardedSkipList) Length() int {
+	unlock := ssl.rlockPrefix(len(ssl.shards) - 1)
+	defer unlock()
+	return ssl.offset(len(ssl.shards))
+}
+
+// GetRange 获取排名区间 [start, end] 内的玩家，跳过整段落在区间之前的分片 - O(分片数 + log n + k)
+func (ssl *ShardedSkipList) GetRange(start, end int) []*Player {
+	unlock := ssl.rlockPrefix(len(ssl.shards) - 1)
+	defer unlock()
+
+	start = max(1, start)
+	var result []*Player
+	before := 0
+	for _, shard := range ssl.shards {
+		if before >= end {
+			break
+		}
+		if before+shard.length >= start {
+			result = append(result, shard.getRange(start-before, end-before)...)
+		}
+		before += shard.length
+	}
+	return result
+}
+
+// GetRankByPlayer 根据玩家排序键获取排名 - O(分片数 + log n)
+func (ssl *ShardedSkipList) GetRankByPlayer(player *Player) (int, bool) {
+	idx := ssl.shardOf(player.Score)
+	unlock := ssl.rlockPrefix(idx)
+	defer unlock()
+
+	rank, found := ssl.shards[idx].getRankByPlayer(player)
+	if !found {
+		return 0, false
+	}
+	return ssl.offset(idx) + rank, true
+}
+
+// GetRankByScore 返回以给定分数新提交时将会获得的排名
+func (ssl *ShardedSkipList) GetRankByScore(score int64) int {
+	idx := ssl.shardOf(score)
+	unlock := ssl.rlockPrefix(idx)
+	defer unlock()
+
+	return ssl.offset(idx) + ssl.shards[idx].getRankByScore(score)
+}
+
+// GetCompetitionRank 返回排序键为（score, secondary）的玩家的竞赛排名
+// 排序键靠前的玩家只会落在同一或更靠前的分片中。
+func (ssl *ShardedSkipList) GetCompetitionRank(score, secondary int64) int {
+	idx := ssl.shardOf(score)
+	unlock := ssl.rlockPrefix(idx)
+	defer unlock()
+
+	return ssl.offset(idx) + ssl.shards[idx].getCompetitionRank(score, secondary)
+}
+
+// GetRanksByPlayers 批量获取排名，players 必须已按排序方向从前到后排列
+// 有序的 players 按分片连续分组，每组在对应分片内单趟查找。
+func (ssl *ShardedSkipList) GetRanksByPlayers(players []*Player) []int {
+	ranks := make([]int, len(players))
+	if len(players) == 0 {
+		return ranks
+	}
+	unlock := ssl.rlockPrefix(ssl.shardOf(players[len(players)-1].Score))
+	defer unlock()
+
+	for lo := 0; lo < len(players); {
+		idx := ssl.shardOf(players[lo].Score)
+		hi := lo + 1
+		for hi < len(players) && ssl.shardOf(players[hi].Score) == idx {
+			hi++
+		}
+		off := ssl.offset(idx)
+		for i, r := range ssl.shards[idx].getRanksByPlayers(players[lo:hi]) {
+			if r > 0 {
+				ranks[lo+i] = off + r
+			}
+		}
+		lo = hi
+	}
+	return ranks
+}
+
+// Iterate 按排名顺序遍历排序键低于 after 的玩家，after 为 nil 时从第一名开始
+// 遍历期间持有全部分片的读锁，fn 不应执行耗时操作。
+func (ssl *ShardedSkipList) Iterate(after *Player, fn func(rank int, player *Player) bool) {
+	unlock := ssl.rlockPrefix(len(ssl.shards) - 1)
+	defer unlock()
+
+	first := 0
+	if after != nil {
+		first = ssl.shardOf(after.Score)
+	}
+	stopped := false
+	for idx := first; idx < len(ssl.shards) && !stopped; idx++ {
+		var from *Player
+		if idx == first {
+			from = after
+		}
+		off := ssl.offset(idx)
+		ssl.shards[idx].iterate(from, func(rank int, p *Player) bool {
+			if !fn(off+rank, p) {
+				stopped = true
+				return false
+			}
+			return true
+		})
+	}
+}
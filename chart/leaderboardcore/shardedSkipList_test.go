@@ -0,0 +1,222 @@
+package leaderboardcore
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// 分片跳表与单个跳表在随机插入、更新、删除后的排名与区间结果一致
+func TestShardedSkipListMatchesSkipList(t *testing.T) {
+	single := NewSkipList()
+	sharded := NewShardedSkipList([]int64{100, 500, 300, 500})
+	if len(sharded.shards) != 4 {
+		t.Fatalf("shards: got=%d want=4", len(sharded.shards))
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	pairs := make(map[int64][2]*Player)
+	for i := int64(1); i <= 2000; i++ {
+		a, b := NewPlayer(i, rnd.Int63n(700)), &Player{}
+		*b = *a
+		single.Insert(a)
+		sharded.Insert(b)
+		pairs[i] = [2]*Player{a, b}
+	}
+	for i := 0; i < 3000; i++ {
+		id := rnd.Int63n(2000) + 1
+		p, ok := pairs[id]
+		if !ok {
+			continue
+		}
+		if i%10 == 0 {
+			single.Delete(p[0])
+			sharded.Delete(p[1])
+			delete(pairs, id)
+		} else {
+			score := rnd.Int63n(700)
+			single.UpdateScore(p[0], score, 0)
+			sharded.UpdateScore(p[1], score, 0)
+			// 两边的更新时间需一致，排序结果才可比较
+			single.Delete(p[0])
+			p[0].UpdateTime = p[1].UpdateTime
+			single.Insert(p[0])
+		}
+	}
+
+	if single.Length() != sharded.Length() {
+		t.Fatalf("length: single=%d sharded=%d", single.Length(), sharded.Length())
+	}
+	for id, p := range pairs {
+		want, _ := single.GetRankByPlayer(p[0])
+		got, ok := sharded.GetRankByPlayer(p[1])
+		if !ok || got != want {
+			t.Fatalf("player %d: rank=%d ok=%v want=%d", id, got, ok, want)
+		}
+	}
+	for _, score := range []int64{-1, 0, 99, 100, 301, 500, 699, 1000} {
+		if got, want := sharded.GetRankByScore(score), single.GetRankByScore(score); got != want {
+			t.Fatalf("GetRankByScore(%d): got=%d want=%d", score, got, want)
+		}
+	}
+	for _, score := range []int64{-1, 0, 99, 100, 301, 500, 699, 1000} {
+		if got, want := sharded.GetCompetitionRank(score, 0), single.GetCompetitionRank(score, 0); got != want {
+			t.Fatalf("GetCompetitionRank(%d): got=%d want=%d", score, got, want)
+		}
+	}
+	for _, r := range [][2]int{{1, 10}, {95, 420}, {1, single.Length()}, {single.Length() - 3, single.Length() + 5}} {
+		want, got := idsOf(single.GetRange(r[0], r[1])), idsOf(sharded.GetRange(r[0], r[1]))
+		if len(want) != len(got) {
+			t.Fatalf("GetRange%v: got %d players want %d", r, len(got), len(want))
+		}
+		for i := range want {
+			if want[i] != got[i] {
+				t.Fatalf("GetRange%v[%d]: got=%d want=%d", r, i, got[i], want[i])
+			}
+		}
+	}
+
+	all := sharded.GetRange(1, sharded.Length())
+	ranks := sharded.GetRanksByPlayers(all)
+	rank := 0
+	sharded.Iterate(all[99], func(r int, p *Player) bool {
+		rank = r
+		return r < 300
+	})
+	if rank != 300 {
+		t.Fatalf("Iterate stopped at rank %d, want 300", rank)
+	}
+	for i := range all {
+		if ranks[i] != i+1 {
+			t.Fatalf("GetRanksByPlayers[%d]: got=%d want=%d", i, ranks[i], i+1)
+		}
+	}
+
+	// 竞赛排名：同分玩家并列，取同分组第一人的序号
+	for i, p := range all {
+		want := i + 1
+		if i > 0 && all[i-1].Score == p.Score {
+			want = sharded.GetCompetitionRank(all[i-1].Score, 0)
+		}
+		if got := sharded.GetCompetitionRank(p.Score, p.SecondaryScore); got != want {
+			t.Fatalf("GetCompetitionRank of rank %d (score %d): got=%d want=%d", i+1, p.Score, got, want)
+		}
+	}
+}
+
+// 并发写入不同分片时排名聚合保持正确（配合 -race 运行）
+func TestShardedSkipListConcurrent(t *testing.T) {
+	sharded := NewShardedSkipList([]int64{750, 500, 250})
+
+	const workers, perWorker = 8, 500
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(int64(w)))
+			for i := 0; i < perWorker; i++ {
+				p := NewPlayer(int64(w*perWorker+i), rnd.Int63n(1000))
+				sharded.Insert(p)
+				sharded.UpdateScore(p, rnd.Int63n(1000), 0)
+				if _, ok := sharded.GetRankByPlayer(p); !ok {
+					t.Errorf("player %d not found after update", p.ID)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if n := sharded.Length(); n != workers*perWorker {
+		t.Fatalf("length: got=%d want=%d", n, workers*perWorker)
+	}
+	all := sharded.GetRange(1, workers*perWorker)
+	for i := 1; i < len(all); i++ {
+		if Compare(all[i-1], all[i]) <= 0 {
+			t.Fatalf("players not ordered at rank %d", i+1)
+		}
+	}
+}
+
+func BenchmarkSkipListParallelInsert(b *testing.B) {
+	benchmarkParallelInsert(b, NewSkipList())
+}
+
+func BenchmarkShardedSkipListParallelInsert(b *testing.B) {
+	bounds := make([]int64, 15)
+	for i := range bounds {
+		bounds[i] = int64(i+1) * 1 << 20 / 16
+	}
+	benchmarkParallelInsert(b, NewShardedSkipList(bounds))
+}
+
+func benchmarkParallelInsert(b *testing.B, index RankIndex) {
+	var seq int64
+	b.RunParallel(func(pb *testing.PB) {
+		rnd := rand.New(rand.NewSource(rand.Int63()))
+		for pb.Next() {
+			index.Insert(NewPlayer(atomic.AddInt64(&seq, 1), rnd.Int63n(1<<20)))
+		}
+	})
+}
+
+func idsOf(players []*Player) []int64 {
+	ids := make([]int64, 0, len(players))
+	for _, p := range players {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+// 升序排行榜：分数低者在前，分片边界按升序划分，排名与单个升序跳表一致
+func TestAscendingOrder(t *testing.T) {
+	single := NewSkipListWithOrder(Ascending)
+	sharded := NewShardedSkipListWithOrder(Ascending, []int64{300, 100, 500})
+	if want := []int64{100, 300, 500}; sharded.bounds[0] != want[0] || sharded.bounds[2] != want[2] {
+		t.Fatalf("bounds: got=%v want=%v", sharded.bounds, want)
+	}
+
+	rnd := rand.New(rand.NewSource(2))
+	players := make([][2]*Player, 0, 1000)
+	for i := int64(1); i <= 1000; i++ {
+		a, b := NewPlayer(i, rnd.Int63n(700)), &Player{}
+		*b = *a
+		single.Insert(a)
+		sharded.Insert(b)
+		players = append(players, [2]*Player{a, b})
+	}
+
+	all := single.GetRange(1, single.Length())
+	for i := 1; i < len(all); i++ {
+		if all[i-1].Score > all[i].Score {
+			t.Fatalf("rank %d score %d is after rank %d score %d", i+1, all[i].Score, i, all[i-1].Score)
+		}
+	}
+	for _, p := range players {
+		want, _ := single.GetRankByPlayer(p[0])
+		if got, ok := sharded.GetRankByPlayer(p[1]); !ok || got != want {
+			t.Fatalf("player %d: rank=%d ok=%v want=%d", p[0].ID, got, ok, want)
+		}
+	}
+	for _, score := range []int64{-1, 0, 99, 100, 301, 500, 699, 1000} {
+		if got, want := sharded.GetRankByScore(score), single.GetRankByScore(score); got != want {
+			t.Fatalf("GetRankByScore(%d): got=%d want=%d", score, got, want)
+		}
+		if got, want := sharded.GetCompetitionRank(score, 0), single.GetCompetitionRank(score, 0); got != want {
+			t.Fatalf("GetCompetitionRank(%d): got=%d want=%d", score, got, want)
+		}
+	}
+	if got := single.GetRankByScore(-1); got != 1 {
+		t.Fatalf("lowest score should rank first, got %d", got)
+	}
+
+	// 跨分片更新后仍按升序排列
+	p := players[0]
+	single.UpdateScore(p[0], -5, 0)
+	sharded.UpdateScore(p[1], -5, 0)
+	if r, _ := sharded.GetRankByPlayer(p[1]); r != 1 {
+		t.Fatalf("updated player rank=%d want=1", r)
+	}
+}
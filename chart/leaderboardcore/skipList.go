@@ -0,0 +1,520 @@
+package leaderboardcore
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// SkipListNode 跳表节点
+type SkipListNode struct {
+	Player   *Player         // 节点承载的玩家对象；头哨兵节点通常为 nil
+	Backward *SkipListNode   // 第 0 层的后退指针，便于反向遍历与维护 tail
+	Level    []SkipListLevel // 各层级的结构信息（前进指针与跨度），长度为该节点高度
+}
+
+// SkipListLevel 跳表层级
+type SkipListLevel struct {
+	Forward *SkipListNode // 本层的前进指针，指向同层的下一个节点；nil 表示该层末尾
+	Span    int           // 从当前节点沿本层 Forward 跳到下一节点时跨过的“排名数量”，用于累计 rank
+}
+
+// SkipList 跳表
+type SkipList struct {
+	header *SkipListNode // 头哨兵节点（不承载玩家），拥有最大层级的 Level，所有查找/插入从此开始
+	tail   *SkipListNode // 第 0 层的尾节点指针，便于末端操作与反向遍历
+	length int           // 当前玩家节点数量，用于边界校验与复杂度估算
+	level  int           // 跳表当前使用的最高层数（1..maxSkipListLevel），决定自顶向下查找的起始层
+	order  SortOrder     // 主分数的排序方向
+	mu     sync.RWMutex  // 并发读写锁：读操作使用 RLock，写操作（插入/删除/更新）使用 Lock，保障线程安全
+}
+
+const (
+	maxSkipListLevel = 32
+	skipListP        = 0.25
+)
+
+// NewSkipList 创建分数高者在前的跳表
+func NewSkipList() *SkipList {
+	return NewSkipListWithOrder(Descending)
+}
+
+// NewSkipListWithOrder 创建按指定方向排序的跳表
+func NewSkipListWithOrder(order SortOrder) *SkipList {
+	// 构造跳表：
+	// - 初始最高层数为 1；
+	// - header 为哨兵节点，预分配 maxSkipListLevel 层；
+	// 复杂度：O(1)
+	sl := &SkipList{
+		level: 1,
+		order: order,
+		header: &SkipListNode{
+			Level: make([]SkipListLevel, maxSkipListLevel),
+		},
+	}
+	return sl
+}
+
+// randomLevel 随机生成层级
+func (sl *SkipList) randomLevel() int {
+	// 随机生成节点高度：以概率 p 递增层级，最大不超过 maxSkipListLevel。
+	// 该策略使得期望复杂度保持在 O(log n)。
+	level := 1
+	for rand.Float32() < skipListP && level < maxSkipListLevel {
+		level++
+	}
+	return level
+}
+
+// Delete 删除节点
+func (sl *SkipList) Delete(player *Player) bool {
+	// 删除指定玩家的节点：写锁保护。
+	// 按排序键（分数、更新时间、ID）定位，player 的排序字段必须与插入时一致。
+	// 复杂度：O(log n)
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+
+	return sl.deleteNode(player)
+}
+
+// GetRange 获取排名范围内的玩家
+func (sl *SkipList) GetRange(start, end int) []*Player {
+	// 返回给定排名区间 [start, end] 的玩家切片：读锁保护。
+	// 先通过各层 span 快速定位到 start，再在第 0 层按节点顺序遍历到 end。
+	// 复杂度：O(log n + k)，k 为区间长度。
+	sl.mu.RLock()
+	defer sl.mu.RUnlock()
+	return sl.getRange(start, end)
+}
+
+// getRange GetRange 的无锁实现，调用方需持有读锁
+func (sl *SkipList) getRange(start, end int) []*Player {
+	if start < 1 {
+		start = 1
+	}
+	if end > sl.length {
+		end = sl.length
+	}
+	if start > end {
+		return nil
+	}
+
+	result := make([]*Player, 0, end-start+1)
+	traversed := 0
+	x := sl.header
+
+	// 自顶向下定位到 rank=start 的前一位置，保持不越过 start
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.Level[i].Forward != nil && (traversed+x.Level[i].Span) < start {
+			traversed += x.Level[i].Span
+			x = x.Level[i].Forward
+		}
+	}
+
+	// 下一个节点即为 rank=start
+	x = x.Level[0].Forward
+	currentRank := traversed + 1
+
+	// 遍历范围内的节点，直到 rank=end
+	for x != nil && currentRank <= end {
+		result = append(result, x.Player)
+		x = x.Level[0].Forward
+		currentRank++
+	}
+
+	return result
+}
+
+// Length 获取跳表长度
+func (sl *SkipList) Length() int {
+	// 返回跳表当前长度：读锁保护，O(1)
+	sl.mu.RLock()
+	defer sl.mu.RUnlock()
+	return sl.length
+}
+
+// SortOrder 主分数的排序方向
+type SortOrder string
+
+const (
+	Descending SortOrder = "desc" // 分数高者在前（默认）
+	Ascending  SortOrder = "asc"  // 分数低者在前，如最短用时
+)
+
+// Valid 判断排序方向是否合法，空值表示默认的 desc
+func (o SortOrder) Valid() bool {
+	switch o {
+	case "", Descending, Ascending:
+		return true
+	default:
+		return false
+	}
+}
+
+// Better 判断主分数 a 是否排在 b 之前
+func (o SortOrder) Better(a, b int64) bool {
+	if o == Ascending {
+		return a < b
+	}
+	return a > b
+}
+
+// Compare 按该方向比较两个玩家的排序键，只有主分数的方向随之改变，其余规则与 Compare 相同
+func (o SortOrder) Compare(p1, p2 *Player) int {
+	if o.Better(p1.Score, p2.Score) {
+		return 1
+	}
+	if o.Better(p2.Score, p1.Score) {
+		return -1
+	}
+	return compareTies(p1, p2)
+}
+
+// keyAbove 判断玩家的（主分数，次级分数）是否严格排在给定值之前
+func (o SortOrder) keyAbove(p *Player, score, secondary int64) bool {
+	return o.Better(p.Score, score) || (p.Score == score && p.SecondaryScore > secondary)
+}
+
+// Compare 比较两个玩家的排序键，分数高者在前
+func Compare(p1, p2 *Player) int {
+	// 排序规则：分数优先，其次次级分数（较高者更前），再次更新时间（先更新者更前），最后 ID。
+	// 返回值：1 表示 p1 更“高”（排在前面），-1 表示 p2 更高，0 表示完全相等。
+	return Descending.Compare(p1, p2)
+}
+
+// compareTies 比较主分数相同的两个玩家
+func compareTies(p1, p2 *Player) int {
+	// 分数相同时，按次级分数排序（较高的排前面）
+	if p1.SecondaryScore > p2.SecondaryScore {
+		return 1
+	}
+	if p1.SecondaryScore < p2.SecondaryScore {
+		return -1
+	}
+	// 次级分数也相同，按更新时间排序（先更新的排前面）
+	if p1.UpdateTime.Before(p2.UpdateTime) {
+		return 1
+	}
+	if p1.UpdateTime.After(p2.UpdateTime) {
+		return -1
+	}
+	// 更新时间也相同，按ID排序
+	if p1.ID < p2.ID {
+		return 1
+	}
+	if p1.ID > p2.ID {
+		return -1
+	}
+	return 0
+}
+
+// Insert 插入节点（优化版）
+func (sl *SkipList) Insert(player *Player) {
+	// 插入玩家节点：写锁保护，随后交给无锁的 insertNode。
+	// 复杂度：期望 O(log n)
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+
+	sl.insertNode(player)
+}
+
+// GetRankByPlayer 根据玩家分数键获取排名（按排序键查找）
+// 使用与插入相同的比较逻辑 Compare，自顶向下按 span 累计 rank。
+// 复杂度：O(log n)
+func (sl *SkipList) GetRankByPlayer(player *Player) (int, bool) {
+	sl.mu.RLock()
+	defer sl.mu.RUnlock()
+	return sl.getRankByPlayer(player)
+}
+
+// getRankByPlayer GetRankByPlayer 的无锁实现，调用方需持有读锁
+func (sl *SkipList) getRankByPlayer(player *Player) (int, bool) {
+	rank := 0
+	x := sl.header
+
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.Level[i].Forward != nil && sl.order.Compare(x.Level[i].Forward.Player, player) > 0 {
+			rank += x.Level[i].Span
+			x = x.Level[i].Forward
+		}
+	}
+
+	x = x.Level[0].Forward
+	if x != nil && x.Player.ID == player.ID {
+		return rank + 1, true
+	}
+	return 0, false
+}
+
+// GetRankByScore 返回以给定分数新提交时将会获得的排名（不修改跳表）
+// 新提交的更新时间晚于所有已有玩家，同分时排在已有玩家之后，
+// 因此排名为分数不差于 score 的玩家数加一。复杂度：O(log n)
+func (sl *SkipList) GetRankByScore(score int64) int {
+	sl.mu.RLock()
+	defer sl.mu.RUnlock()
+	return sl.getRankByScore(score)
+}
+
+// getRankByScore GetRankByScore 的无锁实现，调用方需持有读锁
+func (sl *SkipList) getRankByScore(score int64) int {
+	rank := 0
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.Level[i].Forward != nil && !sl.order.Better(score, x.Level[i].Forward.Player.Score) {
+			rank += x.Level[i].Span
+			x = x.Level[i].Forward
+		}
+	}
+	return rank + 1
+}
+
+// GetCompetitionRank 返回排序键为（score, secondary）的玩家的竞赛排名（不修改跳表）
+// 竞赛排名下主分数与次级分数都相同的玩家并列，排名为排序键严格靠前的玩家数加一，
+// 如 1、1、3。复杂度：O(log n)
+func (sl *SkipList) GetCompetitionRank(score, secondary int64) int {
+	sl.mu.RLock()
+	defer sl.mu.RUnlock()
+	return sl.getCompetitionRank(score, secondary)
+}
+
+// getCompetitionRank GetCompetitionRank 的无锁实现，调用方需持有读锁
+func (sl *SkipList) getCompetitionRank(score, secondary int64) int {
+	rank := 0
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.Level[i].Forward != nil && sl.order.keyAbove(x.Level[i].Forward.Player, score, secondary) {
+			rank += x.Level[i].Span
+			x = x.Level[i].Forward
+		}
+	}
+	return rank + 1
+}
+
+// GetRanksByPlayers 批量获取玩家排名（按排序键单趟查找）
+// players 必须已按跳表的排序方向从前到后排列；返回与 players 一一对应的排名，未找到为 0。
+// 每次查找从上一个玩家在各层的前驱继续向前，整个批次只需一次读锁、单向遍历跳表。
+// 复杂度：O(k log n)，目标密集时接近 O(n) 的一次顺序遍历。
+func (sl *SkipList) GetRanksByPlayers(players []*Player) []int {
+	sl.mu.RLock()
+	defer sl.mu.RUnlock()
+	return sl.getRanksByPlayers(players)
+}
+
+// getRanksByPlayers GetRanksByPlayers 的无锁实现，调用方需持有读锁
+func (sl *SkipList) getRanksByPlayers(players []*Player) []int {
+	ranks := make([]int, len(players))
+	var prev [maxSkipListLevel]*SkipListNode // 上一个目标在各层的前驱
+	var prevRank [maxSkipListLevel]int       // 各层前驱的排名
+	for i := range prev {
+		prev[i] = sl.header
+	}
+
+	for idx, player := range players {
+		x, rank := sl.header, 0
+		for i := sl.level - 1; i >= 0; i-- {
+			// 上一个目标的前驱不会越过当前目标，可直接从更靠后的位置继续
+			if prevRank[i] > rank {
+				x, rank = prev[i], prevRank[i]
+			}
+			for x.Level[i].Forward != nil && sl.order.Compare(x.Level[i].Forward.Player, player) > 0 {
+				rank += x.Level[i].Span
+				x = x.Level[i].Forward
+			}
+			prev[i], prevRank[i] = x, rank
+		}
+
+		if next := x.Level[0].Forward; next != nil && next.Player.ID == player.ID {
+			ranks[idx] = rank + 1
+		}
+	}
+	return ranks
+}
+
+// Iterate 按排名顺序遍历排序键低于 after 的玩家，after 为 nil 时从第一名开始
+// fn 返回 false 时停止遍历。定位 O(log n)，之后沿第 0 层顺序前进，不分配结果切片。
+// 遍历期间持有读锁，fn 不应执行耗时操作，也不得修改跳表。
+func (sl *SkipList) Iterate(after *Player, fn func(rank int, player *Player) bool) {
+	sl.mu.RLock()
+	defer sl.mu.RUnlock()
+	sl.iterate(after, fn)
+}
+
+// iterate Iterate 的无锁实现，调用方需持有读锁
+func (sl *SkipList) iterate(after *Player, fn func(rank int, player *Player) bool) {
+	rank := 0
+	x := sl.header
+	if after != nil {
+		for i := sl.level - 1; i >= 0; i-- {
+			for x.Level[i].Forward != nil && sl.order.Compare(x.Level[i].Forward.Player, after) >= 0 {
+				rank += x.Level[i].Span
+				x = x.Level[i].Forward
+			}
+		}
+	}
+
+	for x = x.Level[0].Forward; x != nil; x = x.Level[0].Forward {
+		rank++
+		if !fn(rank, x.Player) {
+			return
+		}
+	}
+}
+
+// UpdateScore 更新分数（需要删除再插入）
+func (sl *SkipList) UpdateScore(player *Player, newScore, newSecondary int64) {
+	// 更新分数：写锁保护。
+	// 流程：摘除旧节点 -> 更新分数与时间 -> 将同一节点按新排序键重新链入。
+	// 复用节点及其层级（层高与分数无关，仍满足随机分布），更新不产生堆分配。
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+
+	if x := sl.unlinkNode(player); x != nil {
+		player.Score = newScore
+		player.SecondaryScore = newSecondary
+		player.UpdateTime = time.Now()
+		sl.linkNode(x)
+	}
+}
+
+// inlineLevels 与节点一同分配的层级数上限
+// p=0.25 时约 94% 的节点不超过 2 层，这些节点只需一次分配。
+const inlineLevels = 2
+
+// newSkipListNode 创建指定层高的节点，低层节点的层级与节点本身一次分配
+func newSkipListNode(player *Player, level int) *SkipListNode {
+	switch level {
+	case 1:
+		n := &struct {
+			node   SkipListNode
+			levels [1]SkipListLevel
+		}{}
+		n.node.Player, n.node.Level = player, n.levels[:]
+		return &n.node
+	case inlineLevels:
+		n := &struct {
+			node   SkipListNode
+			levels [inlineLevels]SkipListLevel
+		}{}
+		n.node.Player, n.node.Level = player, n.levels[:]
+		return &n.node
+	default:
+		return &SkipListNode{Player: player, Level: make([]SkipListLevel, level)}
+	}
+}
+
+// deleteNode 内部删除节点方法
+func (sl *SkipList) deleteNode(player *Player) bool {
+	return sl.unlinkNode(player) != nil
+}
+
+// unlinkNode 将玩家节点从跳表中摘除并返回该节点，未找到时返回 nil
+func (sl *SkipList) unlinkNode(player *Player) *SkipListNode {
+	// 内部删除：按排序键自顶向下定位（与插入使用同一 Compare），
+	// 命中同一 ID 后维护各层 span 与 Forward。
+	// 若删除的是尾节点，更新 tail；必要时降低最高层 level。
+	// 查找路径记录在栈上的定长数组中，不产生堆分配。
+	// 复杂度：O(log n)
+	var update [maxSkipListLevel]*SkipListNode
+	x := sl.header
+
+	// 查找节点
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.Level[i].Forward != nil &&
+			sl.order.Compare(x.Level[i].Forward.Player, player) > 0 {
+			x = x.Level[i].Forward
+		}
+		update[i] = x
+	}
+
+	x = x.Level[0].Forward
+	if x == nil || x.Player.ID != player.ID {
+		return nil
+	}
+
+	for i := 0; i < sl.level; i++ {
+		if update[i].Level[i].Forward == x {
+			update[i].Level[i].Span += x.Level[i].Span - 1
+			update[i].Level[i].Forward = x.Level[i].Forward
+		} else {
+			update[i].Level[i].Span--
+		}
+	}
+
+	if x.Level[0].Forward != nil {
+		x.Level[0].Forward.Backward = x.Backward
+	} else {
+		sl.tail = x.Backward
+	}
+
+	for sl.level > 1 && sl.header.Level[sl.level-1].Forward == nil {
+		sl.level--
+	}
+	sl.length--
+	return x
+}
+
+// insertNode 内部插入节点方法，调用方需持有写锁
+func (sl *SkipList) insertNode(player *Player) {
+	sl.linkNode(newSkipListNode(player, sl.randomLevel()))
+}
+
+// linkNode 按节点玩家的排序键将节点链入跳表，层高取节点已有的层级数
+func (sl *SkipList) linkNode(x *SkipListNode) {
+	// 内部插入：不加锁（调用方已加锁）。
+	// 维护各层 Forward/Span 与 Backward/tail；查找路径使用栈上的定长数组。
+	// 复杂度：期望 O(log n)
+	var update [maxSkipListLevel]*SkipListNode
+	var rank [maxSkipListLevel]int
+	player, level := x.Player, len(x.Level)
+	cur := sl.header
+
+	// 自顶向下定位插入点
+	for i := sl.level - 1; i >= 0; i-- {
+		if i < sl.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for cur.Level[i].Forward != nil &&
+			sl.order.Compare(cur.Level[i].Forward.Player, player) > 0 {
+			rank[i] += cur.Level[i].Span
+			cur = cur.Level[i].Forward
+		}
+		update[i] = cur
+	}
+
+	// 节点层高超过当前最高层时提升最高层
+	if level > sl.level {
+		for i := sl.level; i < level; i++ {
+			rank[i] = 0
+			update[i] = sl.header
+			update[i].Level[i].Span = sl.length
+		}
+		sl.level = level
+	}
+
+	// 更新各层指针与 span
+	for i := 0; i < level; i++ {
+		x.Level[i].Forward = update[i].Level[i].Forward
+		update[i].Level[i].Forward = x
+		x.Level[i].Span = update[i].Level[i].Span - (rank[0] - rank[i])
+		update[i].Level[i].Span = (rank[0] - rank[i]) + 1
+	}
+
+	// 更新更高层的 span（新节点未触达的层）
+	for i := level; i < sl.level; i++ {
+		update[i].Level[i].Span++
+	}
+
+	// 维护后退指针与尾指针
+	if update[0] == sl.header {
+		x.Backward = nil
+	} else {
+		x.Backward = update[0]
+	}
+	if x.Level[0].Forward != nil {
+		x.Level[0].Forward.Backward = x
+	} else {
+		sl.tail = x
+	}
+
+	sl.length++
+}
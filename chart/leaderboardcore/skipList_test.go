@@ -0,0 +1,38 @@
+package leaderboardcore
+
+import "testing"
+
+// 10 万次插入的耗时与分配次数；查找路径使用栈上的定长数组，
+// 不超过 inlineLevels 层的节点与其层级一次分配，约每次插入 1 次分配
+func BenchmarkSkipListInsert100k(b *testing.B) {
+	players := make([]*Player, 100000)
+	for i := range players {
+		players[i] = NewPlayer(int64(i), int64(i*7919%100000))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		sl := NewSkipList()
+		for _, p := range players {
+			sl.Insert(p)
+		}
+	}
+}
+
+// 10 万个玩家上的分数更新（摘除 + 重新链入同一节点），不产生分配
+func BenchmarkSkipListUpdateScore100k(b *testing.B) {
+	sl := NewSkipList()
+	players := make([]*Player, 100000)
+	for i := range players {
+		players[i] = NewPlayer(int64(i), int64(i))
+		sl.Insert(players[i])
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		p := players[n%len(players)]
+		sl.UpdateScore(p, p.Score+int64(n%1000), 0)
+	}
+}
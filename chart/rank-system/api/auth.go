@@ -0,0 +1,186 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"rank-system/types"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthPolicy 路由的鉴权策略
+type AuthPolicy int
+
+const (
+	// AuthRequired 必须携带有效凭证，写接口使用
+	AuthRequired AuthPolicy = iota
+	// AuthRead 查询接口：配置 PublicReads 时可匿名访问，携带凭证时仍会校验
+	AuthRead
+)
+
+// apiKeySubject 通过API密钥认证的请求在上下文中记录的用户ID
+const apiKeySubject = "api-key"
+
+var (
+	errNoCredentials  = errors.New("missing credentials")
+	errInvalidAPIKey  = errors.New("invalid api key")
+	errMalformedToken = errors.New("malformed token")
+	errTokenSignature = errors.New("invalid token signature")
+	errTokenExpired   = errors.New("token expired")
+	errTokenNotYet    = errors.New("token not valid yet")
+	errTokenIssuer    = errors.New("invalid token issuer")
+)
+
+// Authenticator 校验请求携带的API密钥（X-API-Key）或 Bearer JWT
+type Authenticator struct {
+	apiKeys     [][]byte
+	jwtSecret   []byte
+	jwtIssuer   string
+	publicReads bool
+}
+
+// NewAuthenticator 根据配置创建鉴权器，未配置任何鉴权方式时返回 nil
+func NewAuthenticator(cfg types.AuthConfig) *Authenticator {
+	if !cfg.Enabled() {
+		return nil
+	}
+	a := &Authenticator{
+		jwtIssuer:   cfg.JWTIssuer,
+		publicReads: cfg.PublicReads,
+	}
+	for _, key := range cfg.APIKeys {
+		if key != "" {
+			a.apiKeys = append(a.apiKeys, []byte(key))
+		}
+	}
+	if cfg.JWTSecret != "" {
+		a.jwtSecret = []byte(cfg.JWTSecret)
+	}
+	return a
+}
+
+// Middleware 返回指定策略的鉴权中间件，认证通过后将用户ID写入上下文
+func (a *Authenticator) Middleware(policy AuthPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		subject, err := a.authenticate(c.Request)
+		if errors.Is(err, errNoCredentials) && policy == AuthRead && a.publicReads {
+			c.Next()
+			return
+		}
+		if err != nil {
+			respond(c, http.StatusUnauthorized, types.Response{
+				Code:    types.CodeUnauthorized,
+				Message: types.ErrorMessages[types.CodeUnauthorized],
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set(string(types.ContextKeyUserID), subject)
+		c.Next()
+	}
+}
+
+// authenticate 校验请求凭证并返回用户ID，API密钥优先于 JWT
+func (a *Authenticator) authenticate(r *http.Request) (string, error) {
+	if key := r.Header.Get(types.HeaderAPIKey); key != "" {
+		if !a.validAPIKey(key) {
+			return "", errInvalidAPIKey
+		}
+		return apiKeySubject, nil
+	}
+
+	token, ok := bearerToken(r.Header.Get(types.HeaderAuthorization))
+	if !ok {
+		return "", errNoCredentials
+	}
+	return a.verifyJWT(token, time.Now())
+}
+
+// validAPIKey 以常量时间比较API密钥
+func (a *Authenticator) validAPIKey(key string) bool {
+	valid := false
+	for _, k := range a.apiKeys {
+		if subtle.ConstantTimeCompare(k, []byte(key)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// bearerToken 从 Authorization 头中取出 Bearer 令牌
+func bearerToken(header string) (string, bool) {
+	const prefix = "Bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(header[len(prefix):]), true
+}
+
+// jwtClaims 校验所需的 JWT 声明
+type jwtClaims struct {
+	Subject   string `json:"sub"`
+	Issuer    string `json:"iss"`
+	ExpiresAt *int64 `json:"exp"`
+	NotBefore *int64 `json:"nbf"`
+}
+
+// verifyJWT 校验 HS256 签名的 JWT 并返回其 sub
+func (a *Authenticator) verifyJWT(token string, now time.Time) (string, error) {
+	if a.jwtSecret == nil {
+		return "", errMalformedToken
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errMalformedToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return "", errMalformedToken
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", errMalformedToken
+	}
+	mac := hmac.New(sha256.New, a.jwtSecret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return "", errTokenSignature
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return "", errMalformedToken
+	}
+	if claims.ExpiresAt != nil && now.Unix() >= *claims.ExpiresAt {
+		return "", errTokenExpired
+	}
+	if claims.NotBefore != nil && now.Unix() < *claims.NotBefore {
+		return "", errTokenNotYet
+	}
+	if a.jwtIssuer != "" && claims.Issuer != a.jwtIssuer {
+		return "", errTokenIssuer
+	}
+	return claims.Subject, nil
+}
+
+// decodeSegment 解码 base64url 编码的 JWT 片段
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"rank-system/types"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testJWTSecret = "test-secret"
+
+// signJWT 以 secret 对 header 与 claims 做 HS256 签名，生成 JWT
+func signJWT(t *testing.T, secret string, header, claims map[string]interface{}) string {
+	t.Helper()
+	seg := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signing := seg(header) + "." + seg(claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signing))
+	return signing + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// hs256 是 HS256 的 JWT 头
+var hs256 = map[string]interface{}{"alg": "HS256", "typ": "JWT"}
+
+// JWT 的签名、算法、有效期与签发者校验
+func TestVerifyJWT(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	a := NewAuthenticator(types.AuthConfig{JWTSecret: testJWTSecret, JWTIssuer: "rank"})
+	claims := func(extra map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{"sub": "u1", "iss": "rank", "exp": now.Unix() + 60}
+		for k, v := range extra {
+			c[k] = v
+		}
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantSub string
+		wantErr error
+	}{
+		{"valid", signJWT(t, testJWTSecret, hs256, claims(nil)), "u1", nil},
+		{"bad signature", signJWT(t, "other-secret", hs256, claims(nil)), "", errTokenSignature},
+		{"alg none", signJWT(t, testJWTSecret, map[string]interface{}{"alg": "none"}, claims(nil)), "", errMalformedToken},
+		{"alg HS512", signJWT(t, testJWTSecret, map[string]interface{}{"alg": "HS512"}, claims(nil)), "", errMalformedToken},
+		{"expired", signJWT(t, testJWTSecret, hs256, claims(map[string]interface{}{"exp": now.Unix()})), "", errTokenExpired},
+		{"not yet valid", signJWT(t, testJWTSecret, hs256, claims(map[string]interface{}{"nbf": now.Unix() + 1})), "", errTokenNotYet},
+		{"nbf reached", signJWT(t, testJWTSecret, hs256, claims(map[string]interface{}{"nbf": now.Unix()})), "u1", nil},
+		{"wrong issuer", signJWT(t, testJWTSecret, hs256, claims(map[string]interface{}{"iss": "other"})), "", errTokenIssuer},
+		{"missing issuer", signJWT(t, testJWTSecret, hs256, map[string]interface{}{"sub": "u1"}), "", errTokenIssuer},
+		{"two segments", "a.b", "", errMalformedToken},
+		{"bad signature encoding", signJWT(t, testJWTSecret, hs256, claims(nil)) + "!", "", errMalformedToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, err := a.verifyJWT(tt.token, now)
+			if err != tt.wantErr || sub != tt.wantSub {
+				t.Fatalf("verify: got=%q, %v want=%q, %v", sub, err, tt.wantSub, tt.wantErr)
+			}
+		})
+	}
+
+	// 只配置了API密钥时不接受任何 JWT
+	keyOnly := NewAuthenticator(types.AuthConfig{APIKeys: []string{"k1"}})
+	if _, err := keyOnly.verifyJWT(signJWT(t, testJWTSecret, hs256, claims(nil)), now); err != errMalformedToken {
+		t.Fatalf("jwt without secret: got=%v want=%v", err, errMalformedToken)
+	}
+}
+
+// 未配置任何鉴权方式时不启用鉴权
+func TestNewAuthenticatorDisabled(t *testing.T) {
+	if a := NewAuthenticator(types.AuthConfig{PublicReads: true}); a != nil {
+		t.Fatal("authenticator should be nil without api keys or jwt secret")
+	}
+}
+
+// authRouter 创建只有一个受 policy 保护的路由的测试服务，响应体为上下文中的用户ID
+func authRouter(a *Authenticator, policy AuthPolicy) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", a.Middleware(policy), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(string(types.ContextKeyUserID)))
+	})
+	return r
+}
+
+// 中间件的凭证优先级与 PublicReads 策略
+func TestAuthMiddleware(t *testing.T) {
+	valid := signJWT(t, testJWTSecret, hs256, map[string]interface{}{"sub": "u1", "exp": time.Now().Add(time.Hour).Unix()})
+	forged := signJWT(t, "other-secret", hs256, map[string]interface{}{"sub": "u1"})
+
+	tests := []struct {
+		name        string
+		publicReads bool
+		policy      AuthPolicy
+		apiKey      string
+		bearer      string
+		wantStatus  int
+		wantSubject string
+	}{
+		{"api key", false, AuthRequired, "k1", "", http.StatusOK, apiKeySubject},
+		{"jwt", false, AuthRequired, "", valid, http.StatusOK, "u1"},
+		{"api key wins over bad jwt", false, AuthRequired, "k1", forged, http.StatusOK, apiKeySubject},
+		{"bad api key does not fall back to jwt", false, AuthRequired, "wrong", valid, http.StatusUnauthorized, ""},
+		{"forged jwt", false, AuthRequired, "", forged, http.StatusUnauthorized, ""},
+		{"no credentials", false, AuthRequired, "", "", http.StatusUnauthorized, ""},
+		{"public reads anonymous", true, AuthRead, "", "", http.StatusOK, ""},
+		{"public reads still checks credentials", true, AuthRead, "wrong", "", http.StatusUnauthorized, ""},
+		{"public reads with jwt", true, AuthRead, "", valid, http.StatusOK, "u1"},
+		{"public reads does not open writes", true, AuthRequired, "", "", http.StatusUnauthorized, ""},
+		{"reads need credentials by default", false, AuthRead, "", "", http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAuthenticator(types.AuthConfig{
+				APIKeys:     []string{"", "k1"},
+				JWTSecret:   testJWTSecret,
+				PublicReads: tt.publicReads,
+			})
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.apiKey != "" {
+				req.Header.Set(types.HeaderAPIKey, tt.apiKey)
+			}
+			if tt.bearer != "" {
+				req.Header.Set(types.HeaderAuthorization, "bearer "+tt.bearer)
+			}
+			w := httptest.NewRecorder()
+			authRouter(a, tt.policy).ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status: got=%d want=%d body=%s", w.Code, tt.wantStatus, w.Body)
+			}
+			if w.Code == http.StatusOK && w.Body.String() != tt.wantSubject {
+				t.Fatalf("subject: got=%q want=%q", w.Body, tt.wantSubject)
+			}
+		})
+	}
+}
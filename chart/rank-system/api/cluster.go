@@ -0,0 +1,179 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"rank-system/types"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrInvalidCluster 表示集群配置非法
+var ErrInvalidCluster = errors.New("invalid cluster config")
+
+// LeaderboardIDFunc 从请求中取出排行榜ID
+type LeaderboardIDFunc func(c *gin.Context) (string, error)
+
+// clusterNode 集群中的一个节点
+type clusterNode struct {
+	addr  string
+	proxy *httputil.ReverseProxy
+}
+
+// ClusterRouter 按排行榜ID把请求路由到所属节点，非本节点的排行榜透明代理到所属节点
+// 节点列表为静态配置，所有节点需使用相同的列表；采用最高随机权重（rendezvous）哈希，
+// 增减节点时只有涉及该节点的排行榜会迁移
+type ClusterRouter struct {
+	self  string
+	nodes []*clusterNode
+}
+
+// NewClusterRouter 根据集群配置创建路由器，Self 必须出现在 Nodes 中
+func NewClusterRouter(cfg types.ClusterConfig) (*ClusterRouter, error) {
+	r := &ClusterRouter{self: strings.TrimRight(cfg.Self, "/")}
+	selfFound := false
+	seen := make(map[string]struct{}, len(cfg.Nodes))
+	for _, addr := range cfg.Nodes {
+		addr = strings.TrimRight(addr, "/")
+		if _, dup := seen[addr]; dup {
+			return nil, fmt.Errorf("%w: duplicate node %s", ErrInvalidCluster, addr)
+		}
+		seen[addr] = struct{}{}
+
+		target, err := url.Parse(addr)
+		if err != nil || target.Scheme == "" || target.Host == "" {
+			return nil, fmt.Errorf("%w: node address %q must be like http://host:port", ErrInvalidCluster, addr)
+		}
+		r.nodes = append(r.nodes, &clusterNode{addr: addr, proxy: r.newProxy(target)})
+		if addr == r.self {
+			selfFound = true
+		}
+	}
+	if !selfFound {
+		return nil, fmt.Errorf("%w: self %q is not in nodes", ErrInvalidCluster, cfg.Self)
+	}
+	return r, nil
+}
+
+// newProxy 创建转发到目标节点的反向代理，目标节点不可用时返回 502
+func (r *ClusterRouter) newProxy(target *url.URL) *httputil.ReverseProxy {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+		log.Printf("Cluster proxy to %s failed: %v", target, err)
+		traceID, _ := req.Context().Value(types.ContextKeyTraceID).(string)
+		w.Header().Set(types.HeaderContentType, "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(types.Response{
+			Code:    types.CodeNodeUnavailable,
+			Message: types.ErrorMessages[types.CodeNodeUnavailable],
+			TraceID: traceID,
+		})
+	}
+	return proxy
+}
+
+// Owner 返回负责该排行榜的节点地址
+func (r *ClusterRouter) Owner(leaderboardID string) string {
+	return r.owner(leaderboardID).addr
+}
+
+// Owns 判断排行榜是否由本节点负责
+func (r *ClusterRouter) Owns(leaderboardID string) bool {
+	return r.Owner(leaderboardID) == r.self
+}
+
+// owner 选出对该排行榜权重最高的节点
+func (r *ClusterRouter) owner(leaderboardID string) *clusterNode {
+	var best *clusterNode
+	var bestWeight uint64
+	for _, n := range r.nodes {
+		h := fnv.New64a()
+		h.Write([]byte(n.addr))
+		h.Write([]byte{0})
+		h.Write([]byte(leaderboardID))
+		if w := mix64(h.Sum64()); best == nil || w > bestWeight {
+			best, bestWeight = n, w
+		}
+	}
+	return best
+}
+
+// mix64 打散 FNV 哈希的高位，FNV 对末尾几个字节的差异在高位上区分度不足
+func mix64(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// Middleware 返回路由中间件：排行榜属于本节点时继续处理，否则代理到所属节点
+// 已被其他节点转发过的请求总在本地处理，避免节点列表不一致时循环转发
+func (r *ClusterRouter) Middleware(idOf LeaderboardIDFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader(types.HeaderForwardedBy) != "" {
+			c.Next()
+			return
+		}
+
+		id, err := idOf(c)
+		if err != nil {
+			respondBindError(c, err)
+			c.Abort()
+			return
+		}
+		node := r.owner(id)
+		if id == "" || node.addr == r.self {
+			c.Next()
+			return
+		}
+
+		c.Request.Header.Set(types.HeaderForwardedBy, r.self)
+		c.Request.Header.Set(types.HeaderTraceID, TraceID(c))
+		node.proxy.ServeHTTP(c.Writer, c.Request)
+		c.Abort()
+	}
+}
+
+// pathLeaderboardID 从路径参数 :id 取排行榜ID
+func pathLeaderboardID(c *gin.Context) (string, error) {
+	return c.Param("id"), nil
+}
+
+// queryLeaderboardID 从查询参数 leaderboard_id 取排行榜ID
+func queryLeaderboardID(c *gin.Context) (string, error) {
+	return c.Query("leaderboard_id"), nil
+}
+
+// bodyLeaderboardID 返回从 JSON 请求体的指定字段取排行榜ID的函数，读取后还原请求体
+func bodyLeaderboardID(field string) LeaderboardIDFunc {
+	return func(c *gin.Context) (string, error) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return "", err
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		var req map[string]json.RawMessage
+		if err := json.Unmarshal(body, &req); err != nil {
+			return "", err
+		}
+		var id string
+		if raw, ok := req[field]; ok {
+			if err := json.Unmarshal(raw, &id); err != nil {
+				return "", err
+			}
+		}
+		return id, nil
+	}
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"rank-system/types"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mustClusterRouter 创建路由器，配置非法时测试失败
+func mustClusterRouter(t *testing.T, self string, nodes ...string) *ClusterRouter {
+	t.Helper()
+	r, err := NewClusterRouter(types.ClusterConfig{Self: self, Nodes: nodes})
+	if err != nil {
+		t.Fatalf("new cluster router: %v", err)
+	}
+	return r
+}
+
+// 排行榜均匀分布到各节点，与节点顺序和本节点无关；移除节点时只有该节点的排行榜迁移
+func TestClusterOwnerRendezvous(t *testing.T) {
+	a, b, c := "http://10.0.0.1:8080", "http://10.0.0.2:8080", "http://10.0.0.3:8080"
+	r := mustClusterRouter(t, a, a, b, c)
+	reordered := mustClusterRouter(t, c+"/", c, a, b)
+	shrunk := mustClusterRouter(t, a, a, b)
+
+	const n = 3000
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("lb%d", i)
+		owner := r.Owner(id)
+		counts[owner]++
+		if got := reordered.Owner(id); got != owner {
+			t.Fatalf("owner of %s depends on node order: got=%s want=%s", id, got, owner)
+		}
+		if r.Owns(id) != (owner == a) {
+			t.Fatalf("Owns(%s) disagrees with Owner %s", id, owner)
+		}
+		moved := shrunk.Owner(id)
+		if owner != c && moved != owner {
+			t.Fatalf("%s moved from %s to %s although its owner stayed", id, owner, moved)
+		}
+		if owner == c && moved == c {
+			t.Fatalf("%s still owned by removed node", id)
+		}
+	}
+	for _, node := range []string{a, b, c} {
+		if counts[node] < n/3*8/10 || counts[node] > n/3*12/10 {
+			t.Fatalf("uneven distribution: %v", counts)
+		}
+	}
+}
+
+// 非法的集群配置被拒绝
+func TestNewClusterRouterInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		self  string
+		nodes []string
+	}{
+		{"self missing", "http://10.0.0.9:8080", []string{"http://10.0.0.1:8080"}},
+		{"duplicate node", "http://10.0.0.1:8080", []string{"http://10.0.0.1:8080", "http://10.0.0.1:8080/"}},
+		{"no scheme", "10.0.0.1:8080", []string{"10.0.0.1:8080"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewClusterRouter(types.ClusterConfig{Self: tt.self, Nodes: tt.nodes})
+			if !errors.Is(err, ErrInvalidCluster) {
+				t.Fatalf("got=%v want=%v", err, ErrInvalidCluster)
+			}
+		})
+	}
+}
+
+// clusterNodeServer 集群中的一个测试节点，处理器返回节点名称与转发来源
+type clusterNodeServer struct {
+	addr   string
+	server *httptest.Server
+}
+
+// newClusterNodes 创建 len(names) 个尚未启动的测试节点，地址在创建时确定
+func newClusterNodes(names ...string) []*clusterNodeServer {
+	nodes := make([]*clusterNodeServer, len(names))
+	for i := range names {
+		s := httptest.NewUnstartedServer(nil)
+		nodes[i] = &clusterNodeServer{addr: "http://" + s.Listener.Addr().String(), server: s}
+	}
+	return nodes
+}
+
+// start 以 router 启动节点，路由 /lb/:id 按路径、/update 按请求体中的 leaderboard_id 分配
+func (n *clusterNodeServer) start(name string, router *ClusterRouter) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(TraceMiddleware())
+	reply := func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.JSON(http.StatusOK, gin.H{"node": name, "forwarded_by": c.GetHeader(types.HeaderForwardedBy), "body": string(body)})
+	}
+	engine.GET("/lb/:id", router.Middleware(pathLeaderboardID), reply)
+	engine.POST("/update", router.Middleware(bodyLeaderboardID("leaderboard_id")), reply)
+	n.server.Config.Handler = engine
+	n.server.Start()
+}
+
+// ownedBy 返回一个由 addr 负责的排行榜ID
+func ownedBy(t *testing.T, r *ClusterRouter, addr string) string {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		if id := fmt.Sprintf("lb%d", i); r.Owner(id) == addr {
+			return id
+		}
+	}
+	t.Fatalf("no leaderboard owned by %s", addr)
+	return ""
+}
+
+// clusterReply 测试节点的响应
+type clusterReply struct {
+	Node        string `json:"node"`
+	ForwardedBy string `json:"forwarded_by"`
+	Body        string `json:"body"`
+}
+
+// doCluster 发送请求并解析测试节点的响应
+func doCluster(t *testing.T, req *http.Request) (int, clusterReply) {
+	t.Helper()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer resp.Body.Close()
+	var reply clusterReply
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+	return resp.StatusCode, reply
+}
+
+// 请求转发到所属节点并带上 X-Forwarded-By；已被转发过的请求总在本地处理
+func TestClusterMiddlewareProxy(t *testing.T) {
+	nodes := newClusterNodes("a", "b")
+	a, b := nodes[0], nodes[1]
+	routerA := mustClusterRouter(t, a.addr, a.addr, b.addr)
+	a.start("a", routerA)
+	b.start("b", mustClusterRouter(t, b.addr, a.addr, b.addr))
+	defer a.server.Close()
+	defer b.server.Close()
+
+	local, remote := ownedBy(t, routerA, a.addr), ownedBy(t, routerA, b.addr)
+
+	req, _ := http.NewRequest(http.MethodGet, a.addr+"/lb/"+local, nil)
+	if status, reply := doCluster(t, req); status != http.StatusOK || reply.Node != "a" || reply.ForwardedBy != "" {
+		t.Fatalf("local: status=%d reply=%+v want handled by a", status, reply)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, a.addr+"/lb/"+remote, nil)
+	if status, reply := doCluster(t, req); status != http.StatusOK || reply.Node != "b" || reply.ForwardedBy != a.addr {
+		t.Fatalf("remote: status=%d reply=%+v want handled by b forwarded by a", status, reply)
+	}
+
+	body := `{"leaderboard_id":"` + remote + `","player_id":1,"score":10}`
+	req, _ = http.NewRequest(http.MethodPost, a.addr+"/update", strings.NewReader(body))
+	if status, reply := doCluster(t, req); status != http.StatusOK || reply.Node != "b" || reply.Body != body {
+		t.Fatalf("remote body: status=%d reply=%+v want handled by b with the original body", status, reply)
+	}
+
+	// 节点列表不一致时，已转发的请求不会再次转发
+	req, _ = http.NewRequest(http.MethodGet, a.addr+"/lb/"+remote, nil)
+	req.Header.Set(types.HeaderForwardedBy, "http://10.0.0.9:8080")
+	if status, reply := doCluster(t, req); status != http.StatusOK || reply.Node != "a" {
+		t.Fatalf("loop guard: status=%d reply=%+v want handled by a", status, reply)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, a.addr+"/update", strings.NewReader(`{"leaderboard_id":`))
+	if status, _ := doCluster(t, req); status != http.StatusBadRequest {
+		t.Fatalf("malformed body: status=%d want=%d", status, http.StatusBadRequest)
+	}
+}
+
+// 所属节点不可用时返回 502 与统一格式的错误，带上请求的追踪ID
+func TestClusterMiddlewareNodeUnavailable(t *testing.T) {
+	nodes := newClusterNodes("a", "b")
+	a, b := nodes[0], nodes[1]
+	routerA := mustClusterRouter(t, a.addr, a.addr, b.addr)
+	a.start("a", routerA)
+	defer a.server.Close()
+	b.server.Close() // b 从未启动，关闭其监听
+
+	req, _ := http.NewRequest(http.MethodGet, a.addr+"/lb/"+ownedBy(t, routerA, b.addr), nil)
+	req.Header.Set(types.HeaderTraceID, "trace-502")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Fatalf("status: got=%d want=%d", resp.StatusCode, http.StatusBadGateway)
+	}
+	var out types.Response
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if out.Code != types.CodeNodeUnavailable || out.TraceID != "trace-502" {
+		t.Fatalf("response: code=%d trace=%q want code=%d trace=trace-502", out.Code, out.TraceID, types.CodeNodeUnavailable)
+	}
+}
@@ -1,48 +1,133 @@
 package api
 
 import (
+	"errors"
 	"net/http"
+	"rank-system/domain"
 	"rank-system/service"
 	"rank-system/types"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // Handler HTTP请求处理器
 type Handler struct {
-	rankService *service.RankService
+	rankService   *service.RankService
+	rewardService *service.RewardService
+	limiter       *RateLimiter
+	auth          *Authenticator
+	cluster       *ClusterRouter
+	maxBatchSize  int           // 单次分数更新请求允许的最大条数
+	readOnly      bool          // 只读副本，拒绝写接口
+	maintenance   maintenance   // 维护模式，运行期可切换，开启时拒绝写接口
+	pollTimeout   time.Duration // 复制流长轮询的最长等待时间
 }
 
 // NewHandler 创建处理器
-func NewHandler(rankService *service.RankService) *Handler {
+func NewHandler(rankService *service.RankService, rewardService *service.RewardService) *Handler {
 	return &Handler{
-		rankService: rankService,
+		rankService:   rankService,
+		rewardService: rewardService,
+		maxBatchSize:  types.MaxBatchUpdateSize,
+		pollTimeout:   types.DefaultReplicationPollTimeout,
 	}
 }
 
+// SetMaxBatchSize 设置单次分数更新请求允许的最大条数，n 不大于 0 时忽略
+func (h *Handler) SetMaxBatchSize(n int) {
+	if n > 0 {
+		h.maxBatchSize = n
+	}
+}
+
+// SetRateLimiter 设置分数更新接口的限流器，需在 RegisterRoutes 之前调用；为 nil 时不限流
+func (h *Handler) SetRateLimiter(limiter *RateLimiter) {
+	h.limiter = limiter
+}
+
+// SetAuthenticator 设置接口鉴权器，需在 RegisterRoutes 之前调用；为 nil 时不鉴权
+func (h *Handler) SetAuthenticator(auth *Authenticator) {
+	h.auth = auth
+}
+
+// SetClusterRouter 设置集群路由器，需在 RegisterRoutes 之前调用；为 nil 时所有排行榜都在本地处理
+func (h *Handler) SetClusterRouter(router *ClusterRouter) {
+	h.cluster = router
+}
+
 // CreateLeaderboard 创建排行榜
 func (h *Handler) CreateLeaderboard(c *gin.Context) {
 	var req types.CreateLeaderboardRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, types.Response{
-			Code:    types.CodeInvalidParams,
-			Message: types.ErrorMessages[types.CodeInvalidParams],
-		})
+		respondBindError(c, err)
 		return
 	}
 
 	if err := h.rankService.CreateLeaderboard(&req); err != nil {
-		c.JSON(http.StatusInternalServerError, types.Response{
+		if errors.Is(err, types.ErrUnknownLeaderboardType) || errors.Is(err, domain.ErrInvalidRewardTiers) {
+			respond(c, http.StatusBadRequest, types.Response{
+				Code:    types.CodeInvalidParams,
+				Message: err.Error(),
+			})
+			return
+		}
+		respond(c, http.StatusInternalServerError, types.Response{
 			Code:    types.CodeInternalError,
 			Message: types.ErrorMessages[types.CodeInternalError],
 		})
 		return
 	}
 
-	c.JSON(http.StatusCreated, types.Response{
+	respond(c, http.StatusCreated, types.Response{
+		Code:    types.CodeSuccess,
+		Message: types.ErrorMessages[types.CodeSuccess],
+	})
+}
+
+// CloneLeaderboard 复制排行榜的配置（可选连同玩家数据）创建新排行榜
+// 集群模式下请求按源排行榜路由，新排行榜必须同样归属本节点
+func (h *Handler) CloneLeaderboard(c *gin.Context) {
+	var req types.CloneLeaderboardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if h.cluster != nil && !h.cluster.Owns(req.ID) {
+		respond(c, http.StatusBadRequest, types.Response{
+			Code:    types.CodeInvalidParams,
+			Message: "target leaderboard belongs to node " + h.cluster.Owner(req.ID),
+		})
+		return
+	}
+
+	stats, err := h.rankService.CloneLeaderboard(c.Param("id"), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrLeaderboardNotFound):
+			respond(c, http.StatusNotFound, types.Response{
+				Code:    types.CodeNotFound,
+				Message: types.ErrorMessages[types.CodeNotFound],
+			})
+		case errors.Is(err, domain.ErrLeaderboardExists):
+			respond(c, http.StatusConflict, types.Response{
+				Code:    types.CodeDuplicate,
+				Message: err.Error(),
+			})
+		default:
+			respond(c, http.StatusInternalServerError, types.Response{
+				Code:    types.CodeInternalError,
+				Message: types.ErrorMessages[types.CodeInternalError],
+			})
+		}
+		return
+	}
+
+	respond(c, http.StatusCreated, types.Response{
 		Code:    types.CodeSuccess,
 		Message: types.ErrorMessages[types.CodeSuccess],
+		Data:    stats,
 	})
 }
 
@@ -50,23 +135,29 @@ func (h *Handler) CreateLeaderboard(c *gin.Context) {
 func (h *Handler) UpdateScore(c *gin.Context) {
 	var req types.BatchUpdateScoreRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, types.Response{
+		respondBindError(c, err)
+		return
+	}
+	if len(req.Updates) > h.maxBatchSize {
+		param := strconv.Itoa(h.maxBatchSize)
+		respond(c, http.StatusBadRequest, types.Response{
 			Code:    types.CodeInvalidParams,
 			Message: types.ErrorMessages[types.CodeInvalidParams],
+			Errors:  []*types.FieldError{{Field: "updates", Rule: "max", Param: param, Message: "长度不能大于 " + param}},
 		})
 		return
 	}
 
 	results, err := h.rankService.BatchUpdateScore(&req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.Response{
+		respond(c, http.StatusInternalServerError, types.Response{
 			Code:    types.CodeInternalError,
 			Message: types.ErrorMessages[types.CodeInternalError],
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, types.Response{
+	respond(c, http.StatusOK, types.Response{
 		Code:    types.CodeSuccess,
 		Message: types.ErrorMessages[types.CodeSuccess],
 		Data:    results,
@@ -79,7 +170,7 @@ func (h *Handler) GetPlayerRank(c *gin.Context) {
 	playerIDStr := c.Query("player_id")
 
 	if leaderboardID == "" || playerIDStr == "" {
-		c.JSON(http.StatusBadRequest, types.Response{
+		respond(c, http.StatusBadRequest, types.Response{
 			Code:    types.CodeInvalidParams,
 			Message: types.ErrorMessages[types.CodeInvalidParams],
 		})
@@ -88,7 +179,7 @@ func (h *Handler) GetPlayerRank(c *gin.Context) {
 
 	playerID, err := strconv.ParseInt(playerIDStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, types.Response{
+		respond(c, http.StatusBadRequest, types.Response{
 			Code:    types.CodeInvalidParams,
 			Message: types.ErrorMessages[types.CodeInvalidParams],
 		})
@@ -102,14 +193,38 @@ func (h *Handler) GetPlayerRank(c *gin.Context) {
 
 	response, err := h.rankService.GetPlayerRank(req)
 	if err != nil {
-		c.JSON(http.StatusNotFound, types.Response{
+		respond(c, http.StatusNotFound, types.Response{
 			Code:    types.CodeNotFound,
 			Message: types.ErrorMessages[types.CodeNotFound],
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, types.Response{
+	respond(c, http.StatusOK, types.Response{
+		Code:    types.CodeSuccess,
+		Message: types.ErrorMessages[types.CodeSuccess],
+		Data:    response,
+	})
+}
+
+// GetFriendsRank 获取一组玩家（如好友）之间的相对排名
+func (h *Handler) GetFriendsRank(c *gin.Context) {
+	var req types.FriendsRankRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	response, err := h.rankService.GetFriendsRank(&req)
+	if err != nil {
+		respond(c, http.StatusNotFound, types.Response{
+			Code:    types.CodeNotFound,
+			Message: types.ErrorMessages[types.CodeNotFound],
+		})
+		return
+	}
+
+	respond(c, http.StatusOK, types.Response{
 		Code:    types.CodeSuccess,
 		Message: types.ErrorMessages[types.CodeSuccess],
 		Data:    response,
@@ -120,10 +235,9 @@ func (h *Handler) GetPlayerRank(c *gin.Context) {
 func (h *Handler) GetNearbyRanks(c *gin.Context) {
 	leaderboardID := c.Query("leaderboard_id")
 	playerIDStr := c.Query("player_id")
-	pageSizeStr := c.Query("page_size")
 
 	if leaderboardID == "" || playerIDStr == "" {
-		c.JSON(http.StatusBadRequest, types.Response{
+		respond(c, http.StatusBadRequest, types.Response{
 			Code:    types.CodeInvalidParams,
 			Message: types.ErrorMessages[types.CodeInvalidParams],
 		})
@@ -132,18 +246,16 @@ func (h *Handler) GetNearbyRanks(c *gin.Context) {
 
 	playerID, err := strconv.ParseInt(playerIDStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, types.Response{
+		respond(c, http.StatusBadRequest, types.Response{
 			Code:    types.CodeInvalidParams,
 			Message: types.ErrorMessages[types.CodeInvalidParams],
 		})
 		return
 	}
 
-	pageSize := types.DefaultPageSize
-	if pageSizeStr != "" {
-		if ps, err := strconv.Atoi(pageSizeStr); err == nil {
-			pageSize = ps
-		}
+	pageSize, ok := pageSizeParam(c)
+	if !ok {
+		return
 	}
 
 	req := &types.QueryLeaderboardRequest{
@@ -154,14 +266,14 @@ func (h *Handler) GetNearbyRanks(c *gin.Context) {
 
 	response, err := h.rankService.GetNearbyRanks(req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.Response{
+		respond(c, http.StatusInternalServerError, types.Response{
 			Code:    types.CodeInternalError,
 			Message: types.ErrorMessages[types.CodeInternalError],
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, types.Response{
+	respond(c, http.StatusOK, types.Response{
 		Code:    types.CodeSuccess,
 		Message: types.ErrorMessages[types.CodeSuccess],
 		Data:    response,
@@ -171,21 +283,18 @@ func (h *Handler) GetNearbyRanks(c *gin.Context) {
 // GetTopRanks 获取前N名
 func (h *Handler) GetTopRanks(c *gin.Context) {
 	leaderboardID := c.Query("leaderboard_id")
-	pageSizeStr := c.Query("page_size")
 
 	if leaderboardID == "" {
-		c.JSON(http.StatusBadRequest, types.Response{
+		respond(c, http.StatusBadRequest, types.Response{
 			Code:    types.CodeInvalidParams,
 			Message: types.ErrorMessages[types.CodeInvalidParams],
 		})
 		return
 	}
 
-	pageSize := types.DefaultPageSize
-	if pageSizeStr != "" {
-		if ps, err := strconv.Atoi(pageSizeStr); err == nil {
-			pageSize = ps
-		}
+	pageSize, ok := pageSizeParam(c)
+	if !ok {
+		return
 	}
 
 	req := &types.QueryLeaderboardRequest{
@@ -195,28 +304,236 @@ func (h *Handler) GetTopRanks(c *gin.Context) {
 
 	response, err := h.rankService.GetTopRanks(req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.Response{
+		respond(c, http.StatusInternalServerError, types.Response{
+			Code:    types.CodeInternalError,
+			Message: types.ErrorMessages[types.CodeInternalError],
+		})
+		return
+	}
+
+	respond(c, http.StatusOK, types.Response{
+		Code:    types.CodeSuccess,
+		Message: types.ErrorMessages[types.CodeSuccess],
+		Data:    response,
+	})
+}
+
+// DeleteLeaderboard 删除排行榜
+func (h *Handler) DeleteLeaderboard(c *gin.Context) {
+	if err := h.rankService.DeleteLeaderboard(c.Param("id")); err != nil {
+		respond(c, http.StatusNotFound, types.Response{
+			Code:    types.CodeNotFound,
+			Message: types.ErrorMessages[types.CodeNotFound],
+		})
+		return
+	}
+
+	respond(c, http.StatusOK, types.Response{
+		Code:    types.CodeSuccess,
+		Message: types.ErrorMessages[types.CodeSuccess],
+	})
+}
+
+// ResetLeaderboard 重置排行榜
+func (h *Handler) ResetLeaderboard(c *gin.Context) {
+	if err := h.rankService.ResetLeaderboard(c.Param("id")); err != nil {
+		respond(c, http.StatusNotFound, types.Response{
+			Code:    types.CodeNotFound,
+			Message: types.ErrorMessages[types.CodeNotFound],
+		})
+		return
+	}
+
+	respond(c, http.StatusOK, types.Response{
+		Code:    types.CodeSuccess,
+		Message: types.ErrorMessages[types.CodeSuccess],
+	})
+}
+
+// RolloverLeaderboard 手动结束排行榜当前周期并归档，用于赛季结算
+func (h *Handler) RolloverLeaderboard(c *gin.Context) {
+	archive, err := h.rankService.RolloverLeaderboard(c.Param("id"))
+	if err != nil {
+		respond(c, http.StatusNotFound, types.Response{
+			Code:    types.CodeNotFound,
+			Message: types.ErrorMessages[types.CodeNotFound],
+		})
+		return
+	}
+
+	respond(c, http.StatusOK, types.Response{
+		Code:    types.CodeSuccess,
+		Message: types.ErrorMessages[types.CodeSuccess],
+		Data:    archive,
+	})
+}
+
+// GetHistory 查询排行榜的历史排名
+func (h *Handler) GetHistory(c *gin.Context) {
+	req := &types.HistoryQueryRequest{
+		LeaderboardID: c.Param("id"),
+		Period:        c.Query("period"),
+	}
+
+	if playerIDStr := c.Query("player_id"); playerIDStr != "" {
+		playerID, err := strconv.ParseInt(playerIDStr, 10, 64)
+		if err != nil {
+			respond(c, http.StatusBadRequest, types.Response{
+				Code:    types.CodeInvalidParams,
+				Message: types.ErrorMessages[types.CodeInvalidParams],
+			})
+			return
+		}
+		req.PlayerID = playerID
+	}
+	pageSize, ok := pageSizeParam(c)
+	if !ok {
+		return
+	}
+	req.PageSize = pageSize
+
+	response, err := h.rankService.GetHistory(req)
+	if err != nil {
+		respond(c, http.StatusNotFound, types.Response{
+			Code:    types.CodeNotFound,
+			Message: types.ErrorMessages[types.CodeNotFound],
+		})
+		return
+	}
+
+	respond(c, http.StatusOK, types.Response{
+		Code:    types.CodeSuccess,
+		Message: types.ErrorMessages[types.CodeSuccess],
+		Data:    response,
+	})
+}
+
+// GetPlayerRewards 查询玩家在排行榜上获得的奖励
+func (h *Handler) GetPlayerRewards(c *gin.Context) {
+	leaderboardID := c.Query("leaderboard_id")
+	playerID, err := strconv.ParseInt(c.Query("player_id"), 10, 64)
+	if leaderboardID == "" || err != nil {
+		respond(c, http.StatusBadRequest, types.Response{
+			Code:    types.CodeInvalidParams,
+			Message: types.ErrorMessages[types.CodeInvalidParams],
+		})
+		return
+	}
+
+	rewards, err := h.rewardService.GetPlayerRewards(leaderboardID, playerID)
+	if err != nil {
+		respond(c, http.StatusInternalServerError, types.Response{
 			Code:    types.CodeInternalError,
 			Message: types.ErrorMessages[types.CodeInternalError],
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, types.Response{
+	respond(c, http.StatusOK, types.Response{
+		Code:    types.CodeSuccess,
+		Message: types.ErrorMessages[types.CodeSuccess],
+		Data:    rewards,
+	})
+}
+
+// PreviewReward 查询玩家按当前排名可获得的奖励
+func (h *Handler) PreviewReward(c *gin.Context) {
+	leaderboardID := c.Query("leaderboard_id")
+	playerID, err := strconv.ParseInt(c.Query("player_id"), 10, 64)
+	if leaderboardID == "" || err != nil {
+		respond(c, http.StatusBadRequest, types.Response{
+			Code:    types.CodeInvalidParams,
+			Message: types.ErrorMessages[types.CodeInvalidParams],
+		})
+		return
+	}
+
+	req := &types.QueryLeaderboardRequest{
+		LeaderboardID: leaderboardID,
+		PlayerID:      playerID,
+	}
+
+	response, err := h.rankService.PreviewReward(req)
+	if err != nil {
+		respond(c, http.StatusNotFound, types.Response{
+			Code:    types.CodeNotFound,
+			Message: types.ErrorMessages[types.CodeNotFound],
+		})
+		return
+	}
+
+	respond(c, http.StatusOK, types.Response{
 		Code:    types.CodeSuccess,
 		Message: types.ErrorMessages[types.CodeSuccess],
 		Data:    response,
 	})
 }
 
+// GetStats 获取排行榜统计信息
+func (h *Handler) GetStats(c *gin.Context) {
+	response, err := h.rankService.GetStats(c.Param("id"))
+	if err != nil {
+		respond(c, http.StatusNotFound, types.Response{
+			Code:    types.CodeNotFound,
+			Message: types.ErrorMessages[types.CodeNotFound],
+		})
+		return
+	}
+
+	respond(c, http.StatusOK, types.Response{
+		Code:    types.CodeSuccess,
+		Message: types.ErrorMessages[types.CodeSuccess],
+		Data:    response,
+	})
+}
+
+// updateHandlers 为分数更新接口加上限流中间件
+func (h *Handler) updateHandlers(handler gin.HandlerFunc) []gin.HandlerFunc {
+	if h.limiter == nil {
+		return []gin.HandlerFunc{handler}
+	}
+	return []gin.HandlerFunc{h.limiter.Middleware(), handler}
+}
+
+// withAuth 在处理器前加上指定策略的鉴权中间件，未启用鉴权时原样返回
+func (h *Handler) withAuth(policy AuthPolicy, handlers ...gin.HandlerFunc) []gin.HandlerFunc {
+	if h.auth == nil {
+		return handlers
+	}
+	return append([]gin.HandlerFunc{h.auth.Middleware(policy)}, handlers...)
+}
+
+// routed 集群模式下在处理器前加上按排行榜ID路由的中间件，未启用集群时原样返回
+func (h *Handler) routed(idOf LeaderboardIDFunc, handlers ...gin.HandlerFunc) []gin.HandlerFunc {
+	if h.cluster == nil {
+		return handlers
+	}
+	return append([]gin.HandlerFunc{h.cluster.Middleware(idOf)}, handlers...)
+}
+
 // RegisterRoutes 注册路由
+// 写接口必须鉴权，查询接口在配置 PublicReads 时可匿名访问；只读副本与维护模式下拒绝写接口；
+// 集群模式下先按排行榜ID路由，鉴权与限流由所属节点执行；维护模式按节点切换，不经集群路由
 func (h *Handler) RegisterRoutes(router *gin.Engine) {
-	api := router.Group(types.APIPrefix)
+	byPath, byQuery := pathLeaderboardID, queryLeaderboardID
+	api := router.Group(types.APIPrefix, TraceMiddleware())
 	{
-		api.POST("/leaderboards", h.CreateLeaderboard)
-		api.PUT("/scores", h.UpdateScore)
-		api.GET("/player-rank", h.GetPlayerRank)
-		api.GET("/nearby-ranks", h.GetNearbyRanks)
-		api.GET("/top-ranks", h.GetTopRanks)
+		api.POST("/leaderboards", h.routed(bodyLeaderboardID("id"), h.withAuth(AuthRequired, h.writable(h.CreateLeaderboard)...)...)...)
+		api.DELETE("/leaderboards/:id", h.routed(byPath, h.withAuth(AuthRequired, h.writable(h.DeleteLeaderboard)...)...)...)
+		api.POST("/leaderboards/:id/reset", h.routed(byPath, h.withAuth(AuthRequired, h.writable(h.ResetLeaderboard)...)...)...)
+		api.POST("/leaderboards/:id/clone", h.routed(byPath, h.withAuth(AuthRequired, h.writable(h.CloneLeaderboard)...)...)...)
+		api.POST("/leaderboards/:id/rollover", h.routed(byPath, h.withAuth(AuthRequired, h.writable(h.RolloverLeaderboard)...)...)...)
+		api.GET("/leaderboards/:id/history", h.routed(byPath, h.withAuth(AuthRead, h.GetHistory)...)...)
+		api.GET("/leaderboards/:id/stats", h.routed(byPath, h.withAuth(AuthRead, h.GetStats)...)...)
+		api.PUT("/scores", h.routed(bodyLeaderboardID("leaderboard_id"), h.withAuth(AuthRequired, h.writable(h.updateHandlers(h.UpdateScore)...)...)...)...)
+		api.GET("/player-rank", h.routed(byQuery, h.withAuth(AuthRead, h.GetPlayerRank)...)...)
+		api.GET("/nearby-ranks", h.routed(byQuery, h.withAuth(AuthRead, h.GetNearbyRanks)...)...)
+		api.POST("/friends-rank", h.routed(bodyLeaderboardID("leaderboard_id"), h.withAuth(AuthRead, h.GetFriendsRank)...)...)
+		api.GET("/top-ranks", h.routed(byQuery, h.withAuth(AuthRead, h.GetTopRanks)...)...)
+		api.GET("/rewards", h.routed(byQuery, h.withAuth(AuthRead, h.GetPlayerRewards)...)...)
+		api.GET("/rewards/preview", h.routed(byQuery, h.withAuth(AuthRead, h.PreviewReward)...)...)
+		api.GET("/replication", h.withAuth(AuthRequired, h.GetReplication)...)
+		api.GET("/admin/maintenance", h.withAuth(AuthRequired, h.GetMaintenance)...)
+		api.PUT("/admin/maintenance", h.withAuth(AuthRequired, h.SetMaintenance)...)
 	}
 }
\ No newline at end of file
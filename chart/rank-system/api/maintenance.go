@@ -0,0 +1,90 @@
+package api
+
+import (
+	"net/http"
+	"rank-system/types"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maintenance 维护模式状态：开启期间写接口返回 503，查询接口不受影响
+// 用于快照恢复、数据迁移或赛季结算期间冻结写入
+type maintenance struct {
+	mu      sync.RWMutex
+	enabled bool
+	reason  string
+	since   time.Time
+}
+
+// set 切换维护模式，关闭时清空原因
+func (m *maintenance) set(enabled bool, reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !enabled {
+		reason, m.since = "", time.Time{}
+	} else if !m.enabled {
+		m.since = time.Now()
+	}
+	m.enabled, m.reason = enabled, reason
+}
+
+// status 返回当前的维护模式状态
+func (m *maintenance) status() *types.MaintenanceResponse {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	resp := &types.MaintenanceResponse{Enabled: m.enabled, Reason: m.reason}
+	if m.enabled {
+		since := m.since
+		resp.Since = &since
+	}
+	return resp
+}
+
+// SetMaintenanceMode 开启或关闭维护模式，可在运行期调用
+func (h *Handler) SetMaintenanceMode(enabled bool, reason string) {
+	h.maintenance.set(enabled, reason)
+}
+
+// rejectInMaintenance 维护模式下拒绝写请求，响应中带上维护状态
+func (h *Handler) rejectInMaintenance(c *gin.Context) {
+	status := h.maintenance.status()
+	if !status.Enabled {
+		c.Next()
+		return
+	}
+	respond(c, http.StatusServiceUnavailable, types.Response{
+		Code:    types.CodeMaintenance,
+		Message: types.ErrorMessages[types.CodeMaintenance],
+		Data:    status,
+	})
+	c.Abort()
+}
+
+// GetMaintenance 查询本节点的维护模式状态
+func (h *Handler) GetMaintenance(c *gin.Context) {
+	respond(c, http.StatusOK, types.Response{
+		Code:    types.CodeSuccess,
+		Message: types.ErrorMessages[types.CodeSuccess],
+		Data:    h.maintenance.status(),
+	})
+}
+
+// SetMaintenance 切换本节点的维护模式，集群中需对每个节点分别调用
+func (h *Handler) SetMaintenance(c *gin.Context) {
+	var req types.MaintenanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	h.SetMaintenanceMode(*req.Enabled, req.Reason)
+	respond(c, http.StatusOK, types.Response{
+		Code:    types.CodeSuccess,
+		Message: types.ErrorMessages[types.CodeSuccess],
+		Data:    h.maintenance.status(),
+	})
+}
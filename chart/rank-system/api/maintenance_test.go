@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"rank-system/service"
+	"rank-system/storage"
+	"rank-system/types"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newMaintenanceRouter 创建注册了全部路由的处理器，并预先创建排行榜 lb
+func newMaintenanceRouter(t *testing.T) (*gin.Engine, *service.RankService) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	rankService := service.NewRankService(storage.NewMemoryRepository(), storage.NewMemoryArchiveRepository())
+	t.Cleanup(rankService.Close)
+	err := rankService.CreateLeaderboard(&types.CreateLeaderboardRequest{ID: "lb", Name: "lb", Type: "daily", TotalPlayers: 100, RewardRatio: 0.1, MinReward: 1, MaxReward: 10})
+	if err != nil {
+		t.Fatalf("create leaderboard: %v", err)
+	}
+	router := gin.New()
+	NewHandler(rankService, service.NewRewardService(storage.NewMemoryRewardRepository())).RegisterRoutes(router)
+	return router, rankService
+}
+
+// serveJSON 发送请求并解析统一响应，body 为空时不带请求体
+func serveJSON(t *testing.T, router *gin.Engine, method, path, body string) (int, types.Response) {
+	t.Helper()
+	var req *http.Request
+	if body == "" {
+		req = httptest.NewRequest(method, types.APIPrefix+path, nil)
+	} else {
+		req = httptest.NewRequest(method, types.APIPrefix+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp types.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%s %s: decode %q: %v", method, path, w.Body.String(), err)
+	}
+	return w.Code, resp
+}
+
+// 开启维护模式后写接口返回 503 与 CodeMaintenance，查询接口不受影响；关闭后写入恢复
+func TestMaintenanceMode(t *testing.T) {
+	router, rankService := newMaintenanceRouter(t)
+	update := `{"leaderboard_id":"lb","updates":[{"player_id":1,"score":10}]}`
+
+	if status, resp := serveJSON(t, router, http.MethodPut, "/admin/maintenance", `{"reason":"migration"}`); status != http.StatusBadRequest {
+		t.Fatalf("toggle without enabled: status=%d code=%d want 400", status, resp.Code)
+	}
+	if status, resp := serveJSON(t, router, http.MethodPut, "/admin/maintenance", `{"enabled":true,"reason":"migration"}`); status != http.StatusOK || resp.Code != types.CodeSuccess {
+		t.Fatalf("enable: status=%d code=%d want 200", status, resp.Code)
+	}
+
+	writes := []struct{ method, path, body string }{
+		{http.MethodPut, "/scores", update},
+		{http.MethodPost, "/leaderboards", `{"id":"other","name":"other","type":"daily","total_players":10,"reward_ratio":0.1,"min_reward":1,"max_reward":10}`},
+		{http.MethodPost, "/leaderboards/lb/reset", ""},
+		{http.MethodDelete, "/leaderboards/lb", ""},
+	}
+	for _, w := range writes {
+		status, resp := serveJSON(t, router, w.method, w.path, w.body)
+		if status != http.StatusServiceUnavailable || resp.Code != types.CodeMaintenance {
+			t.Fatalf("%s %s in maintenance: status=%d code=%d want 503/%d", w.method, w.path, status, resp.Code, types.CodeMaintenance)
+		}
+		if data, _ := resp.Data.(map[string]interface{}); data["enabled"] != true || data["reason"] != "migration" {
+			t.Fatalf("%s %s in maintenance: data=%v want the maintenance status", w.method, w.path, resp.Data)
+		}
+	}
+	if status, resp := serveJSON(t, router, http.MethodGet, "/top-ranks?leaderboard_id=lb", ""); status != http.StatusOK || resp.Code != types.CodeSuccess {
+		t.Fatalf("read in maintenance: status=%d code=%d want 200", status, resp.Code)
+	}
+	if status, resp := serveJSON(t, router, http.MethodGet, "/admin/maintenance", ""); status != http.StatusOK || resp.Data.(map[string]interface{})["enabled"] != true {
+		t.Fatalf("maintenance status: status=%d data=%v want enabled", status, resp.Data)
+	}
+	if _, err := rankService.GetPlayerRank(&types.QueryLeaderboardRequest{LeaderboardID: "lb", PlayerID: 1}); err == nil {
+		t.Fatal("rejected update should not be applied")
+	}
+
+	if status, resp := serveJSON(t, router, http.MethodPut, "/admin/maintenance", `{"enabled":false}`); status != http.StatusOK || resp.Data.(map[string]interface{})["enabled"] != false {
+		t.Fatalf("disable: status=%d data=%v want disabled", status, resp.Data)
+	}
+	if status, resp := serveJSON(t, router, http.MethodPut, "/scores", update); status != http.StatusOK || resp.Code != types.CodeSuccess {
+		t.Fatalf("write after maintenance: status=%d code=%d want 200", status, resp.Code)
+	}
+	if _, err := rankService.GetPlayerRank(&types.QueryLeaderboardRequest{LeaderboardID: "lb", PlayerID: 1}); err != nil {
+		t.Fatalf("update after maintenance not applied: %v", err)
+	}
+}
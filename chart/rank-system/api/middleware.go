@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"rank-system/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxTraceIDLength 客户端传入追踪ID的最大长度，超出时重新生成
+const maxTraceIDLength = 128
+
+// TraceMiddleware 追踪ID中间件
+// 优先沿用请求头 X-Trace-ID，缺失时生成新的ID；ID 写入 gin 上下文与请求的 context.Context，
+// 并通过响应头回传，respond 输出的 types.Response 也会带上该ID。
+func TraceMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		traceID := c.GetHeader(types.HeaderTraceID)
+		if !validTraceID(traceID) {
+			traceID = newTraceID()
+		}
+
+		c.Set(string(types.ContextKeyTraceID), traceID)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), types.ContextKeyTraceID, traceID))
+		c.Header(types.HeaderTraceID, traceID)
+		c.Next()
+	}
+}
+
+// TraceID 返回当前请求的追踪ID，未经过 TraceMiddleware 时为空
+func TraceID(c *gin.Context) string {
+	return c.GetString(string(types.ContextKeyTraceID))
+}
+
+// validTraceID 判断客户端传入的追踪ID是否可直接沿用：非空、不超长且只含字母数字与 -_.
+func validTraceID(id string) bool {
+	if id == "" || len(id) > maxTraceIDLength {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// newTraceID 生成 16 字节随机数的十六进制追踪ID
+func newTraceID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// respond 输出统一格式的响应并填充追踪ID
+func respond(c *gin.Context, status int, resp types.Response) {
+	resp.TraceID = TraceID(c)
+	c.JSON(status, resp)
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"rank-system/types"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitSweepInterval 清理空闲令牌桶的间隔
+const rateLimitSweepInterval = time.Minute
+
+// tokenBucket 令牌桶，按 rate 每秒补充令牌，最多累积 burst 个
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// bucketLimiter 按键维护令牌桶的限流器
+type bucketLimiter struct {
+	rate    float64
+	burst   float64
+	buckets map[string]*tokenBucket
+}
+
+func newBucketLimiter(rate float64, burst int) *bucketLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &bucketLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// refill 补充令牌并返回键对应的桶，桶不存在时以满桶创建
+func (l *bucketLimiter) refill(key string, now time.Time) *tokenBucket {
+	b, exists := l.buckets[key]
+	if !exists {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+		return b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(l.burst, b.tokens+elapsed*l.rate)
+		b.last = now
+	}
+	return b
+}
+
+// wait 返回桶攒够一个令牌还需等待的时间
+func (l *bucketLimiter) wait(b *tokenBucket) time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// sweep 删除已经补满的桶，它们与新建的桶没有区别
+func (l *bucketLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// RateLimiter 分数更新接口的限流器，同时按客户端IP和玩家ID维护令牌桶
+type RateLimiter struct {
+	mu        sync.Mutex
+	ip        *bucketLimiter
+	player    *bucketLimiter
+	lastSweep time.Time
+	now       func() time.Time // 当前时间，测试时可替换
+}
+
+// NewRateLimiter 根据配置创建限流器
+func NewRateLimiter(cfg types.RateLimitConfig) *RateLimiter {
+	return &RateLimiter{
+		ip:        newBucketLimiter(cfg.IPRate, cfg.IPBurst),
+		player:    newBucketLimiter(cfg.PlayerRate, cfg.PlayerBurst),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Allow 为一次请求从IP桶和每个玩家的桶中各取一个令牌
+// 只要有一个桶令牌不足就拒绝整个请求且不消耗任何令牌，并返回需要等待的时间
+func (r *RateLimiter) Allow(ip string, playerIDs []int64) (bool, time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if now.Sub(r.lastSweep) >= rateLimitSweepInterval {
+		if r.ip != nil {
+			r.ip.sweep(now)
+		}
+		if r.player != nil {
+			r.player.sweep(now)
+		}
+		r.lastSweep = now
+	}
+
+	var buckets []*tokenBucket
+	var retryAfter time.Duration
+	check := func(l *bucketLimiter, key string) {
+		b := l.refill(key, now)
+		if wait := l.wait(b); wait > retryAfter {
+			retryAfter = wait
+		}
+		buckets = append(buckets, b)
+	}
+
+	if r.ip != nil {
+		check(r.ip, ip)
+	}
+	if r.player != nil {
+		for _, id := range playerIDs {
+			check(r.player, strconv.FormatInt(id, 10))
+		}
+	}
+
+	if retryAfter > 0 {
+		return false, retryAfter
+	}
+	for _, b := range buckets {
+		b.tokens--
+	}
+	return true, 0
+}
+
+// Middleware 返回分数更新接口的限流中间件
+// 从请求体中提取本次更新涉及的玩家ID（同一玩家只计一次），超限时返回 429 并设置 Retry-After
+func (r *RateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var playerIDs []int64
+		if r.player != nil {
+			ids, err := peekPlayerIDs(c)
+			if err != nil {
+				respondBindError(c, err)
+				c.Abort()
+				return
+			}
+			playerIDs = ids
+		}
+
+		if ok, retryAfter := r.Allow(c.ClientIP(), playerIDs); !ok {
+			seconds := int(math.Ceil(retryAfter.Seconds()))
+			c.Header(types.HeaderRetryAfter, strconv.Itoa(seconds))
+			respond(c, http.StatusTooManyRequests, types.Response{
+				Code:    types.CodeTooManyRequests,
+				Message: types.ErrorMessages[types.CodeTooManyRequests],
+				Data:    gin.H{"retry_after": seconds},
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// peekPlayerIDs 读取请求体中的玩家ID并去重，随后还原请求体供处理器绑定
+func peekPlayerIDs(c *gin.Context) ([]int64, error) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var req struct {
+		Updates []struct {
+			PlayerID int64 `json:"player_id"`
+		} `json:"updates"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int64]struct{}, len(req.Updates))
+	ids := make([]int64, 0, len(req.Updates))
+	for _, u := range req.Updates {
+		if _, dup := seen[u.PlayerID]; dup {
+			continue
+		}
+		seen[u.PlayerID] = struct{}{}
+		ids = append(ids, u.PlayerID)
+	}
+	return ids, nil
+}
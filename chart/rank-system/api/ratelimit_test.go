@@ -0,0 +1,194 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"rank-system/types"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeClock 手动推进的时钟
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.t }
+
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// newTestLimiter 创建使用手动时钟的限流器
+func newTestLimiter(cfg types.RateLimitConfig) (*RateLimiter, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1700000000, 0)}
+	r := NewRateLimiter(cfg)
+	r.now = clock.Now
+	r.lastSweep = clock.Now()
+	return r, clock
+}
+
+// 任一桶令牌不足时整个请求被拒绝，其余桶的令牌不被消耗
+func TestRateLimiterAllOrNothing(t *testing.T) {
+	r, _ := newTestLimiter(types.RateLimitConfig{IPRate: 1, IPBurst: 10, PlayerRate: 1, PlayerBurst: 1})
+
+	if ok, _ := r.Allow("ip", []int64{1, 2}); !ok {
+		t.Fatal("first request should be allowed")
+	}
+	ok, wait := r.Allow("ip", []int64{2, 3})
+	if ok || wait != time.Second {
+		t.Fatalf("player 2 exhausted: got=%v, %v want=false, 1s", ok, wait)
+	}
+	if got := r.ip.buckets["ip"].tokens; got != 9 {
+		t.Fatalf("ip tokens after rejection: got=%v want=9", got)
+	}
+	if got := r.player.buckets["3"].tokens; got != 1 {
+		t.Fatalf("player 3 tokens after rejection: got=%v want=1", got)
+	}
+	if ok, _ := r.Allow("ip", []int64{3}); !ok {
+		t.Fatal("player 3 should still have its token")
+	}
+	if got := r.ip.buckets["ip"].tokens; got != 8 {
+		t.Fatalf("ip tokens: got=%v want=8", got)
+	}
+}
+
+// 令牌按速率补充，等待时间取所有不足的桶中最长的一个
+func TestRateLimiterRefill(t *testing.T) {
+	r, clock := newTestLimiter(types.RateLimitConfig{IPRate: 2, IPBurst: 1, PlayerRate: 0.5, PlayerBurst: 1})
+
+	if ok, _ := r.Allow("ip", []int64{1}); !ok {
+		t.Fatal("first request should be allowed")
+	}
+	if ok, wait := r.Allow("ip", []int64{1}); ok || wait != 2*time.Second {
+		t.Fatalf("both exhausted: got=%v, %v want=false, 2s", ok, wait)
+	}
+	clock.Advance(time.Second)
+	if ok, wait := r.Allow("ip", []int64{1}); ok || wait != time.Second {
+		t.Fatalf("player still refilling: got=%v, %v want=false, 1s", ok, wait)
+	}
+	clock.Advance(time.Second)
+	if ok, _ := r.Allow("ip", []int64{1}); !ok {
+		t.Fatal("request should be allowed after refill")
+	}
+
+	// 补充的令牌不超过桶容量
+	clock.Advance(time.Hour)
+	r.Allow("ip", nil)
+	if got := r.ip.buckets["ip"].tokens; got != 0 {
+		t.Fatalf("ip tokens after long idle: got=%v want=0", got)
+	}
+}
+
+// 每隔 rateLimitSweepInterval 清理已补满的桶，未补满的桶保留
+func TestRateLimiterSweep(t *testing.T) {
+	r, clock := newTestLimiter(types.RateLimitConfig{IPRate: 1, IPBurst: 2, PlayerRate: 0.001, PlayerBurst: 1})
+
+	r.Allow("idle", []int64{1})
+	clock.Advance(rateLimitSweepInterval - time.Second)
+	r.Allow("other", nil)
+	if _, ok := r.ip.buckets["idle"]; !ok {
+		t.Fatal("bucket swept before the sweep interval")
+	}
+
+	clock.Advance(time.Second)
+	r.Allow("other", nil)
+	if _, ok := r.ip.buckets["idle"]; ok {
+		t.Fatal("refilled ip bucket should be swept")
+	}
+	if _, ok := r.player.buckets["1"]; !ok {
+		t.Fatal("player bucket still refilling should be kept")
+	}
+	if _, ok := r.ip.buckets["other"]; !ok {
+		t.Fatal("bucket used in this request should exist")
+	}
+}
+
+// 速率不大于 0 的维度不限流
+func TestRateLimiterDisabled(t *testing.T) {
+	r, _ := newTestLimiter(types.RateLimitConfig{PlayerRate: 1, PlayerBurst: 1})
+	for i := 0; i < 100; i++ {
+		if ok, _ := r.Allow("ip", nil); !ok {
+			t.Fatalf("request %d rejected without an ip limit", i)
+		}
+	}
+	if r.ip != nil {
+		t.Fatal("ip limiter should be disabled")
+	}
+}
+
+// rateLimitRouter 创建受限流保护的测试服务，处理器原样返回请求体
+func rateLimitRouter(r *RateLimiter) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/", r.Middleware(), func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(body))
+	})
+	return router
+}
+
+// postUpdates 向测试服务提交请求体
+func postUpdates(router *gin.Engine, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// 超限时返回 429，Retry-After 为向上取整的秒数
+func TestRateLimitMiddlewareRetryAfter(t *testing.T) {
+	r, clock := newTestLimiter(types.RateLimitConfig{PlayerRate: 0.4, PlayerBurst: 1})
+	router := rateLimitRouter(r)
+	body := `{"updates":[{"player_id":1,"score":10}]}`
+
+	if w := postUpdates(router, body); w.Code != http.StatusOK {
+		t.Fatalf("first request: got=%d want=%d", w.Code, http.StatusOK)
+	}
+	tests := []struct {
+		advance time.Duration
+		want    string
+	}{
+		{0, "3"},                       // 还需 2.5s
+		{1500 * time.Millisecond, "1"}, // 还需 1s，不多等一秒
+		{500 * time.Millisecond, "1"},  // 还需 0.5s
+	}
+	for _, tt := range tests {
+		clock.Advance(tt.advance)
+		w := postUpdates(router, body)
+		if w.Code != http.StatusTooManyRequests {
+			t.Fatalf("status: got=%d want=%d", w.Code, http.StatusTooManyRequests)
+		}
+		if got := w.Header().Get(types.HeaderRetryAfter); got != tt.want {
+			t.Fatalf("retry after: got=%q want=%q", got, tt.want)
+		}
+		if !strings.Contains(w.Body.String(), `"retry_after":`+tt.want) {
+			t.Fatalf("body: %s", w.Body)
+		}
+	}
+}
+
+// 中间件读取玩家ID后还原请求体，同一请求中重复的玩家只计一次
+func TestRateLimitMiddlewareBody(t *testing.T) {
+	r, _ := newTestLimiter(types.RateLimitConfig{PlayerRate: 1, PlayerBurst: 1})
+	router := rateLimitRouter(r)
+
+	body := `{"updates":[{"player_id":1,"score":10},{"player_id":1,"score":20},{"player_id":2,"score":5}]}`
+	w := postUpdates(router, body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: got=%d want=%d body=%s", w.Code, http.StatusOK, w.Body)
+	}
+	if w.Body.String() != body {
+		t.Fatalf("handler body: got=%q want=%q", w.Body, body)
+	}
+	if got := r.player.buckets["1"].tokens; got != 0 {
+		t.Fatalf("player 1 tokens: got=%v want=0", got)
+	}
+
+	if w := postUpdates(router, `{"updates":`); w.Code != http.StatusBadRequest {
+		t.Fatalf("malformed body: got=%d want=%d", w.Code, http.StatusBadRequest)
+	}
+}
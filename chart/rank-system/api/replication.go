@@ -0,0 +1,204 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"rank-system/service"
+	"rank-system/types"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// followerRetryInterval 跟随者拉取失败后的重试间隔
+const followerRetryInterval = time.Second
+
+// SetReadOnly 将节点设为只读副本，写接口返回 403，需在 RegisterRoutes 之前调用
+func (h *Handler) SetReadOnly(readOnly bool) {
+	h.readOnly = readOnly
+}
+
+// SetReplicationPollTimeout 设置复制流长轮询的最长等待时间，d 不大于 0 时忽略
+func (h *Handler) SetReplicationPollTimeout(d time.Duration) {
+	if d > 0 {
+		h.pollTimeout = d
+	}
+}
+
+// writable 在写接口前加上拒绝写入的中间件：只读副本总是拒绝，其余节点在维护模式下拒绝
+func (h *Handler) writable(handlers ...gin.HandlerFunc) []gin.HandlerFunc {
+	if !h.readOnly {
+		return append([]gin.HandlerFunc{h.rejectInMaintenance}, handlers...)
+	}
+	reject := func(c *gin.Context) {
+		respond(c, http.StatusForbidden, types.Response{
+			Code:    types.CodeReadOnly,
+			Message: types.ErrorMessages[types.CodeReadOnly],
+		})
+		c.Abort()
+	}
+	return append([]gin.HandlerFunc{reject}, handlers...)
+}
+
+// GetReplication 复制流长轮询接口
+// 查询参数：epoch 与 from 取自上一批次的 epoch 与 next，首次请求留空以获取快照；
+// limit 为单批最大条数；wait 为没有新记录时的最长等待时间，如 10s
+func (h *Handler) GetReplication(c *gin.Context) {
+	from, err := strconv.ParseUint(c.DefaultQuery("from", "0"), 10, 64)
+	if err != nil {
+		respondFieldError(c, "from", "numeric", "", "必须是非负整数")
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(types.MaxReplicationBatch)))
+	if err != nil || limit <= 0 || limit > types.MaxReplicationBatch {
+		respondFieldError(c, "limit", "max", strconv.Itoa(types.MaxReplicationBatch),
+			fmt.Sprintf("必须在 1 到 %d 之间", types.MaxReplicationBatch))
+		return
+	}
+	wait := h.pollTimeout
+	if raw := c.Query("wait"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d < 0 {
+			respondFieldError(c, "wait", "duration", "", "必须是非负的时长，如 10s")
+			return
+		}
+		if d < wait {
+			wait = d
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), wait)
+	defer cancel()
+	batch, err := h.rankService.ReadReplication(ctx, c.Query("epoch"), from, limit)
+	if err != nil {
+		respond(c, http.StatusNotFound, types.Response{
+			Code:    types.CodeNotFound,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	respond(c, http.StatusOK, types.Response{
+		Code:    types.CodeSuccess,
+		Message: types.ErrorMessages[types.CodeSuccess],
+		Data:    batch,
+	})
+}
+
+// respondFieldError 返回单个查询参数的校验错误
+func respondFieldError(c *gin.Context, field, rule, param, message string) {
+	respond(c, http.StatusBadRequest, types.Response{
+		Code:    types.CodeInvalidParams,
+		Message: types.ErrorMessages[types.CodeInvalidParams],
+		Errors: []*types.FieldError{{
+			Field:   field,
+			Rule:    rule,
+			Param:   param,
+			Message: message,
+		}},
+	})
+}
+
+// Follower 跟随者，持续从主节点拉取复制流并应用到本地
+type Follower struct {
+	rankService *service.RankService
+	leader      string
+	apiKey      string
+	pollTimeout time.Duration
+	client      *http.Client
+
+	epoch string
+	next  uint64
+}
+
+// NewFollower 根据复制配置创建跟随者
+func NewFollower(rankService *service.RankService, cfg types.ReplicationConfig) *Follower {
+	pollTimeout := cfg.PollTimeout
+	if pollTimeout <= 0 {
+		pollTimeout = types.DefaultReplicationPollTimeout
+	}
+	return &Follower{
+		rankService: rankService,
+		leader:      strings.TrimRight(cfg.Leader, "/"),
+		apiKey:      cfg.LeaderAPIKey,
+		pollTimeout: pollTimeout,
+		// 留出余量，避免在主节点返回空批次前超时
+		client: &http.Client{Timeout: pollTimeout + 10*time.Second},
+	}
+}
+
+// Run 持续拉取并应用复制流直到 ctx 结束，出错时记录日志并稍后重试
+func (f *Follower) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		batch, err := f.fetch(ctx)
+		if err == nil {
+			err = f.apply(batch)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Replication from %s failed: %v", f.leader, err)
+			select {
+			case <-time.After(followerRetryInterval):
+			case <-ctx.Done():
+			}
+		}
+	}
+}
+
+// apply 应用一批记录并推进拉取位置
+func (f *Follower) apply(batch *service.ReplicationBatch) error {
+	if batch.Snapshot {
+		log.Printf("Replication snapshot from %s: epoch=%s records=%d", f.leader, batch.Epoch, len(batch.Records))
+	}
+	if err := f.rankService.ApplyReplication(batch); err != nil {
+		// 本地状态可能只应用了一部分，下次重新同步快照
+		f.epoch = ""
+		return err
+	}
+	f.epoch, f.next = batch.Epoch, batch.Next
+	return nil
+}
+
+// fetch 向主节点发起一次长轮询
+func (f *Follower) fetch(ctx context.Context) (*service.ReplicationBatch, error) {
+	query := url.Values{}
+	query.Set("epoch", f.epoch)
+	query.Set("from", strconv.FormatUint(f.next, 10))
+	query.Set("wait", f.pollTimeout.String())
+	endpoint := f.leader + types.APIPrefix + "/replication?" + query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if f.apiKey != "" {
+		req.Header.Set(types.HeaderAPIKey, f.apiKey)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Code    int                       `json:"code"`
+		Message string                    `json:"message"`
+		Data    *service.ReplicationBatch `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || body.Data == nil {
+		return nil, fmt.Errorf("leader responded %d: %s", resp.StatusCode, body.Message)
+	}
+	return body.Data, nil
+}
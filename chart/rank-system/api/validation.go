@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"rank-system/types"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// 校验错误中的字段名使用 json 标签，与请求体中的字段一致
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return f.Name
+			}
+			return name
+		})
+	}
+}
+
+// respondBindError 将请求绑定失败转换为带字段级错误的参数错误响应
+func respondBindError(c *gin.Context, err error) {
+	respond(c, http.StatusBadRequest, types.Response{
+		Code:    types.CodeInvalidParams,
+		Message: types.ErrorMessages[types.CodeInvalidParams],
+		Errors:  translateBindError(err),
+	})
+}
+
+// pageSizeParam 解析查询参数 page_size：缺省时为 DefaultPageSize，
+// 不是整数或不在 [1, MaxPageSize] 内时返回参数错误，第二个返回值为 false
+func pageSizeParam(c *gin.Context) (int, bool) {
+	raw := c.Query("page_size")
+	if raw == "" {
+		return types.DefaultPageSize, true
+	}
+	size, err := strconv.Atoi(raw)
+	switch {
+	case err != nil:
+		respondFieldError(c, "page_size", "numeric", "", "必须是整数")
+	case size < 1:
+		respondFieldError(c, "page_size", "min", "1", "不能小于 1")
+	case size > types.MaxPageSize:
+		param := strconv.Itoa(types.MaxPageSize)
+		respondFieldError(c, "page_size", "max", param, "不能大于 "+param)
+	default:
+		return size, true
+	}
+	return 0, false
+}
+
+// translateBindError 将绑定错误转换为字段级错误列表
+func translateBindError(err error) []*types.FieldError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fieldErrs := make([]*types.FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fieldErrs = append(fieldErrs, &types.FieldError{
+				Field:   fieldPath(fe.Namespace()),
+				Rule:    fe.Tag(),
+				Param:   fe.Param(),
+				Message: ruleMessage(fe),
+			})
+		}
+		return fieldErrs
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return []*types.FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Param:   typeErr.Type.String(),
+			Message: fmt.Sprintf("类型错误，应为 %s", typeErr.Type),
+		}}
+	}
+
+	return []*types.FieldError{{Rule: "body", Message: err.Error()}}
+}
+
+// fieldPath 去掉校验错误命名空间中的顶层结构体名，如 BatchUpdateScoreRequest.updates[0].score -> updates[0].score
+func fieldPath(namespace string) string {
+	if i := strings.IndexByte(namespace, '.'); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+// ruleMessage 返回校验规则对应的中文说明
+func ruleMessage(fe validator.FieldError) string {
+	lengthRule := false
+	switch fe.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		lengthRule = true
+	}
+
+	switch fe.Tag() {
+	case "required":
+		return "不能为空"
+	case "min":
+		if lengthRule {
+			return fmt.Sprintf("长度不能小于 %s", fe.Param())
+		}
+		return fmt.Sprintf("不能小于 %s", fe.Param())
+	case "max":
+		if lengthRule {
+			return fmt.Sprintf("长度不能大于 %s", fe.Param())
+		}
+		return fmt.Sprintf("不能大于 %s", fe.Param())
+	case "alphanum":
+		return "只能包含字母和数字"
+	case "oneof":
+		return fmt.Sprintf("必须是 [%s] 之一", fe.Param())
+	default:
+		return fmt.Sprintf("未通过 %s 校验", fe.Tag())
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"rank-system/domain"
 	"rank-system/service"
 	"rank-system/storage"
+	"rank-system/types"
 	"sync"
 	"testing"
 	"time"
@@ -178,7 +179,7 @@ func testGetTopRanks(leaderboardID string) {
 // BenchmarkRankSystem 对排行榜系统的核心功能进行基准测试。
 func BenchmarkRankSystem(b *testing.B) {
 	repo := storage.NewMemoryRepository()
-	rankService := service.NewRankService(repo)
+	rankService := service.NewRankService(repo, storage.NewMemoryArchiveRepository())
 
 	// 创建测试数据
 	leaderboard := domain.NewLeaderboard("benchmark", "Benchmark",
@@ -196,12 +197,11 @@ func BenchmarkRankSystem(b *testing.B) {
 		playerID := int64(i % 100000)
 		score := int64((i % 600) + 1000)
 
-		req := &service.UpdateScoreRequest{
+		req := &types.BatchUpdateScoreRequest{
 			LeaderboardID: "benchmark",
-			PlayerID:      playerID,
-			Score:         score,
+			Updates:       []*types.ScoreUpdate{{PlayerID: playerID, Score: score}},
 		}
 
-		rankService.UpdateScore(req)
+		rankService.BatchUpdateScore(req)
 	}
 }
\ No newline at end of file
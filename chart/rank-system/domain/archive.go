@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Archive 排行榜某一周期结束时冻结的最终排名
+type Archive struct {
+	LeaderboardID string
+	Period        string    // 周期标识，如 2024-01-02、2024-W01、2024-01
+	StartTime     time.Time // 周期开始时间
+	EndTime       time.Time // 周期结束时间
+	Players       []*Player // 按排名排序的玩家快照
+}
+
+// FindPlayer 查找玩家在该周期的最终排名
+func (a *Archive) FindPlayer(playerID int64) (*Player, error) {
+	for _, p := range a.Players {
+		if p.ID == playerID {
+			return p, nil
+		}
+	}
+	return nil, ErrPlayerNotFound
+}
+
+// GetTopRanks 获取该周期的前N名
+func (a *Archive) GetTopRanks(count int) []*Player {
+	count = min(count, len(a.Players))
+	if count <= 0 {
+		return []*Player{}
+	}
+	return a.Players[:count]
+}
+
+// archiveJSON 归档的 JSON 表示，玩家使用 PlayerJSON 的字段名
+type archiveJSON struct {
+	LeaderboardID string
+	Period        string
+	StartTime     time.Time
+	EndTime       time.Time
+	Players       []*PlayerJSON
+}
+
+// MarshalJSON 以 PlayerJSON 输出玩家，保持归档文件与接口响应的字段名不变
+func (a Archive) MarshalJSON() ([]byte, error) {
+	return json.Marshal(archiveJSON{
+		LeaderboardID: a.LeaderboardID,
+		Period:        a.Period,
+		StartTime:     a.StartTime,
+		EndTime:       a.EndTime,
+		Players:       NewPlayerJSONList(a.Players),
+	})
+}
+
+// UnmarshalJSON 读取 MarshalJSON 输出的归档
+func (a *Archive) UnmarshalJSON(data []byte) error {
+	var v archiveJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*a = Archive{LeaderboardID: v.LeaderboardID, Period: v.Period, StartTime: v.StartTime, EndTime: v.EndTime}
+	a.Players = make([]*Player, 0, len(v.Players))
+	for _, p := range v.Players {
+		if p != nil {
+			a.Players = append(a.Players, p.ToPlayer())
+		}
+	}
+	return nil
+}
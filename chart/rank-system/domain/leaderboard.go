@@ -11,9 +11,12 @@ type Leaderboard struct {
 	ID          string
 	Name        string
 	Config      *RankConfig
+	Type        string    // 周期类型（daily/weekly/monthly/season），为空表示常驻排行榜
+	PeriodStart time.Time // 当前周期的开始时间
 	players     map[int64]*Player // 玩家数据
 	sorted      PlayerList        // 排序后的玩家列表
 	isDirty     bool              // 标记是否需要重新排序
+	scoreSum    int64             // 所有玩家分数之和，随更新维护，用于计算平均分
 	Version     int64
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
@@ -25,6 +28,7 @@ type RankConfig struct {
 	RewardRatio  float64
 	MinReward    int
 	MaxReward    int
+	RewardTiers  []RewardTier // 自定义奖励档位，为空时按 10 的幂自动划分
 }
 
 // NewRankConfig 创建排行榜配置
@@ -37,35 +41,110 @@ func NewRankConfig(totalPlayers int, rewardRatio float64, minReward, maxReward i
 	}
 }
 
+// Clone 深拷贝排行榜配置，奖励档位不与原配置共享
+func (c *RankConfig) Clone() *RankConfig {
+	cloned := *c
+	if c.RewardTiers != nil {
+		cloned.RewardTiers = make([]RewardTier, len(c.RewardTiers))
+		copy(cloned.RewardTiers, c.RewardTiers)
+	}
+	return &cloned
+}
+
 // NewLeaderboard 创建新排行榜
 func NewLeaderboard(id, name string, config *RankConfig) *Leaderboard {
 	return &Leaderboard{
 		ID:        id,
 		Name:      name,
-		Config:    config,
-		players:   make(map[int64]*Player),
-		sorted:    make(PlayerList, 0),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		Config:      config,
+		players:     make(map[int64]*Player),
+		sorted:      make(PlayerList, 0),
+		PeriodStart: time.Now(),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
 	}
 }
 
 // UpdatePlayerScore 更新玩家分数
 func (l *Leaderboard) UpdatePlayerScore(playerID, score int64) {
+	l.UpdatePlayerScoreWithMetadata(playerID, score, nil)
+}
+
+// UpdatePlayerScoreWithMetadata 更新玩家分数并合并附加信息
+func (l *Leaderboard) UpdatePlayerScoreWithMetadata(playerID, score int64, metadata map[string]string) {
 	player, exists := l.players[playerID]
 	if exists {
+		l.scoreSum -= player.Score
 		player.UpdateScore(score)
 	} else {
 		player = NewPlayer(playerID, score)
 		l.players[playerID] = player
 		l.sorted = append(l.sorted, player)
 	}
+	l.scoreSum += score
+	player.MergeMetadata(metadata)
+
+	l.isDirty = true
+	l.UpdatedAt = time.Now()
+	l.Version++
+}
+
+// RestorePlayer 以快照覆盖玩家数据，保留快照中的更新时间，用于从日志恢复
+func (l *Leaderboard) RestorePlayer(snapshot *Player) {
+	player := snapshot.Clone()
+	if old, exists := l.players[player.ID]; exists {
+		l.scoreSum -= old.Score
+		for i, p := range l.sorted {
+			if p == old {
+				l.sorted[i] = player
+				break
+			}
+		}
+	} else {
+		l.sorted = append(l.sorted, player)
+	}
+	l.players[player.ID] = player
+	l.scoreSum += player.Score
 
 	l.isDirty = true
+	if player.UpdateTime.After(l.UpdatedAt) {
+		l.UpdatedAt = player.UpdateTime
+	}
+	l.Version++
+}
+
+// Reset 清空排行榜的所有玩家数据，保留配置
+func (l *Leaderboard) Reset() {
+	l.players = make(map[int64]*Player)
+	l.sorted = make(PlayerList, 0)
+	l.isDirty = false
+	l.scoreSum = 0
 	l.UpdatedAt = time.Now()
 	l.Version++
 }
 
+// Rollover 结束当前周期，返回最终排名的归档并清空玩家数据开始新周期
+func (l *Leaderboard) Rollover(period string, now time.Time) *Archive {
+	l.ensureSorted()
+
+	players := make([]*Player, len(l.sorted))
+	for i, p := range l.sorted {
+		players[i] = p.Clone()
+	}
+
+	archive := &Archive{
+		LeaderboardID: l.ID,
+		Period:        period,
+		StartTime:     l.PeriodStart,
+		EndTime:       now,
+		Players:       players,
+	}
+
+	l.Reset()
+	l.PeriodStart = now
+	return archive
+}
+
 // GetPlayerRank 获取玩家排名
 func (l *Leaderboard) GetPlayerRank(playerID int64) (*Player, error) {
 	player, exists := l.players[playerID]
@@ -117,6 +196,36 @@ func (l *Leaderboard) GetTopRanks(count int) []*Player {
 	return result
 }
 
+// Stats 排行榜统计信息
+type Stats struct {
+	TotalPlayers int
+	AverageScore float64
+	MedianScore  int64
+	TopScore     int64
+}
+
+// GetStats 计算排行榜统计信息：最高分取排序后的首位，平均分由维护的分数和得出，中位数按排名定位
+func (l *Leaderboard) GetStats() Stats {
+	total := len(l.players)
+	if total == 0 {
+		return Stats{}
+	}
+
+	l.ensureSorted()
+
+	median := l.sorted[total/2].Score
+	if total%2 == 0 {
+		median = (l.sorted[total/2-1].Score + l.sorted[total/2].Score) / 2
+	}
+
+	return Stats{
+		TotalPlayers: total,
+		AverageScore: float64(l.scoreSum) / float64(total),
+		MedianScore:  median,
+		TopScore:     l.sorted[0].Score,
+	}
+}
+
 // GetPlayerCount 获取玩家数量
 func (l *Leaderboard) GetPlayerCount() int {
 	return len(l.players)
@@ -137,6 +246,16 @@ func (l *Leaderboard) SetSortedPlayers(players PlayerList) {
 	l.sorted = players
 }
 
+// GetScoreSum 获取所有玩家的分数之和
+func (l *Leaderboard) GetScoreSum() int64 {
+	return l.scoreSum
+}
+
+// SetScoreSum 设置所有玩家的分数之和
+func (l *Leaderboard) SetScoreSum(sum int64) {
+	l.scoreSum = sum
+}
+
 // IsDirty 获取是否需要重新排序
 func (l *Leaderboard) IsDirty() bool {
 	return l.isDirty
@@ -147,6 +266,11 @@ func (l *Leaderboard) SetIsDirty(isDirty bool) {
 	l.isDirty = isDirty
 }
 
+// Sort 按排名规则排序并更新玩家排名，已排序时为空操作；排序不改变版本
+func (l *Leaderboard) Sort() {
+	l.ensureSorted()
+}
+
 // ensureSorted 确保玩家列表已排序
 func (l *Leaderboard) ensureSorted() {
 	if !l.isDirty {
@@ -165,11 +289,7 @@ func (l *Leaderboard) ensureSorted() {
 
 // calculateRewardCount 计算奖励人数
 func (l *Leaderboard) calculateRewardCount() int {
-	total := len(l.players)
-	rewardCount := int(float64(total) * l.Config.RewardRatio)
-	rewardCount = max(rewardCount, l.Config.MinReward)
-	rewardCount = min(rewardCount, l.Config.MaxReward)
-	return rewardCount
+	return l.Config.RewardCount(len(l.players))
 }
 
 // 工具函数
@@ -191,4 +311,6 @@ func min(a, b int) int {
 var (
 	ErrPlayerNotFound      = errors.New("player not found")
 	ErrLeaderboardNotFound = errors.New("leaderboard not found")
+	ErrLeaderboardExists   = errors.New("leaderboard already exists")
+	ErrArchiveNotFound     = errors.New("archive not found")
 )
\ No newline at end of file
@@ -1,40 +1,54 @@
 package domain
 
 import (
+	"leaderboardcore"
 	"time"
 )
 
-// Player 玩家实体
-type Player struct {
+// Player 玩家实体，与其他排行榜服务共用 leaderboardcore 中的定义
+type Player = leaderboardcore.Player
+
+// NewPlayer 创建新玩家
+func NewPlayer(id, score int64) *Player {
+	return leaderboardcore.NewPlayer(id, score)
+}
+
+// PlayerList 玩家列表，用于排序，排序规则与跳表一致
+type PlayerList []*Player
+
+func (p PlayerList) Len() int           { return len(p) }
+func (p PlayerList) Less(i, j int) bool { return leaderboardcore.Compare(p[i], p[j]) > 0 }
+func (p PlayerList) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// PlayerJSON 玩家对外的 JSON 表示，用于 HTTP 响应与归档文件。
+// 沿用 rank-system 原有的字段名（ID、Score、Rank、UpdateTime、Metadata），不受 leaderboardcore.Player 的 JSON 标签影响
+type PlayerJSON struct {
 	ID         int64
 	Score      int64
 	Rank       int
 	UpdateTime time.Time
+	Metadata   map[string]string
 }
 
-// NewPlayer 创建新玩家
-func NewPlayer(id, score int64) *Player {
-	return &Player{
-		ID:         id,
-		Score:      score,
-		UpdateTime: time.Now(),
+// NewPlayerJSON 转换为对外的 JSON 表示，附加信息不与 p 共享；p 为 nil 时返回 nil
+func NewPlayerJSON(p *Player) *PlayerJSON {
+	if p == nil {
+		return nil
 	}
+	c := p.Clone()
+	return &PlayerJSON{ID: c.ID, Score: c.Score, Rank: c.Rank, UpdateTime: c.UpdateTime, Metadata: c.Metadata}
 }
 
-// UpdateScore 更新分数
-func (p *Player) UpdateScore(score int64) {
-	p.Score = score
-	p.UpdateTime = time.Now()
+// NewPlayerJSONList 逐个转换玩家列表
+func NewPlayerJSONList(players []*Player) []*PlayerJSON {
+	result := make([]*PlayerJSON, len(players))
+	for i, p := range players {
+		result[i] = NewPlayerJSON(p)
+	}
+	return result
 }
 
-// PlayerList 玩家列表，用于排序
-type PlayerList []*Player
-
-func (p PlayerList) Len() int           { return len(p) }
-func (p PlayerList) Less(i, j int) bool {
-	if p[i].Score == p[j].Score {
-		return p[i].UpdateTime.Before(p[j].UpdateTime)
-	}
-	return p[i].Score > p[j].Score
+// ToPlayer 转换回玩家实体
+func (p *PlayerJSON) ToPlayer() *Player {
+	return &Player{ID: p.ID, Score: p.Score, Rank: p.Rank, UpdateTime: p.UpdateTime, Metadata: p.Metadata}
 }
-func (p PlayerList) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
\ No newline at end of file
@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrInvalidRewardTiers 表示奖励档位的排名区间非法或互相重叠
+var ErrInvalidRewardTiers = errors.New("invalid reward tiers")
+
+// RewardTier 按排名区间 [MinRank, MaxRank] 配置的奖励档位
+type RewardTier struct {
+	MinRank int
+	MaxRank int
+	Reward  string
+}
+
+// Reward 玩家在某一周期结算时获得的奖励
+type Reward struct {
+	LeaderboardID string
+	Period        string
+	PlayerID      int64
+	Rank          int
+	Tier          string // 奖励档位，如 top1、top10 或自定义档位的奖励
+	SettledAt     time.Time
+}
+
+// RewardCount 根据玩家总数计算获奖人数：按比例计算后限制在 [MinReward, MaxReward] 内
+func (c *RankConfig) RewardCount(total int) int {
+	rewardCount := int(float64(total) * c.RewardRatio)
+	rewardCount = max(rewardCount, c.MinReward)
+	rewardCount = min(rewardCount, c.MaxReward)
+	return rewardCount
+}
+
+// SetRewardTiers 校验并设置自定义奖励档位，档位按排名排序且区间不能重叠
+func (c *RankConfig) SetRewardTiers(tiers []RewardTier) error {
+	sorted := make([]RewardTier, len(tiers))
+	copy(sorted, tiers)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].MinRank < sorted[j].MinRank })
+
+	for i, t := range sorted {
+		if t.MinRank < 1 || t.MaxRank < t.MinRank {
+			return fmt.Errorf("%w: rank range %d-%d", ErrInvalidRewardTiers, t.MinRank, t.MaxRank)
+		}
+		if i > 0 && t.MinRank <= sorted[i-1].MaxRank {
+			return fmt.Errorf("%w: rank range %d-%d overlaps %d-%d", ErrInvalidRewardTiers,
+				t.MinRank, t.MaxRank, sorted[i-1].MinRank, sorted[i-1].MaxRank)
+		}
+	}
+
+	c.RewardTiers = sorted
+	return nil
+}
+
+// RewardTier 返回指定排名对应的奖励档位，未获奖时 ok 为 false。
+// 配置了 RewardTiers 时按其排名区间匹配；否则档位按 10 的幂划分
+// （top1、top10、top100…），最后一档截止于获奖人数。
+func (c *RankConfig) RewardTier(rank, total int) (tier string, ok bool) {
+	if len(c.RewardTiers) > 0 {
+		for _, t := range c.RewardTiers {
+			if rank >= t.MinRank && rank <= t.MaxRank {
+				return t.Reward, true
+			}
+		}
+		return "", false
+	}
+
+	rewardCount := min(c.RewardCount(total), total)
+	if rank < 1 || rank > rewardCount {
+		return "", false
+	}
+
+	bound := 1
+	for bound < rank {
+		bound *= 10
+	}
+	return fmt.Sprintf("top%d", min(bound, rewardCount)), true
+}
+
+// SettleRewards 根据周期归档的最终排名计算所有获奖玩家的奖励
+func (c *RankConfig) SettleRewards(archive *Archive, now time.Time) []*Reward {
+	total := len(archive.Players)
+	rewards := make([]*Reward, 0)
+	for _, p := range archive.Players {
+		tier, ok := c.RewardTier(p.Rank, total)
+		if !ok {
+			continue
+		}
+		rewards = append(rewards, &Reward{
+			LeaderboardID: archive.LeaderboardID,
+			Period:        archive.Period,
+			PlayerID:      p.ID,
+			Rank:          p.Rank,
+			Tier:          tier,
+			SettledAt:     now,
+		})
+	}
+	return rewards
+}
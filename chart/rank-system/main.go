@@ -1,43 +1,163 @@
 package main
 
 import (
+	"config"
+	"context"
+	"crontab"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"rank-system/api"
+	"rank-system/domain"
+	"rank-system/metrics"
 	"rank-system/service"
 	"rank-system/storage"
 	"rank-system/types"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	// 加载配置：默认值 < RANK_CONFIG_PATH 指向的 YAML / JSON 文件 < 环境变量
+	cfg := types.DefaultConfig()
+	if err := config.Load(cfg); err != nil {
+		log.Fatal("Failed to load config:", err)
+	}
+
 	// 初始化依赖
 	repo := storage.NewMemoryRepository()
-	rankService := service.NewRankService(repo)
-	handler := api.NewHandler(rankService)
+	archives, err := storage.NewFileArchiveRepository(filepath.Join(cfg.Data.Dir, "archives"))
+	if err != nil {
+		log.Fatal("Failed to open archive storage:", err)
+	}
+	rankService := service.NewRankService(repo, archives)
+	rewardService := service.NewRewardService(storage.NewMemoryRewardRepository())
+	rankService.SetRewardService(rewardService)
+	serviceMetrics := metrics.New()
+	rankService.SetMetrics(serviceMetrics)
+	rewardService.Events().Subscribe("reward-logger", service.RewardSubjectPrefix+"*", func(subject string, reward *domain.Reward) {
+		log.Printf("Reward settled: leaderboard=%s period=%s player=%d rank=%d tier=%s",
+			reward.LeaderboardID, reward.Period, reward.PlayerID, reward.Rank, reward.Tier)
+	})
+	handler := api.NewHandler(rankService, rewardService)
+	handler.SetMaxBatchSize(cfg.System.RankUpdateBatch)
+	handler.SetRateLimiter(api.NewRateLimiter(cfg.RateLimit))
+	if !cfg.Auth.Enabled() {
+		log.Printf("Authentication disabled: set %s or %s to protect the API", types.EnvAPIKeys, types.EnvJWTSecret)
+	}
+	handler.SetAuthenticator(api.NewAuthenticator(cfg.Auth))
+	handler.SetReplicationPollTimeout(cfg.Replication.PollTimeout)
+	var cluster *api.ClusterRouter
+	if cfg.Cluster.Enabled() {
+		if cluster, err = api.NewClusterRouter(cfg.Cluster); err != nil {
+			log.Fatal("Failed to configure cluster:", err)
+		}
+		handler.SetClusterRouter(cluster)
+		log.Printf("Cluster mode: self=%s nodes=%v", cfg.Cluster.Self, cfg.Cluster.Nodes)
+	}
 
-	// 创建默认排行榜
-	createDefaultLeaderboard(rankService)
+	// 主节点回放写操作日志恢复重启前的排行榜并开启复制流；跟随者只读，数据全部来自主节点
+	var journal *storage.Journal
+	replicationCtx, stopReplication := context.WithCancel(context.Background())
+	defer stopReplication()
+	switch cfg.Replication.Role {
+	case types.ReplicationRoleLeader:
+		journal = openJournal(cfg, rankService)
+		rankService.EnableReplication(cfg.Replication.BufferSize)
+		if cluster == nil || cluster.Owns(defaultLeaderboardID) {
+			createDefaultLeaderboard(rankService)
+		}
+	case types.ReplicationRoleFollower:
+		if cfg.Replication.Leader == "" {
+			log.Fatal("Replication leader address is required for followers")
+		}
+		handler.SetReadOnly(true)
+		go api.NewFollower(rankService, cfg.Replication).Run(replicationCtx)
+		log.Printf("Running as read-only follower of %s", cfg.Replication.Leader)
+	default:
+		log.Fatalf("Unknown replication role %q", cfg.Replication.Role)
+	}
+
+	// 启动定时任务，驱动周期排行榜的自动轮转
+	crontab.Initialize()
 
 	// 设置Gin路由
 	router := gin.Default()
 
 	// 注册路由
 	handler.RegisterRoutes(router)
+	router.GET("/metrics", gin.WrapH(serviceMetrics))
 
 	// 启动服务
-	addr := fmt.Sprintf(":%d", types.DefaultServerPort)
-	log.Printf("Server starting on %s", addr)
-	if err := router.Run(addr); err != nil {
-		log.Fatal("Server failed to start:", err)
+	addr := fmt.Sprintf(":%d", cfg.Server.Port)
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      router,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+	}
+	server.RegisterOnShutdown(rankService.StopReplication)
+	go func() {
+		log.Printf("Server starting on %s", addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Server failed to start:", err)
+		}
+	}()
+
+	// 收到 SIGINT / SIGTERM 后停止接收新请求，等待进行中的请求完成，再取消周期轮转任务
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	stop()
+	log.Println("Shutting down server...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Println("Server forced to shutdown:", err)
 	}
+	stopReplication()
+	rankService.Close()
+	if journal != nil {
+		if err := journal.Close(); err != nil {
+			log.Println("Failed to close journal:", err)
+		}
+	}
+	log.Println("Server stopped.")
 }
 
-// createDefaultLeaderboard 创建默认排行榜
+// defaultLeaderboardID 默认排行榜ID，集群模式下只在所属节点上创建
+const defaultLeaderboardID = "default"
+
+// openJournal 打开写操作日志并回放，恢复重启前的排行榜
+func openJournal(cfg *types.Config, rankService *service.RankService) *storage.Journal {
+	journal, err := storage.OpenJournal(filepath.Join(cfg.Data.Dir, "journal.log"), storage.FsyncPolicy(cfg.Data.JournalFsync))
+	if err != nil {
+		log.Fatal("Failed to open journal:", err)
+	}
+	rankService.SetJournal(journal)
+
+	result, err := rankService.Recover(storage.ReplayMode(cfg.Data.JournalReplay))
+	if err != nil {
+		log.Fatal("Failed to replay journal:", err)
+	}
+	log.Printf("Journal replayed: applied=%d skipped=%d truncated=%d bytes", result.Applied, result.Skipped, result.Truncated)
+	return journal
+}
+
+// createDefaultLeaderboard 创建默认排行榜，已从日志恢复时跳过
 func createDefaultLeaderboard(rankService *service.RankService) {
+	if _, err := rankService.GetStats(defaultLeaderboardID); err == nil {
+		return
+	}
+
 	req := &types.CreateLeaderboardRequest{
-		ID:           "default",
+		ID:           defaultLeaderboardID,
 		Name:         "默认排行榜",
 		TotalPlayers: 300000,
 		RewardRatio:  0.003, // 0.3%
@@ -0,0 +1,229 @@
+// Package metrics 以 Prometheus 文本格式导出排行榜服务的运行指标。
+//
+// 指标名称基于 types 中声明的 Metric* 常量，统一加上 rank_ 前缀：
+//   - rank_update_score_duration_seconds：更新分数请求耗时（直方图）
+//   - rank_get_rank_duration_seconds：查询玩家排名耗时（直方图）
+//   - rank_leaderboard_size{leaderboard_id}：排行榜玩家数（仪表）
+//   - rank_cache_hit_rate：排序缓存命中率，即查询时排名已是最新、无需重新排序的比例（仪表）
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"rank-system/types"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// namespace 指标名称前缀
+const namespace = "rank_"
+
+// DefaultBuckets 耗时直方图的默认桶边界（秒）
+var DefaultBuckets = []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1}
+
+// Histogram 累积直方图
+type Histogram struct {
+	name    string
+	help    string
+	mu      sync.Mutex
+	buckets []float64 // 升序的桶上界，不含 +Inf
+	counts  []uint64  // 每个桶（非累积）的样本数，最后一个为 +Inf
+	sum     float64
+	count   uint64
+}
+
+// NewHistogram 创建直方图，buckets 须为升序
+func NewHistogram(name, help string, buckets []float64) *Histogram {
+	return &Histogram{
+		name:    name,
+		help:    help,
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)+1),
+	}
+}
+
+// Observe 记录一个样本
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts[i]++
+	h.sum += v
+	h.count++
+}
+
+// write 以文本格式输出直方图
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	var cumulative uint64
+	for i, le := range h.buckets {
+		cumulative += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", h.name, formatFloat(le), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", h.name, h.count)
+	fmt.Fprintf(w, "%s_sum %s\n%s_count %d\n", h.name, formatFloat(h.sum), h.name, h.count)
+}
+
+// GaugeVec 带单个标签的仪表
+type GaugeVec struct {
+	name   string
+	help   string
+	label  string
+	mu     sync.RWMutex
+	values map[string]float64
+}
+
+// NewGaugeVec 创建带标签的仪表
+func NewGaugeVec(name, help, label string) *GaugeVec {
+	return &GaugeVec{name: name, help: help, label: label, values: make(map[string]float64)}
+}
+
+// Set 设置标签值对应的读数
+func (g *GaugeVec) Set(labelValue string, v float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values[labelValue] = v
+}
+
+// Delete 移除标签值对应的读数
+func (g *GaugeVec) Delete(labelValue string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.values, labelValue)
+}
+
+// write 以文本格式输出仪表，按标签值排序保证输出稳定
+func (g *GaugeVec) write(w io.Writer) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name)
+	keys := make([]string, 0, len(g.values))
+	for k := range g.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s{%s=\"%s\"} %s\n", g.name, g.label, escapeLabel(k), formatFloat(g.values[k]))
+	}
+}
+
+// Metrics 排行榜服务的指标集合，nil 接收者上的记录方法为空操作
+type Metrics struct {
+	updateScoreDuration *Histogram
+	getRankDuration     *Histogram
+	leaderboardSize     *GaugeVec
+	cacheHits           uint64 // 原子读写
+	cacheMisses         uint64 // 原子读写
+}
+
+// New 创建指标集合
+func New() *Metrics {
+	return &Metrics{
+		updateScoreDuration: NewHistogram(namespace+types.MetricUpdateScoreDuration+"_seconds",
+			"Latency of score update requests in seconds.", DefaultBuckets),
+		getRankDuration: NewHistogram(namespace+types.MetricGetRankDuration+"_seconds",
+			"Latency of player rank queries in seconds.", DefaultBuckets),
+		leaderboardSize: NewGaugeVec(namespace+types.MetricLeaderboardSize,
+			"Number of players on the leaderboard.", "leaderboard_id"),
+	}
+}
+
+// ObserveUpdateScore 记录一次更新分数请求的耗时
+func (m *Metrics) ObserveUpdateScore(d time.Duration) {
+	if m != nil {
+		m.updateScoreDuration.Observe(d.Seconds())
+	}
+}
+
+// ObserveGetRank 记录一次排名查询的耗时
+func (m *Metrics) ObserveGetRank(d time.Duration) {
+	if m != nil {
+		m.getRankDuration.Observe(d.Seconds())
+	}
+}
+
+// SetLeaderboardSize 设置排行榜的玩家数
+func (m *Metrics) SetLeaderboardSize(id string, size int) {
+	if m != nil {
+		m.leaderboardSize.Set(id, float64(size))
+	}
+}
+
+// DeleteLeaderboard 移除已删除排行榜的指标
+func (m *Metrics) DeleteLeaderboard(id string) {
+	if m != nil {
+		m.leaderboardSize.Delete(id)
+	}
+}
+
+// RecordCache 记录一次缓存查询的结果
+func (m *Metrics) RecordCache(hit bool) {
+	if m == nil {
+		return
+	}
+	if hit {
+		atomic.AddUint64(&m.cacheHits, 1)
+	} else {
+		atomic.AddUint64(&m.cacheMisses, 1)
+	}
+}
+
+// CacheHitRate 返回缓存命中率，尚无查询时为 0
+func (m *Metrics) CacheHitRate() float64 {
+	hits := atomic.LoadUint64(&m.cacheHits)
+	total := hits + atomic.LoadUint64(&m.cacheMisses)
+	if total == 0 {
+		return 0
+	}
+	return float64(hits) / float64(total)
+}
+
+// Write 以 Prometheus 文本格式（0.0.4）输出全部指标
+func (m *Metrics) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	m.updateScoreDuration.write(bw)
+	m.getRankDuration.write(bw)
+	m.leaderboardSize.write(bw)
+
+	name := namespace + types.MetricCacheHitRate
+	fmt.Fprintf(bw, "# HELP %s Ratio of rank queries served without re-sorting the leaderboard.\n# TYPE %s gauge\n%s %s\n",
+		name, name, name, formatFloat(m.CacheHitRate()))
+	return bw.Flush()
+}
+
+// ServeHTTP 实现 http.Handler，供 /metrics 抓取
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set(types.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+	_ = m.Write(w)
+}
+
+// formatFloat 按 Prometheus 的约定格式化浮点数
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+// escapeLabel 转义标签值中的反斜杠、双引号与换行
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"log"
+	"rank-system/domain"
+	"rank-system/storage"
+	"rank-system/types"
+	"time"
+)
+
+// SetJournal 设置写操作日志，之后的创建、分数更新、重置、轮转与删除都会异步追加到日志中
+func (s *RankService) SetJournal(journal *storage.Journal) {
+	s.journal = journal
+}
+
+// appendJournal 追加日志记录并发布到复制流。内存状态已经更新，写日志失败只记录告警而不影响请求结果
+func (s *RankService) appendJournal(records ...*storage.JournalRecord) {
+	if len(records) == 0 {
+		return
+	}
+	if s.replication != nil {
+		s.replication.append(records...)
+	}
+	if s.journal == nil {
+		return
+	}
+	if err := s.journal.Append(records...); err != nil {
+		log.Printf("Failed to append journal for leaderboard %s: %v", records[0].LeaderboardID, err)
+	}
+}
+
+// journalPlayers 为本次批量更新涉及的玩家追加更新后的快照，同一玩家只记录一次
+func (s *RankService) journalPlayers(leaderboard *domain.Leaderboard, updates []*types.ScoreUpdate) {
+	if s.journal == nil && s.replication == nil {
+		return
+	}
+	players := leaderboard.GetPlayers()
+	seen := make(map[int64]struct{}, len(updates))
+	records := make([]*storage.JournalRecord, 0, len(updates))
+	for _, u := range updates {
+		if _, dup := seen[u.PlayerID]; dup {
+			continue
+		}
+		seen[u.PlayerID] = struct{}{}
+		if p, exists := players[u.PlayerID]; exists {
+			records = append(records, playerRecord(leaderboard.ID, p))
+		}
+	}
+	s.appendJournal(records...)
+}
+
+// playerRecord 生成玩家快照记录
+func playerRecord(leaderboardID string, p *domain.Player) *storage.JournalRecord {
+	return &storage.JournalRecord{
+		Op:            storage.JournalPlayer,
+		LeaderboardID: leaderboardID,
+		Time:          p.UpdateTime,
+		Player:        p.Clone(),
+	}
+}
+
+// createRecord 生成创建排行榜的记录，Time 为当前周期的开始时间
+func createRecord(leaderboard *domain.Leaderboard) *storage.JournalRecord {
+	return &storage.JournalRecord{
+		Op:            storage.JournalCreate,
+		LeaderboardID: leaderboard.ID,
+		Time:          leaderboard.PeriodStart,
+		Name:          leaderboard.Name,
+		Type:          leaderboard.Type,
+		Config:        leaderboard.Config,
+	}
+}
+
+// Recover 回放日志重建排行榜，为周期排行榜重新注册轮转任务，然后按当前状态压缩日志
+// 应在服务开始处理请求之前调用
+func (s *RankService) Recover(mode storage.ReplayMode) (storage.ReplayResult, error) {
+	if s.journal == nil {
+		return storage.ReplayResult{}, nil
+	}
+
+	var ids []string
+	seen := make(map[string]struct{})
+	result, err := s.journal.Replay(mode, func(r *storage.JournalRecord) error {
+		if _, exists := seen[r.LeaderboardID]; r.Op == storage.JournalCreate && !exists {
+			seen[r.LeaderboardID] = struct{}{}
+			ids = append(ids, r.LeaderboardID)
+		}
+		return s.applyJournal(r)
+	})
+	if err != nil {
+		return result, err
+	}
+
+	var records []*storage.JournalRecord
+	for _, id := range ids {
+		leaderboard, err := s.repo.Get(id)
+		if err != nil {
+			continue // 已被后续记录删除
+		}
+		records = append(records, createRecord(leaderboard))
+		for _, p := range leaderboard.GetSortedPlayers() {
+			records = append(records, playerRecord(id, p))
+		}
+
+		s.metrics.SetLeaderboardSize(id, leaderboard.GetPlayerCount())
+		if lbType, err := types.ParseLeaderboardType(leaderboard.Type); err == nil {
+			s.scheduleRollover(id, lbType)
+		}
+	}
+	return result, s.journal.Rewrite(records)
+}
+
+// applyJournal 将一条日志记录应用到仓储，引用不存在排行榜的记录会被忽略
+func (s *RankService) applyJournal(r *storage.JournalRecord) error {
+	if r.Op == storage.JournalCreate {
+		leaderboard := domain.NewLeaderboard(r.LeaderboardID, r.Name, r.Config)
+		leaderboard.Type = r.Type
+		leaderboard.PeriodStart = r.Time
+		leaderboard.CreatedAt = r.Time
+		leaderboard.UpdatedAt = r.Time
+		return s.repo.Save(leaderboard)
+	}
+	if r.Op == storage.JournalDelete {
+		return s.repo.Delete(r.LeaderboardID)
+	}
+
+	leaderboard, err := s.repo.Get(r.LeaderboardID)
+	if err != nil {
+		return nil
+	}
+	switch r.Op {
+	case storage.JournalPlayer:
+		leaderboard.RestorePlayer(r.Player)
+	case storage.JournalReset:
+		leaderboard.Reset()
+	case storage.JournalRollover:
+		leaderboard.Rollover("", r.Time) // 归档在轮转时已写入归档仓储，这里只需开始新周期
+	}
+	return s.repo.Save(leaderboard)
+}
+
+// journalEvent 生成不携带数据的排行榜事件记录
+func journalEvent(op storage.JournalOp, id string, at time.Time) *storage.JournalRecord {
+	return &storage.JournalRecord{Op: op, LeaderboardID: id, Time: at}
+}
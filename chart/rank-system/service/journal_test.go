@@ -0,0 +1,174 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"rank-system/storage"
+	"rank-system/types"
+	"strings"
+	"testing"
+)
+
+// openJournalService 创建使用 dir 中日志的服务并回放，服务与日志在测试结束时关闭
+func openJournalService(t *testing.T, dir string, mode storage.ReplayMode) (*RankService, storage.ReplayResult, error) {
+	t.Helper()
+	journal, err := storage.OpenJournal(dir+"/journal.log", storage.FsyncEverySec)
+	if err != nil {
+		t.Fatalf("open journal: %v", err)
+	}
+	s := NewRankService(storage.NewMemoryRepository(), storage.NewMemoryArchiveRepository())
+	s.SetJournal(journal)
+	t.Cleanup(func() {
+		s.Close()
+		journal.Close()
+	})
+	result, err := s.Recover(mode)
+	return s, result, err
+}
+
+// createBoard 通过接口创建排行榜，lbType 为空表示常驻排行榜
+func createBoard(t *testing.T, s *RankService, id, lbType string) {
+	t.Helper()
+	err := s.CreateLeaderboard(&types.CreateLeaderboardRequest{ID: id, Name: id + " board", Type: lbType, TotalPlayers: 1000, RewardRatio: 0.1, MinReward: 10, MaxReward: 100})
+	if err != nil {
+		t.Fatalf("create leaderboard %s: %v", id, err)
+	}
+}
+
+// assertRecovered 检查回放得到的排行榜与原服务一致：配置、周期与每个玩家的分数、附加信息、更新时间
+func assertRecovered(t *testing.T, got, want *RankService, id string) {
+	t.Helper()
+	assertReplicated(t, got, want, id)
+	g, _ := got.repo.Get(id)
+	w, _ := want.repo.Get(id)
+	if g.Name != w.Name || g.Type != w.Type || !g.PeriodStart.Equal(w.PeriodStart) || g.Config.TotalPlayers != w.Config.TotalPlayers {
+		t.Fatalf("leaderboard %s: got=%s/%s/%v want=%s/%s/%v", id, g.Name, g.Type, g.PeriodStart, w.Name, w.Type, w.PeriodStart)
+	}
+	for playerID, wp := range w.GetPlayers() {
+		gp := g.GetPlayers()[playerID]
+		if !gp.UpdateTime.Equal(wp.UpdateTime) || len(gp.Metadata) != len(wp.Metadata) {
+			t.Fatalf("player %d: got=%v/%v want=%v/%v", playerID, gp.UpdateTime, gp.Metadata, wp.UpdateTime, wp.Metadata)
+		}
+		for k, v := range wp.Metadata {
+			if gp.Metadata[k] != v {
+				t.Fatalf("player %d metadata %s: got=%q want=%q", playerID, k, gp.Metadata[k], v)
+			}
+		}
+	}
+}
+
+// 创建、更新、克隆、重置、轮转与删除写入日志，重启回放后的排行榜与重启前一致；
+// 回放后日志被压缩，从压缩后的日志再次回放得到同样的结果
+func TestRecoverRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	live, _, err := openJournalService(t, dir, storage.ReplayStrict)
+	if err != nil {
+		t.Fatalf("recover empty journal: %v", err)
+	}
+
+	createBoard(t, live, "weekly", "weekly")
+	createBoard(t, live, "plain", "")
+	createBoard(t, live, "gone", "")
+	updateScores(t, live, "weekly", map[int64]int64{1: 10, 2: 20})
+	if _, err := live.RolloverLeaderboard("weekly"); err != nil {
+		t.Fatalf("rollover: %v", err)
+	}
+	updateScores(t, live, "weekly", map[int64]int64{3: 30})
+	for i := int64(1); i <= 3; i++ {
+		req := &types.BatchUpdateScoreRequest{LeaderboardID: "plain", Updates: []*types.ScoreUpdate{
+			{PlayerID: 1, Score: 100 * i, Metadata: map[string]string{"nickname": "one", "round": strings.Repeat("x", int(i))}},
+			{PlayerID: 2, Score: 50 * i},
+		}}
+		if _, err := live.BatchUpdateScore(req); err != nil {
+			t.Fatalf("batch update: %v", err)
+		}
+	}
+	if _, err := live.CloneLeaderboard("plain", &types.CloneLeaderboardRequest{ID: "copy", CopyPlayers: true}); err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+	updateScores(t, live, "gone", map[int64]int64{9: 9})
+	if err := live.ResetLeaderboard("gone"); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	updateScores(t, live, "gone", map[int64]int64{8: 8})
+	createBoard(t, live, "deleted", "")
+	if err := live.DeleteLeaderboard("deleted"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := live.journal.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	before, _ := os.ReadFile(dir + "/journal.log")
+
+	for round := 0; round < 2; round++ {
+		recovered, result, err := openJournalService(t, dir, storage.ReplayStrict)
+		if err != nil {
+			t.Fatalf("round %d: recover: %v", round, err)
+		}
+		if result.Skipped != 0 || result.Truncated != 0 {
+			t.Fatalf("round %d: result: %+v want no corruption", round, result)
+		}
+		for _, id := range []string{"weekly", "plain", "copy", "gone"} {
+			assertRecovered(t, recovered, live, id)
+		}
+		if recovered.repo.Exists("deleted") {
+			t.Fatalf("round %d: deleted leaderboard recovered", round)
+		}
+		if _, scheduled := recovered.rollovers["weekly"]; !scheduled || len(recovered.rollovers) != 1 {
+			t.Fatalf("round %d: rollovers: %v want only weekly", round, recovered.rollovers)
+		}
+		// 压缩后每个排行榜一条创建记录加每个玩家一条记录：weekly 1+1，plain 1+2，copy 1+2，gone 1+1
+		if err := recovered.journal.Flush(); err != nil {
+			t.Fatalf("flush: %v", err)
+		}
+		after, _ := os.ReadFile(dir + "/journal.log")
+		if lines := strings.Count(string(after), "\n"); lines != 10 {
+			t.Fatalf("round %d: compacted journal: got=%d records want=10", round, lines)
+		}
+		if round == 0 && len(after) >= len(before) {
+			t.Fatalf("journal not compacted: before=%d after=%d bytes", len(before), len(after))
+		}
+		recovered.Close()
+		recovered.journal.Close()
+	}
+}
+
+// 宕机留下的半条记录：strict 回放失败，repair 截断后恢复之前的数据，之后的写入可正常回放
+func TestRecoverHalfWrittenRecord(t *testing.T) {
+	dir := t.TempDir()
+	live, _, err := openJournalService(t, dir, storage.ReplayStrict)
+	if err != nil {
+		t.Fatalf("recover empty journal: %v", err)
+	}
+	createBoard(t, live, "lb", "")
+	updateScores(t, live, "lb", map[int64]int64{1: 10})
+	live.journal.Close()
+
+	f, err := os.OpenFile(dir+"/journal.log", os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("open journal file: %v", err)
+	}
+	f.WriteString(`{"op":"player","leaderboard_id":"lb","player":{"id":2,`)
+	f.Close()
+
+	if _, _, err := openJournalService(t, dir, storage.ReplayStrict); !errors.As(err, new(*storage.CorruptRecordError)) {
+		t.Fatalf("strict recover: got=%v want corrupt record", err)
+	}
+
+	recovered, result, err := openJournalService(t, dir, storage.ReplayRepair)
+	if err != nil {
+		t.Fatalf("repair recover: %v", err)
+	}
+	if result.Applied != 2 || result.Truncated == 0 {
+		t.Fatalf("result: %+v want applied=2 and a truncated tail", result)
+	}
+	assertRecovered(t, recovered, live, "lb")
+
+	updateScores(t, recovered, "lb", map[int64]int64{2: 20})
+	recovered.journal.Flush()
+	again, _, err := openJournalService(t, dir, storage.ReplayStrict)
+	if err != nil {
+		t.Fatalf("strict recover after repair: %v", err)
+	}
+	assertRecovered(t, again, recovered, "lb")
+}
@@ -1,47 +1,108 @@
 package service
 
 import (
+	"crontab"
+	"hash/fnv"
 	"rank-system/domain"
+	"rank-system/metrics"
 	"rank-system/storage"
 	"rank-system/types"
+	"sort"
 	"sync"
+	"time"
 )
 
 // RankService 排名应用服务
 type RankService struct {
-	repo storage.Repository
+	repo     storage.Repository
+	archives storage.ArchiveRepository
+	rewards  *RewardService   // 可选，设置后在周期结算时发放奖励
+	metrics  *metrics.Metrics // 可选，为 nil 时不记录指标
+	journal  *storage.Journal // 可选，设置后写操作会追加到日志，重启时回放
+
+	replication *replicationLog // 可选，开启后写操作会保留在内存中供跟随者拉取
+
+	mu        sync.Mutex
+	rollovers map[string]crontab.Handle // 周期排行榜的轮转任务
+
+	// writeLocks 按排行榜ID分段的写锁。仓储的 Get 返回副本、Save 整体覆盖，
+	// 同一排行榜的读取-修改-保存与写日志必须串行，否则并发写入会互相覆盖，日志顺序也会与内存状态不一致
+	writeLocks [leaderboardLockStripes]sync.Mutex
+}
+
+// leaderboardLockStripes 排行榜写锁的分段数
+const leaderboardLockStripes = 64
+
+// lockLeaderboard 锁住排行榜 id 的写锁，返回解锁函数
+func (s *RankService) lockLeaderboard(id string) func() {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	mu := &s.writeLocks[h.Sum32()%leaderboardLockStripes]
+	mu.Lock()
+	return mu.Unlock
 }
 
 // NewRankService 创建排名服务
-func NewRankService(repo storage.Repository) *RankService {
+func NewRankService(repo storage.Repository, archives storage.ArchiveRepository) *RankService {
 	return &RankService{
-		repo: repo,
+		repo:      repo,
+		archives:  archives,
+		rollovers: make(map[string]crontab.Handle),
+	}
+}
+
+// SetRewardService 设置奖励服务，周期结算时据此计算并发放奖励
+func (s *RankService) SetRewardService(rewards *RewardService) {
+	s.rewards = rewards
+}
+
+// SetMetrics 设置指标集合，记录请求耗时、排行榜大小与排序缓存命中率
+func (s *RankService) SetMetrics(m *metrics.Metrics) {
+	s.metrics = m
+}
+
+// getSorted 读取排行榜供查询并记录排序缓存是否命中：存储的排行榜已排序即命中；
+// 未命中时排序，并在存储的版本未变时写回排序结果，之后的查询直接命中，直到下一次写入
+func (s *RankService) getSorted(id string) (*domain.Leaderboard, error) {
+	leaderboard, err := s.repo.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !leaderboard.IsDirty() {
+		s.metrics.RecordCache(true)
+		return leaderboard, nil
 	}
+	s.metrics.RecordCache(false)
+	leaderboard.Sort()
+	// 期间有写入时版本已变，放弃写回，下一次查询重新排序
+	if _, err := s.repo.SaveIfVersion(leaderboard); err != nil {
+		return nil, err
+	}
+	return leaderboard, nil
 }
 
 // BatchUpdateScore 批量更新玩家分数
 func (s *RankService) BatchUpdateScore(req *types.BatchUpdateScoreRequest) (*types.BatchResult, error) {
+	defer func(start time.Time) { s.metrics.ObserveUpdateScore(time.Since(start)) }(time.Now())
+	defer s.lockLeaderboard(req.LeaderboardID)()
+
 	leaderboard, err := s.repo.Get(req.LeaderboardID)
 	if err != nil {
 		return nil, err
 	}
 
-	var wg sync.WaitGroup
 	results := &types.BatchResult{}
 
-	for _, update := range req.Updates {
-		wg.Add(1)
-		go func(u *types.ScoreUpdate) {
-			defer wg.Done()
-			leaderboard.UpdatePlayerScore(u.PlayerID, u.Score)
-		}(update)
+	// 排行榜不是并发安全的，按顺序应用更新；同一玩家的多次更新以最后一次为准
+	for _, u := range req.Updates {
+		leaderboard.UpdatePlayerScoreWithMetadata(u.PlayerID, u.Score, u.Metadata)
 	}
 
-	wg.Wait()
-
 	if err := s.repo.Save(leaderboard); err != nil {
 		return nil, err
 	}
+	s.journalPlayers(leaderboard, req.Updates)
+	s.metrics.SetLeaderboardSize(leaderboard.ID, leaderboard.GetPlayerCount())
 
 	results.Success = len(req.Updates)
 	return results, nil
@@ -49,7 +110,9 @@ func (s *RankService) BatchUpdateScore(req *types.BatchUpdateScoreRequest) (*typ
 
 // GetPlayerRank 获取玩家排名
 func (s *RankService) GetPlayerRank(req *types.QueryLeaderboardRequest) (*types.PlayerRankResponse, error) {
-	leaderboard, err := s.repo.Get(req.LeaderboardID)
+	defer func(start time.Time) { s.metrics.ObserveGetRank(time.Since(start)) }(time.Now())
+
+	leaderboard, err := s.getSorted(req.LeaderboardID)
 	if err != nil {
 		return nil, err
 	}
@@ -59,33 +122,115 @@ func (s *RankService) GetPlayerRank(req *types.QueryLeaderboardRequest) (*types.
 		return nil, err
 	}
 
-	return &types.PlayerRankResponse{Player: player}, nil
+	total := leaderboard.GetPlayerCount()
+	return &types.PlayerRankResponse{
+		PlayerJSON:   domain.NewPlayerJSON(player),
+		TotalPlayers: total,
+		Percentile:   float64(player.Rank) / float64(total),
+	}, nil
+}
+
+// GetFriendsRank 获取一组玩家（如好友）之间的相对排名：逐个查询全榜排名后在本地排序
+// 重复的玩家ID只计一次，不在榜上的玩家列入 NotFound。
+func (s *RankService) GetFriendsRank(req *types.FriendsRankRequest) (*types.FriendsRankResponse, error) {
+	leaderboard, err := s.getSorted(req.LeaderboardID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &types.FriendsRankResponse{
+		LeaderboardID: req.LeaderboardID,
+		Players:       make([]*types.FriendRank, 0, len(req.PlayerIDs)),
+		NotFound:      []int64{},
+	}
+	seen := make(map[int64]struct{}, len(req.PlayerIDs))
+	for _, id := range req.PlayerIDs {
+		if _, dup := seen[id]; dup {
+			continue
+		}
+		seen[id] = struct{}{}
+
+		player, err := leaderboard.GetPlayerRank(id)
+		if err != nil {
+			resp.NotFound = append(resp.NotFound, id)
+			continue
+		}
+		resp.Players = append(resp.Players, &types.FriendRank{PlayerJSON: domain.NewPlayerJSON(player)})
+	}
+
+	sort.Slice(resp.Players, func(i, j int) bool { return resp.Players[i].Rank < resp.Players[j].Rank })
+	for i, p := range resp.Players {
+		p.FriendRank = i + 1
+	}
+	return resp, nil
 }
 
 // GetNearbyRanks 获取临近排名
 func (s *RankService) GetNearbyRanks(req *types.QueryLeaderboardRequest) (*types.LeaderboardResponse, error) {
-	leaderboard, err := s.repo.Get(req.LeaderboardID)
+	leaderboard, err := s.getSorted(req.LeaderboardID)
+	if err != nil {
+		return nil, err
+	}
+
+	nearbyRanks, err := leaderboard.GetNearbyRanks(req.PlayerID, types.NormalizePageSize(req.PageSize))
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.LeaderboardResponse{Players: domain.NewPlayerJSONList(nearbyRanks)}, nil
+}
+
+// PreviewReward 查询玩家按当前排名结算时可获得的奖励
+func (s *RankService) PreviewReward(req *types.QueryLeaderboardRequest) (*types.RewardPreviewResponse, error) {
+	leaderboard, err := s.getSorted(req.LeaderboardID)
 	if err != nil {
 		return nil, err
 	}
 
-	nearbyRanks, err := leaderboard.GetNearbyRanks(req.PlayerID, req.PageSize)
+	player, err := leaderboard.GetPlayerRank(req.PlayerID)
 	if err != nil {
 		return nil, err
 	}
 
-	return &types.LeaderboardResponse{Players: nearbyRanks}, nil
+	total := leaderboard.GetPlayerCount()
+	reward, eligible := leaderboard.Config.RewardTier(player.Rank, total)
+	return &types.RewardPreviewResponse{
+		LeaderboardID: req.LeaderboardID,
+		PlayerID:      player.ID,
+		Rank:          player.Rank,
+		TotalPlayers:  total,
+		Eligible:      eligible,
+		Reward:        reward,
+	}, nil
 }
 
 // GetTopRanks 获取前N名
 func (s *RankService) GetTopRanks(req *types.QueryLeaderboardRequest) (*types.LeaderboardResponse, error) {
-	leaderboard, err := s.repo.Get(req.LeaderboardID)
+	leaderboard, err := s.getSorted(req.LeaderboardID)
 	if err != nil {
 		return nil, err
 	}
 
-	topRanks := leaderboard.GetTopRanks(req.PageSize)
-	return &types.LeaderboardResponse{Players: topRanks}, nil
+	topRanks := leaderboard.GetTopRanks(types.NormalizePageSize(req.PageSize))
+	return &types.LeaderboardResponse{Players: domain.NewPlayerJSONList(topRanks)}, nil
+}
+
+// GetStats 获取排行榜统计信息
+func (s *RankService) GetStats(id string) (*types.LeaderboardStatsResponse, error) {
+	leaderboard, err := s.repo.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := leaderboard.GetStats()
+	return &types.LeaderboardStatsResponse{
+		LeaderboardID: leaderboard.ID,
+		TotalPlayers:  stats.TotalPlayers,
+		AverageScore:  stats.AverageScore,
+		MedianScore:   stats.MedianScore,
+		TopScore:      stats.TopScore,
+		UpdateTime:    leaderboard.UpdatedAt,
+	}, nil
 }
 
 // CreateLeaderboard 创建排行榜
@@ -97,6 +242,106 @@ func (s *RankService) CreateLeaderboard(req *types.CreateLeaderboardRequest) err
 		req.MaxReward,
 	)
 
+	if len(req.RewardTiers) > 0 {
+		tiers := make([]domain.RewardTier, len(req.RewardTiers))
+		for i, t := range req.RewardTiers {
+			tiers[i] = domain.RewardTier{MinRank: t.MinRank, MaxRank: t.MaxRank, Reward: t.Reward}
+		}
+		if err := config.SetRewardTiers(tiers); err != nil {
+			return err
+		}
+	}
+
 	leaderboard := domain.NewLeaderboard(req.ID, req.Name, config)
-	return s.repo.Save(leaderboard)
-}
\ No newline at end of file
+	defer s.lockLeaderboard(req.ID)()
+
+	var lbType types.LeaderboardType
+	if req.Type != "" {
+		var err error
+		if lbType, err = types.ParseLeaderboardType(req.Type); err != nil {
+			return err
+		}
+		leaderboard.Type = lbType.String()
+	}
+
+	if err := s.repo.Save(leaderboard); err != nil {
+		return err
+	}
+	s.appendJournal(createRecord(leaderboard))
+	s.metrics.SetLeaderboardSize(leaderboard.ID, 0)
+	if lbType != 0 {
+		s.scheduleRollover(req.ID, lbType)
+	}
+	return nil
+}
+// CloneLeaderboard 以源排行榜的配置与周期类型创建新排行榜，可选复制玩家数据；
+// 新排行榜从当前时间开始新的周期，玩家保留原分数、附加信息与更新时间
+func (s *RankService) CloneLeaderboard(sourceID string, req *types.CloneLeaderboardRequest) (*types.LeaderboardStatsResponse, error) {
+	source, err := s.repo.Get(sourceID)
+	if err != nil {
+		return nil, err
+	}
+	defer s.lockLeaderboard(req.ID)()
+
+	if s.repo.Exists(req.ID) {
+		return nil, domain.ErrLeaderboardExists
+	}
+
+	name := req.Name
+	if name == "" {
+		name = source.Name
+	}
+	leaderboard := domain.NewLeaderboard(req.ID, name, source.Config.Clone())
+	leaderboard.Type = source.Type
+	records := []*storage.JournalRecord{createRecord(leaderboard)}
+	if req.CopyPlayers {
+		for _, p := range source.GetSortedPlayers() {
+			leaderboard.RestorePlayer(p)
+			records = append(records, playerRecord(leaderboard.ID, p))
+		}
+	}
+
+	if err := s.repo.Save(leaderboard); err != nil {
+		return nil, err
+	}
+	s.appendJournal(records...)
+	s.metrics.SetLeaderboardSize(leaderboard.ID, leaderboard.GetPlayerCount())
+	if lbType, err := types.ParseLeaderboardType(leaderboard.Type); err == nil {
+		s.scheduleRollover(leaderboard.ID, lbType)
+	}
+	return s.GetStats(leaderboard.ID)
+}
+
+// DeleteLeaderboard 删除排行榜
+func (s *RankService) DeleteLeaderboard(id string) error {
+	defer s.lockLeaderboard(id)()
+
+	if !s.repo.Exists(id) {
+		return domain.ErrLeaderboardNotFound
+	}
+	s.cancelRollover(id)
+	s.metrics.DeleteLeaderboard(id)
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+	s.appendJournal(journalEvent(storage.JournalDelete, id, time.Now()))
+	return nil
+}
+
+// ResetLeaderboard 重置排行榜，清空所有玩家数据
+func (s *RankService) ResetLeaderboard(id string) error {
+	defer s.lockLeaderboard(id)()
+
+	leaderboard, err := s.repo.Get(id)
+	if err != nil {
+		return err
+	}
+
+	leaderboard.Reset()
+	if err := s.repo.Save(leaderboard); err != nil {
+		return err
+	}
+	s.appendJournal(journalEvent(storage.JournalReset, id, leaderboard.UpdatedAt))
+	s.metrics.SetLeaderboardSize(id, 0)
+	return nil
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"rank-system/domain"
+	"rank-system/metrics"
+	"rank-system/storage"
+	"rank-system/types"
+	"sync"
+	"testing"
+)
+
+// newTestService 创建使用内存仓储的排名服务，并创建一个空排行榜
+func newTestService(t *testing.T, id string) (*RankService, *storage.MemoryRepository) {
+	t.Helper()
+	repo := storage.NewMemoryRepository()
+	s := NewRankService(repo, storage.NewMemoryArchiveRepository())
+	if err := repo.Save(domain.NewLeaderboard(id, id, domain.NewRankConfig(1000, 0.1, 10, 100))); err != nil {
+		t.Fatalf("save leaderboard: %v", err)
+	}
+	return s, repo
+}
+
+// 一批新玩家的更新全部生效，分数之和（平均分）与逐个更新一致；配合 -race 运行
+func TestBatchUpdateScoreNewPlayers(t *testing.T) {
+	s, _ := newTestService(t, "batch")
+
+	const n = 200
+	req := &types.BatchUpdateScoreRequest{LeaderboardID: "batch"}
+	var sum int64
+	for i := int64(1); i <= n; i++ {
+		req.Updates = append(req.Updates, &types.ScoreUpdate{PlayerID: i, Score: i * 10})
+		sum += i * 10
+	}
+	result, err := s.BatchUpdateScore(req)
+	if err != nil {
+		t.Fatalf("batch update: %v", err)
+	}
+	if result.Success != n {
+		t.Fatalf("success: got=%d want=%d", result.Success, n)
+	}
+
+	stats, err := s.GetStats("batch")
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	if stats.TotalPlayers != n {
+		t.Fatalf("players: got=%d want=%d", stats.TotalPlayers, n)
+	}
+	if want := float64(sum) / n; stats.AverageScore != want {
+		t.Fatalf("average: got=%v want=%v", stats.AverageScore, want)
+	}
+	if stats.TopScore != n*10 {
+		t.Fatalf("top score: got=%d want=%d", stats.TopScore, n*10)
+	}
+}
+
+// 同一批次中同一玩家的多次更新按顺序合并附加信息，分数以最后一次为准
+func TestBatchUpdateScoreSamePlayerMetadata(t *testing.T) {
+	s, _ := newTestService(t, "meta")
+
+	req := &types.BatchUpdateScoreRequest{LeaderboardID: "meta"}
+	for i := int64(1); i <= 100; i++ {
+		req.Updates = append(req.Updates, &types.ScoreUpdate{
+			PlayerID: 7,
+			Score:    i,
+			Metadata: map[string]string{"nickname": "p7", "round": string(rune('a' + i%26))},
+		})
+	}
+	req.Updates = append(req.Updates, &types.ScoreUpdate{PlayerID: 7, Score: 500, Metadata: map[string]string{"round": ""}})
+	if _, err := s.BatchUpdateScore(req); err != nil {
+		t.Fatalf("batch update: %v", err)
+	}
+
+	resp, err := s.GetPlayerRank(&types.QueryLeaderboardRequest{LeaderboardID: "meta", PlayerID: 7})
+	if err != nil {
+		t.Fatalf("player rank: %v", err)
+	}
+	if resp.Score != 500 || resp.TotalPlayers != 1 {
+		t.Fatalf("player: score=%d players=%d, want score=500 players=1", resp.Score, resp.TotalPlayers)
+	}
+	if len(resp.Metadata) != 1 || resp.Metadata["nickname"] != "p7" {
+		t.Fatalf("metadata: got=%v want=map[nickname:p7]", resp.Metadata)
+	}
+}
+
+// 查询时排序结果写回仓储，之后的查询命中排序缓存，直到下一次写入
+func TestSortedCacheHitRate(t *testing.T) {
+	s, repo := newTestService(t, "cache")
+	m := metrics.New()
+	s.SetMetrics(m)
+
+	update := func(playerID, score int64) {
+		req := &types.BatchUpdateScoreRequest{LeaderboardID: "cache", Updates: []*types.ScoreUpdate{{PlayerID: playerID, Score: score}}}
+		if _, err := s.BatchUpdateScore(req); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+	}
+	query := &types.QueryLeaderboardRequest{LeaderboardID: "cache", PlayerID: 1}
+	update(1, 100)
+	update(2, 200)
+	for i := 0; i < 4; i++ {
+		resp, err := s.GetPlayerRank(query)
+		if err != nil {
+			t.Fatalf("player rank: %v", err)
+		}
+		if resp.Rank != 2 {
+			t.Fatalf("rank: got=%d want=2", resp.Rank)
+		}
+	}
+	if got := m.CacheHitRate(); got != 0.75 {
+		t.Fatalf("hit rate after 4 queries: got=%v want=0.75", got)
+	}
+	if stored, _ := repo.Get("cache"); stored.IsDirty() {
+		t.Fatal("sorted leaderboard should be saved back")
+	}
+
+	// 写入后第一次查询重新排序
+	update(1, 300)
+	resp, err := s.GetTopRanks(&types.QueryLeaderboardRequest{LeaderboardID: "cache", PageSize: 10})
+	if err != nil {
+		t.Fatalf("top ranks: %v", err)
+	}
+	if resp.Players[0].ID != 1 {
+		t.Fatalf("top player: got=%d want=1", resp.Players[0].ID)
+	}
+	if got := m.CacheHitRate(); got != 0.6 {
+		t.Fatalf("hit rate after write: got=%v want=0.6", got)
+	}
+
+	// 版本已变时不写回旧的排序结果
+	stale, _ := repo.Get("cache")
+	update(2, 400)
+	stale.Sort()
+	if saved, _ := repo.SaveIfVersion(stale); saved {
+		t.Fatal("stale leaderboard should not be saved back")
+	}
+}
+
+// 同一排行榜上并发的批量更新串行执行，不会互相覆盖；日志回放得到与内存一致的状态
+func TestBatchUpdateScoreConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	live, _, err := openJournalService(t, dir, storage.ReplayStrict)
+	if err != nil {
+		t.Fatalf("recover empty journal: %v", err)
+	}
+	createBoard(t, live, "lb", "")
+	// 排行榜足够大，每次读取与保存的副本耗时更长，并发批次更容易交错
+	const preload = 500
+	seed := &types.BatchUpdateScoreRequest{LeaderboardID: "lb"}
+	for i := int64(0); i < preload; i++ {
+		seed.Updates = append(seed.Updates, &types.ScoreUpdate{PlayerID: 10000 + i, Score: i})
+	}
+	if _, err := live.BatchUpdateScore(seed); err != nil {
+		t.Fatalf("preload: %v", err)
+	}
+
+	const workers, batches = 8, 25
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for b := 0; b < batches; b++ {
+				req := &types.BatchUpdateScoreRequest{LeaderboardID: "lb", Updates: []*types.ScoreUpdate{
+					{PlayerID: int64(w*batches + b), Score: int64(b)},
+					{PlayerID: 5000, Score: int64(w*batches + b)}, // 所有批次都更新的玩家
+				}}
+				if _, err := live.BatchUpdateScore(req); err != nil {
+					t.Errorf("batch update: %v", err)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	stats, err := live.GetStats("lb")
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	if want := preload + workers*batches + 1; stats.TotalPlayers != want {
+		t.Fatalf("players: got=%d want=%d (concurrent batches overwrote each other)", stats.TotalPlayers, want)
+	}
+	if err := live.journal.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	recovered, _, err := openJournalService(t, dir, storage.ReplayStrict)
+	if err != nil {
+		t.Fatalf("recover: %v", err)
+	}
+	assertRecovered(t, recovered, live, "lb")
+}
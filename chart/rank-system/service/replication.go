@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"rank-system/storage"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrReplicationDisabled 表示当前节点没有开启复制流
+var ErrReplicationDisabled = errors.New("replication disabled")
+
+// ReplicationBatch 复制流的一批记录
+type ReplicationBatch struct {
+	Epoch    string                   `json:"epoch"`    // 主节点本次启动的标识，变化时跟随者需要重新同步
+	Next     uint64                   `json:"next"`     // 下次拉取的起始序号
+	Snapshot bool                     `json:"snapshot"` // 为 true 时 Records 是完整快照，跟随者需先清空本地数据
+	Records  []*storage.JournalRecord `json:"records"`
+}
+
+// replicationLog 按序号保存最近的写操作记录，供跟随者长轮询拉取
+// 序号从 1 开始连续递增；超过容量两倍时丢弃最旧的记录，只保留最近 capacity 条
+type replicationLog struct {
+	mu       sync.Mutex
+	epoch    string
+	first    uint64 // records[0] 的序号
+	records  []*storage.JournalRecord
+	capacity int
+	notify   chan struct{} // 有新记录时关闭并替换，唤醒等待中的长轮询
+	stopped  chan struct{} // 关闭后等待中的长轮询立即返回
+	stopOnce sync.Once
+}
+
+func newReplicationLog(capacity int) *replicationLog {
+	return &replicationLog{
+		epoch:    strconv.FormatInt(time.Now().UnixNano(), 36),
+		first:    1,
+		capacity: capacity,
+		notify:   make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+// append 追加记录并唤醒等待者
+func (l *replicationLog) append(records ...*storage.JournalRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.records = append(l.records, records...)
+	if len(l.records) > 2*l.capacity {
+		drop := len(l.records) - l.capacity
+		n := copy(l.records, l.records[drop:])
+		for i := n; i < len(l.records); i++ {
+			l.records[i] = nil
+		}
+		l.records = l.records[:n]
+		l.first += uint64(drop)
+	}
+	close(l.notify)
+	l.notify = make(chan struct{})
+}
+
+// next 返回下一条记录将使用的序号
+func (l *replicationLog) next() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.first + uint64(len(l.records))
+}
+
+// read 读取从 from 开始的最多 limit 条记录；from 已被丢弃或超出范围时 ok 为 false
+// 没有新记录时返回的 wait 会在下一次追加时关闭
+func (l *replicationLog) read(from uint64, limit int) (records []*storage.JournalRecord, ok bool, wait <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	end := l.first + uint64(len(l.records))
+	if from < l.first || from > end {
+		return nil, false, nil
+	}
+	start := int(from - l.first)
+	stop := len(l.records)
+	if limit > 0 && stop-start > limit {
+		stop = start + limit
+	}
+	records = make([]*storage.JournalRecord, stop-start)
+	copy(records, l.records[start:stop])
+	return records, true, l.notify
+}
+
+// EnableReplication 开启复制流，之后的写操作会按顺序保留最近 capacity 条供跟随者拉取
+func (s *RankService) EnableReplication(capacity int) {
+	s.replication = newReplicationLog(capacity)
+}
+
+// ReadReplication 读取复制流。epoch 与主节点不一致或 from 已被丢弃时返回完整快照；
+// 暂无新记录时阻塞等待，直到有新记录或 ctx 结束，超时返回空批次
+func (s *RankService) ReadReplication(ctx context.Context, epoch string, from uint64, limit int) (*ReplicationBatch, error) {
+	log := s.replication
+	if log == nil {
+		return nil, ErrReplicationDisabled
+	}
+
+	for {
+		records, ok, wait := log.read(from, limit)
+		if epoch != log.epoch || !ok {
+			return s.replicationSnapshot(), nil
+		}
+		if len(records) > 0 {
+			return &ReplicationBatch{Epoch: log.epoch, Next: from + uint64(len(records)), Records: records}, nil
+		}
+
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return &ReplicationBatch{Epoch: log.epoch, Next: from, Records: records}, nil
+		case <-log.stopped:
+			return &ReplicationBatch{Epoch: log.epoch, Next: from, Records: records}, nil
+		}
+	}
+}
+
+// StopReplication 让等待中的长轮询立即返回空批次，服务关闭时调用，避免长轮询拖慢优雅退出
+func (s *RankService) StopReplication() {
+	if s.replication != nil {
+		s.replication.stopOnce.Do(func() { close(s.replication.stopped) })
+	}
+}
+
+// replicationSnapshot 生成所有排行榜当前状态的快照
+// 先取序号再读仓储，快照可能已包含序号之后的部分写入，重放这些记录是幂等的
+func (s *RankService) replicationSnapshot() *ReplicationBatch {
+	batch := &ReplicationBatch{
+		Epoch:    s.replication.epoch,
+		Next:     s.replication.next(),
+		Snapshot: true,
+		Records:  []*storage.JournalRecord{},
+	}
+	for _, id := range s.repo.List() {
+		leaderboard, err := s.repo.Get(id)
+		if err != nil {
+			continue
+		}
+		batch.Records = append(batch.Records, createRecord(leaderboard))
+		for _, p := range leaderboard.GetSortedPlayers() {
+			batch.Records = append(batch.Records, playerRecord(id, p))
+		}
+	}
+	return batch
+}
+
+// ApplyReplication 在跟随者上按顺序应用主节点的一批记录，快照会先清空本地所有排行榜
+func (s *RankService) ApplyReplication(batch *ReplicationBatch) error {
+	touched := make(map[string]struct{})
+	if batch.Snapshot {
+		for _, id := range s.repo.List() {
+			if err := s.repo.Delete(id); err != nil {
+				return err
+			}
+			touched[id] = struct{}{}
+		}
+	}
+
+	for _, r := range batch.Records {
+		if err := s.applyJournal(r); err != nil {
+			return err
+		}
+		touched[r.LeaderboardID] = struct{}{}
+	}
+
+	for id := range touched {
+		if leaderboard, err := s.repo.Get(id); err == nil {
+			s.metrics.SetLeaderboardSize(id, leaderboard.GetPlayerCount())
+		} else {
+			s.metrics.DeleteLeaderboard(id)
+		}
+	}
+	return nil
+}
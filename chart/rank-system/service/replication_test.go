@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"rank-system/storage"
+	"rank-system/types"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// seqRecords 生成 LeaderboardID 依次为 from..to 的记录，便于识别序号
+func seqRecords(from, to int) []*storage.JournalRecord {
+	records := make([]*storage.JournalRecord, 0, to-from+1)
+	for i := from; i <= to; i++ {
+		records = append(records, &storage.JournalRecord{Op: storage.JournalReset, LeaderboardID: strconv.Itoa(i)})
+	}
+	return records
+}
+
+// 记录超过容量两倍时丢弃最旧的记录，只保留最近 capacity 条，序号保持连续
+func TestReplicationLogTrim(t *testing.T) {
+	l := newReplicationLog(3)
+	l.append(seqRecords(1, 6)...)
+	if l.first != 1 || len(l.records) != 6 {
+		t.Fatalf("before trim: first=%d len=%d want first=1 len=6", l.first, len(l.records))
+	}
+
+	l.append(seqRecords(7, 7)...)
+	if l.first != 5 || len(l.records) != 3 || l.next() != 8 {
+		t.Fatalf("after trim: first=%d len=%d next=%d want first=5 len=3 next=8", l.first, len(l.records), l.next())
+	}
+	for i, r := range l.records[len(l.records):cap(l.records)] {
+		if r != nil {
+			t.Fatalf("trimmed slot %d still references a record", i)
+		}
+	}
+
+	tests := []struct {
+		from   uint64
+		limit  int
+		ok     bool
+		wantID []string
+	}{
+		{4, 0, false, nil},
+		{5, 0, true, []string{"5", "6", "7"}},
+		{5, 2, true, []string{"5", "6"}},
+		{7, 10, true, []string{"7"}},
+		{8, 0, true, []string{}},
+		{9, 0, false, nil},
+	}
+	for _, tt := range tests {
+		records, ok, _ := l.read(tt.from, tt.limit)
+		if ok != tt.ok || len(records) != len(tt.wantID) {
+			t.Fatalf("read(%d, %d): ok=%v len=%d want ok=%v len=%d", tt.from, tt.limit, ok, len(records), tt.ok, len(tt.wantID))
+		}
+		for i, r := range records {
+			if r.LeaderboardID != tt.wantID[i] {
+				t.Fatalf("read(%d, %d)[%d]: got=%s want=%s", tt.from, tt.limit, i, r.LeaderboardID, tt.wantID[i])
+			}
+		}
+	}
+}
+
+// newReplicationLeader 创建开启复制流的服务，并通过接口创建排行榜 id
+func newReplicationLeader(t *testing.T, id string, capacity int) *RankService {
+	t.Helper()
+	s := NewRankService(storage.NewMemoryRepository(), storage.NewMemoryArchiveRepository())
+	s.EnableReplication(capacity)
+	err := s.CreateLeaderboard(&types.CreateLeaderboardRequest{ID: id, Name: id, TotalPlayers: 1000, RewardRatio: 0.1, MinReward: 10, MaxReward: 100})
+	if err != nil {
+		t.Fatalf("create leaderboard: %v", err)
+	}
+	return s
+}
+
+// updateScores 通过批量接口更新分数
+func updateScores(t *testing.T, s *RankService, id string, scores map[int64]int64) {
+	t.Helper()
+	req := &types.BatchUpdateScoreRequest{LeaderboardID: id}
+	for playerID, score := range scores {
+		req.Updates = append(req.Updates, &types.ScoreUpdate{PlayerID: playerID, Score: score})
+	}
+	if _, err := s.BatchUpdateScore(req); err != nil {
+		t.Fatalf("batch update: %v", err)
+	}
+}
+
+// assertReplicated 检查跟随者的排行榜与主节点一致
+func assertReplicated(t *testing.T, follower, leader *RankService, id string) {
+	t.Helper()
+	want, err := leader.repo.Get(id)
+	if err != nil {
+		t.Fatalf("leader leaderboard: %v", err)
+	}
+	got, err := follower.repo.Get(id)
+	if err != nil {
+		t.Fatalf("follower leaderboard: %v", err)
+	}
+	gp, wp := got.GetSortedPlayers(), want.GetSortedPlayers()
+	if len(gp) != len(wp) {
+		t.Fatalf("players: got=%d want=%d", len(gp), len(wp))
+	}
+	for i := range wp {
+		if gp[i].ID != wp[i].ID || gp[i].Score != wp[i].Score {
+			t.Fatalf("player at %d: got=%d/%d want=%d/%d", i, gp[i].ID, gp[i].Score, wp[i].ID, wp[i].Score)
+		}
+	}
+}
+
+// 跟随者按序号增量拉取，应用后与主节点一致
+func TestReadReplicationIncremental(t *testing.T) {
+	leader := newReplicationLeader(t, "lb", 100)
+	epoch := leader.replication.epoch
+	updateScores(t, leader, "lb", map[int64]int64{1: 100, 2: 200})
+
+	batch, err := leader.ReadReplication(context.Background(), epoch, 1, 0)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if batch.Snapshot || len(batch.Records) != 3 || batch.Next != 4 {
+		t.Fatalf("batch: snapshot=%v records=%d next=%d want incremental 3 records next=4", batch.Snapshot, len(batch.Records), batch.Next)
+	}
+
+	follower := NewRankService(storage.NewMemoryRepository(), storage.NewMemoryArchiveRepository())
+	if err := follower.ApplyReplication(batch); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	assertReplicated(t, follower, leader, "lb")
+
+	updateScores(t, leader, "lb", map[int64]int64{1: 300})
+	batch, err = leader.ReadReplication(context.Background(), epoch, batch.Next, 0)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if batch.Snapshot || len(batch.Records) != 1 || batch.Next != 5 {
+		t.Fatalf("batch: snapshot=%v records=%d next=%d want incremental 1 record next=5", batch.Snapshot, len(batch.Records), batch.Next)
+	}
+	if err := follower.ApplyReplication(batch); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	assertReplicated(t, follower, leader, "lb")
+}
+
+// epoch 变化或 from 已被丢弃时返回完整快照，应用快照会清空跟随者上已有的数据
+func TestReadReplicationSnapshotFallback(t *testing.T) {
+	leader := newReplicationLeader(t, "lb", 2)
+	epoch := leader.replication.epoch
+	for i := int64(1); i <= 10; i++ {
+		updateScores(t, leader, "lb", map[int64]int64{i: i * 10})
+	}
+	if leader.replication.first == 1 {
+		t.Fatal("replication log should have been trimmed")
+	}
+	next := leader.replication.next()
+
+	tests := []struct {
+		name  string
+		epoch string
+		from  uint64
+	}{
+		{"epoch changed", "previous-epoch", next},
+		{"first pull", "", 1},
+		{"from trimmed", epoch, 1},
+		{"from ahead", epoch, next + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batch, err := leader.ReadReplication(context.Background(), tt.epoch, tt.from, 0)
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if !batch.Snapshot || batch.Epoch != epoch || batch.Next != next {
+				t.Fatalf("batch: snapshot=%v epoch=%s next=%d want snapshot epoch=%s next=%d", batch.Snapshot, batch.Epoch, batch.Next, epoch, next)
+			}
+			// 一条创建记录加每个玩家一条记录
+			if len(batch.Records) != 11 {
+				t.Fatalf("snapshot records: got=%d want=11", len(batch.Records))
+			}
+
+			follower := newReplicationLeader(t, "stale", 10)
+			updateScores(t, follower, "stale", map[int64]int64{99: 1})
+			if err := follower.ApplyReplication(batch); err != nil {
+				t.Fatalf("apply: %v", err)
+			}
+			if follower.repo.Exists("stale") {
+				t.Fatal("snapshot should remove leaderboards the leader does not have")
+			}
+			assertReplicated(t, follower, leader, "lb")
+		})
+	}
+}
+
+// pollResult 在后台执行一次长轮询
+func pollResult(ctx context.Context, s *RankService, epoch string, from uint64) <-chan *ReplicationBatch {
+	done := make(chan *ReplicationBatch, 1)
+	go func() {
+		batch, err := s.ReadReplication(ctx, epoch, from, 0)
+		if err != nil {
+			batch = nil
+		}
+		done <- batch
+	}()
+	return done
+}
+
+// 没有新记录时长轮询阻塞，新的写入会立即唤醒它
+func TestReadReplicationLongPollWakeup(t *testing.T) {
+	leader := newReplicationLeader(t, "lb", 100)
+	epoch, next := leader.replication.epoch, leader.replication.next()
+
+	done := pollResult(context.Background(), leader, epoch, next)
+	select {
+	case batch := <-done:
+		t.Fatalf("poll returned before any write: %+v", batch)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	updateScores(t, leader, "lb", map[int64]int64{1: 100})
+	select {
+	case batch := <-done:
+		if batch == nil || batch.Snapshot || len(batch.Records) != 1 || batch.Next != next+1 {
+			t.Fatalf("batch: %+v want one incremental record", batch)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("poll not woken up by a write")
+	}
+}
+
+// ctx 结束或复制流停止时，等待中的长轮询返回空批次，序号不变
+func TestReadReplicationLongPollStop(t *testing.T) {
+	leader := newReplicationLeader(t, "lb", 100)
+	epoch, next := leader.replication.epoch, leader.replication.next()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	batch, err := leader.ReadReplication(ctx, epoch, next, 0)
+	if err != nil || batch.Snapshot || len(batch.Records) != 0 || batch.Next != next {
+		t.Fatalf("timeout: batch=%+v err=%v want empty batch next=%d", batch, err, next)
+	}
+
+	done := pollResult(context.Background(), leader, epoch, next)
+	leader.StopReplication()
+	leader.StopReplication()
+	select {
+	case batch := <-done:
+		if batch == nil || len(batch.Records) != 0 || batch.Next != next {
+			t.Fatalf("stop: batch=%+v want empty batch next=%d", batch, next)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("poll not released by StopReplication")
+	}
+
+	// 停止后的拉取不再等待
+	batch, err = leader.ReadReplication(context.Background(), epoch, next, 0)
+	if err != nil || len(batch.Records) != 0 {
+		t.Fatalf("read after stop: batch=%+v err=%v", batch, err)
+	}
+}
+
+// 未开启复制流时拉取返回 ErrReplicationDisabled
+func TestReadReplicationDisabled(t *testing.T) {
+	s, _ := newTestService(t, "lb")
+	if _, err := s.ReadReplication(context.Background(), "", 1, 0); err != ErrReplicationDisabled {
+		t.Fatalf("read: got=%v want=%v", err, ErrReplicationDisabled)
+	}
+}
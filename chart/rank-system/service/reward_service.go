@@ -0,0 +1,51 @@
+package service
+
+import (
+	"pubsub"
+	"rank-system/domain"
+	"rank-system/storage"
+	"time"
+)
+
+// RewardSubjectPrefix 奖励事件的主题前缀，完整主题为 reward.<leaderboardID>
+const RewardSubjectPrefix = "reward."
+
+// RewardService 奖励应用服务，在周期结算时计算并发放奖励
+type RewardService struct {
+	repo   storage.RewardRepository
+	events *pubsub.GenericPubSub[*domain.Reward]
+}
+
+// NewRewardService 创建奖励服务
+func NewRewardService(repo storage.RewardRepository) *RewardService {
+	return &RewardService{
+		repo:   repo,
+		events: pubsub.NewGenericPubSub[*domain.Reward](),
+	}
+}
+
+// Events 返回奖励事件的发布订阅器，订阅 reward.* 可接收所有排行榜的奖励
+func (s *RewardService) Events() *pubsub.GenericPubSub[*domain.Reward] {
+	return s.events
+}
+
+// Settle 根据周期归档计算奖励，保存后逐个发布奖励事件
+func (s *RewardService) Settle(config *domain.RankConfig, archive *domain.Archive) ([]*domain.Reward, error) {
+	rewards := config.SettleRewards(archive, time.Now())
+	if err := s.repo.SaveRewards(rewards); err != nil {
+		return nil, err
+	}
+
+	subject := RewardSubjectPrefix + archive.LeaderboardID
+	for _, reward := range rewards {
+		if err := s.events.Publish(subject, reward); err != nil {
+			return nil, err
+		}
+	}
+	return rewards, nil
+}
+
+// GetPlayerRewards 获取玩家在排行榜上获得的所有奖励
+func (s *RewardService) GetPlayerRewards(leaderboardID string, playerID int64) ([]*domain.Reward, error) {
+	return s.repo.GetPlayerRewards(leaderboardID, playerID)
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"crontab"
+	"log"
+	"rank-system/domain"
+	"rank-system/storage"
+	"rank-system/types"
+	"time"
+)
+
+// RolloverLeaderboard 结束排行榜的当前周期：归档最终排名，并以相同配置开始新周期
+func (s *RankService) RolloverLeaderboard(id string) (*domain.Archive, error) {
+	defer s.lockLeaderboard(id)()
+
+	leaderboard, err := s.repo.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	period := leaderboard.PeriodStart.Format("20060102-150405")
+	if lbType, err := types.ParseLeaderboardType(leaderboard.Type); err == nil {
+		period = lbType.PeriodKey(leaderboard.PeriodStart)
+	}
+
+	archive := leaderboard.Rollover(period, time.Now())
+	if err := s.archives.SaveArchive(archive); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Save(leaderboard); err != nil {
+		return nil, err
+	}
+	s.appendJournal(journalEvent(storage.JournalRollover, id, archive.EndTime))
+	s.metrics.SetLeaderboardSize(id, 0)
+	if s.rewards != nil {
+		if _, err := s.rewards.Settle(leaderboard.Config, archive); err != nil {
+			return nil, err
+		}
+	}
+	return archive, nil
+}
+
+// scheduleRollover 为周期排行榜注册 crontab 任务，在周期结束时自动轮转
+func (s *RankService) scheduleRollover(id string, lbType types.LeaderboardType) {
+	minute, hour, day, month, dayofweek, ok := lbType.CronSpec()
+	if !ok {
+		return
+	}
+
+	handle := crontab.Register(minute, hour, day, month, dayofweek, func() {
+		archive, err := s.RolloverLeaderboard(id)
+		if err != nil {
+			log.Printf("Failed to rollover leaderboard %s: %v", id, err)
+			return
+		}
+		log.Printf("Leaderboard %s archived period %s with %d players", id, archive.Period, len(archive.Players))
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, exists := s.rollovers[id]; exists {
+		old.Unregister()
+	}
+	s.rollovers[id] = handle
+}
+
+// cancelRollover 取消排行榜的自动轮转任务
+func (s *RankService) cancelRollover(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if handle, exists := s.rollovers[id]; exists {
+		handle.Unregister()
+		delete(s.rollovers, id)
+	}
+}
+
+// GetHistory 查询排行榜的历史排名
+func (s *RankService) GetHistory(req *types.HistoryQueryRequest) (*types.HistoryResponse, error) {
+	if req.Period == "" {
+		archives, err := s.archives.ListArchives(req.LeaderboardID)
+		if err != nil {
+			return nil, err
+		}
+		periods := make([]string, len(archives))
+		for i, a := range archives {
+			periods[i] = a.Period
+		}
+		return &types.HistoryResponse{LeaderboardID: req.LeaderboardID, Periods: periods}, nil
+	}
+
+	archive, err := s.archives.GetArchive(req.LeaderboardID, req.Period)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &types.HistoryResponse{
+		LeaderboardID: archive.LeaderboardID,
+		Period:        archive.Period,
+		StartTime:     &archive.StartTime,
+		EndTime:       &archive.EndTime,
+		TotalPlayers:  len(archive.Players),
+	}
+	if req.PlayerID != 0 {
+		player, err := archive.FindPlayer(req.PlayerID)
+		if err != nil {
+			return nil, err
+		}
+		resp.Player = domain.NewPlayerJSON(player)
+		return resp, nil
+	}
+	resp.Players = domain.NewPlayerJSONList(archive.GetTopRanks(types.NormalizePageSize(req.PageSize)))
+	return resp, nil
+}
+
+// Close 取消所有排行榜的自动轮转任务，服务退出前调用，避免关闭过程中触发轮转
+func (s *RankService) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, handle := range s.rollovers {
+		handle.Unregister()
+		delete(s.rollovers, id)
+	}
+}
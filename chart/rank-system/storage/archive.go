@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"rank-system/domain"
+	"sort"
+	"sync"
+)
+
+// MemoryArchiveRepository 内存归档仓储实现
+type MemoryArchiveRepository struct {
+	archives map[string]map[string]*domain.Archive // leaderboardID -> period -> archive
+	mu       sync.RWMutex
+}
+
+// NewMemoryArchiveRepository 创建内存归档仓储
+func NewMemoryArchiveRepository() *MemoryArchiveRepository {
+	return &MemoryArchiveRepository{
+		archives: make(map[string]map[string]*domain.Archive),
+	}
+}
+
+// SaveArchive 保存归档，同一周期的归档会被覆盖
+func (r *MemoryArchiveRepository) SaveArchive(archive *domain.Archive) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	periods, exists := r.archives[archive.LeaderboardID]
+	if !exists {
+		periods = make(map[string]*domain.Archive)
+		r.archives[archive.LeaderboardID] = periods
+	}
+	periods[archive.Period] = archive
+	return nil
+}
+
+// GetArchive 获取指定周期的归档
+func (r *MemoryArchiveRepository) GetArchive(leaderboardID, period string) (*domain.Archive, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	archive, exists := r.archives[leaderboardID][period]
+	if !exists {
+		return nil, domain.ErrArchiveNotFound
+	}
+	return archive, nil
+}
+
+// ListArchives 列出排行榜的所有归档，按周期结束时间排序
+func (r *MemoryArchiveRepository) ListArchives(leaderboardID string) ([]*domain.Archive, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*domain.Archive, 0, len(r.archives[leaderboardID]))
+	for _, archive := range r.archives[leaderboardID] {
+		result = append(result, archive)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].EndTime.Before(result[j].EndTime) })
+	return result, nil
+}
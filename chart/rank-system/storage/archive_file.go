@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"rank-system/domain"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidArchiveName 表示排行榜 ID 或周期标识不能用作文件名
+var ErrInvalidArchiveName = errors.New("invalid archive name")
+
+const archiveFileExt = ".json"
+
+// FileArchiveRepository 基于文件的归档仓储实现，每个归档保存为 <dir>/<leaderboardID>/<period>.json
+type FileArchiveRepository struct {
+	dir string
+	mu  sync.RWMutex
+}
+
+// NewFileArchiveRepository 创建文件归档仓储，目录不存在时自动创建
+func NewFileArchiveRepository(dir string) (*FileArchiveRepository, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileArchiveRepository{dir: dir}, nil
+}
+
+// SaveArchive 将归档写入文件，先写临时文件再重命名，避免留下不完整的归档
+func (r *FileArchiveRepository) SaveArchive(archive *domain.Archive) error {
+	path, err := r.archivePath(archive.LeaderboardID, archive.Period)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(archive)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// GetArchive 读取指定周期的归档
+func (r *FileArchiveRepository) GetArchive(leaderboardID, period string) (*domain.Archive, error) {
+	path, err := r.archivePath(leaderboardID, period)
+	if err != nil {
+		return nil, domain.ErrArchiveNotFound
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return readArchive(path)
+}
+
+// ListArchives 列出排行榜的所有归档，按周期结束时间排序
+func (r *FileArchiveRepository) ListArchives(leaderboardID string) ([]*domain.Archive, error) {
+	if !validArchiveName(leaderboardID) {
+		return nil, ErrInvalidArchiveName
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	files, err := os.ReadDir(filepath.Join(r.dir, leaderboardID))
+	if os.IsNotExist(err) {
+		return []*domain.Archive{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*domain.Archive, 0, len(files))
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != archiveFileExt {
+			continue
+		}
+		archive, err := readArchive(filepath.Join(r.dir, leaderboardID, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, archive)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].EndTime.Before(result[j].EndTime) })
+	return result, nil
+}
+
+// archivePath 返回归档文件路径
+func (r *FileArchiveRepository) archivePath(leaderboardID, period string) (string, error) {
+	if !validArchiveName(leaderboardID) || !validArchiveName(period) {
+		return "", ErrInvalidArchiveName
+	}
+	return filepath.Join(r.dir, leaderboardID, period+archiveFileExt), nil
+}
+
+// validArchiveName 检查名称能否安全地用作文件名
+func validArchiveName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+// readArchive 从文件读取归档
+func readArchive(path string) (*domain.Archive, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, domain.ErrArchiveNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var archive domain.Archive
+	if err := json.Unmarshal(data, &archive); err != nil {
+		return nil, err
+	}
+	return &archive, nil
+}
@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"encoding/json"
+	"io"
+	"leaderboardcore"
+	"rank-system/domain"
+	"time"
+)
+
+// 日志的缓冲、刷盘、回放与压缩由 leaderboardcore.AppendLog 实现，与 leaderboard 的 AOF 共用；
+// 这里只负责记录的编码：每条记录是一行 JSON，便于携带附加信息。
+
+// FsyncPolicy 决定日志写入何时落盘
+type FsyncPolicy = leaderboardcore.FsyncPolicy
+
+const (
+	// FsyncAlways 每次写入后立即刷盘，最安全也最慢
+	FsyncAlways = leaderboardcore.FsyncAlways
+	// FsyncEverySec 写入先进入缓冲区，由后台每秒刷盘一次，宕机最多丢失约一秒的更新
+	FsyncEverySec = leaderboardcore.FsyncEverySec
+	// FsyncNo 后台每秒把缓冲区写入操作系统，但不主动刷盘
+	FsyncNo = leaderboardcore.FsyncNo
+)
+
+// ReplayMode 决定回放日志时如何处理损坏的记录
+type ReplayMode = leaderboardcore.ReplayMode
+
+const (
+	// ReplayTolerant 跳过无法解析的记录，继续回放后续内容
+	ReplayTolerant = leaderboardcore.ReplayTolerant
+	// ReplayStrict 遇到损坏的记录立即失败
+	ReplayStrict = leaderboardcore.ReplayStrict
+	// ReplayRepair 在第一条损坏的记录处截断日志，只保留之前的内容
+	ReplayRepair = leaderboardcore.ReplayRepair
+)
+
+var (
+	// ErrJournalClosed 表示日志已关闭
+	ErrJournalClosed = leaderboardcore.ErrLogClosed
+	// ErrInvalidFsyncPolicy 表示未知的 fsync 策略
+	ErrInvalidFsyncPolicy = leaderboardcore.ErrInvalidFsyncPolicy
+	// ErrInvalidReplayMode 表示未知的回放模式
+	ErrInvalidReplayMode = leaderboardcore.ErrInvalidReplayMode
+)
+
+// ParseFsyncPolicy 解析 fsync 策略，空字符串表示默认的 everysec
+func ParseFsyncPolicy(s string) (FsyncPolicy, error) {
+	return leaderboardcore.ParseFsyncPolicy(s)
+}
+
+// ParseReplayMode 解析回放模式，空字符串表示默认的 repair
+func ParseReplayMode(s string) (ReplayMode, error) {
+	return leaderboardcore.ParseReplayMode(s)
+}
+
+// JournalOp 日志记录的操作类型
+type JournalOp string
+
+const (
+	// JournalCreate 创建排行榜
+	JournalCreate JournalOp = "create"
+	// JournalPlayer 玩家分数更新后的快照
+	JournalPlayer JournalOp = "player"
+	// JournalReset 清空排行榜
+	JournalReset JournalOp = "reset"
+	// JournalRollover 结束当前周期并开始新周期
+	JournalRollover JournalOp = "rollover"
+	// JournalDelete 删除排行榜
+	JournalDelete JournalOp = "delete"
+)
+
+// JournalRecord 一条日志记录
+// 玩家记录保存更新后的完整快照而不是增量，回放时直接覆盖，与同一批次内的执行顺序无关
+type JournalRecord struct {
+	Op            JournalOp          `json:"op"`
+	LeaderboardID string             `json:"leaderboard_id"`
+	Time          time.Time          `json:"time"`
+	Name          string             `json:"name,omitempty"`   // create
+	Type          string             `json:"type,omitempty"`   // create
+	Config        *domain.RankConfig `json:"config,omitempty"` // create
+	Player        *domain.Player     `json:"player,omitempty"` // player
+}
+
+// valid 检查记录是否携带了操作所需的字段
+func (r *JournalRecord) valid() bool {
+	if r.LeaderboardID == "" {
+		return false
+	}
+	switch r.Op {
+	case JournalCreate:
+		return r.Config != nil
+	case JournalPlayer:
+		return r.Player != nil
+	case JournalReset, JournalRollover, JournalDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// CorruptRecordError 描述日志中一条损坏的记录
+type CorruptRecordError = leaderboardcore.CorruptRecordError
+
+// ReplayResult 汇总一次回放的结果
+type ReplayResult = leaderboardcore.ReplayResult
+
+// Journal 排行榜写操作的追加日志
+type Journal struct {
+	log *leaderboardcore.AppendLog
+}
+
+// OpenJournal 打开（必要时创建）日志文件，policy 为空时使用 everysec
+func OpenJournal(path string, policy FsyncPolicy) (*Journal, error) {
+	l, err := leaderboardcore.OpenAppendLog(path, policy)
+	if err != nil {
+		return nil, err
+	}
+	return &Journal{log: l}, nil
+}
+
+// Flush 将缓冲区中的记录写入文件，并按策略决定是否刷盘
+func (j *Journal) Flush() error {
+	return j.log.Flush()
+}
+
+// Append 追加记录，everysec 与 no 策略下只写入缓冲区
+func (j *Journal) Append(records ...*JournalRecord) error {
+	lines := make([][]byte, 0, len(records))
+	for _, r := range records {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, append(data, '\n'))
+	}
+	return j.log.Append(lines...)
+}
+
+// Replay 按顺序回放日志中的记录。没有换行结尾的最后一行视为写了一半的损坏记录
+func (j *Journal) Replay(mode ReplayMode, apply func(*JournalRecord) error) (ReplayResult, error) {
+	return j.log.Replay(mode, func(line string) error {
+		var record JournalRecord
+		if json.Unmarshal([]byte(line), &record) != nil || !record.valid() {
+			return leaderboardcore.ErrMalformedRecord
+		}
+		return apply(&record)
+	})
+}
+
+// Rewrite 以给定记录替换整个日志，用于回放后压缩已被覆盖的历史记录
+// 先写临时文件再重命名，重写失败时原日志保持不变
+func (j *Journal) Rewrite(records []*JournalRecord) error {
+	return j.log.Compact(func() func(w io.Writer) error {
+		return func(w io.Writer) error {
+			enc := json.NewEncoder(w)
+			for _, r := range records {
+				if err := enc.Encode(r); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	})
+}
+
+// Close 写出缓冲区并关闭日志文件
+func (j *Journal) Close() error {
+	return j.log.Close()
+}
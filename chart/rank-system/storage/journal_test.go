@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"rank-system/domain"
+	"strings"
+	"testing"
+	"time"
+)
+
+// encodeRecords 将记录编码为日志内容
+func encodeRecords(t *testing.T, records ...*JournalRecord) string {
+	t.Helper()
+	var b strings.Builder
+	for _, r := range records {
+		data, err := json.Marshal(r)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		b.Write(data)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// openTestJournal 以 content 为初始内容打开日志
+func openTestJournal(t *testing.T, content string) (*Journal, string) {
+	t.Helper()
+	path := t.TempDir() + "/journal.log"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write journal: %v", err)
+	}
+	j, err := OpenJournal(path, FsyncAlways)
+	if err != nil {
+		t.Fatalf("open journal: %v", err)
+	}
+	t.Cleanup(func() { j.Close() })
+	return j, path
+}
+
+// replayIDs 回放日志并按顺序收集记录的排行榜ID
+func replayIDs(j *Journal, mode ReplayMode) ([]string, ReplayResult, error) {
+	var ids []string
+	result, err := j.Replay(mode, func(r *JournalRecord) error {
+		ids = append(ids, r.LeaderboardID)
+		return nil
+	})
+	return ids, result, err
+}
+
+// 无法解析的 JSON、缺少必需字段或未知操作的记录，以及写了一半的最后一行，按回放模式处理
+func TestJournalReplayModes(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	valid := encodeRecords(t,
+		&JournalRecord{Op: JournalCreate, LeaderboardID: "a", Time: at, Config: domain.NewRankConfig(100, 0.1, 1, 10)},
+		&JournalRecord{Op: JournalPlayer, LeaderboardID: "a", Time: at, Player: &domain.Player{ID: 1, Score: 10, UpdateTime: at}},
+	)
+	tail := encodeRecords(t, &JournalRecord{Op: JournalReset, LeaderboardID: "b", Time: at})
+
+	corruptions := map[string]string{
+		"not json":       "{\"op\":\n",
+		"missing player": encodeRecords(t, &JournalRecord{Op: JournalPlayer, LeaderboardID: "x", Time: at}),
+		"unknown op":     encodeRecords(t, &JournalRecord{Op: "merge", LeaderboardID: "x", Time: at}),
+		"no id":          encodeRecords(t, &JournalRecord{Op: JournalReset, Time: at}),
+	}
+	for name, corrupt := range corruptions {
+		content := valid + corrupt + tail
+		t.Run(name+"/strict", func(t *testing.T) {
+			j, path := openTestJournal(t, content)
+			ids, result, err := replayIDs(j, ReplayStrict)
+			var ce *CorruptRecordError
+			if !errors.As(err, &ce) || ce.Offset != int64(len(valid)) {
+				t.Fatalf("replay error: got=%v want corrupt record at %d", err, len(valid))
+			}
+			if result.Applied != 2 || len(ids) != 2 {
+				t.Fatalf("applied: got=%d want=2", result.Applied)
+			}
+			if got, _ := os.ReadFile(path); string(got) != content {
+				t.Fatal("strict replay should not modify the journal")
+			}
+		})
+		t.Run(name+"/repair", func(t *testing.T) {
+			j, path := openTestJournal(t, content)
+			_, result, err := replayIDs(j, ReplayRepair)
+			if err != nil {
+				t.Fatalf("replay: %v", err)
+			}
+			if result.Applied != 2 || result.Truncated != int64(len(corrupt+tail)) {
+				t.Fatalf("result: got=%+v want applied=2 truncated=%d", result, len(corrupt+tail))
+			}
+			if got, _ := os.ReadFile(path); string(got) != valid {
+				t.Fatalf("journal after repair: got=%q want=%q", got, valid)
+			}
+		})
+		t.Run(name+"/tolerant", func(t *testing.T) {
+			j, _ := openTestJournal(t, content)
+			ids, result, err := replayIDs(j, ReplayTolerant)
+			if err != nil {
+				t.Fatalf("replay: %v", err)
+			}
+			if result.Applied != 3 || result.Skipped != 1 || strings.Join(ids, ",") != "a,a,b" {
+				t.Fatalf("result: got=%+v ids=%v want applied=3 skipped=1 ids=[a a b]", result, ids)
+			}
+		})
+	}
+
+	// 写了一半的最后一行
+	half := strings.TrimSuffix(tail, "\n")
+	j, path := openTestJournal(t, valid+half)
+	_, result, err := replayIDs(j, ReplayRepair)
+	if err != nil || result.Applied != 2 || result.Truncated != int64(len(half)) {
+		t.Fatalf("repair half line: got=%+v, %v want applied=2 truncated=%d", result, err, len(half))
+	}
+	if got, _ := os.ReadFile(path); string(got) != valid {
+		t.Fatalf("journal after repair: got=%q want=%q", got, valid)
+	}
+}
+
+// 回放回调返回的错误使回放立即失败
+func TestJournalReplayApplyError(t *testing.T) {
+	content := encodeRecords(t,
+		&JournalRecord{Op: JournalReset, LeaderboardID: "a"},
+		&JournalRecord{Op: JournalReset, LeaderboardID: "b"},
+	)
+	j, _ := openTestJournal(t, content)
+	failure := errors.New("save failed")
+	result, err := j.Replay(ReplayTolerant, func(r *JournalRecord) error {
+		if r.LeaderboardID == "b" {
+			return failure
+		}
+		return nil
+	})
+	if err != failure || result.Applied != 1 {
+		t.Fatalf("replay: got=%+v, %v want applied=1, %v", result, err, failure)
+	}
+}
+
+// Rewrite 以给定记录替换整个日志，之后的追加接在新内容之后
+func TestJournalRewrite(t *testing.T) {
+	old := encodeRecords(t,
+		&JournalRecord{Op: JournalReset, LeaderboardID: "a"},
+		&JournalRecord{Op: JournalReset, LeaderboardID: "a"},
+		&JournalRecord{Op: JournalDelete, LeaderboardID: "b"},
+	)
+	j, path := openTestJournal(t, old)
+
+	compacted := []*JournalRecord{{Op: JournalReset, LeaderboardID: "a"}}
+	if err := j.Rewrite(compacted); err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	if err := j.Append(&JournalRecord{Op: JournalReset, LeaderboardID: "c"}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	want := encodeRecords(t, compacted[0], &JournalRecord{Op: JournalReset, LeaderboardID: "c"})
+	if got, _ := os.ReadFile(path); string(got) != want {
+		t.Fatalf("journal: got=%q want=%q", got, want)
+	}
+	ids, _, err := replayIDs(j, ReplayStrict)
+	if err != nil || strings.Join(ids, ",") != "a,c" {
+		t.Fatalf("replay after rewrite: ids=%v err=%v want=[a c]", ids, err)
+	}
+
+	if err := j.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if err := j.Rewrite(compacted); err != ErrJournalClosed {
+		t.Fatalf("rewrite after close: got=%v want=%v", err, ErrJournalClosed)
+	}
+}
@@ -41,6 +41,19 @@ func (r *MemoryRepository) Save(leaderboard *domain.Leaderboard) error {
 	return nil
 }
 
+// SaveIfVersion 存储的排行榜版本未变时保存，排行榜不存在或版本已变时返回 false
+func (r *MemoryRepository) SaveIfVersion(leaderboard *domain.Leaderboard) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.leaderboards[leaderboard.ID]
+	if !exists || stored.Version != leaderboard.Version {
+		return false, nil
+	}
+	r.leaderboards[leaderboard.ID] = r.cloneLeaderboard(leaderboard)
+	return true, nil
+}
+
 // Delete 删除排行榜
 func (r *MemoryRepository) Delete(id string) error {
 	r.mu.Lock()
@@ -59,17 +72,30 @@ func (r *MemoryRepository) Exists(id string) bool {
 	return exists
 }
 
+// List 列出所有排行榜ID
+func (r *MemoryRepository) List() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]string, 0, len(r.leaderboards))
+	for id := range r.leaderboards {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
 // cloneLeaderboard 深拷贝排行榜
 func (r *MemoryRepository) cloneLeaderboard(original *domain.Leaderboard) *domain.Leaderboard {
 	cloned := domain.NewLeaderboard(original.ID, original.Name, original.Config)
+	cloned.Type = original.Type
+	cloned.PeriodStart = original.PeriodStart
 	cloned.Version = original.Version
 	cloned.CreatedAt = original.CreatedAt
 	cloned.UpdatedAt = original.UpdatedAt
 
 	// 拷贝玩家数据
 	for _, p := range original.GetPlayers() {
-		playerCopy := *p
-		cloned.GetPlayers()[p.ID] = &playerCopy
+		cloned.GetPlayers()[p.ID] = p.Clone()
 	}
 
 	// 拷贝排序列表
@@ -82,6 +108,7 @@ func (r *MemoryRepository) cloneLeaderboard(original *domain.Leaderboard) *domai
 	}
 	cloned.SetSortedPlayers(sortedPlayers)
 	cloned.SetIsDirty(original.IsDirty())
+	cloned.SetScoreSum(original.GetScoreSum())
 
 	return cloned
 }
\ No newline at end of file
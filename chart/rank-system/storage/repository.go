@@ -6,6 +6,22 @@ import "rank-system/domain"
 type Repository interface {
 	Get(id string) (*domain.Leaderboard, error)
 	Save(leaderboard *domain.Leaderboard) error
+	// SaveIfVersion 存储的排行榜版本与 leaderboard.Version 一致时保存，返回是否保存；用于写回查询时的排序结果
+	SaveIfVersion(leaderboard *domain.Leaderboard) (bool, error)
 	Delete(id string) error
 	Exists(id string) bool
-}
\ No newline at end of file
+	List() []string
+}
+
+// ArchiveRepository 排行榜归档仓储接口
+type ArchiveRepository interface {
+	SaveArchive(archive *domain.Archive) error
+	GetArchive(leaderboardID, period string) (*domain.Archive, error)
+	ListArchives(leaderboardID string) ([]*domain.Archive, error)
+}
+
+// RewardRepository 奖励仓储接口
+type RewardRepository interface {
+	SaveRewards(rewards []*domain.Reward) error
+	GetPlayerRewards(leaderboardID string, playerID int64) ([]*domain.Reward, error)
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"rank-system/domain"
+	"sync"
+)
+
+// MemoryRewardRepository 内存奖励仓储实现
+type MemoryRewardRepository struct {
+	rewards map[string]map[int64][]*domain.Reward // leaderboardID -> playerID -> rewards
+	mu      sync.RWMutex
+}
+
+// NewMemoryRewardRepository 创建内存奖励仓储
+func NewMemoryRewardRepository() *MemoryRewardRepository {
+	return &MemoryRewardRepository{
+		rewards: make(map[string]map[int64][]*domain.Reward),
+	}
+}
+
+// SaveRewards 保存一批奖励
+func (r *MemoryRewardRepository) SaveRewards(rewards []*domain.Reward) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, reward := range rewards {
+		players, exists := r.rewards[reward.LeaderboardID]
+		if !exists {
+			players = make(map[int64][]*domain.Reward)
+			r.rewards[reward.LeaderboardID] = players
+		}
+		players[reward.PlayerID] = append(players[reward.PlayerID], reward)
+	}
+	return nil
+}
+
+// GetPlayerRewards 获取玩家在排行榜上获得的所有奖励，按结算顺序排列
+func (r *MemoryRewardRepository) GetPlayerRewards(leaderboardID string, playerID int64) ([]*domain.Reward, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rewards := r.rewards[leaderboardID][playerID]
+	result := make([]*domain.Reward, len(rewards))
+	copy(result, rewards)
+	return result, nil
+}
@@ -4,18 +4,31 @@ import "time"
 
 // Config 是应用的根配置结构，聚合了所有模块的配置。
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Database DatabaseConfig `yaml:"database"`
-	Redis    RedisConfig    `yaml:"redis"`
-	Log      LogConfig      `yaml:"log"`
-	System   SystemConfig   `yaml:"system"`
+	Server      ServerConfig      `yaml:"server"`
+	Data        DataConfig        `yaml:"data"`
+	Database    DatabaseConfig    `yaml:"database"`
+	Redis       RedisConfig       `yaml:"redis"`
+	Log         LogConfig         `yaml:"log"`
+	System      SystemConfig      `yaml:"system"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit"`
+	Auth        AuthConfig        `yaml:"auth"`
+	Replication ReplicationConfig `yaml:"replication"`
+	Cluster     ClusterConfig     `yaml:"cluster"`
 }
 
 // ServerConfig 定义了HTTP服务器的相关配置。
 type ServerConfig struct {
-	Port         int           `yaml:"port"`
-	ReadTimeout  time.Duration `yaml:"read_timeout"`
-	WriteTimeout time.Duration `yaml:"write_timeout"`
+	Port            int           `yaml:"port" env:"RANK_SERVER_PORT"`
+	ReadTimeout     time.Duration `yaml:"read_timeout" env:"RANK_READ_TIMEOUT"`
+	WriteTimeout    time.Duration `yaml:"write_timeout" env:"RANK_WRITE_TIMEOUT"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"RANK_SHUTDOWN_TIMEOUT"`
+}
+
+// DataConfig 定义了本地数据文件的存放位置与写操作日志的持久化策略。
+type DataConfig struct {
+	Dir           string `yaml:"dir" env:"RANK_DATA_DIR"`
+	JournalFsync  string `yaml:"journal_fsync" env:"RANK_JOURNAL_FSYNC"`   // always / everysec / no，默认 everysec
+	JournalReplay string `yaml:"journal_replay" env:"RANK_JOURNAL_REPLAY"` // repair / tolerant / strict，默认 repair
 }
 
 // DatabaseConfig 定义了数据库连接的配置。
@@ -32,7 +45,7 @@ type RedisConfig struct {
 
 // LogConfig 定义了日志记录的相关配置。
 type LogConfig struct {
-	Level string `yaml:"level"`
+	Level string `yaml:"level" env:"RANK_LOG_LEVEL"`
 	Path  string `yaml:"path"`
 }
 
@@ -42,4 +55,82 @@ type SystemConfig struct {
 	CacheTTL        time.Duration `yaml:"cache_ttl" env:"CACHE_TTL"`
 	CleanupInterval time.Duration `yaml:"cleanup_interval" env:"CLEANUP_INTERVAL"`
 	RankUpdateBatch int           `yaml:"rank_update_batch" env:"RANK_UPDATE_BATCH"`
-}
\ No newline at end of file
+}
+
+// DefaultConfig 返回默认配置，配置文件与环境变量在此基础上覆盖。
+func DefaultConfig() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:            DefaultServerPort,
+			ShutdownTimeout: DefaultShutdownTimeout,
+		},
+		Data: DataConfig{Dir: DefaultDataDir},
+		Log:  LogConfig{Level: "info"},
+		System: SystemConfig{
+			MaxPlayers:      MaxLeaderboardSize,
+			CacheTTL:        CacheTTLShort,
+			RankUpdateBatch: MaxBatchUpdateSize,
+		},
+		RateLimit: DefaultRateLimitConfig(),
+		Replication: ReplicationConfig{
+			Role:        ReplicationRoleLeader,
+			BufferSize:  DefaultReplicationBuffer,
+			PollTimeout: DefaultReplicationPollTimeout,
+		},
+	}
+}
+
+// RateLimitConfig 定义了分数更新接口的令牌桶限流配置。
+// Rate 为每秒补充的令牌数，Burst 为桶容量；Rate 不大于 0 时不启用对应维度的限流。
+type RateLimitConfig struct {
+	IPRate      float64 `yaml:"ip_rate" env:"RATE_LIMIT_IP_RATE"`
+	IPBurst     int     `yaml:"ip_burst" env:"RATE_LIMIT_IP_BURST"`
+	PlayerRate  float64 `yaml:"player_rate" env:"RATE_LIMIT_PLAYER_RATE"`
+	PlayerBurst int     `yaml:"player_burst" env:"RATE_LIMIT_PLAYER_BURST"`
+}
+
+// DefaultRateLimitConfig 返回默认的限流配置。
+func DefaultRateLimitConfig() RateLimitConfig {
+	return RateLimitConfig{
+		IPRate:      DefaultIPRateLimit,
+		IPBurst:     DefaultIPRateBurst,
+		PlayerRate:  DefaultPlayerRateLimit,
+		PlayerBurst: DefaultPlayerRateBurst,
+	}
+}
+
+// AuthConfig 定义了接口鉴权配置。
+// APIKeys 与 JWTSecret 均未配置时不启用鉴权；JWT 仅支持 HS256 签名。
+type AuthConfig struct {
+	APIKeys     []string `yaml:"api_keys" env:"RANK_API_KEYS"`
+	JWTSecret   string   `yaml:"jwt_secret" env:"RANK_JWT_SECRET"`
+	JWTIssuer   string   `yaml:"jwt_issuer" env:"RANK_JWT_ISSUER"`          // 非空时校验 iss
+	PublicReads bool     `yaml:"public_reads" env:"RANK_AUTH_PUBLIC_READS"` // 为 true 时查询接口无需凭证
+}
+
+// Enabled 判断是否配置了任一鉴权方式。
+func (c AuthConfig) Enabled() bool {
+	return len(c.APIKeys) > 0 || c.JWTSecret != ""
+}
+
+// ReplicationConfig 定义了主从复制配置。
+// 主节点在内存中保留最近的写操作供跟随者长轮询拉取；跟随者只读，按顺序应用主节点的写操作。
+type ReplicationConfig struct {
+	Role         string        `yaml:"role" env:"RANK_REPLICATION_ROLE"`                 // leader / follower
+	Leader       string        `yaml:"leader" env:"RANK_REPLICATION_LEADER"`             // 跟随者使用：主节点地址，如 http://10.0.0.1:8080
+	LeaderAPIKey string        `yaml:"leader_api_key" env:"RANK_REPLICATION_API_KEY"`    // 跟随者使用：访问主节点复制流的API密钥
+	BufferSize   int           `yaml:"buffer_size" env:"RANK_REPLICATION_BUFFER"`        // 主节点使用：保留的复制记录条数
+	PollTimeout  time.Duration `yaml:"poll_timeout" env:"RANK_REPLICATION_POLL_TIMEOUT"` // 长轮询等待时长
+}
+
+// ClusterConfig 定义了集群模式配置。
+// 排行榜按ID哈希到 Nodes 中的一个节点，请求到达非所属节点时被代理到所属节点；Nodes 为空时不启用集群模式。
+type ClusterConfig struct {
+	Self  string   `yaml:"self" env:"RANK_CLUSTER_SELF"`   // 本节点地址，必须出现在 Nodes 中
+	Nodes []string `yaml:"nodes" env:"RANK_CLUSTER_NODES"` // 所有节点地址，如 http://10.0.0.1:8080，各节点需配置相同的列表
+}
+
+// Enabled 判断是否启用集群模式。
+func (c ClusterConfig) Enabled() bool {
+	return len(c.Nodes) > 0
+}
@@ -11,6 +11,12 @@ const (
 	DefaultPageSize = 20
 	// MaxPageSize 是分页查询中允许的最大页面大小。
 	MaxPageSize = 1000
+	// DefaultServerPort 是HTTP服务默认监听的端口。
+	DefaultServerPort = 8080
+	// DefaultShutdownTimeout 是优雅退出时等待进行中请求完成的默认时长。
+	DefaultShutdownTimeout = 10 * time.Second
+	// DefaultDataDir 是本地数据文件的默认目录。
+	DefaultDataDir = "./data"
 )
 
 const (
@@ -29,6 +35,30 @@ const (
 	MaxBatchUpdateSize = 1000
 )
 
+const (
+	// DefaultIPRateLimit 是单个IP每秒允许的分数更新请求数。
+	DefaultIPRateLimit = 200
+	// DefaultIPRateBurst 是单个IP允许的突发请求数。
+	DefaultIPRateBurst = 400
+	// DefaultPlayerRateLimit 是单个玩家每秒允许的分数更新次数。
+	DefaultPlayerRateLimit = 5
+	// DefaultPlayerRateBurst 是单个玩家允许的突发更新次数。
+	DefaultPlayerRateBurst = 10
+)
+
+const (
+	// ReplicationRoleLeader 表示节点接受写入，并向跟随者提供复制流。
+	ReplicationRoleLeader = "leader"
+	// ReplicationRoleFollower 表示节点是只读副本，从主节点拉取复制流。
+	ReplicationRoleFollower = "follower"
+	// DefaultReplicationBuffer 是主节点在内存中保留的复制记录条数，落后更多的跟随者需要重新同步快照。
+	DefaultReplicationBuffer = 100000
+	// DefaultReplicationPollTimeout 是复制流长轮询的默认等待时长。
+	DefaultReplicationPollTimeout = 30 * time.Second
+	// MaxReplicationBatch 是单次复制请求返回的最大记录数。
+	MaxReplicationBatch = 1000
+)
+
 const (
 	// MinPlayerID 是玩家ID的最小值。
 	MinPlayerID = 1
@@ -47,6 +77,14 @@ const (
 	EnvLogLevel = "RANK_LOG_LEVEL"
 	// EnvServerPort 是配置服务器端口的环境变量键。
 	EnvServerPort = "RANK_SERVER_PORT"
+	// EnvAPIKeys 是配置API密钥的环境变量键，多个密钥以逗号分隔。
+	EnvAPIKeys = "RANK_API_KEYS"
+	// EnvJWTSecret 是配置JWT签名密钥的环境变量键。
+	EnvJWTSecret = "RANK_JWT_SECRET"
+	// EnvJWTIssuer 是配置JWT签发者的环境变量键。
+	EnvJWTIssuer = "RANK_JWT_ISSUER"
+	// EnvAuthPublicReads 是控制查询接口是否公开的环境变量键。
+	EnvAuthPublicReads = "RANK_AUTH_PUBLIC_READS"
 )
 
 const (
@@ -56,6 +94,14 @@ const (
 	HeaderUserID = "X-User-ID"
 	// HeaderContentType 是标准的Content-Type HTTP头。
 	HeaderContentType = "Content-Type"
+	// HeaderRetryAfter 是限流时告知客户端重试等待秒数的HTTP头。
+	HeaderRetryAfter = "Retry-After"
+	// HeaderAPIKey 是用于传递API密钥的HTTP头。
+	HeaderAPIKey = "X-API-Key"
+	// HeaderAuthorization 是用于传递 Bearer JWT 的HTTP头。
+	HeaderAuthorization = "Authorization"
+	// HeaderForwardedBy 是集群内转发请求时标记来源节点的HTTP头。
+	HeaderForwardedBy = "X-Rank-Forwarded-By"
 )
 
 const (
@@ -82,16 +128,28 @@ const (
 	CodeDuplicate = 10004
 	// CodeUnauthorized 表示未经授权的错误码。
 	CodeUnauthorized = 10005
+	// CodeTooManyRequests 表示请求过于频繁、触发限流的错误码。
+	CodeTooManyRequests = 10006
+	// CodeReadOnly 表示只读副本拒绝写操作的错误码。
+	CodeReadOnly = 10007
+	// CodeNodeUnavailable 表示集群中负责该排行榜的节点不可用的错误码。
+	CodeNodeUnavailable = 10008
+	// CodeMaintenance 表示节点处于维护模式、暂停接受写操作的错误码。
+	CodeMaintenance = 10009
 )
 
 // ErrorMessages 是错误码到错误消息的映射。
 var ErrorMessages = map[int]string{
-	CodeSuccess:       "成功",
-	CodeInvalidParams: "参数错误",
-	CodeNotFound:      "资源不存在",
-	CodeInternalError: "内部错误",
-	CodeDuplicate:     "重复操作",
-	CodeUnauthorized:  "未授权",
+	CodeSuccess:         "成功",
+	CodeInvalidParams:   "参数错误",
+	CodeNotFound:        "资源不存在",
+	CodeInternalError:   "内部错误",
+	CodeDuplicate:       "重复操作",
+	CodeUnauthorized:    "未授权",
+	CodeTooManyRequests: "请求过于频繁",
+	CodeReadOnly:        "只读副本不接受写操作",
+	CodeNodeUnavailable: "节点不可用",
+	CodeMaintenance:     "系统维护中，暂不接受写操作",
 }
 
 // ContextKey 是用于在上下文中存储值的键类型。
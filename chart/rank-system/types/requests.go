@@ -4,13 +4,21 @@ import "time"
 
 // CreateLeaderboardRequest 定义了创建排行榜时所需的请求体结构。
 type CreateLeaderboardRequest struct {
-	ID           string  `json:"id" binding:"required,alphanum"`
-	Name         string  `json:"name" binding:"required"`
-	Type         string  `json:"type" binding:"required"`
-	TotalPlayers int     `json:"total_players" binding:"min=1"`
-	RewardRatio  float64 `json:"reward_ratio" binding:"min=0,max=1"`
-	MinReward    int     `json:"min_reward" binding:"min=1"`
-	MaxReward    int     `json:"max_reward" binding:"min=1"`
+	ID           string        `json:"id" binding:"required,alphanum"`
+	Name         string        `json:"name" binding:"required"`
+	Type         string        `json:"type" binding:"required"`
+	TotalPlayers int           `json:"total_players" binding:"min=1"`
+	RewardRatio  float64       `json:"reward_ratio" binding:"min=0,max=1"`
+	MinReward    int           `json:"min_reward" binding:"min=1"`
+	MaxReward    int           `json:"max_reward" binding:"min=1"`
+	RewardTiers  []*RewardTier `json:"reward_tiers" binding:"omitempty,dive"`
+}
+
+// CloneLeaderboardRequest 定义了复制排行榜时所需的请求体结构，源排行榜由路径参数指定。
+type CloneLeaderboardRequest struct {
+	ID          string `json:"id" binding:"required,alphanum"`
+	Name        string `json:"name"`         // 可选，为空时沿用源排行榜的名称
+	CopyPlayers bool   `json:"copy_players"` // 为 true 时同时复制玩家数据，如以上赛季成绩作为新赛季的定级分
 }
 
 // BatchUpdateScoreRequest 定义了批量更新分数时所需的请求体结构。
@@ -21,8 +29,21 @@ type BatchUpdateScoreRequest struct {
 
 // ScoreUpdate 定义了单个分数更新的数据结构。
 type ScoreUpdate struct {
-	PlayerID int64 `json:"player_id" binding:"required"`
-	Score    int64 `json:"score" binding:"required"`
+	PlayerID int64             `json:"player_id" binding:"required"`
+	Score    int64             `json:"score" binding:"required"`
+	Metadata map[string]string `json:"metadata,omitempty"` // 可选，合并到玩家的附加信息，值为空时删除对应键
+}
+
+// FriendsRankRequest 定义了查询一组玩家（如好友）之间相对排名时所需的请求体结构。
+type FriendsRankRequest struct {
+	LeaderboardID string  `json:"leaderboard_id" binding:"required"`
+	PlayerIDs     []int64 `json:"player_ids" binding:"required,min=1,max=1000"`
+}
+
+// MaintenanceRequest 定义了切换维护模式时所需的请求体结构。
+type MaintenanceRequest struct {
+	Enabled *bool  `json:"enabled" binding:"required"`
+	Reason  string `json:"reason"` // 可选，说明维护原因，如快照恢复、数据迁移、赛季结算
 }
 
 // QueryLeaderboardRequest 定义了查询排行榜时的请求参数结构。
@@ -36,4 +57,23 @@ type QueryLeaderboardRequest struct {
 	Keywords      string    `json:"keywords" form:"keywords"`
 	FromTime      time.Time `json:"from_time" form:"from_time"`
 	ToTime        time.Time `json:"to_time" form:"to_time"`
-}
\ No newline at end of file
+}
+
+// NormalizePageSize 规范化分页大小：不大于 0 时取 DefaultPageSize，超过 MaxPageSize 时截断为 MaxPageSize。
+func NormalizePageSize(size int) int {
+	if size <= 0 {
+		return DefaultPageSize
+	}
+	if size > MaxPageSize {
+		return MaxPageSize
+	}
+	return size
+}
+
+// HistoryQueryRequest 定义了查询排行榜历史排名时的请求参数结构。
+type HistoryQueryRequest struct {
+	LeaderboardID string `json:"leaderboard_id"`
+	Period        string `json:"period" form:"period"`
+	PlayerID      int64  `json:"player_id" form:"player_id"`
+	PageSize      int    `json:"page_size" form:"page_size"`
+}
@@ -7,21 +7,65 @@ import (
 
 // LeaderboardResponse 定义了查询排行榜信息时的响应结构。
 type LeaderboardResponse struct {
-	ID          string          `json:"id"`
-	Name        string          `json:"name"`
-	Type        string          `json:"type"`
-	PlayerCount int             `json:"player_count"`
-	TopScore    int64           `json:"top_score"`
-	Players     []*domain.Player `json:"players,omitempty"`
-	CreatedAt   time.Time       `json:"created_at"`
-	UpdatedAt   time.Time       `json:"updated_at"`
+	ID          string               `json:"id"`
+	Name        string               `json:"name"`
+	Type        string               `json:"type"`
+	PlayerCount int                  `json:"player_count"`
+	TopScore    int64                `json:"top_score"`
+	Players     []*domain.PlayerJSON `json:"players,omitempty"`
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
 }
 
 // PlayerRankResponse 定义了查询玩家排名时的响应结构。
 type PlayerRankResponse struct {
-	*domain.Player
+	*domain.PlayerJSON
 	TotalPlayers int     `json:"total_players"`
-	Percentile   float64 `json:"percentile"` // 百分比排名
+	Percentile   float64 `json:"percentile"` // 百分比排名，即 rank / total_players，0.005 表示前 0.5%
+}
+
+// FriendsRankResponse 定义了查询好友排名时的响应结构。
+// Players 按好友间的相对排名排序；不在榜上的玩家列在 NotFound 中。
+type FriendsRankResponse struct {
+	LeaderboardID string        `json:"leaderboard_id"`
+	Players       []*FriendRank `json:"players"`
+	NotFound      []int64       `json:"not_found"`
+}
+
+// FriendRank 定义了好友排名中的单个玩家，Rank 为全榜排名，FriendRank 为好友间的排名。
+type FriendRank struct {
+	*domain.PlayerJSON
+	FriendRank int `json:"friend_rank"`
+}
+
+// HistoryResponse 定义了查询排行榜历史排名时的响应结构。
+// 未指定周期时只返回 Periods；指定玩家时只返回该玩家的历史排名。
+type HistoryResponse struct {
+	LeaderboardID string               `json:"leaderboard_id"`
+	Periods       []string             `json:"periods,omitempty"`
+	Period        string               `json:"period,omitempty"`
+	StartTime     *time.Time           `json:"start_time,omitempty"`
+	EndTime       *time.Time           `json:"end_time,omitempty"`
+	TotalPlayers  int                  `json:"total_players"`
+	Players       []*domain.PlayerJSON `json:"players,omitempty"`
+	Player        *domain.PlayerJSON   `json:"player,omitempty"`
+}
+
+// RewardPreviewResponse 定义了查询玩家按当前排名可获得奖励时的响应结构。
+type RewardPreviewResponse struct {
+	LeaderboardID string `json:"leaderboard_id"`
+	PlayerID      int64  `json:"player_id"`
+	Rank          int    `json:"rank"`
+	TotalPlayers  int    `json:"total_players"`
+	Eligible      bool   `json:"eligible"`
+	Reward        string `json:"reward,omitempty"`
+}
+
+// MaintenanceResponse 定义了查询维护模式状态时的响应结构。
+type MaintenanceResponse struct {
+	Enabled bool       `json:"enabled"`
+	Reason  string     `json:"reason,omitempty"`
+	Since   *time.Time `json:"since,omitempty"` // 进入维护模式的时间
 }
 
 // LeaderboardStatsResponse 定义了查询排行榜统计信息时的响应结构。
@@ -38,7 +82,7 @@ type LeaderboardStatsResponse struct {
 type BatchResult struct {
 	Total   int           `json:"total"`
 	Success int           `json:"success"`
-	Failed  int           `json:y`
+	Failed  int           `json:"failed"`
 	Errors  []*BatchError `json:"errors,omitempty"`
 }
 
@@ -46,4 +90,4 @@ type BatchResult struct {
 type BatchError struct {
 	PlayerID int64  `json:"player_id"`
 	Error    string `json:"error"`
-}
\ No newline at end of file
+}
@@ -0,0 +1,61 @@
+package types
+
+import (
+	"encoding/json"
+	"rank-system/domain"
+	"testing"
+	"time"
+)
+
+// 响应中的玩家沿用原有的字段名 ID、Score、Rank、UpdateTime、Metadata
+func TestPlayerWireFormat(t *testing.T) {
+	p := domain.NewPlayer(7, 100)
+	p.Rank = 3
+	p.MergeMetadata(map[string]string{"nickname": "p7"})
+
+	data, err := json.Marshal(&PlayerRankResponse{PlayerJSON: domain.NewPlayerJSON(p), TotalPlayers: 10, Percentile: 0.3})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	for _, key := range []string{"ID", "Score", "Rank", "UpdateTime", "Metadata", "total_players", "percentile"} {
+		if _, ok := fields[key]; !ok {
+			t.Fatalf("missing field %q in %s", key, data)
+		}
+	}
+	for _, key := range []string{"id", "score", "rank", "update_time", "metadata"} {
+		if _, ok := fields[key]; ok {
+			t.Fatalf("unexpected field %q in %s", key, data)
+		}
+	}
+}
+
+// 归档文件中的玩家同样沿用原有的字段名，已有的归档文件可以直接读取
+func TestArchiveWireFormat(t *testing.T) {
+	end := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	old := `{"LeaderboardID":"daily","Period":"2024-01-01","StartTime":"2024-01-01T00:00:00Z","EndTime":"2024-01-02T00:00:00Z",` +
+		`"Players":[{"ID":1,"Score":500,"Rank":1,"UpdateTime":"2024-01-01T12:00:00Z","Metadata":{"nickname":"p1"}}]}`
+
+	var archive domain.Archive
+	if err := json.Unmarshal([]byte(old), &archive); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !archive.EndTime.Equal(end) || len(archive.Players) != 1 {
+		t.Fatalf("archive: end=%v players=%d", archive.EndTime, len(archive.Players))
+	}
+	p := archive.Players[0]
+	if p.ID != 1 || p.Score != 500 || p.Rank != 1 || p.UpdateTime.IsZero() || p.Metadata["nickname"] != "p1" {
+		t.Fatalf("player: %+v", p)
+	}
+
+	data, err := json.Marshal(&archive)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if string(data) != old {
+		t.Fatalf("round trip:\ngot  %s\nwant %s", data, old)
+	}
+}
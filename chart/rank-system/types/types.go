@@ -1,13 +1,26 @@
 package types
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 // Response 是一个通用的API响应结构，用于统一返回格式。
 type Response struct {
-	Code    int         `json:"code"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data"`
-	TraceID string      `json:"trace_id,omitempty"` // 用于分布式追踪
+	Code    int           `json:"code"`
+	Message string        `json:"message"`
+	Data    interface{}   `json:"data"`
+	Errors  []*FieldError `json:"errors,omitempty"`   // 参数校验失败时的字段级错误
+	TraceID string        `json:"trace_id,omitempty"` // 用于分布式追踪
+}
+
+// FieldError 描述单个请求字段未通过校验的原因。
+type FieldError struct {
+	Field   string `json:"field"`           // 字段路径，使用 json 名称，如 updates[0].score
+	Rule    string `json:"rule"`            // 未通过的规则，如 required、min、max
+	Param   string `json:"param,omitempty"` // 规则参数，如 min=1 中的 1
+	Message string `json:"message"`         // 可读的错误说明
 }
 
 // PageRequest 定义了分页请求的基础结构，用于API中的分页查询。
@@ -57,10 +70,59 @@ func (t LeaderboardType) String() string {
 	}
 }
 
+// ErrUnknownLeaderboardType 表示无法识别的排行榜类型。
+var ErrUnknownLeaderboardType = errors.New("unknown leaderboard type")
+
+// ParseLeaderboardType 将字符串解析为 LeaderboardType。
+func ParseLeaderboardType(s string) (LeaderboardType, error) {
+	switch s {
+	case "daily":
+		return LeaderboardTypeDaily, nil
+	case "weekly":
+		return LeaderboardTypeWeekly, nil
+	case "monthly":
+		return LeaderboardTypeMonthly, nil
+	case "season":
+		return LeaderboardTypeSeason, nil
+	default:
+		return 0, fmt.Errorf("%w: %q", ErrUnknownLeaderboardType, s)
+	}
+}
+
+// CronSpec 返回该周期结束时触发轮转的 crontab 参数（分、时、日、月、星期）。
+// 赛季没有固定周期，ok 为 false，需要手动结算。
+func (t LeaderboardType) CronSpec() (minute, hour, day, month, dayofweek int, ok bool) {
+	switch t {
+	case LeaderboardTypeDaily:
+		return 0, 0, -1, -1, -1, true
+	case LeaderboardTypeWeekly:
+		return 0, 0, -1, -1, int(time.Monday), true
+	case LeaderboardTypeMonthly:
+		return 0, 0, 1, -1, -1, true
+	default:
+		return 0, 0, 0, 0, 0, false
+	}
+}
+
+// PeriodKey 返回从 start 开始的周期的标识，用于命名归档。
+func (t LeaderboardType) PeriodKey(start time.Time) string {
+	switch t {
+	case LeaderboardTypeDaily:
+		return start.Format("2006-01-02")
+	case LeaderboardTypeWeekly:
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case LeaderboardTypeMonthly:
+		return start.Format("2006-01")
+	default:
+		return start.Format("20060102-150405")
+	}
+}
+
 // RewardTier 定义了排行榜中的奖励等级，根据排名范围给予不同奖励。
 type RewardTier struct {
 	MinRank int    `json:"min_rank"`
-	MaxRank int    `json:tMaxRank"`
+	MaxRank int    `json:"max_rank"`
 	Reward  string `json:"reward"`
 }
 
@@ -0,0 +1,181 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultAckTimeout 确认订阅等待确认的默认超时
+	DefaultAckTimeout = 30 * time.Second
+	// DefaultMaxDeliver 确认订阅默认的最多投递次数（含首次投递）
+	DefaultMaxDeliver = 5
+)
+
+// AckHandler 确认订阅者的回调函数类型，处理完成后需调用 msg.Ack()
+type AckHandler[T any] func(msg *Msg[T])
+
+// AckOptions 确认订阅选项
+type AckOptions[T any] struct {
+	AsyncOptions
+	AckTimeout time.Duration // 投递后等待确认的时间，超时视为 Nak；小于等于 0 时使用 DefaultAckTimeout
+	MaxDeliver int           // 最多投递次数（含首次投递），小于等于 0 时使用 DefaultMaxDeliver
+	// OnDeadLetter 最后一次投递仍被 Nak 或超时时调用，可为 nil；
+	// 在调用 Nak 的协程或超时定时器的协程中执行
+	OnDeadLetter func(msg *Msg[T])
+}
+
+// Msg 确认订阅者收到的一条消息
+type Msg[T any] struct {
+	ID      string // 消息ID，重新投递时不变
+	Subject string
+	Content T
+	Headers map[string]string
+	Attempt int // 第几次投递，从 1 开始
+
+	orig     delivery[T] // 重新投递时使用原始消息，不受中间件修改的影响
+	settled  atomic.Bool
+	timer    *time.Timer // 订阅者已停止时为 nil
+	consumer *ackConsumer[T]
+}
+
+// Ack 确认消息已处理，不再重新投递；只有第一次 Ack/Nak 或超时生效
+func (m *Msg[T]) Ack() {
+	if !m.settled.CompareAndSwap(false, true) {
+		return
+	}
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	m.consumer.forget(m)
+}
+
+// Nak 拒绝消息，未达到最多投递次数时立即重新投递；只有第一次 Ack/Nak 或超时生效
+func (m *Msg[T]) Nak() {
+	if !m.settled.CompareAndSwap(false, true) {
+		return
+	}
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	m.consumer.retry(m)
+}
+
+// expire 确认超时，与 Nak 相同
+func (m *Msg[T]) expire() {
+	if m.settled.CompareAndSwap(false, true) {
+		m.consumer.retry(m)
+	}
+}
+
+// ackConsumer 确认订阅者：记录等待确认的消息，Nak 或超时后重新放回该订阅的队列
+type ackConsumer[T any] struct {
+	handler      AckHandler[T]
+	timeout      time.Duration
+	maxDeliver   int
+	onDeadLetter func(msg *Msg[T])
+	worker       *asyncWorker[T]
+
+	mu      sync.Mutex
+	pending map[*Msg[T]]struct{}
+	closed  bool
+}
+
+func newAckConsumer[T any](handler AckHandler[T], opts AckOptions[T]) *ackConsumer[T] {
+	c := &ackConsumer[T]{
+		handler:      handler,
+		timeout:      opts.AckTimeout,
+		maxDeliver:   opts.MaxDeliver,
+		onDeadLetter: opts.OnDeadLetter,
+		pending:      map[*Msg[T]]struct{}{},
+	}
+	if c.timeout <= 0 {
+		c.timeout = DefaultAckTimeout
+	}
+	if c.maxDeliver <= 0 {
+		c.maxDeliver = DefaultMaxDeliver
+	}
+	c.worker = newAsyncWorker(c.dispatch, opts.AsyncOptions)
+	c.worker.onStop = c.close
+	return c
+}
+
+// dispatch 在处理协程中投递一条消息，并开始等待确认
+func (c *ackConsumer[T]) dispatch(d delivery[T]) {
+	msg := &Msg[T]{
+		ID:       d.msg.ID,
+		Subject:  d.msg.Subject,
+		Content:  d.msg.Payload,
+		Headers:  d.msg.Headers,
+		Attempt:  d.attempt,
+		orig:     d,
+		consumer: c,
+	}
+	c.mu.Lock()
+	if !c.closed {
+		msg.timer = time.AfterFunc(c.timeout, msg.expire)
+		c.pending[msg] = struct{}{}
+	}
+	c.mu.Unlock()
+	c.handler(msg)
+}
+
+// forget 消息已确认，不再等待
+func (c *ackConsumer[T]) forget(msg *Msg[T]) {
+	c.mu.Lock()
+	delete(c.pending, msg)
+	c.mu.Unlock()
+}
+
+// retry 重新投递未确认的消息，超过最多投递次数时转入死信；订阅者已停止时直接丢弃
+func (c *ackConsumer[T]) retry(msg *Msg[T]) {
+	c.mu.Lock()
+	delete(c.pending, msg)
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		return
+	}
+
+	if msg.orig.attempt >= c.maxDeliver {
+		c.worker.deadLettered.Add(1)
+		if c.onDeadLetter != nil {
+			c.onDeadLetter(msg)
+		}
+		return
+	}
+	c.worker.redelivered.Add(1)
+	// Nak 可能在处理协程中调用，队列已满时同步入队会死锁，因此在新协程中等待空位；
+	// 重新投递的消息排在队尾，不保证与其他消息的相对顺序
+	d := msg.orig
+	d.attempt++
+	go c.worker.enqueue(context.Background(), d, true)
+}
+
+// close 订阅者停止时取消所有等待中的确认，之后不再重新投递
+func (c *ackConsumer[T]) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	for msg := range c.pending {
+		msg.timer.Stop()
+	}
+	c.pending = nil
+}
+
+// SubscribeAck 以确认模式订阅主题，提供至少一次投递：消息经该订阅的有界队列异步投递，
+// handler 处理完成后需调用 msg.Ack()；调用 msg.Nak() 或超过 AckTimeout 未确认的消息会重新投递，
+// 直到达到 MaxDeliver 次后交给 OnDeadLetter。handler 可能收到重复消息，需保证幂等。
+// 订阅被替换、取消订阅或 Close 后不再重新投递，等待中的确认被取消。
+func (ps *GenericPubSub[T]) SubscribeAck(subscriberID string, subject string, handler AckHandler[T], opts AckOptions[T]) error {
+	if handler == nil {
+		return errNilHandler
+	}
+	handler = ps.ackWithMiddlewares(handler)
+	return ps.subscribe(subscriberID, subject, nil, func() *asyncWorker[T] {
+		return newAckConsumer(handler, opts).worker
+	})
+}
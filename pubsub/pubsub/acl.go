@@ -0,0 +1,111 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+)
+
+// ErrNotAllowed 订阅或发布的主题不在 ACL 允许的范围内
+var ErrNotAllowed = errors.New("not allowed by ACL")
+
+// ACL 订阅者或发布者允许使用的主题模式，用于多个租户共用一个服务
+type ACL struct {
+	Subscribe []string // 允许订阅的模式，订阅的模式需被其中之一覆盖（如 "tenant1.>" 允许订阅 "tenant1.*.score"）
+	Publish   []string // 允许发布的主题模式
+}
+
+// compiledACL 拆分后的 ACL
+type compiledACL struct {
+	subscribe [][]string
+	publish   [][]string
+}
+
+type publisherKey struct{}
+
+// WithPublisher 在 ctx 中记录发布者，以该 ctx 调用 PublishCtx、PublishMsg 等时按发布者的 ACL 校验主题
+func WithPublisher(ctx context.Context, publisherID string) context.Context {
+	return context.WithValue(ctx, publisherKey{}, publisherID)
+}
+
+// SetACL 为订阅者或发布者（principal）设置 ACL，替换此前的设置；只有设置了 ACL 的 principal 受限制，
+// 多租户时应为每个租户的订阅者与发布者都设置 ACL。已有的订阅不受影响。
+// 订阅者以 subscriberID 识别；发布者以 WithPublisher 记录在 ctx 中的 ID 识别，Publish 等不带发布者的发布不受限制。
+func (ps *GenericPubSub[T]) SetACL(principal string, acl ACL) error {
+	compiled := &compiledACL{}
+	for _, pattern := range acl.Subscribe {
+		tokens, err := splitPattern(pattern)
+		if err != nil {
+			return err
+		}
+		compiled.subscribe = append(compiled.subscribe, tokens)
+	}
+	for _, pattern := range acl.Publish {
+		tokens, err := splitPattern(pattern)
+		if err != nil {
+			return err
+		}
+		compiled.publish = append(compiled.publish, tokens)
+	}
+	ps.updateACLs(func(acls map[string]*compiledACL) { acls[principal] = compiled })
+	return nil
+}
+
+// RemoveACL 移除 principal 的 ACL，之后不再受限制
+func (ps *GenericPubSub[T]) RemoveACL(principal string) {
+	ps.updateACLs(func(acls map[string]*compiledACL) { delete(acls, principal) })
+}
+
+// updateACLs 复制 ACL 表修改后整体替换，发布时无锁读取
+func (ps *GenericPubSub[T]) updateACLs(update func(acls map[string]*compiledACL)) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	acls := map[string]*compiledACL{}
+	if cur := ps.acls.Load(); cur != nil {
+		acls = maps.Clone(*cur)
+	}
+	update(acls)
+	ps.acls.Store(&acls)
+}
+
+// authorizeSubscribe 校验订阅者能否订阅模式
+func (ps *GenericPubSub[T]) authorizeSubscribe(subscriberID string, pattern string, tokens []string) error {
+	acls := ps.acls.Load()
+	if acls == nil {
+		return nil
+	}
+	acl, ok := (*acls)[subscriberID]
+	if !ok {
+		return nil
+	}
+	for _, allowed := range acl.subscribe {
+		if patternCovers(allowed, tokens) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s cannot subscribe to %s", ErrNotAllowed, subscriberID, pattern)
+}
+
+// authorizePublish 校验 ctx 中的发布者能否发布到主题
+func (ps *GenericPubSub[T]) authorizePublish(ctx context.Context, subject string, tokens []string) error {
+	acls := ps.acls.Load()
+	if acls == nil {
+		return nil
+	}
+	publisherID, ok := ctx.Value(publisherKey{}).(string)
+	if !ok {
+		return nil
+	}
+	acl, ok := (*acls)[publisherID]
+	if !ok {
+		return nil
+	}
+	for _, allowed := range acl.publish {
+		if matchPattern(allowed, tokens) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s cannot publish to %s", ErrNotAllowed, publisherID, subject)
+}
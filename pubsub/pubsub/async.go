@@ -0,0 +1,361 @@
+package pubsub
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultQueueSize 异步订阅者队列的默认容量
+const DefaultQueueSize = 1024
+
+// OverflowPolicy 异步订阅者队列已满时的处理方式
+type OverflowPolicy int
+
+const (
+	// OverflowDropNewest 丢弃新发布的消息并计入 Dropped（默认）
+	OverflowDropNewest OverflowPolicy = iota
+	// OverflowBlock 阻塞发布者直到有空位
+	OverflowBlock
+	// OverflowDropOldest 丢弃队列中最早的消息并计入 Dropped，为新消息腾出空位
+	OverflowDropOldest
+	// OverflowCallback 丢弃新发布的消息并计入 Dropped，再调用 AsyncOptions.OnOverflow
+	OverflowCallback
+)
+
+// AsyncOptions 异步投递选项
+type AsyncOptions struct {
+	QueueSize     int            // 每个处理协程的队列容量，小于等于 0 时使用 DefaultQueueSize
+	Overflow      OverflowPolicy // 队列满时的处理方式，默认丢弃新消息
+	BlockWhenFull bool           // 为 true 时等同于 Overflow 为 OverflowBlock
+	// OnOverflow Overflow 为 OverflowCallback 时，以被丢弃消息的主题与内容（类型为订阅的消息类型）调用；
+	// 在发布者协程中执行，不能阻塞
+	OnOverflow func(subject string, content any)
+	// Workers 处理协程数，小于等于 0 时为 1。消息按主题哈希到固定的处理协程，
+	// 同一主题的消息按发布顺序处理，不同主题的消息可以并发处理；大于 1 时 handler 需要并发安全
+	Workers int
+	// MaxConcurrency 同一订阅者所有设置了该项的异步订阅同时执行的 handler 数上限，小于等于 0 时不限制。
+	// 这些订阅共享一个信号量，容量以最先设置的订阅为准；处理协程等待空位时不计入处理耗时。
+	// 与 Workers 配合使用：昂贵的 handler 可以并行处理，又不会占满 CPU 或下游连接
+	MaxConcurrency int
+}
+
+// DeliveryStats 异步订阅者的投递指标
+type DeliveryStats struct {
+	Queued   int   // 队列中等待处理的消息数
+	Capacity int   // 队列总容量
+	Dropped  int64 // 因队列已满被丢弃的消息数
+	// 以下仅对确认订阅（SubscribeAck）与重试订阅（SubscribeE）有效
+	Redelivered  int64 // 因 Nak、确认超时或处理失败重新投递的次数
+	DeadLettered int64 // 达到最多投递次数仍未确认或处理失败的消息数
+}
+
+// delivery 待投递的一条消息
+type delivery[T any] struct {
+	msg     *Message[T]
+	attempt int    // 第几次投递，从 1 开始
+	reply   string // 回复主题，只有发给响应者的请求才有
+}
+
+// asyncWorker 异步订阅的有界队列与处理协程：每个处理协程一个队列，按入队顺序逐条处理，
+// 消息按主题哈希到队列，保证同一主题的顺序
+type asyncWorker[T any] struct {
+	queues   []chan delivery[T]
+	dropped  atomic.Int64
+	stopped  chan struct{} // 关闭后不再接收新消息，处理协程处理完队列中剩余的消息后退出
+	stopOnce sync.Once
+	onStop   func()                             // 可为 nil，停止时调用一次
+	onDrain  func()                             // 可为 nil，所有处理协程处理完剩余消息、done 关闭之前调用
+	done     chan struct{}                      // 所有处理协程退出时关闭
+	onPanic  func(d delivery[T], recovered any) // 可为 nil，处理消息 panic 时调用
+	counters *atomic.Pointer[statsSet]          // 可为 nil，记录各主题与订阅者的丢弃数与处理耗时
+	tracing  *atomic.Pointer[tracing]           // 可为 nil，开启链路追踪时为每条消息的处理创建 span
+
+	overflow   OverflowPolicy                    // 队列满时的处理方式
+	onOverflow func(subject string, content any) // 可为 nil，见 AsyncOptions.OnOverflow
+
+	// 供慢订阅者检测使用：订阅者与模式在登记订阅时设置，busySince 为各处理协程正在处理的消息的开始时间
+	// （UnixNano，空闲时为 0），handled 与 handlerNanos 为已处理的消息数与总耗时
+	subscriberID string
+	pattern      string
+	busySince    []atomic.Int64
+	handled      atomic.Int64
+	handlerNanos atomic.Int64
+
+	responder bool // 为 true 时 Request 发来的消息携带回复主题
+
+	maxConcurrency int                 // 见 AsyncOptions.MaxConcurrency
+	limiter        *concurrencyLimiter // 可为 nil，登记订阅时按 maxConcurrency 设置，停止时归还
+
+	// redelivered、deadLettered 由确认订阅者与重试订阅者更新
+	redelivered  atomic.Int64
+	deadLettered atomic.Int64
+}
+
+// newAsyncWorker 创建处理协程，process 在处理协程中逐条调用
+func newAsyncWorker[T any](process func(d delivery[T]), opts AsyncOptions) *asyncWorker[T] {
+	size := opts.QueueSize
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	w := &asyncWorker[T]{
+		queues:     make([]chan delivery[T], workers),
+		busySince:  make([]atomic.Int64, workers),
+		overflow:   opts.Overflow,
+		onOverflow: opts.OnOverflow,
+		stopped:    make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	w.maxConcurrency = opts.MaxConcurrency
+	if opts.BlockWhenFull {
+		w.overflow = OverflowBlock
+	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := range w.queues {
+		w.queues[i] = make(chan delivery[T], size)
+		go func(queue chan delivery[T], busy *atomic.Int64) {
+			defer wg.Done()
+			w.run(queue, busy, process)
+		}(w.queues[i], &w.busySince[i])
+	}
+	go func() {
+		wg.Wait()
+		if w.onDrain != nil {
+			w.onDrain()
+		}
+		close(w.done)
+	}()
+	return w
+}
+
+// queueFor 返回主题对应的队列，同一主题总是同一个队列
+func (w *asyncWorker[T]) queueFor(subject string) chan delivery[T] {
+	if len(w.queues) == 1 {
+		return w.queues[0]
+	}
+	h := fnv.New32a()
+	h.Write([]byte(subject))
+	return w.queues[h.Sum32()%uint32(len(w.queues))]
+}
+
+// deliver 将新发布的消息放入队列，已停止时直接丢弃；ctx 只用于取消阻塞的入队
+func (w *asyncWorker[T]) deliver(ctx context.Context, msg *Message[T]) {
+	w.deliverWithMode(ctx, delivery[T]{msg: msg, attempt: 1})
+}
+
+// deliverWithReply 返回将请求连同回复主题放入队列的 handler
+func (w *asyncWorker[T]) deliverWithReply(reply string) MsgHandler[T] {
+	return func(ctx context.Context, msg *Message[T]) {
+		w.deliverWithMode(ctx, delivery[T]{msg: msg, attempt: 1, reply: reply})
+	}
+}
+
+// deliverWithMode 按 ctx 中的发布方式入队：TryPublish 时不阻塞，没能入队时记入发布方式
+func (w *asyncWorker[T]) deliverWithMode(ctx context.Context, d delivery[T]) {
+	mode := publishModeFrom(ctx)
+	block := w.overflow == OverflowBlock && (mode == nil || !mode.noBlock)
+	result := w.enqueue(ctx, d, block)
+	if result == enqueueRejected && mode != nil && (block || mode.noBlock) {
+		mode.failed.Add(1)
+	}
+	if counts := deliveryCountsFor(ctx, d.msg); counts != nil {
+		switch result {
+		case enqueueOK:
+			counts.enqueued.Add(1)
+		case enqueueRejected:
+			counts.dropped.Add(1)
+		}
+	}
+}
+
+// enqueueResult enqueue 的结果
+type enqueueResult int
+
+const (
+	enqueueOK       enqueueResult = iota // 已放入队列
+	enqueueRejected                      // 因队列已满或 ctx 取消没能入队
+	enqueueStopped                       // 订阅者已停止（如已取消订阅），消息被丢弃
+)
+
+// enqueue 将消息放入队列：block 为 true 时等待空位或 ctx 取消，否则队列满时按 overflow 丢弃新消息或最早的消息；
+// 已停止时直接丢弃并返回 enqueueStopped
+func (w *asyncWorker[T]) enqueue(ctx context.Context, d delivery[T], block bool) enqueueResult {
+	select {
+	case <-w.stopped:
+		return enqueueStopped
+	default:
+	}
+
+	queue := w.queueFor(d.msg.Subject)
+	if block {
+		select {
+		case queue <- d:
+			return enqueueOK
+		case <-w.stopped:
+			return enqueueStopped
+		case <-ctx.Done():
+			return enqueueRejected
+		}
+	}
+	select {
+	case queue <- d:
+		return enqueueOK
+	default:
+	}
+	if w.overflow == OverflowDropOldest {
+		// 其他发布者可能同时腾出或占用空位，直到入队为止
+		for {
+			select {
+			case old := <-queue:
+				w.drop(old)
+			default:
+			}
+			select {
+			case queue <- d:
+				return enqueueOK
+			default:
+			}
+		}
+	}
+	w.drop(d)
+	if w.overflow == OverflowCallback && w.onOverflow != nil {
+		w.onOverflow(d.msg.Subject, d.msg.Payload)
+	}
+	return enqueueRejected
+}
+
+// drop 记录一条因队列已满被丢弃的消息
+func (w *asyncWorker[T]) drop(d delivery[T]) {
+	w.dropped.Add(1)
+	if w.counters != nil {
+		w.counters.Load().dropped(w.subscriberID, d.msg.Subject)
+	}
+}
+
+// full 主题对应的队列是否已满
+func (w *asyncWorker[T]) full(subject string) bool {
+	queue := w.queueFor(subject)
+	return len(queue) == cap(queue)
+}
+
+// run 逐条处理队列中的消息，停止后处理完剩余消息再退出
+func (w *asyncWorker[T]) run(queue chan delivery[T], busy *atomic.Int64, process func(d delivery[T])) {
+	for {
+		select {
+		case d := <-queue:
+			w.process(process, d, busy)
+		case <-w.stopped:
+			for {
+				select {
+				case d := <-queue:
+					w.process(process, d, busy)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// process 处理一条消息并记录耗时，panic 时交给 onPanic，处理协程继续运行；有并发上限时先等待空位
+func (w *asyncWorker[T]) process(process func(d delivery[T]), d delivery[T], busy *atomic.Int64) {
+	if w.limiter != nil {
+		w.limiter.acquire()
+		defer w.limiter.release()
+	}
+	var span trace.Span
+	if w.tracing != nil {
+		if t := w.tracing.Load(); t != nil {
+			_, span = startProcessSpan(t, context.Background(), w.subscriberID, d.msg)
+		}
+	}
+	start := time.Now()
+	busy.Store(start.UnixNano())
+	defer func() {
+		busy.Store(0)
+		r := recover()
+		if r != nil && w.onPanic != nil {
+			w.onPanic(d, r)
+		}
+		endSpan(span, panicError(r))
+		elapsed := time.Since(start)
+		w.handled.Add(1)
+		w.handlerNanos.Add(int64(elapsed))
+		if w.counters != nil {
+			w.counters.Load().handled(w.subscriberID, d.msg.Subject, elapsed)
+		}
+	}()
+	process(d)
+}
+
+// stop 停止接收新消息，不等待处理协程退出；重复调用是安全的
+func (w *asyncWorker[T]) stop() {
+	w.stopOnce.Do(func() {
+		close(w.stopped)
+		if w.onStop != nil {
+			w.onStop()
+		}
+		if w.limiter != nil {
+			w.limiter.detach()
+		}
+	})
+}
+
+// stats 返回当前的投递指标
+func (w *asyncWorker[T]) stats() DeliveryStats {
+	stats := DeliveryStats{
+		Dropped:      w.dropped.Load(),
+		Redelivered:  w.redelivered.Load(),
+		DeadLettered: w.deadLettered.Load(),
+	}
+	for _, queue := range w.queues {
+		stats.Queued += len(queue)
+		stats.Capacity += cap(queue)
+	}
+	return stats
+}
+
+// SubscribeAsync 以异步方式订阅主题：该订阅拥有独立的有界队列与处理协程，
+// Publish 只负责入队，慢的 handler 不会阻塞发布者或其他订阅；同一订阅中同一主题的消息按发布顺序处理，
+// opts.Workers 大于 1 时不同主题的消息并发处理。
+// 重复订阅同一模式时替换此前的订阅（同步或异步），旧队列中已有的消息仍由旧 handler 处理完。
+func (ps *GenericPubSub[T]) SubscribeAsync(subscriberID string, subject string, handler Handler[T], opts AsyncOptions) error {
+	if handler == nil {
+		return errNilHandler
+	}
+	handler = ps.withMiddlewares(handler)
+	return ps.subscribe(subscriberID, subject, nil, func() *asyncWorker[T] {
+		return newAsyncWorker(func(d delivery[T]) { handler(d.msg.Subject, d.msg.Payload) }, opts)
+	})
+}
+
+// DeliveryStats 返回订阅者所有异步订阅的投递指标之和，订阅者不存在或没有异步订阅时 ok 为 false
+func (ps *GenericPubSub[T]) DeliveryStats(subscriberID string) (stats DeliveryStats, ok bool) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	workers := ps.subs.Load().workers
+	for subject := range ps.subscriberSubjects[subscriberID] {
+		w, found := workers[subscriptionKey(subscriberID, subject)]
+		if !found {
+			continue
+		}
+		s := w.stats()
+		stats.Queued += s.Queued
+		stats.Capacity += s.Capacity
+		stats.Dropped += s.Dropped
+		stats.Redelivered += s.Redelivered
+		stats.DeadLettered += s.DeadLettered
+		ok = true
+	}
+	return stats, ok
+}
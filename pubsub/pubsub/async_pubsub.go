@@ -0,0 +1,168 @@
+package pubsub
+
+import (
+	"errors"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrDrainTimeout Shutdown 没能在 DrainTimeout 内投递完队列中的消息
+var ErrDrainTimeout = errors.New("drain timed out")
+
+// AsyncPubSubOptions 异步发布服务的选项
+type AsyncPubSubOptions struct {
+	Workers   int // 发布协程数，小于等于 0 时为 1
+	QueueSize int // 每个发布协程的队列容量，小于等于 0 时使用 DefaultQueueSize
+	// Affinity 为 true 时消息按主题哈希到固定的发布协程，同一主题的消息按 PublishAsync 的调用顺序投递；
+	// 为 false 时所有发布协程共用一个队列，任一空闲的协程发布下一条消息，不保证顺序
+	Affinity bool
+	// DrainTimeout Shutdown 等待队列中的消息投递完的最长时间，小于等于 0 时一直等待；
+	// 超时后关闭服务，剩余的消息以 ErrClosed 失败
+	DrainTimeout time.Duration
+}
+
+// AsyncStats 异步发布服务的计数：服务的 Stats 以及发布队列的状态
+type AsyncStats struct {
+	Stats
+	Queued   int // 发布队列中等待发布的消息数
+	Capacity int // 发布队列总容量
+	InFlight int // 发布协程正在发布的消息数
+}
+
+// asyncPublish 等待发布的一条消息
+type asyncPublish[T any] struct {
+	subject string
+	content T
+	result  chan error
+}
+
+// AsyncPubSub 异步发布服务：PublishAsync 只把消息放入发布队列，由发布协程调用 Publish，发布者不等待同步 handler 执行。
+// 订阅、统计等其余方法与 GenericPubSub 相同。
+type AsyncPubSub[T any] struct {
+	*GenericPubSub[T]
+	opts AsyncPubSubOptions
+
+	mu       sync.RWMutex // 保护 closed 与关闭队列，PublishAsync 持有读锁入队
+	closed   bool
+	queues   []chan asyncPublish[T] // Affinity 时每个发布协程一个队列，否则只有一个共用的队列
+	inFlight atomic.Int64
+	done     chan struct{} // 所有发布协程退出时关闭
+}
+
+// NewAsyncPubSub 创建有 workers 个发布协程的异步发布服务，其余选项使用默认值
+func NewAsyncPubSub[T any](workers int) *AsyncPubSub[T] {
+	return NewAsyncPubSubWithOptions[T](AsyncPubSubOptions{Workers: workers})
+}
+
+// NewAsyncPubSubWithOptions 按选项创建异步发布服务
+func NewAsyncPubSubWithOptions[T any](opts AsyncPubSubOptions) *AsyncPubSub[T] {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultQueueSize
+	}
+	ps := &AsyncPubSub[T]{
+		GenericPubSub: NewGenericPubSub[T](),
+		opts:          opts,
+		done:          make(chan struct{}),
+	}
+	if opts.Affinity {
+		ps.queues = make([]chan asyncPublish[T], opts.Workers)
+		for i := range ps.queues {
+			ps.queues[i] = make(chan asyncPublish[T], opts.QueueSize)
+		}
+	} else {
+		ps.queues = []chan asyncPublish[T]{make(chan asyncPublish[T], opts.QueueSize*opts.Workers)}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(opts.Workers)
+	for i := 0; i < opts.Workers; i++ {
+		queue := ps.queues[i%len(ps.queues)]
+		go func() {
+			defer wg.Done()
+			for p := range queue {
+				ps.inFlight.Add(1)
+				p.result <- ps.Publish(p.subject, p.content)
+				ps.inFlight.Add(-1)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(ps.done)
+	}()
+	return ps
+}
+
+// PublishAsync 将消息放入发布队列后立即返回，返回的 channel 在发布完成后收到 Publish 的结果；
+// 队列已满时收到 ErrQueueFull，Shutdown 之后收到 ErrClosed。
+func (ps *AsyncPubSub[T]) PublishAsync(subject string, content T) <-chan error {
+	result := make(chan error, 1)
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	if ps.closed {
+		result <- ErrClosed
+		return result
+	}
+	select {
+	case ps.queueFor(subject) <- asyncPublish[T]{subject: subject, content: content, result: result}:
+	default:
+		result <- ErrQueueFull
+	}
+	return result
+}
+
+// queueFor 返回主题对应的发布队列
+func (ps *AsyncPubSub[T]) queueFor(subject string) chan asyncPublish[T] {
+	if len(ps.queues) == 1 {
+		return ps.queues[0]
+	}
+	h := fnv.New32a()
+	h.Write([]byte(subject))
+	return ps.queues[h.Sum32()%uint32(len(ps.queues))]
+}
+
+// Stats 返回服务的计数以及发布队列的深度与正在发布的消息数
+func (ps *AsyncPubSub[T]) Stats() AsyncStats {
+	stats := AsyncStats{Stats: ps.GenericPubSub.Stats(), InFlight: int(ps.inFlight.Load())}
+	for _, queue := range ps.queues {
+		stats.Queued += len(queue)
+		stats.Capacity += cap(queue)
+	}
+	return stats
+}
+
+// Shutdown 优雅关闭：停止接受 PublishAsync，等待队列中的消息发布完（最多 DrainTimeout）后关闭服务（见 Close），
+// 返回时所有结果都已送达。超时时返回 ErrDrainTimeout，剩余的消息以 ErrClosed 失败，Close 仍会等待正在发布的消息；
+// 重复调用是安全的。应使用 Shutdown 而不是 Close 关闭，否则发布协程不会退出。
+func (ps *AsyncPubSub[T]) Shutdown() error {
+	ps.mu.Lock()
+	if !ps.closed {
+		ps.closed = true
+		for _, queue := range ps.queues {
+			close(queue)
+		}
+	}
+	ps.mu.Unlock()
+
+	var err error
+	if ps.opts.DrainTimeout > 0 {
+		timer := time.NewTimer(ps.opts.DrainTimeout)
+		defer timer.Stop()
+		select {
+		case <-ps.done:
+		case <-timer.C:
+			err = ErrDrainTimeout
+		}
+	} else {
+		<-ps.done
+	}
+	ps.Close()
+	<-ps.done
+	return err
+}
@@ -0,0 +1,123 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBatchSize 批量订阅默认的每批最多消息数
+	DefaultBatchSize = 100
+	// DefaultBatchWait 批量订阅默认的最长攒批时间
+	DefaultBatchWait = 100 * time.Millisecond
+)
+
+// BatchHandler 批量订阅者的回调函数类型，batch 按到达顺序排列，至少有一条消息
+type BatchHandler[T any] func(batch []Message[T])
+
+// BatchOptions 批量订阅选项
+type BatchOptions struct {
+	AsyncOptions
+	MaxSize int           // 每批最多消息数，攒满时立即交给 handler；小于等于 0 时使用 DefaultBatchSize
+	MaxWait time.Duration // 一批中第一条消息到达后最多等待的时间，小于等于 0 时使用 DefaultBatchWait
+}
+
+// batcher 攒批并按数量或时间交给 handler，handler 不会并发调用
+type batcher[T any] struct {
+	handler BatchHandler[T]
+	maxSize int
+	maxWait time.Duration
+	onPanic func(recovered any)
+
+	mu     sync.Mutex // 调用 handler 时同样持有，保证批次之间的顺序
+	buf    []Message[T]
+	timer  *time.Timer
+	seq    uint64 // 当前定时器的序号，过期的定时器不再刷新
+	closed bool
+}
+
+// add 加入一条消息，攒满时立即刷新，否则在第一条消息到达时开始计时
+func (b *batcher[T]) add(msg *Message[T]) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.buf = append(b.buf, *msg)
+	if len(b.buf) >= b.maxSize {
+		b.flushLocked()
+		return
+	}
+	if b.timer == nil {
+		seq := b.seq
+		b.timer = time.AfterFunc(b.maxWait, func() { b.flushTimer(seq) })
+	}
+}
+
+// flushTimer 定时器到期时刷新，定时器已被取消或替换时忽略
+func (b *batcher[T]) flushTimer(seq uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if seq == b.seq {
+		b.flushLocked()
+	}
+}
+
+// flushLocked 将已攒的消息交给 handler，调用方需持有 mu
+func (b *batcher[T]) flushLocked() {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.seq++
+	if len(b.buf) == 0 {
+		return
+	}
+	batch := b.buf
+	b.buf = nil
+	defer func() {
+		if r := recover(); r != nil {
+			b.onPanic(r)
+		}
+	}()
+	b.handler(batch)
+}
+
+// close 刷新剩余的消息，之后不再接收
+func (b *batcher[T]) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.flushLocked()
+	b.closed = true
+}
+
+// SubscribeBatch 以批量模式订阅主题：消息经该订阅的有界队列异步接收并攒批，攒满 MaxSize 条或第一条消息到达后
+// 经过 MaxWait 时把整批交给 handler，适合分数更新这类高频主题。中间件对每条消息分别执行。
+// 取消订阅、订阅被替换或 Close 时，队列中剩余的消息与未满的一批会在处理协程退出前交给 handler。
+func (ps *GenericPubSub[T]) SubscribeBatch(subscriberID string, subject string, handler BatchHandler[T], opts BatchOptions) error {
+	if handler == nil {
+		return errNilHandler
+	}
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = DefaultBatchSize
+	}
+	if opts.MaxWait <= 0 {
+		opts.MaxWait = DefaultBatchWait
+	}
+	return ps.subscribe(subscriberID, subject, nil, func() *asyncWorker[T] {
+		b := &batcher[T]{
+			handler: handler,
+			maxSize: opts.MaxSize,
+			maxWait: opts.MaxWait,
+			onPanic: func(recovered any) { ps.handlerPanicked(subscriberID, subject, recovered) },
+		}
+		add := ps.msgWithMiddlewares(func(_ context.Context, msg *Message[T]) { b.add(msg) })
+		w := newAsyncWorker(func(d delivery[T]) { add(context.Background(), d.msg) }, opts.AsyncOptions)
+		w.onDrain = b.close
+		return w
+	})
+}
@@ -0,0 +1,187 @@
+// 外部消息中间件桥接
+//
+// Bridge 将进程内匹配 Outbound 模式的消息转发到外部中间件，并将外部中间件上匹配 Inbound 模式的消息
+// 发布到进程内，订阅者代码不需要改动。消息以 JSON 信封传输，携带来源节点，避免同一条消息在节点之间来回转发；
+// 消息内容按 Codec 编码，默认为 JSON。
+// 外部中间件通过 Broker 接口接入，本包提供 NATS（NewNATSBroker）与 Redis（NewRedisBroker）的实现，
+// Kafka 等其他中间件可以用各自的客户端库实现 Broker 后接入。
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BridgeOriginHeader 从外部中间件收到的消息携带的消息头，值为发出该消息的节点
+const BridgeOriginHeader = "bridge-origin"
+
+// Broker 外部消息中间件，主题使用与本包相同的 '.' 分段与通配符语法
+type Broker interface {
+	// Publish 发布一条消息
+	Publish(subject string, data []byte) error
+	// Subscribe 订阅模式，handler 在中间件的接收协程中调用；返回的函数取消订阅。
+	// 中间件的通配语义可以比模式更宽，Bridge 会按模式重新过滤
+	Subscribe(pattern string, handler func(subject string, data []byte)) (unsubscribe func() error, err error)
+}
+
+// BridgeOptions 桥接选项
+type BridgeOptions struct {
+	NodeID   string   // 本节点的唯一标识，不能为空
+	Outbound []string // 转发到外部中间件的主题模式
+	Inbound  []string // 从外部中间件接收的主题模式
+	// AsyncOptions 转发出站消息的异步订阅选项，外部中间件变慢时不会阻塞本地发布者
+	AsyncOptions
+	// OnError 编码、解码或发布失败时调用，为 nil 时记录日志；在出站或入站的协程中执行
+	OnError func(subject string, err error)
+	// Codec 消息内容的编码，为 nil 时使用 JSONCodec；所有节点需要使用相同的编码
+	Codec Codec
+}
+
+// bridgeEnvelope 在外部中间件上传输的消息
+type bridgeEnvelope struct {
+	ID      string            `json:"id"`
+	Subject string            `json:"subject"`
+	Time    time.Time         `json:"time"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Origin  string            `json:"origin"`
+	Payload json.RawMessage   `json:"payload,omitempty"`
+	Codec   string            `json:"codec,omitempty"` // 内容不是 JSON 编码时的编码名称，内容放在 Data 中
+	Data    []byte            `json:"data,omitempty"`
+}
+
+// Bridge 进程内发布订阅与外部中间件之间的桥接
+type Bridge[T any] struct {
+	ps           *GenericPubSub[T]
+	broker       Broker
+	opts         BridgeOptions
+	subscriberID string
+
+	mu           sync.Mutex
+	unsubscribes []func() error
+	closed       bool
+}
+
+// NewBridge 创建桥接并立即开始转发；出站消息经本地的异步订阅转发，订阅者ID为 "_BRIDGE.<NodeID>"。
+// 从外部中间件收到的消息带有 BridgeOriginHeader，不会再被转发出去。Close 不会关闭 broker。
+func NewBridge[T any](ps *GenericPubSub[T], broker Broker, opts BridgeOptions) (*Bridge[T], error) {
+	if opts.NodeID == "" {
+		return nil, errors.New("bridge NodeID cannot be empty")
+	}
+	for _, pattern := range append(append([]string{}, opts.Outbound...), opts.Inbound...) {
+		if _, err := splitPattern(pattern); err != nil {
+			return nil, fmt.Errorf("invalid bridge pattern: %w", err)
+		}
+	}
+	b := &Bridge[T]{ps: ps, broker: broker, opts: opts, subscriberID: "_BRIDGE." + opts.NodeID}
+
+	for _, pattern := range opts.Outbound {
+		err := ps.SubscribeMsg(b.subscriberID, pattern, b.export, CtxOptions{Async: true, AsyncOptions: opts.AsyncOptions})
+		if err != nil {
+			b.Close()
+			return nil, err
+		}
+	}
+	for _, pattern := range opts.Inbound {
+		tokens, _ := splitPattern(pattern)
+		unsubscribe, err := broker.Subscribe(pattern, func(subject string, data []byte) {
+			if matchPattern(tokens, strings.Split(subject, subjectSeparator)) {
+				b.importMsg(subject, data)
+			}
+		})
+		if err != nil {
+			b.Close()
+			return nil, fmt.Errorf("subscribe %q on broker: %w", pattern, err)
+		}
+		b.mu.Lock()
+		b.unsubscribes = append(b.unsubscribes, unsubscribe)
+		b.mu.Unlock()
+	}
+	return b, nil
+}
+
+// export 将本地消息转发到外部中间件，从外部收到的消息不再转发
+func (b *Bridge[T]) export(_ context.Context, msg *Message[T]) {
+	if msg.Header(BridgeOriginHeader) != "" {
+		return
+	}
+	payload, codec, encoded, err := payloadFields(b.opts.Codec, &msg.Payload)
+	if err != nil {
+		b.fail(msg.Subject, fmt.Errorf("encode payload: %w", err))
+		return
+	}
+	data, err := json.Marshal(bridgeEnvelope{
+		ID:      msg.ID,
+		Subject: msg.Subject,
+		Time:    msg.Time,
+		Headers: msg.Headers,
+		Origin:  b.opts.NodeID,
+		Payload: payload,
+		Codec:   codec,
+		Data:    encoded,
+	})
+	if err != nil {
+		b.fail(msg.Subject, fmt.Errorf("encode message: %w", err))
+		return
+	}
+	if err := b.broker.Publish(msg.Subject, data); err != nil {
+		b.fail(msg.Subject, fmt.Errorf("publish to broker: %w", err))
+	}
+}
+
+// importMsg 将外部中间件的消息发布到本地，本节点发出的消息直接忽略
+func (b *Bridge[T]) importMsg(subject string, data []byte) {
+	var env bridgeEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		b.fail(subject, fmt.Errorf("decode message: %w", err))
+		return
+	}
+	if env.Origin == b.opts.NodeID {
+		return
+	}
+	msg := &Message[T]{ID: env.ID, Subject: subject, Time: env.Time, Headers: env.Headers}
+	if err := decodePayload(b.opts.Codec, env.Payload, env.Codec, env.Data, &msg.Payload); err != nil {
+		b.fail(subject, fmt.Errorf("decode payload: %w", err))
+		return
+	}
+	msg.SetHeader(BridgeOriginHeader, env.Origin)
+	if err := b.ps.PublishMsg(context.Background(), msg); err != nil {
+		b.fail(subject, fmt.Errorf("publish locally: %w", err))
+	}
+}
+
+// fail 报告转发失败
+func (b *Bridge[T]) fail(subject string, err error) {
+	if b.opts.OnError != nil {
+		b.opts.OnError(subject, err)
+		return
+	}
+	log.Printf("pubsub: bridge %s failed on %s: %v", b.opts.NodeID, subject, err)
+}
+
+// Close 停止转发：取消本地的出站订阅（已入队的消息仍会转发）与外部中间件上的入站订阅，重复调用是安全的
+func (b *Bridge[T]) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	unsubscribes := b.unsubscribes
+	b.unsubscribes = nil
+	b.mu.Unlock()
+
+	b.ps.UnsubscribeAll(b.subscriberID)
+	var errs []error
+	for _, unsubscribe := range unsubscribes {
+		if err := unsubscribe(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
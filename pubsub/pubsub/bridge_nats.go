@@ -0,0 +1,199 @@
+package pubsub
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NATSBroker 基于 NATS 核心文本协议的 Broker，只实现发布与订阅；不支持认证、TLS 与断线重连，
+// 连接断开后 Publish 与 Subscribe 返回错误，需要重新创建 Broker 与 Bridge
+type NATSBroker struct {
+	conn net.Conn
+
+	wmu sync.Mutex // 保护 w
+	w   *bufio.Writer
+
+	mu      sync.Mutex
+	subs    map[int64]func(subject string, data []byte) // sid -> handler
+	nextSID int64
+	err     error // 连接断开的原因，为 nil 时连接可用
+
+	done chan struct{} // 接收协程退出时关闭
+}
+
+// NewNATSBroker 连接 NATS 服务器，addr 形如 "127.0.0.1:4222"
+func NewNATSBroker(addr string, timeout time.Duration) (*NATSBroker, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	b, err := newNATSBroker(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+// newNATSBroker 在已建立的连接上完成握手并启动接收协程
+func newNATSBroker(conn net.Conn) (*NATSBroker, error) {
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("read nats INFO: %w", err)
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return nil, fmt.Errorf("unexpected nats greeting %q", strings.TrimSpace(line))
+	}
+	b := &NATSBroker{
+		conn: conn,
+		w:    bufio.NewWriter(conn),
+		subs: map[int64]func(subject string, data []byte){},
+		done: make(chan struct{}),
+	}
+	if err := b.write(`CONNECT {"verbose":false,"pedantic":false,"name":"pubsub-bridge"}` + "\r\nPING\r\n"); err != nil {
+		return nil, err
+	}
+	go b.readLoop(r)
+	return b, nil
+}
+
+// Publish 发布一条消息
+func (b *NATSBroker) Publish(subject string, data []byte) error {
+	if err := b.connErr(); err != nil {
+		return err
+	}
+	b.wmu.Lock()
+	defer b.wmu.Unlock()
+
+	fmt.Fprintf(b.w, "PUB %s %d\r\n", subject, len(data))
+	b.w.Write(data)
+	b.w.WriteString("\r\n")
+	return b.w.Flush()
+}
+
+// Subscribe 订阅模式，NATS 的通配语义与本包相同
+func (b *NATSBroker) Subscribe(pattern string, handler func(subject string, data []byte)) (func() error, error) {
+	b.mu.Lock()
+	if b.err != nil {
+		b.mu.Unlock()
+		return nil, b.err
+	}
+	b.nextSID++
+	sid := b.nextSID
+	b.subs[sid] = handler
+	b.mu.Unlock()
+
+	if err := b.write(fmt.Sprintf("SUB %s %d\r\n", pattern, sid)); err != nil {
+		b.removeSub(sid)
+		return nil, err
+	}
+	return func() error {
+		if !b.removeSub(sid) {
+			return nil
+		}
+		if b.connErr() != nil {
+			return nil
+		}
+		return b.write(fmt.Sprintf("UNSUB %d\r\n", sid))
+	}, nil
+}
+
+// Close 关闭连接并等待接收协程退出，重复调用是安全的
+func (b *NATSBroker) Close() error {
+	err := b.conn.Close()
+	<-b.done
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// write 写入一条协议命令
+func (b *NATSBroker) write(cmd string) error {
+	b.wmu.Lock()
+	defer b.wmu.Unlock()
+
+	b.w.WriteString(cmd)
+	return b.w.Flush()
+}
+
+// removeSub 删除订阅，不存在时返回 false
+func (b *NATSBroker) removeSub(sid int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, ok := b.subs[sid]
+	delete(b.subs, sid)
+	return ok
+}
+
+// connErr 返回连接断开的原因
+func (b *NATSBroker) connErr() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.err
+}
+
+// readLoop 接收服务器发来的消息，连接断开时退出
+func (b *NATSBroker) readLoop(r *bufio.Reader) {
+	defer close(b.done)
+	err := b.read(r)
+	b.conn.Close()
+	if err == nil || errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+		err = errors.New("nats connection closed")
+	}
+	b.mu.Lock()
+	b.err = err
+	b.mu.Unlock()
+}
+
+// read 逐条处理协议命令：MSG 交给订阅的 handler，PING 回复 PONG，其余忽略
+func (b *NATSBroker) read(r *bufio.Reader) error {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case strings.HasPrefix(line, "MSG "):
+			// MSG <subject> <sid> [reply-to] <#bytes>
+			fields := strings.Fields(line[len("MSG "):])
+			if len(fields) < 3 || len(fields) > 4 {
+				return fmt.Errorf("malformed nats MSG %q", line)
+			}
+			sid, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return fmt.Errorf("malformed nats MSG %q", line)
+			}
+			size, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil || size < 0 {
+				return fmt.Errorf("malformed nats MSG %q", line)
+			}
+			data := make([]byte, size+2)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return err
+			}
+			b.mu.Lock()
+			handler := b.subs[sid]
+			b.mu.Unlock()
+			if handler != nil {
+				handler(fields[0], data[:size])
+			}
+		case line == "PING":
+			if err := b.write("PONG\r\n"); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats error: %s", strings.TrimSpace(line[len("-ERR"):]))
+		}
+	}
+}
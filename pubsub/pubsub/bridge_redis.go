@@ -0,0 +1,290 @@
+package pubsub
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RedisBroker 基于 Redis 发布订阅（PUBLISH/PSUBSCRIBE）的 Broker，使用两条连接分别发布与订阅；
+// 不支持 TLS、集群与断线重连，连接断开后需要重新创建 Broker 与 Bridge。
+// Redis 的 glob 模式中 '*' 可以跨越 '.'，订阅的范围比模式更宽，由 Bridge 重新过滤。
+type RedisBroker struct {
+	pubMu sync.Mutex // 保护 pub 与 pubR，一次一条命令
+	pub   net.Conn
+	pubR  *bufio.Reader
+
+	subMu sync.Mutex // 保护 sub 的写入，订阅与取消订阅在持有 subMu 时登记并写入命令，保证命令顺序与登记一致
+	sub   net.Conn
+
+	mu       sync.Mutex
+	patterns map[string]map[int64]func(subject string, data []byte) // glob 模式 -> 订阅ID -> handler
+	nextID   int64
+	err      error // 订阅连接断开的原因，为 nil 时连接可用
+
+	done chan struct{} // 接收协程退出时关闭
+}
+
+// redisError Redis 返回的错误回复
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// NewRedisBroker 连接 Redis，password 为空时不认证
+func NewRedisBroker(addr string, password string, timeout time.Duration) (*RedisBroker, error) {
+	dial := func() (net.Conn, error) {
+		conn, err := net.DialTimeout("tcp", addr, timeout)
+		if err != nil {
+			return nil, err
+		}
+		if password != "" {
+			r := bufio.NewReader(conn)
+			if err := writeRedisCommand(conn, "AUTH", password); err != nil {
+				conn.Close()
+				return nil, err
+			}
+			if _, err := readRedisReply(r); err != nil {
+				conn.Close()
+				return nil, err
+			}
+		}
+		return conn, nil
+	}
+	pub, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	sub, err := dial()
+	if err != nil {
+		pub.Close()
+		return nil, err
+	}
+	return newRedisBroker(pub, sub), nil
+}
+
+// newRedisBroker 在已建立的连接上启动接收协程
+func newRedisBroker(pub, sub net.Conn) *RedisBroker {
+	b := &RedisBroker{
+		pub:      pub,
+		pubR:     bufio.NewReader(pub),
+		sub:      sub,
+		patterns: map[string]map[int64]func(subject string, data []byte){},
+		done:     make(chan struct{}),
+	}
+	go b.readLoop(bufio.NewReader(sub))
+	return b
+}
+
+// Publish 发布一条消息
+func (b *RedisBroker) Publish(subject string, data []byte) error {
+	b.pubMu.Lock()
+	defer b.pubMu.Unlock()
+
+	if err := writeRedisCommand(b.pub, "PUBLISH", subject, string(data)); err != nil {
+		return err
+	}
+	_, err := readRedisReply(b.pubR)
+	return err
+}
+
+// Subscribe 以 PSUBSCRIBE 订阅模式，同一模式只向 Redis 订阅一次
+func (b *RedisBroker) Subscribe(pattern string, handler func(subject string, data []byte)) (func() error, error) {
+	glob := redisPattern(pattern)
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+	b.mu.Lock()
+	if b.err != nil {
+		b.mu.Unlock()
+		return nil, b.err
+	}
+	b.nextID++
+	id := b.nextID
+	handlers, ok := b.patterns[glob]
+	if !ok {
+		handlers = map[int64]func(subject string, data []byte){}
+		b.patterns[glob] = handlers
+	}
+	handlers[id] = handler
+	b.mu.Unlock()
+
+	if !ok {
+		if err := writeRedisCommand(b.sub, "PSUBSCRIBE", glob); err != nil {
+			b.mu.Lock()
+			delete(b.patterns, glob)
+			b.mu.Unlock()
+			return nil, err
+		}
+	}
+	return func() error { return b.unsubscribe(glob, id) }, nil
+}
+
+// unsubscribe 删除订阅，模式的最后一个订阅被删除时向 Redis 取消订阅
+func (b *RedisBroker) unsubscribe(glob string, id int64) error {
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+	b.mu.Lock()
+	handlers := b.patterns[glob]
+	if _, ok := handlers[id]; !ok {
+		b.mu.Unlock()
+		return nil
+	}
+	delete(handlers, id)
+	last := len(handlers) == 0
+	if last {
+		delete(b.patterns, glob)
+	}
+	closed := b.err != nil
+	b.mu.Unlock()
+
+	if !last || closed {
+		return nil
+	}
+	return writeRedisCommand(b.sub, "PUNSUBSCRIBE", glob)
+}
+
+// Close 关闭两条连接并等待接收协程退出，重复调用是安全的
+func (b *RedisBroker) Close() error {
+	err := errors.Join(b.pub.Close(), b.sub.Close())
+	<-b.done
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// readLoop 接收订阅连接上的消息，连接断开时退出
+func (b *RedisBroker) readLoop(r *bufio.Reader) {
+	defer close(b.done)
+	err := b.read(r)
+	b.sub.Close()
+	if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+		err = errors.New("redis connection closed")
+	}
+	b.mu.Lock()
+	b.err = err
+	b.mu.Unlock()
+}
+
+// read 将 pmessage 交给订阅该模式的 handler，订阅确认等其他回复忽略
+func (b *RedisBroker) read(r *bufio.Reader) error {
+	for {
+		reply, err := readRedisReply(r)
+		var redisErr redisError
+		if errors.As(err, &redisErr) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		// pmessage <pattern> <channel> <data>
+		items, ok := reply.([]any)
+		if !ok || len(items) != 4 {
+			continue
+		}
+		kind, _ := items[0].([]byte)
+		glob, _ := items[1].([]byte)
+		channel, _ := items[2].([]byte)
+		data, _ := items[3].([]byte)
+		if string(kind) != "pmessage" {
+			continue
+		}
+		b.mu.Lock()
+		handlers := make([]func(subject string, data []byte), 0, len(b.patterns[string(glob)]))
+		for _, handler := range b.patterns[string(glob)] {
+			handlers = append(handlers, handler)
+		}
+		b.mu.Unlock()
+		for _, handler := range handlers {
+			handler(string(channel), data)
+		}
+	}
+}
+
+// redisGlobEscaper 转义 Redis glob 模式中的特殊字符
+var redisGlobEscaper = strings.NewReplacer(`\`, `\\`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+// redisPattern 将订阅模式转为 Redis glob 模式，'*' 与 '>' 都转为 '*'
+func redisPattern(pattern string) string {
+	tokens := strings.Split(pattern, subjectSeparator)
+	for i, token := range tokens {
+		if token == wildcardToken || token == tailToken {
+			tokens[i] = "*"
+			continue
+		}
+		tokens[i] = redisGlobEscaper.Replace(token)
+	}
+	return strings.Join(tokens, subjectSeparator)
+}
+
+// writeRedisCommand 以 RESP 数组写入一条命令
+func writeRedisCommand(w io.Writer, args ...string) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&sb, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// readRedisReply 读取一条 RESP 回复：简单字符串为 string，整数为 int64，批量字符串为 []byte（空值为 nil），
+// 数组为 []any；错误回复返回 redisError
+func readRedisReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("malformed redis reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("malformed redis reply %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("malformed redis reply %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			item, err := readRedisReply(r)
+			var redisErr redisError
+			if errors.As(err, &redisErr) {
+				// 数组中的错误回复作为元素返回，保证读完整个数组
+				item = redisErr
+			} else if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("malformed redis reply %q", line)
+}
@@ -0,0 +1,216 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Bus 可以承载多种消息类型的发布订阅服务：每个主题模式以 RegisterTopic 登记自己的消息类型，
+// 通过返回的 Topic[T] 发布与订阅；所有主题共用同一个前缀树、订阅者与指标。
+// 类型不同的主题模式不能重叠，保证同一个主题上只有一种消息类型。
+type Bus struct {
+	ps *GenericPubSub[any]
+
+	mu     sync.Mutex
+	topics []busTopic
+}
+
+// busTopic 登记的主题模式及其消息类型
+type busTopic struct {
+	pattern string
+	tokens  []string
+	typ     reflect.Type
+}
+
+// NewBus 创建一个新的多类型发布订阅服务
+func NewBus() *Bus {
+	return &Bus{ps: NewGenericPubSub[any]()}
+}
+
+// Topic 总线上某个主题模式的类型化句柄，只能发布与订阅该模式范围内的主题
+type Topic[T any] struct {
+	bus     *Bus
+	pattern string
+	tokens  []string
+}
+
+// RegisterTopic 以消息类型 T 登记主题模式，如 RegisterTopic[ScoreEvent](bus, "score.>")；
+// 与已登记的其他类型的模式重叠时返回错误，重复登记同一类型时返回新的句柄
+func RegisterTopic[T any](bus *Bus, pattern string) (*Topic[T], error) {
+	tokens, err := splitPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	typ := reflect.TypeFor[T]()
+
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	for _, topic := range bus.topics {
+		if topic.typ != typ && patternsOverlap(topic.tokens, tokens) {
+			return nil, fmt.Errorf("topic %q (%s) overlaps registered topic %q (%s)", pattern, typ, topic.pattern, topic.typ)
+		}
+	}
+	bus.topics = append(bus.topics, busTopic{pattern: pattern, tokens: tokens, typ: typ})
+	return &Topic[T]{bus: bus, pattern: pattern, tokens: tokens}, nil
+}
+
+// Pattern 返回主题模式
+func (t *Topic[T]) Pattern() string {
+	return t.pattern
+}
+
+// Publish 发布消息，主题需匹配该主题模式
+func (t *Topic[T]) Publish(subject string, content T) error {
+	return t.PublishCtx(context.Background(), subject, content)
+}
+
+// PublishCtx 与 Publish 相同，见 GenericPubSub.PublishCtx
+func (t *Topic[T]) PublishCtx(ctx context.Context, subject string, content T) error {
+	if err := t.check(subject); err != nil {
+		return err
+	}
+	return t.bus.ps.PublishCtx(ctx, subject, content)
+}
+
+// TryPublish 不阻塞地发布，见 GenericPubSub.TryPublish
+func (t *Topic[T]) TryPublish(subject string, content T) error {
+	if err := t.check(subject); err != nil {
+		return err
+	}
+	return t.bus.ps.TryPublish(subject, content)
+}
+
+// Subscribe 订阅主题，模式需与该主题模式重叠；只收到落在该主题模式范围内的消息
+func (t *Topic[T]) Subscribe(subscriberID string, subject string, handler Handler[T]) error {
+	if handler == nil {
+		return errNilHandler
+	}
+	if err := t.checkPattern(subject); err != nil {
+		return err
+	}
+	return t.bus.ps.Subscribe(subscriberID, subject, t.wrap(handler))
+}
+
+// SubscribeAsync 异步订阅主题，见 GenericPubSub.SubscribeAsync
+func (t *Topic[T]) SubscribeAsync(subscriberID string, subject string, handler Handler[T], opts AsyncOptions) error {
+	if handler == nil {
+		return errNilHandler
+	}
+	if err := t.checkPattern(subject); err != nil {
+		return err
+	}
+	return t.bus.ps.SubscribeAsync(subscriberID, subject, t.wrap(handler), opts)
+}
+
+// Unsubscribe 取消订阅，subject 需与订阅时的模式一致
+func (t *Topic[T]) Unsubscribe(subscriberID string, subject string) {
+	t.bus.ps.Unsubscribe(subscriberID, subject)
+}
+
+// check 校验发布的主题是否在该主题模式范围内
+func (t *Topic[T]) check(subject string) error {
+	tokens, err := splitSubject(subject)
+	if err != nil {
+		return err
+	}
+	if !matchPattern(t.tokens, tokens) {
+		return fmt.Errorf("subject %q does not match topic %q", subject, t.pattern)
+	}
+	return nil
+}
+
+// checkPattern 校验订阅模式是否与该主题模式重叠
+func (t *Topic[T]) checkPattern(pattern string) error {
+	tokens, err := splitPattern(pattern)
+	if err != nil {
+		return err
+	}
+	if !patternsOverlap(t.tokens, tokens) {
+		return fmt.Errorf("pattern %q does not overlap topic %q", pattern, t.pattern)
+	}
+	return nil
+}
+
+// wrap 将类型化的 handler 转为总线的 handler，订阅模式比主题模式宽时跳过范围外的主题
+func (t *Topic[T]) wrap(handler Handler[T]) Handler[any] {
+	return func(subject string, content any) {
+		if !matchPattern(t.tokens, strings.Split(subject, subjectSeparator)) {
+			return
+		}
+		v, ok := content.(T)
+		if !ok && content != nil {
+			return
+		}
+		handler(subject, v)
+	}
+}
+
+// UnsubscribeAll 取消该订阅者在所有主题上的订阅
+func (b *Bus) UnsubscribeAll(subscriberID string) {
+	b.ps.UnsubscribeAll(subscriberID)
+}
+
+// ListSubjects 见 GenericPubSub.ListSubjects
+func (b *Bus) ListSubjects() []string {
+	return b.ps.ListSubjects()
+}
+
+// SubscribersOf 见 GenericPubSub.SubscribersOf
+func (b *Bus) SubscribersOf(subject string) []string {
+	return b.ps.SubscribersOf(subject)
+}
+
+// Stats 返回所有主题的全局计数与各主题的指标
+func (b *Bus) Stats() Stats {
+	return b.ps.Stats()
+}
+
+// TopicStats 见 GenericPubSub.TopicStats
+func (b *Bus) TopicStats(subject string) (TopicStats, bool) {
+	return b.ps.TopicStats(subject)
+}
+
+// UnsubscribePattern 取消该订阅者在所有主题上被 pattern 覆盖的订阅，见 GenericPubSub.UnsubscribePattern
+func (b *Bus) UnsubscribePattern(subscriberID string, pattern string) (int, error) {
+	return b.ps.UnsubscribePattern(subscriberID, pattern)
+}
+
+// SetACL 见 GenericPubSub.SetACL，ACL 作用于所有主题
+func (b *Bus) SetACL(principal string, acl ACL) error {
+	return b.ps.SetACL(principal, acl)
+}
+
+// RemoveACL 见 GenericPubSub.RemoveACL
+func (b *Bus) RemoveACL(principal string) {
+	b.ps.RemoveACL(principal)
+}
+
+// OnHandlerError 见 GenericPubSub.OnHandlerError
+func (b *Bus) OnHandlerError(hook HandlerErrorHook) {
+	b.ps.OnHandlerError(hook)
+}
+
+// EnableTracing 见 GenericPubSub.EnableTracing
+func (b *Bus) EnableTracing(opts TracingOptions) {
+	b.ps.EnableTracing(opts)
+}
+
+// PrometheusHandler 见 GenericPubSub.PrometheusHandler
+func (b *Bus) PrometheusHandler(namespace string) http.Handler {
+	return b.ps.PrometheusHandler(namespace)
+}
+
+// Drain 见 GenericPubSub.Drain
+func (b *Bus) Drain(ctx context.Context) error {
+	return b.ps.Drain(ctx)
+}
+
+// Close 见 GenericPubSub.Close
+func (b *Bus) Close() {
+	b.ps.Close()
+}
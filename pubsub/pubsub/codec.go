@@ -0,0 +1,247 @@
+// 消息内容编解码
+//
+// 跨进程传输或写入磁盘的消息内容通过 Codec 编码，本包提供 JSON、gob 与 protobuf 三种实现，
+// 其他格式实现 Codec 接口后即可用于 Bridge、Gateway、消息日志与 EncodedPubSub。
+// 在 JSON 信封（桥接消息、网关帧、日志记录）中，JSON 编码的内容直接内嵌，其他编码的内容以 base64 存放并带上编码名称。
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"sync/atomic"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// CodecHeader EncodedPubSub 发布的消息携带的消息头，值为内容的编码名称
+const CodecHeader = "codec"
+
+// Codec 消息内容的编解码器，需要可以并发调用
+type Codec interface {
+	// Name 编码名称，如 "json"，随编码后的内容一起传输，用于接收方校验
+	Name() string
+	// Marshal 编码 v
+	Marshal(v any) ([]byte, error)
+	// Unmarshal 将 data 解码到 v，v 为指针
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	// JSONCodec 使用 encoding/json 编码，nil Codec 等同于 JSONCodec
+	JSONCodec Codec = jsonCodec{}
+	// GobCodec 使用 encoding/gob 编码，每条消息单独编码，接口类型的字段需要先 gob.Register
+	GobCodec Codec = gobCodec{}
+	// ProtoCodec 使用 protobuf 编码，内容类型需要实现 proto.Message（如生成代码中的 *pb.Xxx）
+	ProtoCodec Codec = protoCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return "json" }
+
+func (jsonCodec) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+type gobCodec struct{}
+
+func (gobCodec) Name() string { return "gob" }
+
+func (gobCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+type protoCodec struct{}
+
+func (protoCodec) Name() string { return "proto" }
+
+// Marshal v 为 proto.Message，或指向 proto.Message 的指针（如 *T，T 为 *pb.Xxx）
+func (protoCodec) Marshal(v any) ([]byte, error) {
+	m, ok := protoMessage(v, false)
+	if !ok {
+		return nil, fmt.Errorf("proto codec: %T does not implement proto.Message", v)
+	}
+	return proto.Marshal(m)
+}
+
+// Unmarshal v 为 proto.Message，或指向 proto.Message 的指针，后者为 nil 时创建新的消息
+func (protoCodec) Unmarshal(data []byte, v any) error {
+	m, ok := protoMessage(v, true)
+	if !ok {
+		return fmt.Errorf("proto codec: %T does not implement proto.Message", v)
+	}
+	return proto.Unmarshal(data, m)
+}
+
+var protoMessageType = reflect.TypeOf((*proto.Message)(nil)).Elem()
+
+// protoMessage 取出 v 对应的 proto.Message；v 为 **pb.Xxx 时解引用，alloc 为 true 时为 nil 的消息分配内存
+func protoMessage(v any, alloc bool) (proto.Message, bool) {
+	if m, ok := v.(proto.Message); ok {
+		return m, true
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return nil, false
+	}
+	elem := rv.Elem()
+	if elem.Kind() != reflect.Pointer || !elem.Type().Implements(protoMessageType) {
+		return nil, false
+	}
+	if elem.IsNil() && alloc {
+		elem.Set(reflect.New(elem.Type().Elem()))
+	}
+	return elem.Interface().(proto.Message), true
+}
+
+// payloadFields 编码 v 并放入 JSON 信封：JSON 编码时放入 raw，其他编码时放入 data 并返回编码名称
+func payloadFields(codec Codec, v any) (raw json.RawMessage, name string, data []byte, err error) {
+	if codec == nil || codec.Name() == JSONCodec.Name() {
+		raw, err = json.Marshal(v)
+		return raw, "", nil, err
+	}
+	data, err = codec.Marshal(v)
+	return nil, codec.Name(), data, err
+}
+
+// decodePayload 将 JSON 信封中的内容解码到 v；name 为空表示内容是内嵌的 JSON，否则需与 codec 的名称一致
+func decodePayload(codec Codec, raw json.RawMessage, name string, data []byte, v any) error {
+	if name == "" {
+		return json.Unmarshal(raw, v)
+	}
+	if codec == nil {
+		codec = JSONCodec
+	}
+	if name != codec.Name() {
+		return fmt.Errorf("payload encoded with %q, expected %q", name, codec.Name())
+	}
+	return codec.Unmarshal(data, v)
+}
+
+// DecodeErrorHook EncodedPubSub 解码失败时的回调
+type DecodeErrorHook func(subscriberID string, subject string, err error)
+
+// EncodedPubSub 内容为编码后字节的发布订阅服务之上的类型化视图：发布时将 T 编码，投递给订阅者前解码。
+// 底层的 GenericPubSub[[]byte] 可以直接接入 Bridge、Gateway 与消息日志，不同内容类型的模块也可以共用同一个服务。
+type EncodedPubSub[T any] struct {
+	ps          *GenericPubSub[[]byte]
+	codec       Codec
+	decodeError atomic.Pointer[DecodeErrorHook]
+}
+
+// NewEncodedPubSub 基于 ps 创建使用 codec 编码的发布订阅服务，codec 为 nil 时使用 JSONCodec
+func NewEncodedPubSub[T any](ps *GenericPubSub[[]byte], codec Codec) *EncodedPubSub[T] {
+	if codec == nil {
+		codec = JSONCodec
+	}
+	return &EncodedPubSub[T]{ps: ps, codec: codec}
+}
+
+// PubSub 返回底层的发布订阅服务
+func (e *EncodedPubSub[T]) PubSub() *GenericPubSub[[]byte] {
+	return e.ps
+}
+
+// Codec 返回使用的编解码器
+func (e *EncodedPubSub[T]) Codec() Codec {
+	return e.codec
+}
+
+// OnDecodeError 设置解码失败时的回调，为 nil 时恢复默认行为（记录日志）；解码失败的消息不会交给 handler，
+// 计入该订阅者与主题的 HandlerErrors
+func (e *EncodedPubSub[T]) OnDecodeError(hook DecodeErrorHook) {
+	if hook == nil {
+		e.decodeError.Store(nil)
+		return
+	}
+	e.decodeError.Store(&hook)
+}
+
+// Publish 编码 content 后发布，编码失败时不发布并返回错误
+func (e *EncodedPubSub[T]) Publish(subject string, content T) error {
+	return e.PublishMsg(context.Background(), NewMessage(subject, content))
+}
+
+// PublishCtx 编码 content 后发布，其余行为与 GenericPubSub.PublishCtx 相同
+func (e *EncodedPubSub[T]) PublishCtx(ctx context.Context, subject string, content T) error {
+	return e.PublishMsg(ctx, NewMessage(subject, content))
+}
+
+// PublishMsg 编码消息内容后发布，消息头带上 CodecHeader；msg 的 ID 与发布时间在发布后被填入
+func (e *EncodedPubSub[T]) PublishMsg(ctx context.Context, msg *Message[T]) error {
+	data, err := e.codec.Marshal(&msg.Payload)
+	if err != nil {
+		return fmt.Errorf("encode payload: %w", err)
+	}
+	encoded := &Message[[]byte]{ID: msg.ID, Subject: msg.Subject, Payload: data, Time: msg.Time}
+	for k, v := range msg.Headers {
+		encoded.SetHeader(k, v)
+	}
+	encoded.SetHeader(CodecHeader, e.codec.Name())
+	err = e.ps.PublishMsg(ctx, encoded)
+	msg.ID, msg.Time = encoded.ID, encoded.Time
+	return err
+}
+
+// Subscribe 订阅主题，handler 收到解码后的内容
+func (e *EncodedPubSub[T]) Subscribe(subscriberID string, subject string, handler Handler[T]) error {
+	if handler == nil {
+		return errNilHandler
+	}
+	return e.SubscribeMsg(subscriberID, subject, fromHandler(handler), CtxOptions{})
+}
+
+// SubscribeMsg 以接收完整消息信封的 handler 订阅主题，opts 与 GenericPubSub.SubscribeMsg 相同
+func (e *EncodedPubSub[T]) SubscribeMsg(subscriberID string, subject string, handler MsgHandler[T], opts CtxOptions) error {
+	if handler == nil {
+		return errNilHandler
+	}
+	return e.ps.SubscribeMsg(subscriberID, subject, func(ctx context.Context, msg *Message[[]byte]) {
+		decoded, err := e.decode(msg)
+		if err != nil {
+			e.ps.stats.Load().handlerError(subscriberID, msg.Subject)
+			if hook := e.decodeError.Load(); hook != nil {
+				(*hook)(subscriberID, msg.Subject, err)
+				return
+			}
+			log.Printf("pubsub: failed to decode %s for %s: %v", msg.Subject, subscriberID, err)
+			return
+		}
+		handler(ctx, decoded)
+	}, opts)
+}
+
+// Unsubscribe 取消订阅
+func (e *EncodedPubSub[T]) Unsubscribe(subscriberID string, subject string) {
+	e.ps.Unsubscribe(subscriberID, subject)
+}
+
+// UnsubscribeAll 取消订阅者的所有订阅
+func (e *EncodedPubSub[T]) UnsubscribeAll(subscriberID string) {
+	e.ps.UnsubscribeAll(subscriberID)
+}
+
+// decode 解码消息内容，消息头中的编码名称与 codec 不一致时返回错误；没有 CodecHeader 的消息按 codec 解码
+func (e *EncodedPubSub[T]) decode(msg *Message[[]byte]) (*Message[T], error) {
+	if name := msg.Header(CodecHeader); name != "" && name != e.codec.Name() {
+		return nil, fmt.Errorf("payload encoded with %q, expected %q", name, e.codec.Name())
+	}
+	decoded := &Message[T]{ID: msg.ID, Subject: msg.Subject, Headers: msg.Headers, Time: msg.Time}
+	if err := e.codec.Unmarshal(msg.Payload, &decoded.Payload); err != nil {
+		return nil, fmt.Errorf("decode payload: %w", err)
+	}
+	return decoded, nil
+}
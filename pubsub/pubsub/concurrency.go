@@ -0,0 +1,56 @@
+package pubsub
+
+import "sync"
+
+// concurrencyLimiter 订阅者所有设置了 MaxConcurrency 的异步订阅共享的信号量
+type concurrencyLimiter struct {
+	sem        chan struct{} // 容量为并发上限，处理消息时占用一个位置
+	refs       int           // 共享该信号量的订阅数，由 limiterRegistry.mu 保护
+	registry   *limiterRegistry
+	subscriber string
+}
+
+// acquire 占用一个位置，已达上限时阻塞
+func (l *concurrencyLimiter) acquire() {
+	l.sem <- struct{}{}
+}
+
+// release 归还 acquire 占用的位置
+func (l *concurrencyLimiter) release() {
+	<-l.sem
+}
+
+// detach 订阅停止时调用，最后一个订阅停止后从登记表中删除
+func (l *concurrencyLimiter) detach() {
+	r := l.registry
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l.refs--
+	if l.refs == 0 && r.limiters[l.subscriber] == l {
+		delete(r.limiters, l.subscriber)
+	}
+}
+
+// limiterRegistry 订阅者 -> 并发上限；异步订阅可能在持有写锁时停止，使用独立的锁
+type limiterRegistry struct {
+	mu       sync.Mutex
+	limiters map[string]*concurrencyLimiter
+}
+
+// attach 返回订阅者的信号量，不存在时以 limit 为容量创建
+func (r *limiterRegistry) attach(subscriberID string, limit int) *concurrencyLimiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.limiters == nil {
+		r.limiters = map[string]*concurrencyLimiter{}
+	}
+	l, ok := r.limiters[subscriberID]
+	if !ok {
+		l = &concurrencyLimiter{sem: make(chan struct{}, limit), registry: r, subscriber: subscriberID}
+		r.limiters[subscriberID] = l
+	}
+	l.refs++
+	return l
+}
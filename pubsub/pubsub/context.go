@@ -0,0 +1,57 @@
+package pubsub
+
+import (
+	"context"
+	"time"
+)
+
+// HandlerCtx 上下文感知的订阅者回调函数类型，长时间运行的 handler 应在 ctx 取消时尽快返回
+type HandlerCtx[T any] func(ctx context.Context, subject string, content T)
+
+// CtxOptions 上下文感知订阅的选项
+type CtxOptions struct {
+	Timeout time.Duration // 每次调用 handler 的超时，超时后取消 ctx；小于等于 0 时不限制
+	// Async 为 true 时经该订阅的有界队列异步处理（见 SubscribeAsync），ctx 不随发布方取消，
+	// 只在取消订阅、订阅被替换或 Close 时取消
+	Async bool
+	AsyncOptions
+}
+
+// SubscribeCtx 以上下文感知的 handler 订阅主题：同步订阅时 ctx 来自 PublishCtx（Publish 为 Background），
+// 并在 Close 时取消；opts.Timeout 限制每次调用的时长。取消只是通知，handler 需自行检查 ctx 并返回。
+func (ps *GenericPubSub[T]) SubscribeCtx(subscriberID string, subject string, handler HandlerCtx[T], opts CtxOptions) error {
+	if handler == nil {
+		return errNilHandler
+	}
+	return ps.subscribeWithOptions(subscriberID, subject, func(ctx context.Context, msg *Message[T]) {
+		handler(ctx, msg.Subject, msg.Payload)
+	}, opts)
+}
+
+// subscribeWithOptions 按 CtxOptions 订阅：为每次调用派生 ctx，并按需创建异步处理协程
+func (ps *GenericPubSub[T]) subscribeWithOptions(subscriberID string, subject string, handler MsgHandler[T], opts CtxOptions) error {
+	handler = ps.msgWithMiddlewares(handler)
+	call := func(ctx context.Context, msg *Message[T]) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		stop := context.AfterFunc(ps.ctx, cancel)
+		defer stop()
+		if opts.Timeout > 0 {
+			var cancelTimeout context.CancelFunc
+			ctx, cancelTimeout = context.WithTimeout(ctx, opts.Timeout)
+			defer cancelTimeout()
+		}
+		handler(ctx, msg)
+	}
+	if !opts.Async {
+		return ps.subscribe(subscriberID, subject, call, nil)
+	}
+
+	return ps.subscribe(subscriberID, subject, nil, func() *asyncWorker[T] {
+		// 处理协程停止时取消 ctx，停止后处理剩余消息的 handler 可以据此尽快返回
+		ctx, cancel := context.WithCancel(ps.ctx)
+		w := newAsyncWorker(func(d delivery[T]) { call(ctx, d.msg) }, opts.AsyncOptions)
+		w.onStop = cancel
+		return w
+	})
+}
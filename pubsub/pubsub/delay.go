@@ -0,0 +1,49 @@
+package pubsub
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+	"timeWheel"
+)
+
+const (
+	// delayTick 延时发布时间轮的格跨度，即到期时间的精度
+	delayTick = 10 * time.Millisecond
+	// delayWheelSize 延时发布时间轮的格数，超出一轮的延时溢出到上层时间轮
+	delayWheelSize = 512
+)
+
+var (
+	delayWheel     *timeWheel.TimeWheel // 所有发布订阅服务共用的延时发布时间轮，首次使用时启动
+	delayWheelOnce sync.Once
+)
+
+// PublishAfter 在 delay 之后发布消息，适合“活动 5 分钟后开始”之类的定时通知；delay 小于等于 0 时尽快发布。
+// 到期时间的精度约为 10ms，每条延时消息在自己的协程中发布，到期时间相同的消息之间不保证顺序。
+// 主题在调用时校验；返回的 cancel 取消尚未发布的消息，取消成功时返回 true。
+// 到期时服务已经关闭的消息被丢弃，发布失败时记录日志。
+func (ps *GenericPubSub[T]) PublishAfter(subject string, content T, delay time.Duration) (cancel func() bool, err error) {
+	return ps.PublishAt(subject, content, time.Now().Add(delay))
+}
+
+// PublishAt 在 t 时刻发布消息，t 已过去时尽快发布，见 PublishAfter
+func (ps *GenericPubSub[T]) PublishAt(subject string, content T, t time.Time) (cancel func() bool, err error) {
+	if _, err := splitSubject(subject); err != nil {
+		return nil, err
+	}
+	if ps.subs.Load().closed {
+		return nil, ErrClosed
+	}
+	delayWheelOnce.Do(func() {
+		delayWheel = timeWheel.NewTimeWheel(delayTick.Milliseconds(), delayWheelSize, time.Now().UnixMilli(), timeWheel.NewDelayQueue(64))
+		delayWheel.Start()
+	})
+	task := delayWheel.Schedule(t.UnixMilli(), func() {
+		if err := ps.Publish(subject, content); err != nil && !errors.Is(err, ErrClosed) {
+			log.Printf("pubsub: delayed publish to %s failed: %v", subject, err)
+		}
+	})
+	return task.Stop, nil
+}
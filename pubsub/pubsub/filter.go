@@ -0,0 +1,22 @@
+package pubsub
+
+import "fmt"
+
+// Filter 订阅过滤条件，返回 false 的消息不交给 handler
+type Filter[T any] func(subject string, content T) bool
+
+// SubscribeFiltered 以过滤条件订阅主题（如只关心 1000 分以上的分数更新）：filter 在发布者协程中、
+// 经过中间件之后执行，只有返回 true 的消息才调用 handler；filter 应当快速且没有副作用。
+func (ps *GenericPubSub[T]) SubscribeFiltered(subscriberID string, subject string, filter Filter[T], handler Handler[T]) error {
+	if handler == nil {
+		return errNilHandler
+	}
+	if filter == nil {
+		return fmt.Errorf("filter cannot be nil")
+	}
+	return ps.Subscribe(subscriberID, subject, func(subject string, content T) {
+		if filter(subject, content) {
+			handler(subject, content)
+		}
+	})
+}
@@ -0,0 +1,300 @@
+// WebSocket 网关
+//
+// Gateway 让远程客户端通过 WebSocket 订阅允许的主题模式并接收 JSON 编码的消息，也可以向允许的主题发布消息。
+// 默认只接受同源的握手，且客户端既不能订阅也不能发布，需通过 GatewayOptions 显式放开。
+// 每个连接是一个订阅者（ID 为 "_WS.<n>"），每个订阅经自己的有界队列异步推送，慢客户端不会阻塞发布者；
+// 队列满时按 AsyncOptions 丢弃或等待。连接断开时取消该连接的所有订阅。
+//
+// 客户端发送的帧：
+//
+//	{"op":"sub","subject":"leaderboard.*","id":"1"}
+//	{"op":"unsub","subject":"leaderboard.*","id":"2"}
+//	{"op":"pub","subject":"chat.room1","payload":{...},"headers":{...},"id":"3"}
+//
+// 服务端发送的帧：
+//
+//	{"op":"msg","subject":"leaderboard.daily","msgId":"...","time":"...","headers":{...},"payload":{...}}
+//	{"op":"ok","id":"1"}
+//	{"op":"error","id":"3","error":"publish to chat.room1 is not allowed"}
+//
+// GatewayOptions.Codec 不是 JSON 时，内容以 base64 放在 "data" 中并带上 "codec"，客户端发布时同样如此。
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// DefaultGatewayMaxFrameBytes 网关默认允许的客户端帧大小上限
+	DefaultGatewayMaxFrameBytes = 64 << 10
+	// DefaultGatewayWriteTimeout 网关向客户端写入一帧的默认超时
+	DefaultGatewayWriteTimeout = 10 * time.Second
+)
+
+// GatewayOptions WebSocket 网关选项
+type GatewayOptions struct {
+	// Publishable 允许客户端发布的主题模式，为空时客户端不能发布
+	Publishable []string
+	// Subscribable 允许客户端订阅的主题模式，客户端订阅的模式必须被其中之一覆盖
+	Subscribable []string
+	// CanSubscribe 判断客户端能否订阅模式，与 Subscribable 同时设置时须两者都允许；
+	// 两者都未设置时客户端不能订阅
+	CanSubscribe func(r *http.Request, pattern string) bool
+	// CheckOrigin 校验握手请求，为 nil 时只接受同源请求与不带 Origin 的非浏览器客户端
+	CheckOrigin func(r *http.Request) bool
+	// AsyncOptions 每个订阅推送队列的选项
+	AsyncOptions
+	MaxFrameBytes int           // 客户端帧大小上限，小于等于 0 时使用 DefaultGatewayMaxFrameBytes
+	WriteTimeout  time.Duration // 写入一帧的超时，超时后断开连接；小于等于 0 时使用 DefaultGatewayWriteTimeout
+	// Codec 消息内容的编码，为 nil 时使用 JSONCodec
+	Codec Codec
+}
+
+// gatewayFrame 网关与客户端之间的一帧
+type gatewayFrame struct {
+	Op      string            `json:"op"`
+	ID      string            `json:"id,omitempty"`
+	Subject string            `json:"subject,omitempty"`
+	MsgID   string            `json:"msgId,omitempty"`
+	Time    *time.Time        `json:"time,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Payload json.RawMessage   `json:"payload,omitempty"`
+	Codec   string            `json:"codec,omitempty"`
+	Data    []byte            `json:"data,omitempty"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// Gateway WebSocket 网关，实现 http.Handler
+type Gateway[T any] struct {
+	ps           *GenericPubSub[T]
+	opts         GatewayOptions
+	publishable  [][]string
+	subscribable [][]string
+	server       websocket.Server
+	nextConn     atomic.Int64
+
+	mu     sync.Mutex
+	conns  map[*gatewayConn[T]]struct{}
+	closed bool
+}
+
+// NewGateway 创建 WebSocket 网关，可挂载到任意路径，如 http.Handle("/ws", gw)
+func NewGateway[T any](ps *GenericPubSub[T], opts GatewayOptions) (*Gateway[T], error) {
+	if opts.MaxFrameBytes <= 0 {
+		opts.MaxFrameBytes = DefaultGatewayMaxFrameBytes
+	}
+	if opts.WriteTimeout <= 0 {
+		opts.WriteTimeout = DefaultGatewayWriteTimeout
+	}
+	g := &Gateway[T]{ps: ps, opts: opts, conns: map[*gatewayConn[T]]struct{}{}}
+	for _, pattern := range opts.Publishable {
+		tokens, err := splitPattern(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid publishable pattern: %w", err)
+		}
+		g.publishable = append(g.publishable, tokens)
+	}
+	for _, pattern := range opts.Subscribable {
+		tokens, err := splitPattern(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subscribable pattern: %w", err)
+		}
+		g.subscribable = append(g.subscribable, tokens)
+	}
+	checkOrigin := opts.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	g.server = websocket.Server{
+		Handshake: func(config *websocket.Config, r *http.Request) error {
+			if !checkOrigin(r) {
+				return errors.New("origin not allowed")
+			}
+			return nil
+		},
+		Handler: g.serve,
+	}
+	return g, nil
+}
+
+// ServeHTTP 处理 WebSocket 握手并服务该连接
+func (g *Gateway[T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.server.ServeHTTP(w, r)
+}
+
+// Close 断开所有连接并取消其订阅，之后的新连接会被立即关闭
+func (g *Gateway[T]) Close() {
+	g.mu.Lock()
+	g.closed = true
+	conns := g.conns
+	g.conns = map[*gatewayConn[T]]struct{}{}
+	g.mu.Unlock()
+
+	for c := range conns {
+		c.ws.Close()
+	}
+}
+
+// gatewayConn 网关的一个客户端连接
+type gatewayConn[T any] struct {
+	gw           *Gateway[T]
+	ws           *websocket.Conn
+	request      *http.Request
+	subscriberID string
+
+	wmu sync.Mutex // 保护对 ws 的写入
+}
+
+// serve 读取客户端的帧直到连接断开
+func (g *Gateway[T]) serve(ws *websocket.Conn) {
+	ws.MaxPayloadBytes = g.opts.MaxFrameBytes
+	c := &gatewayConn[T]{
+		gw:           g,
+		ws:           ws,
+		request:      ws.Request(),
+		subscriberID: "_WS." + strconv.FormatInt(g.nextConn.Add(1), 10),
+	}
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		ws.Close()
+		return
+	}
+	g.conns[c] = struct{}{}
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.conns, c)
+		g.mu.Unlock()
+		g.ps.UnsubscribeAll(c.subscriberID)
+		ws.Close()
+	}()
+	for {
+		var frame gatewayFrame
+		if err := websocket.JSON.Receive(ws, &frame); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) || errors.Is(err, websocket.ErrFrameTooLarge) {
+				c.reply(frame.ID, fmt.Errorf("malformed frame: %w", err))
+				continue
+			}
+			return
+		}
+		c.reply(frame.ID, c.handle(frame))
+	}
+}
+
+// handle 处理客户端的一帧
+func (c *gatewayConn[T]) handle(frame gatewayFrame) error {
+	switch frame.Op {
+	case "sub":
+		tokens, err := splitPattern(frame.Subject)
+		if err != nil {
+			return err
+		}
+		if !c.gw.canSubscribe(c.request, frame.Subject, tokens) {
+			return fmt.Errorf("subscribe to %s is not allowed", frame.Subject)
+		}
+		return c.gw.ps.SubscribeMsg(c.subscriberID, frame.Subject, c.push, CtxOptions{Async: true, AsyncOptions: c.gw.opts.AsyncOptions})
+	case "unsub":
+		c.gw.ps.Unsubscribe(c.subscriberID, frame.Subject)
+		return nil
+	case "pub":
+		if !c.gw.canPublish(frame.Subject) {
+			return fmt.Errorf("publish to %s is not allowed", frame.Subject)
+		}
+		msg := &Message[T]{Subject: frame.Subject, Headers: frame.Headers}
+		if err := decodePayload(c.gw.opts.Codec, frame.Payload, frame.Codec, frame.Data, &msg.Payload); err != nil {
+			return fmt.Errorf("decode payload: %w", err)
+		}
+		return c.gw.ps.PublishMsg(context.Background(), msg)
+	}
+	return fmt.Errorf("unknown op %q", frame.Op)
+}
+
+// canPublish 主题是否匹配 Publishable 中的模式
+func (g *Gateway[T]) canPublish(subject string) bool {
+	tokens := strings.Split(subject, subjectSeparator)
+	for _, pattern := range g.publishable {
+		if matchPattern(pattern, tokens) {
+			return true
+		}
+	}
+	return false
+}
+
+// canSubscribe 模式须被 Subscribable 中的模式覆盖并经 CanSubscribe 允许，两者都未设置时不允许订阅
+func (g *Gateway[T]) canSubscribe(r *http.Request, pattern string, tokens []string) bool {
+	if len(g.subscribable) == 0 && g.opts.CanSubscribe == nil {
+		return false
+	}
+	if len(g.subscribable) > 0 {
+		covered := false
+		for _, allowed := range g.subscribable {
+			if patternCovers(allowed, tokens) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return g.opts.CanSubscribe == nil || g.opts.CanSubscribe(r, pattern)
+}
+
+// sameOrigin 握手请求的 Origin 与 Host 一致；不带 Origin 的请求来自非浏览器客户端，同样接受
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// push 在订阅的处理协程中将消息推送给客户端
+func (c *gatewayConn[T]) push(_ context.Context, msg *Message[T]) {
+	payload, codec, data, err := payloadFields(c.gw.opts.Codec, &msg.Payload)
+	if err != nil {
+		log.Printf("pubsub: gateway failed to encode %s: %v", msg.Subject, err)
+		return
+	}
+	t := msg.Time
+	c.send(gatewayFrame{Op: "msg", Subject: msg.Subject, MsgID: msg.ID, Time: &t, Headers: msg.Headers, Payload: payload, Codec: codec, Data: data})
+}
+
+// reply 回复客户端的请求帧，没有请求ID且成功时不回复
+func (c *gatewayConn[T]) reply(id string, err error) {
+	switch {
+	case err != nil:
+		c.send(gatewayFrame{Op: "error", ID: id, Error: err.Error()})
+	case id != "":
+		c.send(gatewayFrame{Op: "ok", ID: id})
+	}
+}
+
+// send 写入一帧，写入失败或超时时断开连接
+func (c *gatewayConn[T]) send(frame gatewayFrame) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	c.ws.SetWriteDeadline(time.Now().Add(c.gw.opts.WriteTimeout))
+	if err := websocket.JSON.Send(c.ws, frame); err != nil {
+		c.ws.Close()
+	}
+}
@@ -2,126 +2,211 @@ package pubsub
 
 import (
 	"common"
+	"context"
 	"fmt"
+	"strings"
 	"sync"
-	"trietst"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Handler 为泛型订阅者的回调函数类型
 type Handler[T any] func(subject string, content T)
 
-// subscribing 表示某主题前缀的订阅集合
-type subscribing struct {
-	subscribers         common.StringSet
-	wildcardSubscribers common.StringSet
-}
-
-func newSubscribing() *subscribing {
-	return &subscribing{
-		subscribers:         common.StringSet{},
-		wildcardSubscribers: common.StringSet{},
-	}
-}
+var errNilHandler = fmt.Errorf("handler cannot be nil")
 
 // GenericPubSub 为通用发布订阅服务（泛型版）
+//
+// 主题由 '.' 分隔为若干分段，订阅时可使用 NATS 风格的通配符：
+// '*' 匹配恰好一个分段（如 player.*.score），'>' 匹配其后的一个或多个分段（如 leaderboard.>）。
+// 订阅模式存放在按分段组织的前缀树中，发布时只沿匹配的路径下钻。
+// 每个（订阅者, 模式）是一个独立的订阅，拥有自己的 handler，前缀树与 handler 都以 subscriptionKey 为键。
+// 前缀树与 handler 组成不可变的快照（见 subscriptions），订阅变化时复制后替换，发布时无锁读取。
 type GenericPubSub[T any] struct {
-	mu   sync.RWMutex
-	tree trietst.Trie
+	mu   sync.RWMutex                     // 保护订阅变化（串行替换快照）与下面的映射
+	subs atomic.Pointer[subscriptions[T]] // 当前的订阅快照
 
-	subscriberExactSubjects    map[string]common.StringSet
-	subscriberWildcardSubjects map[string]common.StringSet
-	subscriberHandlers         map[string]Handler[T]
+	subscriberSubjects map[string]common.StringSet // 订阅者 -> 订阅模式
+	generations        map[string]uint64           // 订阅 -> 代数，每次订阅递增，用于判断订阅是否已被替换
+	log                atomic.Pointer[topicLog]    // 消息日志，为 nil 时不记录；在写锁内替换
+	retained           map[string]*Message[T]      // 主题 -> 保留消息
+	inboxes            map[string]chan reply[T]    // 回复主题 -> 等待回复的请求
+	nextInbox          atomic.Int64
+	nextGeneration     uint64
+	ids                *msgIDGenerator
+	errorHook          atomic.Pointer[HandlerErrorHook]
+	interceptor        atomic.Pointer[DeliveryInterceptor[T]]
+	acls               atomic.Pointer[map[string]*compiledACL]
+	tracing            atomic.Pointer[tracing]               // 链路追踪，为 nil 时不创建 span
+	middlewares        atomic.Pointer[[]scopedMiddleware[T]] // 登记的中间件，只整体替换
+	stats              atomic.Pointer[statsSet]              // 指标计数器，ResetStats 时整体替换
+	limiters           limiterRegistry
+	matchCacheSize     int // 新快照的发布匹配缓存容量
+
+	ctx    context.Context // 上下文感知的 handler 的根 ctx，Close 时取消
+	cancel context.CancelFunc
+
+	publishing atomic.Int64  // 进行中的发布数
+	idle       chan struct{} // 关闭后进行中的发布数降为 0 时通知 drain
+	closeOnce  sync.Once
+	drained    chan struct{} // 关闭流程完成时关闭
 }
 
 // NewGenericPubSub 创建一个新的通用发布订阅服务实例
 func NewGenericPubSub[T any]() *GenericPubSub[T] {
-	return &GenericPubSub[T]{
-		subscriberExactSubjects:    map[string]common.StringSet{},
-		subscriberWildcardSubjects: map[string]common.StringSet{},
-		subscriberHandlers:         map[string]Handler[T]{},
+	ctx, cancel := context.WithCancel(context.Background())
+	ps := &GenericPubSub[T]{
+		subscriberSubjects: map[string]common.StringSet{},
+		ids:                newMsgIDGenerator(),
+		generations:        map[string]uint64{},
+		retained:           map[string]*Message[T]{},
+		inboxes:            map[string]chan reply[T]{},
+		matchCacheSize:     DefaultMatchCacheSize,
+		ctx:                ctx,
+		cancel:             cancel,
+		idle:               make(chan struct{}, 1),
+		drained:            make(chan struct{}),
 	}
+	ps.subs.Store(newSubscriptions[T](DefaultMatchCacheSize))
+	ps.stats.Store(&statsSet{})
+	return ps
+}
+
+// subscriptionKey 订阅的唯一键，由订阅者与订阅模式组成
+func subscriptionKey(subscriberID, pattern string) string {
+	return subscriberID + "\x00" + pattern
 }
 
 // Subscribe 订阅主题，返回错误而不是 panic
+// 同一订阅者可以用不同的 handler 订阅多个模式，各订阅互不影响；重复订阅同一模式时替换该订阅的 handler。
+// 模式中的通配符必须独占一个分段。
+// handler 在 Publish 的调用方协程中同步执行，需要隔离慢订阅者时使用 SubscribeAsync。
 func (ps *GenericPubSub[T]) Subscribe(subscriberID string, subject string, handler Handler[T]) error {
-	ps.mu.Lock()
-	defer ps.mu.Unlock()
+	if handler == nil {
+		return errNilHandler
+	}
+	return ps.subscribe(subscriberID, subject, fromHandler(ps.withMiddlewares(handler)), nil)
+}
 
+// subscribe 订阅主题；newWorker 非 nil 时为该订阅创建异步处理协程，并以其入队函数作为 handler
+func (ps *GenericPubSub[T]) subscribe(subscriberID string, subject string, handler MsgHandler[T], newWorker func() *asyncWorker[T]) error {
+	_, err := ps.subscribeGeneration(subscriberID, subject, handler, newWorker)
+	return err
+}
+
+// subscribeGeneration 与 subscribe 相同，并返回该订阅的代数，供 unsubscribeGeneration 使用
+func (ps *GenericPubSub[T]) subscribeGeneration(subscriberID string, subject string, handler MsgHandler[T], newWorker func() *asyncWorker[T]) (uint64, error) {
 	if subscriberID == "" {
-		return fmt.Errorf("subscriberID cannot be empty")
+		return 0, fmt.Errorf("subscriberID cannot be empty")
 	}
-	if handler == nil {
-		return fmt.Errorf("handler cannot be nil")
+	tokens, err := splitPattern(subject)
+	if err != nil {
+		return 0, err
 	}
-	for i, c := range subject {
-		if c == '*' && i != len(subject)-1 {
-			return fmt.Errorf("'*' can only be used at the end of subject")
-		}
+	if err := ps.authorizeSubscribe(subscriberID, subject, tokens); err != nil {
+		return 0, err
 	}
 
-	ps.subscriberHandlers[subscriberID] = handler
+	// 在写锁内取出匹配的保留消息并完成订阅，保留消息投递完之前到达的实时消息先缓存，保证先旧后新
+	ps.mu.Lock()
+	if ps.subs.Load().closed {
+		ps.mu.Unlock()
+		return 0, ErrClosed
+	}
+	retained := ps.matchRetainedLocked(tokens)
+	var r *replayer[T]
+	if len(retained) > 0 {
+		r = &replayer[T]{}
+	}
+	if handler != nil {
+		handler = ps.guard(subscriberID, handler)
+	}
+	generation := ps.subscribeLocked(subscriberID, subject, tokens, handler, newWorker, r)
+	ps.mu.Unlock()
 
-	wildcard := false
-	if subject != "" && subject[len(subject)-1] == '*' {
-		wildcard = true
-		subject = subject[:len(subject)-1]
+	if r != nil {
+		for _, d := range retained {
+			r.handler(context.Background(), d.msg)
+		}
+		r.finish()
 	}
+	return generation, nil
+}
 
-	subs := ps.getSubscribing(subject, true)
-	if !wildcard {
-		subs.subscribers.Add(subscriberID)
-		exactSet, ok := ps.subscriberExactSubjects[subscriberID]
-		if !ok {
-			exactSet = common.StringSet{}
-			ps.subscriberExactSubjects[subscriberID] = exactSet
+// subscribeLocked 登记订阅并返回其代数，调用方需持有写锁；r 非 nil 时实时消息先经 r 缓存，直到调用 r.finish
+func (ps *GenericPubSub[T]) subscribeLocked(subscriberID string, subject string, tokens []string, handler MsgHandler[T], newWorker func() *asyncWorker[T], r *replayer[T]) uint64 {
+	key := subscriptionKey(subscriberID, subject)
+	s := ps.editLocked()
+	var w *asyncWorker[T]
+	if newWorker != nil {
+		w = newWorker()
+		if w.maxConcurrency > 0 {
+			// 先登记新订阅再停止被替换的订阅，共享的信号量不会在替换期间被删除重建
+			w.limiter = ps.limiters.attach(subscriberID, w.maxConcurrency)
 		}
-		exactSet.Add(subject)
-	} else {
-		subs.wildcardSubscribers.Add(subscriberID)
-		wildcardSet, ok := ps.subscriberWildcardSubjects[subscriberID]
-		if !ok {
-			wildcardSet = common.StringSet{}
-			ps.subscriberWildcardSubjects[subscriberID] = wildcardSet
+	}
+	s.stopWorker(key)
+	if w != nil {
+		// 在消息入队之前设置，处理协程读取时已可见
+		w.onPanic = func(d delivery[T], recovered any) {
+			ps.handlerPanicked(subscriberID, d.msg.Subject, recovered)
 		}
-		wildcardSet.Add(subject)
+		w.counters = &ps.stats
+		w.tracing = &ps.tracing
+		w.subscriberID, w.pattern = subscriberID, subject
+		s.workers[key] = w
+		handler = w.deliver
 	}
-	return nil
+	if r != nil {
+		r.handler, r.replaying = handler, true
+		handler = r.live
+	}
+	s.handlers[key] = handler
+	s.root = s.root.with(tokens, key)
+	ps.subs.Store(s)
+	subjects, ok := ps.subscriberSubjects[subscriberID]
+	if !ok {
+		subjects = common.StringSet{}
+		ps.subscriberSubjects[subscriberID] = subjects
+	}
+	subjects.Add(subject)
+	ps.nextGeneration++
+	ps.generations[key] = ps.nextGeneration
+	return ps.nextGeneration
 }
 
-// Unsubscribe 取消订阅
+// Unsubscribe 取消订阅，subject 需与订阅时的模式一致
 func (ps *GenericPubSub[T]) Unsubscribe(subscriberID string, subject string) {
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
 
-	wildcard := false
-	if subject != "" && subject[len(subject)-1] == '*' {
-		wildcard = true
-		subject = subject[:len(subject)-1]
+	ps.unsubscribeLocked(subscriberID, subject)
+}
+
+// unsubscribeGeneration 只有订阅仍是 generation 那一次订阅（没有被替换或取消）时才取消，返回是否取消
+func (ps *GenericPubSub[T]) unsubscribeGeneration(subscriberID string, subject string, generation uint64) bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if ps.generations[subscriptionKey(subscriberID, subject)] != generation {
+		return false
 	}
+	ps.unsubscribeLocked(subscriberID, subject)
+	return true
+}
 
-	subs := ps.getSubscribing(subject, false)
-	if subs == nil {
+// unsubscribeLocked 取消订阅，调用方需持有写锁
+func (ps *GenericPubSub[T]) unsubscribeLocked(subscriberID string, subject string) {
+	subjects, ok := ps.subscriberSubjects[subscriberID]
+	if !ok || !subjects.Contains(subject) {
 		return
 	}
-
-	if !wildcard {
-		subs.subscribers.Remove(subscriberID)
-		if exactSet, ok := ps.subscriberExactSubjects[subscriberID]; ok {
-			exactSet.Remove(subject)
-			// 如果该订阅者没有任何订阅了，清理 handler
-			if len(exactSet) == 0 && len(ps.subscriberWildcardSubjects[subscriberID]) == 0 {
-				delete(ps.subscriberHandlers, subscriberID)
-			}
-		}
-	} else {
-		subs.wildcardSubscribers.Remove(subscriberID)
-		if wildcardSet, ok := ps.subscriberWildcardSubjects[subscriberID]; ok {
-			wildcardSet.Remove(subject)
-			// 如果该订阅者没有任何订阅了，清理 handler
-			if len(wildcardSet) == 0 && len(ps.subscriberExactSubjects[subscriberID]) == 0 {
-				delete(ps.subscriberHandlers, subscriberID)
-			}
-		}
+	ps.removeSubscriptionLocked(subscriberID, subject)
+	subjects.Remove(subject)
+	if len(subjects) == 0 {
+		delete(ps.subscriberSubjects, subscriberID)
+		ps.stats.Load().subscribers.remove(subscriberID)
 	}
 }
 
@@ -130,101 +215,131 @@ func (ps *GenericPubSub[T]) UnsubscribeAll(subscriberID string) {
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
 
-	if exactSet, ok := ps.subscriberExactSubjects[subscriberID]; ok {
-		delete(ps.subscriberExactSubjects, subscriberID)
-		for subject := range exactSet {
-			// 使用 Find 而不是 Sub，避免创建不存在的节点
-			if node := ps.tree.Find(subject); node != nil {
-				if subs := ps.getSubscribingOfTree(node, false); subs != nil {
-					subs.subscribers.Remove(subscriberID)
-				}
-			}
-		}
+	for subject := range ps.subscriberSubjects[subscriberID] {
+		ps.removeSubscriptionLocked(subscriberID, subject)
 	}
+	delete(ps.subscriberSubjects, subscriberID)
+	ps.stats.Load().subscribers.remove(subscriberID)
+}
 
-	if wildcardSet, ok := ps.subscriberWildcardSubjects[subscriberID]; ok {
-		delete(ps.subscriberWildcardSubjects, subscriberID)
-		for subject := range wildcardSet {
-			// 使用 Find 而不是 Sub，避免创建不存在的节点
-			if node := ps.tree.Find(subject); node != nil {
-				if subs := ps.getSubscribingOfTree(node, false); subs != nil {
-					subs.wildcardSubscribers.Remove(subscriberID)
-				}
-			}
-		}
+// UnsubscribePattern 取消该订阅者所有被 pattern 覆盖的订阅（订阅可能收到的主题都与 pattern 匹配），返回取消的订阅数，
+// 调用方不必记住订阅过的每个模式。例如 pattern 为 "player.>" 时取消 "player.1.score" 与 "player.*.score"；
+// pattern 为 "player.*" 时取消 "player.1" 与 "player.*"，但不取消 "player.>"
+func (ps *GenericPubSub[T]) UnsubscribePattern(subscriberID string, pattern string) (int, error) {
+	tokens, err := splitPattern(pattern)
+	if err != nil {
+		return 0, err
 	}
 
-	// 清理 handler，避免内存泄漏
-	delete(ps.subscriberHandlers, subscriberID)
-}
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
 
-// Publish 发布主题与内容，返回错误而不是 panic
-func (ps *GenericPubSub[T]) Publish(subject string, content T) error {
-	for _, c := range subject {
-		if c == '*' {
-			return fmt.Errorf("subject should not contain '*' while publishing")
+	var matched []string
+	for subject := range ps.subscriberSubjects[subscriberID] {
+		if patternCovers(tokens, strings.Split(subject, subjectSeparator)) {
+			matched = append(matched, subject)
 		}
 	}
+	for _, subject := range matched {
+		ps.unsubscribeLocked(subscriberID, subject)
+	}
+	return len(matched), nil
+}
 
-	// 先收集所有需要调用的 handler（持有读锁）
-	ps.mu.RLock()
-	handlers := ps.collectHandlers(subject, &ps.tree, 0)
-	ps.mu.RUnlock()
-
-	// 释放锁后再调用 handler，避免阻塞其他操作
-	for _, h := range handlers {
-		h(subject, content)
+// removeSubscriptionLocked 从前缀树中移除订阅，并清理其 handler 与异步处理协程，调用方需持有写锁
+func (ps *GenericPubSub[T]) removeSubscriptionLocked(subscriberID, subject string) {
+	key := subscriptionKey(subscriberID, subject)
+	s := ps.editLocked()
+	if s.root = s.root.without(strings.Split(subject, subjectSeparator), key); s.root == nil {
+		s.root = newSubjectNode()
 	}
-	return nil
+	delete(s.handlers, key)
+	s.stopWorker(key)
+	ps.subs.Store(s)
+	delete(ps.generations, key)
 }
 
-// collectHandlers 递归收集所有需要调用的 handler
-func (ps *GenericPubSub[T]) collectHandlers(subject string, st *trietst.Trie, idx int) []Handler[T] {
-	var handlers []Handler[T]
+// Publish 发布主题与内容，返回错误而不是 panic
+// 每个匹配的订阅各回调一次，同一订阅者的多个订阅同时匹配时各自回调；同步订阅的 handler 在返回前执行完毕，
+// 异步订阅者只保证已入队（或按选项被丢弃）。开启消息日志时先写入日志，写入失败则不投递。
+func (ps *GenericPubSub[T]) Publish(subject string, content T) error {
+	_, err := ps.publish(context.Background(), NewMessage(subject, content), "")
+	return err
+}
 
-	// 收集通配订阅者
-	if subs := ps.getSubscribingOfTree(st, false); subs != nil {
-		for subscriberID := range subs.wildcardSubscribers {
-			if h, ok := ps.subscriberHandlers[subscriberID]; ok {
-				handlers = append(handlers, h)
-			}
-		}
+// PublishCtx 与 Publish 相同，但 ctx 会传给上下文感知的 handler（见 SubscribeCtx），
+// 阻塞的异步入队在 ctx 取消时放弃；ctx 已取消时不再调用剩余的同步 handler 并返回 ctx.Err()。
+func (ps *GenericPubSub[T]) PublishCtx(ctx context.Context, subject string, content T) error {
+	_, err := ps.publish(ctx, NewMessage(subject, content), "")
+	return err
+}
+
+// publish 发布消息，reply 非空时发给响应者的消息携带回复主题；返回收到消息的响应者数量
+func (ps *GenericPubSub[T]) publish(ctx context.Context, msg *Message[T], reply string) (responders int, err error) {
+	tokens, err := splitSubject(msg.Subject)
+	if err != nil {
+		return 0, err
+	}
+	if err := ps.authorizePublish(ctx, msg.Subject, tokens); err != nil {
+		return 0, err
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	ps.stamp(msg)
+	if t := ps.tracing.Load(); t != nil {
+		var span trace.Span
+		ctx, span = startPublishSpan(t, ctx, msg)
+		defer func() { endSpan(span, err) }()
 	}
 
-	if idx < len(subject) {
-		// 继续递归收集，使用 ChildIfExists 避免在读锁下创建节点
-		if nextNode := st.ChildIfExists(subject[idx]); nextNode != nil {
-			handlers = append(handlers, ps.collectHandlers(subject, nextNode, idx+1)...)
-		}
-	} else {
-		// 到达叶子节点，收集精确订阅者
-		if subs := ps.getSubscribingOfTree(st, false); subs != nil {
-			for subscriberID := range subs.subscribers {
-				if h, ok := ps.subscriberHandlers[subscriberID]; ok {
-					handlers = append(handlers, h)
-				}
-			}
-		}
+	ps.publishing.Add(1)
+	defer ps.publishDone()
+	handlers, responders, err := ps.collect(ctx, msg, tokens, reply)
+	if err != nil {
+		return 0, err
+	}
+	ps.recordPublish(msg, len(handlers))
+	if counts := deliveryCountsFor(ctx, msg); counts != nil {
+		counts.matched = len(handlers)
 	}
 
-	return handlers
+	if interceptor := ps.interceptor.Load(); interceptor != nil {
+		return responders, (*interceptor)(msg, func() error { return deliver(ctx, msg, handlers) })
+	}
+	return responders, deliver(ctx, msg, handlers)
 }
 
-// 获取订阅集合
-func (ps *GenericPubSub[T]) getSubscribing(subject string, create bool) *subscribing {
-	t := ps.tree.Sub(subject)
-	return ps.getSubscribingOfTree(t, create)
+// deliver 依次调用收集到的 handler；收集完再调用，handler 中可以订阅、取消订阅或再次发布
+func deliver[T any](ctx context.Context, msg *Message[T], handlers []MsgHandler[T]) error {
+	for _, h := range handlers {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		h(ctx, msg)
+	}
+	return nil
 }
 
-// 从树节点获取订阅集合
-func (ps *GenericPubSub[T]) getSubscribingOfTree(t *trietst.Trie, create bool) *subscribing {
-	if t.Val == nil {
-		if create {
-			subs := newSubscribing()
-			t.Val = subs
-			return subs
-		}
-		return nil
+// collect 写入日志并从当前快照收集需要调用的 handler，统计其中的响应者。
+// 快照无锁读取，发布者之间互不争用；需要写入日志的消息持有读锁，保证日志偏移量与订阅的先后一致（见 SubscribeMsgFrom）
+func (ps *GenericPubSub[T]) collect(ctx context.Context, msg *Message[T], tokens []string, reply string) ([]MsgHandler[T], int, error) {
+	l := ps.log.Load()
+	if l != nil && l.accepts(tokens) {
+		ps.mu.RLock()
+		defer ps.mu.RUnlock()
+		l = ps.log.Load()
+	}
+	s := ps.subs.Load()
+	if s.closed {
+		return nil, 0, ErrClosed
+	}
+	if mode := publishModeFrom(ctx); mode != nil && mode.noBlock && s.queueFull(tokens, msg.Subject) {
+		return nil, 0, ErrQueueFull
+	}
+	if err := ps.appendLogLocked(l, msg, tokens); err != nil {
+		return nil, 0, err
 	}
-	return t.Val.(*subscribing)
+	handlers, responders := s.matchHandlers(tokens, msg.Subject, reply)
+	return handlers, responders, nil
 }
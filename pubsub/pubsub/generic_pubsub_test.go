@@ -1,12 +1,32 @@
 package pubsub
 
 import (
+	"bufio"
+	"common"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/bmizerany/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/websocket"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 // recorder 记录接收到的事件
@@ -55,17 +75,20 @@ func TestWildcardSubscription(t *testing.T) {
 	assert.Equal(t, nil, err)
 	t.Logf("Subscribed 'B' to 'apple.*'")
 
-	t.Logf("Publishing 'x' to 'apple.'")
-	ps.Publish("apple.", "x")
+	t.Logf("Publishing 'x' to 'apple.pie'")
+	ps.Publish("apple.pie", "x")
 	t.Logf("Publishing 'y' to 'apple.1'")
 	ps.Publish("apple.1", "y")
+	t.Logf("Publishing 'w' to 'apple.1.2' (should not be received, '*' matches a single segment)")
+	ps.Publish("apple.1.2", "w")
+	t.Logf("Publishing 'v' to 'apple' (should not be received)")
+	ps.Publish("apple", "v")
 	t.Logf("Publishing 'z' to 'banana' (should not be received)")
 	ps.Publish("banana", "z")
 
 	events := r.getEvents()
 	t.Logf("Recorded events: %v", events)
-	sort.Strings(events)
-	assert.Equal(t, []string{"apple.: x", "apple.1: y"}, events)
+	assert.Equal(t, []string{"apple.1: y", "apple.pie: x"}, events)
 	t.Log("--- TestWildcardSubscription PASSED ---")
 }
 
@@ -144,18 +167,75 @@ func TestUnsubscribeAll(t *testing.T) {
 	t.Log("--- TestUnsubscribeAll PASSED ---")
 }
 
+func TestMultipleSubscriptions(t *testing.T) {
+	t.Log("--- Running TestMultipleSubscriptions ---")
+	ps := NewGenericPubSub[string]()
+	scores, ranks, async := &recorder[string]{}, &recorder[string]{}, &recorder[string]{}
+	// 同一订阅者用不同的 handler 订阅不同模式，后一次订阅不会覆盖前一次
+	assert.Equal(t, nil, ps.Subscribe("A", "player.*.score", scores.handle))
+	assert.Equal(t, nil, ps.Subscribe("A", "player.*.rank", ranks.handle))
+	assert.Equal(t, nil, ps.SubscribeAsync("A", "match.>", async.handle, AsyncOptions{}))
+	t.Log("Subscribed 'A' to 'player.*.score', 'player.*.rank' and 'match.>' with distinct handlers")
+
+	ps.Publish("player.1.score", "a")
+	ps.Publish("player.1.rank", "b")
+	ps.Publish("match.1.end", "c")
+	assert.Equal(t, []string{"player.1.score: a"}, scores.getEvents())
+	assert.Equal(t, []string{"player.1.rank: b"}, ranks.getEvents())
+	stats, ok := ps.DeliveryStats("A")
+	assert.Equal(t, true, ok)
+	assert.Equal(t, DefaultQueueSize, stats.Capacity) // 只有 match.> 是异步订阅
+
+	// 重复订阅同一模式只替换该订阅的 handler
+	replaced := &recorder[string]{}
+	assert.Equal(t, nil, ps.Subscribe("A", "player.*.score", replaced.handle))
+	ps.Publish("player.2.score", "d")
+	ps.Publish("player.2.rank", "e")
+	assert.Equal(t, []string{"player.1.score: a"}, scores.getEvents())
+	assert.Equal(t, []string{"player.2.score: d"}, replaced.getEvents())
+	assert.Equal(t, []string{"player.1.rank: b", "player.2.rank: e"}, ranks.getEvents())
+
+	// 取消一个订阅不影响其他订阅
+	ps.Unsubscribe("A", "player.*.rank")
+	ps.Publish("player.3.rank", "f")
+	ps.Publish("player.3.score", "g")
+	assert.Equal(t, 2, len(ranks.getEvents()))
+	assert.Equal(t, []string{"player.2.score: d", "player.3.score: g"}, replaced.getEvents())
+
+	ps.Close()
+	assert.Equal(t, []string{"match.1.end: c"}, async.getEvents())
+	ps.UnsubscribeAll("A")
+	ps.Publish("player.4.score", "h")
+	assert.Equal(t, 2, len(replaced.getEvents()))
+	_, ok = ps.DeliveryStats("A")
+	assert.Equal(t, false, ok)
+	t.Log("--- TestMultipleSubscriptions PASSED ---")
+}
+
 func TestErrorHandling(t *testing.T) {
 	t.Log("--- Running TestErrorHandling ---")
 	ps := NewGenericPubSub[string]()
 
-	err := ps.Subscribe("s1", "a.*.c", func(s string, c string) {})
+	err := ps.Subscribe("s1", "a.b*", func(s string, c string) {})
 	assert.NotEqual(t, nil, err)
-	t.Logf("Caught expected error for invalid wildcard: %v", err)
+	t.Logf("Caught expected error for wildcard inside a segment: %v", err)
+
+	err = ps.Subscribe("s1", "a.>.c", func(s string, c string) {})
+	assert.NotEqual(t, nil, err)
+	t.Logf("Caught expected error for '>' before the last segment: %v", err)
+
+	err = ps.Subscribe("s1", "a..c", func(s string, c string) {})
+	assert.NotEqual(t, nil, err)
+	t.Logf("Caught expected error for empty segment: %v", err)
 
 	err = ps.Publish("a.*.c", "hello")
 	assert.NotEqual(t, nil, err)
 	t.Logf("Caught expected error for publishing with wildcard: %v", err)
 
+	err = ps.Publish("a.>", "hello")
+	assert.NotEqual(t, nil, err)
+	t.Logf("Caught expected error for publishing with tail wildcard: %v", err)
+
 	err = ps.Subscribe("", "a.b.c", func(s string, c string) {})
 	assert.NotEqual(t, nil, err)
 	t.Logf("Caught expected error for empty subscriber ID: %v", err)
@@ -166,6 +246,1939 @@ func TestErrorHandling(t *testing.T) {
 	t.Log("--- TestErrorHandling PASSED ---")
 }
 
+func TestSegmentWildcards(t *testing.T) {
+	t.Log("--- Running TestSegmentWildcards ---")
+	ps := NewGenericPubSub[string]()
+	single, tail, both := &recorder[string]{}, &recorder[string]{}, &recorder[string]{}
+	assert.Equal(t, nil, ps.Subscribe("single", "player.*.score", single.handle))
+	assert.Equal(t, nil, ps.Subscribe("tail", "leaderboard.>", tail.handle))
+	// 同一订阅者的多个订阅各自独立，同时匹配时各回调一次
+	assert.Equal(t, nil, ps.Subscribe("both", "leaderboard.*.topk", both.handle))
+	assert.Equal(t, nil, ps.Subscribe("both", "leaderboard.>", both.handle))
+	t.Logf("Subscribed 'single' to 'player.*.score', 'tail' to 'leaderboard.>', 'both' to both patterns")
+
+	ps.Publish("player.1.score", "a")
+	ps.Publish("player.1.rank", "b")
+	ps.Publish("player.1.score.extra", "c")
+	ps.Publish("leaderboard", "d")
+	ps.Publish("leaderboard.s1.topk", "e")
+	ps.Publish("leaderboard.s1", "f")
+
+	assert.Equal(t, []string{"player.1.score: a"}, single.getEvents())
+	assert.Equal(t, []string{"leaderboard.s1.topk: e", "leaderboard.s1: f"}, tail.getEvents())
+	assert.Equal(t, []string{"leaderboard.s1.topk: e", "leaderboard.s1.topk: e", "leaderboard.s1: f"}, both.getEvents())
+
+	// 取消一个模式后其余模式仍然有效
+	ps.Unsubscribe("both", "leaderboard.>")
+	ps.Publish("leaderboard.s2", "g")
+	ps.Publish("leaderboard.s2.topk", "h")
+	assert.Equal(t, []string{"leaderboard.s1.topk: e", "leaderboard.s1.topk: e", "leaderboard.s1: f", "leaderboard.s2.topk: h"}, both.getEvents())
+	t.Log("--- TestSegmentWildcards PASSED ---")
+}
+
+func TestAsyncDelivery(t *testing.T) {
+	t.Log("--- Running TestAsyncDelivery ---")
+	ps := NewGenericPubSub[string]()
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	slow := &recorder[string]{}
+	fast := &recorder[string]{}
+	assert.Equal(t, nil, ps.SubscribeAsync("slow", "game.>", func(subject string, content string) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		slow.handle(subject, content)
+	}, AsyncOptions{QueueSize: 2}))
+	assert.Equal(t, nil, ps.Subscribe("fast", "game.>", fast.handle))
+	t.Log("Subscribed 'slow' asynchronously with queue size 2 and 'fast' synchronously")
+
+	// 慢订阅者阻塞在第一条消息上时，发布者与同步订阅者不受影响，超出队列容量的消息被丢弃
+	assert.Equal(t, nil, ps.Publish("game.0", "data"))
+	<-started
+	for i := 1; i < 5; i++ {
+		assert.Equal(t, nil, ps.Publish(fmt.Sprintf("game.%d", i), "data"))
+	}
+	assert.Equal(t, 5, len(fast.getEvents()))
+	stats, ok := ps.DeliveryStats("slow")
+	t.Logf("Delivery stats of 'slow': %+v", stats)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, DeliveryStats{Queued: 2, Capacity: 2, Dropped: 2}, stats)
+
+	_, ok = ps.DeliveryStats("fast")
+	assert.Equal(t, false, ok)
+
+	// Close 等待队列中的消息处理完毕，之后的消息被丢弃
+	close(release)
+	ps.Close()
+	assert.Equal(t, []string{"game.0: data", "game.1: data", "game.2: data"}, slow.getEvents())
+	ps.Publish("game.5", "data")
+	assert.Equal(t, 3, len(slow.getEvents()))
+	t.Log("--- TestAsyncDelivery PASSED ---")
+}
+
+func TestAsyncDeliveryBlockWhenFull(t *testing.T) {
+	t.Log("--- Running TestAsyncDeliveryBlockWhenFull ---")
+	ps := NewPubSubWithMiddleware[string]()
+	ps.Use(func(subject string, content string, next Handler[string]) {
+		next(subject, "mw-"+content)
+	})
+	r := &recorder[string]{}
+	var order []string
+	assert.Equal(t, nil, ps.SubscribeAsync("A", "order.*", func(subject string, content string) {
+		order = append(order, subject) // 只在处理协程中访问
+		r.handle(subject, content)
+	}, AsyncOptions{QueueSize: 1, BlockWhenFull: true}))
+
+	numMessages := 50
+	for i := 0; i < numMessages; i++ {
+		ps.Publish(fmt.Sprintf("order.%02d", i), "data")
+	}
+	stats, _ := ps.DeliveryStats("A")
+	assert.Equal(t, int64(0), stats.Dropped)
+	ps.Close()
+
+	assert.Equal(t, numMessages, len(order))
+	assert.Equal(t, true, sort.StringsAreSorted(order))
+	assert.Equal(t, "order.00: mw-data", r.getEvents()[0])
+
+	// 取消订阅后处理协程停止，不再接收消息
+	ps.UnsubscribeAll("A")
+	ps.Publish("order.99", "data")
+	assert.Equal(t, numMessages, len(r.getEvents()))
+	t.Log("--- TestAsyncDeliveryBlockWhenFull PASSED ---")
+}
+
+func TestAsyncPerSubjectOrdering(t *testing.T) {
+	t.Log("--- Running TestAsyncPerSubjectOrdering ---")
+	opts := AsyncOptions{Workers: 4, BlockWhenFull: true}
+	// 找到两个落在不同处理协程上的主题
+	probe := newAsyncWorker(func(delivery[string]) {}, opts)
+	slow, fast := "order.0", ""
+	for i := 1; fast == ""; i++ {
+		if s := fmt.Sprintf("order.%d", i); probe.queueFor(s) != probe.queueFor(slow) {
+			fast = s
+		}
+	}
+	probe.stop()
+
+	ps := NewGenericPubSub[int]()
+	var mu sync.Mutex
+	seqs := map[string][]int{}
+	fastDone := make(chan struct{})
+	assert.Equal(t, nil, ps.SubscribeAsync("A", "order.*", func(subject string, n int) {
+		// slow 主题的第一条消息等 fast 主题处理完，不同主题之间不会互相阻塞
+		if subject == slow && n == 0 {
+			<-fastDone
+		}
+		mu.Lock()
+		seqs[subject] = append(seqs[subject], n)
+		mu.Unlock()
+		if subject == fast && n == 99 {
+			close(fastDone)
+		}
+	}, opts))
+
+	subjects := []string{slow, fast, "order.x", "order.y"}
+	for i := 0; i < 100; i++ {
+		for _, subject := range subjects {
+			assert.Equal(t, nil, ps.Publish(subject, i))
+		}
+	}
+	stats, _ := ps.DeliveryStats("A")
+	assert.Equal(t, 4*DefaultQueueSize, stats.Capacity)
+	ps.Close()
+
+	// 每个主题内按发布顺序处理
+	for _, subject := range subjects {
+		seq := seqs[subject]
+		assert.Equal(t, 100, len(seq))
+		for i, n := range seq {
+			if n != i {
+				t.Fatalf("%s: message %d handled out of order: got %d", subject, i, n)
+			}
+		}
+	}
+	t.Log("--- TestAsyncPerSubjectOrdering PASSED ---")
+}
+
+func TestAckDelivery(t *testing.T) {
+	t.Log("--- Running TestAckDelivery ---")
+	ps := NewPubSubWithMiddleware[string]()
+	ps.Use(func(subject string, content string, next Handler[string]) {
+		next(subject, "mw-"+content)
+	})
+	got := make(chan *Msg[string], 10)
+	dead := make(chan *Msg[string], 10)
+	assert.Equal(t, nil, ps.SubscribeAck("A", "job.*", func(msg *Msg[string]) {
+		got <- msg
+	}, AckOptions[string]{AckTimeout: 50 * time.Millisecond, MaxDeliver: 3, OnDeadLetter: func(msg *Msg[string]) {
+		dead <- msg
+	}}))
+	next := func() *Msg[string] {
+		select {
+		case msg := <-got:
+			t.Logf("Received %s: %s (attempt %d)", msg.Subject, msg.Content, msg.Attempt)
+			return msg
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for delivery")
+			return nil
+		}
+	}
+
+	// 确认后不再投递，重复确认无影响
+	ps.Publish("job.ack", "data")
+	msg := next()
+	assert.Equal(t, "job.ack", msg.Subject)
+	assert.Equal(t, "mw-data", msg.Content)
+	assert.Equal(t, 1, msg.Attempt)
+	msg.Ack()
+	msg.Nak()
+
+	// Nak 立即重新投递，达到最多投递次数后转入死信
+	ps.Publish("job.nak", "data")
+	for attempt := 1; attempt <= 3; attempt++ {
+		msg = next()
+		assert.Equal(t, "job.nak", msg.Subject)
+		assert.Equal(t, "mw-data", msg.Content) // 重新投递时中间件只应用一次
+		assert.Equal(t, attempt, msg.Attempt)
+		msg.Nak()
+	}
+	select {
+	case msg = <-dead:
+		assert.Equal(t, 3, msg.Attempt)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for dead letter")
+	}
+
+	// 超时未确认视为 Nak，确认后不再投递
+	ps.Publish("job.timeout", "data")
+	assert.Equal(t, 1, next().Attempt)
+	msg = next()
+	assert.Equal(t, "job.timeout", msg.Subject)
+	assert.Equal(t, 2, msg.Attempt)
+	msg.Ack()
+	select {
+	case msg = <-got:
+		t.Fatalf("unexpected redelivery of %s (attempt %d)", msg.Subject, msg.Attempt)
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	stats, ok := ps.DeliveryStats("A")
+	t.Logf("Delivery stats of 'A': %+v", stats)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, int64(3), stats.Redelivered)
+	assert.Equal(t, int64(1), stats.DeadLettered)
+
+	// 取消订阅后等待中的确认被取消，不再重新投递
+	ps.Publish("job.close", "data")
+	next()
+	ps.UnsubscribeAll("A")
+	select {
+	case msg = <-got:
+		t.Fatalf("unexpected redelivery of %s (attempt %d)", msg.Subject, msg.Attempt)
+	case <-time.After(150 * time.Millisecond):
+	}
+	assert.Equal(t, 0, len(dead))
+	t.Log("--- TestAckDelivery PASSED ---")
+}
+
+func TestRetryDelivery(t *testing.T) {
+	t.Log("--- Running TestRetryDelivery ---")
+	ps := NewGenericPubSub[string]()
+	var mu sync.Mutex
+	attempts := map[string][]time.Time{}
+	done := make(chan string, 10)
+	assert.Equal(t, nil, ps.SubscribeE("A", "job.*", func(subject string, content string) error {
+		mu.Lock()
+		attempts[subject] = append(attempts[subject], time.Now())
+		n := len(attempts[subject])
+		mu.Unlock()
+		switch {
+		case subject == "job.panic":
+			panic("boom")
+		case subject == "job.flaky" && n < 2:
+			return fmt.Errorf("attempt %d failed", n)
+		case subject == "job.fail":
+			return fmt.Errorf("always fails")
+		}
+		done <- subject
+		return nil
+	}, RetryOptions{MaxAttempts: 3, InitialBackoff: 20 * time.Millisecond, DeadLetterSubject: "dlq"}))
+	dead := &recorder[string]{}
+	assert.Equal(t, nil, ps.Subscribe("inspector", "dlq.>", dead.handle))
+
+	// 失败一次后重试成功
+	assert.Equal(t, nil, ps.Publish("job.flaky", "data"))
+	select {
+	case subject := <-done:
+		assert.Equal(t, "job.flaky", subject)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for retry")
+	}
+
+	// 返回错误与 panic 都重试 MaxAttempts 次，之后以 "dlq.<原主题>" 发布死信
+	assert.Equal(t, nil, ps.Publish("job.fail", "data"))
+	assert.Equal(t, nil, ps.Publish("job.panic", "data"))
+	deadline := time.Now().Add(time.Second)
+	for len(dead.getEvents()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, []string{"dlq.job.fail: data", "dlq.job.panic: data"}, dead.getEvents())
+
+	mu.Lock()
+	assert.Equal(t, 2, len(attempts["job.flaky"]))
+	assert.Equal(t, 3, len(attempts["job.fail"]))
+	assert.Equal(t, 3, len(attempts["job.panic"]))
+	// 指数退避：第二次重试的等待时间是第一次的两倍
+	fail := attempts["job.fail"]
+	assert.Equal(t, true, fail[1].Sub(fail[0]) >= 20*time.Millisecond)
+	assert.Equal(t, true, fail[2].Sub(fail[1]) >= 40*time.Millisecond)
+	mu.Unlock()
+
+	stats, _ := ps.DeliveryStats("A")
+	t.Logf("Delivery stats of 'A': %+v", stats)
+	assert.Equal(t, int64(5), stats.Redelivered)
+	assert.Equal(t, int64(2), stats.DeadLettered)
+
+	assert.NotEqual(t, nil, ps.SubscribeE("B", "job.*", func(string, string) error { return nil }, RetryOptions{DeadLetterSubject: "dlq.*"}))
+	ps.Close()
+	t.Log("--- TestRetryDelivery PASSED ---")
+}
+
+func TestMessageLog(t *testing.T) {
+	t.Log("--- Running TestMessageLog ---")
+	dir := t.TempDir()
+	ps := NewGenericPubSub[string]()
+	assert.Equal(t, ErrLogDisabled, ps.SubscribeFrom("A", "order.>", FromOffset(0), func(string, string) {}))
+	assert.Equal(t, nil, ps.EnableLog(LogOptions{Dir: dir, SegmentBytes: 200, Subjects: []string{"order.>"}}))
+
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, nil, ps.Publish("order.a", fmt.Sprintf("a%d", i)))
+		if i < 3 {
+			assert.Equal(t, nil, ps.Publish("order.b", fmt.Sprintf("b%d", i)))
+		}
+	}
+	assert.Equal(t, nil, ps.Publish("chat.x", "not logged"))
+	mid := time.Now()
+	time.Sleep(time.Millisecond)
+	assert.Equal(t, nil, ps.Publish("order.b", "b3"))
+
+	var mu sync.Mutex
+	received := map[string][]string{}
+	handle := func(id string) Handler[string] {
+		return func(subject string, content string) {
+			mu.Lock()
+			defer mu.Unlock()
+			received[id] = append(received[id], subject+": "+content)
+		}
+	}
+	events := func(id string) []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), received[id]...)
+	}
+
+	// 按偏移量回放单个主题，之后接收实时消息
+	assert.Equal(t, nil, ps.SubscribeFrom("late", "order.a", FromOffset(3), handle("late")))
+	assert.Equal(t, []string{"order.a: a3", "order.a: a4"}, events("late"))
+	// 通配模式按发布时间顺序回放多个主题
+	assert.Equal(t, nil, ps.SubscribeFrom("all", "order.>", FromOffset(0), handle("all")))
+	assert.Equal(t, []string{
+		"order.a: a0", "order.b: b0", "order.a: a1", "order.b: b1", "order.a: a2",
+		"order.b: b2", "order.a: a3", "order.a: a4", "order.b: b3",
+	}, events("all"))
+	// 按时间回放
+	assert.Equal(t, nil, ps.SubscribeFrom("recent", "order.*", FromTime(mid), handle("recent")))
+	assert.Equal(t, []string{"order.b: b3"}, events("recent"))
+	assert.Equal(t, nil, ps.Publish("order.a", "a5"))
+	assert.Equal(t, []string{"order.a: a3", "order.a: a4", "order.a: a5"}, events("late"))
+	assert.Equal(t, "order.a: a5", events("recent")[1])
+
+	// 超过分段大小后新建分段
+	segments, _ := os.ReadDir(filepath.Join(dir, "order.a"))
+	t.Logf("order.a has %d segments", len(segments))
+	assert.Equal(t, true, len(segments) > 1)
+	ps.Close()
+
+	// 重新打开时截掉不完整的末尾，偏移量接着已有的消息递增
+	last := filepath.Join(dir, "order.a", segments[len(segments)-1].Name())
+	f, err := os.OpenFile(last, os.O_APPEND|os.O_WRONLY, 0644)
+	assert.Equal(t, nil, err)
+	f.WriteString(`{"offset":6,"ti`)
+	f.Close()
+	ps = NewGenericPubSub[string]()
+	assert.Equal(t, nil, ps.EnableLog(LogOptions{Dir: dir, SegmentBytes: 200}))
+	assert.Equal(t, nil, ps.Publish("order.a", "a6"))
+	assert.Equal(t, nil, ps.SubscribeFrom("reopen", "order.a", FromOffset(5), handle("reopen")))
+	assert.Equal(t, []string{"order.a: a5", "order.a: a6"}, events("reopen"))
+	ps.Close()
+	t.Log("--- TestMessageLog PASSED ---")
+}
+
+func TestMessageLogReplayHandoff(t *testing.T) {
+	t.Log("--- Running TestMessageLogReplayHandoff ---")
+	ps := NewGenericPubSub[int]()
+	assert.Equal(t, nil, ps.EnableLog(LogOptions{Dir: t.TempDir(), SegmentBytes: 1024}))
+	defer ps.Close()
+
+	// 回放与并发发布同时进行时，订阅者按顺序收到每条消息恰好一次
+	numMessages := 2000
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < numMessages; i++ {
+			ps.Publish("seq", i)
+		}
+	}()
+	var mu sync.Mutex
+	var got []int
+	time.Sleep(time.Millisecond)
+	assert.Equal(t, nil, ps.SubscribeFrom("A", "seq", FromOffset(0), func(subject string, n int) {
+		mu.Lock()
+		got = append(got, n)
+		mu.Unlock()
+	}))
+	wg.Wait()
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, numMessages, len(got))
+	for i, n := range got {
+		if n != i {
+			t.Fatalf("message %d out of order: got %d", i, n)
+		}
+	}
+	t.Log("--- TestMessageLogReplayHandoff PASSED ---")
+}
+
+func TestRetainedMessages(t *testing.T) {
+	t.Log("--- Running TestRetainedMessages ---")
+	ps := NewGenericPubSub[string]()
+	assert.Equal(t, nil, ps.PublishRetained("config.game", "v1"))
+	assert.Equal(t, nil, ps.PublishRetained("config.chat", "c1"))
+	assert.Equal(t, nil, ps.Publish("config.game", "plain")) // 普通发布不影响保留消息
+	assert.Equal(t, nil, ps.PublishRetained("config.game", "v2"))
+	assert.NotEqual(t, nil, ps.PublishRetained("config.*", "bad"))
+	content, ok := ps.Retained("config.game")
+	assert.Equal(t, true, ok)
+	assert.Equal(t, "v2", content)
+
+	// 新订阅者立即按主题顺序收到匹配的保留消息，之后接收实时消息
+	var events []string
+	assert.Equal(t, nil, ps.Subscribe("A", "config.*", func(subject string, content string) {
+		events = append(events, subject+": "+content)
+	}))
+	assert.Equal(t, []string{"config.chat: c1", "config.game: v2"}, events)
+	assert.Equal(t, nil, ps.PublishRetained("config.game", "v3"))
+	assert.Equal(t, []string{"config.chat: c1", "config.game: v2", "config.game: v3"}, events)
+
+	async := &recorder[string]{}
+	assert.Equal(t, nil, ps.SubscribeAsync("B", "config.game", async.handle, AsyncOptions{}))
+
+	// 删除后新订阅者不再收到
+	ps.ClearRetained("config.game")
+	_, ok = ps.Retained("config.game")
+	assert.Equal(t, false, ok)
+	later := &recorder[string]{}
+	assert.Equal(t, nil, ps.Subscribe("C", "config.game", later.handle))
+	assert.Equal(t, 0, len(later.getEvents()))
+
+	ps.Close()
+	assert.Equal(t, []string{"config.game: v3"}, async.getEvents())
+	t.Log("--- TestRetainedMessages PASSED ---")
+}
+
+func TestRequestReply(t *testing.T) {
+	t.Log("--- Running TestRequestReply ---")
+	ps := NewGenericPubSub[int]()
+	defer ps.Close()
+	_, err := ps.Request("math.double", 1, time.Second)
+	assert.Equal(t, ErrNoResponders, err)
+
+	errOdd := fmt.Errorf("odd number")
+	assert.Equal(t, nil, ps.SubscribeResponder("doubler", "math.*", func(subject string, n int) (int, error) {
+		switch {
+		case subject == "math.slow":
+			time.Sleep(200 * time.Millisecond)
+		case n < 0:
+			panic("negative")
+		case n%2 == 1:
+			return 0, errOdd
+		}
+		return n * 2, nil
+	}, AsyncOptions{}))
+	observer := &recorder[int]{}
+	assert.Equal(t, nil, ps.Subscribe("observer", "math.double", observer.handle))
+
+	result, err := ps.Request("math.double", 4, time.Second)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 8, result)
+	// 普通订阅者同样收到请求
+	assert.Equal(t, []string{"math.double: 4"}, observer.getEvents())
+
+	// 响应者返回的错误与 panic 都交给请求方
+	_, err = ps.Request("math.double", 3, time.Second)
+	assert.Equal(t, errOdd, err)
+	_, err = ps.Request("math.double", -2, time.Second)
+	assert.NotEqual(t, nil, err)
+	t.Logf("Panic reply: %v", err)
+
+	_, err = ps.Request("math.slow", 2, 50*time.Millisecond)
+	assert.Equal(t, ErrRequestTimeout, err)
+	_, err = ps.Request("math.double", 2, 0)
+	assert.NotEqual(t, nil, err)
+	t.Log("--- TestRequestReply PASSED ---")
+}
+
+type ctxKey struct{}
+
+func TestContextHandlers(t *testing.T) {
+	t.Log("--- Running TestContextHandlers ---")
+	ps := NewGenericPubSub[string]()
+	results := make(chan string, 10)
+	assert.Equal(t, nil, ps.SubscribeCtx("sync", "job.*", func(ctx context.Context, subject string, content string) {
+		if subject == "job.slow" {
+			<-ctx.Done()
+			results <- fmt.Sprintf("%s: %v", subject, ctx.Err())
+			return
+		}
+		results <- fmt.Sprintf("%s: %v", subject, ctx.Value(ctxKey{}))
+	}, CtxOptions{Timeout: 20 * time.Millisecond}))
+
+	// PublishCtx 的 ctx 传给 handler
+	ctx := context.WithValue(context.Background(), ctxKey{}, "v")
+	assert.Equal(t, nil, ps.PublishCtx(ctx, "job.fast", "data"))
+	assert.Equal(t, "job.fast: v", <-results)
+
+	// 每次调用的超时
+	start := time.Now()
+	assert.Equal(t, nil, ps.Publish("job.slow", "data"))
+	assert.Equal(t, "job.slow: "+context.DeadlineExceeded.Error(), <-results)
+	assert.Equal(t, true, time.Since(start) >= 20*time.Millisecond)
+
+	// ctx 已取消时不再投递
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, ps.PublishCtx(cancelled, "job.fast", "data"))
+	assert.Equal(t, 0, len(results))
+
+	// Close 取消异步订阅者正在执行的 handler
+	started := make(chan struct{})
+	assert.Equal(t, nil, ps.SubscribeCtx("async", "task.*", func(ctx context.Context, subject string, content string) {
+		close(started)
+		<-ctx.Done()
+		results <- fmt.Sprintf("%s: %v", subject, ctx.Err())
+	}, CtxOptions{Async: true}))
+	assert.Equal(t, nil, ps.Publish("task.long", "data"))
+	<-started
+	ps.Close()
+	assert.Equal(t, "task.long: "+context.Canceled.Error(), <-results)
+	t.Log("--- TestContextHandlers PASSED ---")
+}
+
+func TestMessageEnvelope(t *testing.T) {
+	t.Log("--- Running TestMessageEnvelope ---")
+	ps := NewPubSubWithMiddleware[string]()
+	ps.Use(func(subject string, content string, next Handler[string]) {
+		next(subject, "mw-"+content)
+	})
+	assert.Equal(t, nil, ps.EnableLog(LogOptions{Dir: t.TempDir()}))
+	got := make(chan *Message[string], 10)
+	assert.Equal(t, nil, ps.SubscribeMsg("A", "order.*", func(ctx context.Context, msg *Message[string]) {
+		got <- msg
+	}, CtxOptions{}))
+	plain := &recorder[string]{}
+	assert.Equal(t, nil, ps.Subscribe("plain", "order.*", plain.handle))
+
+	// 消息头随消息传递，ID 与发布时间自动填入
+	before := time.Now()
+	msg := NewMessage("order.created", "o1").SetHeader("trace-id", "t-1").SetHeader("schema", "v2")
+	assert.Equal(t, nil, ps.PublishMsg(context.Background(), msg))
+	received := <-got
+	assert.Equal(t, "order.created", received.Subject)
+	assert.Equal(t, "mw-o1", received.Payload)
+	assert.Equal(t, "t-1", received.Header("trace-id"))
+	assert.Equal(t, "v2", received.Header("schema"))
+	assert.NotEqual(t, "", received.ID)
+	assert.Equal(t, false, received.Time.Before(before))
+	assert.Equal(t, []string{"order.created: mw-o1"}, plain.getEvents())
+	firstID := received.ID
+
+	// 普通 Publish 同样带有 ID 与发布时间，消息头为空
+	assert.Equal(t, nil, ps.Publish("order.paid", "o1"))
+	received = <-got
+	assert.NotEqual(t, firstID, received.ID)
+	assert.Equal(t, "", received.Header("trace-id"))
+	assert.Equal(t, false, received.Time.IsZero())
+
+	// 回放保留原有的 ID、消息头与发布时间
+	assert.Equal(t, nil, ps.SubscribeMsgFrom("late", "order.created", FromOffset(0), func(ctx context.Context, msg *Message[string]) {
+		got <- msg
+	}))
+	replayed := <-got
+	assert.Equal(t, firstID, replayed.ID)
+	assert.Equal(t, "t-1", replayed.Header("trace-id"))
+	assert.Equal(t, true, replayed.Time.Equal(msg.Time))
+	assert.Equal(t, "mw-o1", replayed.Payload)
+	ps.Close()
+	t.Log("--- TestMessageEnvelope PASSED ---")
+}
+
+func TestScopedMiddleware(t *testing.T) {
+	t.Log("--- Running TestScopedMiddleware ---")
+	ps := NewGenericPubSub[string]()
+	syncRec := &recorder[string]{}
+	asyncRec := make(chan string, 10)
+	assert.Equal(t, nil, ps.Subscribe("sync", ">", syncRec.handle))
+	assert.Equal(t, nil, ps.SubscribeAsync("async", ">", func(subject string, content string) {
+		asyncRec <- subject + ": " + content
+	}, AsyncOptions{}))
+
+	// 中间件在订阅之后登记同样生效，按登记顺序执行，带模式的中间件只作用于匹配的主题
+	ps.Use(func(subject string, content string, next Handler[string]) {
+		next(subject, content+"|all")
+	})
+	assert.Equal(t, nil, ps.UseFor("leaderboard.*", func(subject string, content string, next Handler[string]) {
+		next(subject, content+"|lb")
+	}))
+	ps.Use(func(subject string, content string, next Handler[string]) {
+		if content != "drop|all" {
+			next(subject, content+"|last")
+		}
+	})
+	assert.NotEqual(t, nil, ps.UseFor("leaderboard.>.x", func(string, string, Handler[string]) {}))
+
+	assert.Equal(t, nil, ps.Publish("leaderboard.daily", "a"))
+	assert.Equal(t, nil, ps.Publish("player.login", "b"))
+	assert.Equal(t, nil, ps.Publish("player.login", "drop"))
+	want := []string{"leaderboard.daily: a|all|lb|last", "player.login: b|all|last"}
+	assert.Equal(t, want, syncRec.getEvents())
+	assert.Equal(t, want[0], <-asyncRec)
+	assert.Equal(t, want[1], <-asyncRec)
+	ps.Close()
+	assert.Equal(t, 0, len(asyncRec))
+	t.Log("--- TestScopedMiddleware PASSED ---")
+}
+
+func TestBoundedPublish(t *testing.T) {
+	t.Log("--- Running TestBoundedPublish ---")
+	ps := NewGenericPubSub[string]()
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	slow := func(subject string, content string) {
+		started <- struct{}{}
+		<-release
+	}
+	assert.Equal(t, nil, ps.SubscribeAsync("drop", "slow.drop", slow, AsyncOptions{QueueSize: 1}))
+	assert.Equal(t, nil, ps.SubscribeAsync("block", "slow.block", slow, AsyncOptions{QueueSize: 1, BlockWhenFull: true}))
+	fast := &recorder[string]{}
+	assert.Equal(t, nil, ps.Subscribe("fast", "slow.*", fast.handle))
+
+	// 处理协程取走第一条消息后阻塞，第二条占满队列
+	for _, subject := range []string{"slow.drop", "slow.block"} {
+		assert.Equal(t, nil, ps.TryPublish(subject, "1"))
+		<-started
+		assert.Equal(t, nil, ps.TryPublish(subject, "2"))
+	}
+
+	// 队列已满时 TryPublish 立即失败，消息不会发给任何订阅者
+	assert.Equal(t, ErrQueueFull, ps.TryPublish("slow.drop", "3"))
+	assert.Equal(t, ErrQueueFull, ps.TryPublish("slow.block", "3"))
+	assert.Equal(t, 4, len(fast.getEvents()))
+	assert.Equal(t, nil, ps.TryPublish("slow.other", "3"))
+
+	// 阻塞的订阅者最多等到期限；丢弃消息的订阅者不视为超时
+	begin := time.Now()
+	assert.Equal(t, ErrPublishTimeout, ps.PublishWithTimeout("slow.block", "4", 50*time.Millisecond))
+	assert.Equal(t, true, time.Since(begin) >= 50*time.Millisecond)
+	assert.Equal(t, nil, ps.PublishWithTimeout("slow.drop", "4", 50*time.Millisecond))
+	stats, _ := ps.DeliveryStats("drop")
+	assert.Equal(t, int64(1), stats.Dropped)
+
+	close(release)
+	assert.Equal(t, nil, ps.PublishWithTimeout("slow.block", "5", time.Second))
+	ps.Close()
+	t.Log("--- TestBoundedPublish PASSED ---")
+}
+
+func TestIntrospection(t *testing.T) {
+	t.Log("--- Running TestIntrospection ---")
+	ps := NewGenericPubSub[string]()
+	r := &recorder[string]{}
+	assert.Equal(t, nil, ps.Subscribe("A", "leaderboard.*", r.handle))
+	assert.Equal(t, nil, ps.Subscribe("A", "leaderboard.daily", r.handle))
+	assert.Equal(t, nil, ps.Subscribe("B", "leaderboard.>", r.handle))
+	assert.Equal(t, nil, ps.Subscribe("C", "player.login", r.handle))
+
+	assert.Equal(t, []string{"leaderboard.*", "leaderboard.>", "leaderboard.daily", "player.login"}, ps.ListSubjects())
+	assert.Equal(t, []string{"A", "B"}, ps.SubscribersOf("leaderboard.daily"))
+	assert.Equal(t, []string{"B"}, ps.SubscribersOf("leaderboard.daily.top"))
+	assert.Equal(t, []string{}, ps.SubscribersOf("guild.created"))
+	assert.Equal(t, 0, len(ps.SubscribersOf("leaderboard.*")))
+
+	_, ok := ps.TopicStats("leaderboard.daily")
+	assert.Equal(t, false, ok)
+	before := time.Now()
+	assert.Equal(t, nil, ps.Publish("leaderboard.daily", "1"))
+	assert.Equal(t, nil, ps.PublishRetained("leaderboard.daily", "2"))
+	assert.Equal(t, nil, ps.Publish("guild.created", "g"))
+	stats, ok := ps.TopicStats("leaderboard.daily")
+	assert.Equal(t, true, ok)
+	assert.Equal(t, int64(2), stats.Published)
+	assert.Equal(t, int64(6), stats.Delivered)
+	assert.Equal(t, false, stats.LastPublished.Before(before))
+	stats, _ = ps.TopicStats("guild.created")
+	assert.Equal(t, TopicStats{Published: 1, LastPublished: stats.LastPublished}, stats)
+
+	ps.UnsubscribeAll("A")
+	assert.Equal(t, []string{"leaderboard.>", "player.login"}, ps.ListSubjects())
+	t.Log("--- TestIntrospection PASSED ---")
+}
+
+func TestTopicMetrics(t *testing.T) {
+	t.Log("--- Running TestTopicMetrics ---")
+	ps := NewGenericPubSub[string]()
+	ps.OnHandlerError(func(string, string, any) {})
+	assert.Equal(t, nil, ps.Subscribe("ok", "order.*", func(subject string, content string) {}))
+	assert.Equal(t, nil, ps.Subscribe("bad", "order.paid", func(subject string, content string) {
+		panic("boom")
+	}))
+	started := make(chan struct{})
+	release := make(chan struct{})
+	assert.Equal(t, nil, ps.SubscribeAsync("slow", "order.created", func(subject string, content string) {
+		if content == "1" {
+			close(started)
+			<-release
+		}
+	}, AsyncOptions{QueueSize: 1}))
+
+	assert.Equal(t, nil, ps.Publish("order.created", "1"))
+	<-started
+	assert.Equal(t, nil, ps.Publish("order.created", "2"))
+	assert.Equal(t, nil, ps.Publish("order.created", "3"))
+	assert.Equal(t, nil, ps.Publish("order.paid", "p"))
+	close(release)
+	ps.Close()
+
+	stats := ps.Stats()
+	created := stats.Subjects["order.created"]
+	assert.Equal(t, int64(3), created.Published)
+	assert.Equal(t, int64(6), created.Delivered)
+	assert.Equal(t, int64(1), created.Dropped)
+	assert.Equal(t, int64(5), created.Handled)
+	assert.Equal(t, true, created.HandlerTime > 0)
+	paid := stats.Subjects["order.paid"]
+	assert.Equal(t, int64(1), paid.HandlerErrors)
+	assert.Equal(t, int64(2), paid.Handled)
+
+	var out strings.Builder
+	assert.Equal(t, nil, ps.WritePrometheus(&out, "bus"))
+	text := out.String()
+	for _, line := range []string{
+		"# TYPE bus_published_total counter",
+		`bus_published_total{subject="order.created"} 3`,
+		`bus_dropped_total{subject="order.created"} 1`,
+		`bus_handler_errors_total{subject="order.paid"} 1`,
+		`bus_handler_duration_seconds_bucket{subject="order.created",le="+Inf"} 5`,
+		`bus_handler_duration_seconds_count{subject="order.paid"} 2`,
+	} {
+		assert.Equal(t, true, strings.Contains(text, line+"\n"), line)
+	}
+	assert.Equal(t, `"a\"b\\c\nd"`, quoteLabel("a\"b\\c\nd"))
+	t.Log("--- TestTopicMetrics PASSED ---")
+}
+
+// memBroker 测试用的进程内 Broker，发布时同步调用匹配的订阅
+type memBroker struct {
+	mu   sync.Mutex
+	subs map[int]memBrokerSub
+	next int
+}
+
+type memBrokerSub struct {
+	tokens  []string
+	handler func(subject string, data []byte)
+}
+
+func (b *memBroker) Publish(subject string, data []byte) error {
+	b.mu.Lock()
+	var handlers []func(subject string, data []byte)
+	for _, sub := range b.subs {
+		if matchPattern(sub.tokens, strings.Split(subject, ".")) {
+			handlers = append(handlers, sub.handler)
+		}
+	}
+	b.mu.Unlock()
+	for _, h := range handlers {
+		h(subject, data)
+	}
+	return nil
+}
+
+func (b *memBroker) Subscribe(pattern string, handler func(subject string, data []byte)) (func() error, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs == nil {
+		b.subs = map[int]memBrokerSub{}
+	}
+	b.next++
+	id := b.next
+	b.subs[id] = memBrokerSub{tokens: strings.Split(pattern, "."), handler: handler}
+	return func() error {
+		b.mu.Lock()
+		delete(b.subs, id)
+		b.mu.Unlock()
+		return nil
+	}, nil
+}
+
+func TestBridge(t *testing.T) {
+	t.Log("--- Running TestBridge ---")
+	broker := &memBroker{}
+	nodeA, nodeB := NewGenericPubSub[string](), NewGenericPubSub[string]()
+	opts := BridgeOptions{Outbound: []string{"order.>"}, Inbound: []string{"order.>"}}
+	opts.NodeID = "A"
+	bridgeA, err := NewBridge(nodeA, broker, opts)
+	assert.Equal(t, nil, err)
+	opts.NodeID = "B"
+	bridgeB, err := NewBridge(nodeB, broker, opts)
+	assert.Equal(t, nil, err)
+	_, err = NewBridge(nodeA, broker, BridgeOptions{})
+	assert.NotEqual(t, nil, err)
+
+	gotA := make(chan *Message[string], 10)
+	gotB := make(chan *Message[string], 10)
+	assert.Equal(t, nil, nodeA.SubscribeMsg("a", "order.*", func(ctx context.Context, msg *Message[string]) { gotA <- msg }, CtxOptions{}))
+	assert.Equal(t, nil, nodeB.SubscribeMsg("b", "order.*", func(ctx context.Context, msg *Message[string]) { gotB <- msg }, CtxOptions{}))
+
+	// A 发布的消息转发到 B，保留ID与消息头，并标记来源
+	msg := NewMessage("order.created", "o1").SetHeader("trace-id", "t-1")
+	assert.Equal(t, nil, nodeA.PublishMsg(context.Background(), msg))
+	assert.Equal(t, "o1", (<-gotA).Payload)
+	received := <-gotB
+	assert.Equal(t, "o1", received.Payload)
+	assert.Equal(t, msg.ID, received.ID)
+	assert.Equal(t, "t-1", received.Header("trace-id"))
+	assert.Equal(t, "A", received.Header(BridgeOriginHeader))
+
+	// 不匹配 Outbound 的主题不转发；B 收到的消息不会转发回 A
+	assert.Equal(t, nil, nodeA.Publish("player.login", "p1"))
+	assert.Equal(t, nil, nodeB.Publish("order.paid", "o2"))
+	assert.Equal(t, "o2", (<-gotB).Payload)
+	assert.Equal(t, "o2", (<-gotA).Payload)
+	assert.Equal(t, nil, bridgeA.Close())
+	assert.Equal(t, nil, bridgeB.Close())
+	nodeA.Close()
+	nodeB.Close()
+	assert.Equal(t, 0, len(gotA))
+	assert.Equal(t, 0, len(gotB))
+	t.Log("--- TestBridge PASSED ---")
+}
+
+func TestNATSBroker(t *testing.T) {
+	t.Log("--- Running TestNATSBroker ---")
+	client, server := net.Pipe()
+	defer server.Close()
+	sr := bufio.NewReader(server)
+	readLine := func() string {
+		line, err := sr.ReadString('\n')
+		assert.Equal(t, nil, err)
+		return strings.TrimRight(line, "\r\n")
+	}
+
+	ready := make(chan *NATSBroker)
+	go func() {
+		b, err := newNATSBroker(client)
+		assert.Equal(t, nil, err)
+		ready <- b
+	}()
+	io.WriteString(server, "INFO {\"server_id\":\"test\"}\r\n")
+	assert.Equal(t, true, strings.HasPrefix(readLine(), "CONNECT "))
+	assert.Equal(t, "PING", readLine())
+	b := <-ready
+
+	got := make(chan string, 1)
+	go func() {
+		_, err := b.Subscribe("order.>", func(subject string, data []byte) { got <- subject + ": " + string(data) })
+		assert.Equal(t, nil, err)
+	}()
+	assert.Equal(t, "SUB order.> 1", readLine())
+	io.WriteString(server, "MSG order.created 1 5\r\nhello\r\nPING\r\n")
+	assert.Equal(t, "order.created: hello", <-got)
+	assert.Equal(t, "PONG", readLine())
+
+	go b.Publish("order.paid", []byte("o2"))
+	assert.Equal(t, "PUB order.paid 2", readLine())
+	assert.Equal(t, "o2", readLine())
+
+	// 连接断开后发布返回错误
+	server.Close()
+	<-b.done
+	assert.NotEqual(t, nil, b.Publish("order.paid", []byte("o3")))
+	assert.Equal(t, nil, b.Close())
+	t.Log("--- TestNATSBroker PASSED ---")
+}
+
+func TestRedisBroker(t *testing.T) {
+	t.Log("--- Running TestRedisBroker ---")
+	pubClient, pubServer := net.Pipe()
+	subClient, subServer := net.Pipe()
+	b := newRedisBroker(pubClient, subClient)
+	pubR, subR := bufio.NewReader(pubServer), bufio.NewReader(subServer)
+	readCommand := func(r *bufio.Reader) []string {
+		reply, err := readRedisReply(r)
+		assert.Equal(t, nil, err)
+		var args []string
+		for _, item := range reply.([]any) {
+			args = append(args, string(item.([]byte)))
+		}
+		return args
+	}
+
+	got := make(chan string, 2)
+	handler := func(subject string, data []byte) { got <- subject + ": " + string(data) }
+	var unsubscribe func() error
+	subscribed := make(chan struct{})
+	go func() {
+		var err error
+		unsubscribe, err = b.Subscribe("order.>", handler)
+		assert.Equal(t, nil, err)
+		close(subscribed)
+	}()
+	assert.Equal(t, []string{"PSUBSCRIBE", "order.*"}, readCommand(subR))
+	<-subscribed
+	io.WriteString(subServer, "*3\r\n$10\r\npsubscribe\r\n$7\r\norder.*\r\n:1\r\n")
+	io.WriteString(subServer, "*4\r\n$8\r\npmessage\r\n$7\r\norder.*\r\n$13\r\norder.created\r\n$5\r\nhello\r\n")
+	assert.Equal(t, "order.created: hello", <-got)
+
+	published := make(chan error)
+	go func() { published <- b.Publish("order.paid", []byte("o2")) }()
+	assert.Equal(t, []string{"PUBLISH", "order.paid", "o2"}, readCommand(pubR))
+	io.WriteString(pubServer, ":1\r\n")
+	assert.Equal(t, nil, <-published)
+
+	go unsubscribe()
+	assert.Equal(t, []string{"PUNSUBSCRIBE", "order.*"}, readCommand(subR))
+	assert.Equal(t, `a\?\[b\].*.c`, redisPattern("a?[b].*.c"))
+	pubServer.Close()
+	subServer.Close()
+	b.Close()
+	t.Log("--- TestRedisBroker PASSED ---")
+}
+
+func TestGateway(t *testing.T) {
+	t.Log("--- Running TestGateway ---")
+	ps := NewGenericPubSub[map[string]int]()
+	gw, err := NewGateway(ps, GatewayOptions{
+		Publishable:  []string{"chat.*"},
+		CanSubscribe: func(r *http.Request, pattern string) bool { return !strings.HasPrefix(pattern, "admin") },
+	})
+	assert.Equal(t, nil, err)
+	server := httptest.NewServer(gw)
+	defer server.Close()
+
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http"), "", server.URL)
+	assert.Equal(t, nil, err)
+	call := func(frame gatewayFrame) gatewayFrame {
+		assert.Equal(t, nil, websocket.JSON.Send(ws, frame))
+		var reply gatewayFrame
+		assert.Equal(t, nil, websocket.JSON.Receive(ws, &reply))
+		return reply
+	}
+
+	assert.Equal(t, gatewayFrame{Op: "ok", ID: "1"}, call(gatewayFrame{Op: "sub", ID: "1", Subject: "leaderboard.*"}))
+	assert.Equal(t, "error", call(gatewayFrame{Op: "sub", ID: "2", Subject: "admin.>"}).Op)
+	assert.Equal(t, "error", call(gatewayFrame{Op: "pub", ID: "3", Subject: "leaderboard.daily", Payload: json.RawMessage(`{"a":1}`)}).Op)
+	assert.Equal(t, []string{"_WS.1"}, ps.SubscribersOf("leaderboard.daily"))
+
+	// 服务端发布的消息推送给客户端
+	assert.Equal(t, nil, ps.PublishMsg(context.Background(), NewMessage("leaderboard.daily", map[string]int{"p1": 100}).SetHeader("v", "2")))
+	var frame gatewayFrame
+	assert.Equal(t, nil, websocket.JSON.Receive(ws, &frame))
+	assert.Equal(t, "msg", frame.Op)
+	assert.Equal(t, "leaderboard.daily", frame.Subject)
+	assert.Equal(t, "2", frame.Headers["v"])
+	assert.Equal(t, `{"p1":100}`, string(frame.Payload))
+
+	// 客户端向允许的主题发布
+	got := make(chan map[string]int, 1)
+	assert.Equal(t, nil, ps.Subscribe("server", "chat.*", func(subject string, content map[string]int) { got <- content }))
+	assert.Equal(t, gatewayFrame{Op: "ok", ID: "4"}, call(gatewayFrame{Op: "pub", ID: "4", Subject: "chat.room1", Payload: json.RawMessage(`{"hi":1}`)}))
+	assert.Equal(t, map[string]int{"hi": 1}, <-got)
+
+	// 断开连接后取消该连接的所有订阅
+	ws.Close()
+	deadline := time.Now().Add(time.Second)
+	for len(ps.SubscribersOf("leaderboard.daily")) > 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.Equal(t, []string{}, ps.SubscribersOf("leaderboard.daily"))
+	gw.Close()
+	ps.Close()
+	t.Log("--- TestGateway PASSED ---")
+}
+
+func TestGatewayDefaults(t *testing.T) {
+	t.Log("--- Running TestGatewayDefaults ---")
+	ps := NewGenericPubSub[int]()
+	defer ps.Close()
+	dial := func(opts GatewayOptions, origin string) (*websocket.Conn, func(), error) {
+		gw, err := NewGateway(ps, opts)
+		assert.Equal(t, nil, err)
+		server := httptest.NewServer(gw)
+		if origin == "" {
+			origin = server.URL
+		}
+		ws, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http"), "", origin)
+		return ws, func() { gw.Close(); server.Close() }, err
+	}
+	call := func(ws *websocket.Conn, frame gatewayFrame) string {
+		assert.Equal(t, nil, websocket.JSON.Send(ws, frame))
+		var reply gatewayFrame
+		assert.Equal(t, nil, websocket.JSON.Receive(ws, &reply))
+		return reply.Op
+	}
+
+	// 未设置 CheckOrigin 时拒绝跨源握手
+	_, closeGateway, err := dial(GatewayOptions{}, "http://evil.example/")
+	assert.NotEqual(t, nil, err)
+	closeGateway()
+
+	// 未设置 Subscribable 与 CanSubscribe 时不能订阅任何模式
+	ws, closeGateway, err := dial(GatewayOptions{}, "")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "error", call(ws, gatewayFrame{Op: "sub", ID: "1", Subject: "leaderboard.daily"}))
+	assert.Equal(t, "error", call(ws, gatewayFrame{Op: "sub", ID: "2", Subject: ">"}))
+	assert.Equal(t, []string{}, ps.SubscribersOf("leaderboard.daily"))
+	ws.Close()
+	closeGateway()
+
+	// Subscribable 只允许被覆盖的模式，与 CanSubscribe 同时设置时须两者都允许
+	_, err = NewGateway(ps, GatewayOptions{Subscribable: []string{"a.>.b"}})
+	assert.NotEqual(t, nil, err)
+	ws, closeGateway, err = dial(GatewayOptions{
+		Subscribable: []string{"leaderboard.>"},
+		CanSubscribe: func(r *http.Request, pattern string) bool { return pattern != "leaderboard.secret" },
+		CheckOrigin:  func(r *http.Request) bool { return true },
+	}, "http://other.example/")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "ok", call(ws, gatewayFrame{Op: "sub", ID: "1", Subject: "leaderboard.*"}))
+	assert.Equal(t, "ok", call(ws, gatewayFrame{Op: "sub", ID: "2", Subject: "leaderboard.weekly.>"}))
+	assert.Equal(t, "error", call(ws, gatewayFrame{Op: "sub", ID: "3", Subject: ">"}))
+	assert.Equal(t, "error", call(ws, gatewayFrame{Op: "sub", ID: "4", Subject: "admin.users"}))
+	assert.Equal(t, "error", call(ws, gatewayFrame{Op: "sub", ID: "5", Subject: "leaderboard.secret"}))
+	ws.Close()
+	closeGateway()
+	t.Log("--- TestGatewayDefaults PASSED ---")
+}
+
+func TestSubscribeWithTTL(t *testing.T) {
+	t.Log("--- Running TestSubscribeWithTTL ---")
+	ps := NewGenericPubSub[string]()
+	r := &recorder[string]{}
+	assert.NotEqual(t, nil, ps.SubscribeWithTTL("watcher", "match.1", r.handle, 0))
+	assert.Equal(t, nil, ps.SubscribeWithTTL("watcher", "match.1", r.handle, 50*time.Millisecond))
+	assert.Equal(t, nil, ps.SubscribeWithTTL("watcher", "match.2", r.handle, 50*time.Millisecond))
+	// 过期前重新订阅，新的订阅不受原来的过期影响
+	assert.Equal(t, nil, ps.Subscribe("watcher", "match.2", r.handle))
+	assert.Equal(t, nil, ps.Publish("match.1", "kill"))
+	assert.Equal(t, []string{"watcher"}, ps.SubscribersOf("match.1"))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(ps.SubscribersOf("match.1")) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, []string{}, ps.SubscribersOf("match.1"))
+	assert.Equal(t, nil, ps.Publish("match.1", "ignored"))
+	assert.Equal(t, nil, ps.Publish("match.2", "goal"))
+	assert.Equal(t, []string{"match.1: kill", "match.2: goal"}, r.getEvents())
+	t.Log("--- TestSubscribeWithTTL PASSED ---")
+}
+
+func TestSubscribeOnce(t *testing.T) {
+	t.Log("--- Running TestSubscribeOnce ---")
+	ps := NewGenericPubSub[int]()
+	var calls atomic.Int32
+	assert.Equal(t, nil, ps.SubscribeOnce("once", "score.*", func(subject string, content int) {
+		calls.Add(1)
+	}))
+
+	// 并发发布时 handler 只被调用一次，之后订阅已被取消
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ps.Publish("score.p"+fmt.Sprint(i), i)
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, []string{}, ps.SubscribersOf("score.p1"))
+
+	// 订阅时收到保留消息也会取消订阅
+	assert.Equal(t, nil, ps.PublishRetained("config.version", 7))
+	got := make(chan int, 2)
+	assert.Equal(t, nil, ps.SubscribeOnce("once", "config.*", func(subject string, content int) { got <- content }))
+	assert.Equal(t, 7, <-got)
+	assert.Equal(t, nil, ps.Publish("config.version", 8))
+	assert.Equal(t, 0, len(got))
+	assert.Equal(t, []string{}, ps.ListSubjects())
+	t.Log("--- TestSubscribeOnce PASSED ---")
+}
+
+func TestSubscribeFiltered(t *testing.T) {
+	t.Log("--- Running TestSubscribeFiltered ---")
+	ps := NewGenericPubSub[int]()
+	r := &recorder[int]{}
+	assert.NotEqual(t, nil, ps.SubscribeFiltered("high", "score.*", nil, r.handle))
+	assert.Equal(t, nil, ps.SubscribeFiltered("high", "score.*", func(subject string, score int) bool {
+		return score > 1000
+	}, r.handle))
+
+	for _, score := range []int{500, 1500, 1000, 2000} {
+		assert.Equal(t, nil, ps.Publish("score.p1", score))
+	}
+	assert.Equal(t, []string{"score.p1: 1500", "score.p1: 2000"}, r.getEvents())
+	t.Log("--- TestSubscribeFiltered PASSED ---")
+}
+
+func TestSubscribeBatch(t *testing.T) {
+	t.Log("--- Running TestSubscribeBatch ---")
+	ps := NewGenericPubSub[int]()
+	batches := make(chan []int, 10)
+	assert.Equal(t, nil, ps.SubscribeBatch("scores", "score.*", func(batch []Message[int]) {
+		var contents []int
+		for _, msg := range batch {
+			contents = append(contents, msg.Payload)
+		}
+		batches <- contents
+	}, BatchOptions{MaxSize: 3, MaxWait: 200 * time.Millisecond}))
+
+	// 攒满 MaxSize 条立即交给 handler，剩余的在 MaxWait 后交给 handler
+	for i := 1; i <= 7; i++ {
+		assert.Equal(t, nil, ps.Publish("score.p1", i))
+	}
+	assert.Equal(t, []int{1, 2, 3}, <-batches)
+	assert.Equal(t, []int{4, 5, 6}, <-batches)
+	begin := time.Now()
+	assert.Equal(t, []int{7}, <-batches)
+	assert.Equal(t, true, time.Since(begin) < time.Second)
+
+	// Close 时未满的一批同样交给 handler
+	assert.Equal(t, nil, ps.Publish("score.p2", 8))
+	assert.Equal(t, nil, ps.Publish("score.p3", 9))
+	ps.Close()
+	assert.Equal(t, []int{8, 9}, <-batches)
+	assert.Equal(t, 0, len(batches))
+	t.Log("--- TestSubscribeBatch PASSED ---")
+}
+
+func TestDrain(t *testing.T) {
+	t.Log("--- Running TestDrain ---")
+	ps := NewGenericPubSub[int]()
+	release := make(chan struct{})
+	var handled atomic.Int64
+	assert.Equal(t, nil, ps.SubscribeAsync("slow", "score.*", func(subject string, content int) {
+		<-release
+		handled.Add(1)
+	}, AsyncOptions{QueueSize: 10}))
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, nil, ps.Publish("score.p1", i))
+	}
+	assert.Equal(t, nil, ps.PublishRetained("config.max", 100))
+
+	// 队列中的消息没有处理完时 Drain 等到 ctx 结束，但已经不再接受发布与订阅
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, ps.Drain(ctx))
+	assert.Equal(t, ErrClosed, ps.Publish("score.p1", 4))
+	assert.Equal(t, ErrClosed, ps.PublishRetained("config.max", 200))
+	assert.Equal(t, ErrClosed, ps.Subscribe("late", "score.*", func(string, int) {}))
+
+	close(release)
+	assert.Equal(t, nil, ps.Drain(context.Background()))
+	assert.Equal(t, int64(3), handled.Load())
+	assert.Equal(t, 0, len(ps.ListSubjects()))
+	_, ok := ps.Retained("config.max")
+	assert.Equal(t, false, ok)
+	stats, _ := ps.TopicStats("score.p1")
+	assert.Equal(t, int64(3), stats.Published)
+
+	// 重复关闭是安全的
+	ps.Close()
+	assert.Equal(t, nil, ps.Drain(context.Background()))
+	t.Log("--- TestDrain PASSED ---")
+}
+
+func TestDrainWaitsForPublish(t *testing.T) {
+	t.Log("--- Running TestDrainWaitsForPublish ---")
+	ps := NewGenericPubSub[int]()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	assert.Equal(t, nil, ps.Subscribe("sync", "score.p1", func(subject string, content int) {
+		close(started)
+		<-release
+	}))
+	assert.Equal(t, nil, ps.SubscribeResponder("calc", "calc.double", func(subject string, content int) (int, error) {
+		return content * 2, nil
+	}, AsyncOptions{}))
+
+	published := make(chan error, 1)
+	go func() { published <- ps.Publish("score.p1", 1) }()
+	<-started
+
+	drained := make(chan error, 1)
+	go func() { drained <- ps.Drain(context.Background()) }()
+	select {
+	case <-drained:
+		t.Fatal("Drain returned before the in-flight publish")
+	case <-time.After(50 * time.Millisecond):
+	}
+	_, err := ps.Request("calc.double", 2, time.Second)
+	assert.Equal(t, ErrClosed, err)
+
+	close(release)
+	assert.Equal(t, nil, <-published)
+	assert.Equal(t, nil, <-drained)
+	t.Log("--- TestDrainWaitsForPublish PASSED ---")
+}
+
+func TestBusTopics(t *testing.T) {
+	t.Log("--- Running TestBusTopics ---")
+	type scoreEvent struct {
+		Player string
+		Score  int
+	}
+	bus := NewBus()
+	scores, err := RegisterTopic[scoreEvent](bus, "score.>")
+	assert.Equal(t, nil, err)
+	chat, err := RegisterTopic[string](bus, "chat.*")
+	assert.Equal(t, nil, err)
+
+	// 类型不同的模式不能重叠，同一类型可以
+	_, err = RegisterTopic[int](bus, "score.daily")
+	assert.NotEqual(t, nil, err)
+	_, err = RegisterTopic[string](bus, "chat.room1")
+	assert.Equal(t, nil, err)
+
+	var events []string
+	assert.Equal(t, nil, scores.Subscribe("rank", "score.*", func(subject string, e scoreEvent) {
+		events = append(events, fmt.Sprintf("%s: %s %d", subject, e.Player, e.Score))
+	}))
+	// 订阅模式比主题宽时只收到主题范围内的消息
+	assert.Equal(t, nil, chat.Subscribe("audit", ">", func(subject string, content string) {
+		events = append(events, subject+": "+content)
+	}))
+	assert.NotEqual(t, nil, chat.Subscribe("audit", "score.*", func(string, string) {}))
+
+	assert.Equal(t, nil, scores.Publish("score.daily", scoreEvent{Player: "p1", Score: 100}))
+	assert.Equal(t, nil, chat.Publish("chat.room1", "hello"))
+	assert.NotEqual(t, nil, chat.Publish("score.daily", "oops"))
+	assert.Equal(t, []string{"score.daily: p1 100", "chat.room1: hello"}, events)
+
+	// 所有主题共用订阅与指标
+	assert.Equal(t, []string{">", "score.*"}, bus.ListSubjects())
+	assert.Equal(t, int64(2), bus.Stats().Published)
+	bus.Close()
+	assert.Equal(t, ErrClosed, chat.Publish("chat.room1", "bye"))
+	t.Log("--- TestBusTopics PASSED ---")
+}
+
+func TestOverflowPolicies(t *testing.T) {
+	t.Log("--- Running TestOverflowPolicies ---")
+	ps := NewGenericPubSub[int]()
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	var mu sync.Mutex
+	handled := map[string][]int{}
+	slow := func(subject string, content int) {
+		if content == 0 {
+			started <- struct{}{}
+			<-release
+		}
+		mu.Lock()
+		handled[subject] = append(handled[subject], content)
+		mu.Unlock()
+	}
+	var overflowed []int
+	assert.Equal(t, nil, ps.SubscribeAsync("oldest", "q.oldest", slow, AsyncOptions{QueueSize: 2, Overflow: OverflowDropOldest}))
+	assert.Equal(t, nil, ps.SubscribeAsync("callback", "q.callback", slow, AsyncOptions{
+		QueueSize: 2,
+		Overflow:  OverflowCallback,
+		OnOverflow: func(subject string, content any) {
+			overflowed = append(overflowed, content.(int))
+		},
+	}))
+
+	// 处理协程阻塞在第一条消息上，其后 2 条入队，再发布的 2 条溢出
+	for _, subject := range []string{"q.oldest", "q.callback"} {
+		assert.Equal(t, nil, ps.Publish(subject, 0))
+		<-started
+		for i := 1; i <= 4; i++ {
+			assert.Equal(t, nil, ps.Publish(subject, i))
+		}
+	}
+	assert.Equal(t, []int{3, 4}, overflowed)
+	assert.Equal(t, int64(4), ps.Stats().Dropped)
+
+	close(release)
+	ps.Close()
+	assert.Equal(t, []int{0, 3, 4}, handled["q.oldest"])
+	assert.Equal(t, []int{0, 1, 2}, handled["q.callback"])
+	t.Log("--- TestOverflowPolicies PASSED ---")
+}
+
+func TestWatchSlowSubscribers(t *testing.T) {
+	t.Log("--- Running TestWatchSlowSubscribers ---")
+	ps := NewGenericPubSub[int]()
+	release := make(chan struct{})
+	blocked := func(subject string, content int) { <-release }
+	assert.Equal(t, nil, ps.SubscribeAsync("backlog", "q.backlog", blocked, AsyncOptions{QueueSize: 10}))
+	assert.Equal(t, nil, ps.SubscribeAsync("stuck", "q.stuck", blocked, AsyncOptions{}))
+	assert.Equal(t, nil, ps.SubscribeAsync("healthy", "q.>", func(string, int) {}, AsyncOptions{}))
+
+	_, err := ps.WatchSlowSubscribers(SlowSubscriberOptions{})
+	assert.NotEqual(t, nil, err)
+
+	events := make(chan SlowSubscriberEvent, 10)
+	// 队列积压的订阅被取消，处理超时的订阅只报告
+	stopBacklog, err := ps.WatchSlowSubscribers(SlowSubscriberOptions{
+		MaxQueued: 2,
+		Sustain:   50 * time.Millisecond,
+		Interval:  10 * time.Millisecond,
+		Evict:     true,
+		OnSlow:    func(event SlowSubscriberEvent) { events <- event },
+	})
+	assert.Equal(t, nil, err)
+	defer stopBacklog()
+	stopLatency, err := ps.WatchSlowSubscribers(SlowSubscriberOptions{
+		MaxLatency: 20 * time.Millisecond,
+		Sustain:    50 * time.Millisecond,
+		Interval:   10 * time.Millisecond,
+		OnSlow: func(event SlowSubscriberEvent) {
+			if event.SubscriberID == "stuck" {
+				events <- event
+			}
+		},
+	})
+	assert.Equal(t, nil, err)
+
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, nil, ps.Publish("q.backlog", i))
+	}
+	assert.Equal(t, nil, ps.Publish("q.stuck", 0))
+
+	got := map[string]SlowSubscriberEvent{}
+	for len(got) < 2 {
+		select {
+		case event := <-events:
+			got[event.SubscriberID] = event
+		case <-time.After(2 * time.Second):
+			t.Fatalf("slow subscribers not reported, got %v", got)
+		}
+	}
+	assert.Equal(t, "q.backlog", got["backlog"].Subject)
+	assert.Equal(t, 3, got["backlog"].Queued)
+	assert.Equal(t, true, got["backlog"].Evicted)
+	assert.Equal(t, true, got["stuck"].Latency > 20*time.Millisecond)
+	assert.Equal(t, false, got["stuck"].Evicted)
+	assert.Equal(t, []string{"healthy", "stuck"}, ps.SubscribersOf("q.stuck"))
+	assert.Equal(t, []string{"healthy"}, ps.SubscribersOf("q.backlog"))
+
+	// 每次超限只报告一次
+	stopLatency()
+	stopLatency()
+	close(release)
+	ps.Close()
+	assert.Equal(t, 0, len(events))
+	t.Log("--- TestWatchSlowSubscribers PASSED ---")
+}
+
+func TestMatchCache(t *testing.T) {
+	t.Log("--- Running TestMatchCache ---")
+	ps := NewGenericPubSub[string]()
+	ps.SetMatchCacheSize(2)
+	r := &recorder[string]{}
+	assert.Equal(t, nil, ps.Subscribe("s1", "game.*", r.handle))
+	for _, subject := range []string{"game.a", "game.b", "game.a", "game.c"} {
+		assert.Equal(t, nil, ps.Publish(subject, "x"))
+	}
+	// 容量为 2 时淘汰最久未使用的 game.b
+	_, ok := ps.subs.Load().matches.get("game.b")
+	assert.Equal(t, false, ok)
+	keys, ok := ps.subs.Load().matches.get("game.a")
+	assert.Equal(t, true, ok)
+	assert.Equal(t, 1, len(keys))
+
+	// 订阅变化清空缓存，新的订阅立即生效
+	assert.Equal(t, nil, ps.Subscribe("s2", "game.>", r.handle))
+	assert.Equal(t, 0, ps.subs.Load().matches.lru.Len())
+	assert.Equal(t, nil, ps.Publish("game.a", "y"))
+	ps.Unsubscribe("s1", "game.*")
+	assert.Equal(t, nil, ps.Publish("game.a", "z"))
+	assert.Equal(t, []string{"game.a: x", "game.a: x", "game.a: y", "game.a: y", "game.a: z", "game.b: x", "game.c: x"}, r.getEvents())
+
+	// 容量小于等于 0 时关闭缓存
+	ps.SetMatchCacheSize(0)
+	assert.Equal(t, 0, ps.subs.Load().matches.lru.Len())
+	assert.Equal(t, nil, ps.Publish("game.a", "w"))
+	assert.Equal(t, 0, ps.subs.Load().matches.lru.Len())
+	t.Log("--- TestMatchCache PASSED ---")
+}
+
+func TestSubjectTreeCopyOnWrite(t *testing.T) {
+	t.Log("--- Running TestSubjectTreeCopyOnWrite ---")
+	match := func(n *subjectNode, subject string) []string {
+		matched := common.StringSet{}
+		n.match(strings.Split(subject, subjectSeparator), matched)
+		list := matched.ToList()
+		sort.Strings(list)
+		return list
+	}
+	v1 := newSubjectNode().with([]string{"game", "*"}, "a")
+	v2 := v1.with([]string{"game", ">"}, "b")
+	v3 := v2.without([]string{"game", "*"}, "a")
+
+	// 旧版本不受后续修改影响
+	assert.Equal(t, []string{"a"}, match(v1, "game.x"))
+	assert.Equal(t, []string{"a", "b"}, match(v2, "game.x"))
+	assert.Equal(t, []string{"b"}, match(v3, "game.x"))
+	assert.Equal(t, (*subjectNode)(nil), v3.without([]string{"game", ">"}, "b"))
+	t.Log("--- TestSubjectTreeCopyOnWrite PASSED ---")
+}
+
+func TestConcurrentPublishAndSubscribe(t *testing.T) {
+	t.Log("--- Running TestConcurrentPublishAndSubscribe ---")
+	ps := NewGenericPubSub[int]()
+	var stable atomic.Int64
+	assert.Equal(t, nil, ps.Subscribe("stable", "game.>", func(string, int) { stable.Add(1) }))
+
+	// 发布与订阅变化并发进行，已有的订阅不会漏收消息
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				assert.Equal(t, nil, ps.Publish(fmt.Sprintf("game.%d", j%10), j))
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 200; j++ {
+			id := fmt.Sprintf("churn%d", j%5)
+			assert.Equal(t, nil, ps.Subscribe(id, fmt.Sprintf("game.%d", j%10), func(string, int) {}))
+			ps.UnsubscribeAll(id)
+		}
+	}()
+	wg.Wait()
+	assert.Equal(t, int64(2000), stable.Load())
+	assert.Equal(t, []string{"game.>"}, ps.ListSubjects())
+	t.Log("--- TestConcurrentPublishAndSubscribe PASSED ---")
+}
+
+func TestPublishAfter(t *testing.T) {
+	t.Log("--- Running TestPublishAfter ---")
+	ps := NewGenericPubSub[string]()
+	received := make(chan string, 10)
+	assert.Equal(t, nil, ps.Subscribe("s1", "event.*", func(subject string, content string) {
+		received <- subject + ": " + content
+	}))
+
+	start := time.Now()
+	_, err := ps.PublishAfter("event.start", "go", 100*time.Millisecond)
+	assert.Equal(t, nil, err)
+	cancel, err := ps.PublishAt("event.cancelled", "never", start.Add(150*time.Millisecond))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, cancel())
+	_, err = ps.PublishAfter("event.*", "bad", time.Second)
+	assert.NotEqual(t, nil, err)
+
+	select {
+	case event := <-received:
+		assert.Equal(t, "event.start: go", event)
+		assert.Equal(t, true, time.Since(start) >= 90*time.Millisecond)
+	case <-time.After(2 * time.Second):
+		t.Fatal("delayed message was not published")
+	}
+
+	// 已过去的时间尽快发布
+	_, err = ps.PublishAt("event.late", "now", start)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "event.late: now", <-received)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 0, len(received))
+
+	ps.Close()
+	_, err = ps.PublishAfter("event.start", "closed", time.Millisecond)
+	assert.Equal(t, ErrClosed, err)
+	t.Log("--- TestPublishAfter PASSED ---")
+}
+
+func TestSchedulePublish(t *testing.T) {
+	t.Log("--- Running TestSchedulePublish ---")
+	ps := NewGenericPubSub[string]()
+
+	cancel, err := ps.SchedulePublish("0 * * * *", "leaderboard.refresh", func() string { return "refresh" })
+	assert.Equal(t, nil, err)
+	cancel()
+
+	_, err = ps.SchedulePublish("61 * * * *", "leaderboard.refresh", func() string { return "refresh" })
+	assert.NotEqual(t, nil, err)
+	_, err = ps.SchedulePublish("0 * * * *", "leaderboard.*", func() string { return "refresh" })
+	assert.NotEqual(t, nil, err)
+	_, err = ps.SchedulePublish("0 * * * *", "leaderboard.refresh", nil)
+	assert.NotEqual(t, nil, err)
+
+	ps.Close()
+	_, err = ps.SchedulePublish("0 * * * *", "leaderboard.refresh", func() string { return "refresh" })
+	assert.Equal(t, ErrClosed, err)
+	t.Log("--- TestSchedulePublish PASSED ---")
+}
+
+func TestMaxConcurrency(t *testing.T) {
+	t.Log("--- Running TestMaxConcurrency ---")
+	ps := NewGenericPubSub[string]()
+	var running, peak atomic.Int32
+	var handled sync.WaitGroup
+	handler := func(subject string, content string) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		running.Add(-1)
+		handled.Done()
+	}
+	// 同一订阅者的两个订阅共享上限，共 8 个处理协程，同时最多 2 个 handler
+	opts := AsyncOptions{Workers: 4, MaxConcurrency: 2}
+	assert.Equal(t, nil, ps.SubscribeAsync("expensive", "order.*", handler, opts))
+	assert.Equal(t, nil, ps.SubscribeAsync("expensive", "refund.*", handler, opts))
+
+	handled.Add(16)
+	for i := 0; i < 8; i++ {
+		assert.Equal(t, nil, ps.Publish(fmt.Sprintf("order.%d", i), "o"))
+		assert.Equal(t, nil, ps.Publish(fmt.Sprintf("refund.%d", i), "r"))
+	}
+	handled.Wait()
+	assert.Equal(t, int32(2), peak.Load())
+
+	ps.Unsubscribe("expensive", "order.*")
+	ps.Unsubscribe("expensive", "refund.*")
+	ps.limiters.mu.Lock()
+	assert.Equal(t, 0, len(ps.limiters.limiters))
+	ps.limiters.mu.Unlock()
+	ps.Close()
+	t.Log("--- TestMaxConcurrency PASSED ---")
+}
+
+func TestTracing(t *testing.T) {
+	t.Log("--- Running TestTracing ---")
+	ps := NewGenericPubSub[string]()
+	spans := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	ps.EnableTracing(TracingOptions{TracerProvider: provider})
+	ps.OnHandlerError(func(string, string, any) {})
+
+	// 同步 handler 的 ctx 携带 process span，继续发布时串起链路
+	assert.Equal(t, nil, ps.SubscribeCtx("scorer", "score.update", func(ctx context.Context, subject string, content string) {
+		assert.Equal(t, true, trace.SpanContextFromContext(ctx).IsValid())
+		assert.Equal(t, nil, ps.PublishCtx(ctx, "leaderboard.changed", content))
+	}, CtxOptions{}))
+	done := make(chan context.Context, 1)
+	assert.Equal(t, nil, ps.SubscribeMsg("notifier", "leaderboard.changed", func(ctx context.Context, msg *Message[string]) {
+		done <- ps.TraceContext(context.Background(), msg)
+	}, CtxOptions{Async: true}))
+	assert.Equal(t, nil, ps.Subscribe("bad", "leaderboard.changed", func(string, string) { panic("boom") }))
+
+	ctx, root := provider.Tracer("test").Start(context.Background(), "http")
+	assert.Equal(t, nil, ps.PublishCtx(ctx, "score.update", "p1"))
+	root.End()
+	remote := trace.SpanContextFromContext(<-done)
+	assert.Equal(t, root.SpanContext().TraceID(), remote.TraceID())
+	ps.Close()
+
+	byName := map[string][]sdktrace.ReadOnlySpan{}
+	for _, span := range spans.Ended() {
+		assert.Equal(t, root.SpanContext().TraceID(), span.SpanContext().TraceID())
+		byName[span.Name()] = append(byName[span.Name()], span)
+	}
+	assert.Equal(t, 1, len(byName["publish score.update"]))
+	assert.Equal(t, 1, len(byName["process score.update"]))
+	assert.Equal(t, 1, len(byName["publish leaderboard.changed"]))
+	assert.Equal(t, 2, len(byName["process leaderboard.changed"]))
+
+	publish := byName["publish score.update"][0]
+	assert.Equal(t, root.SpanContext().SpanID(), publish.Parent().SpanID())
+	assert.Equal(t, trace.SpanKindProducer, publish.SpanKind())
+	process := byName["process score.update"][0]
+	assert.Equal(t, publish.SpanContext().SpanID(), process.Parent().SpanID())
+	assert.Equal(t, process.SpanContext().SpanID(), byName["publish leaderboard.changed"][0].Parent().SpanID())
+	// 异步 handler 的父 span 从消息头恢复，panic 的 handler 标记为错误
+	changed := byName["publish leaderboard.changed"][0].SpanContext().SpanID()
+	assert.Equal(t, changed, remote.SpanID())
+	failed := 0
+	for _, span := range byName["process leaderboard.changed"] {
+		assert.Equal(t, changed, span.Parent().SpanID())
+		if span.Status().Code == codes.Error {
+			failed++
+		}
+	}
+	assert.Equal(t, 1, failed)
+	t.Log("--- TestTracing PASSED ---")
+}
+
+func TestUnsubscribePattern(t *testing.T) {
+	t.Log("--- Running TestUnsubscribePattern ---")
+	ps := NewGenericPubSub[string]()
+	r := &recorder[string]{}
+	for _, pattern := range []string{"player.1", "player.*", "player.>", "player.1.score", "player.*.score", "chat.room1"} {
+		assert.Equal(t, nil, ps.Subscribe("s1", pattern, r.handle))
+	}
+	assert.Equal(t, nil, ps.Subscribe("s2", "player.1", r.handle))
+
+	n, err := ps.UnsubscribePattern("s1", "player.*")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"player.*.score", "player.1.score", "player.>"}, sortedSubjects(ps, "s1", "player"))
+
+	n, err = ps.UnsubscribePattern("s1", "player.>")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, []string{"s2"}, ps.SubscribersOf("player.1"))
+	assert.Equal(t, []string{"s1"}, ps.SubscribersOf("chat.room1"))
+
+	n, err = ps.UnsubscribePattern("s1", "player.>")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, n)
+	_, err = ps.UnsubscribePattern("s1", "player.>.x")
+	assert.NotEqual(t, nil, err)
+	t.Log("--- TestUnsubscribePattern PASSED ---")
+}
+
+// sortedSubjects 返回订阅者以 prefix 开头的订阅模式，按字典序排列
+func sortedSubjects(ps *GenericPubSub[string], subscriberID string, prefix string) []string {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	var subjects []string
+	for subject := range ps.subscriberSubjects[subscriberID] {
+		if strings.HasPrefix(subject, prefix) {
+			subjects = append(subjects, subject)
+		}
+	}
+	sort.Strings(subjects)
+	return subjects
+}
+
+func TestSubscriberStats(t *testing.T) {
+	t.Log("--- Running TestSubscriberStats ---")
+	ps := NewGenericPubSub[string]()
+	ps.OnHandlerError(func(string, string, any) {})
+	assert.Equal(t, nil, ps.Subscribe("A", "order.*", func(subject string, content string) {}))
+	assert.Equal(t, nil, ps.Subscribe("A", "order.paid", func(subject string, content string) {}))
+	assert.Equal(t, nil, ps.Subscribe("B", "order.*", func(subject string, content string) {
+		if content == "bad" {
+			panic("boom")
+		}
+	}))
+	block := make(chan struct{})
+	assert.Equal(t, nil, ps.SubscribeAsync("C", "order.*", func(subject string, content string) { <-block }, AsyncOptions{QueueSize: 1}))
+
+	assert.Equal(t, nil, ps.Publish("order.paid", "ok"))
+	assert.Equal(t, nil, ps.Publish("order.new", "bad"))
+	assert.Equal(t, nil, ps.Publish("order.new", "ok"))
+	close(block)
+	assert.Equal(t, nil, ps.Drain(context.Background()))
+
+	stats := ps.Stats()
+	assert.Equal(t, SubscriberStats{Delivered: 4}, stats.Subscribers["A"])
+	assert.Equal(t, SubscriberStats{Delivered: 3, HandlerErrors: 1}, stats.Subscribers["B"])
+	c := stats.Subscribers["C"]
+	assert.Equal(t, int64(3), c.Delivered+c.Dropped)
+	assert.Equal(t, stats.Dropped, c.Dropped)
+
+	ps.ResetStats()
+	stats = ps.Stats()
+	assert.Equal(t, int64(0), stats.Published)
+	assert.Equal(t, 0, len(stats.Subjects))
+	assert.Equal(t, 0, len(stats.Subscribers))
+	t.Log("--- TestSubscriberStats PASSED ---")
+}
+
+func TestStatsConcurrentWithPublish(t *testing.T) {
+	t.Log("--- Running TestStatsConcurrentWithPublish ---")
+	ps := NewGenericPubSub[int]()
+	assert.Equal(t, nil, ps.Subscribe("A", "n.*", func(subject string, n int) {}))
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				ps.Publish(fmt.Sprintf("n.%d", i), j)
+			}
+		}(i)
+	}
+	for i := 0; i < 20; i++ {
+		stats := ps.Stats()
+		var published int64
+		for _, s := range stats.Subjects {
+			published += s.Published
+		}
+		// 先读全局计数再读主题计数，主题计数只会更多
+		assert.Equal(t, true, published >= stats.Published)
+		if i == 10 {
+			ps.ResetStats()
+		}
+	}
+	wg.Wait()
+	ps.Unsubscribe("A", "n.*")
+	_, ok := ps.Stats().Subscribers["A"]
+	assert.Equal(t, false, ok)
+	t.Log("--- TestStatsConcurrentWithPublish PASSED ---")
+}
+
+func TestPublishCount(t *testing.T) {
+	t.Log("--- Running TestPublishCount ---")
+	ps := NewGenericPubSub[string]()
+	ps.OnHandlerError(func(string, string, any) {})
+
+	result, err := ps.PublishCount("score.update", "nobody")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, PublishResult{}, result)
+
+	assert.Equal(t, nil, ps.SubscribeCtx("A", "score.*", func(ctx context.Context, subject string, content string) {
+		// 以同一个 ctx 继续发布的消息不计入
+		ps.PublishCtx(ctx, "audit.score", content)
+	}, CtxOptions{}))
+	assert.Equal(t, nil, ps.Subscribe("B", "score.update", func(string, string) { panic("boom") }))
+	assert.Equal(t, nil, ps.Subscribe("C", "audit.*", func(string, string) {}))
+	block := make(chan struct{})
+	assert.Equal(t, nil, ps.SubscribeAsync("D", "score.*", func(string, string) { <-block }, AsyncOptions{QueueSize: 1}))
+
+	result, err = ps.PublishCount("score.update", "s1")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, PublishResult{Matched: 3, Executed: 2, Enqueued: 1}, result)
+
+	// 异步订阅的队列满后计入 Dropped
+	dropped := 0
+	for i := 0; i < 3; i++ {
+		result, err = ps.PublishCount("score.other", "s2")
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 2, result.Matched)
+		assert.Equal(t, 1, result.Enqueued+result.Dropped)
+		dropped += result.Dropped
+	}
+	assert.Equal(t, true, dropped >= 1)
+	close(block)
+
+	_, err = ps.PublishCount("score.*", "bad")
+	assert.NotEqual(t, nil, err)
+	ps.Close()
+	t.Log("--- TestPublishCount PASSED ---")
+}
+
+func TestPublishCountStoppedSubscriber(t *testing.T) {
+	t.Log("--- Running TestPublishCountStoppedSubscriber ---")
+	ps := NewGenericPubSub[string]()
+	received := make(chan string, 4)
+	assert.Equal(t, nil, ps.SubscribeAsync("A", "score.update", func(_ string, content string) { received <- content }, AsyncOptions{QueueSize: 4}))
+
+	// 匹配之后、投递之前取消订阅：已停止的订阅者既不计入 Enqueued 也不计入 Dropped
+	ps.Intercept(func(msg *Message[string], deliver func() error) error {
+		ps.Unsubscribe("A", "score.update")
+		return deliver()
+	})
+	result, err := ps.PublishCount("score.update", "s1")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, PublishResult{Matched: 1}, result)
+	ps.Intercept(nil)
+
+	// 已停止的订阅者不是队列已满，TryPublish 不报错
+	assert.Equal(t, nil, ps.SubscribeAsync("B", "score.update", func(_ string, content string) { received <- content }, AsyncOptions{QueueSize: 4}))
+	ps.Intercept(func(msg *Message[string], deliver func() error) error {
+		ps.UnsubscribeAll("B")
+		return deliver()
+	})
+	assert.Equal(t, nil, ps.TryPublish("score.update", "s2"))
+	ps.Intercept(nil)
+
+	result, err = ps.PublishCount("score.update", "s3")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, PublishResult{}, result)
+
+	assert.Equal(t, nil, ps.SubscribeAsync("C", "score.update", func(_ string, content string) { received <- content }, AsyncOptions{QueueSize: 4}))
+	result, err = ps.PublishCount("score.update", "s4")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, PublishResult{Matched: 1, Enqueued: 1}, result)
+	assert.Equal(t, nil, ps.Drain(context.Background()))
+	assert.Equal(t, "s4", <-received)
+	assert.Equal(t, 0, len(received))
+
+	result, err = ps.PublishCount("score.update", "s5")
+	assert.Equal(t, ErrClosed, err)
+	assert.Equal(t, PublishResult{}, result)
+	t.Log("--- TestPublishCountStoppedSubscriber PASSED ---")
+}
+
+func TestACL(t *testing.T) {
+	t.Log("--- Running TestACL ---")
+	ps := NewGenericPubSub[string]()
+	assert.Equal(t, nil, ps.SetACL("tenant1", ACL{Subscribe: []string{"tenant1.>", "global.news"}, Publish: []string{"tenant1.*.score"}}))
+	assert.NotEqual(t, nil, ps.SetACL("tenant2", ACL{Subscribe: []string{"tenant2.>.x"}}))
+
+	r := &recorder[string]{}
+	assert.Equal(t, nil, ps.Subscribe("tenant1", "tenant1.*.score", r.handle))
+	assert.Equal(t, nil, ps.Subscribe("tenant1", "global.news", r.handle))
+	err := ps.Subscribe("tenant1", "tenant2.>", r.handle)
+	assert.Equal(t, true, errors.Is(err, ErrNotAllowed))
+	err = ps.Subscribe("tenant1", "global.*", r.handle)
+	assert.Equal(t, true, errors.Is(err, ErrNotAllowed))
+	err = ps.SubscribeAsync("tenant1", ">", r.handle, AsyncOptions{})
+	assert.Equal(t, true, errors.Is(err, ErrNotAllowed))
+	// 没有 ACL 的订阅者不受限制
+	assert.Equal(t, nil, ps.Subscribe("admin", ">", r.handle))
+
+	ctx := WithPublisher(context.Background(), "tenant1")
+	assert.Equal(t, nil, ps.PublishCtx(ctx, "tenant1.p1.score", "100"))
+	err = ps.PublishCtx(ctx, "tenant2.p1.score", "100")
+	assert.Equal(t, true, errors.Is(err, ErrNotAllowed))
+	err = ps.PublishMsg(ctx, NewMessage("global.news", "fake"))
+	assert.Equal(t, true, errors.Is(err, ErrNotAllowed))
+	assert.Equal(t, nil, ps.Publish("global.news", "hello"))
+	assert.Equal(t, []string{"global.news: hello", "global.news: hello", "tenant1.p1.score: 100", "tenant1.p1.score: 100"}, r.getEvents())
+
+	ps.RemoveACL("tenant1")
+	assert.Equal(t, nil, ps.PublishCtx(ctx, "tenant2.p1.score", "100"))
+	assert.Equal(t, nil, ps.Subscribe("tenant1", "tenant2.>", r.handle))
+	t.Log("--- TestACL PASSED ---")
+}
+
+type codecOrder struct {
+	ID    string
+	Items []string
+}
+
+func TestCodecs(t *testing.T) {
+	t.Log("--- Running TestCodecs ---")
+	order := codecOrder{ID: "o1", Items: []string{"sword", "shield"}}
+	for _, codec := range []Codec{JSONCodec, GobCodec} {
+		data, err := codec.Marshal(order)
+		assert.Equal(t, nil, err)
+		var decoded codecOrder
+		assert.Equal(t, nil, codec.Unmarshal(data, &decoded))
+		assert.Equal(t, order, decoded)
+	}
+	// protobuf 内容类型为 *pb.Xxx，解码时为 nil 的指针会被分配
+	data, err := ProtoCodec.Marshal(wrapperspb.String("hello"))
+	assert.Equal(t, nil, err)
+	var pb *wrapperspb.StringValue
+	assert.Equal(t, nil, ProtoCodec.Unmarshal(data, &pb))
+	assert.Equal(t, "hello", pb.GetValue())
+	_, err = ProtoCodec.Marshal(order)
+	assert.NotEqual(t, nil, err)
+
+	// EncodedPubSub 在边界编解码，底层服务只看到字节
+	raw := NewGenericPubSub[[]byte]()
+	orders := NewEncodedPubSub[codecOrder](raw, GobCodec)
+	got := make(chan codecOrder, 1)
+	assert.Equal(t, nil, orders.Subscribe("shop", "order.*", func(subject string, content codecOrder) { got <- content }))
+	assert.Equal(t, nil, orders.Publish("order.created", order))
+	assert.Equal(t, order, <-got)
+
+	// 编码不一致的消息不会交给 handler
+	var decodeErr error
+	orders.OnDecodeError(func(subscriberID, subject string, err error) { decodeErr = err })
+	assert.Equal(t, nil, NewEncodedPubSub[codecOrder](raw, JSONCodec).Publish("order.paid", order))
+	assert.NotEqual(t, nil, decodeErr)
+	assert.Equal(t, 0, len(got))
+	assert.Equal(t, int64(1), raw.Stats().Subscribers["shop"].HandlerErrors)
+	raw.Close()
+
+	// 桥接与消息日志按 Codec 编码内容
+	broker := &memBroker{}
+	nodeA, nodeB := NewGenericPubSub[codecOrder](), NewGenericPubSub[codecOrder]()
+	opts := BridgeOptions{NodeID: "A", Outbound: []string{"order.>"}, Inbound: []string{"order.>"}, Codec: GobCodec}
+	bridgeA, err := NewBridge(nodeA, broker, opts)
+	assert.Equal(t, nil, err)
+	opts.NodeID = "B"
+	bridgeB, err := NewBridge(nodeB, broker, opts)
+	assert.Equal(t, nil, err)
+	gotB := make(chan codecOrder, 1)
+	assert.Equal(t, nil, nodeB.Subscribe("b", "order.*", func(subject string, content codecOrder) { gotB <- content }))
+	assert.Equal(t, nil, nodeA.Publish("order.created", order))
+	assert.Equal(t, order, <-gotB)
+	bridgeA.Close()
+	bridgeB.Close()
+
+	dir := t.TempDir()
+	assert.Equal(t, nil, nodeA.EnableLog(LogOptions{Dir: dir, Codec: GobCodec}))
+	assert.Equal(t, nil, nodeA.Publish("order.paid", order))
+	var replayed []codecOrder
+	assert.Equal(t, nil, nodeA.SubscribeFrom("audit", "order.paid", FromOffset(0), func(subject string, content codecOrder) {
+		replayed = append(replayed, content)
+	}))
+	assert.Equal(t, []codecOrder{order}, replayed)
+	nodeA.Close()
+	nodeB.Close()
+	t.Log("--- TestCodecs PASSED ---")
+}
+
+func TestHandlerPanicIsolation(t *testing.T) {
+	t.Log("--- Running TestHandlerPanicIsolation ---")
+	ps := NewGenericPubSub[string]()
+	type panicked struct {
+		subscriberID, subject string
+		recovered             any
+	}
+	panics := make(chan panicked, 10)
+	ps.OnHandlerError(func(subscriberID string, subject string, recovered any) {
+		panics <- panicked{subscriberID, subject, recovered}
+	})
+
+	after := &recorder[string]{}
+	assert.Equal(t, nil, ps.Subscribe("bad", "order.*", func(subject string, content string) {
+		panic("boom " + content)
+	}))
+	assert.Equal(t, nil, ps.Subscribe("good", "order.*", after.handle))
+	asyncDone := make(chan string, 10)
+	assert.Equal(t, nil, ps.SubscribeAsync("badAsync", "order.*", func(subject string, content string) {
+		if content == "o1" {
+			panic("async boom")
+		}
+		asyncDone <- content
+	}, AsyncOptions{}))
+
+	// 同步 handler 的 panic 不会传到发布者，其他订阅者照常收到消息
+	assert.Equal(t, nil, ps.Publish("order.created", "o1"))
+	assert.Equal(t, []string{"order.created: o1"}, after.getEvents())
+	got := map[string]panicked{}
+	for i := 0; i < 2; i++ {
+		p := <-panics
+		got[p.subscriberID] = p
+	}
+	assert.Equal(t, panicked{"bad", "order.created", "boom o1"}, got["bad"])
+	assert.Equal(t, panicked{"badAsync", "order.created", "async boom"}, got["badAsync"])
+
+	// 异步处理协程 panic 后继续处理后续消息
+	assert.Equal(t, nil, ps.Publish("order.paid", "o2"))
+	assert.Equal(t, "o2", <-asyncDone)
+	<-panics
+	stats := ps.Stats()
+	assert.Equal(t, int64(2), stats.Published)
+	assert.Equal(t, int64(3), stats.HandlerPanics)
+	ps.Close()
+	t.Log("--- TestHandlerPanicIsolation PASSED ---")
+}
+
 func TestMiddleware(t *testing.T) {
 	t.Log("--- Running TestMiddleware ---")
 	ps := NewPubSubWithMiddleware[string]()
@@ -291,36 +2304,92 @@ func TestMiddleware(t *testing.T) {
 // 	t.Log("--- TestIsSubscribed PASSED ---")
 // }
 
-// TestAsyncPublish 已注释：NewAsyncPubSub 和 PublishAsync 方法尚未实现
-// func TestAsyncPublish(t *testing.T) {
-// 	t.Log("--- Running TestAsyncPublish ---")
-// 	ps := NewAsyncPubSub[string](2)
-// 	defer ps.Shutdown()
-//
-// 	r := &recorder[string]{}
-// 	ps.Subscribe("A", "async.topic", r.handle)
-//
-// 	// Use a wait group to wait for the handler to be called
-// 	var wg sync.WaitGroup
-// 	wg.Add(1)
-// 	originalHandler := r.handle
-// 	r.handle = func(subject string, content string) {
-// 		originalHandler(subject, content)
-// 		wg.Done()
-// 	}
-//
-// 	errChan := ps.PublishAsync("async.topic", "async_data")
-//
-// 	err := <-errChan
-// 	assert.Equal(t, nil, err)
-//
-// 	wg.Wait() // Wait for the message to be processed
-//
-// 	events := r.getEvents()
-// 	t.Logf("Recorded events: %v", events)
-// 	assert.Equal(t, []string{"async.topic: async_data"}, events)
-// 	t.Log("--- TestAsyncPublish PASSED ---")
-// }
+func TestAsyncPublish(t *testing.T) {
+	t.Log("--- Running TestAsyncPublish ---")
+	ps := NewAsyncPubSub[string](2)
+	defer ps.Shutdown()
+
+	r := &recorder[string]{}
+	ps.Subscribe("A", "async.topic", r.handle)
+
+	err := <-ps.PublishAsync("async.topic", "async_data")
+	assert.Equal(t, nil, err)
+
+	events := r.getEvents()
+	t.Logf("Recorded events: %v", events)
+	assert.Equal(t, []string{"async.topic: async_data"}, events)
+	t.Log("--- TestAsyncPublish PASSED ---")
+}
+
+func TestAsyncPubSubOptions(t *testing.T) {
+	t.Log("--- Running TestAsyncPubSubOptions ---")
+	ps := NewAsyncPubSubWithOptions[int](AsyncPubSubOptions{Workers: 4, QueueSize: 50, Affinity: true, DrainTimeout: 50 * time.Millisecond})
+	var mu sync.Mutex
+	got := map[string][]int{}
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	assert.Equal(t, nil, ps.Subscribe("A", "n.*", func(subject string, n int) {
+		if subject == "n.slow" && n == 0 {
+			started <- struct{}{}
+			<-release
+		}
+		mu.Lock()
+		got[subject] = append(got[subject], n)
+		mu.Unlock()
+	}))
+	assert.NotEqual(t, ps.queueFor("n.ordered"), ps.queueFor("n.slow"))
+
+	// 同一主题按调用顺序发布
+	var results []<-chan error
+	for i := 0; i < 50; i++ {
+		results = append(results, ps.PublishAsync("n.ordered", i))
+	}
+	for _, result := range results {
+		assert.Equal(t, nil, <-result)
+	}
+
+	// 慢主题占住一个发布协程，其队列满后返回 ErrQueueFull，其他主题不受影响
+	first := ps.PublishAsync("n.slow", 0)
+	<-started
+	var queued []<-chan error
+	for i := 1; ; i++ {
+		result := ps.PublishAsync("n.slow", i)
+		select {
+		case err := <-result:
+			assert.Equal(t, ErrQueueFull, err)
+		default:
+			queued = append(queued, result)
+			continue
+		}
+		break
+	}
+	assert.Equal(t, 50, len(queued))
+	stats := ps.Stats()
+	assert.Equal(t, 1, stats.InFlight)
+	assert.Equal(t, 50, stats.Queued)
+	assert.Equal(t, 200, stats.Capacity)
+	assert.Equal(t, nil, <-ps.PublishAsync("n.ordered", 50))
+
+	// 超时后关闭，队列中剩余的消息以 ErrClosed 失败
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+	}()
+	assert.Equal(t, ErrDrainTimeout, ps.Shutdown())
+	assert.Equal(t, nil, <-first)
+	for _, result := range queued {
+		assert.Equal(t, ErrClosed, <-result)
+	}
+	assert.Equal(t, ErrClosed, <-ps.PublishAsync("n.ordered", 51))
+
+	want := make([]int, 51)
+	for i := range want {
+		want[i] = i
+	}
+	assert.Equal(t, want, got["n.ordered"])
+	assert.Equal(t, []int{0}, got["n.slow"])
+	t.Log("--- TestAsyncPubSubOptions PASSED ---")
+}
 
 func TestConcurrentPublish(t *testing.T) {
 	t.Log("--- Running TestConcurrentPublish ---")
@@ -363,8 +2432,9 @@ func TestConcurrentPublish(t *testing.T) {
 		<-completion
 	}
 
+	// 两轮发布共用同一个 recorder
 	events := r.getEvents()
 	t.Logf("Recorded %d events", len(events))
-	assert.Equal(t, numMessages, len(events))
+	assert.Equal(t, 2*numMessages, len(events))
 	t.Log("--- TestConcurrentPublish PASSED ---")
 }
@@ -2,10 +2,23 @@ module pubsub
 
 go 1.24
 
-require github.com/bmizerany/assert v0.0.0-20160611221934-b7ed37b82869
+require (
+	github.com/bmizerany/assert v0.0.0-20160611221934-b7ed37b82869
+	go.opentelemetry.io/otel v1.37.0
+	go.opentelemetry.io/otel/sdk v1.37.0
+	go.opentelemetry.io/otel/trace v1.37.0
+	golang.org/x/net v0.42.0
+	google.golang.org/protobuf v1.36.9
+)
 
 require (
+	github.com/go-logr/logr v1.4.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
 	github.com/kr/pretty v0.3.1 // indirect
 	github.com/kr/text v0.2.0 // indirect
-	github.com/rogpeppe/go-internal v1.9.0 // indirect
+	github.com/rogpeppe/go-internal v1.13.1 // indirect
+	go.opentelemetry.io/auto/sdk v1.1.0 // indirect
+	go.opentelemetry.io/otel/metric v1.37.0 // indirect
+	golang.org/x/sys v0.34.0 // indirect
 )
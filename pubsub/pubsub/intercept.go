@@ -0,0 +1,15 @@
+package pubsub
+
+// DeliveryInterceptor 投递拦截器：每条被接受的消息在写入日志、统计之后，交给拦截器而不是直接投递。
+// deliver 将消息交给所有匹配的订阅（同步 handler 执行完毕、异步订阅入队），拦截器可以立即调用、延后调用或不调用；
+// 返回值作为 Publish 的返回值。延后的投递不受 Drain 等待，PublishCtx 的 ctx 取消后同步 handler 不再被调用。
+type DeliveryInterceptor[T any] func(msg *Message[T], deliver func() error) error
+
+// Intercept 设置投递拦截器，为 nil 时恢复直接投递；主要供测试记录发布与手动投递使用（见 pubsubtest 包）
+func (ps *GenericPubSub[T]) Intercept(interceptor DeliveryInterceptor[T]) {
+	if interceptor == nil {
+		ps.interceptor.Store(nil)
+		return
+	}
+	ps.interceptor.Store(&interceptor)
+}
@@ -0,0 +1,77 @@
+package pubsub
+
+import (
+	"common"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TopicStats 单个主题的指标
+type TopicStats struct {
+	Published     int64         // 发布次数
+	Delivered     int64         // 交给订阅的次数，每条消息按匹配的订阅数计；异步订阅不论是否入队成功都计入，不含重新投递
+	Dropped       int64         // 异步订阅因队列已满丢弃的次数
+	HandlerErrors int64         // handler panic，以及重试订阅、响应者返回错误的次数
+	Handled       int64         // handler 执行完毕的次数（含重新投递与重试）
+	HandlerTime   time.Duration // handler 的累计耗时，除以 Handled 得到平均耗时
+	LastPublished time.Time     // 最近一次发布的时间，只有订阅了该主题但从未发布过时为零值
+}
+
+// recordPublish 记录一次发布的全局与主题计数
+func (ps *GenericPubSub[T]) recordPublish(msg *Message[T], delivered int) {
+	set := ps.stats.Load()
+	set.published.Add(1)
+	counters := set.topics.get(msg.Subject)
+	counters.published.Add(1)
+	counters.delivered.Add(int64(delivered))
+	counters.lastPublished.Store(msg.Time.UnixNano())
+}
+
+// ListSubjects 返回当前有订阅的所有模式，按字典序排列
+func (ps *GenericPubSub[T]) ListSubjects() []string {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	patterns := common.StringSet{}
+	for _, subjects := range ps.subscriberSubjects {
+		for subject := range subjects {
+			patterns.Add(subject)
+		}
+	}
+	list := make([]string, 0, len(patterns))
+	for pattern := range patterns {
+		list = append(list, pattern)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// SubscribersOf 返回发布到 subject 时会收到消息的订阅者，按字典序排列；subject 不是合法的发布主题时返回 nil
+func (ps *GenericPubSub[T]) SubscribersOf(subject string) []string {
+	tokens, err := splitSubject(subject)
+	if err != nil {
+		return nil
+	}
+	matched := ps.subs.Load().match(tokens, subject)
+
+	subscribers := common.StringSet{}
+	for _, key := range matched {
+		subscribers.Add(key[:strings.IndexByte(key, 0)])
+	}
+	list := make([]string, 0, len(subscribers))
+	for subscriberID := range subscribers {
+		list = append(list, subscriberID)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// TopicStats 返回主题的指标，主题从未发布或处理过时 ok 为 false；指标在服务的生命周期内一直保留，ResetStats 时清零
+func (ps *GenericPubSub[T]) TopicStats(subject string) (stats TopicStats, ok bool) {
+	counters, ok := ps.stats.Load().topics.load(subject)
+	if !ok {
+		return stats, false
+	}
+	return counters.snapshot(), true
+}
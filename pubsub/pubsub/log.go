@@ -0,0 +1,545 @@
+// 主题消息日志
+//
+// 每个主题一个目录（目录名为转义后的主题），消息以 JSON 行追加写入分段文件，
+// 文件名为该分段第一条消息的偏移量；当前分段超过 SegmentBytes 后新建分段。
+// 偏移量按主题从 0 开始连续递增。写入不做 fsync，进程崩溃时末尾不完整的一行在下次打开时截掉。
+package pubsub
+
+import (
+	"bufio"
+	"common"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSegmentBytes 消息日志分段文件的默认大小上限
+const DefaultSegmentBytes = 64 << 20
+
+const segmentExt = ".log"
+
+// ErrLogDisabled 没有开启消息日志
+var ErrLogDisabled = errors.New("message log is not enabled")
+
+// LogOptions 消息日志选项
+type LogOptions struct {
+	Dir          string   // 日志目录，不存在时创建
+	SegmentBytes int64    // 分段文件大小上限，小于等于 0 时使用 DefaultSegmentBytes
+	Subjects     []string // 需要记录的主题模式，为空时记录所有主题
+	Codec        Codec    // 消息内容的编码，为 nil 时使用 JSONCodec；回放时按同一编码解码
+}
+
+// ReplayFrom 回放起点：Time 非零时从该时间（含）之后的消息开始，否则从偏移量 Offset（含）开始
+type ReplayFrom struct {
+	Offset int64
+	Time   time.Time
+}
+
+// FromOffset 从偏移量 offset 开始回放，0 表示从头回放
+func FromOffset(offset int64) ReplayFrom {
+	return ReplayFrom{Offset: offset}
+}
+
+// FromTime 从 t（含）之后发布的消息开始回放
+func FromTime(t time.Time) ReplayFrom {
+	return ReplayFrom{Time: t}
+}
+
+// logRecord 日志中的一条消息
+type logRecord struct {
+	Offset  int64             `json:"offset"`
+	ID      string            `json:"id,omitempty"`
+	Time    time.Time         `json:"time"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Content json.RawMessage   `json:"content,omitempty"`
+	Codec   string            `json:"codec,omitempty"` // 内容不是 JSON 编码时的编码名称，内容放在 Data 中
+	Data    []byte            `json:"data,omitempty"`
+}
+
+// subjectLog 单个主题的分段日志
+type subjectLog struct {
+	dir          string
+	segmentBytes int64
+	segments     []int64 // 各分段的起始偏移量，升序
+	next         int64   // 下一条消息的偏移量
+	file         *os.File
+	size         int64 // 当前分段的字节数
+}
+
+// topicLog 按主题保存消息的日志
+type topicLog struct {
+	mu           sync.Mutex
+	dir          string
+	segmentBytes int64
+	filter       *subjectNode // 为 nil 时记录所有主题
+	codec        Codec
+	subjects     map[string]*subjectLog
+}
+
+// openTopicLog 打开日志目录并加载已有的主题日志
+func openTopicLog(opts LogOptions) (*topicLog, error) {
+	if opts.Dir == "" {
+		return nil, fmt.Errorf("log dir cannot be empty")
+	}
+	l := &topicLog{
+		dir:          opts.Dir,
+		segmentBytes: opts.SegmentBytes,
+		codec:        opts.Codec,
+		subjects:     map[string]*subjectLog{},
+	}
+	if l.segmentBytes <= 0 {
+		l.segmentBytes = DefaultSegmentBytes
+	}
+	if len(opts.Subjects) > 0 {
+		l.filter = newSubjectNode()
+		for _, pattern := range opts.Subjects {
+			tokens, err := splitPattern(pattern)
+			if err != nil {
+				return nil, err
+			}
+			l.filter.add(tokens, pattern)
+		}
+	}
+
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		subject, err := url.PathUnescape(entry.Name())
+		if err != nil {
+			continue
+		}
+		sl, err := openSubjectLog(filepath.Join(opts.Dir, entry.Name()), l.segmentBytes)
+		if err != nil {
+			l.close()
+			return nil, fmt.Errorf("open log of %q: %w", subject, err)
+		}
+		l.subjects[subject] = sl
+	}
+	return l, nil
+}
+
+// openSubjectLog 加载主题目录下的分段，并打开最后一个分段用于追加
+func openSubjectLog(dir string, segmentBytes int64) (*subjectLog, error) {
+	sl := &subjectLog{dir: dir, segmentBytes: segmentBytes}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		base, err := strconv.ParseInt(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		sl.segments = append(sl.segments, base)
+	}
+	sort.Slice(sl.segments, func(i, j int) bool { return sl.segments[i] < sl.segments[j] })
+	if len(sl.segments) == 0 {
+		return sl, nil
+	}
+
+	base := sl.segments[len(sl.segments)-1]
+	count, size, err := repairSegment(sl.segmentPath(base))
+	if err != nil {
+		return nil, err
+	}
+	sl.next, sl.size = base+count, size
+	sl.file, err = os.OpenFile(sl.segmentPath(base), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return sl, nil
+}
+
+// repairSegment 统计分段中完整的消息数，并截掉末尾不完整或无法解析的部分
+func repairSegment(path string) (count, size int64, err error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		var rec logRecord
+		if json.Unmarshal(line, &rec) != nil {
+			break
+		}
+		count++
+		size += int64(len(line))
+	}
+	if info, err := f.Stat(); err != nil {
+		return 0, 0, err
+	} else if info.Size() > size {
+		if err := f.Truncate(size); err != nil {
+			return 0, 0, err
+		}
+	}
+	return count, size, nil
+}
+
+func (sl *subjectLog) segmentPath(base int64) string {
+	return filepath.Join(sl.dir, fmt.Sprintf("%020d%s", base, segmentExt))
+}
+
+// append 追加一条消息并为其分配偏移量，当前分段已满时先新建分段
+func (sl *subjectLog) append(rec logRecord) error {
+	if sl.file == nil || sl.size >= sl.segmentBytes {
+		if err := sl.roll(); err != nil {
+			return err
+		}
+	}
+	rec.Offset = sl.next
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if _, err := sl.file.Write(line); err != nil {
+		return err
+	}
+	sl.next++
+	sl.size += int64(len(line))
+	return nil
+}
+
+// roll 关闭当前分段，以下一条消息的偏移量新建分段
+func (sl *subjectLog) roll() error {
+	if err := os.MkdirAll(sl.dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(sl.segmentPath(sl.next), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if sl.file != nil {
+		sl.file.Close()
+	}
+	if len(sl.segments) == 0 || sl.segments[len(sl.segments)-1] != sl.next {
+		sl.segments = append(sl.segments, sl.next)
+	}
+	sl.file, sl.size = f, 0
+	return nil
+}
+
+// read 按偏移量顺序读取 [from, end) 范围内的消息
+func (sl *subjectLog) read(segments []int64, from, end int64, fn func(rec *logRecord) error) error {
+	// 从最后一个起始偏移量不大于 from 的分段开始
+	start := sort.Search(len(segments), func(i int) bool { return segments[i] > from }) - 1
+	if start < 0 {
+		start = 0
+	}
+	for _, base := range segments[start:] {
+		if base >= end {
+			return nil
+		}
+		done, err := sl.readSegment(base, from, end, fn)
+		if err != nil || done {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSegment 读取一个分段中 [from, end) 范围内的消息，读到 end 时 done 为 true
+func (sl *subjectLog) readSegment(base, from, end int64, fn func(rec *logRecord) error) (done bool, err error) {
+	f, err := os.Open(sl.segmentPath(base))
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		rec := &logRecord{}
+		if err := json.Unmarshal(line, rec); err != nil {
+			return false, fmt.Errorf("corrupted record in %s: %w", sl.segmentPath(base), err)
+		}
+		if rec.Offset >= end {
+			return true, nil
+		}
+		if rec.Offset < from {
+			continue
+		}
+		if err := fn(rec); err != nil {
+			return false, err
+		}
+	}
+}
+
+// accepts 判断主题是否需要记录
+func (l *topicLog) accepts(tokens []string) bool {
+	if l.filter == nil {
+		return true
+	}
+	matched := common.StringSet{}
+	l.filter.match(tokens, matched)
+	return len(matched) > 0
+}
+
+// append 追加一条消息到主题日志
+func (l *topicLog) append(subject string, rec logRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sl, ok := l.subjects[subject]
+	if !ok {
+		sl = &subjectLog{dir: filepath.Join(l.dir, url.PathEscape(subject)), segmentBytes: l.segmentBytes}
+		l.subjects[subject] = sl
+	}
+	return sl.append(rec)
+}
+
+// logCursor 回放时一个主题的读取范围
+type logCursor struct {
+	subject  string
+	log      *subjectLog
+	segments []int64
+	end      int64
+}
+
+// cursors 返回与模式匹配的主题及其当前的末尾偏移量，回放只读到此处
+func (l *topicLog) cursors(tokens []string) []logCursor {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var cursors []logCursor
+	for subject, sl := range l.subjects {
+		if sl.next == 0 || !matchPattern(tokens, strings.Split(subject, subjectSeparator)) {
+			continue
+		}
+		segments := append([]int64(nil), sl.segments...)
+		cursors = append(cursors, logCursor{subject: subject, log: sl, segments: segments, end: sl.next})
+	}
+	sort.Slice(cursors, func(i, j int) bool { return cursors[i].subject < cursors[j].subject })
+	return cursors
+}
+
+// replay 按发布时间顺序回放多个主题的消息，同一主题内保持偏移量顺序
+func replay(cursors []logCursor, from ReplayFrom, fn func(subject string, rec *logRecord) error) error {
+	if len(cursors) == 1 {
+		c := cursors[0]
+		return c.log.read(c.segments, from.Offset, c.end, func(rec *logRecord) error {
+			if rec.Time.Before(from.Time) {
+				return nil
+			}
+			return fn(c.subject, rec)
+		})
+	}
+
+	// 多个主题时先按主题读出，再按时间归并
+	type pending struct {
+		subject string
+		records []*logRecord
+	}
+	all := make([]pending, 0, len(cursors))
+	for _, c := range cursors {
+		p := pending{subject: c.subject}
+		err := c.log.read(c.segments, from.Offset, c.end, func(rec *logRecord) error {
+			if !rec.Time.Before(from.Time) {
+				p.records = append(p.records, rec)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		all = append(all, p)
+	}
+	for {
+		best := -1
+		for i, p := range all {
+			if len(p.records) > 0 && (best < 0 || p.records[0].Time.Before(all[best].records[0].Time)) {
+				best = i
+			}
+		}
+		if best < 0 {
+			return nil
+		}
+		rec := all[best].records[0]
+		all[best].records = all[best].records[1:]
+		if err := fn(all[best].subject, rec); err != nil {
+			return err
+		}
+	}
+}
+
+// close 关闭所有分段文件
+func (l *topicLog) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, sl := range l.subjects {
+		if sl.file != nil {
+			sl.file.Close()
+			sl.file = nil
+		}
+	}
+}
+
+// EnableLog 开启消息日志：之后发布到匹配 opts.Subjects 的主题的消息先以 JSON 行追加到磁盘（内容按 opts.Codec 编码），再投递给订阅者，
+// 可通过 SubscribeFrom 回放。目录中已有的日志会被加载，偏移量接着已有的消息递增。
+func (ps *GenericPubSub[T]) EnableLog(opts LogOptions) error {
+	l, err := openTopicLog(opts)
+	if err != nil {
+		return err
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if ps.subs.Load().closed {
+		l.close()
+		return ErrClosed
+	}
+	if old := ps.log.Swap(l); old != nil {
+		old.close()
+	}
+	return nil
+}
+
+// appendLogLocked 消息需要记录时写入日志 l（可为 nil），l 需是持有锁时读取的 ps.log；调用方需持有读锁或写锁
+func (ps *GenericPubSub[T]) appendLogLocked(l *topicLog, msg *Message[T], tokens []string) error {
+	if l == nil || !l.accepts(tokens) {
+		return nil
+	}
+	content, codec, data, err := payloadFields(l.codec, &msg.Payload)
+	if err != nil {
+		return fmt.Errorf("encode content for log: %w", err)
+	}
+	return l.append(msg.Subject, logRecord{ID: msg.ID, Time: msg.Time, Headers: msg.Headers, Content: content, Codec: codec, Data: data})
+}
+
+// replayer 回放（历史消息或保留消息）期间缓存实时消息，回放结束后按顺序补发，再切换为直接调用 handler
+type replayer[T any] struct {
+	handler   MsgHandler[T]
+	mu        sync.Mutex
+	replaying bool
+	buffered  []delivery[T]
+}
+
+func (r *replayer[T]) live(ctx context.Context, msg *Message[T]) {
+	r.mu.Lock()
+	if r.replaying {
+		r.buffered = append(r.buffered, delivery[T]{msg: msg})
+		r.mu.Unlock()
+		return
+	}
+	r.mu.Unlock()
+	r.handler(ctx, msg)
+}
+
+// finish 补发回放期间缓存的实时消息，缓存为空时结束回放
+func (r *replayer[T]) finish() {
+	for {
+		r.mu.Lock()
+		buffered := r.buffered
+		r.buffered = nil
+		if len(buffered) == 0 {
+			r.replaying = false
+			r.mu.Unlock()
+			return
+		}
+		r.mu.Unlock()
+		// 缓存的消息的发布方已返回，不再沿用其 ctx
+		for _, d := range buffered {
+			r.handler(context.Background(), d.msg)
+		}
+	}
+}
+
+// SubscribeFrom 订阅主题并先回放日志中的历史消息：从 from 开始按发布时间顺序调用 handler，
+// 回放完成后再接收实时消息，回放期间发布的消息会缓存并在回放后补发，不重复也不遗漏。
+// 模式匹配多个主题时 from.Offset 对每个主题分别生效。回放在调用方协程中同步执行，
+// 失败时取消本次订阅并返回错误；没有开启消息日志时返回 ErrLogDisabled。
+func (ps *GenericPubSub[T]) SubscribeFrom(subscriberID string, subject string, from ReplayFrom, handler Handler[T]) error {
+	if handler == nil {
+		return errNilHandler
+	}
+	return ps.SubscribeMsgFrom(subscriberID, subject, from, fromHandler(handler))
+}
+
+// SubscribeMsgFrom 与 SubscribeFrom 相同，但 handler 收到完整的消息信封；回放的消息保留原有的ID、消息头与发布时间
+func (ps *GenericPubSub[T]) SubscribeMsgFrom(subscriberID string, subject string, from ReplayFrom, handler MsgHandler[T]) error {
+	if handler == nil {
+		return errNilHandler
+	}
+	if subscriberID == "" {
+		return fmt.Errorf("subscriberID cannot be empty")
+	}
+	tokens, err := splitPattern(subject)
+	if err != nil {
+		return err
+	}
+	if err := ps.authorizeSubscribe(subscriberID, subject, tokens); err != nil {
+		return err
+	}
+
+	// 在写锁内记下各主题的末尾偏移量并完成订阅，此时没有进行中的写入日志的 Publish：
+	// 末尾之前的消息由回放投递，之后的消息由实时订阅投递
+	handler = ps.guard(subscriberID, ps.msgWithMiddlewares(handler))
+	r := &replayer[T]{}
+	ps.mu.Lock()
+	if ps.subs.Load().closed {
+		ps.mu.Unlock()
+		return ErrClosed
+	}
+	l := ps.log.Load()
+	if l == nil {
+		ps.mu.Unlock()
+		return ErrLogDisabled
+	}
+	cursors := l.cursors(tokens)
+	ps.subscribeLocked(subscriberID, subject, tokens, handler, nil, r)
+	ps.mu.Unlock()
+
+	err = replay(cursors, from, func(subject string, rec *logRecord) error {
+		msg := &Message[T]{ID: rec.ID, Subject: subject, Headers: rec.Headers, Time: rec.Time}
+		if err := decodePayload(l.codec, rec.Content, rec.Codec, rec.Data, &msg.Payload); err != nil {
+			return fmt.Errorf("decode %s@%d: %w", subject, rec.Offset, err)
+		}
+		handler(context.Background(), msg)
+		return nil
+	})
+	if err != nil {
+		ps.Unsubscribe(subscriberID, subject)
+		return err
+	}
+	r.finish()
+	return nil
+}
@@ -0,0 +1,86 @@
+package pubsub
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultMatchCacheSize 发布匹配缓存默认缓存的主题数
+const DefaultMatchCacheSize = 1024
+
+// matchCache 具体主题 -> 匹配的订阅键的 LRU 缓存，热点主题发布时不必每次遍历前缀树。
+// 每个订阅快照有自己的缓存，只缓存该快照的匹配结果；订阅变化时新快照从空缓存开始。
+type matchCache struct {
+	mu      sync.Mutex
+	size    int                      // 最多缓存的主题数，小于等于 0 时不缓存
+	entries map[string]*list.Element // 主题 -> lru 中的元素
+	lru     *list.List               // 元素为 *matchEntry，最近使用的在前
+}
+
+// matchEntry 一个主题匹配的订阅键，填充后不再修改
+type matchEntry struct {
+	subject string
+	keys    []string
+}
+
+func newMatchCache(size int) *matchCache {
+	return &matchCache{size: size, entries: map[string]*list.Element{}, lru: list.New()}
+}
+
+// get 返回主题匹配的订阅键
+func (c *matchCache) get(subject string) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[subject]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	return e.Value.(*matchEntry).keys, true
+}
+
+// put 缓存主题匹配的订阅键，超出容量时淘汰最久未使用的主题
+func (c *matchCache) put(subject string, keys []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.size <= 0 {
+		return
+	}
+	if e, ok := c.entries[subject]; ok {
+		e.Value.(*matchEntry).keys = keys
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.entries[subject] = c.lru.PushFront(&matchEntry{subject: subject, keys: keys})
+	c.evictLocked()
+}
+
+// resize 修改容量，超出新容量的主题被淘汰
+func (c *matchCache) resize(size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.size = size
+	c.evictLocked()
+}
+
+// evictLocked 淘汰超出容量的主题，调用方需持有 c.mu
+func (c *matchCache) evictLocked() {
+	for c.lru.Len() > max(c.size, 0) {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.entries, e.Value.(*matchEntry).subject)
+	}
+}
+
+// SetMatchCacheSize 设置发布匹配缓存最多缓存的主题数（默认 DefaultMatchCacheSize），小于等于 0 时关闭缓存。
+// 缓存以具体主题为键记录匹配的订阅，任何订阅或取消订阅后都从空缓存开始；主题数量远大于容量时命中率很低，可以关闭。
+func (ps *GenericPubSub[T]) SetMatchCacheSize(size int) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	ps.matchCacheSize = size
+	ps.subs.Load().matches.resize(size)
+}
@@ -0,0 +1,91 @@
+package pubsub
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Message 消息信封：除主题与内容外还携带消息头、消息ID与发布时间，
+// 用于让链路追踪ID、数据结构版本等元数据随事件一起传递。同一条消息会交给所有匹配的订阅，handler 不应修改它。
+type Message[T any] struct {
+	ID      string            // 为空时发布时自动生成
+	Subject string            // 主题
+	Payload T                 // 内容
+	Headers map[string]string // 消息头，可为 nil
+	Time    time.Time         // 发布时间，为零值时发布时填入当前时间
+}
+
+// NewMessage 创建消息，ID 与发布时间在发布时填入
+func NewMessage[T any](subject string, payload T) *Message[T] {
+	return &Message[T]{Subject: subject, Payload: payload}
+}
+
+// Header 返回消息头的值，不存在时为空字符串
+func (m *Message[T]) Header(key string) string {
+	return m.Headers[key]
+}
+
+// SetHeader 设置消息头，应在发布前调用
+func (m *Message[T]) SetHeader(key, value string) *Message[T] {
+	if m.Headers == nil {
+		m.Headers = map[string]string{}
+	}
+	m.Headers[key] = value
+	return m
+}
+
+// MsgHandler 接收完整消息信封的订阅者回调函数类型
+type MsgHandler[T any] func(ctx context.Context, msg *Message[T])
+
+// msgIDGenerator 生成进程内唯一的消息ID：随机前缀 + 递增序号
+type msgIDGenerator struct {
+	prefix string
+	seq    atomic.Uint64
+}
+
+func newMsgIDGenerator() *msgIDGenerator {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return &msgIDGenerator{prefix: hex.EncodeToString(b) + "-"}
+}
+
+func (g *msgIDGenerator) next() string {
+	return g.prefix + strconv.FormatUint(g.seq.Add(1), 10)
+}
+
+// stamp 补全消息的ID与发布时间
+func (ps *GenericPubSub[T]) stamp(msg *Message[T]) {
+	if msg.ID == "" {
+		msg.ID = ps.ids.next()
+	}
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+}
+
+// PublishMsg 发布消息信封，其余行为与 PublishCtx 相同；ID 或发布时间为空时自动填入
+// 普通 handler 只收到主题与内容，MsgHandler 收到完整的消息。
+func (ps *GenericPubSub[T]) PublishMsg(ctx context.Context, msg *Message[T]) error {
+	_, err := ps.publish(ctx, msg, "")
+	return err
+}
+
+// SubscribeMsg 以接收完整消息信封的 handler 订阅主题，opts 与 SubscribeCtx 相同
+// 通过 Publish 发布的消息同样带有自动生成的 ID 与发布时间，消息头为空。
+func (ps *GenericPubSub[T]) SubscribeMsg(subscriberID string, subject string, handler MsgHandler[T], opts CtxOptions) error {
+	if handler == nil {
+		return errNilHandler
+	}
+	return ps.subscribeWithOptions(subscriberID, subject, handler, opts)
+}
+
+// fromHandler 将普通 handler 转为接收消息信封的 handler
+func fromHandler[T any](handler Handler[T]) MsgHandler[T] {
+	return func(_ context.Context, msg *Message[T]) {
+		handler(msg.Subject, msg.Payload)
+	}
+}
@@ -0,0 +1,233 @@
+package pubsub
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets handler 耗时直方图的桶上界
+var latencyBuckets = [...]time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// topicCounters 单个主题的计数器
+type topicCounters struct {
+	published     atomic.Int64
+	delivered     atomic.Int64
+	dropped       atomic.Int64
+	handlerErrors atomic.Int64
+	handled       atomic.Int64
+	handlerNanos  atomic.Int64
+	lastPublished atomic.Int64 // UnixNano，从未发布过时为 0
+	// buckets 落在各个桶内的次数（不累计），超过最后一个桶上界的只计入 handled
+	buckets [len(latencyBuckets)]atomic.Int64
+}
+
+// observe 记录一次 handler 的耗时
+func (c *topicCounters) observe(elapsed time.Duration) {
+	c.handled.Add(1)
+	c.handlerNanos.Add(int64(elapsed))
+	for i, bound := range latencyBuckets {
+		if elapsed <= bound {
+			c.buckets[i].Add(1)
+			return
+		}
+	}
+}
+
+// snapshot 返回当前的指标
+func (c *topicCounters) snapshot() TopicStats {
+	stats := TopicStats{
+		Published:     c.published.Load(),
+		Delivered:     c.delivered.Load(),
+		Dropped:       c.dropped.Load(),
+		HandlerErrors: c.handlerErrors.Load(),
+		Handled:       c.handled.Load(),
+		HandlerTime:   time.Duration(c.handlerNanos.Load()),
+	}
+	if nanos := c.lastPublished.Load(); nanos != 0 {
+		stats.LastPublished = time.Unix(0, nanos)
+	}
+	return stats
+}
+
+// statsSet 一组计数器：全局、各主题与各订阅者的计数。ResetStats 时整体替换为新的一组，
+// 读取指标时只读取同一组，不会看到重置了一半的计数
+type statsSet struct {
+	published     atomic.Int64
+	handlerPanics atomic.Int64
+	topics        topicRegistry
+	subscribers   subscriberRegistry
+}
+
+// handled 记录订阅者的 handler 处理完一条消息及其耗时
+func (s *statsSet) handled(subscriberID, subject string, elapsed time.Duration) {
+	s.topics.get(subject).observe(elapsed)
+	s.subscribers.get(subscriberID).delivered.Add(1)
+}
+
+// handlerError 记录订阅者的 handler panic 或返回错误
+func (s *statsSet) handlerError(subscriberID, subject string) {
+	s.topics.get(subject).handlerErrors.Add(1)
+	s.subscribers.get(subscriberID).handlerErrors.Add(1)
+}
+
+// dropped 记录订阅者因异步队列已满丢弃一条消息
+func (s *statsSet) dropped(subscriberID, subject string) {
+	s.topics.get(subject).dropped.Add(1)
+	s.subscribers.get(subscriberID).dropped.Add(1)
+}
+
+// subscriberCounters 单个订阅者的计数器
+type subscriberCounters struct {
+	delivered     atomic.Int64
+	handlerErrors atomic.Int64
+	dropped       atomic.Int64
+}
+
+// subscriberRegistry 订阅者 -> 计数器，在锁外并发更新
+type subscriberRegistry struct {
+	subscribers sync.Map
+}
+
+// get 返回订阅者的计数器，不存在时创建
+func (r *subscriberRegistry) get(subscriberID string) *subscriberCounters {
+	if c, ok := r.subscribers.Load(subscriberID); ok {
+		return c.(*subscriberCounters)
+	}
+	c, _ := r.subscribers.LoadOrStore(subscriberID, &subscriberCounters{})
+	return c.(*subscriberCounters)
+}
+
+// remove 移除订阅者的计数器，订阅者取消所有订阅时调用
+func (r *subscriberRegistry) remove(subscriberID string) {
+	r.subscribers.Delete(subscriberID)
+}
+
+// snapshot 返回所有订阅者的计数
+func (r *subscriberRegistry) snapshot() map[string]SubscriberStats {
+	stats := map[string]SubscriberStats{}
+	r.subscribers.Range(func(subscriberID, c any) bool {
+		counters := c.(*subscriberCounters)
+		stats[subscriberID.(string)] = SubscriberStats{
+			Delivered:     counters.delivered.Load(),
+			HandlerErrors: counters.handlerErrors.Load(),
+			Dropped:       counters.dropped.Load(),
+		}
+		return true
+	})
+	return stats
+}
+
+// topicRegistry 主题 -> 计数器，在锁外并发更新
+type topicRegistry struct {
+	topics sync.Map
+}
+
+// get 返回主题的计数器，不存在时创建
+func (r *topicRegistry) get(subject string) *topicCounters {
+	if c, ok := r.topics.Load(subject); ok {
+		return c.(*topicCounters)
+	}
+	c, _ := r.topics.LoadOrStore(subject, &topicCounters{})
+	return c.(*topicCounters)
+}
+
+// load 返回主题的计数器
+func (r *topicRegistry) load(subject string) (*topicCounters, bool) {
+	c, ok := r.topics.Load(subject)
+	if !ok {
+		return nil, false
+	}
+	return c.(*topicCounters), true
+}
+
+// snapshot 返回所有主题的指标
+func (r *topicRegistry) snapshot() map[string]TopicStats {
+	stats := map[string]TopicStats{}
+	r.topics.Range(func(subject, c any) bool {
+		stats[subject.(string)] = c.(*topicCounters).snapshot()
+		return true
+	})
+	return stats
+}
+
+// WritePrometheus 以 Prometheus 文本格式输出各主题的指标，指标名以 namespace 为前缀（为空时使用 "pubsub"）
+// 每个主题一组时间序列，主题中含有玩家ID等无界取值时会产生大量序列，需要在发布时控制主题的数量。
+func (ps *GenericPubSub[T]) WritePrometheus(w io.Writer, namespace string) error {
+	if namespace == "" {
+		namespace = "pubsub"
+	}
+	set := ps.stats.Load()
+	stats := set.topics.snapshot()
+	subjects := make([]string, 0, len(stats))
+	for subject := range stats {
+		subjects = append(subjects, subject)
+	}
+	sort.Strings(subjects)
+
+	bw := bufio.NewWriter(w)
+	counter := func(name, help string, value func(s TopicStats) int64) {
+		fmt.Fprintf(bw, "# HELP %s_%s %s\n# TYPE %s_%s counter\n", namespace, name, help, namespace, name)
+		for _, subject := range subjects {
+			fmt.Fprintf(bw, "%s_%s{subject=%s} %d\n", namespace, name, quoteLabel(subject), value(stats[subject]))
+		}
+	}
+	counter("published_total", "Messages published per subject.", func(s TopicStats) int64 { return s.Published })
+	counter("delivered_total", "Messages handed to subscriptions per subject.", func(s TopicStats) int64 { return s.Delivered })
+	counter("dropped_total", "Messages dropped because an async subscriber queue was full.", func(s TopicStats) int64 { return s.Dropped })
+	counter("handler_errors_total", "Handler panics and returned errors per subject.", func(s TopicStats) int64 { return s.HandlerErrors })
+
+	name := namespace + "_handler_duration_seconds"
+	fmt.Fprintf(bw, "# HELP %s Handler latency per subject.\n# TYPE %s histogram\n", name, name)
+	for _, subject := range subjects {
+		counters, ok := set.topics.load(subject)
+		if !ok {
+			continue
+		}
+		label := quoteLabel(subject)
+		var cumulative int64
+		for i, bound := range latencyBuckets {
+			cumulative += counters.buckets[i].Load()
+			fmt.Fprintf(bw, "%s_bucket{subject=%s,le=\"%s\"} %d\n", name, label, strconv.FormatFloat(bound.Seconds(), 'g', -1, 64), cumulative)
+		}
+		s := stats[subject]
+		// 各桶与 Handled 不是同时读取的，保证 +Inf 桶不小于前面的桶
+		count := max(s.Handled, cumulative)
+		fmt.Fprintf(bw, "%s_bucket{subject=%s,le=\"+Inf\"} %d\n", name, label, count)
+		fmt.Fprintf(bw, "%s_sum{subject=%s} %s\n", name, label, strconv.FormatFloat(s.HandlerTime.Seconds(), 'g', -1, 64))
+		fmt.Fprintf(bw, "%s_count{subject=%s} %d\n", name, label, count)
+	}
+	return bw.Flush()
+}
+
+// PrometheusHandler 返回输出 WritePrometheus 指标的 http.Handler，可挂载到 /metrics 供 Prometheus 抓取
+func (ps *GenericPubSub[T]) PrometheusHandler(namespace string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		ps.WritePrometheus(w, namespace)
+	})
+}
+
+// labelEscaper 转义 Prometheus 标签值中的反斜杠、双引号与换行
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// quoteLabel 返回加上双引号的标签值
+func quoteLabel(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
@@ -1,46 +1,153 @@
 package pubsub
 
-// Middleware 泛型中间件类型
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Middleware 泛型中间件类型，调用 next 继续处理，可修改传给 next 的主题与内容，不调用 next 时消息被丢弃
 type Middleware[T any] func(subject string, content T, next Handler[T])
 
-// PubSubWithMiddleware 是带有中间件功能的发布订阅服务
-type PubSubWithMiddleware[T any] struct {
-	*GenericPubSub[T]
-	middlewares []Middleware[T]
+// scopedMiddleware 登记的中间件及其作用范围
+type scopedMiddleware[T any] struct {
+	tokens []string // 作用的主题模式，为 nil 时作用于所有主题
+	mw     Middleware[T]
 }
 
-// NewPubSubWithMiddleware 创建一个带中间件的发布订阅服务实例
-func NewPubSubWithMiddleware[T any]() *PubSubWithMiddleware[T] {
-	return &PubSubWithMiddleware[T]{
-		GenericPubSub: NewGenericPubSub[T](),
-		middlewares:   []Middleware[T]{},
+// Use 添加作用于所有主题的中间件
+// 中间件在每次投递时按登记顺序执行，对之前已有的订阅同样生效；同步订阅在发布者协程中执行，
+// 异步、确认、重试订阅与响应者在各自的处理协程中执行，重新投递与重试都会重新经过中间件。
+func (ps *GenericPubSub[T]) Use(middlewares ...Middleware[T]) {
+	ps.addMiddlewares(nil, middlewares)
+}
+
+// UseFor 添加只作用于匹配 pattern 的主题的中间件（如 "leaderboard.*"），与 Use 添加的中间件一起按登记顺序执行
+func (ps *GenericPubSub[T]) UseFor(pattern string, middlewares ...Middleware[T]) error {
+	tokens, err := splitPattern(pattern)
+	if err != nil {
+		return err
 	}
+	ps.addMiddlewares(tokens, middlewares)
+	return nil
 }
 
-// Use 添加一个或多个中间件
-func (ps *PubSubWithMiddleware[T]) Use(middlewares ...Middleware[T]) {
-	ps.middlewares = append(ps.middlewares, middlewares...)
+// addMiddlewares 追加中间件；链表只追加不修改，投递时无锁读取
+func (ps *GenericPubSub[T]) addMiddlewares(tokens []string, middlewares []Middleware[T]) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	var chain []scopedMiddleware[T]
+	if old := ps.middlewares.Load(); old != nil {
+		chain = append(chain, *old...)
+	}
+	for _, mw := range middlewares {
+		if mw != nil {
+			chain = append(chain, scopedMiddleware[T]{tokens: tokens, mw: mw})
+		}
+	}
+	ps.middlewares.Store(&chain)
 }
 
-// Subscribe 订阅主题，并应用中间件
-func (ps *PubSubWithMiddleware[T]) Subscribe(subscriberID string, subject string, handler Handler[T]) error {
-	wrappedHandler := ps.wrapHandler(handler)
-	return ps.GenericPubSub.Subscribe(subscriberID, subject, wrappedHandler)
+// hasMiddlewares 是否登记了中间件
+func (ps *GenericPubSub[T]) hasMiddlewares() bool {
+	chain := ps.middlewares.Load()
+	return chain != nil && len(*chain) > 0
 }
 
-// wrapHandler 将处理器包装在中间件链中
-func (ps *PubSubWithMiddleware[T]) wrapHandler(handler Handler[T]) Handler[T] {
-	if len(ps.middlewares) == 0 {
-		return handler
+// applyMiddlewares 依次执行与主题匹配的中间件，最后以中间件传给 next 的主题与内容调用 handler
+func (ps *GenericPubSub[T]) applyMiddlewares(subject string, content T, handler Handler[T]) {
+	chain := ps.middlewares.Load()
+	if chain == nil {
+		handler(subject, content)
+		return
+	}
+	var tokens []string
+	next := handler
+	for i := len(*chain) - 1; i >= 0; i-- {
+		m := (*chain)[i]
+		if m.tokens != nil {
+			if tokens == nil {
+				tokens = strings.Split(subject, subjectSeparator)
+			}
+			if !matchPattern(m.tokens, tokens) {
+				continue
+			}
+		}
+		current := next
+		next = func(subject string, content T) {
+			m.mw(subject, content, current)
+		}
 	}
+	next(subject, content)
+}
+
+// withMiddlewares 将处理器包装在中间件链中
+func (ps *GenericPubSub[T]) withMiddlewares(handler Handler[T]) Handler[T] {
+	return func(subject string, content T) {
+		ps.applyMiddlewares(subject, content, handler)
+	}
+}
 
-	wrapped := handler
-	for i := len(ps.middlewares) - 1; i >= 0; i-- {
-		mw := ps.middlewares[i]
-		current := wrapped
-		wrapped = func(subject string, content T) {
-			mw(subject, content, current)
+// msgWithMiddlewares 将接收消息信封的处理器包装在中间件链中，中间件传给 next 的主题与内容写入消息的副本
+func (ps *GenericPubSub[T]) msgWithMiddlewares(handler MsgHandler[T]) MsgHandler[T] {
+	return func(ctx context.Context, msg *Message[T]) {
+		if !ps.hasMiddlewares() {
+			handler(ctx, msg)
+			return
 		}
+		ps.applyMiddlewares(msg.Subject, msg.Payload, func(subject string, content T) {
+			m := *msg
+			m.Subject, m.Payload = subject, content
+			handler(ctx, &m)
+		})
 	}
-	return wrapped
-}
\ No newline at end of file
+}
+
+// ackWithMiddlewares 将确认订阅的处理器包装在中间件链中；中间件传给 next 的主题与内容写回 msg，
+// 重新投递时仍从原始消息开始。中间件没有调用 next 时消息不会被确认，超时后重新投递
+func (ps *GenericPubSub[T]) ackWithMiddlewares(handler AckHandler[T]) AckHandler[T] {
+	return func(msg *Msg[T]) {
+		ps.applyMiddlewares(msg.Subject, msg.Content, func(subject string, content T) {
+			msg.Subject, msg.Content = subject, content
+			handler(msg)
+		})
+	}
+}
+
+// errorWithMiddlewares 将重试订阅的处理器包装在中间件链中，每次重试都会重新经过中间件
+func (ps *GenericPubSub[T]) errorWithMiddlewares(handler HandlerE[T]) HandlerE[T] {
+	return func(subject string, content T) error {
+		var err error
+		ps.applyMiddlewares(subject, content, func(subject string, content T) {
+			err = handler(subject, content)
+		})
+		return err
+	}
+}
+
+// responderWithMiddlewares 将响应者包装在中间件链中，中间件没有调用 next 时请求方收到错误
+func (ps *GenericPubSub[T]) responderWithMiddlewares(responder Responder[T]) Responder[T] {
+	return func(subject string, content T) (T, error) {
+		var result T
+		err := fmt.Errorf("request dropped by middleware")
+		ps.applyMiddlewares(subject, content, func(subject string, content T) {
+			result, err = responder(subject, content)
+		})
+		return result, err
+	}
+}
+
+// PubSubWithMiddleware 是带有中间件功能的发布订阅服务
+//
+// Deprecated: GenericPubSub 已内置 Use 与 UseFor，直接使用 NewGenericPubSub。
+type PubSubWithMiddleware[T any] struct {
+	*GenericPubSub[T]
+}
+
+// NewPubSubWithMiddleware 创建一个带中间件的发布订阅服务实例
+//
+// Deprecated: 使用 NewGenericPubSub。
+func NewPubSubWithMiddleware[T any]() *PubSubWithMiddleware[T] {
+	return &PubSubWithMiddleware[T]{GenericPubSub: NewGenericPubSub[T]()}
+}
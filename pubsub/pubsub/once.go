@@ -0,0 +1,32 @@
+package pubsub
+
+import "sync/atomic"
+
+// SubscribeOnce 订阅主题，第一条消息交给 handler 之前自动取消该订阅：并发发布时 handler 也只会被调用一次，
+// 中间件丢弃的消息不计入。handler 被调用前订阅被替换或取消时，不影响新的订阅。
+func (ps *GenericPubSub[T]) SubscribeOnce(subscriberID string, subject string, handler Handler[T]) error {
+	if handler == nil {
+		return errNilHandler
+	}
+	var fired atomic.Bool
+	var generation atomic.Uint64 // 订阅完成前为 0
+	once := func(msgSubject string, content T) {
+		if !fired.CompareAndSwap(false, true) {
+			return
+		}
+		if g := generation.Load(); g != 0 {
+			ps.unsubscribeGeneration(subscriberID, subject, g)
+		}
+		handler(msgSubject, content)
+	}
+	g, err := ps.subscribeGeneration(subscriberID, subject, fromHandler(ps.withMiddlewares(once)), nil)
+	if err != nil {
+		return err
+	}
+	generation.Store(g)
+	// 订阅时投递的保留消息可能已经触发了 handler
+	if fired.Load() {
+		ps.unsubscribeGeneration(subscriberID, subject, g)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrQueueFull TryPublish 时有异步订阅者的队列已满
+	ErrQueueFull = errors.New("subscriber queue is full")
+	// ErrPublishTimeout PublishWithTimeout 在期限内没有完成投递
+	ErrPublishTimeout = errors.New("publish timed out")
+)
+
+// publishMode 随 ctx 传给异步入队的发布方式，并记录没能入队的订阅数
+type publishMode struct {
+	noBlock bool // 为 true 时所有异步订阅都不阻塞入队
+	failed  atomic.Int32
+	counts  *deliveryCounts // PublishCount 时非 nil
+}
+
+// deliveryCounts PublishCount 统计的投递结果；只统计 msg 本身，handler 中以同一个 ctx 继续发布的消息不计入
+type deliveryCounts struct {
+	msg      any // *Message[T]
+	matched  int
+	executed atomic.Int32
+	enqueued atomic.Int32
+	dropped  atomic.Int32
+}
+
+// PublishResult PublishCount 的投递结果
+type PublishResult struct {
+	Matched  int // 匹配的订阅数，为 0 时没有任何订阅者
+	Executed int // 执行完的同步 handler 数（含 panic 的）
+	Enqueued int // 放入队列的异步订阅数，不含匹配后才停止（如并发取消订阅）的异步订阅
+	Dropped  int // 因队列已满没能入队的异步订阅数
+}
+
+type publishModeKey struct{}
+
+// publishModeFrom 取出 ctx 中的发布方式，普通发布时为 nil
+func publishModeFrom(ctx context.Context) *publishMode {
+	mode, _ := ctx.Value(publishModeKey{}).(*publishMode)
+	return mode
+}
+
+// deliveryCountsFor 返回 PublishCount 统计 msg 的投递结果，其他发布时为 nil
+func deliveryCountsFor(ctx context.Context, msg any) *deliveryCounts {
+	if mode := publishModeFrom(ctx); mode != nil && mode.counts != nil && mode.counts.msg == msg {
+		return mode.counts
+	}
+	return nil
+}
+
+// PublishCount 与 Publish 相同，并返回匹配的订阅数以及同步执行、异步入队与丢弃的数量，
+// 调用方可以据此发现“没有订阅者”并改为轮询或记录日志。
+func (ps *GenericPubSub[T]) PublishCount(subject string, content T) (PublishResult, error) {
+	msg := NewMessage(subject, content)
+	counts := &deliveryCounts{msg: msg}
+	ctx := context.WithValue(context.Background(), publishModeKey{}, &publishMode{counts: counts})
+	_, err := ps.publish(ctx, msg, "")
+	return PublishResult{
+		Matched:  counts.matched,
+		Executed: int(counts.executed.Load()),
+		Enqueued: int(counts.enqueued.Load()),
+		Dropped:  int(counts.dropped.Load()),
+	}, err
+}
+
+// TryPublish 与 Publish 相同，但不会因异步订阅者阻塞：任一匹配的异步订阅者的队列已满时不发布并返回 ErrQueueFull；
+// 检查之后队列被并发填满的订阅者丢弃该消息并计入 Dropped，同样返回 ErrQueueFull。同步订阅的 handler 仍在调用方协程中执行。
+func (ps *GenericPubSub[T]) TryPublish(subject string, content T) error {
+	mode := &publishMode{noBlock: true}
+	ctx := context.WithValue(context.Background(), publishModeKey{}, mode)
+	if _, err := ps.publish(ctx, NewMessage(subject, content), ""); err != nil {
+		return err
+	}
+	if mode.failed.Load() > 0 {
+		return ErrQueueFull
+	}
+	return nil
+}
+
+// PublishWithTimeout 与 PublishCtx 相同，但最多等待 d：队列已满且策略为 OverflowBlock 的异步订阅者最多等到期限，
+// 期限已过时不再调用剩余的同步 handler；有订阅者没能收到消息时返回 ErrPublishTimeout。
+// 其他策略的异步订阅者照常在队列满时丢弃消息，不视为超时。
+func (ps *GenericPubSub[T]) PublishWithTimeout(subject string, content T, d time.Duration) error {
+	mode := &publishMode{}
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), publishModeKey{}, mode), d)
+	defer cancel()
+	_, err := ps.publish(ctx, NewMessage(subject, content), "")
+	if errors.Is(err, context.DeadlineExceeded) || (err == nil && mode.failed.Load() > 0) {
+		return ErrPublishTimeout
+	}
+	return err
+}
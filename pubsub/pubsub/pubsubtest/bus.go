@@ -0,0 +1,182 @@
+// Package pubsubtest 为依赖发布订阅服务的代码提供测试工具：记录所有发布的消息供断言，
+// 或关闭自动投递，由测试逐条投递消息，使订阅者的执行顺序完全确定。
+package pubsubtest
+
+import (
+	"fmt"
+	"pubsub"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// Options 测试总线选项
+type Options struct {
+	// Manual 为 true 时发布的消息不会自动投递，由 Step 或 Flush 在测试协程中按发布顺序投递
+	Manual bool
+}
+
+// Bus 记录发布的测试总线：包装一个真实的发布订阅服务，被测代码照常使用 PubSub 返回的服务订阅与发布。
+// 手动投递时，同步订阅的 handler 在调用 Step 的协程中执行；异步订阅在 Step 时入队，仍由处理协程执行。
+type Bus[T any] struct {
+	ps     *pubsub.GenericPubSub[T]
+	manual bool
+
+	mu        sync.Mutex
+	published []*pubsub.Message[T] // 按发布顺序记录的消息
+	pending   []func() error       // 手动投递时尚未投递的消息
+}
+
+// New 创建测试总线及其包装的发布订阅服务
+func New[T any](opts Options) *Bus[T] {
+	return Attach(pubsub.NewGenericPubSub[T](), opts)
+}
+
+// Attach 包装已有的发布订阅服务（如被测代码自己创建的服务），替换其投递拦截器（见 GenericPubSub.Intercept）
+func Attach[T any](ps *pubsub.GenericPubSub[T], opts Options) *Bus[T] {
+	b := &Bus[T]{ps: ps, manual: opts.Manual}
+	ps.Intercept(b.intercept)
+	return b
+}
+
+// intercept 记录消息，自动投递时立即投递，否则排队等待 Step
+func (b *Bus[T]) intercept(msg *pubsub.Message[T], deliver func() error) error {
+	b.mu.Lock()
+	b.published = append(b.published, msg)
+	if b.manual {
+		b.pending = append(b.pending, deliver)
+		b.mu.Unlock()
+		return nil
+	}
+	b.mu.Unlock()
+	return deliver()
+}
+
+// PubSub 返回包装的发布订阅服务
+func (b *Bus[T]) PubSub() *pubsub.GenericPubSub[T] {
+	return b.ps
+}
+
+// Published 返回按发布顺序记录的所有消息
+func (b *Bus[T]) Published() []*pubsub.Message[T] {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]*pubsub.Message[T](nil), b.published...)
+}
+
+// Subjects 返回按发布顺序记录的所有主题
+func (b *Bus[T]) Subjects() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	subjects := make([]string, len(b.published))
+	for i, msg := range b.published {
+		subjects[i] = msg.Subject
+	}
+	return subjects
+}
+
+// Payloads 返回发布到与 pattern 匹配的主题的内容，按发布顺序排列
+func (b *Bus[T]) Payloads(pattern string) []T {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var payloads []T
+	for _, msg := range b.published {
+		if pubsub.MatchSubject(pattern, msg.Subject) {
+			payloads = append(payloads, msg.Payload)
+		}
+	}
+	return payloads
+}
+
+// Reset 清空已记录的消息，不影响尚未投递的消息
+func (b *Bus[T]) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.published = nil
+}
+
+// Pending 返回手动投递时尚未投递的消息数
+func (b *Bus[T]) Pending() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.pending)
+}
+
+// Step 投递最早的一条尚未投递的消息，没有时返回 false；handler 中发布的消息排在队尾
+func (b *Bus[T]) Step() bool {
+	b.mu.Lock()
+	if len(b.pending) == 0 {
+		b.mu.Unlock()
+		return false
+	}
+	deliver := b.pending[0]
+	b.pending = b.pending[1:]
+	b.mu.Unlock()
+
+	deliver()
+	return true
+}
+
+// Flush 依次投递所有尚未投递的消息，包括投递过程中 handler 新发布的消息，返回投递的消息数
+func (b *Bus[T]) Flush() int {
+	n := 0
+	for b.Step() {
+		n++
+	}
+	return n
+}
+
+// AssertPublished 断言有内容为 payload 的消息发布到与 pattern 匹配的主题，内容以 reflect.DeepEqual 比较
+func (b *Bus[T]) AssertPublished(t testing.TB, pattern string, payload T) {
+	t.Helper()
+	for _, p := range b.Payloads(pattern) {
+		if reflect.DeepEqual(p, payload) {
+			return
+		}
+	}
+	t.Errorf("no message %s published to %s; published: %s", format(payload), pattern, b.describe())
+}
+
+// AssertNotPublished 断言没有消息发布到与 pattern 匹配的主题
+func (b *Bus[T]) AssertNotPublished(t testing.TB, pattern string) {
+	t.Helper()
+	if n := len(b.Payloads(pattern)); n > 0 {
+		t.Errorf("%d messages published to %s; published: %s", n, pattern, b.describe())
+	}
+}
+
+// AssertCount 断言发布到与 pattern 匹配的主题的消息数
+func (b *Bus[T]) AssertCount(t testing.TB, pattern string, n int) {
+	t.Helper()
+	if got := len(b.Payloads(pattern)); got != n {
+		t.Errorf("%d messages published to %s, want %d; published: %s", got, pattern, n, b.describe())
+	}
+}
+
+// describe 以 “主题: 内容” 列出已记录的消息，用于断言失败时的提示
+func (b *Bus[T]) describe() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.published) == 0 {
+		return "none"
+	}
+	s := ""
+	for i, msg := range b.published {
+		if i > 0 {
+			s += ", "
+		}
+		s += msg.Subject + ": " + format(msg.Payload)
+	}
+	return s
+}
+
+// format 格式化消息内容
+func format(payload any) string {
+	return fmt.Sprintf("%+v", payload)
+}
@@ -0,0 +1,64 @@
+package pubsubtest
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestRecordingBus(t *testing.T) {
+	t.Log("--- Running TestRecordingBus ---")
+	bus := New[string](Options{})
+	ps := bus.PubSub()
+	var received []string
+	assert.Equal(t, nil, ps.Subscribe("s1", "leaderboard.*", func(subject string, content string) {
+		received = append(received, content)
+	}))
+
+	assert.Equal(t, nil, ps.Publish("leaderboard.daily", "refresh"))
+	assert.Equal(t, nil, ps.Publish("player.1.score", "100"))
+	assert.Equal(t, []string{"refresh"}, received)
+	assert.Equal(t, []string{"leaderboard.daily", "player.1.score"}, bus.Subjects())
+	assert.Equal(t, []string{"100"}, bus.Payloads("player.*.score"))
+	bus.AssertPublished(t, "leaderboard.>", "refresh")
+	bus.AssertNotPublished(t, "reward.>")
+	bus.AssertCount(t, ">", 2)
+
+	// 断言失败时报告已记录的消息
+	probe := &testing.T{}
+	bus.AssertPublished(probe, "leaderboard.daily", "other")
+	assert.Equal(t, true, probe.Failed())
+
+	bus.Reset()
+	assert.Equal(t, 0, len(bus.Published()))
+	ps.Close()
+	t.Log("--- TestRecordingBus PASSED ---")
+}
+
+func TestManualDelivery(t *testing.T) {
+	t.Log("--- Running TestManualDelivery ---")
+	bus := New[int](Options{Manual: true})
+	ps := bus.PubSub()
+	var received []int
+	assert.Equal(t, nil, ps.Subscribe("s1", "n", func(subject string, n int) {
+		received = append(received, n)
+		if n < 3 {
+			ps.Publish("n", n+1)
+		}
+	}))
+
+	assert.Equal(t, nil, ps.Publish("n", 1))
+	assert.Equal(t, 0, len(received))
+	assert.Equal(t, 1, bus.Pending())
+	bus.AssertCount(t, "n", 1)
+
+	assert.Equal(t, true, bus.Step())
+	assert.Equal(t, []int{1}, received)
+	assert.Equal(t, 1, bus.Pending())
+	assert.Equal(t, 2, bus.Flush())
+	assert.Equal(t, []int{1, 2, 3}, received)
+	assert.Equal(t, false, bus.Step())
+	assert.Equal(t, []int{1, 2, 3}, bus.Payloads("n"))
+	ps.Close()
+	t.Log("--- TestManualDelivery PASSED ---")
+}
@@ -0,0 +1,96 @@
+package pubsub
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HandlerErrorHook handler panic 时的回调，recovered 为 recover() 的返回值
+type HandlerErrorHook func(subscriberID string, subject string, recovered any)
+
+// Stats 发布订阅服务的全局计数、各主题与各订阅者的指标，所有计数都来自同一组计数器（见 ResetStats）
+type Stats struct {
+	Published     int64                      // 成功发布的消息数
+	HandlerPanics int64                      // handler panic 的次数
+	Dropped       int64                      // 因异步订阅者队列已满被丢弃的消息数，即各主题 Dropped 之和
+	Subjects      map[string]TopicStats      // 主题 -> 指标，见 TopicStats
+	Subscribers   map[string]SubscriberStats // 订阅者 -> 计数，订阅者取消所有订阅后移除
+}
+
+// SubscriberStats 单个订阅者所有订阅的计数
+type SubscriberStats struct {
+	Delivered     int64 // handler 处理完的消息数（含重新投递与重试），异步订阅在处理协程处理完时计入
+	HandlerErrors int64 // handler panic，以及重试订阅、响应者返回错误的次数
+	Dropped       int64 // 因异步队列已满被丢弃的消息数
+}
+
+// OnHandlerError 设置 handler panic 时的回调，为 nil 时恢复默认行为（记录日志与调用栈）
+// panic 总会被隔离在单次调用内：同步订阅不会影响发布者与其他订阅，异步订阅的处理协程继续处理后续消息。
+func (ps *GenericPubSub[T]) OnHandlerError(hook HandlerErrorHook) {
+	if hook == nil {
+		ps.errorHook.Store(nil)
+		return
+	}
+	ps.errorHook.Store(&hook)
+}
+
+// Stats 返回当前的计数，可以与发布并发调用；计数器均为原子计数，各项之间可能相差正在进行的发布与处理
+func (ps *GenericPubSub[T]) Stats() Stats {
+	set := ps.stats.Load()
+	stats := Stats{
+		Published:     set.published.Load(),
+		HandlerPanics: set.handlerPanics.Load(),
+		Subjects:      set.topics.snapshot(),
+		Subscribers:   set.subscribers.snapshot(),
+	}
+	for _, s := range stats.Subjects {
+		stats.Dropped += s.Dropped
+	}
+	return stats
+}
+
+// ResetStats 将 Stats、TopicStats 与 Prometheus 输出的计数清零：整体换上一组新的计数器，
+// 读取指标时不会看到重置了一半的计数；重置时正在进行的发布与处理可能记入旧的计数器。
+// DeliveryStats 与慢订阅者检测使用的计数不受影响。
+func (ps *GenericPubSub[T]) ResetStats() {
+	ps.stats.Store(&statsSet{})
+}
+
+// handlerPanicked 记录一次 handler panic 并调用回调
+func (ps *GenericPubSub[T]) handlerPanicked(subscriberID, subject string, recovered any) {
+	set := ps.stats.Load()
+	set.handlerPanics.Add(1)
+	set.handlerError(subscriberID, subject)
+	if hook := ps.errorHook.Load(); hook != nil {
+		(*hook)(subscriberID, subject, recovered)
+		return
+	}
+	log.Printf("pubsub: handler of %s panicked on %s: %v\n%s", subscriberID, subject, recovered, debug.Stack())
+}
+
+// guard 为同步 handler 加上 panic 隔离，记录耗时，开启链路追踪时为每次调用创建 span
+func (ps *GenericPubSub[T]) guard(subscriberID string, handler MsgHandler[T]) MsgHandler[T] {
+	return func(ctx context.Context, msg *Message[T]) {
+		start := time.Now()
+		var span trace.Span
+		if t := ps.tracing.Load(); t != nil {
+			ctx, span = startProcessSpan(t, ctx, subscriberID, msg)
+		}
+		defer func() {
+			r := recover()
+			if r != nil {
+				ps.handlerPanicked(subscriberID, msg.Subject, r)
+			}
+			ps.stats.Load().handled(subscriberID, msg.Subject, time.Since(start))
+			if counts := deliveryCountsFor(ctx, msg); counts != nil {
+				counts.executed.Add(1)
+			}
+			endSpan(span, panicError(r))
+		}()
+		handler(ctx, msg)
+	}
+}
@@ -0,0 +1,109 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// inboxPrefix 回复主题的前缀，每个请求一个唯一的回复主题
+const inboxPrefix = "_INBOX."
+
+var (
+	// ErrNoResponders 没有响应者订阅请求的主题
+	ErrNoResponders = errors.New("no responders")
+	// ErrRequestTimeout 超时仍未收到回复
+	ErrRequestTimeout = errors.New("request timed out")
+)
+
+// Responder 响应者的处理函数，返回值作为回复发给请求方，返回错误或 panic 时请求方收到该错误
+type Responder[T any] func(subject string, content T) (T, error)
+
+// reply 一条回复
+type reply[T any] struct {
+	content T
+	err     error
+}
+
+// SubscribeResponder 以响应者身份订阅主题：Request 发来的消息由 responder 处理并把结果回复给请求方，
+// 普通 Publish 发来的消息同样会处理，但结果被丢弃。响应者经该订阅的有界队列异步处理（见 SubscribeAsync），
+// 多个响应者匹配同一请求时请求方只取最先到达的回复。
+func (ps *GenericPubSub[T]) SubscribeResponder(subscriberID string, subject string, responder Responder[T], opts AsyncOptions) error {
+	if responder == nil {
+		return errNilHandler
+	}
+	responder = ps.responderWithMiddlewares(responder)
+	return ps.subscribe(subscriberID, subject, nil, func() *asyncWorker[T] {
+		w := newAsyncWorker(func(d delivery[T]) {
+			content, err := callResponder(responder, d.msg.Subject, d.msg.Payload)
+			if err != nil {
+				ps.stats.Load().handlerError(subscriberID, d.msg.Subject)
+			}
+			if d.reply != "" {
+				ps.resolve(d.reply, reply[T]{content: content, err: err})
+			}
+		}, opts)
+		w.responder = true
+		return w
+	})
+}
+
+// callResponder 调用 responder，将 panic 转为错误
+func callResponder[T any](responder Responder[T], subject string, content T) (result T, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("responder panic: %v", r)
+		}
+	}()
+	return responder(subject, content)
+}
+
+// Request 发布请求并等待第一个回复：请求以唯一的回复主题发给匹配的响应者，普通订阅者照常收到该消息。
+// 没有响应者时立即返回 ErrNoResponders，timeout 内没有回复时返回 ErrRequestTimeout。
+func (ps *GenericPubSub[T]) Request(subject string, content T, timeout time.Duration) (T, error) {
+	var zero T
+	if timeout <= 0 {
+		return zero, fmt.Errorf("timeout must be positive")
+	}
+
+	inbox := fmt.Sprintf("%s%d", inboxPrefix, ps.nextInbox.Add(1))
+	ch := make(chan reply[T], 1)
+	ps.mu.Lock()
+	ps.inboxes[inbox] = ch
+	ps.mu.Unlock()
+	defer func() {
+		ps.mu.Lock()
+		delete(ps.inboxes, inbox)
+		ps.mu.Unlock()
+	}()
+
+	responders, err := ps.publish(context.Background(), NewMessage(subject, content), inbox)
+	if err != nil {
+		return zero, err
+	}
+	if responders == 0 {
+		return zero, ErrNoResponders
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-ch:
+		return r.content, r.err
+	case <-timer.C:
+		return zero, ErrRequestTimeout
+	}
+}
+
+// resolve 把回复交给等待中的请求，请求已返回或已有回复时丢弃
+func (ps *GenericPubSub[T]) resolve(inbox string, r reply[T]) {
+	ps.mu.Lock()
+	ch, ok := ps.inboxes[inbox]
+	delete(ps.inboxes, inbox)
+	ps.mu.Unlock()
+
+	if ok {
+		ch <- r
+	}
+}
@@ -0,0 +1,73 @@
+package pubsub
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// PublishRetained 发布消息并将其保留为该主题的最新值：之后订阅到匹配该主题的订阅者会在订阅时立即收到，
+// 适合“当前前 10 名”、配置广播之类的状态型主题。每个主题只保留最近一条，普通 Publish 不影响保留消息。
+func (ps *GenericPubSub[T]) PublishRetained(subject string, content T) error {
+	tokens, err := splitSubject(subject)
+	if err != nil {
+		return err
+	}
+	msg := NewMessage(subject, content)
+	ps.stamp(msg)
+
+	// 更新保留消息与收集 handler 在同一次写锁内完成，新订阅者不会先收到新值再收到旧的保留消息
+	ps.mu.Lock()
+	s := ps.subs.Load()
+	if s.closed {
+		ps.mu.Unlock()
+		return ErrClosed
+	}
+	if err := ps.appendLogLocked(ps.log.Load(), msg, tokens); err != nil {
+		ps.mu.Unlock()
+		return err
+	}
+	ps.retained[subject] = msg
+	handlers, _ := s.matchHandlers(tokens, subject, "")
+	ps.publishing.Add(1)
+	ps.mu.Unlock()
+	defer ps.publishDone()
+	ps.recordPublish(msg, len(handlers))
+
+	for _, h := range handlers {
+		h(context.Background(), msg)
+	}
+	return nil
+}
+
+// Retained 返回主题的保留消息
+func (ps *GenericPubSub[T]) Retained(subject string) (content T, ok bool) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	msg, ok := ps.retained[subject]
+	if !ok {
+		return content, false
+	}
+	return msg.Payload, true
+}
+
+// ClearRetained 删除主题的保留消息，不影响已投递的消息
+func (ps *GenericPubSub[T]) ClearRetained(subject string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	delete(ps.retained, subject)
+}
+
+// matchRetainedLocked 返回与模式匹配的保留消息，按主题排序；调用方需持有锁
+func (ps *GenericPubSub[T]) matchRetainedLocked(tokens []string) []delivery[T] {
+	var matched []delivery[T]
+	for subject, msg := range ps.retained {
+		if matchPattern(tokens, strings.Split(subject, subjectSeparator)) {
+			matched = append(matched, delivery[T]{msg: msg})
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].msg.Subject < matched[j].msg.Subject })
+	return matched
+}
@@ -0,0 +1,103 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultMaxAttempts 重试订阅默认的最多处理次数（含首次处理）
+	DefaultMaxAttempts = 3
+	// DefaultInitialBackoff 重试订阅第一次重试前的默认等待时间
+	DefaultInitialBackoff = 100 * time.Millisecond
+	// DefaultMaxBackoff 重试订阅两次重试之间的默认最长等待时间
+	DefaultMaxBackoff = 10 * time.Second
+)
+
+// HandlerE 可返回错误的订阅者回调函数类型，返回错误或 panic 视为处理失败
+type HandlerE[T any] func(subject string, content T) error
+
+// RetryOptions 重试订阅选项
+type RetryOptions struct {
+	AsyncOptions
+	MaxAttempts    int           // 最多处理次数（含首次处理），小于等于 0 时使用 DefaultMaxAttempts
+	InitialBackoff time.Duration // 第一次重试前的等待时间，之后每次翻倍；小于等于 0 时使用 DefaultInitialBackoff
+	MaxBackoff     time.Duration // 等待时间上限，小于等于 0 时使用 DefaultMaxBackoff
+	// DeadLetterSubject 死信主题前缀，为空时丢弃；最后一次处理仍失败的消息
+	// 以 "<DeadLetterSubject>.<原主题>" 重新发布，可用 "<DeadLetterSubject>.>" 订阅
+	DeadLetterSubject string
+}
+
+// backoff 返回第 attempt 次处理失败后的等待时间
+func (o RetryOptions) backoff(attempt int) time.Duration {
+	d := o.InitialBackoff
+	for i := 1; i < attempt && d < o.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > o.MaxBackoff {
+		d = o.MaxBackoff
+	}
+	return d
+}
+
+// callSafely 调用 handler，将 panic 转为错误
+func callSafely[T any](handler HandlerE[T], subject string, content T) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+		}
+	}()
+	return handler(subject, content)
+}
+
+// SubscribeE 以重试模式订阅主题：消息经该订阅的有界队列异步处理，handler 返回错误或 panic 时
+// 按指数退避重新放回队列，处理 MaxAttempts 次仍失败时发布到死信主题。
+// 重试的消息排在队尾，不保证与其他消息的相对顺序；订阅停止后不再重试。
+func (ps *GenericPubSub[T]) SubscribeE(subscriberID string, subject string, handler HandlerE[T], opts RetryOptions) error {
+	if handler == nil {
+		return errNilHandler
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = DefaultInitialBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = DefaultMaxBackoff
+	}
+	if opts.DeadLetterSubject != "" {
+		if _, err := splitSubject(opts.DeadLetterSubject); err != nil {
+			return fmt.Errorf("invalid dead-letter subject: %w", err)
+		}
+	}
+
+	handler = ps.errorWithMiddlewares(handler)
+	return ps.subscribe(subscriberID, subject, nil, func() *asyncWorker[T] {
+		var w *asyncWorker[T]
+		w = newAsyncWorker(func(d delivery[T]) {
+			if callSafely(handler, d.msg.Subject, d.msg.Payload) == nil {
+				return
+			}
+			ps.stats.Load().handlerError(subscriberID, d.msg.Subject)
+			if d.attempt < opts.MaxAttempts {
+				w.redelivered.Add(1)
+				d.attempt++
+				time.AfterFunc(opts.backoff(d.attempt-1), func() { w.enqueue(context.Background(), d, true) })
+				return
+			}
+			w.deadLettered.Add(1)
+			// 死信本身处理失败时不再转发，避免订阅了死信主题的订阅者循环发布
+			if opts.DeadLetterSubject != "" && !strings.HasPrefix(d.msg.Subject, opts.DeadLetterSubject+subjectSeparator) {
+				ps.PublishMsg(context.Background(), &Message[T]{
+					Subject: opts.DeadLetterSubject + subjectSeparator + d.msg.Subject,
+					Payload: d.msg.Payload,
+					Headers: d.msg.Headers,
+				})
+			}
+		}, opts.AsyncOptions)
+		return w
+	})
+}
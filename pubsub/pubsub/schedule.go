@@ -0,0 +1,41 @@
+package pubsub
+
+import (
+	"crontab"
+	"errors"
+	"log"
+)
+
+// SchedulePublish 按 crontab 表达式定时发布 contentFn 生成的消息，例如 "0 * * * *" 每小时发布一次“刷新排行榜缓存”事件。
+// 表达式格式见 crontab.ParseSpec，主题与表达式在调用时校验；调度依赖 crontab 模块，需先调用 crontab.Initialize。
+// contentFn 在 crontab 的调度协程中执行，发布失败时记录日志；服务关闭后任务自动取消，返回的 cancel 也可提前取消。
+func (ps *GenericPubSub[T]) SchedulePublish(cronSpec string, subject string, contentFn func() T) (cancel func(), err error) {
+	if _, err := splitSubject(subject); err != nil {
+		return nil, err
+	}
+	if contentFn == nil {
+		return nil, errors.New("contentFn is nil")
+	}
+	if ps.subs.Load().closed {
+		return nil, ErrClosed
+	}
+
+	var handle crontab.Handle
+	registered := make(chan struct{})
+	handle, err = crontab.RegisterSpec(cronSpec, func() {
+		<-registered
+		err := ps.Publish(subject, contentFn())
+		if errors.Is(err, ErrClosed) {
+			handle.Unregister()
+			return
+		}
+		if err != nil {
+			log.Printf("pubsub: scheduled publish to %s failed: %v", subject, err)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	close(registered)
+	return handle.Unregister, nil
+}
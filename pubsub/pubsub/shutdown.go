@@ -0,0 +1,88 @@
+package pubsub
+
+import (
+	"common"
+	"context"
+	"errors"
+)
+
+// ErrClosed Close 或 Drain 之后发布或订阅
+var ErrClosed = errors.New("pubsub is closed")
+
+// Drain 优雅关闭：立即停止接受新的发布与订阅（返回 ErrClosed），等待进行中的 Publish 返回、
+// 异步订阅者处理完队列中的消息后，释放前缀树、订阅与保留消息，关闭消息日志并取消上下文感知的 handler 的 ctx；
+// 等待回复的 Request 返回 ErrClosed。ctx 结束时返回 ctx.Err()，关闭流程在后台继续，可再次调用 Drain 等待。
+// 重复调用是安全的；不能在 handler 中调用。Stats 与 TopicStats 在关闭后仍可读取。
+func (ps *GenericPubSub[T]) Drain(ctx context.Context) error {
+	ps.closeOnce.Do(func() {
+		ps.mu.Lock()
+		s := ps.editLocked()
+		s.closed = true
+		ps.subs.Store(s)
+		ps.mu.Unlock()
+		go ps.drain()
+	})
+
+	select {
+	case <-ps.drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close 与 Drain 相同，但先取消上下文感知的 handler 的 ctx，让它们尽快返回；阻塞直到关闭完成
+func (ps *GenericPubSub[T]) Close() {
+	ps.cancel()
+	ps.Drain(context.Background())
+}
+
+// drain 等待进行中的投递结束并释放内部状态，完成后关闭 drained
+func (ps *GenericPubSub[T]) drain() {
+	// 快照已标记关闭，之后登记的发布都会被拒绝
+	for ps.publishing.Load() > 0 {
+		<-ps.idle
+	}
+
+	ps.mu.Lock()
+	workers := ps.subs.Load().workers
+	inboxes := ps.inboxes
+	s := newSubscriptions[T](0)
+	s.closed = true
+	ps.subs.Store(s)
+	ps.subscriberSubjects = map[string]common.StringSet{}
+	ps.generations = map[string]uint64{}
+	ps.retained = map[string]*Message[T]{}
+	ps.inboxes = map[string]chan reply[T]{}
+	ps.mu.Unlock()
+
+	for _, ch := range inboxes {
+		ch <- reply[T]{err: ErrClosed}
+	}
+	for _, w := range workers {
+		w.stop()
+	}
+	for _, w := range workers {
+		<-w.done
+	}
+
+	// 异步 handler 处理完之后再关闭日志，它们发布的消息已被拒绝，不会再写入
+	ps.mu.Lock()
+	if l := ps.log.Swap(nil); l != nil {
+		l.close()
+	}
+	ps.mu.Unlock()
+	ps.cancel()
+	close(ps.drained)
+}
+
+// publishDone 结束一次发布；关闭后进行中的发布数降为 0 时通知 drain
+func (ps *GenericPubSub[T]) publishDone() {
+	// 发布者先登记再读取快照，drain 先替换快照再读取计数，两者总有一方看到对方的修改
+	if ps.publishing.Add(-1) == 0 && ps.subs.Load().closed {
+		select {
+		case ps.idle <- struct{}{}:
+		default:
+		}
+	}
+}
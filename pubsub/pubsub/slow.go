@@ -0,0 +1,155 @@
+package pubsub
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultSlowSustain 订阅持续超限多久后视为慢订阅者的默认值
+	DefaultSlowSustain = 10 * time.Second
+	// DefaultSlowCheckInterval 慢订阅者检测的默认检查间隔
+	DefaultSlowCheckInterval = time.Second
+)
+
+// SlowSubscriberOptions 慢订阅者检测选项，MaxLatency 与 MaxQueued 至少设置一个
+type SlowSubscriberOptions struct {
+	// MaxLatency 处理耗时阈值：检查周期内的平均耗时或正在处理的消息已用的时间超过它时视为超限，小于等于 0 时不检查
+	MaxLatency time.Duration
+	MaxQueued  int           // 队列中等待处理的消息数阈值，小于等于 0 时不检查
+	Sustain    time.Duration // 持续超限多久后视为慢订阅者，小于等于 0 时使用 DefaultSlowSustain
+	Interval   time.Duration // 检查间隔，小于等于 0 时使用 DefaultSlowCheckInterval
+	Evict      bool          // 为 true 时自动取消慢订阅者的该订阅
+	// OnSlow 发现慢订阅者时调用，为 nil 时记录日志；在检测协程中执行
+	OnSlow func(event SlowSubscriberEvent)
+}
+
+// SlowSubscriberEvent 发现慢订阅者时的事件
+type SlowSubscriberEvent struct {
+	SubscriberID string
+	Subject      string        // 订阅模式
+	Latency      time.Duration // 最近一次检查时的处理耗时
+	Queued       int           // 最近一次检查时队列中等待处理的消息数
+	Capacity     int
+	Since        time.Time // 开始持续超限的时间
+	Evicted      bool      // 是否已自动取消该订阅
+}
+
+// slowState 检测协程中单个异步订阅的状态
+type slowState struct {
+	handled      int64
+	handlerNanos int64
+	since        time.Time // 开始超限的时间，未超限时为零值
+	reported     bool      // 本次超限是否已报告
+}
+
+// WatchSlowSubscribers 启动慢订阅者检测：每隔 opts.Interval 检查所有异步订阅的处理耗时与队列深度，
+// 持续超限 opts.Sustain 的订阅报告一次（恢复后再次超限时重新报告），opts.Evict 为 true 时自动取消该订阅，
+// 避免拖累整个服务。同步订阅的 handler 在发布者协程中执行，不在检测范围内。
+// 返回的函数停止检测，重复调用是安全的；Close 或 Drain 完成后检测自动停止。
+func (ps *GenericPubSub[T]) WatchSlowSubscribers(opts SlowSubscriberOptions) (stop func(), err error) {
+	if opts.MaxLatency <= 0 && opts.MaxQueued <= 0 {
+		return nil, errors.New("MaxLatency or MaxQueued must be positive")
+	}
+	if opts.Sustain <= 0 {
+		opts.Sustain = DefaultSlowSustain
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultSlowCheckInterval
+	}
+
+	stopped := make(chan struct{})
+	var once sync.Once
+	go func() {
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		states := map[*asyncWorker[T]]*slowState{}
+		for {
+			select {
+			case now := <-ticker.C:
+				ps.checkSlowSubscribers(opts, states, now)
+			case <-stopped:
+				return
+			case <-ps.drained:
+				return
+			}
+		}
+	}()
+	return func() { once.Do(func() { close(stopped) }) }, nil
+}
+
+// checkSlowSubscribers 检查一次所有异步订阅，states 记录各订阅上一次检查时的状态
+func (ps *GenericPubSub[T]) checkSlowSubscribers(opts SlowSubscriberOptions, states map[*asyncWorker[T]]*slowState, now time.Time) {
+	type subscription struct {
+		w          *asyncWorker[T]
+		generation uint64
+	}
+	ps.mu.RLock()
+	workers := ps.subs.Load().workers
+	subscriptions := make([]subscription, 0, len(workers))
+	for key, w := range workers {
+		subscriptions = append(subscriptions, subscription{w: w, generation: ps.generations[key]})
+	}
+	ps.mu.RUnlock()
+
+	current := make(map[*asyncWorker[T]]bool, len(subscriptions))
+	for _, sub := range subscriptions {
+		w := sub.w
+		current[w] = true
+		state, ok := states[w]
+		if !ok {
+			state = &slowState{}
+			states[w] = state
+		}
+
+		// 检查周期内的平均耗时，与正在处理的消息已用的时间取较大者
+		handled, nanos := w.handled.Load(), w.handlerNanos.Load()
+		var latency time.Duration
+		if n := handled - state.handled; n > 0 {
+			latency = time.Duration((nanos - state.handlerNanos) / n)
+		}
+		state.handled, state.handlerNanos = handled, nanos
+		for i := range w.busySince {
+			if since := w.busySince[i].Load(); since != 0 {
+				latency = max(latency, now.Sub(time.Unix(0, since)))
+			}
+		}
+		s := w.stats()
+
+		if (opts.MaxLatency <= 0 || latency <= opts.MaxLatency) && (opts.MaxQueued <= 0 || s.Queued <= opts.MaxQueued) {
+			state.since, state.reported = time.Time{}, false
+			continue
+		}
+		if state.since.IsZero() {
+			state.since = now
+		}
+		if state.reported || now.Sub(state.since) < opts.Sustain {
+			continue
+		}
+		state.reported = true
+		event := SlowSubscriberEvent{
+			SubscriberID: w.subscriberID,
+			Subject:      w.pattern,
+			Latency:      latency,
+			Queued:       s.Queued,
+			Capacity:     s.Capacity,
+			Since:        state.since,
+		}
+		if opts.Evict {
+			event.Evicted = ps.unsubscribeGeneration(w.subscriberID, w.pattern, sub.generation)
+		}
+		if opts.OnSlow != nil {
+			opts.OnSlow(event)
+			continue
+		}
+		log.Printf("pubsub: slow subscriber %s on %s: latency %v, queued %d/%d since %v, evicted: %v",
+			event.SubscriberID, event.Subject, event.Latency, event.Queued, event.Capacity, event.Since.Format(time.RFC3339), event.Evicted)
+	}
+	for w := range states {
+		if !current[w] {
+			delete(states, w)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package pubsub
+
+import (
+	"common"
+	"maps"
+)
+
+// subscriptions 订阅结构的不可变快照：前缀树、handler 与异步处理协程。
+// 订阅变化时在写锁内复制一份修改后整体替换（前缀树只复制变化的路径），发布者无锁读取当前快照，互不争用。
+type subscriptions[T any] struct {
+	root     *subjectNode
+	handlers map[string]MsgHandler[T]   // 订阅 -> handler
+	workers  map[string]*asyncWorker[T] // 订阅 -> 异步订阅的队列与处理协程
+	matches  *matchCache                // 本快照的发布匹配缓存，快照替换后随之丢弃
+	closed   bool                       // Close 或 Drain 之后为 true，不再接受发布与订阅
+}
+
+// newSubscriptions 创建空的快照
+func newSubscriptions[T any](matchCacheSize int) *subscriptions[T] {
+	return &subscriptions[T]{
+		root:     newSubjectNode(),
+		handlers: map[string]MsgHandler[T]{},
+		workers:  map[string]*asyncWorker[T]{},
+		matches:  newMatchCache(matchCacheSize),
+	}
+}
+
+// editLocked 复制当前快照供修改，修改后以 ps.subs.Store 发布；调用方需持有写锁
+func (ps *GenericPubSub[T]) editLocked() *subscriptions[T] {
+	cur := ps.subs.Load()
+	return &subscriptions[T]{
+		root:     cur.root,
+		handlers: maps.Clone(cur.handlers),
+		workers:  maps.Clone(cur.workers),
+		matches:  newMatchCache(ps.matchCacheSize),
+		closed:   cur.closed,
+	}
+}
+
+// stopWorker 停止订阅的异步处理协程（如有）并从快照中移除，key 见 subscriptionKey；只用于尚未发布的快照
+func (s *subscriptions[T]) stopWorker(key string) {
+	if w, ok := s.workers[key]; ok {
+		w.stop()
+		delete(s.workers, key)
+	}
+}
+
+// match 返回与主题匹配的订阅键，优先读取缓存
+func (s *subscriptions[T]) match(tokens []string, subject string) []string {
+	if keys, ok := s.matches.get(subject); ok {
+		return keys
+	}
+	matched := common.StringSet{}
+	s.root.match(tokens, matched)
+	keys := make([]string, 0, len(matched))
+	for key := range matched {
+		keys = append(keys, key)
+	}
+	s.matches.put(subject, keys)
+	return keys
+}
+
+// matchHandlers 收集与主题匹配的订阅的 handler，并统计其中的响应者
+func (s *subscriptions[T]) matchHandlers(tokens []string, subject string, reply string) ([]MsgHandler[T], int) {
+	matched := s.match(tokens, subject)
+	handlers := make([]MsgHandler[T], 0, len(matched))
+	responders := 0
+	for _, key := range matched {
+		if w, ok := s.workers[key]; ok && w.responder {
+			responders++
+			if reply != "" {
+				handlers = append(handlers, w.deliverWithReply(reply))
+				continue
+			}
+		}
+		if h, ok := s.handlers[key]; ok {
+			handlers = append(handlers, h)
+		}
+	}
+	return handlers, responders
+}
+
+// queueFull 是否有与主题匹配的异步订阅者的队列已满
+func (s *subscriptions[T]) queueFull(tokens []string, subject string) bool {
+	for _, key := range s.match(tokens, subject) {
+		if w, ok := s.workers[key]; ok && w.full(subject) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+package pubsub
+
+import (
+	"common"
+	"fmt"
+	"strings"
+)
+
+const (
+	subjectSeparator = "."
+	wildcardToken    = "*" // 匹配恰好一个分段
+	tailToken        = ">" // 匹配其后的一个或多个分段，只能作为最后一个分段
+)
+
+// splitPattern 拆分并校验订阅模式：分段不能为空，'*' 与 '>' 必须独占一个分段，'>' 只能位于末尾
+func splitPattern(pattern string) ([]string, error) {
+	tokens := strings.Split(pattern, subjectSeparator)
+	for i, token := range tokens {
+		switch {
+		case token == "":
+			return nil, fmt.Errorf("subject %q contains an empty segment", pattern)
+		case token == tailToken && i != len(tokens)-1:
+			return nil, fmt.Errorf("'>' can only be used as the last segment of subject")
+		case token != wildcardToken && token != tailToken && strings.ContainsAny(token, wildcardToken+tailToken):
+			return nil, fmt.Errorf("wildcard must be a whole segment, got %q", token)
+		}
+	}
+	return tokens, nil
+}
+
+// splitSubject 拆分并校验发布主题：分段不能为空，且不能包含通配符
+func splitSubject(subject string) ([]string, error) {
+	if strings.ContainsAny(subject, wildcardToken+tailToken) {
+		return nil, fmt.Errorf("subject should not contain '*' or '>' while publishing")
+	}
+	tokens := strings.Split(subject, subjectSeparator)
+	for _, token := range tokens {
+		if token == "" {
+			return nil, fmt.Errorf("subject %q contains an empty segment", subject)
+		}
+	}
+	return tokens, nil
+}
+
+// matchPattern 判断主题分段是否与模式分段匹配，用于逐个检查主题而不建树的场景
+func matchPattern(pattern, subject []string) bool {
+	for i, token := range pattern {
+		if token == tailToken {
+			return len(subject) > i
+		}
+		if i >= len(subject) || (token != wildcardToken && token != subject[i]) {
+			return false
+		}
+	}
+	return len(pattern) == len(subject)
+}
+
+// MatchSubject 判断主题是否与订阅模式匹配，模式或主题不合法时返回 false
+func MatchSubject(pattern, subject string) bool {
+	patternTokens, err := splitPattern(pattern)
+	if err != nil {
+		return false
+	}
+	subjectTokens, err := splitSubject(subject)
+	if err != nil {
+		return false
+	}
+	return matchPattern(patternTokens, subjectTokens)
+}
+
+// patternsOverlap 判断两个模式是否可能匹配同一个主题
+func patternsOverlap(a, b []string) bool {
+	for i := 0; ; i++ {
+		switch {
+		case i == len(a) || i == len(b):
+			return len(a) == len(b)
+		case a[i] == tailToken || b[i] == tailToken:
+			return true
+		case a[i] != b[i] && a[i] != wildcardToken && b[i] != wildcardToken:
+			return false
+		}
+	}
+}
+
+// patternCovers 判断模式 a 是否覆盖模式 b：b 可能匹配的每个主题都与 a 匹配
+func patternCovers(a, b []string) bool {
+	for i := 0; ; i++ {
+		switch {
+		case i == len(a) || i == len(b):
+			return len(a) == len(b)
+		case a[i] == tailToken:
+			return true
+		case b[i] == tailToken || (a[i] != wildcardToken && a[i] != b[i]):
+			return false
+		}
+	}
+}
+
+// subjectNode 分段前缀树的节点，每层对应主题的一个分段，'*' 作为普通分段存储
+type subjectNode struct {
+	children map[string]*subjectNode
+	// subscribers 模式恰好在此节点结束的订阅者
+	subscribers common.StringSet
+	// tailSubscribers 模式以 '>' 结束、前缀为此节点的订阅者，匹配其后的一个或多个分段
+	tailSubscribers common.StringSet
+}
+
+func newSubjectNode() *subjectNode {
+	return &subjectNode{
+		children:        map[string]*subjectNode{},
+		subscribers:     common.StringSet{},
+		tailSubscribers: common.StringSet{},
+	}
+}
+
+// add 按分段下钻（不存在时创建），把订阅者挂到模式对应的集合上
+func (n *subjectNode) add(tokens []string, subscriberID string) {
+	for i, token := range tokens {
+		if token == tailToken && i == len(tokens)-1 {
+			n.tailSubscribers.Add(subscriberID)
+			return
+		}
+		child, ok := n.children[token]
+		if !ok {
+			child = newSubjectNode()
+			n.children[token] = child
+		}
+		n = child
+	}
+	n.subscribers.Add(subscriberID)
+}
+
+// with 返回挂上订阅者后的新树：只复制模式路径上的节点，其余节点与原树共用，原树不变
+func (n *subjectNode) with(tokens []string, subscriberID string) *subjectNode {
+	c := n.clone()
+	switch {
+	case len(tokens) == 0:
+		c.subscribers = copySet(n.subscribers)
+		c.subscribers.Add(subscriberID)
+	case len(tokens) == 1 && tokens[0] == tailToken:
+		c.tailSubscribers = copySet(n.tailSubscribers)
+		c.tailSubscribers.Add(subscriberID)
+	default:
+		child, ok := n.children[tokens[0]]
+		if !ok {
+			child = newSubjectNode()
+		}
+		c.children[tokens[0]] = child.with(tokens[1:], subscriberID)
+	}
+	return c
+}
+
+// without 返回移除订阅者并剪掉不再使用的节点后的新树，原树不变；新树为空时返回 nil
+func (n *subjectNode) without(tokens []string, subscriberID string) *subjectNode {
+	c := n.clone()
+	switch {
+	case len(tokens) == 0:
+		c.subscribers = copySet(n.subscribers)
+		c.subscribers.Remove(subscriberID)
+	case len(tokens) == 1 && tokens[0] == tailToken:
+		c.tailSubscribers = copySet(n.tailSubscribers)
+		c.tailSubscribers.Remove(subscriberID)
+	default:
+		child, ok := n.children[tokens[0]]
+		if !ok {
+			return n
+		}
+		if next := child.without(tokens[1:], subscriberID); next != nil {
+			c.children[tokens[0]] = next
+		} else {
+			delete(c.children, tokens[0])
+		}
+	}
+	if len(c.children) == 0 && len(c.subscribers) == 0 && len(c.tailSubscribers) == 0 {
+		return nil
+	}
+	return c
+}
+
+// clone 复制节点本身，子节点与订阅者集合仍与原节点共用
+func (n *subjectNode) clone() *subjectNode {
+	c := *n
+	c.children = make(map[string]*subjectNode, len(n.children))
+	for token, child := range n.children {
+		c.children[token] = child
+	}
+	return &c
+}
+
+// copySet 复制订阅者集合
+func copySet(set common.StringSet) common.StringSet {
+	c := make(common.StringSet, len(set))
+	for id := range set {
+		c.Add(id)
+	}
+	return c
+}
+
+// match 收集与主题分段匹配的订阅者 - O(匹配路径上的节点数)
+func (n *subjectNode) match(tokens []string, matched common.StringSet) {
+	if len(tokens) == 0 {
+		for id := range n.subscribers {
+			matched.Add(id)
+		}
+		return
+	}
+	for id := range n.tailSubscribers {
+		matched.Add(id)
+	}
+	if child, ok := n.children[tokens[0]]; ok {
+		child.match(tokens[1:], matched)
+	}
+	if child, ok := n.children[wildcardToken]; ok {
+		child.match(tokens[1:], matched)
+	}
+}
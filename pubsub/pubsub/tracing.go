@@ -0,0 +1,116 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName 创建 span 的 instrumentation 名称
+const tracerName = "pubsub"
+
+// TracingOptions 链路追踪选项
+type TracingOptions struct {
+	TracerProvider trace.TracerProvider // 为 nil 时使用 otel.GetTracerProvider()
+	// Propagator 追踪上下文与消息头之间的编解码，为 nil 时使用 W3C Trace Context 与 Baggage
+	Propagator propagation.TextMapPropagator
+}
+
+// tracing 开启链路追踪后的 tracer 与 propagator，只整体替换
+type tracing struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// EnableTracing 开启 OpenTelemetry 链路追踪，可重复调用以替换选项：
+// 发布时创建 "publish <subject>" span（Producer），并把追踪上下文写入消息头（如 traceparent），随消息传给订阅者与桥接的远端；
+// 每次调用 handler 时创建 "process <subject>" span（Consumer），父 span 为发布时的 span，handler panic 时标记为错误。
+// 同步 handler 的 ctx 携带该 span，handler 中以 PublishCtx(ctx, ...) 继续发布即可串起整条链路；
+// 异步 handler 的 ctx 不携带 span，可用 TraceContext 从消息头恢复追踪上下文。
+func (ps *GenericPubSub[T]) EnableTracing(opts TracingOptions) {
+	provider := opts.TracerProvider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	propagator := opts.Propagator
+	if propagator == nil {
+		propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+	}
+	ps.tracing.Store(&tracing{tracer: provider.Tracer(tracerName), propagator: propagator})
+}
+
+// DisableTracing 关闭链路追踪
+func (ps *GenericPubSub[T]) DisableTracing() {
+	ps.tracing.Store(nil)
+}
+
+// TraceContext 从消息头恢复追踪上下文并放入 ctx，未开启链路追踪或消息不带追踪上下文时原样返回 ctx
+func (ps *GenericPubSub[T]) TraceContext(ctx context.Context, msg *Message[T]) context.Context {
+	t := ps.tracing.Load()
+	if t == nil || msg.Headers == nil {
+		return ctx
+	}
+	return t.propagator.Extract(ctx, propagation.MapCarrier(msg.Headers))
+}
+
+// startPublishSpan 创建发布 span 并把追踪上下文写入消息头；ctx 不带 span 时以消息头中的追踪上下文（如桥接收到的消息）为父 span
+func startPublishSpan[T any](t *tracing, ctx context.Context, msg *Message[T]) (context.Context, trace.Span) {
+	if msg.Headers == nil {
+		msg.Headers = map[string]string{}
+	}
+	carrier := propagation.MapCarrier(msg.Headers)
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = t.propagator.Extract(ctx, carrier)
+	}
+	ctx, span := t.tracer.Start(ctx, "publish "+msg.Subject,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(messageAttributes(msg, "publish")...))
+	t.propagator.Inject(ctx, carrier)
+	return ctx, span
+}
+
+// startProcessSpan 创建一次 handler 调用的 span；ctx 不带 span 时（异步处理）从消息头恢复父 span
+func startProcessSpan[T any](t *tracing, ctx context.Context, subscriberID string, msg *Message[T]) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() && msg.Headers != nil {
+		ctx = t.propagator.Extract(ctx, propagation.MapCarrier(msg.Headers))
+	}
+	attrs := append(messageAttributes(msg, "process"), attribute.String("messaging.consumer.group.name", subscriberID))
+	return t.tracer.Start(ctx, "process "+msg.Subject,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attrs...))
+}
+
+// messageAttributes 按 OpenTelemetry 消息语义约定返回消息的属性
+func messageAttributes[T any](msg *Message[T], operation string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("messaging.system", tracerName),
+		attribute.String("messaging.operation.type", operation),
+		attribute.String("messaging.destination.name", msg.Subject),
+		attribute.String("messaging.message.id", msg.ID),
+	}
+}
+
+// endSpan 结束 span，err 非 nil 时标记为错误；span 为 nil（未开启链路追踪）时什么也不做
+func endSpan(span trace.Span, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// panicError 将 recover() 的返回值转为 endSpan 使用的错误，没有 panic 时为 nil
+func panicError(recovered any) error {
+	if recovered == nil {
+		return nil
+	}
+	return fmt.Errorf("handler panicked: %v", recovered)
+}
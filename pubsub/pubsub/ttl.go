@@ -0,0 +1,42 @@
+package pubsub
+
+import (
+	"crontab"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ttlTickInterval 订阅过期调度器的检查间隔，即过期时间的精度
+const ttlTickInterval = 50 * time.Millisecond
+
+var (
+	ttlScheduler     *crontab.Scheduler // 所有发布订阅服务共用的订阅过期调度器，首次使用时启动
+	ttlSchedulerOnce sync.Once
+)
+
+// expireAfter 在 ttl 之后调用 fn
+func expireAfter(ttl time.Duration, fn func()) {
+	ttlSchedulerOnce.Do(func() {
+		ttlScheduler = crontab.NewScheduler()
+		ttlScheduler.Start(ttlTickInterval)
+	})
+	ttlScheduler.AddCallback(ttl, fn)
+}
+
+// SubscribeWithTTL 订阅主题，ttl 之后自动取消该订阅，适合“观战这场比赛 10 分钟”这类临时监听；
+// 过期时间的精度约为 50ms。过期前订阅被替换或取消时，过期不再影响新的订阅。
+func (ps *GenericPubSub[T]) SubscribeWithTTL(subscriberID string, subject string, handler Handler[T], ttl time.Duration) error {
+	if handler == nil {
+		return errNilHandler
+	}
+	if ttl <= 0 {
+		return fmt.Errorf("ttl must be positive, got %v", ttl)
+	}
+	generation, err := ps.subscribeGeneration(subscriberID, subject, fromHandler(ps.withMiddlewares(handler)), nil)
+	if err != nil {
+		return err
+	}
+	expireAfter(ttl, func() { ps.unsubscribeGeneration(subscriberID, subject, generation) })
+	return nil
+}
@@ -3,6 +3,7 @@ package crontab
 import (
 	"fmt"
 	"gwutils"
+	"sync"
 	"time"
 )
 
@@ -15,6 +16,7 @@ type Handle int
 
 var (
 	cronScheduler    = NewScheduler()
+	entriesLock      sync.Mutex            // 保护以下注册状态，允许在其他 goroutine 中注册或取消任务
 	cancelledHandles = []Handle{}          // 待取消的任务句柄列表
 	entries          = map[Handle]*entry{} // 所有注册的定时任务条目
 	nextHandle       = Handle(1)           // 下一个可用的任务句柄
@@ -72,6 +74,9 @@ func (entry *entry) match(minute int, hour int, day int, month time.Month, weekd
 func Register(minute, hour, day, month, dayofweek int, cb func()) Handle {
 	validateTime(minute, hour, day, month, dayofweek)
 
+	entriesLock.Lock()
+	defer entriesLock.Unlock()
+
 	h := genNextHandle()
 	entries[h] = &entry{
 		minute:    minute,
@@ -112,16 +117,23 @@ func genNextHandle() (h Handle) {
 
 // Unregister 取消注册一个定时任务
 func (h Handle) Unregister() {
+	entriesLock.Lock()
+	defer entriesLock.Unlock()
+
 	cancelledHandles = append(cancelledHandles, h)
 }
 
 // reset resets the crontab state for testing.
 func reset() {
+	entriesLock.Lock()
+	defer entriesLock.Unlock()
+
 	entries = make(map[Handle]*entry)
 	nextHandle = Handle(1)
 	cancelledHandles = []Handle{}
 }
 
+// unregisterCancelledHandles 清理已取消的任务，调用方需持有 entriesLock
 func unregisterCancelledHandles() {
 	for _, h := range cancelledHandles {
 		fmt.Printf("unregisterCancelledHandles: cancelling %d", h)
@@ -151,18 +163,28 @@ func Initialize() {
 }
 
 func check(now time.Time) {
+	entriesLock.Lock()
 	unregisterCancelledHandles()
 
 	fmt.Printf("Crontab: checking %d callbacks ...", len(entries))
 	dayofweek, month, day, hour, minute := now.Weekday(), now.Month(), now.Day(), now.Hour(), now.Minute()
 
+	// 回调在锁外执行，以便回调中可以注册或取消任务
+	var callbacks []func()
 	for _, entry := range entries {
 		if entry.match(minute, hour, day, month, dayofweek) {
-			gwutils.RunPanicless(entry.callback)
+			callbacks = append(callbacks, entry.callback)
 		}
 	}
+	entriesLock.Unlock()
+
+	for _, cb := range callbacks {
+		gwutils.RunPanicless(cb)
+	}
 
+	entriesLock.Lock()
 	unregisterCancelledHandles()
+	entriesLock.Unlock()
 }
 
 func checkNow() {
@@ -76,4 +76,42 @@ func TestCrontab_SpecificTime(t *testing.T) {
 		t.Fatal("Callback 2 was triggered at the wrong time")
 	}
 	lock.Unlock()
-}
\ No newline at end of file
+}
+func TestCrontab_RegisterSpec(t *testing.T) {
+	reset()
+
+	var lock sync.Mutex
+	triggered := 0
+
+	// 每小时第 0 分钟触发
+	if _, err := RegisterSpec("0 * * * *", func() {
+		lock.Lock()
+		triggered++
+		lock.Unlock()
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	check(time.Date(2025, 10, 27, 10, 0, 0, 0, time.UTC))
+	check(time.Date(2025, 10, 27, 10, 30, 0, 0, time.UTC))
+
+	lock.Lock()
+	if triggered != 1 {
+		t.Fatalf("Callback triggered %d times, want 1", triggered)
+	}
+	lock.Unlock()
+
+	minute, hour, day, month, dayofweek, err := ParseSpec("*/15 3 * */2 7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if minute != -15 || hour != 3 || day != -1 || month != -2 || dayofweek != 7 {
+		t.Fatalf("ParseSpec returned %d %d %d %d %d", minute, hour, day, month, dayofweek)
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "a * * * *", "* * * * */2", "-5 * * * *"} {
+		if _, _, _, _, _, err := ParseSpec(spec); err == nil {
+			t.Fatalf("ParseSpec(%q) should fail", spec)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package crontab
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseSpec 解析 5 段 crontab 表达式“分 时 日 月 星期”，返回 Register 的参数。
+// 每段支持 "*"、确切值 "n" 与步长 "*/n"；星期只支持 "*" 与确切值（0 和 7 均为周日）。
+func ParseSpec(spec string) (minute, hour, day, month, dayofweek int, err error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return 0, 0, 0, 0, 0, fmt.Errorf("crontab spec %q: expected 5 fields, got %d", spec, len(fields))
+	}
+	values := make([]int, len(fields))
+	for i, field := range fields {
+		if values[i], err = parseField(field); err != nil {
+			return 0, 0, 0, 0, 0, fmt.Errorf("crontab spec %q: %v", spec, err)
+		}
+	}
+	minute, hour, day, month, dayofweek = values[0], values[1], values[2], values[3], values[4]
+	if dayofweek < -1 {
+		return 0, 0, 0, 0, 0, fmt.Errorf("crontab spec %q: step is not supported for day of week", spec)
+	}
+	if !validateTime(minute, hour, day, month, dayofweek) {
+		return 0, 0, 0, 0, 0, fmt.Errorf("crontab spec %q: value out of range", spec)
+	}
+	return minute, hour, day, month, dayofweek, nil
+}
+
+// parseField 解析表达式的一段，"*" 返回 -1，"*/n" 返回 -n
+func parseField(field string) (int, error) {
+	if field == "*" {
+		return -1, nil
+	}
+	step, isStep := strings.CutPrefix(field, "*/")
+	n, err := strconv.Atoi(step)
+	if err != nil || n < 0 || (isStep && n == 0) {
+		return 0, fmt.Errorf("invalid field %q", field)
+	}
+	if isStep {
+		return -n, nil
+	}
+	return n, nil
+}
+
+// RegisterSpec 以 crontab 表达式注册定时任务，表达式格式见 ParseSpec
+func RegisterSpec(spec string, cb func()) (Handle, error) {
+	minute, hour, day, month, dayofweek, err := ParseSpec(spec)
+	if err != nil {
+		return 0, err
+	}
+	return Register(minute, hour, day, month, dayofweek, cb), nil
+}
@@ -2,6 +2,7 @@ package timeWheel
 
 import (
 	"log"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -40,4 +41,113 @@ func TestTimeWheel(t *testing.T) {
 
 	// Stop the time wheel
 	tw.Stop()
-}
\ No newline at end of file
+}
+
+func TestSchedule(t *testing.T) {
+	tw := NewTimeWheel(10, 64, time.Now().UnixNano()/1e6, NewDelayQueue(64))
+	tw.Start()
+	defer tw.Stop()
+
+	fired := make(chan int64, 2)
+	start := time.Now()
+	tw.Schedule(start.Add(100*time.Millisecond).UnixNano()/1e6, func() { fired <- time.Since(start).Milliseconds() })
+	var cancelledRan atomic.Bool
+	cancelled := tw.Schedule(start.Add(200*time.Millisecond).UnixNano()/1e6, func() { cancelledRan.Store(true) })
+	if !cancelled.Stop() {
+		t.Fatal("pending task should be cancellable")
+	}
+
+	select {
+	case elapsed := <-fired:
+		if elapsed < 90 {
+			t.Fatalf("task ran too early: %dms", elapsed)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("task did not run")
+	}
+	time.Sleep(200 * time.Millisecond)
+	if cancelledRan.Load() {
+		t.Fatal("cancelled task ran")
+	}
+}
+
+func TestAddTask(t *testing.T) {
+	tw := NewTimeWheel(10, 64, time.Now().UnixNano()/1e6, NewDelayQueue(64))
+	tw.Start()
+	defer tw.Stop()
+
+	fired := make(chan string, 4)
+	tw.AddTask(50*time.Millisecond, "a", func() { fired <- "a" })
+	cancelled := tw.AddTask(50*time.Millisecond, nil, func() { fired <- "cancelled" })
+	if !cancelled.Cancel() || cancelled.Cancel() {
+		t.Fatal("pending task should be cancellable exactly once")
+	}
+	// 跨越上层时间轮的任务同样可以按 key 取消
+	tw.AddTask(2*time.Second, "long", func() { fired <- "long" })
+	if !tw.RemoveTask("long") || tw.RemoveTask("long") {
+		t.Fatal("keyed task should be removable exactly once")
+	}
+	// 相同 key 的任务替换原有的任务
+	replaced := tw.AddTask(80*time.Millisecond, "b", func() { fired <- "b1" })
+	tw.AddTask(100*time.Millisecond, "b", func() { fired <- "b2" })
+	if replaced.Cancel() {
+		t.Fatal("replaced task should already be cancelled")
+	}
+
+	for _, want := range []string{"a", "b2"} {
+		select {
+		case got := <-fired:
+			if got != want {
+				t.Fatalf("got %q, want %q", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("task %q did not run", want)
+		}
+	}
+	if tw.RemoveTask("a") {
+		t.Fatal("executed task should leave the index")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if len(fired) != 0 {
+		t.Fatalf("unexpected task ran: %q", <-fired)
+	}
+}
+
+func TestAddRepeatingTask(t *testing.T) {
+	tw := NewTimeWheel(10, 64, time.Now().UnixNano()/1e6, NewDelayQueue(64))
+	tw.Start()
+	defer tw.Stop()
+
+	// 任务内部在第 3 次执行后停止
+	var runs atomic.Int32
+	stopped := make(chan struct{})
+	tw.AddRepeatingTask(20*time.Millisecond, "tick", func(h *TaskHandle) {
+		if runs.Add(1) == 3 {
+			h.Cancel()
+			close(stopped)
+		}
+	})
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("repeating task ran %d times", runs.Load())
+	}
+
+	// 外部按 key 停止
+	var external atomic.Int32
+	tw.AddRepeatingTask(20*time.Millisecond, "external", func(*TaskHandle) { external.Add(1) })
+	time.Sleep(150 * time.Millisecond)
+	if !tw.RemoveTask("external") {
+		t.Fatal("repeating task should stay in the index until cancelled")
+	}
+	// 取消时正在进行的一次执行不受影响
+	time.Sleep(30 * time.Millisecond)
+	n := external.Load()
+	if n < 2 {
+		t.Fatalf("repeating task ran only %d times", n)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if runs.Load() != 3 || external.Load() != n {
+		t.Fatalf("task ran after cancel: runs=%d external=%d->%d", runs.Load(), n, external.Load())
+	}
+}
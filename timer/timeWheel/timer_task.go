@@ -3,6 +3,7 @@ package timeWheel
 import (
 	"container/list"
 	"sync/atomic"
+	"time"
 	"unsafe"
 )
 
@@ -50,4 +51,67 @@ func (t *TimerTaskEntity) Stop() bool {
 		stopped = b.Remove(t)
 	}
 	return stopped
-}
\ No newline at end of file
+}
+
+// TaskHandle AddTask、AddRepeatingTask 返回的任务句柄：
+// - Cancel 取消任务，与执行互斥：返回 true 时任务之后一定不会执行，返回 false 表示任务已执行或已取消
+// - 带 key 的任务同时登记在时间轮的索引中，可通过 RemoveTask(key) 以 O(1) 取消
+// - 重复任务每次执行后重新加入时间轮，job 中调用 Cancel 即可停止
+type TaskHandle struct {
+	tw       *TimeWheel
+	key      any
+	interval int64 // 重复任务的间隔（毫秒），一次性任务为 0
+	task     atomic.Pointer[TimerTaskEntity]
+	done     atomic.Bool // 一次性任务已开始执行，或任务已取消
+}
+
+// Key 返回任务的 key，可能为 nil
+func (h *TaskHandle) Key() any {
+	return h.key
+}
+
+// Cancel 取消任务并从索引中移除，返回是否取消成功；重复调用返回 false。
+// 重复任务正在执行时取消，本次执行不受影响，之后不再执行
+func (h *TaskHandle) Cancel() bool {
+	if !h.done.CompareAndSwap(false, true) {
+		return false
+	}
+	h.tw.unindex(h)
+	if t := h.task.Load(); t != nil {
+		t.Stop()
+	}
+	return true
+}
+
+// schedule 在 at（毫秒时间戳）执行 task，并记录为句柄当前的任务
+func (h *TaskHandle) schedule(at int64, task func()) {
+	t := &TimerTaskEntity{DelayTime: at, Task: task}
+	h.task.Store(t)
+	h.tw.tryAdd(t)
+}
+
+// run 一次性任务到期时执行：已被取消时直接返回
+func (h *TaskHandle) run(job func()) {
+	if !h.done.CompareAndSwap(false, true) {
+		return
+	}
+	h.tw.unindex(h)
+	job()
+}
+
+// repeat 重复任务到期时执行，执行完且未被取消时按固定频率安排下一次；
+// 执行耗时超过间隔而错过的次数直接跳过，下一次在当前时间的一个间隔之后
+func (h *TaskHandle) repeat(job func(h *TaskHandle)) {
+	if h.done.Load() {
+		return
+	}
+	job(h)
+	if h.done.Load() {
+		return
+	}
+	next := h.task.Load().DelayTime + h.interval
+	if now := time.Now().UnixNano() / 1e6; next <= now {
+		next = now + h.interval
+	}
+	h.schedule(next, func() { h.repeat(job) })
+}
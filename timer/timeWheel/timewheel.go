@@ -38,6 +38,10 @@ type TimeWheel struct {
 	currentTime int64       // 当前时间
 	exitC       chan struct{}
 	waitGroup   sync.WaitGroup
+
+	// 带 key 的任务索引，仅最底层时间轮使用，首次 AddTask 时创建
+	keysMu sync.Mutex
+	keys   map[any]*TaskHandle
 }
 
 // NewTimeWheel 创建一个时间轮。
@@ -79,13 +83,18 @@ func (tw *TimeWheel) add(t *TimerTaskEntity) bool {
 		}
 		return true
 	} else {
-		if tw.overflow == nil {
+		if tw.getOverflow() == nil {
 			atomic.CompareAndSwapPointer((*unsafe.Pointer)(unsafe.Pointer(&tw.overflow)), nil, unsafe.Pointer(NewTimeWheel(tw.interval, tw.wheelSize, currentTime, tw.queue)))
 		}
-		return tw.overflow.add(t)
+		return tw.getOverflow().add(t)
 	}
 }
 
+// getOverflow 获取上层时间轮，可能为 nil；上层轮由 add 按需创建，与推进时间并发，使用原子读取。
+func (tw *TimeWheel) getOverflow() *TimeWheel {
+	return (*TimeWheel)(atomic.LoadPointer((*unsafe.Pointer)(unsafe.Pointer(&tw.overflow))))
+}
+
 // tryAdd 将任务尝试加入时间轮；若已到执行窗口内，则直接异步执行。
 func (tw *TimeWheel) tryAdd(t *TimerTaskEntity) {
 	if !tw.add(t) {
@@ -93,12 +102,82 @@ func (tw *TimeWheel) tryAdd(t *TimerTaskEntity) {
 	}
 }
 
+// Schedule 在 at（毫秒时间戳）执行 task，供包外使用；at 已进入当前 tick 时立即异步执行。
+// 每个任务在自己的协程中执行，返回的任务可以用 Stop 取消。
+func (tw *TimeWheel) Schedule(at int64, task func()) *TimerTaskEntity {
+	t := &TimerTaskEntity{DelayTime: at, Task: task}
+	tw.tryAdd(t)
+	return t
+}
+
+// AddTask 在 delay 之后执行 job，返回可以取消的任务句柄；delay 小于等于 0 或落在当前 tick 内时立即异步执行。
+// key 不为 nil 时登记到索引中，可通过 RemoveTask(key) 取消；key 已存在时先取消原有的任务。
+func (tw *TimeWheel) AddTask(delay time.Duration, key any, job func()) *TaskHandle {
+	h := &TaskHandle{tw: tw, key: key}
+	tw.index(h)
+	h.schedule(time.Now().Add(delay).UnixNano()/1e6, func() { h.run(job) })
+	return h
+}
+
+// AddRepeatingTask 每隔 interval 执行一次 job，直到任务被取消；job 收到任务句柄，调用其 Cancel 即可在任务内部停止。
+// 下一次执行在本次执行完后安排，同一任务不会并发执行；interval 小于 tick 时按 tick 执行，小于等于 0 时 panic。
+// key 的含义与 AddTask 相同，重复任务在取消前一直保留在索引中。
+func (tw *TimeWheel) AddRepeatingTask(interval time.Duration, key any, job func(h *TaskHandle)) *TaskHandle {
+	if interval <= 0 {
+		panic("non-positive interval for TimeWheel.AddRepeatingTask")
+	}
+	h := &TaskHandle{tw: tw, key: key, interval: max(interval.Milliseconds(), tw.tick)}
+	tw.index(h)
+	h.schedule(time.Now().UnixNano()/1e6+h.interval, func() { h.repeat(job) })
+	return h
+}
+
+// RemoveTask 取消 key 对应的任务，返回是否取消成功；通过索引查找，不遍历时间格
+func (tw *TimeWheel) RemoveTask(key any) bool {
+	tw.keysMu.Lock()
+	h := tw.keys[key]
+	tw.keysMu.Unlock()
+	return h != nil && h.Cancel()
+}
+
+// index 将带 key 的任务登记到索引中，key 已存在时取消原有的任务
+func (tw *TimeWheel) index(h *TaskHandle) {
+	if h.key == nil {
+		return
+	}
+	tw.keysMu.Lock()
+	if tw.keys == nil {
+		tw.keys = map[any]*TaskHandle{}
+	}
+	old := tw.keys[h.key]
+	tw.keys[h.key] = h
+	tw.keysMu.Unlock()
+	if old != nil {
+		old.Cancel()
+	}
+}
+
+// unindex 任务执行或取消后从索引中移除，key 已被新任务占用时保留新任务
+func (tw *TimeWheel) unindex(h *TaskHandle) {
+	if h.key == nil {
+		return
+	}
+	tw.keysMu.Lock()
+	if tw.keys[h.key] == h {
+		delete(tw.keys, h.key)
+	}
+	tw.keysMu.Unlock()
+}
+
 // advanceClock 推进时间轮的当前时间到给定 timeMs 所在的对齐刻度，并联动上层轮。
+// currentTime 会被 add 并发读取，使用原子读写。
 func (tw *TimeWheel) advanceClock(timeMs int64) {
-	if timeMs >= tw.currentTime+tw.tick {
-		tw.currentTime = truncate(timeMs, tw.tick)
-		if tw.overflow != nil {
-			tw.overflow.advanceClock(tw.currentTime)
+	currentTime := atomic.LoadInt64(&tw.currentTime)
+	if timeMs >= currentTime+tw.tick {
+		currentTime = truncate(timeMs, tw.tick)
+		atomic.StoreInt64(&tw.currentTime, currentTime)
+		if overflow := tw.getOverflow(); overflow != nil {
+			overflow.advanceClock(currentTime)
 		}
 	}
 }